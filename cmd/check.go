@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/jaeles-project/gospider/core"
+	"github.com/spf13/cobra"
+)
+
+// newCheckCmd returns the check subcommand, which explains how the given
+// flags would scope a URL without crawling anything
+func newCheckCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check <url>",
+		Short: "Show whether a URL would be crawled with the given scope flags",
+		Long: "Runs a URL through the scope, blacklist and extension filters a crawl would use, without sending any request.\n" +
+			"The scope is built from -s when given, otherwise from the URL itself.",
+		Args:         cobra.ExactArgs(1),
+		RunE:         runCheck,
+		SilenceUsage: true,
+	}
+	registerGlobalFlags(cmd)
+	return cmd
+}
+
+func runCheck(cmd *cobra.Command, args []string) error {
+	cfg := core.NewCrawlerConfig(cmd)
+	target := cfg.Site
+	if target == "" {
+		target = args[0]
+	}
+	site, err := url.Parse(target)
+	if err != nil || site.Scheme == "" || site.Host == "" {
+		return fmt.Errorf("invalid site %q: expected an absolute URL", target)
+	}
+
+	check, err := core.CheckScope(args[0], site, cfg)
+	if err != nil {
+		return err
+	}
+	printScopeCheck(cmd.OutOrStdout(), site, check)
+	return nil
+}
+
+func printScopeCheck(w io.Writer, site *url.URL, check core.ScopeCheck) {
+	yesNo := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+
+	fmt.Fprintf(w, "URL:                  %s\n", check.URL)
+	fmt.Fprintf(w, "Site:                 %s\n", site)
+	if check.Excluded {
+		fmt.Fprintf(w, "Normalized:           rejected (unsupported scheme, asset extension or looping path)\n")
+	} else {
+		fmt.Fprintf(w, "Normalized:           %s\n", check.Normalized)
+	}
+	scope := yesNo(check.InScope)
+	if check.InScope {
+		scope += " (" + check.ScopeMatch + ")"
+	}
+	fmt.Fprintf(w, "In scope:             %s\n", scope)
+	blacklisted := yesNo(check.Blacklisted)
+	if check.Blacklisted {
+		blacklisted += " (" + check.BlacklistMatch + ")"
+	}
+	fmt.Fprintf(w, "Blacklisted:          %s\n", blacklisted)
+	fmt.Fprintf(w, "Skipped extension:    %s\n", yesNo(check.DisallowedExtension))
+	fmt.Fprintf(w, "Would crawl:          %s\n", yesNo(check.WouldCrawl))
+	fmt.Fprintf(w, "Scope patterns:       %s\n", strings.Join(check.ScopePatterns, ", "))
+	fmt.Fprintf(w, "Blacklist patterns:   %s\n", strings.Join(check.BlacklistPatterns, ", "))
+}
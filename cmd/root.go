@@ -1,11 +1,13 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 
 	"github.com/jaeles-project/gospider/core"
+	"github.com/jaeles-project/gospider/internal/config"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -27,10 +29,23 @@ func newRootCmd() *cobra.Command {
 		RunE:  runRoot,
 	}
 	registerGlobalFlags(cmd)
+	cmd.AddCommand(newCheckCmd())
+	cmd.AddCommand(newServeCmd())
 	return cmd
 }
 // runRoot is the main function for the crawler.
 func runRoot(cmd *cobra.Command, _ []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	profile, _ := cmd.Flags().GetString("profile")
+	if profile != "" && configFile == "" {
+		return fmt.Errorf("--profile needs a --config file")
+	}
+	if configFile != "" {
+		if err := config.ApplyFile(cmd.Flags(), configFile, profile); err != nil {
+			return fmt.Errorf("--config %s: %w", configFile, err)
+		}
+	}
+
 	version, _ := cmd.Flags().GetBool("version")
 	if version {
 		fmt.Printf("Version: %s\n", core.VERSION)
@@ -38,6 +53,16 @@ func runRoot(cmd *cobra.Command, _ []string) error {
 		return nil
 	}
 
+	printSchema, _ := cmd.Flags().GetBool("print-schema")
+	if printSchema {
+		data, err := json.MarshalIndent(core.SpiderOutputSchema(), "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
 	isDebug, _ := cmd.Flags().GetBool("debug")
 	if isDebug {
 		core.Logger.SetLevel(logrus.DebugLevel)
@@ -51,6 +76,23 @@ func runRoot(cmd *cobra.Command, _ []string) error {
 	}
 
 	outputFolder, _ := cmd.Flags().GetString("output")
+	stdoutFormat, _ := cmd.Flags().GetString("stdout-format")
+	fileFormat, _ := cmd.Flags().GetString("file-format")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	if _, _, err := core.ResolveOutputFormats(stdoutFormat, fileFormat, jsonOutput, quiet, outputFolder != ""); err != nil {
+		return err
+	}
+	if tui, _ := cmd.Flags().GetBool("tui"); tui && (jsonOutput || quiet || (stdoutFormat != "" && stdoutFormat != core.OutputFormatText)) {
+		return fmt.Errorf("--tui cannot be combined with --json, --quiet or a non-text --stdout-format")
+	}
+	outputTemplate, _ := cmd.Flags().GetString("template")
+	if _, err := core.ParseOutputTemplate(outputTemplate); err != nil {
+		return err
+	}
+	if replayFiles, _ := cmd.Flags().GetBool("replay-files"); replayFiles && outputFolder == "" {
+		return fmt.Errorf("--replay-files needs an --output folder")
+	}
 	if outputFolder != "" {
 		if _, err := os.Stat(outputFolder); os.IsNotExist(err) {
 			_ = os.Mkdir(outputFolder, os.ModePerm)
@@ -62,18 +104,30 @@ func runRoot(cmd *cobra.Command, _ []string) error {
 		cmd.Flags().Set("js", "false")
 		cmd.Flags().Set("sitemap", "false")
 		cmd.Flags().Set("robots", "false")
+		cmd.Flags().Set("well-known", "false")
+		cmd.Flags().Set("openapi", "false")
 		cmd.Flags().Set("other-source", "false")
 		cmd.Flags().Set("include-subs", "false")
 		cmd.Flags().Set("include-other-source", "false")
 	}
 
 	crawlerConfig := core.NewCrawlerConfig(cmd)
-	engine := core.NewEngine(crawlerConfig)
+	engine, err := core.NewEngine(crawlerConfig)
+	if err != nil {
+		return err
+	}
+	if crawlerConfig.MetricsAddr != "" {
+		stopMetrics, err := engine.ServeMetrics(crawlerConfig.MetricsAddr)
+		if err != nil {
+			return err
+		}
+		defer stopMetrics()
+	}
 
-	engine.Start()
+	err = engine.Start()
 	engine.Shutdown()
 
-	return nil
+	return err
 }
 
 func Examples() string {
@@ -84,14 +138,27 @@ echo 'http://target.com' | gospider -o output -c 10 -d 1 --other-source`
 }
 
 func registerGlobalFlags(cmd *cobra.Command) {
+	cmd.Flags().String("config", "", "YAML or TOML file setting any of these flags by their long name; flags given on the command line win")
+	cmd.Flags().String("profile", "", "Apply a named profile from the --config file's profiles table on top of its other settings")
 	cmd.Flags().StringP("site", "s", "", "Site to crawl")
 	cmd.Flags().StringP("sites", "S", "", "Site list to crawl")
 	cmd.Flags().StringP("proxy", "p", "", "Proxy (Ex: http://127.0.0.1:8080)")
+	cmd.Flags().String("proxy-file", "", "File with proxies to rotate through (one per line)")
+	cmd.Flags().Bool("test-proxies", false, "Test proxies from --proxy-file before crawling and drop dead ones")
+	cmd.Flags().Bool("require-working-proxies", false, "With --test-proxies, abort instead of crawling when no proxy passes the test")
+	cmd.Flags().StringSlice("proxy-test-url", []string{}, "URL used to test proxies (Use multiple flag to set multiple URLs)")
+	cmd.Flags().String("proxy-rotate-every", "", "Rotate --proxy-file proxies every N requests or after a duration (Ex: 50, 30s); unset rotates on 5xx/429 only")
+	cmd.Flags().Int("proxy-health-interval", 0, "Re-test rotated proxies every N seconds during the crawl (0 disables)")
 	cmd.Flags().StringP("output", "o", "", "Output folder")
+	cmd.Flags().String("dedup-output", "", "Deduplicate output files by record\n\tstream: drop duplicates as they are written\n\tfinal: also rewrite each file on exit keeping the richest record")
+	cmd.Flags().StringSlice("dedup-fields", core.DefaultDedupFields, "JSON fields that identify a duplicate record for --dedup-output")
+	cmd.Flags().Bool("split-output", false, "Write one file per finding type (<host>.urls, <host>.subdomains, ...) inside the output folder")
+	cmd.Flags().Bool("output-by-host", false, "Write each finding to the file of the host it was found on instead of the target's file")
 	cmd.Flags().StringP("user-agent", "u", "web", "User Agent to use\n\tweb: random web user-agent\n\tmobi: random mobile user-agent\n\tor you can set your special user-agent")
 	cmd.Flags().StringP("cookie", "", "", "Cookie to use (testA=a; testB=b)")
 	cmd.Flags().StringArrayP("header", "H", []string{}, "Header to use (Use multiple flag to set multiple header)")
 	cmd.Flags().StringP("burp", "", "", "Load headers and cookie from burp raw http request")
+	cmd.Flags().String("burp-sitemap", "", "Seed the crawl with the requests of a Burp Suite sitemap XML export")
 	cmd.Flags().StringP("blacklist", "", "", "Blacklist URL Regex")
 	cmd.Flags().StringP("whitelist", "", "", "Whitelist URL Regex")
 	cmd.Flags().StringP("whitelist-domain", "", "", "Whitelist Domain")
@@ -99,41 +166,142 @@ func registerGlobalFlags(cmd *cobra.Command) {
 
 	cmd.Flags().BoolP("stealth", "", false, "Enable stealth mode with advanced WAF bypass techniques")
 	cmd.Flags().IntP("threads", "t", 1, "Number of threads (Run sites in parallel)")
+	cmd.Flags().Bool("deterministic", false, "Crawl sequentially in a stable order with seeded randomness for reproducible output (slow)")
+	cmd.Flags().Int64("seed", 1, "Random seed used by --deterministic")
+	cmd.Flags().Int("max-conns-per-host", 0, "Max open connections per host (0 keeps the client default, lowered to fit the open file ulimit)")
+	cmd.Flags().Int("max-idle-conns", 0, "Max idle keep-alive connections kept per crawler (0 keeps the client default, lowered to fit the open file ulimit)")
 	cmd.Flags().IntP("concurrent", "c", 5, "The number of the maximum allowed concurrent requests of the matching domains")
+	cmd.Flags().Int("ramp-up", 0, "Ramp concurrency up to --concurrent over this many seconds instead of starting at full speed (0 to disable)")
+	cmd.Flags().Int("ramp-up-requests", 0, "Ramp concurrency up to --concurrent over this many completed requests (0 to disable)")
+	cmd.Flags().Int("ramp-floor", 1, "Concurrency a --ramp-up or --ramp-up-requests ramp starts from")
+	cmd.Flags().String("ramp-shape", core.RampShapeLinear, "Ramp curve: linear, or exponential to double concurrency in steps")
+	cmd.Flags().Int("js-concurrent", 0, "Maximum concurrent JavaScript/LinkFinder requests per domain (0 = same as --concurrent)")
+	cmd.Flags().Float64("global-rps", 0, "Cap the requests per second of the whole run, shared by all threads and sites (0 = no cap)")
 	cmd.Flags().IntP("depth", "d", 1, "MaxDepth limits the recursion depth of visited URLs. (Set it to 0 for infinite recursion)")
 	cmd.Flags().IntP("delay", "k", 0, "Delay is the duration to wait before creating a new request to the matching domains (second)")
 	cmd.Flags().IntP("random-delay", "K", 0, "RandomDelay is the extra randomized duration to wait added to Delay before creating a new request (second)")
 	cmd.Flags().IntP("timeout", "m", 10, "Request timeout (second)")
+	cmd.Flags().Int("host-timeout", 0, "Per-host request timeout, applied when stricter than --timeout (second, 0 to disable)")
+	cmd.Flags().Bool("resolve-once", false, "Cache DNS answers in-process instead of resolving every new connection")
+	cmd.Flags().Int("dns-cache-ttl", 0, "How long --resolve-once keeps an answer (second, 0 for the whole crawl)")
+	cmd.Flags().Bool("dns-pin", false, "With --resolve-once, stick to the first resolved IP instead of rotating over all of them")
+	cmd.Flags().String("sni", "", "TLS server name (SNI) to send instead of the URL host, for authorized domain-fronting tests")
+	cmd.Flags().Bool("prioritize", false, "Crawl discovered pages whose path contains a --priority-keywords keyword before the others")
+	cmd.Flags().StringSlice("priority-keywords", core.DefaultPriorityKeywords, "Path keywords that --prioritize moves to the front of the crawl queue")
+	cmd.Flags().Int("max-queue-size", 0, "Max discovered pages waiting in memory; the rest spill to a temp file until the queue drains (0 = unbounded)")
+	cmd.Flags().Int("request-queue-size", 1000, "Max generated requests (JS requests, form variants, reflection mutations) awaiting a response before generation blocks (0 to disable)")
+	cmd.Flags().Int("read-timeout", 0, "Body read deadline once headers arrive; the body read so far is kept (second, 0 to disable; streaming responses default to 3)")
+	cmd.Flags().Int("slow-host-threshold", 5000, "Warn about hosts whose average response time exceeds this (millisecond, 0 to disable)")
+	cmd.Flags().Int("slow-host-samples", 3, "Responses to average before judging a host slow")
+	cmd.Flags().Int("dead-host-threshold", 5, "Skip a host after this many consecutive DNS or connection-refused failures (0 to disable)")
+	cmd.Flags().Int("tls-fallback-threshold", 3, "Warn that a host may need an HTTP/1.1 fallback after this many consecutive TLS failures (0 to disable)")
+	cmd.Flags().Bool("skip-slow-hosts", false, "Skip remaining requests to hosts flagged slow instead of only warning")
 
 	cmd.Flags().BoolP("base", "B", false, "Disable all and only use HTML content")
 	cmd.Flags().BoolP("js", "", true, "Enable linkfinder in javascript file")
+	cmd.Flags().Bool("js-ast", false, "Extract JS requests by parsing scripts instead of with regexes (slower, more accurate; falls back to regexes on parse errors)")
+	cmd.Flags().Bool("json-linkfinder", true, "Also run linkfinder on JSON responses (use --json-linkfinder=false on API-heavy targets)")
+	cmd.Flags().Bool("head-first", false, "Send HEAD before GET and skip non-text resources larger than --head-first-max-size")
+	cmd.Flags().Int("head-first-max-size", 1024, "Largest non-text resource fetched in --head-first mode (KiB)")
+	cmd.Flags().Bool("version-probe", false, "Probe adjacent API versions of crawled paths (v1 -> v2, api -> api/internal) and report the ones that exist")
+	cmd.Flags().StringSlice("version-rule", core.DefaultVersionRules, "Path segment rewrite for --version-probe, e.g. api=api/internal|api/beta (Use multiple flag to set multiple rules)")
+	cmd.Flags().Int("version-bump", 2, "How many versions above an observed vN segment --version-probe tries")
+	cmd.Flags().Int("version-probe-budget", 50, "Maximum number of --version-probe requests per site")
+	cmd.Flags().Bool("report-errors", false, "Report failed requests as error findings with their class (timeout, dns, tls, refused, status), once per host and class")
+	cmd.Flags().String("failed-output", "", "Write the requests that failed with a retryable error (network errors, 408, 429, 5xx) to this file, one JSON object per line")
+	cmd.Flags().String("resume", "", "State file to record the crawl in; rerun with the same file to resume an interrupted crawl where it stopped")
+	cmd.Flags().String("replay-failed", "", "Crawl starting from exactly the requests of a --failed-output file instead of the sites' start pages")
+	cmd.Flags().Int("max-pages-follow", 0, "Follow pagination (rel=next, \"next\"/\"load more\" links, ?page=N and ?offset=N) for up to N pages per listing (0 to disable)")
+	cmd.Flags().Bool("parse-documents", false, "Extract metadata and embedded URLs from PDF and Office (DOCX/XLSX/PPTX) files up to 10 MiB")
+	cmd.Flags().Bool("wasm", false, "Extract endpoint strings from WebAssembly (.wasm) modules")
 	cmd.Flags().BoolP("sitemap", "", false, "Try to crawl sitemap.xml")
 	cmd.Flags().BoolP("robots", "", true, "Try to crawl robots.txt")
+	cmd.Flags().Bool("obey-robots", false, "Never request paths robots.txt disallows for our user agent; they are still reported (overrides the --robots crawl of disallowed paths)")
+	cmd.Flags().String("params-output", "", "Write a sorted wordlist of every parameter name seen in URLs, forms and JSON bodies to this file")
+	cmd.Flags().Bool("params-split", false, "With --params-output, also write per-source lists (-query, -body, -json)")
+	cmd.Flags().String("openapi-out", "", "Write a draft OpenAPI 3.0 spec of the requests the crawl sent (paths, methods, parameters, content types, status codes) to this file, as YAML for .yaml/.yml and JSON otherwise")
+	cmd.Flags().String("words-output", "", "Write a target-specific wordlist from URL path segments, source map entries and page titles/headings to this file")
+	cmd.Flags().String("sourcemap-dir", "", "Write the original sources embedded in the source maps found by --js to this folder, one subfolder per host")
+	cmd.Flags().Int("words-min-length", 3, "Shortest word kept by --words-output")
+	cmd.Flags().Int("words-min-count", 1, "Times a word must be seen to be kept by --words-output")
+	cmd.Flags().String("postman", "", "Seed the crawl with the requests of a Postman v2.x collection or Insomnia v4 export")
+	cmd.Flags().String("har", "", "Seed the crawl with the requests of a HAR capture and reuse the cookies it recorded")
+	cmd.Flags().String("postman-out", "", "Write every request discovered in scripts, forms and Katana results to this file as a Postman v2.1 collection, with headers, bodies and the session cookie")
+	cmd.Flags().String("postman-env", "", "Postman environment (or flat JSON object) resolving --postman variables such as {{baseUrl}}")
+	cmd.Flags().String("since-modified", "", "ETag/Last-Modified cache file; unchanged pages are revalidated with conditional requests and their recorded links re-queued")
+	cmd.Flags().Bool("well-known", false, "Probe /.well-known/ documents (security.txt, openid-configuration, assetlinks.json, ...)")
+	cmd.Flags().String("session-heartbeat", "", "URL (absolute or relative to the site) requested periodically with the crawl's cookies to check the session is still logged in, e.g. /api/me")
+	cmd.Flags().Int("session-heartbeat-interval", 60, "Seconds between --session-heartbeat checks")
+	cmd.Flags().String("session-logged-out", "", "Regex that marks a --session-heartbeat response as logged out, e.g. 'name=\"password\"'")
+	cmd.Flags().Bool("follow-logout", false, "Follow logout, password-change and account-deletion links instead of only reporting them")
+	cmd.Flags().StringSlice("session-pattern", core.DefaultSessionPatterns, "URL fragment marking a link that would end the session; such links are reported but not followed (Use multiple flag to set multiple patterns)")
+	cmd.Flags().StringSlice("url-attr", core.DefaultURLAttributes, "Extra attribute scanned on every element for URLs (Use multiple flag to set multiple attributes)")
+	cmd.Flags().StringSlice("attr-method", core.DefaultAttributeMethods, "Attribute mapped to an HTTP method, e.g. hx-get=GET (Use multiple flag to set multiple mappings)")
+	cmd.Flags().Bool("dependencies", false, "Report third-party scripts and stylesheets loaded from other origins, with their subresource integrity hash")
+	cmd.Flags().Bool("check-sensitive", false, "Probe commonly exposed sensitive files (.git/config, .env, backups, phpinfo, server-status) and report the real hits")
+	cmd.Flags().Bool("soft-404", true, "Detect hosts answering any path with a catch-all page, from one random path per host and extension, and drop probe hits that look like it")
+	cmd.Flags().StringArray("match-regex", nil, "Report responses whose body matches this regex, as name=regex or a bare regex (Use multiple flag to set multiple patterns)")
+	cmd.Flags().String("match-file", "", "File of named patterns for content matching, one 'name regex' per line")
+	cmd.Flags().String("secret-rules", "", "YAML file of extra secret detection rules (name, pattern, confidence) run with the built-in ones")
+	cmd.Flags().String("sensitive-list", "", "File of paths for --check-sensitive, one per line, optionally followed by a regex the content must match")
+	cmd.Flags().StringSlice("well-known-path", core.DefaultWellKnownPaths, "/.well-known/ document to probe with --well-known (Use multiple flag to set multiple paths)")
+	cmd.Flags().Bool("openapi", false, "Probe common Swagger/OpenAPI spec paths and crawl every operation they document")
+	cmd.Flags().StringSlice("openapi-path", core.DefaultOpenAPIPaths, "Spec path to probe with --openapi (Use multiple flag to set multiple paths)")
 	cmd.Flags().BoolP("other-source", "a", false, "Find URLs from 3rd party (Archive.org, CommonCrawl.org, VirusTotal.com, AlienVault.com)")
+	cmd.Flags().Int("other-source-limit", 0, "Stop other-source lookups after this many unique URLs across providers (0 = no cap)")
+	cmd.Flags().Float64("other-source-rps", 1, "Page requests per second sent to each other-source provider (0 = no cap)")
 	cmd.Flags().BoolP("include-subs", "w", false, "Include subdomains crawled from 3rd party. Default is main domain")
 	cmd.Flags().BoolP("include-other-source", "r", false, "Also include other-source's urls (still crawl and request)")
 	cmd.Flags().Bool("subs", false, "Include subdomains")
 
 	cmd.Flags().BoolP("debug", "", false, "Turn on debug mode")
+	cmd.Flags().Bool("profile-extractors", false, "Time each response extractor and print the breakdown when the crawl ends")
+	cmd.Flags().Bool("tui", false, "Show a live dashboard of counters, status codes, queues, hosts and recent findings instead of printing results (p pauses, q quits)")
+	cmd.Flags().String("metrics-addr", "", "Serve pprof and Prometheus metrics on this address while crawling (Ex: :6060)")
 	cmd.Flags().BoolP("json", "", false, "Enable JSON output")
 	cmd.Flags().BoolP("verbose", "v", false, "Turn on verbose")
 	cmd.Flags().BoolP("quiet", "q", false, "Suppress all the output and only show URL")
+	cmd.Flags().String("stdout-format", "", "Format of results on stdout: text, plain or json (default follows --json/--quiet)")
+	cmd.Flags().String("file-format", "", "Format of results in the -o files: text, plain or json (default same as stdout)")
+	cmd.Flags().String("template", "", "Go text/template for each text or plain result line, e.g. '{{.Output}} {{.StatusCode}}' (helpers: host, path, query, scheme)")
+	cmd.Flags().Bool("in-scope-only", false, "Only report findings whose URL is in the crawl scope (--whitelist, --whitelist-domain or the target)")
+	cmd.Flags().Bool("normalize-unicode", false, "Report internationalized hosts in Unicode (bücher.example) instead of punycode (xn--bcher-kva.example)")
+	cmd.Flags().StringSlice("exclude-status", []string{}, "Do not report findings with these status codes, e.g. 404,403 (URLs are still crawled)")
+	cmd.Flags().StringSlice("include-status", []string{}, "Only report findings with these status codes, e.g. 200,301,302 (findings without a status are kept)")
 	cmd.Flags().BoolP("no-redirect", "", false, "Disable redirect")
+	cmd.Flags().Int("max-redirects", 10, "Maximum number of redirects to follow per request (0 uses the default of 10, a negative value disables following)")
+	cmd.Flags().Bool("redirect-chain", false, "Report redirect chains as [redirect] findings")
 	cmd.Flags().BoolP("version", "", false, "Check version")
+	cmd.Flags().Bool("print-schema", false, "Print the JSON Schema of --json records and exit")
 	cmd.Flags().BoolP("length", "l", false, "Turn on length")
 	cmd.Flags().BoolP("raw", "R", false, "Enable raw output")
 	cmd.Flags().Bool("reflected", false, "Enable reflected payload detection")
 	cmd.Flags().String("reflected-output", "", "File path to store reflected findings")
+	cmd.Flags().Bool("replay-files", false, "Write a raw HTTP request file and a curl command for every reflected finding and every non-GET, body or header-carrying js-request into <output>/replay")
+	cmd.Flags().String("sarif", "", "Write reflected and dom-sink findings to this file as a SARIF 2.1.0 log")
+	cmd.Flags().StringArray("sink", []string{}, "Also send findings to an output sink, e.g. webhook:https://hooks.example.com/x, file:results.jsonl stdout:json, nats:nats://host:4222/subject or kafka-rest:http://rest-proxy:8082/topic (repeatable)")
+	cmd.Flags().String("stats-output", "", "Write the end-of-run statistics, with per-host counters, to this file as JSON")
+	cmd.Flags().String("redis", "", "Share the crawl with other gospider instances through this Redis server, e.g. redis://localhost:6379/0")
+	cmd.Flags().String("redis-role", "worker", "Role in a --redis crawl: coordinator queues the targets, worker crawls them")
+	cmd.Flags().String("redis-prefix", "gospider", "Prefix of the Redis keys of a --redis crawl, to run several crawls on one server")
+	cmd.Flags().String("webhook-url", "", "POST findings to this URL as batched JSON arrays while crawling")
 	cmd.Flags().Bool("dom-dedup", false, "Enable DOM structural deduplication")
 	cmd.Flags().Int("dom-dedup-threshold", 6, "Hamming threshold for DOM dedup")
 	cmd.Flags().Int("baseline-fuzz-cap", 2, "Maximum baseline fuzz mutations per parameter")
+	cmd.Flags().String("mutation-delay", "50,170", "Random delay range in milliseconds before queuing mutated requests (min,max; 0 disables)")
 	cmd.Flags().Bool("hybrid", false, "Enable state-aware hybrid crawling (requires Chromium)")
 	cmd.Flags().Int("hybrid-workers", 2, "Number of concurrent browser workers for hybrid crawling")
+	cmd.Flags().Int("hybrid-nav-concurrency", 0, "Maximum browser pages navigating at once; the rest stay warm in the pool (0 = --hybrid-workers)")
 	cmd.Flags().Int("hybrid-nav-timeout", 12, "Hybrid browser navigation timeout in seconds")
 	cmd.Flags().Int("hybrid-stabilization", 600, "Extra wait after load before analysis in milliseconds")
+	cmd.Flags().String("hybrid-wait", core.HybridWaitLoad, "When hybrid navigation is done: load, or network-idle to also wait for XHR/fetch traffic to settle")
+	cmd.Flags().Int("hybrid-idle-window", 500, "Quiet period without requests that counts as --hybrid-wait network-idle (milliseconds)")
 	cmd.Flags().Bool("hybrid-headless", true, "Run hybrid browser workers in headless mode")
 	cmd.Flags().StringSlice("hybrid-init-script", []string{}, "Inject JavaScript files into hybrid browsers before navigation")
 	cmd.Flags().Int("hybrid-max-visits", 150, "Limit total pages explored by hybrid browser (0 = unlimited)")
+	cmd.Flags().Bool("hybrid-selective", true, "Only render pages in the hybrid browser when the static crawl suggests client-side rendering")
+	cmd.Flags().Int("hybrid-min-links", 5, "With --hybrid-selective, render pages where the static crawl found fewer links than this")
+	cmd.Flags().Int("hybrid-thin-text", 200, "With --hybrid-selective, render pages with scripts and less visible text than this (characters)")
 	cmd.Flags().String("intensity", "passive", "Crawl intensity (passive, medium, aggressive, ultra)")
 
 	cmd.Flags().SortFlags = false
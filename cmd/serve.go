@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jaeles-project/gospider/core"
+	"github.com/jaeles-project/gospider/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// serverOnlyFlags cannot be set per job: they control the server process
+// or name targets, which jobs list in their own field
+var serverOnlyFlags = map[string]bool{
+	"config": true, "profile": true, "site": true, "sites": true, "tui": true, "metrics-addr": true,
+	"version": true, "print-schema": true, "debug": true, "verbose": true, "listen": true, "api-token": true,
+	"max-jobs": true, "max-job-results": true, "job-retention": true,
+}
+
+// serverFileFlags read or write files on the server, so only serve may set
+// them; jobs share the server's values. --sink is limited to sinks other
+// than file:
+var serverFileFlags = map[string]bool{
+	"output": true, "resume": true, "sourcemap-dir": true, "replay-files": true, "openapi-out": true,
+	"postman-out": true, "stats-output": true, "sarif": true, "params-output": true, "failed-output": true,
+	"words-output": true, "reflected-output": true, "since-modified": true,
+	"sensitive-list": true, "hybrid-init-script": true, "burp": true, "burp-sitemap": true, "har": true,
+	"postman": true, "postman-env": true, "match-file": true, "secret-rules": true, "proxy-file": true,
+	"replay-failed": true,
+}
+
+// newServeCmd returns the serve subcommand, which runs crawl jobs submitted
+// over an HTTP API
+func newServeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run as a service that crawls jobs submitted over an HTTP API",
+		Long: "Starts an HTTP API to submit crawl jobs, query their status and statistics, stream their findings and cancel them.\n" +
+			"Crawl flags given to serve are the defaults of every job; a job's options override them.",
+		Args:         cobra.NoArgs,
+		RunE:         runServe,
+		SilenceUsage: true,
+	}
+	registerGlobalFlags(cmd)
+	cmd.Flags().String("listen", "127.0.0.1:8080", "Address the API listens on")
+	cmd.Flags().String("api-token", "", "Require this bearer token on every API request")
+	cmd.Flags().Int("max-jobs", core.DefaultMaxJobs, "Jobs run at once; more are refused with 429 until one ends")
+	cmd.Flags().Int("max-job-results", core.DefaultMaxJobResults, "Findings kept per job; the oldest are dropped past it")
+	cmd.Flags().Duration("job-retention", core.DefaultJobRetention, "How long a finished job and its findings are kept before they are forgotten")
+	return cmd
+}
+
+func runServe(cmd *cobra.Command, _ []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	profile, _ := cmd.Flags().GetString("profile")
+	if profile != "" && configFile == "" {
+		return fmt.Errorf("--profile needs a --config file")
+	}
+	if configFile != "" {
+		if err := config.ApplyFile(cmd.Flags(), configFile, profile); err != nil {
+			return fmt.Errorf("--config %s: %w", configFile, err)
+		}
+	}
+
+	isDebug, _ := cmd.Flags().GetBool("debug")
+	if isDebug {
+		core.Logger.SetLevel(logrus.DebugLevel)
+	} else {
+		core.Logger.SetLevel(logrus.InfoLevel)
+	}
+	if verbose, _ := cmd.Flags().GetBool("verbose"); !verbose && !isDebug {
+		core.Logger.SetOutput(io.Discard)
+	}
+
+	defaults := config.FileOptions{Values: changedFlags(cmd.Flags())}
+	listen, _ := cmd.Flags().GetString("listen")
+	token, _ := cmd.Flags().GetString("api-token")
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	server := core.NewJobServer(ctx, func(options map[string]interface{}) (core.CrawlerConfig, error) {
+		return jobConfig(defaults, options)
+	}, token)
+	server.MaxJobs, _ = cmd.Flags().GetInt("max-jobs")
+	server.MaxResults, _ = cmd.Flags().GetInt("max-job-results")
+	server.Retention, _ = cmd.Flags().GetDuration("job-retention")
+
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return err
+	}
+	httpServer := &http.Server{Handler: server.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdown)
+	}()
+	fmt.Fprintf(cmd.ErrOrStderr(), "Serving the job API on http://%s/jobs\n", listener.Addr())
+	if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// jobConfig builds a job's configuration from a fresh set of the crawl
+// flags: the job's options first, then the server defaults for every flag
+// the options left alone
+func jobConfig(defaults config.FileOptions, options map[string]interface{}) (core.CrawlerConfig, error) {
+	for name, value := range options {
+		if serverOnlyFlags[name] {
+			return core.CrawlerConfig{}, fmt.Errorf("option %q cannot be set per job", name)
+		}
+		if serverFileFlags[name] {
+			return core.CrawlerConfig{}, fmt.Errorf("option %q uses files on the server and can only be given to serve", name)
+		}
+		if name == "sink" && fileSink(value) {
+			return core.CrawlerConfig{}, fmt.Errorf("file: sinks can only be given to serve")
+		}
+	}
+	cmd := &cobra.Command{}
+	registerGlobalFlags(cmd)
+	if err := (config.FileOptions{Values: options}).Apply(cmd.Flags(), ""); err != nil {
+		return core.CrawlerConfig{}, err
+	}
+	if err := defaults.Apply(cmd.Flags(), ""); err != nil {
+		return core.CrawlerConfig{}, err
+	}
+
+	outputFolder, _ := cmd.Flags().GetString("output")
+	stdoutFormat, _ := cmd.Flags().GetString("stdout-format")
+	fileFormat, _ := cmd.Flags().GetString("file-format")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	if _, _, err := core.ResolveOutputFormats(stdoutFormat, fileFormat, jsonOutput, quiet, outputFolder != ""); err != nil {
+		return core.CrawlerConfig{}, err
+	}
+	outputTemplate, _ := cmd.Flags().GetString("template")
+	if _, err := core.ParseOutputTemplate(outputTemplate); err != nil {
+		return core.CrawlerConfig{}, err
+	}
+	if outputFolder != "" {
+		if err := os.MkdirAll(outputFolder, os.ModePerm); err != nil {
+			return core.CrawlerConfig{}, err
+		}
+	}
+	return core.NewCrawlerConfig(cmd), nil
+}
+
+// fileSink reports whether a job's sink option names a file: sink
+func fileSink(value interface{}) bool {
+	specs, ok := value.([]interface{})
+	if !ok {
+		specs = []interface{}{value}
+	}
+	for _, spec := range specs {
+		if s, ok := spec.(string); ok && strings.HasPrefix(strings.TrimSpace(s), "file:") {
+			return true
+		}
+	}
+	return false
+}
+
+// changedFlags returns the crawl flags set on the command line or by
+// --config, as values config.FileOptions can apply
+func changedFlags(flags *pflag.FlagSet) map[string]interface{} {
+	values := map[string]interface{}{}
+	flags.Visit(func(f *pflag.Flag) {
+		if serverOnlyFlags[f.Name] {
+			return
+		}
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			items := []interface{}{}
+			for _, item := range slice.GetSlice() {
+				items = append(items, item)
+			}
+			values[f.Name] = items
+			return
+		}
+		values[f.Name] = f.Value.String()
+	})
+	return values
+}
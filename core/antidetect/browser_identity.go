@@ -0,0 +1,148 @@
+package antidetect
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// identityBrowsers are the browsers a "random" profile picks from
+var identityBrowsers = []string{"chrome", "firefox", "safari", "edge"}
+
+// BrowserIdentity is one coherent browser persona. The user agent, TLS
+// profile, JA3 fingerprint and HTTP/2 settings all come from the same
+// browser, because mixing them (a Chrome UA over a Firefox handshake) is
+// trivially detectable
+type BrowserIdentity struct {
+	Browser   string
+	UserAgent BrowserUserAgent
+	TLS       BrowserProfile
+	JA3       JA3Fingerprint
+	HTTP2     BrowserHTTP2Profile
+}
+
+// NewBrowserIdentity derives every fingerprint from a single browser.
+// "random" or an unknown name picks one browser for the whole session
+func NewBrowserIdentity(browser string) BrowserIdentity {
+	browser = strings.ToLower(strings.TrimSpace(browser))
+	known := false
+	for _, b := range identityBrowsers {
+		if b == browser {
+			known = true
+			break
+		}
+	}
+	if !known {
+		browser = identityBrowsers[randIntn(len(identityBrowsers))]
+	}
+
+	engine := engineFamily(browser)
+	id := BrowserIdentity{
+		Browser:   browser,
+		UserAgent: GetUserAgentByBrowser(browser),
+		TLS:       GetBrowserProfiles()[0],
+		JA3:       GetRandomJA3Fingerprint(engine),
+		HTTP2:     ChromeHTTP2Profile,
+	}
+	for _, p := range GetBrowserProfiles() {
+		if strings.EqualFold(p.Name, engine) {
+			id.TLS = p
+		}
+	}
+	for _, p := range GetHTTP2Profiles() {
+		if strings.EqualFold(p.Name, engine) {
+			id.HTTP2 = p
+		}
+	}
+	return id
+}
+
+// engineFamily maps a browser to the network stack it shares fingerprints
+// with; Edge is Chromium underneath
+func engineFamily(browser string) string {
+	if browser == "edge" {
+		return "chrome"
+	}
+	return browser
+}
+
+// BrowserFamily guesses the browser behind a user agent string, or returns
+// "" when it is not one of the identity browsers
+func BrowserFamily(userAgent string) string {
+	switch {
+	case strings.Contains(userAgent, "Edg/"):
+		return "edge"
+	case strings.Contains(userAgent, "Firefox/"):
+		return "firefox"
+	case strings.Contains(userAgent, "Chrome/"), strings.Contains(userAgent, "CriOS/"):
+		return "chrome"
+	case strings.Contains(userAgent, "Safari/") && strings.Contains(userAgent, "Version/"):
+		return "safari"
+	}
+	return ""
+}
+
+// Validate reports every part of the identity that does not belong to its
+// browser. Parts left empty, such as the JA3 fingerprint when it is
+// disabled, are not checked
+func (id BrowserIdentity) Validate() error {
+	engine := engineFamily(id.Browser)
+	var problems []string
+
+	if ua := id.UserAgent.UserAgent; ua != "" {
+		if family := BrowserFamily(ua); family != id.Browser {
+			problems = append(problems, fmt.Sprintf("user agent looks like %q", orUnknown(family)))
+		}
+		if engine != "chrome" && id.UserAgent.Headers["Sec-Ch-Ua"] != "" {
+			problems = append(problems, "Chromium client hints sent with a non-Chromium user agent")
+		}
+	}
+	if id.TLS.Name != "" && !strings.EqualFold(id.TLS.Name, engine) {
+		problems = append(problems, fmt.Sprintf("TLS profile is %s", id.TLS.Name))
+	}
+	if id.HTTP2.Name != "" && !strings.EqualFold(id.HTTP2.Name, engine) {
+		problems = append(problems, fmt.Sprintf("HTTP/2 profile is %s", id.HTTP2.Name))
+	}
+	if len(id.JA3.CipherSuites) > 0 && !ja3MatchesBrowser(id.JA3, engine) {
+		problems = append(problems, "JA3 fingerprint belongs to another browser")
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("fingerprint is inconsistent with %s: %s", id.Browser, strings.Join(problems, "; "))
+}
+
+// ja3MatchesBrowser compares cipher suites as a set, since a randomized
+// fingerprint keeps the browser's suites but shuffles their order
+func ja3MatchesBrowser(fp JA3Fingerprint, browser string) bool {
+	var known []JA3Fingerprint
+	switch browser {
+	case "chrome":
+		known = ChromeJA3Fingerprints
+	case "firefox":
+		known = FirefoxJA3Fingerprints
+	case "safari":
+		known = SafariJA3Fingerprints
+	}
+	want := sortedSuites(fp.CipherSuites)
+	for _, k := range known {
+		if k.Version == fp.Version && sortedSuites(k.CipherSuites) == want {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedSuites(suites []uint16) string {
+	sorted := append([]uint16(nil), suites...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return fmt.Sprint(sorted)
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
@@ -0,0 +1,61 @@
+package antidetect
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBrowserIdentityIsConsistent(t *testing.T) {
+	for _, browser := range []string{"chrome", "firefox", "safari", "edge", "random", ""} {
+		for i := 0; i < 20; i++ {
+			id := NewBrowserIdentity(browser)
+			if browser != "random" && browser != "" && id.Browser != browser {
+				t.Fatalf("asked for %s, got %s", browser, id.Browser)
+			}
+			if err := id.Validate(); err != nil {
+				t.Fatalf("%s: %v", browser, err)
+			}
+		}
+	}
+}
+
+func TestBrowserIdentityValidateReportsMismatch(t *testing.T) {
+	id := NewBrowserIdentity("firefox")
+	id.UserAgent = ChromeUserAgents[0]
+	id.HTTP2 = SafariHTTP2Profile
+
+	err := id.Validate()
+	if err == nil {
+		t.Fatal("expected a mismatch")
+	}
+	for _, want := range []string{`user agent looks like "chrome"`, "client hints", "HTTP/2 profile is Safari"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("%q missing from %v", want, err)
+		}
+	}
+}
+
+func TestClientKeepsIdentityAcrossRotation(t *testing.T) {
+	cfg := DefaultAntiDetectConfig()
+	cfg.EnableTimingRandomization = false
+	cfg.BrowserProfile = "random"
+	client := NewAntiDetectClient(cfg)
+	defer client.Close()
+
+	browser := client.Identity().Browser
+	for i := 0; i < 10; i++ {
+		client.RotateFingerprint()
+		client.RotateJA3Fingerprint()
+		if err := client.ValidateFingerprint(); err != nil {
+			t.Fatalf("rotation %d: %v", i, err)
+		}
+		if got := client.Identity().Browser; got != browser {
+			t.Fatalf("identity changed from %s to %s", browser, got)
+		}
+	}
+
+	client.SetUserAgent("Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0")
+	if browser != "firefox" && client.ValidateFingerprint() == nil {
+		t.Fatal("a foreign user agent should be reported")
+	}
+}
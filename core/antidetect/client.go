@@ -2,8 +2,10 @@ package antidetect
 
 import (
 	"crypto/tls"
+	"net"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/gocolly/colly/v2"
@@ -26,8 +28,18 @@ type AntiDetectConfig struct {
 	BrowserProfile            string // "chrome", "firefox", "safari", "edge", "random"
 	TimingProfile             *TimingProfile
 	ProxyList                 []string
+	ProxyTestURLs             []string
+	ProxyHealthInterval       time.Duration
+	ProxyRotateEvery          int           // rotate after this many requests (0 disables)
+	ProxyRotateInterval       time.Duration // rotate once the current proxy has been used this long (0 disables)
 	MaxRetries                int
 	RetryDelay                time.Duration
+	MaxConnsPerHost           int // 0 keeps the transport default
+	MaxIdleConns              int // 0 keeps the transport default
+	EnableDNSCache            bool
+	DNSCacheTTL               time.Duration // 0 caches for the lifetime of the client
+	DNSCachePin               bool          // prefer the first resolved address instead of rotating
+	SNI                       string        // TLS server name to present instead of the dialed host
 }
 
 // DefaultAntiDetectConfig returns a default configuration with all features enabled
@@ -62,11 +74,19 @@ type AntiDetectClient struct {
 	userAgent        BrowserUserAgent
 	tlsConfig        *tls.Config
 	proxyRotator     *ProxyRotator
+	healthChecker    *ProxyHealthChecker
 	cloudflareSolver *CloudflareSolver
 	connectionPool   *ConnectionPool
 	patternExecutor  *RequestPatternExecutor
 	ja3Fingerprint   JA3Fingerprint
+	identity         BrowserIdentity
 	wafBypassHeaders map[string]string
+
+	closeOnce       sync.Once
+	proxyMu         sync.Mutex
+	currentProxy    *url.URL
+	proxyRequests   int
+	proxySelectedAt time.Time
 }
 
 // NewAntiDetectClient creates a new anti-detection HTTP client
@@ -85,49 +105,38 @@ func NewAntiDetectClient(config *AntiDetectConfig) *AntiDetectClient {
 
 // initialize sets up the client with anti-detection features
 func (c *AntiDetectClient) initialize() {
+	// One browser is picked for the session and every fingerprint derives from it
+	c.identity = NewBrowserIdentity(c.config.BrowserProfile)
+
 	// Setup TLS configuration
 	if c.config.EnableTLSFingerprinting {
-		if c.config.BrowserProfile == "random" {
-			c.tlsConfig = CreateRandomTLSConfig()
-		} else {
-			profile := GetBrowserProfiles()[0] // Default to Chrome
-			for _, p := range GetBrowserProfiles() {
-				if p.Name == c.config.BrowserProfile {
-					profile = p
-					break
-				}
-			}
-			c.tlsConfig = CreateTLSConfig(profile)
-		}
+		c.tlsConfig = CreateTLSConfig(c.identity.TLS)
 	} else {
 		c.tlsConfig = &tls.Config{
 			InsecureSkipVerify: true,
 			Renegotiation:      tls.RenegotiateOnceAsClient,
 		}
 	}
+	c.tlsConfig = c.withSNI(c.tlsConfig)
 
 	// Setup HTTP transport
+	maxConnsPerHost, maxIdleConns := 10, 100
+	if c.config.MaxConnsPerHost > 0 {
+		maxConnsPerHost = c.config.MaxConnsPerHost
+	}
+	if c.config.MaxIdleConns > 0 {
+		maxIdleConns = c.config.MaxIdleConns
+	}
 	c.transport = &http.Transport{
 		TLSClientConfig:       c.tlsConfig,
-		MaxIdleConns:          100,
-		MaxConnsPerHost:       10,
+		MaxIdleConns:          maxIdleConns,
+		MaxConnsPerHost:       maxConnsPerHost,
 		IdleConnTimeout:       30 * time.Second,
 		TLSHandshakeTimeout:   10 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
 		ForceAttemptHTTP2:     c.config.EnableHTTP2Fingerprinting,
 	}
 
-	// Setup proxy rotation if enabled
-	if c.config.EnableProxyRotation && len(c.config.ProxyList) > 0 {
-		c.proxyRotator = NewProxyRotator(c.config.ProxyList, 3)
-		if proxy := c.proxyRotator.GetNextProxy(); proxy != nil {
-			proxyURL, err := url.Parse(proxy.URL)
-			if err == nil {
-				c.transport.Proxy = http.ProxyURL(proxyURL)
-			}
-		}
-	}
-
 	// Setup HTTP client
 	c.httpClient = &http.Client{
 		Transport: c.transport,
@@ -144,7 +153,7 @@ func (c *AntiDetectClient) initialize() {
 
 	// Setup user agent
 	if c.config.EnableUserAgentRotation {
-		c.userAgent = GetUserAgentByBrowser(c.config.BrowserProfile)
+		c.userAgent = c.identity.UserAgent
 	}
 
 	// Setup timing
@@ -163,21 +172,68 @@ func (c *AntiDetectClient) initialize() {
 
 	// Setup JA3 fingerprinting
 	if c.config.EnableJA3Fingerprinting {
-		c.ja3Fingerprint = GetRandomJA3Fingerprint(c.config.BrowserProfile)
+		c.ja3Fingerprint = c.identity.JA3
 	}
 
 	// Setup connection pooling
 	if c.config.EnableConnectionPooling {
 		c.connectionPool = NewConnectionPool(100, 90*time.Second)
 		c.connectionPool.SetTLSConfig(c.tlsConfig)
+		c.connectionPool.SetConnectionLimits(c.config.MaxConnsPerHost, c.config.MaxIdleConns)
 		c.transport = c.connectionPool.GetTransport()
 		c.httpClient.Transport = c.transport
 	}
 
+	// Advertise the identity's HTTP/2 SETTINGS on whichever transport ended up in use
+	if c.config.EnableHTTP2Fingerprinting {
+		ApplyHTTP2Profile(c.transport, c.identity.HTTP2)
+	}
+
+	// Resolve through the cache on whichever transport ended up in use
+	if c.config.EnableDNSCache {
+		dial := DialFunc(c.transport.DialContext)
+		if dial == nil {
+			dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+		}
+		c.transport.DialContext = NewDNSCache(c.config.DNSCacheTTL, c.config.DNSCachePin).DialContext(dial)
+	}
+
+	// Decode br/deflate/gzip bodies ourselves since we set Accept-Encoding manually
+	c.httpClient.Transport = NewDecodingRoundTripper(c.httpClient.Transport)
+
 	// Setup request patterns
 	if c.config.EnableRequestPatterns {
 		c.patternExecutor = NewRequestPatternExecutor(c.httpClient, "")
 	}
+
+	// Setup proxy rotation last so it applies to the final transport
+	if c.config.EnableProxyRotation && len(c.config.ProxyList) > 0 {
+		c.proxyRotator = NewProxyRotator(c.config.ProxyList, 3)
+		c.proxyRotator.SetTestURLs(c.config.ProxyTestURLs)
+		c.transport.Proxy = c.proxyForRequest
+		c.rotateProxy()
+
+		if c.config.ProxyHealthInterval > 0 {
+			c.healthChecker = NewProxyHealthChecker(c.proxyRotator, c.config.ProxyHealthInterval)
+			go c.healthChecker.Start()
+		}
+	}
+}
+
+// Close releases background resources held by the client: the proxy health
+// checker, the connection pool cleanup timer and idle connections. It is safe
+// to call more than once.
+func (c *AntiDetectClient) Close() {
+	c.closeOnce.Do(func() {
+		if c.healthChecker != nil {
+			c.healthChecker.Stop()
+		}
+		if c.connectionPool != nil {
+			c.connectionPool.Stop()
+		} else if c.transport != nil {
+			c.transport.CloseIdleConnections()
+		}
+	})
 }
 
 // rotateProxy rotates to the next proxy in the list
@@ -189,11 +245,41 @@ func (c *AntiDetectClient) rotateProxy() {
 	if proxy := c.proxyRotator.GetNextProxy(); proxy != nil {
 		proxyURL, err := url.Parse(proxy.URL)
 		if err == nil {
-			c.transport.Proxy = http.ProxyURL(proxyURL)
+			c.proxyMu.Lock()
+			c.currentProxy = proxyURL
+			c.proxyRequests = 0
+			c.proxySelectedAt = time.Now()
+			c.proxyMu.Unlock()
 		}
 	}
 }
 
+// proxyForRequest is the transport proxy func used while rotation is enabled
+func (c *AntiDetectClient) proxyForRequest(*http.Request) (*url.URL, error) {
+	c.proxyMu.Lock()
+	defer c.proxyMu.Unlock()
+	return c.currentProxy, nil
+}
+
+// countProxyRequest records a request on the current proxy and rotates once
+// the configured request count or interval has been reached
+func (c *AntiDetectClient) countProxyRequest() {
+	c.proxyMu.Lock()
+	c.proxyRequests++
+	due := c.config.ProxyRotateEvery > 0 && c.proxyRequests > c.config.ProxyRotateEvery
+	if c.config.ProxyRotateInterval > 0 && time.Since(c.proxySelectedAt) >= c.config.ProxyRotateInterval {
+		due = true
+	}
+	c.proxyMu.Unlock()
+
+	if due {
+		c.rotateProxy()
+		c.proxyMu.Lock()
+		c.proxyRequests = 1
+		c.proxyMu.Unlock()
+	}
+}
+
 // GetHTTPClient returns the configured HTTP client
 func (c *AntiDetectClient) GetHTTPClient() *http.Client {
 	return c.httpClient
@@ -219,6 +305,13 @@ func (c *AntiDetectClient) ApplyToCollyCollector(collector *colly.Collector) {
 		c.composeHeaders(*r.Headers)
 	})
 
+	// Stick with a proxy for the configured cadence before rotating
+	if c.proxyRotator != nil && (c.config.ProxyRotateEvery > 0 || c.config.ProxyRotateInterval > 0) {
+		collector.OnRequest(func(r *colly.Request) {
+			c.countProxyRequest()
+		})
+	}
+
 	// Apply timing randomization
 	if c.config.EnableTimingRandomization && c.timer != nil {
 		collector.OnRequest(func(r *colly.Request) {
@@ -268,14 +361,14 @@ func (c *AntiDetectClient) ApplyToCollyCollector(collector *colly.Collector) {
 	// Apply retry logic with exponential backoff
 	collector.OnError(func(r *colly.Response, err error) {
 		if r.StatusCode >= 500 || r.StatusCode == 429 {
-			// Rotate proxy if enabled
+			// Rotate proxy if enabled, regardless of the rotation cadence
 			if c.config.EnableProxyRotation {
 				c.rotateProxy()
 			}
 
-			// Rotate user agent
+			// Rotate user agent within the session's browser
 			if c.config.EnableUserAgentRotation {
-				c.userAgent = GetUserAgentByBrowser(c.config.BrowserProfile)
+				c.userAgent = GetUserAgentByBrowser(c.identity.Browser)
 			}
 		}
 	})
@@ -319,7 +412,7 @@ func (c *AntiDetectClient) composeHeaders(h http.Header) {
 	}
 
 	// Viewport-Width hint for Chrome-like profiles
-	if c.config.BrowserProfile == "chrome" || c.config.BrowserProfile == "random" {
+	if engineFamily(c.identity.Browser) == "chrome" {
 		viewportWidths := []string{"1920", "1366", "1536", "1440", "1280"}
 		vwIdx := GetRandomInt(0, len(viewportWidths))
 		if vwIdx >= len(viewportWidths) {
@@ -329,17 +422,28 @@ func (c *AntiDetectClient) composeHeaders(h http.Header) {
 	}
 }
 
-// RotateFingerprint rotates the browser fingerprint
+// withSNI pins the server name sent in the TLS handshake when one is
+// configured. net/http only fills ServerName from the URL host when it is
+// empty, so the connection still goes to the dialed host
+func (c *AntiDetectClient) withSNI(cfg *tls.Config) *tls.Config {
+	if c.config.SNI != "" {
+		cfg.ServerName = c.config.SNI
+	}
+	return cfg
+}
+
+// RotateFingerprint rotates the browser fingerprint without leaving the
+// session's browser, so the rotated parts stay consistent with each other
 func (c *AntiDetectClient) RotateFingerprint() {
 	// Rotate TLS config
 	if c.config.EnableTLSFingerprinting {
-		c.tlsConfig = CreateStealthTLSConfig()
+		c.tlsConfig = c.withSNI(CreateStealthTLSConfigFor(c.identity.TLS))
 		c.transport.TLSClientConfig = c.tlsConfig
 	}
 
 	// Rotate user agent
 	if c.config.EnableUserAgentRotation {
-		c.userAgent = GetUserAgentByBrowser(c.config.BrowserProfile)
+		c.userAgent = GetUserAgentByBrowser(c.identity.Browser)
 	}
 
 	// Rotate JA3 fingerprint
@@ -382,12 +486,32 @@ func (c *AntiDetectClient) SetUserAgent(userAgent string) {
 	}
 }
 
+// UserAgent returns the user agent currently presented
+func (c *AntiDetectClient) UserAgent() string {
+	return c.userAgent.UserAgent
+}
+
+// Identity returns the session's browser identity with the user agent and
+// JA3 fingerprint currently in use
+func (c *AntiDetectClient) Identity() BrowserIdentity {
+	id := c.identity
+	id.UserAgent = c.userAgent
+	id.JA3 = c.ja3Fingerprint
+	return id
+}
+
+// ValidateFingerprint reports whether the user agent, TLS, JA3 and HTTP/2
+// fingerprints in use still describe the same browser
+func (c *AntiDetectClient) ValidateFingerprint() error {
+	return c.Identity().Validate()
+}
+
 // GetRandomInt returns a random integer between min and max (exclusive)
 func GetRandomInt(min, max int) int {
 	if min >= max {
 		return min
 	}
-	return min + int(time.Now().UnixNano())%(max-min)
+	return min + randIntn(max-min)
 }
 
 // EnableDebugMode enables debug mode for the HTTP client
@@ -401,6 +525,7 @@ func (c *AntiDetectClient) GetStats() map[string]interface{} {
 	stats := make(map[string]interface{})
 
 	stats["browser_profile"] = c.config.BrowserProfile
+	stats["browser_identity"] = c.identity.Browser
 	stats["tls_fingerprinting"] = c.config.EnableTLSFingerprinting
 	stats["http2_fingerprinting"] = c.config.EnableHTTP2Fingerprinting
 	stats["user_agent_rotation"] = c.config.EnableUserAgentRotation
@@ -449,11 +574,11 @@ func (c *AntiDetectClient) SimulateBrowserBehavior(baseURL string) error {
 // RotateJA3Fingerprint rotates the JA3 fingerprint
 func (c *AntiDetectClient) RotateJA3Fingerprint() {
 	if c.config.EnableJA3Fingerprinting {
-		c.ja3Fingerprint = GetRandomJA3Fingerprint(c.config.BrowserProfile)
+		c.ja3Fingerprint = GetRandomJA3Fingerprint(engineFamily(c.identity.Browser))
 
 		// Update TLS config to match new fingerprint
 		if c.config.EnableTLSFingerprinting {
-			c.tlsConfig = CreateStealthTLSConfig()
+			c.tlsConfig = c.withSNI(CreateStealthTLSConfigFor(c.identity.TLS))
 			c.transport.TLSClientConfig = c.tlsConfig
 		}
 	}
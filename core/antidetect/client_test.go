@@ -0,0 +1,103 @@
+package antidetect
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAntiDetectClientCloseReleasesGoroutines(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	for i := 0; i < 50; i++ {
+		cfg := DefaultAntiDetectConfig()
+		cfg.EnableTimingRandomization = false
+		cfg.EnableProxyRotation = true
+		cfg.ProxyList = []string{"http://127.0.0.1:1"}
+		cfg.ProxyHealthInterval = time.Hour
+		client := NewAntiDetectClient(cfg)
+		client.Close()
+		client.Close()
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > baseline+2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > baseline+2 {
+		t.Fatalf("goroutines grew from %d to %d after closing clients", baseline, n)
+	}
+}
+
+func TestAntiDetectClientSendsConfiguredSNI(t *testing.T) {
+	var mu sync.Mutex
+	var names []string
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		mu.Lock()
+		names = append(names, hello.ServerName)
+		mu.Unlock()
+		return nil, nil
+	}}
+	srv.StartTLS()
+	defer srv.Close()
+
+	cfg := DefaultAntiDetectConfig()
+	cfg.EnableTimingRandomization = false
+	cfg.EnableRetryLogic = false
+	cfg.SNI = "front.example.com"
+	client := NewAntiDetectClient(cfg)
+	defer client.Close()
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		req.Close = true
+		resp, err := client.GetHTTPClient().Do(req)
+		if err != nil {
+			t.Fatalf("request to %s failed: %v", srv.URL, err)
+		}
+		resp.Body.Close()
+		// Fresh TLS configs from rotation must keep the override
+		client.RotateFingerprint()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(names) != 2 {
+		t.Fatalf("got %d handshakes, want 2", len(names))
+	}
+	for _, name := range names {
+		if name != cfg.SNI {
+			t.Fatalf("SNI = %q for dialed host %s, want %q", name, srv.Listener.Addr(), cfg.SNI)
+		}
+	}
+}
+
+func TestAntiDetectClientRotatesEveryNRequests(t *testing.T) {
+	cfg := DefaultAntiDetectConfig()
+	cfg.EnableTimingRandomization = false
+	cfg.EnableProxyRotation = true
+	cfg.ProxyList = []string{"http://127.0.0.1:8001", "http://127.0.0.1:8002", "http://127.0.0.1:8003"}
+	cfg.ProxyRotateEvery = 2
+	client := NewAntiDetectClient(cfg)
+	defer client.Close()
+
+	var used []string
+	for i := 0; i < 6; i++ {
+		client.countProxyRequest()
+		proxy, _ := client.proxyForRequest(nil)
+		used = append(used, proxy.Port())
+	}
+	for i := 0; i < len(used); i += 2 {
+		if used[i] != used[i+1] {
+			t.Fatalf("requests %d and %d should share a proxy: %v", i, i+1, used)
+		}
+		if i > 0 && used[i] == used[i-1] {
+			t.Fatalf("request %d should rotate to a new proxy: %v", i, used)
+		}
+	}
+}
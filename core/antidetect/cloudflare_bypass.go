@@ -276,10 +276,10 @@ func GenerateFakeCFRay() string {
 	
 	ray := ""
 	for i := 0; i < 8; i++ {
-		ray += string(hexChars[time.Now().UnixNano()%int64(len(hexChars))])
+		ray += string(hexChars[randIntn(len(hexChars))])
 	}
 	
-	airport := airports[time.Now().UnixNano()%int64(len(airports))]
+	airport := airports[randIntn(len(airports))]
 	
 	return ray + "-" + airport
 }
@@ -16,6 +16,8 @@ type ConnectionPool struct {
 	maxConns     int
 	maxIdleTime  time.Duration
 	cleanupTimer *time.Timer
+	timerMutex   sync.Mutex
+	stopped      bool
 }
 
 // ConnectionInfo tracks information about a connection
@@ -82,6 +84,20 @@ func (cp *ConnectionPool) SetTLSConfig(tlsConfig *tls.Config) {
 	cp.transport.TLSClientConfig = tlsConfig
 }
 
+// SetConnectionLimits caps per-host and idle connections on the transport;
+// values of 0 keep the browser-like defaults
+func (cp *ConnectionPool) SetConnectionLimits(maxConnsPerHost, maxIdleConns int) {
+	if maxConnsPerHost > 0 {
+		cp.transport.MaxConnsPerHost = maxConnsPerHost
+		if cp.transport.MaxIdleConnsPerHost > maxConnsPerHost {
+			cp.transport.MaxIdleConnsPerHost = maxConnsPerHost
+		}
+	}
+	if maxIdleConns > 0 {
+		cp.transport.MaxIdleConns = maxIdleConns
+	}
+}
+
 // TrackConnection tracks a new connection
 func (cp *ConnectionPool) TrackConnection(host, port, protocol string) {
 	cp.mutex.Lock()
@@ -160,17 +176,26 @@ func (cp *ConnectionPool) CleanupIdleConnections() {
 
 // startCleanup starts the periodic cleanup routine
 func (cp *ConnectionPool) startCleanup() {
+	cp.timerMutex.Lock()
+	defer cp.timerMutex.Unlock()
+	if cp.stopped {
+		return
+	}
 	cp.cleanupTimer = time.AfterFunc(cp.maxIdleTime/2, func() {
 		cp.CleanupIdleConnections()
 		cp.startCleanup() // Reschedule
 	})
 }
 
-// Stop stops the connection pool and cleanup routine
+// Stop stops the connection pool and cleanup routine; a cleanup already
+// running when Stop is called will not reschedule itself
 func (cp *ConnectionPool) Stop() {
+	cp.timerMutex.Lock()
+	cp.stopped = true
 	if cp.cleanupTimer != nil {
 		cp.cleanupTimer.Stop()
 	}
+	cp.timerMutex.Unlock()
 	
 	// Close all idle connections
 	cp.transport.CloseIdleConnections()
@@ -0,0 +1,40 @@
+package antidetect
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConnectionPoolStopHaltsCleanup(t *testing.T) {
+	pool := NewConnectionPool(10, 20*time.Millisecond)
+
+	// Let the cleanup timer fire and reschedule a few times before stopping.
+	time.Sleep(50 * time.Millisecond)
+	pool.Stop()
+	// A cleanup that had already fired may still be finishing.
+	time.Sleep(20 * time.Millisecond)
+
+	pool.TrackConnection("example.com", "443", "https")
+	pool.MarkConnectionIdle("example.com", "443")
+	pool.mutex.Lock()
+	pool.connections["example.com:443"].LastUsed = time.Now().Add(-time.Hour)
+	pool.mutex.Unlock()
+
+	time.Sleep(100 * time.Millisecond)
+	if got := pool.GetConnectionStats()["total_connections"]; got != 1 {
+		t.Fatalf("cleanup ran after Stop: total_connections = %v", got)
+	}
+}
+
+func TestAntiDetectClientCloseStopsConnectionPool(t *testing.T) {
+	cfg := DefaultAntiDetectConfig()
+	cfg.EnableTimingRandomization = false
+	client := NewAntiDetectClient(cfg)
+	client.Close()
+
+	client.connectionPool.timerMutex.Lock()
+	defer client.connectionPool.timerMutex.Unlock()
+	if !client.connectionPool.stopped {
+		t.Fatal("connection pool was not stopped by Close")
+	}
+}
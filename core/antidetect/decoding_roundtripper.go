@@ -0,0 +1,112 @@
+package antidetect
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// DecodingRoundTripper decompresses gzip, deflate and br response bodies.
+// Because we send our own Accept-Encoding header, net/http leaves bodies
+// compressed, so decoding has to happen here before extractors see them.
+type DecodingRoundTripper struct {
+	base http.RoundTripper
+}
+
+// NewDecodingRoundTripper wraps base with Content-Encoding decoding
+func NewDecodingRoundTripper(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &DecodingRoundTripper{base: base}
+}
+
+func (rt *DecodingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := rt.base.RoundTrip(req)
+	if err != nil || resp == nil || resp.Body == nil || resp.Uncompressed {
+		return resp, err
+	}
+
+	encoding := strings.TrimSpace(resp.Header.Get("Content-Encoding"))
+	if encoding == "" || req.Method == http.MethodHead {
+		return resp, nil
+	}
+
+	// Encodings are listed in the order they were applied, so undo them in reverse
+	codings := strings.Split(encoding, ",")
+	body := resp.Body
+	var reader io.Reader = body
+	for i := len(codings) - 1; i >= 0; i-- {
+		decoded, ok := decodeContent(strings.ToLower(strings.TrimSpace(codings[i])), reader)
+		if !ok {
+			// Unknown coding: hand the body over untouched
+			return resp, nil
+		}
+		reader = decoded
+	}
+
+	resp.Body = &decodedBody{Reader: reader, closer: body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+func decodeContent(coding string, r io.Reader) (io.Reader, bool) {
+	switch coding {
+	case "identity":
+		return r, true
+	case "gzip", "x-gzip":
+		return &lazyReader{open: func() (io.Reader, error) { return gzip.NewReader(r) }}, true
+	case "br":
+		return brotli.NewReader(r), true
+	case "deflate":
+		return &lazyReader{open: func() (io.Reader, error) { return newDeflateReader(r) }}, true
+	}
+	return nil, false
+}
+
+// newDeflateReader handles both zlib-wrapped deflate (per the RFC) and the
+// raw deflate streams some servers send instead
+func newDeflateReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// lazyReader defers constructing a decoder until the body is first read, so
+// header parsing errors surface from Read rather than from RoundTrip
+type lazyReader struct {
+	open   func() (io.Reader, error)
+	reader io.Reader
+	err    error
+}
+
+func (l *lazyReader) Read(p []byte) (int, error) {
+	if l.reader == nil && l.err == nil {
+		l.reader, l.err = l.open()
+	}
+	if l.err != nil {
+		return 0, l.err
+	}
+	return l.reader.Read(p)
+}
+
+type decodedBody struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (b *decodedBody) Close() error {
+	return b.closer.Close()
+}
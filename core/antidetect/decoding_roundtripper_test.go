@@ -0,0 +1,81 @@
+package antidetect
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gocolly/colly/v2"
+)
+
+const encodedFixture = `<html><body><a href="/api/v1/users">users</a><script src="/static/app.js"></script></body></html>`
+
+func encodeFixture(t *testing.T, coding string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	switch coding {
+	case "br":
+		w := brotli.NewWriter(&buf)
+		w.Write([]byte(encodedFixture))
+		w.Close()
+	case "deflate":
+		w := zlib.NewWriter(&buf)
+		w.Write([]byte(encodedFixture))
+		w.Close()
+	case "raw-deflate":
+		w, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+		w.Write([]byte(encodedFixture))
+		w.Close()
+	}
+	return buf.Bytes()
+}
+
+func TestDecodingRoundTripperReachesCollector(t *testing.T) {
+	for _, coding := range []string{"br", "deflate", "raw-deflate"} {
+		t.Run(coding, func(t *testing.T) {
+			body := encodeFixture(t, coding)
+			header := coding
+			if coding == "raw-deflate" {
+				header = "deflate"
+			}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				w.Header().Set("Content-Encoding", header)
+				w.Write(body)
+			}))
+			defer srv.Close()
+
+			cfg := DefaultAntiDetectConfig()
+			cfg.EnableTimingRandomization = false
+			cfg.EnableRequestPatterns = false
+			client := NewAntiDetectClient(cfg)
+			defer client.Close()
+
+			collector := colly.NewCollector()
+			client.ApplyToCollyCollector(collector)
+
+			var got string
+			var links []string
+			collector.OnResponse(func(r *colly.Response) {
+				got = string(r.Body)
+			})
+			collector.OnHTML("a[href]", func(e *colly.HTMLElement) {
+				links = append(links, e.Attr("href"))
+			})
+			if err := collector.Visit(srv.URL); err != nil {
+				t.Fatalf("visit: %v", err)
+			}
+
+			if got != encodedFixture {
+				t.Fatalf("body was not decoded: %q", got)
+			}
+			if len(links) != 1 || links[0] != "/api/v1/users" {
+				t.Fatalf("unexpected links %v", links)
+			}
+		})
+	}
+}
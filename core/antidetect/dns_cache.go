@@ -0,0 +1,130 @@
+package antidetect
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// dnsLookupTimeout bounds a shared lookup, which no single caller's context
+// may cancel
+const dnsLookupTimeout = 10 * time.Second
+
+// DialFunc matches http.Transport.DialContext
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// DNSCache resolves each host once and reuses the answer until its TTL runs
+// out, so large single-domain crawls do not hammer the resolver. Dials rotate
+// over the cached addresses unless pinning is enabled, in which case the
+// first address is preferred and the rest are only tried when it fails
+type DNSCache struct {
+	ttl     time.Duration
+	pin     bool
+	lookup  func(ctx context.Context, host string) ([]string, error)
+	mu      sync.Mutex
+	entries map[string]*dnsCacheEntry
+}
+
+type dnsCacheEntry struct {
+	ready   chan struct{}
+	addrs   []string
+	err     error
+	expires time.Time
+	next    int
+}
+
+// NewDNSCache returns a cache whose answers live for ttl; a ttl of 0 keeps
+// them for the lifetime of the cache
+func NewDNSCache(ttl time.Duration, pin bool) *DNSCache {
+	return &DNSCache{
+		ttl:     ttl,
+		pin:     pin,
+		lookup:  net.DefaultResolver.LookupHost,
+		entries: make(map[string]*dnsCacheEntry),
+	}
+}
+
+// Lookup returns the cached addresses of host, resolving it when missing or
+// expired. Concurrent lookups of the same host share one query and failures
+// are not cached. The query runs on its own context, so a caller that gives
+// up early does not fail the others waiting on it
+func (d *DNSCache) Lookup(ctx context.Context, host string) ([]string, error) {
+	d.mu.Lock()
+	entry, ok := d.entries[host]
+	if ok {
+		select {
+		case <-entry.ready:
+			if entry.err != nil || (d.ttl > 0 && time.Now().After(entry.expires)) {
+				ok = false
+			}
+		default:
+		}
+	}
+	if !ok {
+		entry = &dnsCacheEntry{ready: make(chan struct{})}
+		d.entries[host] = entry
+		go func() {
+			lookupCtx, cancel := context.WithTimeout(context.Background(), dnsLookupTimeout)
+			defer cancel()
+			entry.addrs, entry.err = d.lookup(lookupCtx, host)
+			entry.expires = time.Now().Add(d.ttl)
+			close(entry.ready)
+		}()
+	}
+	d.mu.Unlock()
+
+	select {
+	case <-entry.ready:
+		return entry.addrs, entry.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// order returns the addresses to try for host, starting at the next one in
+// the rotation unless pinned
+func (d *DNSCache) order(host string, addrs []string) []string {
+	if d.pin || len(addrs) < 2 {
+		return addrs
+	}
+	d.mu.Lock()
+	start := 0
+	if entry, ok := d.entries[host]; ok {
+		start = entry.next % len(addrs)
+		entry.next++
+	}
+	d.mu.Unlock()
+	return append(append([]string{}, addrs[start:]...), addrs[:start]...)
+}
+
+// DialContext wraps dial so host names are resolved through the cache.
+// Addresses that are already IPs are dialed as they are
+func (d *DNSCache) DialContext(dial DialFunc) DialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dial(ctx, network, addr)
+		}
+		addrs, err := d.Lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		var lastErr error
+		for _, ip := range d.order(host, addrs) {
+			conn, err := dial(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		if lastErr == nil {
+			lastErr = &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+		}
+		return nil, lastErr
+	}
+}
@@ -0,0 +1,138 @@
+package antidetect
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestDNSCacheResolvesOnce(t *testing.T) {
+	lookups := 0
+	cache := NewDNSCache(0, false)
+	cache.lookup = func(ctx context.Context, host string) ([]string, error) {
+		lookups++
+		return []string{"10.0.0.1", "10.0.0.2"}, nil
+	}
+
+	var dialed []string
+	dial := cache.DialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		client, server := net.Pipe()
+		server.Close()
+		return client, nil
+	})
+	for i := 0; i < 3; i++ {
+		if _, err := dial(context.Background(), "tcp", "example.com:443"); err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+	}
+	if lookups != 1 {
+		t.Fatalf("expected one lookup, got %d", lookups)
+	}
+	want := []string{"10.0.0.1:443", "10.0.0.2:443", "10.0.0.1:443"}
+	for i := range want {
+		if dialed[i] != want[i] {
+			t.Fatalf("dial %d went to %s, want %s", i, dialed[i], want[i])
+		}
+	}
+
+	// IPs bypass the cache
+	if _, err := dial(context.Background(), "tcp", "192.0.2.1:80"); err != nil || lookups != 1 {
+		t.Fatalf("IP dial looked up a host: %v", err)
+	}
+}
+
+func TestDNSCachePinFallsBack(t *testing.T) {
+	cache := NewDNSCache(0, true)
+	cache.lookup = func(ctx context.Context, host string) ([]string, error) {
+		return []string{"10.0.0.1", "10.0.0.2"}, nil
+	}
+
+	var dialed []string
+	dial := cache.DialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		if addr == "10.0.0.1:80" && len(dialed) > 2 {
+			return nil, errors.New("connection refused")
+		}
+		client, server := net.Pipe()
+		server.Close()
+		return client, nil
+	})
+	for i := 0; i < 3; i++ {
+		if _, err := dial(context.Background(), "tcp", "example.com:80"); err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+	}
+	want := []string{"10.0.0.1:80", "10.0.0.1:80", "10.0.0.1:80", "10.0.0.2:80"}
+	if len(dialed) != len(want) {
+		t.Fatalf("dialed %v, want %v", dialed, want)
+	}
+	for i := range want {
+		if dialed[i] != want[i] {
+			t.Fatalf("dialed %v, want %v", dialed, want)
+		}
+	}
+}
+
+func TestDNSCacheExpiresAndSkipsFailures(t *testing.T) {
+	lookups := 0
+	cache := NewDNSCache(20*time.Millisecond, false)
+	cache.lookup = func(ctx context.Context, host string) ([]string, error) {
+		lookups++
+		if lookups == 1 {
+			return nil, &net.DNSError{Err: "server misbehaving", Name: host, IsTemporary: true}
+		}
+		return []string{"10.0.0.1"}, nil
+	}
+
+	if _, err := cache.Lookup(context.Background(), "example.com"); err == nil {
+		t.Fatal("expected the first lookup to fail")
+	}
+	cache.Lookup(context.Background(), "example.com")
+	cache.Lookup(context.Background(), "example.com")
+	if lookups != 2 {
+		t.Fatalf("failure should not be cached, got %d lookups", lookups)
+	}
+	time.Sleep(30 * time.Millisecond)
+	cache.Lookup(context.Background(), "example.com")
+	if lookups != 3 {
+		t.Fatalf("expired answer should be refreshed, got %d lookups", lookups)
+	}
+}
+
+func TestDNSCacheSharedLookupOutlivesCaller(t *testing.T) {
+	release := make(chan struct{})
+	cache := NewDNSCache(0, false)
+	cache.lookup = func(ctx context.Context, host string) ([]string, error) {
+		select {
+		case <-release:
+			return []string{"10.0.0.1"}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	first, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := cache.Lookup(first, "example.com")
+		firstErr <- err
+	}()
+	secondAddrs := make(chan []string, 1)
+	go func() {
+		addrs, _ := cache.Lookup(context.Background(), "example.com")
+		secondAddrs <- addrs
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled caller got %v", err)
+	}
+	close(release)
+	if addrs := <-secondAddrs; len(addrs) != 1 || addrs[0] != "10.0.0.1" {
+		t.Fatalf("waiting caller got %v, want the shared answer", addrs)
+	}
+}
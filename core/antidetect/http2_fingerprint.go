@@ -2,9 +2,7 @@ package antidetect
 
 import (
 	"crypto/tls"
-	"math/rand"
 	"net/http"
-	"time"
 
 	"golang.org/x/net/http2"
 )
@@ -117,8 +115,7 @@ func GetHTTP2Profiles() []BrowserHTTP2Profile {
 // GetRandomHTTP2Profile returns a random HTTP/2 profile
 func GetRandomHTTP2Profile() BrowserHTTP2Profile {
 	profiles := GetHTTP2Profiles()
-	rand.Seed(time.Now().UnixNano())
-	return profiles[rand.Intn(len(profiles))]
+	return profiles[randIntn(len(profiles))]
 }
 
 // CreateHTTP2Transport creates an HTTP/2 transport with browser-like settings
@@ -126,29 +123,47 @@ func CreateHTTP2Transport(profile BrowserHTTP2Profile, tlsConfig *tls.Config) *h
 	// Create HTTP/2 transport
 	transport := &http.Transport{
 		TLSClientConfig: tlsConfig,
-		// Force HTTP/2
-		ForceAttemptHTTP2: true,
 	}
+	ApplyHTTP2Profile(transport, profile)
+	return transport
+}
 
-	// Configure HTTP/2 transport
-	// Note: HTTP/2 transport configuration is handled internally by Go's http package
+// HTTP2ConfigFor maps a profile onto the HTTP/2 settings net/http exposes.
+// The client sends them as SETTINGS_HEADER_TABLE_SIZE, _INITIAL_WINDOW_SIZE
+// and _MAX_FRAME_SIZE, followed by the connection WINDOW_UPDATE. Go always
+// advertises ENABLE_PUSH=0 and picks the settings order itself, so profiles
+// that enable push or reorder settings are only approximated
+func HTTP2ConfigFor(profile BrowserHTTP2Profile) *http.HTTP2Config {
+	settings := profile.Settings
+	return &http.HTTP2Config{
+		MaxDecoderHeaderTableSize:     int(settings.HeaderTableSize),
+		MaxReadFrameSize:              int(settings.MaxFrameSize),
+		MaxReceiveBufferPerStream:     int(settings.InitialWindowSize),
+		MaxReceiveBufferPerConnection: int(profile.WindowUpdateIncrement),
+	}
+}
 
-	return transport
+// ApplyHTTP2Profile makes transport speak HTTP/2 with the SETTINGS of profile
+func ApplyHTTP2Profile(transport *http.Transport, profile BrowserHTTP2Profile) {
+	transport.ForceAttemptHTTP2 = true
+	transport.HTTP2 = HTTP2ConfigFor(profile)
+	if profile.Settings.MaxHeaderListSize > 0 {
+		transport.MaxResponseHeaderBytes = int64(profile.Settings.MaxHeaderListSize)
+	}
 }
 
 // RandomizeHTTP2Settings creates randomized HTTP/2 settings
 func RandomizeHTTP2Settings(baseProfile BrowserHTTP2Profile) HTTP2Settings {
-	rand.Seed(time.Now().UnixNano())
 
 	settings := baseProfile.Settings
 
 	// Add some randomization to settings
-	settings.HeaderTableSize += uint32(rand.Intn(8192))
-	settings.InitialWindowSize += uint32(rand.Intn(1048576))
-	settings.MaxFrameSize += uint32(rand.Intn(1024))
+	settings.HeaderTableSize += uint32(randIntn(8192))
+	settings.InitialWindowSize += uint32(randIntn(1048576))
+	settings.MaxFrameSize += uint32(randIntn(1024))
 
 	// Randomly enable/disable push
-	if rand.Intn(2) == 0 {
+	if randIntn(2) == 0 {
 		settings.EnablePush = !settings.EnablePush
 	}
 
@@ -261,7 +276,7 @@ func CreateRandomHTTP2Config() *http2.Transport {
 
 	return &http2.Transport{
 		// Randomize settings
-		DisableCompression: rand.Intn(2) == 0,
+		DisableCompression: randIntn(2) == 0,
 		AllowHTTP:          false,
 		// Add more randomization as needed
 	}
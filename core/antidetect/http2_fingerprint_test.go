@@ -0,0 +1,116 @@
+package antidetect
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// h2Fingerprint is what an HTTP/2 fingerprinting service sees of a client:
+// its SETTINGS and the connection WINDOW_UPDATE that follows them
+type h2Fingerprint struct {
+	settings     map[http2.SettingID]uint32
+	windowUpdate uint32
+}
+
+// startH2EchoServer accepts one TLS connection negotiating h2 and records the
+// client's opening frames
+func startH2EchoServer(t *testing.T) (string, <-chan h2Fingerprint) {
+	cert := httptest.NewTLSServer(http.NotFoundHandler())
+	tlsCert := cert.TLS.Certificates[0]
+	cert.Close()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{tlsCert}, NextProtos: []string{"h2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	result := make(chan h2Fingerprint, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		preface := make([]byte, len(http2.ClientPreface))
+		if _, err := io.ReadFull(conn, preface); err != nil {
+			return
+		}
+		fr := http2.NewFramer(conn, conn)
+		fp := h2Fingerprint{settings: map[http2.SettingID]uint32{}}
+		for fp.windowUpdate == 0 {
+			frame, err := fr.ReadFrame()
+			if err != nil {
+				return
+			}
+			switch f := frame.(type) {
+			case *http2.SettingsFrame:
+				f.ForeachSetting(func(s http2.Setting) error {
+					fp.settings[s.ID] = s.Val
+					return nil
+				})
+			case *http2.WindowUpdateFrame:
+				if f.StreamID == 0 {
+					fp.windowUpdate = f.Increment
+				}
+			}
+		}
+		result <- fp
+	}()
+	return "https://" + ln.Addr().(*net.TCPAddr).String() + "/", result
+}
+
+func TestClientAdvertisesHTTP2Profile(t *testing.T) {
+	for _, browser := range []string{"chrome", "firefox", "safari"} {
+		t.Run(browser, func(t *testing.T) {
+			url, result := startH2EchoServer(t)
+
+			cfg := DefaultAntiDetectConfig()
+			cfg.BrowserProfile = browser
+			cfg.EnableTimingRandomization = false
+			cfg.EnableRetryLogic = false
+			client := NewAntiDetectClient(cfg)
+			defer client.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			go func() {
+				if resp, err := client.GetHTTPClient().Do(req); err == nil {
+					resp.Body.Close()
+				}
+			}()
+
+			var fp h2Fingerprint
+			select {
+			case fp = <-result:
+			case <-ctx.Done():
+				t.Fatal("no HTTP/2 connection was made")
+			}
+
+			profile := client.Identity().HTTP2
+			if got := fp.settings[http2.SettingInitialWindowSize]; got != profile.Settings.InitialWindowSize {
+				t.Errorf("INITIAL_WINDOW_SIZE = %d, want %d", got, profile.Settings.InitialWindowSize)
+			}
+			if got := fp.settings[http2.SettingMaxFrameSize]; got != profile.Settings.MaxFrameSize {
+				t.Errorf("MAX_FRAME_SIZE = %d, want %d", got, profile.Settings.MaxFrameSize)
+			}
+			if got, ok := fp.settings[http2.SettingHeaderTableSize]; profile.Settings.HeaderTableSize != 4096 && got != profile.Settings.HeaderTableSize {
+				t.Errorf("HEADER_TABLE_SIZE = %d (sent %v), want %d", got, ok, profile.Settings.HeaderTableSize)
+			}
+			if fp.windowUpdate != profile.WindowUpdateIncrement {
+				t.Errorf("WINDOW_UPDATE = %d, want %d", fp.windowUpdate, profile.WindowUpdateIncrement)
+			}
+		})
+	}
+}
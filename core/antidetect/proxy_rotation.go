@@ -2,7 +2,6 @@ package antidetect
 
 import (
 	"fmt"
-	"math/rand"
 	"net/http"
 	"net/url"
 	"sync"
@@ -38,6 +37,14 @@ type ProxyRotator struct {
 	maxFailures int
 	mutex       sync.RWMutex
 	client      *http.Client
+	testURLs    []string
+}
+
+// DefaultProxyTestURLs are probed when no custom test URLs are configured
+var DefaultProxyTestURLs = []string{
+	"http://httpbin.org/ip",
+	"https://api.ipify.org",
+	"http://icanhazip.com",
 }
 
 // NewProxyRotator creates a new proxy rotator
@@ -46,6 +53,7 @@ func NewProxyRotator(proxyURLs []string, maxFailures int) *ProxyRotator {
 		proxies:     make([]*ProxyInfo, 0, len(proxyURLs)),
 		maxFailures: maxFailures,
 		client:      &http.Client{Timeout: 30 * time.Second},
+		testURLs:    DefaultProxyTestURLs,
 	}
 
 	for _, proxyURL := range proxyURLs {
@@ -142,8 +150,7 @@ func (pr *ProxyRotator) GetRandomProxy() *ProxyInfo {
 		return nil
 	}
 
-	rand.Seed(time.Now().UnixNano())
-	return activeProxies[rand.Intn(len(activeProxies))]
+	return activeProxies[randIntn(len(activeProxies))]
 }
 
 // MarkProxyFailed marks a proxy as failed
@@ -200,12 +207,9 @@ func (pr *ProxyRotator) TestProxy(proxy *ProxyInfo) bool {
 		Timeout:   proxy.Timeout,
 	}
 
-	// Test with a simple HTTP request
-	testURLs := []string{
-		"http://httpbin.org/ip",
-		"https://api.ipify.org",
-		"http://icanhazip.com",
-	}
+	pr.mutex.RLock()
+	testURLs := pr.testURLs
+	pr.mutex.RUnlock()
 
 	for _, testURL := range testURLs {
 		resp, err := client.Get(testURL)
@@ -221,22 +225,72 @@ func (pr *ProxyRotator) TestProxy(proxy *ProxyInfo) bool {
 	return false
 }
 
-// TestAllProxies tests all proxies and updates their status
-func (pr *ProxyRotator) TestAllProxies() {
+// SetTestURLs overrides the URLs used to probe proxies
+func (pr *ProxyRotator) SetTestURLs(urls []string) {
+	if len(urls) == 0 {
+		return
+	}
 	pr.mutex.Lock()
 	defer pr.mutex.Unlock()
 
-	for _, proxy := range pr.proxies {
-		if pr.TestProxy(proxy) {
+	pr.testURLs = append([]string(nil), urls...)
+}
+
+// TestAllProxies tests all proxies concurrently, updates their status and
+// returns how many passed and failed this round
+func (pr *ProxyRotator) TestAllProxies() (working, dead int) {
+	pr.mutex.RLock()
+	proxies := make([]*ProxyInfo, len(pr.proxies))
+	copy(proxies, pr.proxies)
+	pr.mutex.RUnlock()
+
+	// Probe without holding the lock so rotation is not blocked by slow proxies
+	results := make([]bool, len(proxies))
+	var wg sync.WaitGroup
+	for i, proxy := range proxies {
+		wg.Add(1)
+		go func(i int, proxy *ProxyInfo) {
+			defer wg.Done()
+			results[i] = pr.TestProxy(proxy)
+		}(i, proxy)
+	}
+	wg.Wait()
+
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+
+	for i, proxy := range proxies {
+		if results[i] {
 			proxy.Active = true
 			proxy.Failures = 0
+			working++
 		} else {
 			proxy.Failures++
 			if proxy.Failures >= pr.maxFailures {
 				proxy.Active = false
 			}
+			dead++
+		}
+	}
+	return working, dead
+}
+
+// RemoveInactiveProxies drops proxies that are no longer active and returns the survivors
+func (pr *ProxyRotator) RemoveInactiveProxies() []string {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+
+	kept := pr.proxies[:0]
+	urls := make([]string, 0, len(pr.proxies))
+	for _, proxy := range pr.proxies {
+		if proxy.Active && proxy.Failures == 0 {
+			kept = append(kept, proxy)
+			urls = append(urls, proxy.URL)
 		}
 	}
+	pr.proxies = kept
+	pr.currentIdx = 0
+	return urls
 }
 
 // GetActiveProxyCount returns the number of active proxies
@@ -333,7 +387,8 @@ func (pc *ProxyChain) GetNextInChain() *ProxyInfo {
 type ProxyHealthChecker struct {
 	rotator  *ProxyRotator
 	interval time.Duration
-	stopChan chan bool
+	stopChan chan struct{}
+	stopOnce sync.Once
 }
 
 // NewProxyHealthChecker creates a new proxy health checker
@@ -341,7 +396,7 @@ func NewProxyHealthChecker(rotator *ProxyRotator, interval time.Duration) *Proxy
 	return &ProxyHealthChecker{
 		rotator:  rotator,
 		interval: interval,
-		stopChan: make(chan bool),
+		stopChan: make(chan struct{}),
 	}
 }
 
@@ -360,9 +415,11 @@ func (phc *ProxyHealthChecker) Start() {
 	}
 }
 
-// Stop stops the health checker
+// Stop stops the health checker; it is safe to call more than once
 func (phc *ProxyHealthChecker) Stop() {
-	phc.stopChan <- true
+	phc.stopOnce.Do(func() {
+		close(phc.stopChan)
+	})
 }
 
 // GetFreeProxies returns a list of free proxy URLs for testing
@@ -0,0 +1,40 @@
+package antidetect
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterSharedAcrossGoroutines(t *testing.T) {
+	limiter := NewRateLimiter(1, 10*time.Millisecond)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := limiter.WaitContext(context.Background()); err != nil {
+				t.Errorf("WaitContext: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	// The first token is free, the other 19 arrive 10ms apart
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond {
+		t.Fatalf("20 requests took %s, want at least 190ms", elapsed)
+	}
+}
+
+func TestRateLimiterWaitContextCancels(t *testing.T) {
+	limiter := NewRateLimiter(1, time.Hour)
+	if !limiter.Allow() {
+		t.Fatal("a fresh limiter should allow one request")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.WaitContext(ctx); err == nil {
+		t.Fatal("WaitContext returned without a token or an error")
+	}
+}
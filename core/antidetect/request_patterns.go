@@ -1,7 +1,6 @@
 package antidetect
 
 import (
-	"math/rand"
 	"net/http"
 	"strings"
 	"time"
@@ -165,7 +164,7 @@ func (rpe *RequestPatternExecutor) ExecutePattern(patternType string, patternInd
 		// Apply pattern timing
 		delay := pattern.Timing.BaseDelay
 		if pattern.Timing.RandomJitter > 0 {
-			jitter := time.Duration(rand.Int63n(int64(pattern.Timing.RandomJitter)))
+			jitter := time.Duration(randInt63n(int64(pattern.Timing.RandomJitter)))
 			delay += jitter
 		}
 		time.Sleep(delay)
@@ -221,8 +220,7 @@ func (rpe *RequestPatternExecutor) GetRandomPattern(patternType string) *Request
 		return nil
 	}
 
-	rand.Seed(time.Now().UnixNano())
-	return &patterns[rand.Intn(len(patterns))]
+	return &patterns[randIntn(len(patterns))]
 }
 
 // AddCustomPattern adds a custom request pattern
@@ -345,7 +343,7 @@ func (bs *BehaviorSimulator) SimulatePageVisit() error {
 	bs.simulateScrolling()
 
 	// Possibly execute API calls
-	if rand.Float64() < 0.3 { // 30% chance of API interaction
+	if randFloat64() < 0.3 { // 30% chance of API interaction
 		err = bs.executor.ExecutePattern("api_calls", 0)
 		if err != nil {
 			return err
@@ -357,7 +355,7 @@ func (bs *BehaviorSimulator) SimulatePageVisit() error {
 
 // simulateScrolling simulates realistic scrolling behavior
 func (bs *BehaviorSimulator) simulateScrolling() {
-	scrollCount := 1 + rand.Intn(5) // 1-5 scroll actions
+	scrollCount := 1 + randIntn(5) // 1-5 scroll actions
 	
 	for i := 0; i < scrollCount; i++ {
 		time.Sleep(bs.userProfile.ScrollBehavior.ScrollSpeed)
@@ -377,7 +375,7 @@ func (bs *BehaviorSimulator) SimulateBrowsingSession(pageCount int) error {
 
 		// Simulate navigation delay between pages
 		navigationDelay := bs.userProfile.ClickFrequency
-		if rand.Float64() < 0.2 { // 20% chance of longer delay
+		if randFloat64() < 0.2 { // 20% chance of longer delay
 			navigationDelay *= 3
 		}
 		time.Sleep(navigationDelay)
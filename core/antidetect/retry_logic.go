@@ -1,9 +1,11 @@
 package antidetect
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -146,7 +148,7 @@ func (rr *RetryableRequest) calculateDelay(attempt int) time.Duration {
 	// Add jitter
 	if rr.Config.JitterPercent > 0 {
 		jitter := delay * rr.Config.JitterPercent / 100.0
-		delay += (2*jitter*float64(time.Now().UnixNano()%1000)/1000.0 - jitter)
+		delay += 2*jitter*randFloat64() - jitter
 	}
 
 	return time.Duration(delay)
@@ -285,16 +287,21 @@ func (cb *CircuitBreaker) GetState() CircuitState {
 	return cb.state
 }
 
-// RateLimiter implements a token bucket rate limiter
+// RateLimiter implements a token bucket rate limiter. It is safe for
+// concurrent use, so one limiter can cap several crawlers at once
 type RateLimiter struct {
-	tokens    int
-	maxTokens int
+	mu         sync.Mutex
+	tokens     int
+	maxTokens  int
 	refillRate time.Duration
 	lastRefill time.Time
 }
 
 // NewRateLimiter creates a new rate limiter
 func NewRateLimiter(maxTokens int, refillRate time.Duration) *RateLimiter {
+	if maxTokens < 1 {
+		maxTokens = 1
+	}
 	return &RateLimiter{
 		tokens:     maxTokens,
 		maxTokens:  maxTokens,
@@ -305,35 +312,61 @@ func NewRateLimiter(maxTokens int, refillRate time.Duration) *RateLimiter {
 
 // Allow checks if a request is allowed
 func (rl *RateLimiter) Allow() bool {
-	rl.refill()
-	
-	if rl.tokens > 0 {
-		rl.tokens--
-		return true
-	}
-	
-	return false
+	ok, _ := rl.take()
+	return ok
 }
 
 // Wait waits until a token is available
 func (rl *RateLimiter) Wait() {
-	for !rl.Allow() {
-		time.Sleep(rl.refillRate / time.Duration(rl.maxTokens))
+	_ = rl.WaitContext(context.Background())
+}
+
+// WaitContext waits until a token is available or ctx ends
+func (rl *RateLimiter) WaitContext(ctx context.Context) error {
+	for {
+		ok, wait := rl.take()
+		if ok {
+			return nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
 	}
 }
 
-// refill refills the token bucket
+// take consumes a token, or reports how long until the next one
+func (rl *RateLimiter) take() (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.refill()
+	if rl.tokens > 0 {
+		rl.tokens--
+		return true, 0
+	}
+	return false, rl.refillRate - time.Since(rl.lastRefill)
+}
+
+// refill refills the token bucket. The caller holds mu
 func (rl *RateLimiter) refill() {
-	now := time.Now()
-	elapsed := now.Sub(rl.lastRefill)
-	
+	if rl.refillRate <= 0 {
+		rl.tokens = rl.maxTokens
+		return
+	}
+	elapsed := time.Since(rl.lastRefill)
+
 	tokensToAdd := int(elapsed / rl.refillRate)
 	if tokensToAdd > 0 {
 		rl.tokens += tokensToAdd
-		if rl.tokens > rl.maxTokens {
+		// Keep the remainder so the long-run rate does not drift below the target
+		rl.lastRefill = rl.lastRefill.Add(time.Duration(tokensToAdd) * rl.refillRate)
+		if rl.tokens >= rl.maxTokens {
 			rl.tokens = rl.maxTokens
+			rl.lastRefill = time.Now()
 		}
-		rl.lastRefill = now
 	}
 }
 
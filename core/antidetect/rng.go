@@ -0,0 +1,41 @@
+package antidetect
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// The package draws all of its randomness from one RNG so a crawl can be
+// made reproducible with SetSeed. *rand.Rand is not safe for concurrent use,
+// hence the mutex.
+var (
+	rngMutex sync.Mutex
+	rng      = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// SetSeed reseeds the package RNG; the same seed yields the same sequence of
+// user agents, fingerprints and timing choices
+func SetSeed(seed int64) {
+	rngMutex.Lock()
+	rng = rand.New(rand.NewSource(seed))
+	rngMutex.Unlock()
+}
+
+func randIntn(n int) int {
+	rngMutex.Lock()
+	defer rngMutex.Unlock()
+	return rng.Intn(n)
+}
+
+func randInt63n(n int64) int64 {
+	rngMutex.Lock()
+	defer rngMutex.Unlock()
+	return rng.Int63n(n)
+}
+
+func randFloat64() float64 {
+	rngMutex.Lock()
+	defer rngMutex.Unlock()
+	return rng.Float64()
+}
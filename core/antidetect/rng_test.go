@@ -0,0 +1,35 @@
+package antidetect
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetSeedReproducesChoices(t *testing.T) {
+	sample := func() []string {
+		var out []string
+		for i := 0; i < 10; i++ {
+			out = append(out,
+				GetRandomUserAgent().UserAgent,
+				GetUserAgentByBrowser("random").UserAgent,
+				GetRandomHTTP2Profile().Name,
+				GetRandomTimingProfile().MinDelay.String(),
+				JitterDelay(1000000000, 0.5).String(),
+			)
+		}
+		return out
+	}
+
+	SetSeed(42)
+	first := sample()
+	SetSeed(42)
+	second := sample()
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("same seed produced different sequences:\n%v\n%v", first, second)
+	}
+
+	SetSeed(43)
+	if reflect.DeepEqual(first, sample()) {
+		t.Fatal("different seeds produced identical sequences")
+	}
+}
@@ -1,7 +1,6 @@
 package antidetect
 
 import (
-	"math/rand"
 	"time"
 )
 
@@ -44,37 +43,33 @@ func GetRealisticTimingProfiles() []TimingProfile {
 // GetRandomTimingProfile returns a random timing profile
 func GetRandomTimingProfile() TimingProfile {
 	profiles := GetRealisticTimingProfiles()
-	rand.Seed(time.Now().UnixNano())
-	return profiles[rand.Intn(len(profiles))]
+	return profiles[randIntn(len(profiles))]
 }
 
 // CalculateDelay calculates a realistic delay based on the timing profile
 func (tp TimingProfile) CalculateDelay() time.Duration {
-	rand.Seed(time.Now().UnixNano())
 	
 	// Random delay between min and max
 	delayRange := tp.MaxDelay - tp.MinDelay
-	randomDelay := time.Duration(rand.Int63n(int64(delayRange)))
+	randomDelay := time.Duration(randInt63n(int64(delayRange)))
 	
 	return tp.MinDelay + randomDelay
 }
 
 // CalculateBurstDelay calculates delay for burst requests
 func (tp TimingProfile) CalculateBurstDelay() time.Duration {
-	rand.Seed(time.Now().UnixNano())
 	
 	// Add some randomness to burst delay
-	variance := time.Duration(rand.Int63n(int64(tp.BurstDelay / 2)))
+	variance := time.Duration(randInt63n(int64(tp.BurstDelay / 2)))
 	return tp.BurstDelay + variance
 }
 
 // CalculateThinkTime calculates think time between request groups
 func (tp TimingProfile) CalculateThinkTime() time.Duration {
-	rand.Seed(time.Now().UnixNano())
 	
 	// Add randomness to think time (±50%)
-	variance := time.Duration(rand.Int63n(int64(tp.ThinkTime)))
-	if rand.Intn(2) == 0 {
+	variance := time.Duration(randInt63n(int64(tp.ThinkTime)))
+	if randIntn(2) == 0 {
 		return tp.ThinkTime + variance
 	}
 	return tp.ThinkTime - variance/2
@@ -161,14 +156,13 @@ func JitterDelay(baseDelay time.Duration, jitterPercent float64) time.Duration {
 		return baseDelay
 	}
 	
-	rand.Seed(time.Now().UnixNano())
 	
 	// Calculate jitter range
 	jitterRange := float64(baseDelay) * jitterPercent / 100.0
-	jitter := time.Duration(rand.Float64() * jitterRange)
+	jitter := time.Duration(randFloat64() * jitterRange)
 	
 	// Randomly add or subtract jitter
-	if rand.Intn(2) == 0 {
+	if randIntn(2) == 0 {
 		return baseDelay + jitter
 	}
 	
@@ -206,9 +200,8 @@ func RandomDelay(min, max time.Duration) time.Duration {
 		return min
 	}
 	
-	rand.Seed(time.Now().UnixNano())
 	diff := max - min
-	randomDiff := time.Duration(rand.Int63n(int64(diff)))
+	randomDiff := time.Duration(randInt63n(int64(diff)))
 	
 	return min + randomDiff
 }
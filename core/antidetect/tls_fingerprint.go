@@ -2,8 +2,6 @@ package antidetect
 
 import (
 	"crypto/tls"
-	"math/rand"
-	"time"
 )
 
 // TLS cipher suites that mimic real browsers
@@ -129,8 +127,7 @@ func GetBrowserProfiles() []BrowserProfile {
 // GetRandomBrowserProfile returns a random browser profile
 func GetRandomBrowserProfile() BrowserProfile {
 	profiles := GetBrowserProfiles()
-	rand.Seed(time.Now().UnixNano())
-	return profiles[rand.Intn(len(profiles))]
+	return profiles[randIntn(len(profiles))]
 }
 
 // CreateTLSConfig creates a TLS config that mimics a real browser
@@ -155,12 +152,11 @@ func CreateRandomTLSConfig() *tls.Config {
 
 // ShuffleCipherSuites randomly shuffles cipher suites to avoid fingerprinting
 func ShuffleCipherSuites(suites []uint16) []uint16 {
-	rand.Seed(time.Now().UnixNano())
 	shuffled := make([]uint16, len(suites))
 	copy(shuffled, suites)
 
 	for i := len(shuffled) - 1; i > 0; i-- {
-		j := rand.Intn(i + 1)
+		j := randIntn(i + 1)
 		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
 	}
 
@@ -169,13 +165,16 @@ func ShuffleCipherSuites(suites []uint16) []uint16 {
 
 // CreateStealthTLSConfig creates a highly randomized TLS config for maximum stealth
 func CreateStealthTLSConfig() *tls.Config {
-	profile := GetRandomBrowserProfile()
+	return CreateStealthTLSConfigFor(GetRandomBrowserProfile())
+}
 
+// CreateStealthTLSConfigFor randomizes the TLS config of a given browser profile
+func CreateStealthTLSConfigFor(profile BrowserProfile) *tls.Config {
 	// Shuffle cipher suites for additional randomization
 	shuffledCiphers := ShuffleCipherSuites(profile.CipherSuites)
 
 	// Take only a subset of cipher suites to mimic real browser behavior
-	numCiphers := 8 + rand.Intn(8) // 8-15 cipher suites
+	numCiphers := 8 + randIntn(8) // 8-15 cipher suites
 	if numCiphers > len(shuffledCiphers) {
 		numCiphers = len(shuffledCiphers)
 	}
@@ -188,6 +187,6 @@ func CreateStealthTLSConfig() *tls.Config {
 		MaxVersion:             profile.MaxVersion,
 		InsecureSkipVerify:     true,
 		Renegotiation:          tls.RenegotiateOnceAsClient,
-		SessionTicketsDisabled: rand.Intn(2) == 0, // Randomly enable/disable session tickets
+		SessionTicketsDisabled: randIntn(2) == 0, // Randomly enable/disable session tickets
 	}
 }
@@ -0,0 +1,32 @@
+package antidetect
+
+import (
+	"crypto/tls"
+	"net/http"
+)
+
+// TLSObserver receives the TLS connection state of HTTPS responses
+type TLSObserver func(host string, state *tls.ConnectionState)
+
+// tlsObserverRoundTripper hands response TLS state to an observer
+type tlsObserverRoundTripper struct {
+	base    http.RoundTripper
+	observe TLSObserver
+}
+
+func (rt *tlsObserverRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := rt.base.RoundTrip(req)
+	if err == nil && resp != nil && resp.TLS != nil {
+		rt.observe(req.URL.Hostname(), resp.TLS)
+	}
+	return resp, err
+}
+
+// ObserveTLS calls observer with the TLS state of every HTTPS response made
+// through the client, e.g. to read the peer certificate
+func (c *AntiDetectClient) ObserveTLS(observer TLSObserver) {
+	if observer == nil {
+		return
+	}
+	c.httpClient.Transport = &tlsObserverRoundTripper{base: c.httpClient.Transport, observe: observer}
+}
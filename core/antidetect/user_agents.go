@@ -1,10 +1,5 @@
 package antidetect
 
-import (
-	"math/rand"
-	"time"
-)
-
 // BrowserUserAgent represents a browser user agent with associated headers
 type BrowserUserAgent struct {
 	UserAgent string
@@ -158,32 +153,27 @@ func GetAllUserAgents() []BrowserUserAgent {
 // GetRandomUserAgent returns a random user agent with headers
 func GetRandomUserAgent() BrowserUserAgent {
 	agents := GetAllUserAgents()
-	rand.Seed(time.Now().UnixNano())
-	return agents[rand.Intn(len(agents))]
+	return agents[randIntn(len(agents))]
 }
 
 // GetRandomChromeUserAgent returns a random Chrome user agent
 func GetRandomChromeUserAgent() BrowserUserAgent {
-	rand.Seed(time.Now().UnixNano())
-	return ChromeUserAgents[rand.Intn(len(ChromeUserAgents))]
+	return ChromeUserAgents[randIntn(len(ChromeUserAgents))]
 }
 
 // GetRandomFirefoxUserAgent returns a random Firefox user agent
 func GetRandomFirefoxUserAgent() BrowserUserAgent {
-	rand.Seed(time.Now().UnixNano())
-	return FirefoxUserAgents[rand.Intn(len(FirefoxUserAgents))]
+	return FirefoxUserAgents[randIntn(len(FirefoxUserAgents))]
 }
 
 // GetRandomSafariUserAgent returns a random Safari user agent
 func GetRandomSafariUserAgent() BrowserUserAgent {
-	rand.Seed(time.Now().UnixNano())
-	return SafariUserAgents[rand.Intn(len(SafariUserAgents))]
+	return SafariUserAgents[randIntn(len(SafariUserAgents))]
 }
 
 // GetRandomEdgeUserAgent returns a random Edge user agent
 func GetRandomEdgeUserAgent() BrowserUserAgent {
-	rand.Seed(time.Now().UnixNano())
-	return EdgeUserAgents[rand.Intn(len(EdgeUserAgents))]
+	return EdgeUserAgents[randIntn(len(EdgeUserAgents))]
 }
 
 // GetUserAgentByBrowser returns a random user agent for a specific browser
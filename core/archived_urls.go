@@ -0,0 +1,83 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// archivedSource is the Source of every record about an other-source URL
+const archivedSource = "other-source"
+
+// addArchivedURL handles one URL from the other-source providers. A URL the
+// crawl already found live is dropped. Otherwise it is reported as
+// [other] right away, or with --include-other-source it is crawled and
+// reported once its response says whether it is still live
+func (crawler *Crawler) addArchivedURL(rawURL string) {
+	u, ok := NormalizeURL(crawler.site, rawURL)
+	if !ok || crawler.registry.Seen(u) {
+		return
+	}
+	key := canonicalRequestKey(http.MethodGet, u, "")
+	if _, dup := crawler.archived.LoadOrStore(key, u); dup {
+		return
+	}
+	if crawler.Stats != nil {
+		crawler.Stats.IncrementURLsFound()
+	}
+	parsed, err := url.Parse(u)
+	if !crawler.includeOtherSourceResult || err != nil || !InScope(parsed, crawler.C.URLFilters) {
+		crawler.archived.Store(key, "")
+		crawler.reportArchived(u)
+		return
+	}
+	// Later links to the URL are left to this visit, so it is reported once
+	if crawler.registry.Duplicate(u) {
+		crawler.archived.Store(key, "")
+		return
+	}
+	crawler.visit(nil, u)
+}
+
+// reportArchived reports an archived URL whose liveness is unknown
+func (crawler *Crawler) reportArchived(u string) {
+	sout := SpiderOutput{
+		Input:      crawler.Input,
+		Source:     archivedSource,
+		OutputType: "other",
+		Output:     u,
+	}
+	crawler.emit(sout, fmt.Sprintf("[other] - %s", u), u)
+}
+
+// takeArchived reports whether rawURL is an archived URL being re-crawled
+// and not reported yet, and marks it reported
+func (crawler *Crawler) takeArchived(rawURL string) bool {
+	key := canonicalRequestKey(http.MethodGet, rawURL, "")
+	u, ok := crawler.archived.Load(key)
+	return ok && u != "" && crawler.archived.CompareAndSwap(key, u, "")
+}
+
+// tagArchived turns the [url] record of a re-crawled archived URL into an
+// [other] record carrying its current status; a request that got no
+// response at all is marked dead
+func tagArchived(sout *SpiderOutput, outputFormat string) string {
+	sout.Source = archivedSource
+	sout.OutputType = "other"
+	if sout.StatusCode == 0 {
+		return fmt.Sprintf("[other] - [dead] - %s", sout.Output)
+	}
+	return strings.Replace(outputFormat, "[url]", "[other]", 1)
+}
+
+// flushArchived reports the re-crawled archived URLs that never got a
+// record of their own, such as those that redirected elsewhere
+func (crawler *Crawler) flushArchived() {
+	crawler.archived.Range(func(key, u any) bool {
+		if u != "" && crawler.archived.CompareAndSwap(key, u, "") {
+			crawler.reportArchived(u.(string))
+		}
+		return true
+	})
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeArchive replaces the other-source providers with one returning paths
+// on base
+func fakeArchive(t *testing.T, base string, paths ...string) {
+	prev := otherSourceFetchers
+	otherSourceFetchers = []fetchFn{func(ctx context.Context, p *otherSourceProvider, domain string, noSubs bool) error {
+		for _, path := range paths {
+			p.add(wurl{url: base + path})
+		}
+		return nil
+	}}
+	t.Cleanup(func() { otherSourceFetchers = prev })
+}
+
+func archiveSite(t *testing.T, hits *int32) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><a href="/live">live</a></body></html>`)
+		case "/live", "/old-but-live":
+			if r.URL.Path == "/old-but-live" {
+				atomic.AddInt32(hits, 1)
+			}
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body>here</body></html>`)
+		default:
+			atomic.AddInt32(hits, 1)
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func collectArchived(t *testing.T, srv *httptest.Server, include bool) map[string]SpiderOutput {
+	results := crawlAll(t, srv.URL, CrawlerConfig{
+		OtherSource: true, IncludeOtherSourceResult: include,
+	})
+
+	found := map[string]SpiderOutput{}
+	for _, sout := range results {
+		u, _ := url.Parse(sout.Output)
+		key := sout.OutputType + " " + u.Path
+		_, dup := found[key]
+		assert.False(t, dup, "%s reported twice", key)
+		found[key] = sout
+	}
+	return found
+}
+
+func TestArchivedURLsRecrawled(t *testing.T) {
+	var hits int32
+	srv := archiveSite(t, &hits)
+	fakeArchive(t, srv.URL, "/old-but-live", "/gone", "/gone#frag")
+
+	found := collectArchived(t, srv, true)
+	live, ok := found["other /old-but-live"]
+	require.True(t, ok, "%v", found)
+	assert.Equal(t, http.StatusOK, live.StatusCode)
+	assert.Equal(t, archivedSource, live.Source)
+	gone, ok := found["other /gone"]
+	require.True(t, ok, "%v", found)
+	assert.Equal(t, http.StatusNotFound, gone.StatusCode)
+	assert.NotContains(t, found, "url /old-but-live")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&hits))
+}
+
+func TestArchivedURLsReportedWithoutRecrawl(t *testing.T) {
+	var hits int32
+	srv := archiveSite(t, &hits)
+	fakeArchive(t, srv.URL, "/old-but-live", "/gone")
+
+	found := collectArchived(t, srv, false)
+	for _, path := range []string{"/old-but-live", "/gone"} {
+		sout, ok := found["other "+path]
+		require.True(t, ok, "%s: %v", path, found)
+		assert.Zero(t, sout.StatusCode)
+	}
+	assert.Zero(t, atomic.LoadInt32(&hits))
+}
+
+func TestArchivedURLFoundLiveIsNotReported(t *testing.T) {
+	site, _ := url.Parse("http://example.com/")
+	sink := &recordingSink{}
+	crawler := &Crawler{site: site, registry: NewURLRegistry(), sink: sink}
+
+	crawler.registry.Duplicate("http://example.com/live")
+	crawler.addArchivedURL("http://example.com/live")
+	crawler.addArchivedURL("http://example.com/old")
+	crawler.addArchivedURL("http://example.com/old#again")
+
+	require.Len(t, sink.results, 1)
+	assert.Equal(t, "other", sink.results[0].OutputType)
+	assert.Equal(t, "http://example.com/old", sink.results[0].Output)
+}
@@ -20,8 +20,11 @@ import (
 
 type BrowserPoolConfig struct {
 	PoolSize           int
+	NavConcurrency     int
 	NavigationTimeout  time.Duration
 	StabilizationDelay time.Duration
+	WaitStrategy       string
+	IdleWindow         time.Duration
 	Headless           *bool
 	InitScripts        []string
 }
@@ -63,6 +66,7 @@ type BrowserPool struct {
 	browser     *rod.Browser
 	sessions    []*rod.Browser
 	pagePool    chan *rod.Page
+	navSem      chan struct{}
 	initOnce    sync.Once
 	shutdownMu  sync.Mutex
 	initialized bool
@@ -77,6 +81,7 @@ type PageAnalysisResult struct {
 	Digest      string
 	IsNewState  bool
 	APICalls    []string
+	WebSockets  []string
 	Transitions []StateTransition
 }
 
@@ -84,17 +89,27 @@ func NewBrowserPool(cfg BrowserPoolConfig) *BrowserPool {
 	if cfg.PoolSize <= 0 {
 		cfg.PoolSize = 2
 	}
+	// Pages beyond the navigation limit stay warm in the pool
+	if cfg.NavConcurrency <= 0 || cfg.NavConcurrency > cfg.PoolSize {
+		cfg.NavConcurrency = cfg.PoolSize
+	}
 	if cfg.NavigationTimeout <= 0 {
 		cfg.NavigationTimeout = 12 * time.Second
 	}
 	if cfg.StabilizationDelay <= 0 {
 		cfg.StabilizationDelay = 600 * time.Millisecond
 	}
+	if cfg.WaitStrategy == "" {
+		cfg.WaitStrategy = HybridWaitLoad
+	}
+	if cfg.IdleWindow <= 0 {
+		cfg.IdleWindow = defaultHybridIdleWindow
+	}
 	headless := true
 	if cfg.Headless != nil {
 		headless = *cfg.Headless
 	}
-	return &BrowserPool{cfg: cfg, headless: headless}
+	return &BrowserPool{cfg: cfg, headless: headless, navSem: make(chan struct{}, cfg.NavConcurrency)}
 }
 
 func (bp *BrowserPool) Initialize(ctx context.Context) error {
@@ -291,10 +306,21 @@ func (bp *BrowserPool) NavigateAndAnalyze(ctx context.Context, url string, graph
 	}
 	defer func() { _ = bp.ReleasePage(page) }()
 
+	// Held for the whole analysis, where a heavy page peaks in memory
+	select {
+	case bp.navSem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-bp.navSem }()
+
 	apiSet := make(map[string]struct{})
 	apiCalls := make([]string, 0, 8)
+	var webSockets []string
 	var apiMu sync.Mutex
+	network := newNetworkIdleTracker()
 	stopEvents := page.EachEvent(func(e *proto.NetworkRequestWillBeSent) {
+		network.started(e)
 		if e.Type == proto.NetworkResourceTypeXHR || e.Type == proto.NetworkResourceTypeFetch {
 			apiMu.Lock()
 			if _, exists := apiSet[e.Request.URL]; !exists {
@@ -303,9 +329,18 @@ func (bp *BrowserPool) NavigateAndAnalyze(ctx context.Context, url string, graph
 			}
 			apiMu.Unlock()
 		}
+	}, func(e *proto.NetworkWebSocketCreated) {
+		apiMu.Lock()
+		webSockets = append(webSockets, e.URL)
+		apiMu.Unlock()
+	}, func(e *proto.NetworkLoadingFinished) {
+		network.finished(e.RequestID)
+	}, func(e *proto.NetworkLoadingFailed) {
+		network.finished(e.RequestID)
 	})
 	defer stopEvents()
 
+	navStart := time.Now()
 	navCtx := page.Context(ctx)
 	if bp.cfg.NavigationTimeout > 0 {
 		navCtx = navCtx.Timeout(bp.cfg.NavigationTimeout)
@@ -316,7 +351,16 @@ func (bp *BrowserPool) NavigateAndAnalyze(ctx context.Context, url string, graph
 	if err := navCtx.WaitLoad(); err != nil && !errors.Is(err, context.DeadlineExceeded) {
 		return nil, fmt.Errorf("wait load %s: %w", url, err)
 	}
-	if bp.cfg.StabilizationDelay > 0 {
+	if bp.cfg.WaitStrategy == HybridWaitNetworkIdle {
+		// The navigation timeout also bounds the wait, so a page that keeps
+		// polling cannot hold the worker
+		if !network.wait(ctx, bp.cfg.IdleWindow, navStart.Add(bp.cfg.NavigationTimeout)) {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			Logger.Debugf("hybrid: %s never went network-idle; analysing it as is", url)
+		}
+	} else if bp.cfg.StabilizationDelay > 0 {
 		select {
 		case <-time.After(bp.cfg.StabilizationDelay):
 		case <-ctx.Done():
@@ -353,6 +397,7 @@ func (bp *BrowserPool) NavigateAndAnalyze(ctx context.Context, url string, graph
 		Digest:      digest,
 		IsNewState:  isNew,
 		APICalls:    apiCalls,
+		WebSockets:  webSockets,
 		Transitions: transitions,
 	}, nil
 }
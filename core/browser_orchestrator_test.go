@@ -0,0 +1,18 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewBrowserPoolNavConcurrency(t *testing.T) {
+	bp := NewBrowserPool(BrowserPoolConfig{PoolSize: 4})
+	assert.Equal(t, 4, cap(bp.navSem), "defaults to the pool size")
+
+	bp = NewBrowserPool(BrowserPoolConfig{PoolSize: 4, NavConcurrency: 2})
+	assert.Equal(t, 2, cap(bp.navSem))
+
+	bp = NewBrowserPool(BrowserPoolConfig{PoolSize: 2, NavConcurrency: 8})
+	assert.Equal(t, 2, cap(bp.navSem), "more navigations than pages is pointless")
+}
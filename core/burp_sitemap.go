@@ -0,0 +1,120 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// burpSitemapSource is the source of requests seeded from --burp-sitemap
+const burpSitemapSource = "burp-sitemap"
+
+type burpSitemap struct {
+	Items []burpSitemapItem `xml:"item"`
+}
+
+type burpSitemapItem struct {
+	URL     string          `xml:"url"`
+	Method  string          `xml:"method"`
+	Request burpSitemapData `xml:"request"`
+}
+
+type burpSitemapData struct {
+	Base64 bool   `xml:"base64,attr"`
+	Data   string `xml:",chardata"`
+}
+
+// LoadBurpSitemap reads the requests of a Burp Suite sitemap, as written by
+// "Save selected items" on the Target tab. The raw request of each item
+// supplies its headers and body; items without one are sent as bare
+// requests. Repeated requests are kept once
+func LoadBurpSitemap(path string) ([]JSRequest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sitemap burpSitemap
+	if err := xml.Unmarshal(data, &sitemap); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var requests []JSRequest
+	seen := map[string]struct{}{}
+	for _, item := range sitemap.Items {
+		u, err := url.Parse(strings.TrimSpace(item.URL))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			continue
+		}
+		u.Fragment = ""
+		req := JSRequest{
+			Method:  strings.ToUpper(strings.TrimSpace(item.Method)),
+			RawURL:  u.String(),
+			Headers: map[string]string{},
+			Source:  burpSitemapSource,
+		}
+		if raw, ok := item.Request.raw(); ok {
+			if parsed, err := readRawRequest(raw); err == nil {
+				if req.Method == "" {
+					req.Method = parsed.Method
+				}
+				for name, values := range parsed.Header {
+					if _, skip := seedSkippedHeaders[name]; !skip && len(values) > 0 {
+						req.Headers[name] = values[0]
+					}
+				}
+				body, _ := io.ReadAll(parsed.Body)
+				req.Body = string(body)
+				req.ContentType = parsed.Header.Get("Content-Type")
+			}
+		}
+		if req.Method == "" {
+			req.Method = http.MethodGet
+		}
+
+		key := req.Method + " " + req.RawURL + "\n" + req.Body
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		seen[key] = struct{}{}
+		requests = append(requests, req)
+	}
+	return requests, nil
+}
+
+func (d burpSitemapData) raw() ([]byte, bool) {
+	data := strings.TrimSpace(d.Data)
+	if data == "" {
+		return nil, false
+	}
+	if !d.Base64 {
+		// Trimming may have taken the blank line ending the headers
+		raw := []byte(strings.TrimLeft(d.Data, " \t\r\n"))
+		if !bytes.Contains(raw, []byte("\n\n")) && !bytes.Contains(raw, []byte("\r\n\r\n")) {
+			raw = append([]byte(data), "\r\n\r\n"...)
+		}
+		return raw, true
+	}
+	raw, err := base64.StdEncoding.DecodeString(data)
+	return raw, err == nil
+}
+
+// readRawRequest parses a request as Burp shows it. Burp writes HTTP/2
+// requests with an HTTP/2 request line, which net/http does not read
+func readRawRequest(raw []byte) (*http.Request, error) {
+	if line, rest, ok := bytes.Cut(raw, []byte("\n")); ok {
+		if trimmed := bytes.TrimRight(line, "\r"); bytes.HasSuffix(trimmed, []byte(" HTTP/2")) {
+			fixed := make([]byte, 0, len(raw)+4)
+			fixed = append(fixed, bytes.TrimSuffix(trimmed, []byte("2"))...)
+			fixed = append(fixed, "1.1\r\n"...)
+			raw = append(fixed, rest...)
+		}
+	}
+	return http.ReadRequest(bufio.NewReader(bytes.NewReader(raw)))
+}
@@ -0,0 +1,98 @@
+package core
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeBurpSitemap(t *testing.T, base, host string) string {
+	post := "POST /api/login HTTP/2\r\nHost: " + host + "\r\nContent-Type: application/json\r\nContent-Length: 13\r\nX-Api-Key: k\r\nCookie: sid=1\r\n\r\n{\"user\":\"a\"}"
+	sitemap := fmt.Sprintf(`<?xml version="1.0"?>
+<items burpVersion="2024.1">
+  <item>
+    <url><![CDATA[%[1]s/admin/]]></url>
+    <host ip="127.0.0.1">%[2]s</host>
+    <method><![CDATA[GET]]></method>
+    <request base64="false"><![CDATA[GET /admin/ HTTP/1.1
+Host: %[2]s
+X-Requested-With: XMLHttpRequest
+
+]]></request>
+    <status>200</status>
+  </item>
+  <item>
+    <url><![CDATA[%[1]s/api/login]]></url>
+    <method><![CDATA[POST]]></method>
+    <request base64="true"><![CDATA[%[3]s]]></request>
+  </item>
+  <item>
+    <url><![CDATA[%[1]s/admin/#again]]></url>
+    <method><![CDATA[GET]]></method>
+  </item>
+  <item>
+    <url><![CDATA[%[1]s/static/app.js]]></url>
+  </item>
+</items>`, base, host, base64.StdEncoding.EncodeToString([]byte(post)))
+	path := filepath.Join(t.TempDir(), "sitemap.xml")
+	require.NoError(t, os.WriteFile(path, []byte(sitemap), 0o644))
+	return path
+}
+
+func TestLoadBurpSitemap(t *testing.T) {
+	requests, err := LoadBurpSitemap(writeBurpSitemap(t, "https://app.example.com", "app.example.com"))
+	require.NoError(t, err)
+
+	require.Len(t, requests, 3, "repeated requests are kept once")
+	assert.Equal(t, JSRequest{
+		Method:  "GET",
+		RawURL:  "https://app.example.com/admin/",
+		Headers: map[string]string{"X-Requested-With": "XMLHttpRequest"},
+		Source:  burpSitemapSource,
+	}, requests[0])
+	assert.Equal(t, JSRequest{
+		Method:      "POST",
+		RawURL:      "https://app.example.com/api/login",
+		Body:        `{"user":"a"}`,
+		Headers:     map[string]string{"Content-Type": "application/json", "X-Api-Key": "k"},
+		ContentType: "application/json",
+		Source:      burpSitemapSource,
+	}, requests[1], "HTTP/2 requests are read too")
+	assert.Equal(t, "GET", requests[2].Method)
+	assert.Equal(t, "https://app.example.com/static/app.js", requests[2].RawURL)
+}
+
+func TestCrawlSeedsBurpSitemapRequests(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen[r.Method+" "+r.URL.Path] = r.Header.Get("X-Api-Key")
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/admin/" {
+			fmt.Fprint(w, `<html><body><a href="/admin/users">users</a></body></html>`)
+			return
+		}
+		fmt.Fprint(w, `<html><body>ok</body></html>`)
+	}))
+	defer srv.Close()
+
+	crawlAll(t, srv.URL, CrawlerConfig{
+		MaxDepth: 3, BurpSitemap: writeBurpSitemap(t, srv.URL, srv.Listener.Addr().String()),
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "k", seen["POST /api/login"])
+	assert.Contains(t, seen, "GET /admin/users", "the crawl expands from the sitemap's pages")
+	assert.Contains(t, seen, "GET /static/app.js")
+}
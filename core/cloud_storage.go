@@ -0,0 +1,91 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// cloudStorageRule finds the bucket addresses of one provider. Its
+// patterns name the bucket, and the region where the address has one
+type cloudStorageRule struct {
+	Provider string
+	Pattern  *regexp.Regexp
+}
+
+var cloudStorageRules = []cloudStorageRule{
+	{"aws-s3", regexp.MustCompile(`(?i)\b(?P<bucket>[a-z0-9.-]+)\.s3[.-](?:website[.-])?(?P<region>[a-z]{2}-[a-z]+-[0-9])\.amazonaws\.com|\b(?P<bucket>[a-z0-9.-]+)\.s3\.amazonaws\.com|//s3[.-](?:(?P<region>[a-z]{2}-[a-z]+-[0-9])\.)?amazonaws\.com/(?P<bucket>[a-z0-9._-]+)|//s3\.amazonaws\.com/(?P<bucket>[a-z0-9._-]+)`)},
+	{"gcs", regexp.MustCompile(`(?i)\b(?P<bucket>[a-z0-9._-]+)\.storage\.googleapis\.com|//storage\.(?:googleapis|cloud\.google)\.com/(?P<bucket>[a-z0-9._-]+)|\bgs://(?P<bucket>[a-z0-9._-]+)`)},
+	{"azure-blob", regexp.MustCompile(`(?i)\b(?P<bucket>[a-z0-9]{3,24}\.blob\.core\.windows\.net(?:/[a-z0-9$][a-z0-9-]{2,62})?)`)},
+	{"do-spaces", regexp.MustCompile(`(?i)\b(?P<bucket>[a-z0-9.-]+)\.(?P<region>[a-z]{3}[0-9])\.(?:cdn\.)?digitaloceanspaces\.com|//(?P<region>[a-z]{3}[0-9])\.digitaloceanspaces\.com/(?P<bucket>[a-z0-9._-]+)`)},
+	{"cloudflare-r2", regexp.MustCompile(`(?i)\b[0-9a-f]{32}\.r2\.cloudflarestorage\.com/(?P<bucket>[a-z0-9._-]+)|\b(?P<bucket>pub-[0-9a-f]{32})\.r2\.dev`)},
+}
+
+// CloudStorage is a storage bucket address found in a response
+type CloudStorage struct {
+	Provider string
+	Location string
+	Bucket   string
+	Region   string
+}
+
+// FindCloudStorage returns the S3, Google Cloud Storage, Azure Blob,
+// DigitalOcean Spaces and Cloudflare R2 buckets addressed in source, each
+// location once. Azure buckets are named account/container
+func FindCloudStorage(source string) []CloudStorage {
+	var found []CloudStorage
+	seen := map[string]bool{}
+	for _, rule := range cloudStorageRules {
+		names := rule.Pattern.SubexpNames()
+		for _, m := range rule.Pattern.FindAllStringSubmatch(source, -1) {
+			storage := CloudStorage{Provider: rule.Provider, Location: strings.TrimPrefix(DecodeChars(m[0]), "//")}
+			for i, name := range names {
+				switch {
+				case m[i] == "":
+				case name == "bucket":
+					storage.Bucket = strings.ToLower(m[i])
+				case name == "region":
+					storage.Region = strings.ToLower(m[i])
+				}
+			}
+			if rule.Provider == "azure-blob" {
+				storage.Bucket = strings.Replace(storage.Bucket, ".blob.core.windows.net", "", 1)
+			}
+			if key := rule.Provider + "|" + storage.Location; !seen[key] {
+				seen[key] = true
+				found = append(found, storage)
+			}
+		}
+	}
+	return found
+}
+
+// findCloudStorage reports the buckets addressed in the body of rawURL,
+// each once per crawl
+func (crawler *Crawler) findCloudStorage(body, rawURL string, depth int) {
+	stop := crawler.profile.start(extractorCloudStorage)
+	buckets := FindCloudStorage(body)
+	stop()
+	for _, storage := range buckets {
+		if crawler.bucketSet.Duplicate(storage.Provider + "|" + storage.Location) {
+			continue
+		}
+		if crawler.Stats != nil {
+			crawler.Stats.IncrementURLsFound()
+		}
+		snippet := storage.Bucket
+		if storage.Region != "" {
+			snippet += " (" + storage.Region + ")"
+		}
+		sout := SpiderOutput{
+			Input:      crawler.Input,
+			Source:     rawURL,
+			OutputType: "cloud-storage",
+			Output:     storage.Location,
+			Param:      storage.Provider,
+			Snippet:    snippet,
+			Depth:      depth,
+		}
+		crawler.emit(sout, fmt.Sprintf("[cloud-storage] - [%s] - [%s] - %s", storage.Provider, snippet, storage.Location), storage.Location)
+	}
+}
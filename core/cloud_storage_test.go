@@ -0,0 +1,38 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindCloudStorage(t *testing.T) {
+	for _, tc := range []struct {
+		source string
+		want   []CloudStorage
+	}{
+		{`<img src="https://assets.s3.amazonaws.com/logo.png">`, []CloudStorage{{"aws-s3", "assets.s3.amazonaws.com", "assets", ""}}},
+		{`"https://media.s3.eu-west-1.amazonaws.com/a.png"`, []CloudStorage{{"aws-s3", "media.s3.eu-west-1.amazonaws.com", "media", "eu-west-1"}}},
+		{`"https://s3.us-east-2.amazonaws.com/backups/db.gz"`, []CloudStorage{{"aws-s3", "s3.us-east-2.amazonaws.com/backups", "backups", "us-east-2"}}},
+		{`"https://storage.googleapis.com/my-bucket/x.js"`, []CloudStorage{{"gcs", "storage.googleapis.com/my-bucket", "my-bucket", ""}}},
+		{`"https://static.example.com.storage.googleapis.com/x"`, []CloudStorage{{"gcs", "static.example.com.storage.googleapis.com", "static.example.com", ""}}},
+		{`upload("gs://exports")`, []CloudStorage{{"gcs", "gs://exports", "exports", ""}}},
+		{`"https://acct01.blob.core.windows.net/images/a.png"`, []CloudStorage{{"azure-blob", "acct01.blob.core.windows.net/images", "acct01/images", ""}}},
+		{`"https://files.nyc3.digitaloceanspaces.com/a"`, []CloudStorage{{"do-spaces", "files.nyc3.digitaloceanspaces.com", "files", "nyc3"}}},
+		{`"https://cdn-x.sgp1.cdn.digitaloceanspaces.com/a"`, []CloudStorage{{"do-spaces", "cdn-x.sgp1.cdn.digitaloceanspaces.com", "cdn-x", "sgp1"}}},
+		{`"https://0123456789abcdef0123456789abcdef.r2.cloudflarestorage.com/uploads/a"`, []CloudStorage{{"cloudflare-r2", "0123456789abcdef0123456789abcdef.r2.cloudflarestorage.com/uploads", "uploads", ""}}},
+		{`"https://pub-0123456789abcdef0123456789abcdef.r2.dev/a.png"`, []CloudStorage{{"cloudflare-r2", "pub-0123456789abcdef0123456789abcdef.r2.dev", "pub-0123456789abcdef0123456789abcdef", ""}}},
+		{`"https://www.googleapis.com/oauth2" "https://example.com/s3/amazonaws"`, nil},
+	} {
+		assert.Equal(t, tc.want, FindCloudStorage(tc.source), tc.source)
+	}
+
+	twice := `a="//s3.amazonaws.com/logs";b="//s3.amazonaws.com/logs"`
+	assert.Len(t, FindCloudStorage(twice), 1, "each location is reported once")
+}
+
+func TestGetAWSS3(t *testing.T) {
+	source := `<img src="https://assets.s3.amazonaws.com/logo.png"><a href="https://storage.googleapis.com/my-bucket/x.js">`
+	assert.Equal(t, []string{"assets.s3.amazonaws.com"}, GetAWSS3(source))
+	assert.True(t, AWSS3.MatchString(source))
+}
@@ -1,8 +1,14 @@
 package core
 
 import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/jaeles-project/gospider/core/antidetect"
+	"github.com/jaeles-project/gospider/core/report"
 	"github.com/spf13/cobra"
 )
 
@@ -10,49 +16,184 @@ import (
 type CrawlerConfig struct {
 	Site                     string
 	Sites                    string
+	Targets                  []Target
 	BurpFile                 string
+	BurpSitemap              string
+	BurpRequests             []JSRequest
 	Cookie                   string
 	UserAgent                string
 	Headers                  []string
 	Timeout                  time.Duration
+	HostTimeout              time.Duration
+	ReadTimeout              time.Duration
+	SlowHostThreshold        time.Duration
+	SlowHostSamples          int
+	SkipSlowHosts            bool
 	MaxDepth                 int
 	MaxConcurrency           int
+	JSConcurrency            int
+	MaxConnsPerHost          int
+	MaxIdleConns             int
+	ResolveOnce              bool
+	DNSCacheTTL              time.Duration
+	DNSPin                   bool
+	SNI                      string
+	RequestQueueSize         int
+	MaxQueueSize             int
+	RampUp                   time.Duration
+	RampUpRequests           int
+	RampFloor                int
+	RampShape                string
 	Threads                  int
+	Deterministic            bool
+	Seed                     int64
 	Delay                    time.Duration
 	RandomDelay              time.Duration
 	OutputDir                string
+	DedupOutput              string
+	SplitOutput              bool
+	OutputByHost             bool
+	HostOutputs              *HostOutputs
+	JSONLOutput              *Output
+	StdoutFormat             string
+	FileFormat               string
+	DedupFields              []string
 	Quiet                    bool
 	JSONOutput               bool
 	Length                   bool
 	Raw                      bool
 	Subs                     bool
 	OtherSource              bool
+	OtherSourceLimit         int
+	OtherSourceRPS           float64
 	IncludeSubs              bool
 	IncludeOtherSourceResult bool
 	NoRedirect               bool
+	MaxRedirects             int
+	RedirectChain            bool
 	Proxy                    string
+	ProxyList                []string
+	TestProxies              bool
+	RequireWorkingProxies    bool
+	ProxyTestURLs            []string
+	ProxyHealthInterval      time.Duration
+	ProxyRotateEvery         int
+	ProxyRotateInterval      time.Duration
 	Blacklist                string
 	Whitelist                string
 	WhitelistDomain          string
 	LinkFinder               bool
+	JSONLinkFinder           bool
+	JSAST                    bool
+	Wasm                     bool
 	Reflected                bool
 	Stealth                  bool
 	ReflectedOutput          string
+	ReplayFiles              bool
+	SARIFOutput              string
+	SARIF                    *report.SARIF
 	FilterLength             string
 	DomDedup                 bool
 	DomDedupThresh           int
 	BaselineFuzzCap          int
+	MutationDelayMin         time.Duration
+	MutationDelayMax         time.Duration
 	HybridCrawl              bool
 	HybridWorkers            int
+	HybridNavConcurrency     int
 	HybridNavigationTimeout  time.Duration
 	HybridStabilizationDelay time.Duration
+	HybridWait               string
+	HybridIdleWindow         time.Duration
 	HybridHeadless           bool
 	HybridInitScripts        []string
 	HybridVisitLimit         int
+	HybridSelective          bool
+	HybridMinLinks           int
+	HybridThinText           int
 	Intensity                string
 	Registry                 *URLRegistry
+	SinceModified            string
+	Validators               *ValidatorCache
+	ParamsOutput             string
+	ParamsSplit              bool
+	Params                   *ParamCollector
+	OpenAPIOutput            string
+	OpenAPIDraft             *OpenAPICollector
+	WordsOutput              string
+	SourceMapDir             string
+	WordsMinLength           int
+	WordsMinCount            int
+	Words                    *WordCollector
+	ProfileExtractors        bool
+	MetricsAddr              string
+	GlobalRPS                float64
+	Prioritize               bool
+	PriorityKeywords         []string
+	OutputTemplate           string
+	Postman                  string
+	PostmanEnv               string
+	PostmanOutput            string
+	PostmanExport            *PostmanExporter
+	HAR                      string
+	HARCapture               *HARCapture
+	APIRequests              []JSRequest
+	RateLimiter              *antidetect.RateLimiter
+	Profile                  *ExtractorProfile
+	Sink                     OutputSink
+	SinkSpecs                []string
+	WebhookURL               string
+	StatsOutput              string
+	Redis                    string
+	RedisRole                string
+	RedisPrefix              string
+	Frontier                 *Frontier
+	OutputSinks              []OutputSink
+	Pause                    *PauseGate
+	TUI                      bool
+	RequestTransform         RequestTransform
+	ResponseTransform        ResponseTransform
 	Sitemap                  bool
 	Robots                   bool
+	WellKnown                bool
+	WellKnownPaths           []string
+	OpenAPI                  bool
+	OpenAPIPaths             []string
+	CheckSensitive           bool
+	Dependencies             bool
+	Soft404                  bool
+	SensitiveFiles           []SensitiveFile
+	ContentMatches           []ContentMatch
+	SecretRules              []SecretRule
+	ObeyRobots               bool
+	ParseDocuments           bool
+	HeadFirst                bool
+	HeadFirstMaxSize         int64
+	VersionProbe             bool
+	VersionRules             map[string][]string
+	VersionBump              int
+	VersionProbeBudget       int
+	MaxPagesFollow           int
+	ReportErrors             bool
+	FailedOutput             string
+	Failed                   *FailedCollector
+	ReplayFailed             string
+	ReplayRequests           []FailedRequest
+	Resume                   string
+	State                    *CrawlState
+	InScopeOnly              bool
+	NormalizeUnicode         bool
+	ExcludeStatus            []int
+	IncludeStatus            []int
+	FollowLogout             bool
+	SessionHeartbeat         string
+	SessionHeartbeatInterval time.Duration
+	SessionLoggedOut         string
+	SessionPatterns          []string
+	DeadHostThreshold        int
+	TLSFallbackThreshold     int
+	URLAttributes            []string
+	AttributeMethods         map[string]string
 }
 
 // NewCrawlerConfig is a constructor for CrawlerConfig.
@@ -61,94 +202,577 @@ func NewCrawlerConfig(cmd *cobra.Command) CrawlerConfig {
 	site, _ := cmd.Flags().GetString("site")
 	sites, _ := cmd.Flags().GetString("sites")
 	burpFile, _ := cmd.Flags().GetString("burp")
+	burpSitemap, _ := cmd.Flags().GetString("burp-sitemap")
 	cookie, _ := cmd.Flags().GetString("cookie")
 	userAgent, _ := cmd.Flags().GetString("user-agent")
 	headers, _ := cmd.Flags().GetStringArray("header")
 	timeout, _ := cmd.Flags().GetInt("timeout")
+	hostTimeout, _ := cmd.Flags().GetInt("host-timeout")
+	readTimeout, _ := cmd.Flags().GetInt("read-timeout")
+	resolveOnce, _ := cmd.Flags().GetBool("resolve-once")
+	dnsCacheTTL, _ := cmd.Flags().GetInt("dns-cache-ttl")
+	dnsPin, _ := cmd.Flags().GetBool("dns-pin")
+	sni, _ := cmd.Flags().GetString("sni")
+	requestQueueSize, _ := cmd.Flags().GetInt("request-queue-size")
+	maxQueueSize, _ := cmd.Flags().GetInt("max-queue-size")
+	slowHostThreshold, _ := cmd.Flags().GetInt("slow-host-threshold")
+	slowHostSamples, _ := cmd.Flags().GetInt("slow-host-samples")
+	skipSlowHosts, _ := cmd.Flags().GetBool("skip-slow-hosts")
 	depth, _ := cmd.Flags().GetInt("depth")
 	concurrent, _ := cmd.Flags().GetInt("concurrent")
+	rampUp, _ := cmd.Flags().GetInt("ramp-up")
+	rampUpRequests, _ := cmd.Flags().GetInt("ramp-up-requests")
+	rampFloor, _ := cmd.Flags().GetInt("ramp-floor")
+	rampShape, _ := cmd.Flags().GetString("ramp-shape")
+	jsConcurrent, _ := cmd.Flags().GetInt("js-concurrent")
 	threads, _ := cmd.Flags().GetInt("threads")
+	deterministic, _ := cmd.Flags().GetBool("deterministic")
+	seed, _ := cmd.Flags().GetInt64("seed")
+	maxConnsPerHost, _ := cmd.Flags().GetInt("max-conns-per-host")
+	maxIdleConns, _ := cmd.Flags().GetInt("max-idle-conns")
 	delay, _ := cmd.Flags().GetInt("delay")
 	randomDelay, _ := cmd.Flags().GetInt("random-delay")
 	output, _ := cmd.Flags().GetString("output")
+	dedupOutput, _ := cmd.Flags().GetString("dedup-output")
+	dedupFields, _ := cmd.Flags().GetStringSlice("dedup-fields")
+	splitOutput, _ := cmd.Flags().GetBool("split-output")
+	outputByHost, _ := cmd.Flags().GetBool("output-by-host")
+	stdoutFormat, _ := cmd.Flags().GetString("stdout-format")
+	fileFormat, _ := cmd.Flags().GetString("file-format")
+	outputTemplate, _ := cmd.Flags().GetString("template")
 	quiet, _ := cmd.Flags().GetBool("quiet")
 	json, _ := cmd.Flags().GetBool("json")
 	length, _ := cmd.Flags().GetBool("length")
 	raw, _ := cmd.Flags().GetBool("raw")
 	subs, _ := cmd.Flags().GetBool("subs")
 	otherSource, _ := cmd.Flags().GetBool("other-source")
+	otherSourceLimit, _ := cmd.Flags().GetInt("other-source-limit")
+	otherSourceRPS, _ := cmd.Flags().GetFloat64("other-source-rps")
 	includeSubs, _ := cmd.Flags().GetBool("include-subs")
-	includeOtherSourceResult, _ := cmd.Flags().GetBool("include-other-source-result")
+	includeOtherSourceResult, _ := cmd.Flags().GetBool("include-other-source")
 	noRedirect, _ := cmd.Flags().GetBool("no-redirect")
+	maxRedirects, _ := cmd.Flags().GetInt("max-redirects")
+	redirectChain, _ := cmd.Flags().GetBool("redirect-chain")
 	proxy, _ := cmd.Flags().GetString("proxy")
+	proxyFile, _ := cmd.Flags().GetString("proxy-file")
+	testProxies, _ := cmd.Flags().GetBool("test-proxies")
+	requireWorkingProxies, _ := cmd.Flags().GetBool("require-working-proxies")
+	proxyTestURLs, _ := cmd.Flags().GetStringSlice("proxy-test-url")
+	proxyHealthInterval, _ := cmd.Flags().GetInt("proxy-health-interval")
+	proxyRotateEvery, _ := cmd.Flags().GetString("proxy-rotate-every")
 	blacklist, _ := cmd.Flags().GetString("blacklist")
 	whitelist, _ := cmd.Flags().GetString("whitelist")
 	whitelistDomain, _ := cmd.Flags().GetString("whitelist-domain")
-	linkfinder, _ := cmd.Flags().GetBool("linkfinder")
+	linkfinder, _ := cmd.Flags().GetBool("js")
+	jsonLinkfinder, _ := cmd.Flags().GetBool("json-linkfinder")
+	jsAST, _ := cmd.Flags().GetBool("js-ast")
+	wasm, _ := cmd.Flags().GetBool("wasm")
 	reflected, _ := cmd.Flags().GetBool("reflected")
 	stealth, _ := cmd.Flags().GetBool("stealth")
 	reflectedOutput, _ := cmd.Flags().GetString("reflected-output")
+	replayFiles, _ := cmd.Flags().GetBool("replay-files")
+	sarifOutput, _ := cmd.Flags().GetString("sarif")
+	sinkSpecs, _ := cmd.Flags().GetStringArray("sink")
+	webhookURL, _ := cmd.Flags().GetString("webhook-url")
+	statsOutput, _ := cmd.Flags().GetString("stats-output")
+	redisURL, _ := cmd.Flags().GetString("redis")
+	redisRole, _ := cmd.Flags().GetString("redis-role")
+	redisPrefix, _ := cmd.Flags().GetString("redis-prefix")
 	filterLength, _ := cmd.Flags().GetString("filter-length")
 	domDedup, _ := cmd.Flags().GetBool("dom-dedup")
 	domDedupThresh, _ := cmd.Flags().GetInt("dom-dedup-threshold")
 	baselineFuzzCap, _ := cmd.Flags().GetInt("baseline-fuzz-cap")
+	mutationDelay, _ := cmd.Flags().GetString("mutation-delay")
 	hybrid, _ := cmd.Flags().GetBool("hybrid")
 	hybridWorkers, _ := cmd.Flags().GetInt("hybrid-workers")
+	hybridNavConcurrency, _ := cmd.Flags().GetInt("hybrid-nav-concurrency")
 	hybridNavTimeout, _ := cmd.Flags().GetInt("hybrid-nav-timeout")
 	hybridStabilization, _ := cmd.Flags().GetInt("hybrid-stabilization")
+	hybridWait, _ := cmd.Flags().GetString("hybrid-wait")
+	hybridIdleWindow, _ := cmd.Flags().GetInt("hybrid-idle-window")
 	hybridHeadless, _ := cmd.Flags().GetBool("hybrid-headless")
 	hybridInitScripts, _ := cmd.Flags().GetStringSlice("hybrid-init-script")
 	hybridMaxVisits, _ := cmd.Flags().GetInt("hybrid-max-visits")
+	hybridSelective, _ := cmd.Flags().GetBool("hybrid-selective")
+	hybridMinLinks, _ := cmd.Flags().GetInt("hybrid-min-links")
+	hybridThinText, _ := cmd.Flags().GetInt("hybrid-thin-text")
 	sitemap, _ := cmd.Flags().GetBool("sitemap")
 	robots, _ := cmd.Flags().GetBool("robots")
+	obeyRobots, _ := cmd.Flags().GetBool("obey-robots")
+	parseDocuments, _ := cmd.Flags().GetBool("parse-documents")
+	headFirst, _ := cmd.Flags().GetBool("head-first")
+	headFirstMaxSize, _ := cmd.Flags().GetInt("head-first-max-size")
+	versionProbe, _ := cmd.Flags().GetBool("version-probe")
+	versionRuleValues, _ := cmd.Flags().GetStringSlice("version-rule")
+	versionBump, _ := cmd.Flags().GetInt("version-bump")
+	versionProbeBudget, _ := cmd.Flags().GetInt("version-probe-budget")
+	maxPagesFollow, _ := cmd.Flags().GetInt("max-pages-follow")
+	reportErrors, _ := cmd.Flags().GetBool("report-errors")
+	failedOutput, _ := cmd.Flags().GetString("failed-output")
+	replayFailed, _ := cmd.Flags().GetString("replay-failed")
+	resume, _ := cmd.Flags().GetString("resume")
+	inScopeOnly, _ := cmd.Flags().GetBool("in-scope-only")
+	normalizeUnicode, _ := cmd.Flags().GetBool("normalize-unicode")
+	profileExtractors, _ := cmd.Flags().GetBool("profile-extractors")
+	metricsAddr, _ := cmd.Flags().GetString("metrics-addr")
+	tui, _ := cmd.Flags().GetBool("tui")
+	postman, _ := cmd.Flags().GetString("postman")
+	postmanEnv, _ := cmd.Flags().GetString("postman-env")
+	postmanOutput, _ := cmd.Flags().GetString("postman-out")
+	har, _ := cmd.Flags().GetString("har")
+	globalRPS, _ := cmd.Flags().GetFloat64("global-rps")
+	prioritize, _ := cmd.Flags().GetBool("prioritize")
+	priorityKeywords, _ := cmd.Flags().GetStringSlice("priority-keywords")
+	excludeStatusValues, _ := cmd.Flags().GetStringSlice("exclude-status")
+	includeStatusValues, _ := cmd.Flags().GetStringSlice("include-status")
+	followLogout, _ := cmd.Flags().GetBool("follow-logout")
+	sessionHeartbeat, _ := cmd.Flags().GetString("session-heartbeat")
+	sessionHeartbeatInterval, _ := cmd.Flags().GetInt("session-heartbeat-interval")
+	sessionLoggedOut, _ := cmd.Flags().GetString("session-logged-out")
+	sessionPatterns, _ := cmd.Flags().GetStringSlice("session-pattern")
+	deadHostThreshold, _ := cmd.Flags().GetInt("dead-host-threshold")
+	tlsFallbackThreshold, _ := cmd.Flags().GetInt("tls-fallback-threshold")
+	sinceModified, _ := cmd.Flags().GetString("since-modified")
+	paramsOutput, _ := cmd.Flags().GetString("params-output")
+	paramsSplit, _ := cmd.Flags().GetBool("params-split")
+	openAPIOutput, _ := cmd.Flags().GetString("openapi-out")
+	wordsOutput, _ := cmd.Flags().GetString("words-output")
+	sourceMapDir, _ := cmd.Flags().GetString("sourcemap-dir")
+	wordsMinLength, _ := cmd.Flags().GetInt("words-min-length")
+	wordsMinCount, _ := cmd.Flags().GetInt("words-min-count")
+	wellKnown, _ := cmd.Flags().GetBool("well-known")
+	wellKnownPaths, _ := cmd.Flags().GetStringSlice("well-known-path")
+	openAPI, _ := cmd.Flags().GetBool("openapi")
+	openAPIPaths, _ := cmd.Flags().GetStringSlice("openapi-path")
+	checkSensitive, _ := cmd.Flags().GetBool("check-sensitive")
+	dependencies, _ := cmd.Flags().GetBool("dependencies")
+	soft404, _ := cmd.Flags().GetBool("soft-404")
+	sensitiveList, _ := cmd.Flags().GetString("sensitive-list")
+	matchRegexes, _ := cmd.Flags().GetStringArray("match-regex")
+	matchFile, _ := cmd.Flags().GetString("match-file")
+	secretRulesFile, _ := cmd.Flags().GetString("secret-rules")
+	urlAttributes, _ := cmd.Flags().GetStringSlice("url-attr")
+	attrMethods, _ := cmd.Flags().GetStringSlice("attr-method")
 
 	if reflectedOutput != "" {
 		reflected = true
 	}
 
+	if deterministic {
+		concurrent = 1
+		jsConcurrent = 1
+		threads = 1
+		randomDelay = 0
+	}
+
+	switch hybridWait {
+	case "", HybridWaitLoad, HybridWaitNetworkIdle:
+	default:
+		Logger.Warnf("Invalid --hybrid-wait %q, expected %s or %s; waiting for load", hybridWait, HybridWaitLoad, HybridWaitNetworkIdle)
+		hybridWait = HybridWaitLoad
+	}
+
+	switch rampShape {
+	case "", RampShapeLinear, RampShapeExponential:
+	default:
+		Logger.Warnf("Invalid --ramp-shape %q, expected %s or %s; ramping linearly", rampShape, RampShapeLinear, RampShapeExponential)
+		rampShape = RampShapeLinear
+	}
+
+	switch dedupOutput {
+	case "", DedupOutputStream, DedupOutputFinal:
+	default:
+		Logger.Warnf("Invalid --dedup-output %q, expected %s or %s; disabling", dedupOutput, DedupOutputStream, DedupOutputFinal)
+		dedupOutput = ""
+	}
+	stdoutFormat, fileFormat, err := ResolveOutputFormats(stdoutFormat, fileFormat, json, quiet, output != "")
+	if err != nil {
+		Logger.Warnf("%s; falling back to --json/--quiet", err)
+		stdoutFormat, fileFormat, _ = ResolveOutputFormats("", "", json, quiet, output != "")
+	}
+	if _, err := ParseOutputTemplate(outputTemplate); err != nil {
+		Logger.Warnf("%s; using the built-in format", err)
+		outputTemplate = ""
+	}
+	if redisRole != RedisRoleCoordinator && redisRole != RedisRoleWorker {
+		Logger.Warnf("invalid --redis-role %q, expected %s or %s; using %s", redisRole, RedisRoleCoordinator, RedisRoleWorker, RedisRoleWorker)
+		redisRole = RedisRoleWorker
+	}
+	if splitOutput && output == "" {
+		Logger.Warnf("--split-output has no effect without -o")
+	}
+	if outputByHost && output == "" {
+		Logger.Warnf("--output-by-host has no effect without -o")
+	}
+
+	attributeMethods, err := ParseAttributeMethods(attrMethods)
+	if err != nil {
+		Logger.Warnf("Invalid --attr-method: %s, using defaults", err)
+		attributeMethods, _ = ParseAttributeMethods(DefaultAttributeMethods)
+	}
+
+	versionRules, err := ParseVersionRules(versionRuleValues)
+	if err != nil {
+		Logger.Warnf("Invalid --version-rule: %s, using defaults", err)
+		versionRules, _ = ParseVersionRules(DefaultVersionRules)
+	}
+
+	excludeStatus, err := ParseStatusCodes(excludeStatusValues)
+	if err != nil {
+		Logger.Warnf("Invalid --exclude-status: %s; not excluding any status", err)
+	}
+	includeStatus, err := ParseStatusCodes(includeStatusValues)
+	if err != nil {
+		Logger.Warnf("Invalid --include-status: %s; not restricting statuses", err)
+	}
+
+	sensitiveFiles := DefaultSensitiveFiles
+	if sensitiveList != "" {
+		if files, err := LoadSensitiveFiles(sensitiveList); err != nil {
+			Logger.Warnf("Invalid --sensitive-list: %s; using the default list", err)
+		} else {
+			sensitiveFiles = files
+		}
+	}
+
+	contentMatches, err := ParseContentMatches(matchRegexes)
+	if err != nil {
+		Logger.Warnf("Invalid --match-regex: %s; not matching any --match-regex pattern", err)
+		contentMatches = nil
+	}
+	if matchFile != "" {
+		if matches, err := LoadContentMatches(matchFile); err != nil {
+			Logger.Warnf("Invalid --match-file: %s; ignoring it", err)
+		} else {
+			contentMatches = append(contentMatches, matches...)
+		}
+	}
+	var secretRules []SecretRule
+	if secretRulesFile != "" {
+		if rules, err := LoadSecretRules(secretRulesFile); err != nil {
+			Logger.Warnf("Invalid --secret-rules: %s; using the built-in rules only", err)
+		} else {
+			secretRules = rules
+		}
+	}
+
+	var proxyList []string
+	if proxyFile != "" {
+		var invalid []string
+		proxyList, invalid = ParseProxyList(ReadingLines(proxyFile))
+		for _, line := range invalid {
+			Logger.Warnf("Skipping invalid proxy %q in %s", line, proxyFile)
+		}
+		if len(proxyList) == 0 {
+			Logger.Warnf("No proxies loaded from %s", proxyFile)
+		}
+	}
+
+	rotateEvery, rotateInterval, err := ParseRotateEvery(proxyRotateEvery)
+	if err != nil {
+		Logger.Warnf("Invalid --proxy-rotate-every %q: %s, rotating on errors only", proxyRotateEvery, err)
+	}
+
+	mutationDelayMin, mutationDelayMax, err := ParseDelayRange(mutationDelay)
+	if err != nil {
+		Logger.Warnf("Invalid --mutation-delay %q: %s, using default", mutationDelay, err)
+		mutationDelayMin, mutationDelayMax = defaultMutationDelayMin, defaultMutationDelayMax
+	}
+
 	return CrawlerConfig{
 		Site:                     site,
 		Sites:                    sites,
 		BurpFile:                 burpFile,
+		BurpSitemap:              burpSitemap,
 		Cookie:                   cookie,
 		UserAgent:                userAgent,
 		Headers:                  headers,
 		Timeout:                  time.Duration(timeout) * time.Second,
+		HostTimeout:              time.Duration(hostTimeout) * time.Second,
+		ReadTimeout:              time.Duration(readTimeout) * time.Second,
+		SlowHostThreshold:        time.Duration(slowHostThreshold) * time.Millisecond,
+		SlowHostSamples:          slowHostSamples,
+		SkipSlowHosts:            skipSlowHosts,
 		MaxDepth:                 depth,
 		MaxConcurrency:           concurrent,
+		JSConcurrency:            jsConcurrent,
 		Threads:                  threads,
+		Deterministic:            deterministic,
+		Seed:                     seed,
+		MaxConnsPerHost:          maxConnsPerHost,
+		MaxIdleConns:             maxIdleConns,
+		ResolveOnce:              resolveOnce,
+		DNSCacheTTL:              time.Duration(dnsCacheTTL) * time.Second,
+		DNSPin:                   dnsPin,
+		SNI:                      strings.TrimSpace(sni),
+		RequestQueueSize:         requestQueueSize,
+		MaxQueueSize:             maxQueueSize,
+		RampUp:                   time.Duration(rampUp) * time.Second,
+		RampUpRequests:           rampUpRequests,
+		RampFloor:                rampFloor,
+		RampShape:                rampShape,
 		Delay:                    time.Duration(delay) * time.Second,
 		RandomDelay:              time.Duration(randomDelay) * time.Second,
 		OutputDir:                output,
+		DedupOutput:              dedupOutput,
+		DedupFields:              dedupFields,
+		SplitOutput:              splitOutput,
+		OutputByHost:             outputByHost,
+		StdoutFormat:             stdoutFormat,
+		FileFormat:               fileFormat,
+		OutputTemplate:           outputTemplate,
+		Postman:                  postman,
+		PostmanEnv:               postmanEnv,
+		PostmanOutput:            postmanOutput,
+		HAR:                      har,
 		Quiet:                    quiet,
 		JSONOutput:               json,
 		Length:                   length,
 		Raw:                      raw,
 		Subs:                     subs,
 		OtherSource:              otherSource,
+		OtherSourceLimit:         otherSourceLimit,
+		OtherSourceRPS:           otherSourceRPS,
 		IncludeSubs:              includeSubs,
 		IncludeOtherSourceResult: includeOtherSourceResult,
 		NoRedirect:               noRedirect,
+		MaxRedirects:             maxRedirects,
+		RedirectChain:            redirectChain,
 		Proxy:                    proxy,
+		ProxyList:                proxyList,
+		TestProxies:              testProxies,
+		RequireWorkingProxies:    requireWorkingProxies,
+		ProxyTestURLs:            proxyTestURLs,
+		ProxyHealthInterval:      time.Duration(proxyHealthInterval) * time.Second,
+		ProxyRotateEvery:         rotateEvery,
+		ProxyRotateInterval:      rotateInterval,
 		Blacklist:                blacklist,
 		Whitelist:                whitelist,
 		WhitelistDomain:          whitelistDomain,
 		LinkFinder:               linkfinder,
+		JSONLinkFinder:           jsonLinkfinder,
+		JSAST:                    jsAST,
+		Wasm:                     wasm,
 		Reflected:                reflected,
 		Stealth:                  stealth,
 		ReflectedOutput:          reflectedOutput,
+		ReplayFiles:              replayFiles,
+		SARIFOutput:              sarifOutput,
+		SinkSpecs:                sinkSpecs,
+		WebhookURL:               webhookURL,
+		StatsOutput:              statsOutput,
+		Redis:                    redisURL,
+		RedisRole:                redisRole,
+		RedisPrefix:              redisPrefix,
 		FilterLength:             filterLength,
 		DomDedup:                 domDedup,
 		DomDedupThresh:           domDedupThresh,
 		BaselineFuzzCap:          baselineFuzzCap,
+		MutationDelayMin:         mutationDelayMin,
+		MutationDelayMax:         mutationDelayMax,
 		HybridCrawl:              hybrid,
 		HybridWorkers:            hybridWorkers,
+		HybridNavConcurrency:     hybridNavConcurrency,
 		HybridNavigationTimeout:  time.Duration(hybridNavTimeout) * time.Second,
 		HybridStabilizationDelay: time.Duration(hybridStabilization) * time.Millisecond,
+		HybridWait:               hybridWait,
+		HybridIdleWindow:         time.Duration(hybridIdleWindow) * time.Millisecond,
 		HybridHeadless:           hybridHeadless,
 		HybridInitScripts:        hybridInitScripts,
 		HybridVisitLimit:         hybridMaxVisits,
+		HybridSelective:          hybridSelective,
+		HybridMinLinks:           hybridMinLinks,
+		HybridThinText:           hybridThinText,
 		Sitemap:                  sitemap,
 		Robots:                   robots,
+		ObeyRobots:               obeyRobots,
+		ParseDocuments:           parseDocuments,
+		HeadFirst:                headFirst,
+		HeadFirstMaxSize:         int64(headFirstMaxSize) * 1024,
+		VersionProbe:             versionProbe,
+		VersionRules:             versionRules,
+		VersionBump:              versionBump,
+		VersionProbeBudget:       versionProbeBudget,
+		MaxPagesFollow:           maxPagesFollow,
+		ReportErrors:             reportErrors,
+		FailedOutput:             failedOutput,
+		ReplayFailed:             replayFailed,
+		Resume:                   resume,
+		InScopeOnly:              inScopeOnly,
+		NormalizeUnicode:         normalizeUnicode,
+		ProfileExtractors:        profileExtractors,
+		MetricsAddr:              metricsAddr,
+		TUI:                      tui,
+		GlobalRPS:                globalRPS,
+		Prioritize:               prioritize,
+		PriorityKeywords:         priorityKeywords,
+		ExcludeStatus:            excludeStatus,
+		IncludeStatus:            includeStatus,
+		FollowLogout:             followLogout,
+		SessionHeartbeat:         strings.TrimSpace(sessionHeartbeat),
+		SessionHeartbeatInterval: time.Duration(sessionHeartbeatInterval) * time.Second,
+		SessionLoggedOut:         sessionLoggedOut,
+		SessionPatterns:          sessionPatterns,
+		DeadHostThreshold:        deadHostThreshold,
+		TLSFallbackThreshold:     tlsFallbackThreshold,
+		SinceModified:            sinceModified,
+		ParamsOutput:             paramsOutput,
+		ParamsSplit:              paramsSplit,
+		OpenAPIOutput:            openAPIOutput,
+		WordsOutput:              wordsOutput,
+		SourceMapDir:             sourceMapDir,
+		WordsMinLength:           wordsMinLength,
+		WordsMinCount:            wordsMinCount,
+		WellKnown:                wellKnown,
+		WellKnownPaths:           wellKnownPaths,
+		OpenAPI:                  openAPI,
+		OpenAPIPaths:             openAPIPaths,
+		CheckSensitive:           checkSensitive,
+		Dependencies:             dependencies,
+		Soft404:                  soft404,
+		SensitiveFiles:           sensitiveFiles,
+		ContentMatches:           contentMatches,
+		SecretRules:              secretRules,
+		URLAttributes:            urlAttributes,
+		AttributeMethods:         attributeMethods,
+	}
+}
+
+const (
+	defaultMutationDelayMin = 50 * time.Millisecond
+	defaultMutationDelayMax = 170 * time.Millisecond
+)
+
+// ParseDelayRange parses a "min,max" millisecond range. A single value is used
+// for both bounds and "0" disables the delay entirely.
+func ParseDelayRange(raw string) (time.Duration, time.Duration, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return defaultMutationDelayMin, defaultMutationDelayMax, nil
+	}
+	parts := strings.SplitN(raw, ",", 2)
+	minMs, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("parse min delay: %w", err)
+	}
+	maxMs := minMs
+	if len(parts) == 2 {
+		if maxMs, err = strconv.Atoi(strings.TrimSpace(parts[1])); err != nil {
+			return 0, 0, fmt.Errorf("parse max delay: %w", err)
+		}
+	}
+	if minMs < 0 || maxMs < 0 {
+		return 0, 0, fmt.Errorf("delay must not be negative")
+	}
+	if maxMs < minMs {
+		minMs, maxMs = maxMs, minMs
+	}
+	return time.Duration(minMs) * time.Millisecond, time.Duration(maxMs) * time.Millisecond, nil
+}
+
+// ParseProxyList parses the lines of a --proxy-file. Lines starting with #
+// are comments, and a host:port without a scheme is an HTTP proxy. Lines
+// that are not a proxy URL with a host and port are returned as invalid
+func ParseProxyList(lines []string) (proxies, invalid []string) {
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		raw := line
+		if !strings.Contains(raw, "://") {
+			raw = "http://" + raw
+		}
+		u, err := url.Parse(raw)
+		if err != nil || u.Hostname() == "" || u.Port() == "" {
+			invalid = append(invalid, line)
+			continue
+		}
+		switch u.Scheme {
+		case "http", "https", "socks4", "socks5", "socks5h":
+			proxies = append(proxies, raw)
+		default:
+			invalid = append(invalid, line)
+		}
+	}
+	return proxies, invalid
+}
+
+// ParseRotateEvery parses the --proxy-rotate-every value, either a request
+// count ("50") or a duration ("30s")
+func ParseRotateEvery(raw string) (int, time.Duration, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, 0, nil
+	}
+	if n, err := strconv.Atoi(raw); err == nil {
+		if n < 0 {
+			return 0, 0, fmt.Errorf("request count must not be negative")
+		}
+		return n, 0, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, 0, fmt.Errorf("expected a request count or duration")
+	}
+	if d < 0 {
+		return 0, 0, fmt.Errorf("duration must not be negative")
+	}
+	return 0, d, nil
+}
+
+// ParseStatusCodes parses --exclude-status and --include-status values such
+// as "404" into HTTP status codes
+func ParseStatusCodes(values []string) ([]int, error) {
+	var codes []int
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		code, err := strconv.Atoi(value)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("expected a status code between 100 and 599, got %q", value)
+		}
+		codes = append(codes, code)
 	}
-}
\ No newline at end of file
+	return codes, nil
+}
+
+// ParseAttributeMethods parses --attr-method values of the form "hx-get=GET"
+// into a map of lower-cased attribute name to upper-cased method
+func ParseAttributeMethods(values []string) (map[string]string, error) {
+	methods := make(map[string]string, len(values))
+	for _, value := range values {
+		name, method, ok := strings.Cut(value, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		method = strings.ToUpper(strings.TrimSpace(method))
+		if !ok || name == "" || method == "" {
+			return nil, fmt.Errorf("expected attribute=METHOD, got %q", value)
+		}
+		methods[name] = method
+	}
+	return methods, nil
+}
+
+// ParseVersionRules parses --version-rule values of the form
+// "api=api/internal|api/beta" into a map of lower-cased path segment to the
+// paths probed in its place
+func ParseVersionRules(values []string) (map[string][]string, error) {
+	rules := make(map[string][]string, len(values))
+	for _, value := range values {
+		segment, replacements, ok := strings.Cut(value, "=")
+		segment = strings.ToLower(strings.Trim(strings.TrimSpace(segment), "/"))
+		if !ok || segment == "" || strings.Contains(segment, "/") {
+			return nil, fmt.Errorf("expected segment=path|path, got %q", value)
+		}
+		for _, replacement := range strings.Split(replacements, "|") {
+			replacement = strings.Trim(strings.TrimSpace(replacement), "/")
+			if replacement == "" {
+				return nil, fmt.Errorf("empty replacement in %q", value)
+			}
+			rules[segment] = append(rules[segment], replacement)
+		}
+	}
+	return rules, nil
+}
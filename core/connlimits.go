@@ -0,0 +1,38 @@
+package core
+
+const (
+	// The anti-detect connection pool defaults, kept while the open file
+	// limit has room for them
+	defaultMaxConnsPerHost = 6
+	defaultMaxIdleConns    = 100
+	// fdReserve is kept free for output files, DNS and the browser
+	fdReserve = 64
+)
+
+// ResolveConnLimits lowers unset connection caps to fit in the process open
+// file limit, shared between the crawlers running in parallel. An unset cap
+// the limit has room for stays 0, so the HTTP client keeps its own default.
+// Explicit values are returned unchanged.
+func ResolveConnLimits(maxConnsPerHost, maxIdleConns, threads int) (int, int) {
+	return resolveConnLimits(maxConnsPerHost, maxIdleConns, threads, openFileLimit())
+}
+
+func resolveConnLimits(maxConnsPerHost, maxIdleConns, threads, fileLimit int) (int, int) {
+	if fileLimit <= 0 {
+		return maxConnsPerHost, maxIdleConns
+	}
+	if threads <= 0 {
+		threads = 1
+	}
+	budget := (fileLimit - fdReserve) / threads
+	if budget < 2 {
+		budget = 2
+	}
+	if maxConnsPerHost <= 0 && budget/2 < defaultMaxConnsPerHost {
+		maxConnsPerHost = budget / 2
+	}
+	if maxIdleConns <= 0 && budget/2 < defaultMaxIdleConns {
+		maxIdleConns = budget / 2
+	}
+	return maxConnsPerHost, maxIdleConns
+}
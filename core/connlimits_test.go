@@ -0,0 +1,25 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveConnLimits(t *testing.T) {
+	perHost, idle := resolveConnLimits(3, 7, 4, 256)
+	assert.Equal(t, 3, perHost)
+	assert.Equal(t, 7, idle)
+
+	perHost, idle = resolveConnLimits(0, 0, 1, 1024)
+	assert.Zero(t, perHost, "the client keeps its default when the file limit has room")
+	assert.Zero(t, idle)
+
+	perHost, idle = resolveConnLimits(0, 0, 8, 128)
+	assert.Equal(t, 4, perHost)
+	assert.Equal(t, 4, idle)
+
+	perHost, idle = resolveConnLimits(0, 0, 1, 0)
+	assert.Zero(t, perHost)
+	assert.Zero(t, idle)
+}
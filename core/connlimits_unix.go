@@ -0,0 +1,17 @@
+//go:build !windows
+
+package core
+
+import "syscall"
+
+// openFileLimit returns the soft RLIMIT_NOFILE, or 0 when it is unknown
+func openFileLimit() int {
+	var rlim syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlim); err != nil {
+		return 0
+	}
+	if rlim.Cur > 1<<20 {
+		return 1 << 20
+	}
+	return int(rlim.Cur)
+}
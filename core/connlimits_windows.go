@@ -0,0 +1,8 @@
+//go:build windows
+
+package core
+
+// openFileLimit is not detectable on Windows; callers fall back to defaults
+func openFileLimit() int {
+	return 0
+}
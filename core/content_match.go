@@ -0,0 +1,146 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+)
+
+const (
+	// maxContentMatchPattern caps the length of a --match-regex pattern
+	maxContentMatchPattern = 1024
+	// maxContentMatchInsts caps the compiled size of a pattern. Go regexps run
+	// in linear time, but nested counted repetitions such as (a{100}){100}
+	// compile into huge programs that make every body scan slow
+	maxContentMatchInsts = 20000
+	// maxContentMatchSnippet caps the matched text kept in a finding
+	maxContentMatchSnippet = 120
+)
+
+var contentMatchNameRegex = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// ContentMatch is a named pattern searched in every response body by
+// --match-regex and --match-file
+type ContentMatch struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// CompileContentMatch compiles a pattern, rejecting ones that match the
+// empty string, and so every page, and ones too large to scan every body with
+func CompileContentMatch(name, expr string) (ContentMatch, error) {
+	if !contentMatchNameRegex.MatchString(name) {
+		return ContentMatch{}, fmt.Errorf("invalid pattern name %q", name)
+	}
+	if len(expr) > maxContentMatchPattern {
+		return ContentMatch{}, fmt.Errorf("%s: pattern longer than %d characters", name, maxContentMatchPattern)
+	}
+	parsed, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return ContentMatch{}, fmt.Errorf("%s: %w", name, err)
+	}
+	prog, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return ContentMatch{}, fmt.Errorf("%s: %w", name, err)
+	}
+	if len(prog.Inst) > maxContentMatchInsts {
+		return ContentMatch{}, fmt.Errorf("%s: pattern too complex", name)
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return ContentMatch{}, fmt.Errorf("%s: %w", name, err)
+	}
+	if re.MatchString("") {
+		return ContentMatch{}, fmt.Errorf("%s: pattern matches the empty string", name)
+	}
+	return ContentMatch{Name: name, Pattern: re}, nil
+}
+
+// ParseContentMatches parses --match-regex values. A value is either
+// name=regex or a bare regex, which is named regex-N after its position
+func ParseContentMatches(values []string) ([]ContentMatch, error) {
+	var matches []ContentMatch
+	for i, value := range values {
+		name, expr := "regex-"+strconv.Itoa(i+1), value
+		if idx := strings.Index(value, "="); idx > 0 && contentMatchNameRegex.MatchString(value[:idx]) {
+			name, expr = value[:idx], value[idx+1:]
+		}
+		match, err := CompileContentMatch(name, expr)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, match)
+	}
+	return matches, nil
+}
+
+// LoadContentMatches reads a --match-file. Each line holds a name, then
+// whitespace and the regex; blank lines and lines starting with # are skipped
+func LoadContentMatches(filename string) ([]ContentMatch, error) {
+	var matches []ContentMatch
+	for i, line := range ReadingLines(filename) {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		idx := strings.IndexAny(line, " \t")
+		if idx == -1 {
+			return nil, fmt.Errorf("%s: entry %d: expected a name and a regex", filename, i+1)
+		}
+		match, err := CompileContentMatch(line[:idx], strings.TrimSpace(line[idx+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("%s: entry %d: %w", filename, i+1, err)
+		}
+		matches = append(matches, match)
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%s: no patterns found", filename)
+	}
+	return matches, nil
+}
+
+// registerContentMatch searches the bodies of pages and of the scripts
+// fetched for LinkFinder for the configured patterns
+func (crawler *Crawler) registerContentMatch() {
+	if len(crawler.contentMatches) == 0 {
+		return
+	}
+	handler := func(response *colly.Response) {
+		if response.Request == nil || response.Request.URL == nil || crawler.stopped.Load() {
+			return
+		}
+		crawler.matchContent(response.Request.URL.String(), response.StatusCode, DecodeChars(string(response.Body)), response.Request.Depth)
+	}
+	crawler.C.OnResponse(handler)
+	crawler.LinkFinderCollector.OnResponse(handler)
+}
+
+// matchContent reports the first match of each pattern in body, once per
+// pattern and URL
+func (crawler *Crawler) matchContent(rawURL string, status int, body string, depth int) {
+	u := NormalizeDisplayURL(rawURL)
+	for _, match := range crawler.contentMatches {
+		found := match.Pattern.FindString(body)
+		if found == "" || crawler.matchSet.Duplicate(match.Name+"|"+u) {
+			continue
+		}
+		snippet := strings.Join(strings.Fields(found), " ")
+		if len(snippet) > maxContentMatchSnippet {
+			snippet = snippet[:maxContentMatchSnippet]
+		}
+		sout := SpiderOutput{
+			Input:      crawler.Input,
+			Source:     "body",
+			OutputType: "match",
+			StatusCode: status,
+			Output:     u,
+			Param:      match.Name,
+			Snippet:    snippet,
+			Depth:      depth,
+		}
+		crawler.emit(sout, fmt.Sprintf("[match:%s] - [code-%d] - %s - %s", match.Name, status, u, snippet), u)
+	}
+}
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseContentMatches(t *testing.T) {
+	matches, err := ParseContentMatches([]string{"aws=AKIA[0-9A-Z]{16}", `a=b\d+`, "[0-9]{3}-[0-9]{4}"})
+	require.NoError(t, err)
+	require.Len(t, matches, 3)
+	assert.Equal(t, "aws", matches[0].Name)
+	assert.Equal(t, "a", matches[1].Name)
+	assert.Equal(t, "regex-3", matches[2].Name)
+	assert.Equal(t, "[0-9]{3}-[0-9]{4}", matches[2].Pattern.String())
+
+	for _, expr := range []string{"x=[unclosed", "x=.*", "x=a?", "x=((a{1000}){1000}){1000}"} {
+		_, err := ParseContentMatches([]string{expr})
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestLoadContentMatches(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "matches.txt")
+	require.NoError(t, os.WriteFile(file, []byte("# patterns\nstacktrace\tException in thread \"main\"\n\njquery   jQuery v1\\.[0-9.]+\n"), 0o644))
+
+	matches, err := LoadContentMatches(file)
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	assert.Equal(t, "stacktrace", matches[0].Name)
+	assert.Equal(t, `Exception in thread "main"`, matches[0].Pattern.String())
+	assert.Equal(t, "jquery", matches[1].Name)
+
+	require.NoError(t, os.WriteFile(file, []byte("lonely\n"), 0o644))
+	_, err = LoadContentMatches(file)
+	assert.Error(t, err)
+}
+
+func TestCrawlContentMatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><a href="/error">e</a><a href="/plain">p</a><script src="/app.js"></script></body></html>`)
+		case "/error":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, "<html><body><pre>Exception in thread \"main\"\n java.lang.NullPointerException\nException in thread \"main\"</pre></body></html>")
+		case "/app.js":
+			w.Header().Set("Content-Type", "application/javascript")
+			fmt.Fprint(w, `/*! jQuery v1.8.3 jquery.com */ var a = 1;`)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body>nothing here</body></html>`)
+		}
+	}))
+	defer srv.Close()
+
+	matches, err := ParseContentMatches([]string{"stacktrace=Exception in thread \"main\"\\s+[\\w.]+", `jquery=jQuery v1\.[0-9.]+`})
+	require.NoError(t, err)
+
+	results := crawlAll(t, srv.URL, CrawlerConfig{LinkFinder: true, ContentMatches: matches})
+
+	found := map[string]SpiderOutput{}
+	for _, sout := range results {
+		if sout.OutputType == "match" {
+			_, dup := found[sout.Param+" "+sout.Output]
+			assert.False(t, dup, "duplicate match %s %s", sout.Param, sout.Output)
+			found[sout.Param+" "+sout.Output] = sout
+		}
+	}
+	require.Len(t, found, 2)
+	stacktrace := found["stacktrace "+srv.URL+"/error"]
+	assert.Equal(t, http.StatusOK, stacktrace.StatusCode)
+	assert.Equal(t, `Exception in thread "main" java.lang.NullPointerException`, stacktrace.Snippet)
+	assert.Equal(t, "jQuery v1.8.3", found["jquery "+srv.URL+"/app.js"].Snippet)
+}
@@ -0,0 +1,430 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// crawlStateFlush is how often buffered --resume changes are written
+	crawlStateFlush = 2 * time.Second
+	// stateKeyCtx carries the --resume key of a request through colly
+	stateKeyCtx = "__state_key"
+	// hybridStatePrefix sets hybrid pages apart from requests in a site's
+	// pending bucket; request keys start with an upper case method
+	hybridStatePrefix = "hybrid "
+)
+
+var (
+	stateSeenBucket    = []byte("seen")
+	stateSitesBucket   = []byte("sites")
+	statePendingBucket = []byte("pending")
+
+	stateStarted = []byte("started")
+	stateDone    = []byte("done")
+)
+
+// CrawlState is the --resume state file. It holds every URL the run has
+// seen, which sites were started and finished, and per site the visits,
+// generated requests and hybrid pages still outstanding, so an interrupted
+// run picks up where it stopped. Changes are buffered and written every
+// crawlStateFlush, and on Close
+type CrawlState struct {
+	db   *bolt.DB
+	path string
+
+	mu      sync.Mutex
+	seen    []string
+	sites   map[string][]byte
+	pending map[string]map[string][]byte
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// OpenCrawlState opens or creates the state file at path
+func OpenCrawlState(path string) (*CrawlState, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	// Buffered writes reach the file when flushed; only the fsync waits
+	// for Close, which is enough to survive the process being killed
+	db.NoSync = true
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{stateSeenBucket, stateSitesBucket, statePendingBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	s := &CrawlState{
+		db:      db,
+		path:    path,
+		sites:   make(map[string][]byte),
+		pending: make(map[string]map[string][]byte),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go s.flushLoop()
+	return s, nil
+}
+
+func (s *CrawlState) flushLoop() {
+	defer close(s.done)
+	ticker := time.NewTicker(crawlStateFlush)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.flush(); err != nil {
+				Logger.Errorf("Failed to write crawl state %s: %s", s.path, err)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// flush writes the buffered changes in one transaction. When the write
+// fails they are put back for the next flush
+func (s *CrawlState) flush() error {
+	s.mu.Lock()
+	seen, sites, pending := s.seen, s.sites, s.pending
+	s.seen = nil
+	s.sites = make(map[string][]byte)
+	s.pending = make(map[string]map[string][]byte)
+	s.mu.Unlock()
+	if len(seen) == 0 && len(sites) == 0 && len(pending) == 0 {
+		return nil
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		seenBucket := tx.Bucket(stateSeenBucket)
+		for _, key := range seen {
+			if err := seenBucket.Put([]byte(key), nil); err != nil {
+				return err
+			}
+		}
+		sitesBucket := tx.Bucket(stateSitesBucket)
+		for site, status := range sites {
+			if err := sitesBucket.Put([]byte(site), status); err != nil {
+				return err
+			}
+		}
+		for site, changes := range pending {
+			bucket, err := tx.Bucket(statePendingBucket).CreateBucketIfNotExists([]byte(site))
+			if err != nil {
+				return err
+			}
+			for key, value := range changes {
+				if value == nil {
+					err = bucket.Delete([]byte(key))
+				} else {
+					err = bucket.Put([]byte(key), value)
+				}
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.restore(seen, sites, pending)
+	}
+	return err
+}
+
+// restore merges changes whose flush failed back into the buffers. Changes
+// buffered since then are newer and win
+func (s *CrawlState) restore(seen []string, sites map[string][]byte, pending map[string]map[string][]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seen = append(seen, s.seen...)
+	for site, status := range sites {
+		if _, newer := s.sites[site]; !newer {
+			s.sites[site] = status
+		}
+	}
+	for site, changes := range pending {
+		current := s.pending[site]
+		if current == nil {
+			s.pending[site] = changes
+			continue
+		}
+		for key, value := range changes {
+			if _, newer := current[key]; !newer {
+				current[key] = value
+			}
+		}
+	}
+}
+
+// Close writes what is buffered and closes the file. It is nil-safe
+func (s *CrawlState) Close() error {
+	if s == nil {
+		return nil
+	}
+	var err error
+	s.once.Do(func() {
+		close(s.stop)
+		<-s.done
+		err = s.flush()
+		if syncErr := s.db.Sync(); err == nil {
+			err = syncErr
+		}
+		if closeErr := s.db.Close(); err == nil {
+			err = closeErr
+		}
+	})
+	return err
+}
+
+// Attach loads the URLs seen by earlier runs into registry and records
+// the ones it sees from now on. It returns how many were loaded
+func (s *CrawlState) Attach(registry *URLRegistry) (int, error) {
+	if s == nil || registry == nil {
+		return 0, nil
+	}
+	registry.ensure()
+	loaded := 0
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(stateSeenBucket).ForEach(func(k, _ []byte) error {
+			registry.filter.Duplicate(string(k))
+			loaded++
+			return nil
+		})
+	})
+	registry.journal = s.addSeen
+	return loaded, err
+}
+
+func (s *CrawlState) addSeen(key string) {
+	s.mu.Lock()
+	s.seen = append(s.seen, key)
+	s.mu.Unlock()
+}
+
+// Finished reports whether an earlier run crawled site to the end
+func (s *CrawlState) Finished(site string) bool {
+	return string(s.status(site)) == string(stateDone)
+}
+
+func (s *CrawlState) status(site string) []byte {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	status, buffered := s.sites[site]
+	s.mu.Unlock()
+	if buffered {
+		return status
+	}
+	_ = s.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(stateSitesBucket).Get([]byte(site)); v != nil {
+			status = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	return status
+}
+
+func (s *CrawlState) setStatus(site string, status []byte) {
+	s.mu.Lock()
+	s.sites[site] = status
+	s.mu.Unlock()
+}
+
+// Site returns the progress of one site, or nil when s is nil
+func (s *CrawlState) Site(site string) *SiteState {
+	if s == nil {
+		return nil
+	}
+	return &SiteState{state: s, site: site}
+}
+
+// SiteState tracks what is outstanding for one crawled site. Its methods
+// are nil-safe so the crawler calls them whether or not --resume is set
+type SiteState struct {
+	state *CrawlState
+	site  string
+}
+
+// Resume marks the site started and returns what an interrupted earlier
+// run left outstanding: requests to send again and hybrid pages to render.
+// started is false when no earlier run got to the site
+func (ss *SiteState) Resume() (requests []FailedRequest, hybrid []string, started bool) {
+	if ss == nil {
+		return nil, nil, false
+	}
+	started = ss.state.status(ss.site) != nil
+	ss.state.setStatus(ss.site, stateStarted)
+	if !started {
+		return nil, nil, false
+	}
+	_ = ss.state.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(statePendingBucket).Bucket([]byte(ss.site))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			if page, ok := strings.CutPrefix(string(k), hybridStatePrefix); ok {
+				hybrid = append(hybrid, page)
+				return nil
+			}
+			var req FailedRequest
+			if json.Unmarshal(v, &req) == nil && req.URL != "" {
+				requests = append(requests, req)
+			}
+			return nil
+		})
+	})
+	return requests, hybrid, true
+}
+
+// Finish marks the site crawled to the end
+func (ss *SiteState) Finish() {
+	if ss == nil {
+		return
+	}
+	ss.state.setStatus(ss.site, stateDone)
+}
+
+func (ss *SiteState) put(key string, value []byte) {
+	s := ss.state
+	s.mu.Lock()
+	changes := s.pending[ss.site]
+	if changes == nil {
+		changes = make(map[string][]byte)
+		s.pending[ss.site] = changes
+	}
+	changes[key] = value
+	s.mu.Unlock()
+}
+
+// AddRequest records a request as outstanding and returns its key
+func (ss *SiteState) AddRequest(req FailedRequest) string {
+	if ss == nil {
+		return ""
+	}
+	key := canonicalRequestKey(req.Method, req.URL, req.Body)
+	if key == "" {
+		return ""
+	}
+	value, err := json.Marshal(req)
+	if err != nil {
+		return ""
+	}
+	ss.put(key, value)
+	return key
+}
+
+// AddHybrid records a page queued for the hybrid browser
+func (ss *SiteState) AddHybrid(page string) {
+	if ss == nil {
+		return
+	}
+	ss.put(hybridStatePrefix+page, []byte{})
+}
+
+// Done clears an outstanding request or hybrid page
+func (ss *SiteState) Done(key string) {
+	if ss == nil || key == "" {
+		return
+	}
+	ss.put(key, nil)
+}
+
+// DoneHybrid clears a hybrid page
+func (ss *SiteState) DoneHybrid(page string) {
+	ss.Done(hybridStatePrefix + page)
+}
+
+// stateSiteKey is the key of a site in the state file
+func stateSiteKey(site *url.URL) string {
+	return asciiURL(site).String()
+}
+
+// trackVisit records a page visit as outstanding, returning its key
+func (crawler *Crawler) trackVisit(rawURL string) string {
+	return crawler.state.AddRequest(FailedRequest{URL: rawURL, Method: http.MethodGet})
+}
+
+// trackRequest records a generated request as outstanding, with its key in
+// ctx so the response clears it even after a redirect
+func (crawler *Crawler) trackRequest(method, rawURL string, ctx *colly.Context, headers http.Header) {
+	if crawler.state == nil || ctx == nil {
+		return
+	}
+	req := FailedRequest{URL: rawURL, Method: method}
+	if body, ok := ctx.GetAny("body").(string); ok && body != "" {
+		req.Body = body
+		req.ContentType = headers.Get("Content-Type")
+	}
+	ctx.Put(stateKeyCtx, crawler.state.AddRequest(req))
+}
+
+// registerStateTracking clears requests from the state file once their
+// response or error is in. Requests cut off by stopping the crawl stay
+// outstanding for the next run
+func (crawler *Crawler) registerStateTracking() {
+	if crawler.state == nil {
+		return
+	}
+	done := func(r *colly.Request) {
+		if r == nil || r.URL == nil {
+			return
+		}
+		if r.Ctx != nil {
+			if key := r.Ctx.Get(stateKeyCtx); key != "" {
+				crawler.state.Done(key)
+				return
+			}
+		}
+		crawler.state.Done(canonicalRequestKey(http.MethodGet, r.URL.String(), ""))
+	}
+	for _, c := range []*colly.Collector{crawler.C, crawler.LinkFinderCollector} {
+		if c == nil {
+			continue
+		}
+		c.OnResponse(func(response *colly.Response) {
+			done(response.Request)
+		})
+		c.OnError(func(response *colly.Response, err error) {
+			if crawler.stopped.Load() || errors.Is(err, context.Canceled) {
+				return
+			}
+			done(response.Request)
+		})
+	}
+}
+
+// resumeState picks up an interrupted earlier run of the site, queueing
+// what it left outstanding in place of the start page. It reports whether
+// there was an earlier run
+func (crawler *Crawler) resumeState() bool {
+	requests, hybrid, started := crawler.state.Resume()
+	if !started {
+		return false
+	}
+	Logger.Infof("Resuming %s: %d requests and %d hybrid pages outstanding", crawler.site, len(requests), len(hybrid))
+	crawler.replay = append(crawler.replay, requests...)
+	crawler.resumeHybrid = hybrid
+	return true
+}
@@ -0,0 +1,155 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestCrawlStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	state, err := OpenCrawlState(path)
+	require.NoError(t, err)
+
+	registry := NewURLRegistry()
+	loaded, err := state.Attach(registry)
+	require.NoError(t, err)
+	assert.Zero(t, loaded)
+	registry.Duplicate("http://example.com/a")
+	registry.Duplicate("http://example.com/b")
+
+	site := state.Site("http://example.com")
+	_, _, started := site.Resume()
+	assert.False(t, started)
+	done := site.AddRequest(FailedRequest{URL: "http://example.com/a", Method: http.MethodGet})
+	site.AddRequest(FailedRequest{URL: "http://example.com/api", Method: http.MethodPost, Body: `{"a":1}`, ContentType: "application/json"})
+	site.AddHybrid("http://example.com/app")
+	site.Done(done)
+	state.Site("http://other.example").Finish()
+	require.NoError(t, state.Close())
+
+	state, err = OpenCrawlState(path)
+	require.NoError(t, err)
+	defer state.Close()
+	registry = NewURLRegistry()
+	loaded, err = state.Attach(registry)
+	require.NoError(t, err)
+	assert.Equal(t, 2, loaded)
+	assert.True(t, registry.Duplicate("http://example.com/a"))
+	assert.False(t, registry.Duplicate("http://example.com/c"))
+
+	assert.True(t, state.Finished("http://other.example"))
+	assert.False(t, state.Finished("http://example.com"))
+	requests, hybrid, started := state.Site("http://example.com").Resume()
+	assert.True(t, started)
+	assert.Equal(t, []FailedRequest{{URL: "http://example.com/api", Method: http.MethodPost, Body: `{"a":1}`, ContentType: "application/json"}}, requests)
+	assert.Equal(t, []string{"http://example.com/app"}, hybrid)
+}
+
+func TestCrawlStateFlushFailureKeepsChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	state, err := OpenCrawlState(path)
+	require.NoError(t, err)
+
+	registry := NewURLRegistry()
+	_, err = state.Attach(registry)
+	require.NoError(t, err)
+	registry.Duplicate("http://example.com/a")
+	site := state.Site("http://example.com")
+	site.Resume()
+	first := site.AddRequest(FailedRequest{URL: "http://example.com/a", Method: http.MethodGet})
+	site.AddRequest(FailedRequest{URL: "http://example.com/b", Method: http.MethodGet})
+
+	// A failed write keeps the changes buffered
+	require.NoError(t, state.db.Close())
+	assert.Error(t, state.flush())
+	state.db, err = bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	require.NoError(t, err)
+
+	// Changes made after the failure are newer than the restored ones
+	site.Done(first)
+	require.NoError(t, state.Close())
+
+	state, err = OpenCrawlState(path)
+	require.NoError(t, err)
+	defer state.Close()
+	registry = NewURLRegistry()
+	loaded, err := state.Attach(registry)
+	require.NoError(t, err)
+	assert.Equal(t, 1, loaded)
+	requests, _, started := state.Site("http://example.com").Resume()
+	assert.True(t, started)
+	assert.Equal(t, []FailedRequest{{URL: "http://example.com/b", Method: http.MethodGet}}, requests)
+}
+
+func TestCrawlResume(t *testing.T) {
+	var mu sync.Mutex
+	hits := map[string]int{}
+	var interrupt atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		n := hits[r.URL.Path]
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><body><a href="/a">a</a><a href="/b">b</a></body></html>`)
+		case "/b":
+			if n == 1 {
+				// The run is killed while this page loads
+				interrupt.Load().(context.CancelFunc)()
+				time.Sleep(200 * time.Millisecond)
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, `<html><body><a href="/c">c</a><a href="/a">a</a></body></html>`)
+		default:
+			fmt.Fprint(w, `<html><body>leaf</body></html>`)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := CrawlerConfig{MaxDepth: 3, MaxConcurrency: 1, Timeout: 5 * time.Second, Deterministic: true, Resume: filepath.Join(t.TempDir(), "state.db")}
+	run := func() []string {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		interrupt.Store(context.CancelFunc(cancel))
+		results, err := Crawl(ctx, srv.URL, cfg)
+		require.NoError(t, err)
+		var urls []string
+		for sout := range results {
+			if sout.OutputType == "url" {
+				urls = append(urls, sout.Output)
+			}
+		}
+		return urls
+	}
+
+	run()
+	mu.Lock()
+	assert.Equal(t, map[string]int{"/": 1, "/a": 1, "/b": 1}, hits)
+	mu.Unlock()
+
+	urls := run()
+	mu.Lock()
+	assert.Equal(t, map[string]int{"/": 1, "/a": 1, "/b": 2, "/c": 1}, hits)
+	mu.Unlock()
+	assert.ElementsMatch(t, []string{srv.URL + "/b", srv.URL + "/c"}, urls)
+
+	// A finished site has nothing left to do
+	assert.Empty(t, run())
+	mu.Lock()
+	assert.Equal(t, 2, hits["/b"])
+	mu.Unlock()
+}
@@ -1,25 +1,26 @@
 package core
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"math/rand"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"text/template"
 	"time"
 
-	jsoniter "github.com/json-iterator/go"
 
 	"github.com/gocolly/colly/v2"
 	"github.com/gocolly/colly/v2/extensions"
 	"github.com/jaeles-project/gospider/core/antidetect"
+	"github.com/jaeles-project/gospider/core/report"
 	"github.com/jaeles-project/gospider/stringset"
 )
 
@@ -27,7 +28,13 @@ type Crawler struct {
 	C                   *colly.Collector
 	LinkFinderCollector *colly.Collector
 	Output              *Output
+	hostOutputs         *HostOutputs
+	ownsHostOutputs     bool
+	sharedOutput        bool
 	AntiDetectClient    *antidetect.AntiDetectClient
+	RequestTransform    RequestTransform
+	ResponseTransform   ResponseTransform
+	redirects           *redirectPolicy
 	Stats               *CrawlStats
 	urlProcessor        *URLProcessor
 	ctx                 context.Context
@@ -35,8 +42,19 @@ type Crawler struct {
 	intensity           ExtractorIntensity
 
 	subSet       *stringset.StringFilter
-	awsSet       *stringset.StringFilter
+	bucketSet    *stringset.StringFilter
+	secretSet    *stringset.StringFilter
 	jsSet        *stringset.StringFilter
+	sourceMapSet *stringset.StringFilter
+	wasmSet      *stringset.StringFilter
+	sanSet       *stringset.StringFilter
+	documentSet  *stringset.StringFilter
+	versionSet   *stringset.StringFilter
+	streamSet    *stringset.StringFilter
+	webSocketSet *stringset.StringFilter
+	graphQLSet   *stringset.StringFilter
+	errorSet     *stringset.StringFilter
+	sanHostSet   *stringset.StringFilter
 	jsRequestSet *stringset.StringFilter
 	formSet      *stringset.StringFilter
 
@@ -45,13 +63,75 @@ type Crawler struct {
 	Input            string
 	Quiet            bool
 	JsonOutput       bool
+	stdoutFormat     string
+	fileFormat       string
+	outputTemplate   *template.Template
 	length           bool
 	raw                      bool
 	subs                     bool
 	linkfinder               bool
+	jsonLinkfinder           bool
+	jsAST                    bool
+	wasm                     bool
+	parseDocuments           bool
+	headFirst                bool
+	headFirstMaxSize         int64
+	versionProbe             bool
+	maxPagesFollow           int
+	reportErrors             bool
+	failed                   *FailedCollector
+	sarif                    *report.SARIF
+	pause                    *PauseGate
+	replay                   []FailedRequest
+	// state tracks outstanding work for --resume; resumed is set when an
+	// earlier run of the site is picked up instead of starting over
+	state        *SiteState
+	resumed      bool
+	resumeHybrid []string
+	inScopeOnly              bool
+	normalizeUnicode         bool
+	excludeStatus            map[int]bool
+	includeStatus            map[int]bool
+	followLogout             bool
+	sessionPatterns          []string
+	pagesFollowed            map[string]int
+	pagesMutex               sync.Mutex
+	versionRules             map[string][]string
+	versionBump              int
+	versionProbeBudget       atomic.Int64
 	sitemap                  bool
 	robots                   bool
+	obeyRobots               bool
+	robotsDisallow           []string
+	robotsRules              RobotsRules
+	wellKnown                bool
+	deterministic            bool
+	hostLatency              *HostLatency
+	slowStart                *SlowStart
+	keepalive                *sessionKeepalive
+	hostHealth               *HostHealth
+	requestQueue             *requestQueue
+	visitQueue               *visitQueue
+	slotReleaseRegistered    bool
+	urlAttributes            map[string]struct{}
+	attributeMethods         map[string]string
+	skipSlowHosts            bool
+	wellKnownPaths           []string
+	openAPI                  bool
+	openAPIPaths             []string
+	checkSensitive           bool
+	dependencies             bool
+	dependencySet            *stringset.StringFilter
+	sensitiveFiles           []SensitiveFile
+	contentMatches           []ContentMatch
+	secrets                  *SecretScanner
+	matchSet                 *stringset.StringFilter
+	soft404                  *soft404Detector
 	otherSource              bool
+	otherSourceOptions       OtherSourceOptions
+	// archived maps the canonical key of each other-source URL to the URL
+	// while its re-crawl is pending, and to "" once it was reported
+	archived sync.Map
 	includeSubs              bool
 	includeOtherSourceResult bool
 	reflected                bool
@@ -59,7 +139,21 @@ type Crawler struct {
 	reflectedStore   map[string]*reflectionEntry
 	reflectedMutex   sync.Mutex
 	reflectedWriter  *Output
+	replayFiles      *ReplayWriter
+	sessionHeaders   http.Header
 	registry         *URLRegistry
+	validators       *ValidatorCache
+	params           *ParamCollector
+	openAPIDraft     *OpenAPICollector
+	postmanExport    *PostmanExporter
+	sourceMapDir     string
+	words            *WordCollector
+	profile          *ExtractorProfile
+	apiRequests      []JSRequest
+	harRequests      []JSRequest
+	burpRequests     []JSRequest
+	sink             OutputSink
+	extraSinks       []OutputSink
 	backoffMutex     sync.Mutex
 	backoff429       int
 	backoff403       int
@@ -76,6 +170,8 @@ type Crawler struct {
 	baselinePayloads   []PayloadVariant
 	payloadRNG         *rand.Rand
 	payloadRNGMutex    sync.Mutex
+	mutationDelayMin   time.Duration
+	mutationDelayMax   time.Duration
 	domAnalyzer        *DOMAnalyzer
 	jsRequestLogSet    *stringset.StringFilter
 
@@ -93,21 +189,23 @@ type Crawler struct {
 	hybridVisitCap int
 	hybridEnqueued int64
 
+	// Links found per page while --hybrid-selective decides on rendering it
+	hybridSelective bool
+	hybridMinLinks  int
+	hybridThinText  int
+	hybridLinks     sync.Map
+
+	// Per-response <base href> resolution, see documentBase
+	documentBases sync.Map
+
+	sanMutex    sync.Mutex
+	pendingSANs []string
+
 	stopChan chan struct{}
 	stopped  atomic.Bool
-}
 
-type SpiderOutput struct {
-	Input      string `json:"input"`
-	Source     string `json:"source"`
-	OutputType string `json:"type"`
-	Output     string `json:"output"`
-	StatusCode int    `json:"status"`
-	Length     int    `json:"length"`
-	Param      string `json:"param,omitempty"`
-	Payload    string `json:"payload,omitempty"`
-	Confidence string `json:"confidence,omitempty"`
-	Snippet    string `json:"snippet,omitempty"`
+	// Responses both collectors have finished handling, see waitCollectors
+	handled atomic.Int64
 }
 
 func (crawler *Crawler) IsStopped() bool {
@@ -167,7 +265,9 @@ func (crawler *Crawler) emitDOMFindings(url, body, sourceLabel string) {
 	if crawler.domAnalyzer == nil {
 		return
 	}
+	stop := crawler.profile.start(extractorDOMAnalysis)
 	findings := crawler.domAnalyzer.Analyze(url, body, sourceLabel)
+	stop()
 	if len(findings) == 0 {
 		return
 	}
@@ -177,26 +277,27 @@ func (crawler *Crawler) emitDOMFindings(url, body, sourceLabel string) {
 			rendered = fmt.Sprintf("%s :: %s", rendered, finding.Snippet)
 		}
 		output := rendered
-		if crawler.JsonOutput {
-			sout := SpiderOutput{
-				Input:      crawler.Input,
+		sout := SpiderOutput{
+			Input:      crawler.Input,
+			Source:     finding.Source,
+			OutputType: "dom-sink",
+			Output:     url,
+			Param:      finding.Sink,
+			Payload:    finding.Snippet,
+			Confidence: finding.Confidence,
+			Snippet:    finding.Snippet,
+		}
+		crawler.emit(sout, output, fmt.Sprintf("%s %s", url, finding.Sink))
+		if crawler.sarif != nil {
+			crawler.sarif.AddDOMSink(report.DOMSink{
+				URL:        url,
+				Rule:       finding.Rule,
 				Source:     finding.Source,
-				OutputType: "dom-sink",
-				Output:     url,
-				Param:      finding.Sink,
-				Payload:    finding.Snippet,
-				Confidence: finding.Confidence,
+				Sink:       finding.Sink,
 				Snippet:    finding.Snippet,
-			}
-			if data, err := jsoniter.MarshalToString(sout); err == nil {
-				output = data
-			}
-		} else if crawler.Quiet {
-			output = fmt.Sprintf("%s %s", url, finding.Sink)
-		}
-		fmt.Println(output)
-		if crawler.Output != nil {
-			crawler.Output.WriteToFile(output)
+				Confidence: finding.Confidence,
+				Line:       finding.Line,
+			})
 		}
 	}
 }
@@ -207,30 +308,66 @@ func (crawler *Crawler) maybeThrottleMutations(reflected bool) {
 	if crawler.baselineFuzzCap <= 0 {
 		return
 	}
+	minDelay, maxDelay := crawler.mutationDelayMin, crawler.mutationDelayMax
+	if minDelay <= 0 && maxDelay <= 0 {
+		return
+	}
+	if maxDelay < minDelay {
+		maxDelay = minDelay
+	}
 	crawler.payloadRNGMutex.Lock()
 	rng := crawler.payloadRNG
+	wait := minDelay
+	if rng != nil && maxDelay > minDelay {
+		wait += time.Duration(rng.Int63n(int64(maxDelay - minDelay)))
+	}
 	crawler.payloadRNGMutex.Unlock()
-	if rng == nil {
-		return
+
+	// Back off harder while the target is answering with 429s.
+	crawler.backoffMutex.Lock()
+	pressure := minInt(crawler.backoff429, 5)
+	crawler.backoffMutex.Unlock()
+	if pressure > 0 {
+		wait *= time.Duration(1 + pressure)
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-crawler.stopChan:
+	case <-crawler.ctxDone():
 	}
-	wait := 50 + rng.Intn(120)
-	time.Sleep(time.Duration(wait) * time.Millisecond)
+}
+
+// ctxDone returns the crawler context's done channel, or nil when no context is attached.
+func (crawler *Crawler) ctxDone() <-chan struct{} {
+	if crawler.ctx == nil {
+		return nil
+	}
+	return crawler.ctx.Done()
 }
 
 func NewCrawler(ctx context.Context, site *url.URL, cfg CrawlerConfig, stats *CrawlStats) *Crawler {
+	site = asciiURL(site)
 	domain := GetDomain(site)
 	if domain == "" {
 		Logger.Error("Failed to parse domain")
 		os.Exit(1)
 	}
+	if cfg.Subs && IsIPHost(site) {
+		Logger.Infof("%s is an IP address, ignoring subdomain options", site.Host)
+		cfg.Subs = false
+	}
 	Logger.Infof("Start crawling: %s", site)
 	registry := cfg.Registry
 	if registry == nil {
 		registry = NewURLRegistry()
 	}
 
+	// Deterministic crawls run synchronously so URLs are visited in discovery order
 	c := colly.NewCollector(
-		colly.Async(true),
+		colly.Async(!cfg.Deterministic),
 		colly.MaxDepth(cfg.MaxDepth),
 		colly.IgnoreRobotsTxt(),
 	)
@@ -246,6 +383,37 @@ func NewCrawler(ctx context.Context, site *url.URL, cfg CrawlerConfig, stats *Cr
 		antiDetectConfig.BrowserProfile = "random"
 	}
 
+	antiDetectConfig.MaxConnsPerHost, antiDetectConfig.MaxIdleConns = ResolveConnLimits(cfg.MaxConnsPerHost, cfg.MaxIdleConns, cfg.Threads)
+	antiDetectConfig.EnableDNSCache = cfg.ResolveOnce
+	antiDetectConfig.DNSCacheTTL = cfg.DNSCacheTTL
+	antiDetectConfig.DNSCachePin = cfg.DNSPin
+	antiDetectConfig.SNI = cfg.SNI
+
+	if cfg.Deterministic {
+		antidetect.SetSeed(cfg.Seed)
+		antiDetectConfig.EnableTimingRandomization = false
+		antiDetectConfig.EnableHeaderRandomization = false
+		if antiDetectConfig.BrowserProfile == "random" {
+			antiDetectConfig.BrowserProfile = "chrome"
+		}
+	}
+
+	// A custom user agent decides which browser the other fingerprints imitate
+	customUA := cfg.UserAgent != "" && cfg.UserAgent != "web" && cfg.UserAgent != "mobi"
+	uaFamily := antidetect.BrowserFamily(cfg.UserAgent)
+	if customUA && uaFamily != "" {
+		antiDetectConfig.BrowserProfile = uaFamily
+	}
+
+	if len(cfg.ProxyList) > 0 {
+		antiDetectConfig.EnableProxyRotation = true
+		antiDetectConfig.ProxyList = cfg.ProxyList
+		antiDetectConfig.ProxyTestURLs = cfg.ProxyTestURLs
+		antiDetectConfig.ProxyHealthInterval = cfg.ProxyHealthInterval
+		antiDetectConfig.ProxyRotateEvery = cfg.ProxyRotateEvery
+		antiDetectConfig.ProxyRotateInterval = cfg.ProxyRotateInterval
+	}
+
 	antiDetectClient := antidetect.NewAntiDetectClient(antiDetectConfig)
 
 	if cfg.Proxy != "" {
@@ -264,67 +432,54 @@ func NewCrawler(ctx context.Context, site *url.URL, cfg CrawlerConfig, stats *Cr
 		client.Timeout = cfg.Timeout
 	}
 
-	if cfg.NoRedirect {
-		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-			nextLocation := req.Response.Header.Get("Location")
-			Logger.Debugf("Found Redirect: %s", nextLocation)
-			if strings.Contains(nextLocation, site.Hostname()) {
-				Logger.Infof("Redirecting to: %s", nextLocation)
-				return nil
-			}
-			return http.ErrUseLastResponse
-		}
-	} else {
-		client.CheckRedirect = nil
-	}
+	redirects := newRedirectPolicy(site.Hostname(), cfg.NoRedirect, cfg.redirectLimit(), cfg.RedirectChain)
+	client.CheckRedirect = redirects.CheckRedirect
 
 	antiDetectClient.ApplyToCollyCollector(c)
 
+	// The credentials every request carries, for requests sent outside colly
+	sessionHeaders := http.Header{}
 	burpFile := cfg.BurpFile
 	if burpFile != "" {
 		bF, err := os.Open(burpFile)
 		if err != nil {
 			Logger.Errorf("Failed to open Burp File: %s", err)
 		} else {
-			rd := bufio.NewReader(bF)
-			req, err := http.ReadRequest(rd)
+			burpHeaders, err := ParseBurpHeaders(bF)
+			bF.Close()
 			if err != nil {
 				Logger.Errorf("Failed to Parse Raw Request in %s: %s", burpFile, err)
 			} else {
+				applyHeaders(sessionHeaders, burpHeaders)
 				c.OnRequest(func(r *colly.Request) {
-					r.Headers.Set("Cookie", GetRawCookie(req.Cookies()))
-				})
-
-				c.OnRequest(func(r *colly.Request) {
-					for k, v := range req.Header {
-						r.Headers.Set(strings.TrimSpace(k), strings.TrimSpace(v[0]))
-					}
+					applyHeaders(*r.Headers, burpHeaders)
 				})
-
 			}
 		}
 	}
 
+	// The capture's cookies for the site stand in for a missing --cookie
+	var harRequests []JSRequest
+	if cfg.HARCapture != nil {
+		harRequests = cfg.HARCapture.Requests
+		if cfg.Cookie == "" {
+			cfg.Cookie = cfg.HARCapture.Cookies[strings.ToLower(site.Hostname())]
+		}
+	}
+
 	if cfg.Cookie != "" && burpFile == "" {
 		cookie := cfg.Cookie
+		sessionHeaders.Set("Cookie", cookie)
 		c.OnRequest(func(r *colly.Request) {
 			r.Headers.Set("Cookie", cookie)
 		})
 	}
 
 	if burpFile == "" {
-		for _, h := range cfg.Headers {
-			headerArgs := strings.SplitN(h, ":", 2)
-			if len(headerArgs) != 2 {
-				continue
-			}
-			headerKey := strings.TrimSpace(headerArgs[0])
-			headerValue := strings.TrimSpace(headerArgs[1])
-			if headerKey == "" {
-				continue
-			}
+		if flagHeaders := ParseHeaderFlags(cfg.Headers); len(flagHeaders) > 0 {
+			applyHeaders(sessionHeaders, flagHeaders)
 			c.OnRequest(func(r *colly.Request) {
-				r.Headers.Set(headerKey, headerValue)
+				applyHeaders(*r.Headers, flagHeaders)
 			})
 		}
 	}
@@ -332,18 +487,48 @@ func NewCrawler(ctx context.Context, site *url.URL, cfg CrawlerConfig, stats *Cr
 	switch ua := cfg.UserAgent; {
 	case ua == "mobi":
 		extensions.RandomMobileUserAgent(c)
-	case ua == "web":
-		extensions.RandomUserAgent(c)
+	case ua == "web" || ua == "":
+		// Keep the session's user agent so it matches the TLS and HTTP/2 fingerprints
+		c.UserAgent = antiDetectClient.UserAgent()
+		if c.UserAgent == "" {
+			extensions.RandomUserAgent(c)
+		}
 	default:
+		antiDetectClient.SetUserAgent(ua)
 		c.UserAgent = ua
 	}
+	// Random mobile and non-browser user agents are a deliberate choice
+	if cfg.UserAgent != "mobi" && (!customUA || uaFamily != "") {
+		if err := antiDetectClient.ValidateFingerprint(); err != nil {
+			Logger.Warnf("[fingerprint] %s", err)
+		}
+	}
 
 	extensions.Referer(c)
 
 	var output *Output
-	if cfg.OutputDir != "" {
+	sharedOutput := false
+	hostOutputs, ownsHostOutputs := cfg.HostOutputs, false
+	if cfg.OutputByHost && cfg.OutputDir != "" && hostOutputs == nil {
+		hostOutputs, ownsHostOutputs = NewHostOutputs(cfg.OutputDir, cfg.SplitOutput, cfg.DedupOutput, cfg.DedupFields), true
+	}
+	if hostOutputs != nil {
+		// The target's own file is one of the per-host files
+		output = hostOutputs.Get(site.Hostname())
+	} else if cfg.JSONLOutput != nil {
+		// The run's typed JSONL files, closed by the engine
+		output, sharedOutput = cfg.JSONLOutput, true
+	} else if cfg.jsonlOutput() {
+		output = NewJSONLOutput(cfg.OutputDir)
+		output.EnableDedup(cfg.DedupOutput, cfg.DedupFields)
+	} else if cfg.OutputDir != "" {
 		filename := strings.ReplaceAll(site.Hostname(), ".", "_")
-		output = NewOutput(cfg.OutputDir, filename)
+		if cfg.SplitOutput {
+			output = NewSplitOutput(cfg.OutputDir, filename)
+		} else {
+			output = NewOutput(cfg.OutputDir, filename)
+		}
+		output.EnableDedup(cfg.DedupOutput, cfg.DedupFields)
 	}
 
 	var reflectedOutput *Output
@@ -351,6 +536,11 @@ func NewCrawler(ctx context.Context, site *url.URL, cfg CrawlerConfig, stats *Cr
 		reflectedOutput = NewOutputPath(cfg.ReflectedOutput)
 	}
 
+	var replayWriter *ReplayWriter
+	if cfg.ReplayFiles && cfg.OutputDir != "" {
+		replayWriter = NewReplayWriter(filepath.Join(cfg.OutputDir, replayDir))
+	}
+
 	filterLengthSlice := []int{}
 	if cfg.FilterLength != "" {
 		lengthArgs := strings.Split(cfg.FilterLength, ",")
@@ -361,16 +551,16 @@ func NewCrawler(ctx context.Context, site *url.URL, cfg CrawlerConfig, stats *Cr
 		}
 	}
 
-	reg := ""
-	hostPattern := regexp.QuoteMeta(site.Hostname())
-	if cfg.Subs {
-		reg = "(?i)" + hostPattern
-	} else {
-		reg = "(?i)(?:https?://)" + hostPattern
-	}
+	// Already validated by NewCrawlerConfig
+	outputTemplate, _ := ParseOutputTemplate(cfg.OutputTemplate)
 
-	sRegex := regexp.MustCompile(reg)
-	c.URLFilters = append(c.URLFilters, sRegex)
+	scope, disallowed, err := ScopeFilters(site, cfg)
+	if err != nil {
+		Logger.Errorf("Failed to set scope: %s", err)
+		os.Exit(1)
+	}
+	c.URLFilters = scope
+	c.DisallowedURLFilters = disallowed
 
 	if err := c.Limit(&colly.LimitRule{
 		DomainGlob:  "*",
@@ -382,25 +572,30 @@ func NewCrawler(ctx context.Context, site *url.URL, cfg CrawlerConfig, stats *Cr
 		os.Exit(1)
 	}
 
-	disallowedRegex := `(?i)\.(png|apng|bmp|gif|ico|cur|jpg|jpeg|jfif|pjp|pjpeg|svg|tif|tiff|webp|xbm|3gp|aac|flac|mpg|mpeg|mp3|mp4|m4a|m4v|m4p|oga|ogg|ogv|mov|wav|webm|eot|woff|woff2|ttf|otf|css)(?:\?|#|$)`
-	c.DisallowedURLFilters = append(c.DisallowedURLFilters, regexp.MustCompile(disallowedRegex))
-
-	if cfg.Blacklist != "" {
-		c.DisallowedURLFilters = append(c.DisallowedURLFilters, regexp.MustCompile(cfg.Blacklist))
-	}
-
-	if cfg.Whitelist != "" {
-		c.URLFilters = make([]*regexp.Regexp, 0)
-		c.URLFilters = append(c.URLFilters, regexp.MustCompile(cfg.Whitelist))
-	}
-
-	if cfg.WhitelistDomain != "" {
-		c.URLFilters = make([]*regexp.Regexp, 0)
-		c.URLFilters = append(c.URLFilters, regexp.MustCompile("http(s)?://"+cfg.WhitelistDomain))
+	// Clones share the backend and with it the limit rules, so JS fetching
+	// gets a collector of its own to be throttled independently
+	linkFinderCollector := colly.NewCollector(
+		colly.Async(!cfg.Deterministic),
+		colly.MaxDepth(cfg.MaxDepth),
+		colly.IgnoreRobotsTxt(),
+	)
+	linkFinderCollector.SetClient(client)
+	linkFinderCollector.UserAgent = c.UserAgent
+	linkFinderCollector.MaxBodySize = c.MaxBodySize
+	linkFinderCollector.DisallowedURLFilters = c.DisallowedURLFilters
+	jsConcurrency := cfg.JSConcurrency
+	if jsConcurrency <= 0 {
+		jsConcurrency = cfg.MaxConcurrency
+	}
+	if err := linkFinderCollector.Limit(&colly.LimitRule{
+		DomainGlob:  "*",
+		Parallelism: jsConcurrency,
+		Delay:       cfg.Delay,
+		RandomDelay: cfg.RandomDelay,
+	}); err != nil {
+		Logger.Errorf("Failed to set LinkFinder Limit Rule: %s", err)
+		os.Exit(1)
 	}
-
-	linkFinderCollector := c.Clone()
-	linkFinderCollector.URLFilters = nil
 	if cfg.Whitelist != "" {
 		linkFinderCollector.URLFilters = append(linkFinderCollector.URLFilters, regexp.MustCompile(cfg.Whitelist))
 	}
@@ -417,12 +612,40 @@ func NewCrawler(ctx context.Context, site *url.URL, cfg CrawlerConfig, stats *Cr
 	if cfg.DomDedup {
 		domDeduper = NewDOMDeduper(cfg.DomDedupThresh)
 	}
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	seed := time.Now().UnixNano()
+	if cfg.Deterministic {
+		seed = cfg.Seed
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	headFirstMaxSize := cfg.HeadFirstMaxSize
+	if headFirstMaxSize <= 0 {
+		headFirstMaxSize = defaultHeadFirstMaxSize
+	}
+	versionRules := cfg.VersionRules
+	if versionRules == nil {
+		versionRules, _ = ParseVersionRules(DefaultVersionRules)
+	}
+	sessionPatterns := cfg.SessionPatterns
+	if sessionPatterns == nil {
+		sessionPatterns = DefaultSessionPatterns
+	}
+	versionBump := cfg.VersionBump
+	if versionBump <= 0 {
+		versionBump = defaultVersionBump
+	}
+	versionProbeBudget := cfg.VersionProbeBudget
+	if versionProbeBudget <= 0 {
+		versionProbeBudget = defaultVersionProbeBudget
+	}
 
 	crawler := &Crawler{
 		C:                        c,
 		LinkFinderCollector:      linkFinderCollector,
+		RequestTransform:         cfg.RequestTransform,
+		ResponseTransform:        cfg.ResponseTransform,
 		AntiDetectClient:         antiDetectClient,
+		redirects:                redirects,
 		site:                     site,
 		ctx:                      ctx,
 		cfg:                      cfg,
@@ -431,22 +654,94 @@ func NewCrawler(ctx context.Context, site *url.URL, cfg CrawlerConfig, stats *Cr
 		Quiet:                    cfg.Quiet,
 		Input:                    site.String(),
 		JsonOutput:               cfg.JSONOutput,
+		stdoutFormat:             cfg.StdoutFormat,
+		fileFormat:               cfg.FileFormat,
+		outputTemplate:           outputTemplate,
 		length:                   cfg.Length,
 		raw:                      cfg.Raw,
 		domain:                   domain,
 		Output:                   output,
+		hostOutputs:              hostOutputs,
+		ownsHostOutputs:          ownsHostOutputs,
+		sharedOutput:             sharedOutput,
 		reflectedWriter:          reflectedOutput,
+		replayFiles:              replayWriter,
 		registry:                 registry,
+		validators:               cfg.Validators,
+		params:                   cfg.Params,
+		openAPIDraft:             cfg.OpenAPIDraft,
+		postmanExport:            cfg.PostmanExport,
+		sourceMapDir:             cfg.SourceMapDir,
+		words:                    cfg.Words,
+		profile:                  cfg.Profile,
+		apiRequests:              cfg.APIRequests,
+		harRequests:              harRequests,
+		burpRequests:             cfg.BurpRequests,
+		sink:                     cfg.Sink,
+		extraSinks:               cfg.OutputSinks,
 		subSet:                   stringset.NewStringFilter(),
 		jsSet:                    stringset.NewStringFilter(),
+		sourceMapSet:             stringset.NewStringFilter(),
+		wasmSet:                  stringset.NewStringFilter(),
+		sanSet:                   stringset.NewStringFilter(),
+		documentSet:              stringset.NewStringFilter(),
+		versionSet:               stringset.NewStringFilter(),
+		streamSet:                stringset.NewStringFilter(),
+		webSocketSet:             stringset.NewStringFilter(),
+		graphQLSet:               stringset.NewStringFilter(),
+		errorSet:                 stringset.NewStringFilter(),
+		sanHostSet:               stringset.NewStringFilter(),
 		jsRequestSet:             stringset.NewStringFilter(),
 		formSet:                  stringset.NewStringFilter(),
-		awsSet:                   stringset.NewStringFilter(),
+		bucketSet:                stringset.NewStringFilter(),
+		secretSet:                stringset.NewStringFilter(),
 		subs:                     cfg.Subs,
 		linkfinder:               cfg.LinkFinder,
+		jsonLinkfinder:           cfg.JSONLinkFinder,
+		jsAST:                    cfg.JSAST,
+		wasm:                     cfg.Wasm,
+		parseDocuments:           cfg.ParseDocuments,
+		headFirst:                cfg.HeadFirst,
+		headFirstMaxSize:         headFirstMaxSize,
+		versionProbe:             cfg.VersionProbe,
+		maxPagesFollow:           cfg.MaxPagesFollow,
+		reportErrors:             cfg.ReportErrors,
+		failed:                   cfg.Failed,
+		sarif:                    cfg.SARIF,
+		pause:                    cfg.Pause,
+		replay:                   replayRequestsFor(site, cfg.ReplayRequests),
+		state:                    cfg.State.Site(stateSiteKey(site)),
+		inScopeOnly:              cfg.InScopeOnly,
+		normalizeUnicode:         cfg.NormalizeUnicode,
+		excludeStatus:            statusSet(cfg.ExcludeStatus),
+		includeStatus:            statusSet(cfg.IncludeStatus),
+		followLogout:             cfg.FollowLogout,
+		sessionPatterns:          sessionPatterns,
+		pagesFollowed:            make(map[string]int),
+		versionRules:             versionRules,
+		versionBump:              versionBump,
 		sitemap:                  cfg.Sitemap,
 		robots:                   cfg.Robots,
+		obeyRobots:               cfg.ObeyRobots,
+		wellKnown:                cfg.WellKnown,
+		deterministic:            cfg.Deterministic,
+		hostLatency:              NewHostLatency(cfg.SlowHostThreshold, cfg.SlowHostSamples),
+		hostHealth:               NewHostHealth(cfg.DeadHostThreshold, cfg.TLSFallbackThreshold),
+		skipSlowHosts:            cfg.SkipSlowHosts,
+		urlAttributes:            attributeSet(cfg.URLAttributes),
+		attributeMethods:         cfg.AttributeMethods,
+		wellKnownPaths:           cfg.WellKnownPaths,
+		openAPI:                  cfg.OpenAPI,
+		openAPIPaths:             cfg.OpenAPIPaths,
+		checkSensitive:           cfg.CheckSensitive,
+		dependencies:             cfg.Dependencies,
+		dependencySet:            stringset.NewStringFilter(),
+		sensitiveFiles:           cfg.SensitiveFiles,
+		contentMatches:           cfg.ContentMatches,
+		secrets:                  NewSecretScanner(cfg.SecretRules),
+		matchSet:                 stringset.NewStringFilter(),
 		otherSource:              cfg.OtherSource,
+		otherSourceOptions:       OtherSourceOptions{Limit: cfg.OtherSourceLimit, RPS: cfg.OtherSourceRPS},
 		includeSubs:              cfg.IncludeSubs,
 		includeOtherSourceResult: cfg.IncludeOtherSourceResult,
 		reflected:                cfg.Reflected,
@@ -461,15 +756,91 @@ func NewCrawler(ctx context.Context, site *url.URL, cfg CrawlerConfig, stats *Cr
 		payloadVariants:          payloadVariants,
 		baselinePayloads:         baselinePayloads,
 		payloadRNG:               rng,
+		mutationDelayMin:         cfg.MutationDelayMin,
+		mutationDelayMax:         cfg.MutationDelayMax,
 		domAnalyzer:              NewDOMAnalyzer(),
 		stopChan:                 make(chan struct{}),
 	}
 
 	crawler.urlProcessor = NewURLProcessor(crawler)
+	if cfg.Soft404 {
+		crawler.soft404 = newSoft404Detector()
+	}
+	if cfg.RequestQueueSize > 0 && !cfg.Deterministic {
+		crawler.initRequestQueue(cfg.RequestQueueSize)
+	}
+	if (cfg.Prioritize || cfg.MaxQueueSize > 0) && !cfg.Deterministic {
+		var keywords []string
+		if cfg.Prioritize {
+			keywords = cfg.PriorityKeywords
+		}
+		crawler.initVisitQueue(cfg.MaxConcurrency, keywords, cfg.MaxQueueSize)
+	}
+	antiDetectClient.ObserveTLS(crawler.observeCertificate)
+
+	hostTimeout := cfg.HostTimeout
+	if hostTimeout >= client.Timeout {
+		hostTimeout = 0
+	}
+	client.Transport = &hostLatencyRoundTripper{base: client.Transport, timeout: hostTimeout, observe: crawler.observeHostLatency}
+	if crawler.Stats != nil {
+		client.Transport = &hostStatsRoundTripper{base: client.Transport, stats: crawler.Stats}
+	}
+
+	streamWindow := cfg.ReadTimeout
+	if streamWindow <= 0 {
+		streamWindow = defaultStreamReadWindow
+	}
+	streamPrefix := int64(maxStreamPrefix)
+	if c.MaxBodySize > 0 && int64(c.MaxBodySize) < streamPrefix {
+		streamPrefix = int64(c.MaxBodySize)
+	}
+	client.Transport = &streamRoundTripper{
+		base:         client.Transport,
+		readTimeout:  cfg.ReadTimeout,
+		streamWindow: streamWindow,
+		maxPrefix:    streamPrefix,
+		onStream:     crawler.emitStreamEndpoint,
+	}
+	if !cfg.Deterministic {
+		ramp, err := NewSlowStart(cfg.RampFloor, cfg.MaxConcurrency, cfg.RampUp, cfg.RampUpRequests, cfg.RampShape)
+		if err != nil {
+			Logger.Warnf("%s; starting at full concurrency", err)
+		} else if ramp != nil {
+			crawler.slowStart = ramp
+			client.Transport = &concurrencyGateRoundTripper{base: client.Transport, gate: NewConcurrencyGate(ramp.Limit()), ramp: ramp}
+		}
+	}
+	if cfg.RateLimiter != nil {
+		client.Transport = &rateLimitRoundTripper{base: client.Transport, limiter: cfg.RateLimiter}
+	}
+	// Outermost, so the transform sees each request exactly as it is sent
+	client.Transport = &requestTransformRoundTripper{base: client.Transport, crawler: crawler}
+	if sessionHeaders.Get("User-Agent") == "" && c.UserAgent != "" {
+		sessionHeaders.Set("User-Agent", c.UserAgent)
+	}
+	crawler.keepalive = newSessionKeepalive(site, cfg, sessionHeaders, client)
+	crawler.sessionHeaders = sessionHeaders
+	if crawler.postmanExport != nil {
+		crawler.postmanExport.AddSession(site.String(), sessionHeaders)
+	}
 
 	crawler.C.OnRequest(func(r *colly.Request) {
+		crawler.pause.Wait(crawler.stopChan, crawler.ctxDone())
 		if crawler.stopped.Load() {
-			r.Abort()
+			crawler.abortRequest(r)
+			return
+		}
+		if crawler.skipSlowHosts && crawler.hostLatency.IsSlow(r.URL.Hostname()) {
+			crawler.abortRequest(r)
+			return
+		}
+		if crawler.hostHealth.IsDead(r.URL.Hostname()) {
+			crawler.abortRequest(r)
+			return
+		}
+		if crawler.robotsDisallows(r.URL) {
+			crawler.abortRequest(r)
 			return
 		}
 		if depthStr := r.Ctx.Get("__depth"); depthStr != "" {
@@ -477,16 +848,58 @@ func NewCrawler(ctx context.Context, site *url.URL, cfg CrawlerConfig, stats *Cr
 				r.Depth = depth
 			}
 		}
+		if crawler.headFirst && r.Method == http.MethodGet && crawler.probeHead(r) {
+			crawler.abortRequest(r)
+			return
+		}
+		if crawler.validators != nil && r.Method == http.MethodGet {
+			if entry, ok := crawler.validators.Get(r.URL.String()); ok {
+				if entry.ETag != "" {
+					r.Headers.Set("If-None-Match", entry.ETag)
+				}
+				if entry.LastModified != "" {
+					r.Headers.Set("If-Modified-Since", entry.LastModified)
+				}
+			}
+		}
 		if crawler.Stats != nil {
 			crawler.Stats.IncrementRequestsMade()
 		}
 	})
 
+	if cfg.Wasm {
+		crawler.C.OnResponseHeaders(crawler.skipOversizedWasm)
+	}
+	if cfg.ParseDocuments {
+		crawler.C.OnResponseHeaders(crawler.skipOversizedDocument)
+	}
+	crawler.versionProbeBudget.Store(int64(versionProbeBudget))
+
+	crawler.C.OnResponse(crawler.applyResponseTransform)
+	crawler.LinkFinderCollector.OnResponse(crawler.applyResponseTransform)
+	if crawler.Stats != nil {
+		recordStatus := func(response *colly.Response) { crawler.Stats.RecordStatus(response.StatusCode) }
+		crawler.C.OnResponse(recordStatus)
+		crawler.LinkFinderCollector.OnResponse(recordStatus)
+		recordErrorStatus := func(response *colly.Response, _ error) {
+			if response != nil && response.StatusCode != 0 {
+				crawler.Stats.RecordStatus(response.StatusCode)
+			}
+		}
+		crawler.C.OnError(recordErrorStatus)
+		crawler.LinkFinderCollector.OnError(recordErrorStatus)
+	}
+
 	crawler.LinkFinderCollector.OnRequest(func(r *colly.Request) {
+		crawler.pause.Wait(crawler.stopChan, crawler.ctxDone())
 		if crawler.stopped.Load() {
 			r.Abort()
 			return
 		}
+		if crawler.robotsDisallows(r.URL) {
+			crawler.abortRequest(r)
+			return
+		}
 		if crawler.Stats != nil {
 			crawler.Stats.IncrementRequestsMade()
 		}
@@ -503,35 +916,31 @@ func (crawler *Crawler) feedLinkfinder(jsFileUrl string, OutputType string, sour
 		}
 		outputFormat := fmt.Sprintf("[%s] - %s", OutputType, jsFileUrl)
 
-		if crawler.JsonOutput {
-			sout := SpiderOutput{
-				Input:      crawler.Input,
-				Source:     source,
-				OutputType: OutputType,
-				Output:     jsFileUrl,
-			}
-			if data, err := jsoniter.MarshalToString(sout); err == nil {
-				outputFormat = data
-				fmt.Println(outputFormat)
-			}
-
-		} else if !crawler.Quiet {
-			fmt.Println(outputFormat)
-		}
-
-		if crawler.Output != nil {
-			crawler.Output.WriteToFile(outputFormat)
+		sout := SpiderOutput{
+			Input:      crawler.Input,
+			Source:     source,
+			OutputType: OutputType,
+			Output:     jsFileUrl,
 		}
+		crawler.emit(sout, outputFormat, "")
 
 		if strings.Contains(jsFileUrl, ".min.js") {
 			originalJS := strings.ReplaceAll(jsFileUrl, ".min.js", ".js")
-			_ = crawler.LinkFinderCollector.Visit(originalJS)
+			crawler.visitScript(originalJS)
 		}
-		_ = crawler.LinkFinderCollector.Visit(jsFileUrl)
+		crawler.visitScript(jsFileUrl)
 	}
 }
 
-func (crawler *Crawler) emitJSRequest(req JSRequest, origin string) bool {
+// visitScript fetches a script with the LinkFinder collector
+func (crawler *Crawler) visitScript(rawURL string) {
+	key := crawler.trackVisit(rawURL)
+	if err := crawler.LinkFinderCollector.Visit(rawURL); err != nil {
+		crawler.state.Done(key)
+	}
+}
+
+func (crawler *Crawler) emitJSRequest(req JSRequest, origin string, depth int) bool {
 	if crawler.jsRequestLogSet == nil {
 		crawler.jsRequestLogSet = stringset.NewStringFilter()
 	}
@@ -543,6 +952,10 @@ func (crawler *Crawler) emitJSRequest(req JSRequest, origin string) bool {
 	if crawler.jsRequestSet.Duplicate(key) {
 		return false
 	}
+	if crawler.postmanExport != nil {
+		crawler.postmanExport.Add(crawler.site.String(), req)
+	}
+	crawler.writeJSRequestReplay(req)
 
 	method := strings.TrimSpace(req.Method)
 	if method == "" {
@@ -560,32 +973,34 @@ func (crawler *Crawler) emitJSRequest(req JSRequest, origin string) bool {
 		shouldLog = false
 	}
 	rendered := fmt.Sprintf("[js-request] - [%s] %s", method, req.RawURL)
-	if crawler.JsonOutput {
-		sout := SpiderOutput{
-			Input:      crawler.Input,
-			Source:     source,
-			OutputType: "js-request",
-			Output:     strings.TrimSpace(method + " " + req.RawURL),
-			Length:     len(req.Body),
-		}
-		if data, err := jsoniter.MarshalToString(sout); err == nil {
-			rendered = data
-		}
-	} else if crawler.Quiet {
-		rendered = strings.TrimSpace(method + " " + req.RawURL)
+	sout := SpiderOutput{
+		Input:      crawler.Input,
+		Source:     source,
+		OutputType: "js-request",
+		Output:     strings.TrimSpace(method + " " + req.RawURL),
+		Length:     len(req.Body),
+		Depth:      depth,
 	}
-
 	if shouldLog {
-		fmt.Println(rendered)
-		if crawler.Output != nil {
-			crawler.Output.WriteToFile(rendered)
-		}
+		crawler.emit(sout, rendered, sout.Output)
 	}
 
 	return true
 }
 
 func (crawler *Crawler) Start() {
+	defer crawler.AntiDetectClient.Close()
+	defer crawler.closeOutputs()
+	defer crawler.closeVisitQueue()
+
+	// Disallow filters must be in place before the first request goes out
+	if crawler.obeyRobots {
+		crawler.obeyRobotsTxt()
+	}
+
+	stopKeepalive := crawler.startSessionKeepalive()
+	defer stopKeepalive()
+
 	if crawler.intensity != IntensityPassive {
 		err := crawler.DeepCrawlWithKatana(crawler.cfg)
 		if err != nil {
@@ -598,6 +1013,28 @@ func (crawler *Crawler) Start() {
 	}
 
 	// The linkfinder parameter is now implicitly handled by the unified OnResponse handler
+	if crawler.linkfinder {
+		crawler.LinkFinderCollector.OnResponse(func(response *colly.Response) {
+			if isLikelyJS(response.Headers.Get("Content-Type"), response.Body) {
+				body := DecodeChars(string(response.Body))
+				crawler.discoverSourceMap(response, body)
+				crawler.findWebSockets(body, response.Request.URL)
+				crawler.findWebpackChunks(string(response.Body), response.Request.URL)
+				crawler.findGraphQL(string(response.Body), response.Request)
+				if InScope(response.Request.URL, crawler.C.URLFilters) {
+					crawler.findSecrets(string(response.Body), response.Request.URL.String(), response.Request.Depth)
+					crawler.findCloudStorage(string(response.Body), response.Request.URL.String(), response.Request.Depth)
+				}
+			}
+			crawler.handleSourceMap(response)
+		})
+	}
+
+	crawler.registerPagination()
+	crawler.registerContentMatch()
+	crawler.registerStateTracking()
+	crawler.resumed = crawler.resumeState()
+
 	crawler.C.OnHTML("[href]", func(e *colly.HTMLElement) {
 		if crawler.stopped.Load() {
 			return
@@ -605,12 +1042,31 @@ func (crawler *Crawler) Start() {
 		if crawler.shouldSkipDOM(e.Request.URL.String()) {
 			return
 		}
+		// <link> and <base> have dedicated handlers
+		if e.Name == "link" || e.Name == "base" {
+			return
+		}
 		raw := e.Attr("href")
-		if urlToVisit := crawler.urlProcessor.Process(raw, "body", "href", e.Request); urlToVisit != "" {
-			_ = e.Request.Visit(urlToVisit)
+		outputType := "href"
+		if isSessionLinkText(e.Text) {
+			outputType = sessionEndpointType
+		}
+		if urlToVisit := crawler.urlProcessor.ProcessWithBase(raw, "body", outputType, crawler.documentBase(e), e.Request); urlToVisit != "" {
+			crawler.visit(e.Request, urlToVisit)
 		}
 	})
 
+	crawler.registerHTMLExtras()
+	crawler.registerURLAttributes()
+	crawler.registerEventHandlers()
+	crawler.registerHybridTrigger()
+
+	if crawler.words != nil {
+		crawler.C.OnHTML("title, h1, h2, h3", func(e *colly.HTMLElement) {
+			crawler.words.AddText(e.Text)
+		})
+	}
+
 	crawler.C.OnHTML("form", func(e *colly.HTMLElement) {
 		if crawler.stopped.Load() {
 			return
@@ -624,26 +1080,20 @@ func (crawler *Crawler) Start() {
 				crawler.Stats.IncrementURLsFound()
 			}
 			outputFormat := fmt.Sprintf("[form] - %s", formURL)
-			if crawler.JsonOutput {
-				sout := SpiderOutput{
-					Input:      crawler.Input,
-					Source:     "body",
-					OutputType: "form",
-					Output:     formURL,
-				}
-				if data, err := jsoniter.MarshalToString(sout); err == nil {
-					outputFormat = data
-					fmt.Println(outputFormat)
-				}
-			} else if !crawler.Quiet {
-				fmt.Println(outputFormat)
-			}
-			if crawler.Output != nil {
-				crawler.Output.WriteToFile(outputFormat)
+			sout := SpiderOutput{
+				Input:      crawler.Input,
+				Source:     "body",
+				OutputType: "form",
+				Output:     formURL,
+				Depth:      e.Request.Depth,
 			}
+			crawler.emit(sout, outputFormat, "")
 		}
 
-		requests := ExtractFormRequests(e.DOM, e.Request.URL)
+		if crawler.params != nil {
+			crawler.params.AddFormFields(e.Attr("method"), extractFormFields(e.DOM))
+		}
+		requests := ExtractFormRequests(e.DOM, crawler.documentBase(e))
 		if crawler.Stats != nil {
 			crawler.Stats.AddURLsFound(len(requests))
 		}
@@ -664,23 +1114,14 @@ func (crawler *Crawler) Start() {
 		uploadUrl := e.Request.URL.String()
 		if !uploadFormSet.Duplicate(uploadUrl) {
 			outputFormat := fmt.Sprintf("[upload-form] - %s", uploadUrl)
-			if crawler.JsonOutput {
-				sout := SpiderOutput{
-					Input:      crawler.Input,
-					Source:     "body",
-					OutputType: "upload-form",
-					Output:     uploadUrl,
-				}
-				if data, err := jsoniter.MarshalToString(sout); err == nil {
-					outputFormat = data
-					fmt.Println(outputFormat)
-				}
-			} else if !crawler.Quiet {
-				fmt.Println(outputFormat)
-			}
-			if crawler.Output != nil {
-				crawler.Output.WriteToFile(outputFormat)
+			sout := SpiderOutput{
+				Input:      crawler.Input,
+				Source:     "body",
+				OutputType: "upload-form",
+				Output:     uploadUrl,
+				Depth:      e.Request.Depth,
 			}
+			crawler.emit(sout, outputFormat, "")
 		}
 
 	})
@@ -693,10 +1134,13 @@ func (crawler *Crawler) Start() {
 			return
 		}
 		srcURL := e.Attr("src")
+		if e.Name == "script" {
+			crawler.recordDependency(e, srcURL)
+		}
 
 		fileExt := GetExtType(srcURL)
 		if fileExt == ".js" || fileExt == ".xml" || fileExt == ".json" {
-			jsFileURL, ok := NormalizeURL(e.Request.URL, srcURL)
+			jsFileURL, ok := NormalizeURL(crawler.documentBase(e), srcURL)
 			if !ok {
 				jsFileURL, ok = NormalizeURL(crawler.site, srcURL)
 				if !ok {
@@ -705,8 +1149,8 @@ func (crawler *Crawler) Start() {
 			}
 			crawler.feedLinkfinder(jsFileURL, "javascript", "body")
 		} else {
-			if urlToVisit := crawler.urlProcessor.Process(srcURL, "body", "src", e.Request); urlToVisit != "" {
-				_ = e.Request.Visit(urlToVisit)
+			if urlToVisit := crawler.urlProcessor.ProcessWithBase(srcURL, "body", "src", crawler.documentBase(e), e.Request); urlToVisit != "" {
+				crawler.visit(e.Request, urlToVisit)
 			}
 		}
 	})
@@ -715,6 +1159,7 @@ func (crawler *Crawler) Start() {
 		if crawler.stopped.Load() {
 			return
 		}
+		crawler.recordOpenAPI(response)
 		if response.Ctx != nil && response.Ctx.Get("reflected") == "true" {
 			crawler.handleReflectedResponse(response)
 			return
@@ -726,19 +1171,28 @@ func (crawler *Crawler) Start() {
 		var urlStr string
 		if response.Request != nil && response.Request.URL != nil {
 			urlStr = response.Request.URL.String()
+			crawler.emitRedirectChain(urlStr, response.StatusCode)
+			if crawler.validators != nil && response.Request.Method == http.MethodGet {
+				crawler.validators.Store(urlStr, response.Headers.Get("ETag"), response.Headers.Get("Last-Modified"))
+			}
 		}
 		contentType := strings.ToLower(response.Headers.Get("Content-Type"))
 		if idx := strings.Index(contentType, ";"); idx != -1 {
 			contentType = strings.TrimSpace(contentType[:idx])
 		}
+		crawler.flushCertificateSANs()
 		htmlLike := isLikelyHTML(contentType, response.Body)
-		jsLike := isLikelyJS(contentType, response.Body)
-		if htmlLike && urlStr != "" {
+		jsonLike := isLikelyJSON(contentType)
+		jsLike := !jsonLike && isLikelyJS(contentType, response.Body)
+		// With --hybrid-selective the page waits for its link count, see registerHybridTrigger
+		if htmlLike && urlStr != "" && !crawler.hybridSelective {
 			crawler.enqueueHybrid(urlStr)
 		}
 		if crawler.domDedup && urlStr != "" {
 			if htmlLike && crawler.domDeduper != nil {
+				stop := crawler.profile.start(extractorDOMFingerprint)
 				skip, _, err := crawler.domDeduper.ShouldSkip(crawler.domain, response.Body)
+				stop()
 				if err != nil {
 					Logger.Debugf("dom-dedup failed for %s: %v", urlStr, err)
 				} else {
@@ -757,6 +1211,10 @@ func (crawler *Crawler) Start() {
 			duplicateContent = crawler.registry.MarkResponse(response.Request.Method, response.Request.URL.String(), response.Body)
 		}
 		crawler.recordBackoff(response.StatusCode)
+		crawler.hostHealth.Success(response.Request.URL.Hostname())
+		if crawler.versionProbe {
+			crawler.probeAPIVersions(response.Request)
+		}
 		respStr := DecodeChars(string(response.Body))
 
 		if crawler.domAnalyzer != nil && urlStr != "" && (htmlLike || jsLike) && !crawler.shouldSkipDOM(urlStr) {
@@ -767,9 +1225,33 @@ func (crawler *Crawler) Start() {
 			crawler.emitDOMFindings(urlStr, respStr, sourceLabel)
 		}
 
-		if crawler.linkfinder && jsLike {
+		if crawler.linkfinder {
+			crawler.handleSourceMap(response)
+		}
+
+		if crawler.linkfinder && (jsLike || htmlLike) {
+			crawler.findWebSockets(respStr, response.Request.URL)
+			crawler.findWebpackChunks(string(response.Body), response.Request.URL)
+			crawler.findGraphQL(string(response.Body), response.Request)
+		}
+
+		if crawler.wasm && urlStr != "" && isWasmResponse(urlStr, contentType, response.Body) {
+			crawler.handleWasm(response)
+		}
+
+		if crawler.parseDocuments && urlStr != "" {
+			if kind := documentKind(urlStr, contentType, response.Body); kind != "" {
+				crawler.handleDocument(response, kind)
+			}
+		}
+
+		if crawler.linkfinder && (jsLike || (jsonLike && crawler.jsonLinkfinder)) {
+			if jsLike {
+				crawler.discoverSourceMap(response, respStr)
+			}
+
 			// LinkFinder from response body
-			paths, jsRequests, err := LinkFinder(respStr, response.Request.URL)
+			paths, jsRequests, err := crawler.linkFinder(respStr, response.Request.URL)
 			if err != nil {
 				Logger.Error(err)
 				if crawler.Stats != nil {
@@ -794,7 +1276,7 @@ func (crawler *Crawler) Start() {
 						crawler.feedLinkfinder(rebuildURL, "linkfinder", response.Request.URL.String())
 					} else {
 						if urlToVisit := crawler.urlProcessor.Process(rebuildURL, response.Request.URL.String(), "linkfinder", response.Request); urlToVisit != "" {
-							_ = response.Request.Visit(urlToVisit)
+							crawler.visit(response.Request, urlToVisit)
 						}
 					}
 				}
@@ -817,37 +1299,32 @@ func (crawler *Crawler) Start() {
 				outputFormat = fmt.Sprintf("[url] - [code-%d] - [len_%d] - %s", response.StatusCode, len(respStr), u)
 			}
 
-			if crawler.JsonOutput {
-				sout := SpiderOutput{
-					Input:      crawler.Input,
-					Source:     "body",
-					OutputType: "url",
-					StatusCode: response.StatusCode,
-					Output:     u,
-					Length:     strings.Count(respStr, "\n"),
-				}
-				if data, err := jsoniter.MarshalToString(sout); err == nil {
-					outputFormat = data
-				}
-			} else if crawler.Quiet {
-				outputFormat = u
+			sout := SpiderOutput{
+				Input:      crawler.Input,
+				Source:     "body",
+				OutputType: "url",
+				StatusCode: response.StatusCode,
+				Output:     u,
+				Length:     strings.Count(respStr, "\n"),
+				Depth:      response.Request.Depth,
 			}
-			fmt.Println(outputFormat)
-			if crawler.Output != nil {
-				crawler.Output.WriteToFile(outputFormat)
+			if crawler.takeArchived(u) {
+				outputFormat = tagArchived(&sout, outputFormat)
 			}
+			crawler.emit(sout, outputFormat, u)
 			if InScope(response.Request.URL, crawler.C.URLFilters) {
 				crawler.findSubdomains(respStr)
-				crawler.findAWSS3(respStr)
+				crawler.findSecrets(string(response.Body), response.Request.URL.String(), response.Request.Depth)
+				crawler.findCloudStorage(string(response.Body), response.Request.URL.String(), response.Request.Depth)
 			}
 
 			if crawler.raw {
 				outputFormat := fmt.Sprintf("[Raw] - \n%s\n", respStr)
-				if !crawler.Quiet {
-					fmt.Println(outputFormat)
+				if stdoutFormat, _ := crawler.outputFormats(); stdoutFormat != OutputFormatPlain {
+					crawler.println(outputFormat)
 				}
 				if crawler.Output != nil {
-					crawler.Output.WriteToFile(outputFormat)
+					crawler.Output.WriteRecord("raw", outputFormat)
 				}
 			}
 		}
@@ -858,78 +1335,208 @@ func (crawler *Crawler) Start() {
 			crawler.Stats.IncrementErrors()
 		}
 		Logger.Debugf("Error request: %s - Status code: %v - Error: %s", response.Request.URL.String(), response.StatusCode, err)
-		crawler.recordBackoff(response.StatusCode)
+		crawler.recordOpenAPI(response)
+		class := ClassifyRequestError(response.StatusCode, err)
+		// Slowing down does not help a host that cannot be resolved or reached over TLS
+		if class != ErrorClassDNS && class != ErrorClassTLS {
+			crawler.recordBackoff(response.StatusCode)
+		}
+		if response.Request != nil && response.Request.URL != nil {
+			crawler.observeRequestFailure(response.Request.URL.Hostname(), class)
+		}
+		crawler.recordFailedRequest(response, class, err)
+		if response.Request != nil && response.Request.URL != nil {
+			crawler.emitRedirectChain(response.Request.URL.String(), response.StatusCode)
+		}
 
-		if response.StatusCode == 404 || response.StatusCode == 429 || response.StatusCode < 100 || response.StatusCode >= 500 {
+		if response.StatusCode == http.StatusNotModified && crawler.validators != nil {
+			crawler.handleNotModified(response)
 			return
 		}
+		if crawler.reportErrors {
+			crawler.reportRequestError(response, class, err)
+		}
 
+		// Archived URLs are reported whatever the outcome: a 404 or a dead
+		// host is the answer to whether they are still live
 		u := NormalizeDisplayURL(response.Request.URL.String())
+		archived := crawler.takeArchived(u)
+		if !archived && (response.StatusCode == 404 || response.StatusCode == 429 || response.StatusCode < 100 || response.StatusCode >= 500) {
+			return
+		}
+
 		outputFormat := fmt.Sprintf("[url] - [code-%d] - %s", response.StatusCode, u)
 
-		if crawler.JsonOutput {
-			sout := SpiderOutput{
-				Input:      crawler.Input,
-				Source:     "body",
-				OutputType: "url",
-				StatusCode: response.StatusCode,
-				Output:     u,
-				Length:     strings.Count(DecodeChars(string(response.Body)), "\n"),
-			}
-			if data, err := jsoniter.MarshalToString(sout); err == nil {
-				outputFormat = data
-				fmt.Println(outputFormat)
-			}
-		} else if crawler.Quiet {
-			fmt.Println(u)
-		} else {
-			fmt.Println(outputFormat)
+		sout := SpiderOutput{
+			Input:      crawler.Input,
+			Source:     "body",
+			OutputType: "url",
+			StatusCode: response.StatusCode,
+			Output:     u,
+			Length:     strings.Count(DecodeChars(string(response.Body)), "\n"),
+			Depth:      response.Request.Depth,
 		}
-
-		if crawler.Output != nil {
-			crawler.Output.WriteToFile(outputFormat)
+		if archived {
+			outputFormat = tagArchived(&sout, outputFormat)
 		}
+		crawler.emit(sout, outputFormat, u)
 	})
 
+	// Registered last so a response counts once all its handlers have run, and
+	// before the seed sources below start sending requests
+	countHandled := func(*colly.Response) { crawler.handled.Add(1) }
+	countFailed := func(*colly.Response, error) { crawler.handled.Add(1) }
+	for _, collector := range []*colly.Collector{crawler.C, crawler.LinkFinderCollector} {
+		collector.OnScraped(countHandled)
+		collector.OnError(countFailed)
+	}
+
+	// Seed sources run in the background, or one after another when deterministic
+	background := func(f func()) {
+		if crawler.deterministic {
+			f()
+			return
+		}
+		go f()
+	}
+
 	var wg sync.WaitGroup
 	if crawler.sitemap {
 		wg.Add(1)
-		go ParseSiteMap(crawler.site, crawler, crawler.C, &wg)
+		background(func() { ParseSiteMap(crawler.site, crawler, crawler.C, &wg) })
 	}
 
 	if crawler.robots {
 		wg.Add(1)
-		go ParseRobots(crawler.site, crawler, crawler.C, &wg)
+		background(func() { ParseRobots(crawler.site, crawler, crawler.C, &wg) })
+	}
+
+	if crawler.wellKnown {
+		wg.Add(1)
+		background(func() { ParseWellKnown(crawler.site, crawler, crawler.C, &wg) })
+	}
+
+	if crawler.openAPI {
+		wg.Add(1)
+		background(func() { ProbeOpenAPI(crawler.site, crawler, &wg) })
+	}
+
+	if crawler.checkSensitive {
+		wg.Add(1)
+		background(func() {
+			defer wg.Done()
+			crawler.checkSensitiveFiles()
+		})
+	}
+
+	if len(crawler.apiRequests) > 0 {
+		wg.Add(1)
+		background(func() {
+			defer wg.Done()
+			crawler.seedRequests(postmanSource, crawler.apiRequests)
+		})
+	}
+
+	if len(crawler.harRequests) > 0 {
+		wg.Add(1)
+		background(func() {
+			defer wg.Done()
+			crawler.seedRequests(harSource, crawler.harRequests)
+		})
+	}
+
+	if len(crawler.burpRequests) > 0 {
+		wg.Add(1)
+		background(func() {
+			defer wg.Done()
+			crawler.seedRequests(burpSitemapSource, crawler.burpRequests)
+		})
 	}
 
 	if crawler.otherSource {
-		go func() {
-			urls := OtherSources(crawler.domain, crawler.includeSubs)
-			for _, url := range urls {
-				if urlToVisit := crawler.urlProcessor.Process(url, "other-source", "other", nil); urlToVisit != "" {
-					_ = crawler.C.Visit(urlToVisit)
-				}
-			}
-		}()
+		wg.Add(1)
+		background(func() {
+			defer wg.Done()
+			crawler.streamOtherSources()
+		})
 	}
 
 	if crawler.subs {
 		crawler.bootstrapSubdomains()
 	}
-	err := crawler.C.Visit(crawler.site.String())
-	if err != nil {
-		Logger.Errorf("Failed to start %s: %s", crawler.site.String(), err)
-		if crawler.Stats != nil {
-			crawler.Stats.IncrementErrors()
+	for _, page := range crawler.resumeHybrid {
+		crawler.enqueueHybrid(page)
+	}
+	if len(crawler.replay) > 0 {
+		crawler.replayFailedRequests()
+	} else if crawler.resumed {
+		Logger.Infof("Nothing outstanding for %s", crawler.site)
+	} else {
+		key := crawler.trackVisit(crawler.site.String())
+		if err := crawler.C.Visit(crawler.site.String()); err != nil {
+			Logger.Errorf("Failed to start %s: %s", crawler.site.String(), err)
+			crawler.state.Done(key)
+			if crawler.Stats != nil {
+				crawler.Stats.IncrementErrors()
+			}
 		}
 	}
 
 	wg.Wait()
 
-	// Wait for all collectors to finish
-	crawler.C.Wait()
-	crawler.LinkFinderCollector.Wait()
+	crawler.waitCollectors()
 	crawler.WaitHybrid()
+	crawler.flushArchived()
+	if !crawler.stopped.Load() && (crawler.ctx == nil || crawler.ctx.Err() == nil) {
+		crawler.state.Finish()
+	}
+}
+
+// waitCollectors waits for both collectors to finish. Handlers of each
+// queue requests on the other, such as pages found in a script, so the
+// waits repeat until a pass in which no response was handled
+func (crawler *Crawler) waitCollectors() {
+	for {
+		before := crawler.handled.Load()
+		crawler.C.Wait()
+		crawler.LinkFinderCollector.Wait()
+		if crawler.handled.Load() == before {
+			return
+		}
+	}
+}
+
+// streamOtherSources visits archive URLs as provider pages arrive, until
+// the crawl is stopped or cancelled
+func (crawler *Crawler) streamOtherSources() {
+	ctx := crawler.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-crawler.stopChan:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	StreamOtherSources(ctx, crawler.domain, crawler.includeSubs, crawler.otherSourceOptions, crawler.addArchivedURL)
+}
+
+func (crawler *Crawler) closeOutputs() {
+	// Shared per-host files are closed by their owner, usually the engine
+	if crawler.hostOutputs != nil {
+		if crawler.ownsHostOutputs {
+			crawler.hostOutputs.Close()
+		}
+	} else if crawler.Output != nil && !crawler.sharedOutput {
+		crawler.Output.Close()
+	}
+	if crawler.reflectedWriter != nil {
+		crawler.reflectedWriter.Close()
+	}
 }
 
 func (crawler *Crawler) bootstrapSubdomains() {
@@ -954,33 +1561,20 @@ func (crawler *Crawler) bootstrapSubdomains() {
 		}
 
 		logLine := "[subdomains] - " + sub
-		if crawler.JsonOutput {
-			sout := SpiderOutput{
-				Input:      crawler.Input,
-				Source:     "crt.sh",
-				OutputType: "subdomain",
-				Output:     sub,
-			}
-			if data, err := jsoniter.MarshalToString(sout); err == nil {
-				logLine = data
-			}
-		} else if crawler.Quiet {
-			logLine = sub
-		}
-
-		if !crawler.Quiet || crawler.JsonOutput {
-			fmt.Println(logLine)
-		}
-		if crawler.Output != nil {
-			crawler.Output.WriteToFile(logLine)
+		sout := SpiderOutput{
+			Input:      crawler.Input,
+			Source:     "crt.sh",
+			OutputType: "subdomain",
+			Output:     sub,
 		}
+		crawler.emit(sout, logLine, "")
 
 		for _, scheme := range []string{"https", "http"} {
 			seedURL := fmt.Sprintf("%s://%s", scheme, sub)
 			if crawler.isDuplicateURL(seedURL) {
 				continue
 			}
-			_ = crawler.C.Visit(seedURL)
+			crawler.visit(nil, seedURL)
 		}
 	}
 }
@@ -989,34 +1583,23 @@ func (crawler *Crawler) findSubdomains(resp string) {
 	if !crawler.subs {
 		return
 	}
+	stop := crawler.profile.start(extractorSubdomains)
 	subs := GetSubdomains(resp, crawler.domain)
+	stop()
 	for _, sub := range subs {
 		if !crawler.subSet.Duplicate(sub) {
 			if crawler.Stats != nil {
 				crawler.Stats.IncrementURLsFound()
 			}
-			outputFormat := fmt.Sprintf("[subdomains] - %s", sub)
-
-			if crawler.JsonOutput {
-				sout := SpiderOutput{
-					Input:      crawler.Input,
-					Source:     "body",
-					OutputType: "subdomain",
-					Output:     sub,
-				}
-				if data, err := jsoniter.MarshalToString(sout); err == nil {
-					outputFormat = data
-				}
-				fmt.Println(outputFormat)
-			} else if !crawler.Quiet {
-				outputFormat = fmt.Sprintf("[subdomains] - http://%s", sub)
-				fmt.Println(outputFormat)
-				outputFormat = fmt.Sprintf("[subdomains] - https://%s", sub)
-				fmt.Println(outputFormat)
-			}
-			if crawler.Output != nil {
-				crawler.Output.WriteToFile(outputFormat)
+			outputFormat := fmt.Sprintf("[subdomains] - http://%s\n[subdomains] - https://%s", sub, sub)
+
+			sout := SpiderOutput{
+				Input:      crawler.Input,
+				Source:     "body",
+				OutputType: "subdomain",
+				Output:     sub,
 			}
+			crawler.emit(sout, outputFormat, "")
 		}
 	}
 }
@@ -1034,6 +1617,9 @@ func (crawler *Crawler) recordBackoff(status int) {
 	switch status {
 	case http.StatusTooManyRequests:
 		crawler.backoff429++
+		if crawler.slowStart != nil {
+			crawler.slowStart.Restart()
+		}
 		sleep = time.Duration(minInt(crawler.backoff429, 5)) * time.Second
 	case http.StatusForbidden:
 		crawler.backoff403++
@@ -1060,34 +1646,6 @@ func minInt(a, b int) int {
 	return b
 }
 
-func (crawler *Crawler) findAWSS3(resp string) {
-	aws := GetAWSS3(resp)
-	for _, e := range aws {
-		if !crawler.awsSet.Duplicate(e) {
-			if crawler.Stats != nil {
-				crawler.Stats.IncrementURLsFound()
-			}
-			outputFormat := fmt.Sprintf("[aws-s3] - %s", e)
-			if crawler.JsonOutput {
-				sout := SpiderOutput{
-					Input:      crawler.Input,
-					Source:     "body",
-					OutputType: "aws",
-					Output:     e,
-				}
-				if data, err := jsoniter.MarshalToString(sout); err == nil {
-					outputFormat = data
-				}
-			}
-			fmt.Println(outputFormat)
-			if crawler.Output != nil {
-				crawler.Output.WriteToFile(outputFormat)
-			}
-		}
-	}
-}
-
-
 func (crawler *Crawler) initializeHybrid(cfg CrawlerConfig) {
 	if !cfg.HybridCrawl {
 		return
@@ -1119,8 +1677,11 @@ func (crawler *Crawler) initializeHybrid(cfg CrawlerConfig) {
 
 	poolCfg := BrowserPoolConfig{
 		PoolSize:           workers,
+		NavConcurrency:     cfg.HybridNavConcurrency,
 		NavigationTimeout:  navTimeout,
 		StabilizationDelay: stabilization,
+		WaitStrategy:       cfg.HybridWait,
+		IdleWindow:         cfg.HybridIdleWindow,
 		Headless:           &headless,
 		InitScripts:        initScripts,
 	}
@@ -1138,6 +1699,9 @@ func (crawler *Crawler) initializeHybrid(cfg CrawlerConfig) {
 	crawler.hybridWorkers = workers
 	crawler.hybridEnqueued = 0
 	crawler.hybridVisitCap = cfg.HybridVisitLimit
+	crawler.hybridSelective = cfg.HybridSelective
+	crawler.hybridMinLinks = cfg.HybridMinLinks
+	crawler.hybridThinText = cfg.HybridThinText
 	if crawler.hybridVisitCap <= 0 {
 		crawler.hybridVisitCap = 150
 	}
@@ -1199,6 +1763,9 @@ func (crawler *Crawler) hybridWorker() {
 				crawler.Stats.IncrementRequestsMade()
 			}
 			result, err := crawler.browserPool.NavigateAndAnalyze(crawler.hybridCtx, url, crawler.stateGraph)
+			if crawler.hybridCtx.Err() == nil && !crawler.stopped.Load() {
+				crawler.state.DoneHybrid(url)
+			}
 			if err != nil {
 				Logger.Debugf("hybrid analyze failed for %s: %v", url, err)
 				if crawler.Stats != nil {
@@ -1236,6 +1803,7 @@ func (crawler *Crawler) enqueueHybrid(raw string) {
 		return
 	case crawler.hybridQueue <- raw:
 		atomic.AddInt64(&crawler.hybridEnqueued, 1)
+		crawler.state.AddHybrid(raw)
 	default:
 		Logger.Debugf("hybrid queue saturated, dropping %s", raw)
 	}
@@ -1259,6 +1827,12 @@ func (crawler *Crawler) handleHybridResult(result *PageAnalysisResult) {
 		crawler.emitHybridAPICalls(result.URL, result.APICalls)
 	}
 
+	for _, ws := range result.WebSockets {
+		if u, ok := webSocketURL(ws, nil); ok {
+			crawler.emitWebSocket(u, result.URL, webSocketBrowser, "")
+		}
+	}
+
 	if crawler.Stats != nil {
 		crawler.Stats.AddURLsFound(len(result.Transitions))
 	}
@@ -1280,22 +1854,13 @@ func (crawler *Crawler) emitHybridAPICalls(origin string, calls []string) {
 		}
 
 		output := fmt.Sprintf("[hybrid][api] - %s", call)
-		if crawler.JsonOutput {
-			sout := SpiderOutput{
-				Input:      crawler.Input,
-				Source:     origin,
-				OutputType: "hybrid-api",
-				Output:     call,
-			}
-			if data, err := jsoniter.MarshalToString(sout); err == nil {
-				output = data
-			}
-		}
-
-		fmt.Println(output)
-		if crawler.Output != nil {
-			crawler.Output.WriteToFile(output)
+		sout := SpiderOutput{
+			Input:      crawler.Input,
+			Source:     origin,
+			OutputType: "hybrid-api",
+			Output:     call,
 		}
+		crawler.emit(sout, output, output)
 	}
 }
 
@@ -1346,7 +1911,7 @@ func (crawler *Crawler) scheduleHybridVisit(origin, candidate string) {
 	}
 
 	if !crawler.isDuplicateURL(normalized) {
-		_ = crawler.C.Visit(normalized)
+		crawler.visit(nil, normalized)
 	}
 
 	crawler.enqueueHybrid(normalized)
@@ -15,7 +15,7 @@ import (
 	"strings"
 
 	"github.com/gocolly/colly/v2"
-	jsoniter "github.com/json-iterator/go"
+	"github.com/jaeles-project/gospider/core/report"
 )
 
 const (
@@ -41,7 +41,10 @@ type reflectionEntry struct {
 	origin             string
 	param              string
 	payload            string
+	depth              int
 	mutatedMarkers     []string
+	mutatedHeader      http.Header
+	mutatedBody        string
 	emitted            bool
 	mutationsScheduled int
 	mutatedParams      map[string]struct{}
@@ -56,6 +59,9 @@ type reflectionFinding struct {
 	Param   string
 	Payload string
 	Reasons []string
+	Depth   int
+	Header  http.Header
+	Body    string
 }
 
 const reflectionPayloadPlaceholder = "__payload__"
@@ -225,7 +231,11 @@ func (crawler *Crawler) processGeneratedRequest(req JSRequest, origin string, pa
 	if !ok {
 		return
 	}
-	if !crawler.emitJSRequest(normalized, origin) {
+	if !crawler.emitJSRequest(normalized, origin, parentDepth+1) {
+		return
+	}
+	// A logout form or script call is reported above but never sent
+	if crawler.skipSessionEndpoint(normalized.RawURL, "") {
 		return
 	}
 	crawler.scheduleJSRequest(normalized, origin, parentDepth)
@@ -369,6 +379,13 @@ func (crawler *Crawler) queueRequest(req JSRequest, origin string, reflected boo
 	if crawler.isDuplicateRequest(method, req.RawURL, req.Body) {
 		return
 	}
+	if crawler.params != nil && !reflected {
+		crawler.params.AddURL(req.RawURL)
+		crawler.params.AddBody(req.Body, req.ContentType)
+	}
+	if crawler.words != nil && !reflected {
+		crawler.words.AddPath(req.RawURL)
+	}
 
 	headers := http.Header{}
 	for k, v := range req.Headers {
@@ -385,6 +402,10 @@ func (crawler *Crawler) queueRequest(req JSRequest, origin string, reflected boo
 
 	ctx := colly.NewContext()
 	ctx.Put("method", method)
+	if bodyReader != nil {
+		// Kept for --failed-output, as colly consumes the reader
+		ctx.Put("body", req.Body)
+	}
 	ctx.Put("__depth", strconv.Itoa(nextDepth))
 	ctx.Put("origin", origin)
 	if baselineKey == "" {
@@ -427,9 +448,7 @@ func (crawler *Crawler) queueRequest(req JSRequest, origin string, reflected boo
 		crawler.maybeThrottleMutations(reflected)
 	}
 
-	if err := crawler.C.Request(method, req.RawURL, bodyReader, ctx, headers); err != nil {
-		Logger.Debugf("failed to queue request %s %s: %v", method, req.RawURL, err)
-	}
+	crawler.submitRequest(method, req.RawURL, bodyReader, ctx, headers)
 }
 
 func (crawler *Crawler) buildReflectedRequests(req JSRequest, aggressive bool, budget int) []reflectionMutation {
@@ -838,6 +857,7 @@ func appendUniqueMarker(list []string, marker string) []string {
 }
 
 func (crawler *Crawler) handleBaselineReflection(response *colly.Response) {
+	defer crawler.profile.start(extractorReflection)()
 	if !crawler.reflected || response.Ctx == nil {
 		return
 	}
@@ -874,6 +894,7 @@ func (crawler *Crawler) handleBaselineReflection(response *colly.Response) {
 }
 
 func (crawler *Crawler) handleReflectedResponse(response *colly.Response) {
+	defer crawler.profile.start(extractorReflection)()
 	if response.Ctx == nil {
 		return
 	}
@@ -905,7 +926,12 @@ func (crawler *Crawler) handleReflectedResponse(response *colly.Response) {
 	entry.mutatedLen = len(body)
 	entry.mutatedContains = contains
 	entry.mutatedMarkers = reasons
+	if response.Request.Headers != nil {
+		entry.mutatedHeader = response.Request.Headers.Clone()
+	}
+	entry.mutatedBody = response.Ctx.Get("body")
 	entry.url = response.Request.URL.String()
+	entry.depth = response.Request.Depth
 	if entry.method == "" {
 		entry.method = response.Ctx.Get("method")
 	}
@@ -982,6 +1008,9 @@ func (entry *reflectionEntry) evaluate() *reflectionFinding {
 		Param:   entry.param,
 		Payload: entry.payload,
 		Reasons: reasons,
+		Depth:   entry.depth,
+		Header:  entry.mutatedHeader,
+		Body:    entry.mutatedBody,
 	}
 }
 
@@ -997,35 +1026,32 @@ func (crawler *Crawler) outputReflection(f reflectionFinding) {
 	}
 	reason := strings.Join(f.Reasons, ",")
 	rendered := fmt.Sprintf("%s %s param:%s payload:%s (%s)", method, f.URL, param, payload, reason)
-	output := rendered
-
-	if crawler.JsonOutput {
-		sout := SpiderOutput{
-			Input:      crawler.Input,
-			Source:     f.Origin,
-			OutputType: "reflected",
-			Output:     f.URL,
-			StatusCode: f.Status,
-			Length:     f.Length,
-			Param:      param,
-			Payload:    payload,
-		}
-		if data, err := jsoniter.MarshalToString(sout); err == nil {
-			output = data
-		}
-	} else if crawler.Quiet {
-		output = f.URL
-	}
 
-	if !crawler.Quiet || crawler.JsonOutput {
-		fmt.Println(output)
-	} else if crawler.Quiet {
-		fmt.Println(output)
-	}
-	if crawler.Output != nil {
-		crawler.Output.WriteToFile(output)
-	}
+	sout := SpiderOutput{
+		Input:      crawler.Input,
+		Source:     f.Origin,
+		OutputType: "reflected",
+		Output:     f.URL,
+		StatusCode: f.Status,
+		Length:     f.Length,
+		Param:      param,
+		Payload:    payload,
+		Depth:      f.Depth,
+	}
+	crawler.emit(sout, rendered, f.URL)
 	if crawler.reflectedWriter != nil {
 		crawler.reflectedWriter.WriteToFile(rendered)
 	}
+	crawler.writeReplay("reflected", replayRequest{Method: method, URL: f.URL, Header: f.Header, Body: f.Body})
+	if crawler.sarif != nil {
+		crawler.sarif.AddReflection(report.Reflection{
+			URL:     f.URL,
+			Method:  method,
+			Param:   param,
+			Payload: payload,
+			Origin:  f.Origin,
+			Status:  f.Status,
+			Reasons: f.Reasons,
+		})
+	}
 }
\ No newline at end of file
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// dependencyKind classifies a <script> or <link> element as a script or
+// stylesheet the page loads, or returns "" for other links
+func dependencyKind(e *colly.HTMLElement) string {
+	if e.Name == "script" {
+		return "script"
+	}
+	rels := strings.Fields(strings.ToLower(e.Attr("rel")))
+	for _, rel := range rels {
+		switch rel {
+		case "stylesheet":
+			return "stylesheet"
+		case "modulepreload":
+			return "script"
+		}
+	}
+	for _, rel := range rels {
+		if rel == "preload" || rel == "prefetch" {
+			switch strings.ToLower(e.Attr("as")) {
+			case "script":
+				return "script"
+			case "style":
+				return "stylesheet"
+			}
+		}
+	}
+	return ""
+}
+
+// recordDependency reports a script or stylesheet served from another origin
+// than the page, with its subresource integrity hash if it is pinned
+func (crawler *Crawler) recordDependency(e *colly.HTMLElement, raw string) {
+	if !crawler.dependencies || crawler.dependencySet == nil {
+		return
+	}
+	kind := dependencyKind(e)
+	if kind == "" {
+		return
+	}
+	// Not NormalizeURL, whose crawl exclusions drop stylesheets and CDN paths
+	u, err := crawler.documentBase(e).Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return
+	}
+	u.Fragment = ""
+	resolved := u.String()
+	page := e.Request.URL
+	if strings.EqualFold(u.Scheme, page.Scheme) && strings.EqualFold(u.Host, page.Host) {
+		return
+	}
+	if crawler.dependencySet.Duplicate(resolved) {
+		return
+	}
+
+	integrity := strings.TrimSpace(e.Attr("integrity"))
+	sri := "[no-sri]"
+	snippet := "subresource integrity missing"
+	if integrity != "" {
+		sri = "[" + integrity + "]"
+		snippet = ""
+	}
+	outputFormat := fmt.Sprintf("[dependency] - [%s] - %s - %s - %s", kind, u.Host, resolved, sri)
+	sout := SpiderOutput{
+		Input:      crawler.Input,
+		Source:     page.String(),
+		OutputType: "dependency",
+		Output:     resolved,
+		Param:      kind,
+		Payload:    integrity,
+		Snippet:    snippet,
+		Depth:      e.Request.Depth,
+	}
+	crawler.emit(sout, outputFormat, resolved)
+}
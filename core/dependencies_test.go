@@ -0,0 +1,55 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCrawlReportsDependencies(t *testing.T) {
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer cdn.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><head>
+<script src="%[1]s/lib/jquery.min.js" integrity="sha384-abc" crossorigin="anonymous"></script>
+<link rel="stylesheet" href="%[1]s/css/theme.css">
+<link rel="modulepreload" href="%[1]s/esm/app.mjs">
+<link rel="icon" href="%[1]s/favicon.ico">
+<script src="/static/own.js"></script>
+</head><body><a href="/again">again</a><script src="%[1]s/lib/jquery.min.js"></script></body></html>`, cdn.URL)
+	}))
+	defer srv.Close()
+
+	crawl := func(enabled bool) map[string]SpiderOutput {
+		results := crawlAll(t, srv.URL, CrawlerConfig{Dependencies: enabled})
+		found := map[string]SpiderOutput{}
+		for _, sout := range results {
+			if sout.OutputType == "dependency" {
+				_, dup := found[sout.Output]
+				assert.False(t, dup, "%s reported twice", sout.Output)
+				found[sout.Output] = sout
+			}
+		}
+		return found
+	}
+
+	found := crawl(true)
+	require.Len(t, found, 3)
+	jquery := found[cdn.URL+"/lib/jquery.min.js"]
+	assert.Equal(t, "script", jquery.Param)
+	assert.Equal(t, "sha384-abc", jquery.Payload)
+	assert.Empty(t, jquery.Snippet)
+	css := found[cdn.URL+"/css/theme.css"]
+	assert.Equal(t, "stylesheet", css.Param)
+	assert.Empty(t, css.Payload)
+	assert.Equal(t, "subresource integrity missing", css.Snippet)
+	assert.Equal(t, "script", found[cdn.URL+"/esm/app.mjs"].Param)
+
+	assert.Empty(t, crawl(false))
+}
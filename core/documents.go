@@ -0,0 +1,238 @@
+package core
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/ledongthuc/pdf"
+)
+
+// maxDocumentSize caps the PDF and Office files parsed by --parse-documents
+const maxDocumentSize = 10 * 1024 * 1024
+
+var (
+	pdfMagic    = []byte("%PDF-")
+	zipMagic    = []byte("PK\x03\x04")
+	pdfURIRegex = regexp.MustCompile(`/URI\s*\(([^)]+)\)`)
+	ooxmlExts   = map[string]bool{".docx": true, ".xlsx": true, ".pptx": true, ".docm": true, ".xlsm": true, ".pptm": true}
+)
+
+// DocumentField is a metadata value found in a document, e.g. author
+type DocumentField struct {
+	Name  string
+	Value string
+}
+
+// DocumentInfo holds the metadata and embedded links of a parsed document
+type DocumentInfo struct {
+	Fields []DocumentField
+	URLs   []string
+}
+
+// isDocumentCandidate reports whether the URL or content type suggests a
+// PDF or Office document
+func isDocumentCandidate(rawURL, contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	ext := GetExtType(rawURL)
+	return strings.Contains(contentType, "application/pdf") || strings.Contains(contentType, "officedocument") ||
+		ext == ".pdf" || ooxmlExts[ext]
+}
+
+// documentKind returns "pdf", "ooxml" or "" for a response
+func documentKind(rawURL, contentType string, body []byte) string {
+	if bytes.HasPrefix(body, pdfMagic) {
+		return "pdf"
+	}
+	if bytes.HasPrefix(body, zipMagic) && isDocumentCandidate(rawURL, contentType) {
+		return "ooxml"
+	}
+	return ""
+}
+
+// ParsePDF reads the document info dictionary and link annotation URIs of a PDF
+func ParsePDF(data []byte) (info DocumentInfo, err error) {
+	// The PDF reader panics on some malformed files
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed pdf: %v", r)
+		}
+	}()
+
+	// Uncompressed link annotations are visible even if the xref is broken
+	for _, m := range pdfURIRegex.FindAllSubmatch(data, -1) {
+		info.URLs = append(info.URLs, string(m[1]))
+	}
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		info.URLs = Unique(info.URLs)
+		return info, err
+	}
+	meta := reader.Trailer().Key("Info")
+	for _, key := range []string{"Author", "Creator", "Producer", "Title", "Subject"} {
+		if value := strings.TrimSpace(meta.Key(key).Text()); value != "" {
+			info.Fields = append(info.Fields, DocumentField{Name: strings.ToLower(key), Value: value})
+		}
+	}
+	for i := 1; i <= reader.NumPage(); i++ {
+		annots := reader.Page(i).V.Key("Annots")
+		for j := 0; j < annots.Len(); j++ {
+			if uri := strings.TrimSpace(annots.Index(j).Key("A").Key("URI").RawString()); uri != "" {
+				info.URLs = append(info.URLs, uri)
+			}
+		}
+	}
+	info.URLs = Unique(info.URLs)
+	return info, nil
+}
+
+type ooxmlRelationships struct {
+	Relationships []struct {
+		Target     string `xml:"Target,attr"`
+		TargetMode string `xml:"TargetMode,attr"`
+	} `xml:"Relationship"`
+}
+
+// ooxmlProperties covers docProps/core.xml and docProps/app.xml; the
+// namespaces differ but the local names do not collide
+type ooxmlProperties struct {
+	Creator        string `xml:"creator"`
+	LastModifiedBy string `xml:"lastModifiedBy"`
+	Title          string `xml:"title"`
+	Application    string `xml:"Application"`
+	Company        string `xml:"Company"`
+	Manager        string `xml:"Manager"`
+}
+
+// ParseOOXML reads the properties and external relationships of a DOCX, XLSX
+// or PPTX file. External targets that are not web URLs, such as template
+// paths, are returned as fields since they often leak usernames
+func ParseOOXML(data []byte) (DocumentInfo, error) {
+	var info DocumentInfo
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return info, err
+	}
+
+	var paths []string
+	for _, file := range archive.File {
+		name := file.Name
+		switch {
+		case name == "docProps/core.xml" || name == "docProps/app.xml":
+			var props ooxmlProperties
+			if err := decodeZipXML(file, &props); err != nil {
+				continue
+			}
+			for _, field := range []DocumentField{
+				{"author", props.Creator},
+				{"last-modified-by", props.LastModifiedBy},
+				{"title", props.Title},
+				{"creator", props.Application},
+				{"company", props.Company},
+				{"manager", props.Manager},
+			} {
+				if field.Value = strings.TrimSpace(field.Value); field.Value != "" {
+					info.Fields = append(info.Fields, field)
+				}
+			}
+		case path.Ext(name) == ".rels":
+			var rels ooxmlRelationships
+			if err := decodeZipXML(file, &rels); err != nil {
+				continue
+			}
+			for _, rel := range rels.Relationships {
+				if !strings.EqualFold(rel.TargetMode, "External") {
+					continue
+				}
+				target := strings.TrimSpace(rel.Target)
+				lower := strings.ToLower(target)
+				if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") {
+					info.URLs = append(info.URLs, target)
+				} else if target != "" && !strings.HasPrefix(lower, "mailto:") {
+					paths = append(paths, target)
+				}
+			}
+		}
+	}
+	for _, p := range Unique(paths) {
+		info.Fields = append(info.Fields, DocumentField{Name: "external-path", Value: p})
+	}
+	info.URLs = Unique(info.URLs)
+	return info, nil
+}
+
+func decodeZipXML(file *zip.File, v interface{}) error {
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(io.LimitReader(rc, maxDocumentSize)).Decode(v)
+}
+
+// skipOversizedDocument aborts document downloads whose declared size exceeds the cap
+func (crawler *Crawler) skipOversizedDocument(response *colly.Response) {
+	if response.Request == nil || response.Request.URL == nil {
+		return
+	}
+	if !isDocumentCandidate(response.Request.URL.String(), response.Headers.Get("Content-Type")) {
+		return
+	}
+	if size, err := strconv.ParseInt(response.Headers.Get("Content-Length"), 10, 64); err == nil && size > maxDocumentSize {
+		Logger.Debugf("Skipping document %s: %d bytes exceeds cap", response.Request.URL, size)
+		response.Request.Abort()
+	}
+}
+
+// handleDocument reports a document's metadata and queues its embedded URLs
+func (crawler *Crawler) handleDocument(response *colly.Response, kind string) {
+	docURL := response.Request.URL.String()
+	if len(response.Body) > maxDocumentSize {
+		Logger.Debugf("Skipping document %s: %d bytes exceeds cap", docURL, len(response.Body))
+		return
+	}
+
+	var info DocumentInfo
+	var err error
+	if kind == "pdf" {
+		info, err = ParsePDF(response.Body)
+	} else {
+		info, err = ParseOOXML(response.Body)
+	}
+	if err != nil {
+		Logger.Debugf("Failed to parse document %s: %s", docURL, err)
+	}
+
+	for _, field := range info.Fields {
+		crawler.emitDocumentField(docURL, field)
+	}
+	for _, raw := range info.URLs {
+		if urlToVisit := crawler.urlProcessor.Process(raw, docURL, "document", response.Request); urlToVisit != "" {
+			crawler.visit(nil, urlToVisit)
+		}
+	}
+}
+
+func (crawler *Crawler) emitDocumentField(docURL string, field DocumentField) {
+	value := fmt.Sprintf("%s: %s", field.Name, field.Value)
+	if crawler.documentSet.Duplicate(docURL + "\x00" + value) {
+		return
+	}
+	outputFormat := fmt.Sprintf("[document-meta] - %s - %s", docURL, value)
+
+	sout := SpiderOutput{
+		Input:      crawler.Input,
+		Source:     docURL,
+		OutputType: "document-meta",
+		Output:     value,
+	}
+	crawler.emit(sout, outputFormat, value)
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildPDF assembles a one-page PDF with an info dictionary and a link annotation
+func buildPDF() []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Annots [4 0 R] >>",
+		"<< /Type /Annot /Subtype /Link /Rect [0 0 10 10] /A << /S /URI /URI (https://intranet.example.com/wiki) >> >>",
+		"<< /Author (jdoe) /Creator (Microsoft Word) /Producer (Acrobat Distiller 9.0) >>",
+	}
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func TestParsePDF(t *testing.T) {
+	data := buildPDF()
+	assert.Equal(t, "pdf", documentKind("https://example.com/report", "application/pdf", data))
+
+	info, err := ParsePDF(data)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://intranet.example.com/wiki"}, info.URLs)
+	assert.Equal(t, []DocumentField{
+		{"author", "jdoe"},
+		{"creator", "Microsoft Word"},
+		{"producer", "Acrobat Distiller 9.0"},
+	}, info.Fields)
+
+	_, err = ParsePDF([]byte("%PDF-1.4 truncated"))
+	assert.Error(t, err)
+}
+
+func TestParseOOXML(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := map[string]string{
+		"docProps/core.xml": `<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:creator>Jane Roe</dc:creator><cp:lastModifiedBy>admin</cp:lastModifiedBy></cp:coreProperties>`,
+		"docProps/app.xml":  `<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/extended-properties"><Application>Microsoft Office Word</Application><Company>ACME</Company></Properties>`,
+		"word/_rels/document.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://portal.example.com/hr" TargetMode="External"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>`,
+		"word/_rels/settings.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/attachedTemplate" Target="file:///C:\Users\jroe\Templates\Normal.dotm" TargetMode="External"/></Relationships>`,
+	}
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, _ = w.Write([]byte(content))
+	}
+	require.NoError(t, zw.Close())
+
+	data := buf.Bytes()
+	assert.Equal(t, "ooxml", documentKind("https://example.com/files/plan.docx", "", data))
+	assert.Equal(t, "", documentKind("https://example.com/archive.zip", "application/zip", data))
+
+	info, err := ParseOOXML(data)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://portal.example.com/hr"}, info.URLs)
+	assert.ElementsMatch(t, []DocumentField{
+		{"author", "Jane Roe"},
+		{"last-modified-by", "admin"},
+		{"creator", "Microsoft Office Word"},
+		{"company", "ACME"},
+		{"external-path", `file:///C:\Users\jroe\Templates\Normal.dotm`},
+	}, info.Fields)
+}
@@ -16,10 +16,13 @@ type domRule struct {
 // DOMFinding captures a static sink suspicion discovered during passive analysis.
 type DOMFinding struct {
 	URL        string
+	Rule       string
 	Source     string
 	Sink       string
 	Snippet    string
 	Confidence string
+	// Line is the 1-based line of the snippet in the analysed code
+	Line int
 }
 
 // DOMAnalyzer scans HTML/JS for common DOM sink antipatterns inspired by domdig.
@@ -95,10 +98,12 @@ func (a *DOMAnalyzer) Analyze(url, code, sourceLabel string) []DOMFinding {
 			a.seen[key] = struct{}{}
 			findings = append(findings, DOMFinding{
 				URL:        url,
+				Rule:       rule.name,
 				Source:     sourceLabel,
 				Sink:       sinkName,
 				Snippet:    snippet,
 				Confidence: rule.confidence,
+				Line:       strings.Count(code[:idxs[0]], "\n") + 1,
 			})
 		}
 	}
@@ -137,6 +137,11 @@ func isLikelyHTML(contentType string, body []byte) bool {
 	return bytes.HasPrefix(lower, []byte("<!doctype html")) || bytes.HasPrefix(lower, []byte("<html"))
 }
 
+func isLikelyJSON(contentType string) bool {
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	return strings.Contains(contentType, "application/json") || strings.HasSuffix(contentType, "+json")
+}
+
 func isLikelyJS(contentType string, body []byte) bool {
 	contentType = strings.ToLower(strings.TrimSpace(contentType))
 	if strings.Contains(contentType, "javascript") || strings.Contains(contentType, "ecmascript") {
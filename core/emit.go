@@ -0,0 +1,257 @@
+package core
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// Formats accepted by --stdout-format and --file-format
+const (
+	OutputFormatText  = "text"
+	OutputFormatPlain = "plain"
+	OutputFormatJSON  = "json"
+)
+
+// ResolveOutputFormats validates --stdout-format and --file-format against
+// the older --json and --quiet switches. An empty stdout format follows
+// those switches and an empty file format follows stdout
+func ResolveOutputFormats(stdoutFormat, fileFormat string, jsonOutput, quiet, hasOutputDir bool) (string, string, error) {
+	legacy := OutputFormatText
+	switch {
+	case jsonOutput:
+		legacy = OutputFormatJSON
+	case quiet:
+		legacy = OutputFormatPlain
+	}
+
+	for flag, format := range map[string]string{"--stdout-format": stdoutFormat, "--file-format": fileFormat} {
+		switch format {
+		case "", OutputFormatText, OutputFormatPlain, OutputFormatJSON:
+		default:
+			return "", "", fmt.Errorf("invalid %s %q, expected %s, %s or %s", flag, format, OutputFormatText, OutputFormatPlain, OutputFormatJSON)
+		}
+	}
+
+	if stdoutFormat == "" {
+		stdoutFormat = legacy
+	} else if (jsonOutput || quiet) && stdoutFormat != legacy {
+		return "", "", fmt.Errorf("--stdout-format %s conflicts with --json/--quiet", stdoutFormat)
+	}
+	if fileFormat == "" {
+		fileFormat = stdoutFormat
+	} else if !hasOutputDir {
+		return "", "", fmt.Errorf("--file-format needs an output folder (-o)")
+	}
+	return stdoutFormat, fileFormat, nil
+}
+
+// outputFormats returns the stdout and file formats, deriving them from
+// JsonOutput and Quiet when the crawler was built without explicit ones
+func (crawler *Crawler) outputFormats() (string, string) {
+	stdoutFormat, fileFormat := crawler.stdoutFormat, crawler.fileFormat
+	if stdoutFormat == "" {
+		stdoutFormat, _, _ = ResolveOutputFormats("", "", crawler.JsonOutput, crawler.Quiet, false)
+	}
+	if fileFormat == "" {
+		fileFormat = stdoutFormat
+	}
+	return stdoutFormat, fileFormat
+}
+
+// emit writes a finding to every output sink. text is the
+// "[type] - ..." line and plain the bare value; an empty plain keeps the
+// finding off plain stdout while a plain file falls back to text, so the
+// file never loses records
+func (crawler *Crawler) emit(sout SpiderOutput, text, plain string) {
+	if crawler.inScopeOnly && !crawler.outputInScope(sout) {
+		return
+	}
+	if !crawler.statusReported(sout.StatusCode) {
+		return
+	}
+	if crawler.normalizeUnicode {
+		unicodeDisplay(&sout, &text, &plain)
+	}
+	for _, sink := range crawler.outputSinks() {
+		var err error
+		if ls, ok := sink.(lineSink); ok {
+			err = ls.writeLine(sout, text, plain)
+		} else {
+			err = sink.Write(sout)
+		}
+		if err != nil {
+			sinkErrors.report(sink, sout, err)
+		}
+	}
+}
+
+// sinkErrorInterval is how often a failing kind of output sink is warned about
+const sinkErrorInterval = time.Minute
+
+// sinkErrorLog warns about output sink failures once per sink type and
+// interval, counting the failures it held back in between. Sinks are keyed
+// by type because stdout and file sinks are built afresh for each finding
+type sinkErrorLog struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+	held map[string]int
+}
+
+var sinkErrors = &sinkErrorLog{last: map[string]time.Time{}, held: map[string]int{}}
+
+func (l *sinkErrorLog) report(sink OutputSink, sout SpiderOutput, err error) {
+	kind := fmt.Sprintf("%T", sink)
+	now := time.Now()
+	l.mu.Lock()
+	if last, ok := l.last[kind]; ok && now.Sub(last) < sinkErrorInterval {
+		l.held[kind]++
+		l.mu.Unlock()
+		Logger.Debugf("Output sink %s failed on %s: %s", kind, sout.Output, err)
+		return
+	}
+	held := l.held[kind]
+	l.last[kind] = now
+	l.held[kind] = 0
+	l.mu.Unlock()
+	if held > 0 {
+		Logger.Warnf("Output sink %s failed on %s: %s (%d more failures since the last warning)", kind, sout.Output, err, held)
+		return
+	}
+	Logger.Warnf("Output sink %s failed on %s: %s", kind, sout.Output, err)
+}
+
+// hostFinding reports whether findings of a type are bare host names
+func hostFinding(outputType string) bool {
+	return outputType == "subdomain" || outputType == "san"
+}
+
+// outputInScope reports whether a finding's URL matches the crawl scope.
+// Subdomains and certificate SANs are judged as hosts, and findings that
+// carry no URL at all are kept
+func (crawler *Crawler) outputInScope(sout SpiderOutput) bool {
+	if crawler.C == nil || len(crawler.C.URLFilters) == 0 {
+		return true
+	}
+	raw := sout.Output
+	if hostFinding(sout.OutputType) && !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u := outputURL(raw)
+	if u == nil {
+		return true
+	}
+	return InScope(u, crawler.C.URLFilters)
+}
+
+// outputURL returns the http(s) URL an Output value refers to. Requests such
+// as "POST https://..." carry it in their last field
+func outputURL(output string) *url.URL {
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return nil
+	}
+	u, err := url.Parse(fields[len(fields)-1])
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil
+	}
+	return u
+}
+
+// outputTemplateFuncs are the helpers --template gets on top of the
+// SpiderOutput fields. Each takes an Output value and returns "" when it
+// holds no URL
+var outputTemplateFuncs = template.FuncMap{
+	"host": func(output string) string {
+		if u := outputURL(output); u != nil {
+			return u.Host
+		}
+		return ""
+	},
+	"path": func(output string) string {
+		if u := outputURL(output); u != nil {
+			return u.EscapedPath()
+		}
+		return ""
+	},
+	"query": func(output string) string {
+		if u := outputURL(output); u != nil {
+			return u.RawQuery
+		}
+		return ""
+	},
+	"scheme": func(output string) string {
+		if u := outputURL(output); u != nil {
+			return u.Scheme
+		}
+		return ""
+	},
+}
+
+// ParseOutputTemplate parses a --template value such as
+// "{{.Output}} {{.StatusCode}}". An empty value returns nil
+func ParseOutputTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("output").Funcs(outputTemplateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// renderLine renders one line of a finding. --template replaces the text
+// and plain formats; JSON stays JSON so it remains machine-readable
+func renderLine(format string, tmpl *template.Template, sout SpiderOutput, text, plain string) string {
+	if tmpl != nil && format != OutputFormatJSON {
+		var buf strings.Builder
+		err := tmpl.Execute(&buf, sout)
+		if err == nil {
+			return buf.String()
+		}
+		Logger.Debugf("--template failed on %s: %s", sout.Output, err)
+	}
+	return renderOutput(format, sout, text, plain)
+}
+
+// statusReported applies --exclude-status and --include-status. Findings
+// that carry no status, such as linkfinder leads, are always kept
+func (crawler *Crawler) statusReported(status int) bool {
+	if status == 0 {
+		return true
+	}
+	if crawler.excludeStatus[status] {
+		return false
+	}
+	return len(crawler.includeStatus) == 0 || crawler.includeStatus[status]
+}
+
+// statusSet returns nil for an empty list so an unset filter costs nothing
+func statusSet(codes []int) map[int]bool {
+	if len(codes) == 0 {
+		return nil
+	}
+	set := make(map[int]bool, len(codes))
+	for _, code := range codes {
+		set[code] = true
+	}
+	return set
+}
+
+func renderOutput(format string, sout SpiderOutput, text, plain string) string {
+	switch format {
+	case OutputFormatJSON:
+		if data, err := jsoniter.MarshalToString(sout); err == nil {
+			return data
+		}
+	case OutputFormatPlain:
+		return plain
+	}
+	return text
+}
@@ -0,0 +1,187 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveOutputFormats(t *testing.T) {
+	cases := []struct {
+		name                 string
+		stdout, file         string
+		json, quiet, hasDir  bool
+		wantStdout, wantFile string
+		wantErr              bool
+	}{
+		{name: "defaults", wantStdout: "text", wantFile: "text"},
+		{name: "json", json: true, wantStdout: "json", wantFile: "json"},
+		{name: "quiet", quiet: true, wantStdout: "plain", wantFile: "plain"},
+		{name: "independent", stdout: "plain", file: "json", hasDir: true, wantStdout: "plain", wantFile: "json"},
+		{name: "quiet with json file", quiet: true, file: "json", hasDir: true, wantStdout: "plain", wantFile: "json"},
+		{name: "unknown format", stdout: "xml", wantErr: true},
+		{name: "conflicts with json", json: true, stdout: "plain", wantErr: true},
+		{name: "file format without dir", file: "json", wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout, file, err := ResolveOutputFormats(tc.stdout, tc.file, tc.json, tc.quiet, tc.hasDir)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantStdout, stdout)
+			assert.Equal(t, tc.wantFile, file)
+		})
+	}
+}
+
+type recordingSink struct {
+	results []SpiderOutput
+}
+
+func (s *recordingSink) Write(sout SpiderOutput) error {
+	s.results = append(s.results, sout)
+	return nil
+}
+
+func (s *recordingSink) Close() {}
+
+type failingSink struct{}
+
+func (failingSink) Write(SpiderOutput) error { return errors.New("disk full") }
+func (failingSink) Close()                   {}
+
+func TestEmitWarnsOncePerFailingSink(t *testing.T) {
+	var logged bytes.Buffer
+	Logger.SetOutput(&logged)
+	defer Logger.SetOutput(os.Stderr)
+	crawler := &Crawler{Input: "http://example.com", sink: &recordingSink{}, extraSinks: []OutputSink{failingSink{}}}
+
+	for _, path := range []string{"/a", "/b", "/c"} {
+		crawler.emit(SpiderOutput{Input: crawler.Input, Output: "http://example.com" + path, OutputType: "url"}, "[url] - http://example.com"+path, "http://example.com"+path)
+	}
+
+	assert.Equal(t, 1, strings.Count(logged.String(), "disk full"), "a failing sink is warned about once, not per finding")
+}
+
+func TestEmitRendersFilePerFormat(t *testing.T) {
+	dir := t.TempDir()
+	out := NewOutput(dir, "example_com")
+	sink := &recordingSink{}
+	crawler := &Crawler{Input: "http://example.com", Output: out, sink: sink, fileFormat: OutputFormatJSON}
+
+	crawler.emit(SpiderOutput{Input: crawler.Input, Source: "body", OutputType: "url", Output: "http://example.com/a"}, "[url] - http://example.com/a", "http://example.com/a")
+	crawler.fileFormat = OutputFormatPlain
+	crawler.emit(SpiderOutput{Input: crawler.Input, Source: "body", OutputType: "form", Output: "http://example.com/login"}, "[form] - http://example.com/login", "")
+	out.Close()
+	assert.Len(t, sink.results, 2)
+
+	data, err := os.ReadFile(filepath.Join(dir, "example_com"))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], "{") && strings.Contains(lines[0], `"output":"http://example.com/a"`), lines[0])
+	assert.Equal(t, "[form] - http://example.com/login", lines[1], "a plain file falls back to text for findings without a bare value")
+}
+
+func TestEmitInScopeOnly(t *testing.T) {
+	sink := &recordingSink{}
+	c := colly.NewCollector()
+	c.URLFilters = []*regexp.Regexp{regexp.MustCompile(ScopePattern(&url.URL{Scheme: "http", Host: "example.com"}, true))}
+	crawler := &Crawler{Input: "http://example.com", C: c, sink: sink, inScopeOnly: true}
+
+	for _, sout := range []SpiderOutput{
+		{OutputType: "href", Output: "http://example.com/a"},
+		{OutputType: "linkfinder", Output: "https://cdn.other.net/lib.js"},
+		{OutputType: "js-request", Output: "POST https://api.example.com/login"},
+		{OutputType: "js-request", Output: "GET https://tracker.other.net/pixel"},
+		{OutputType: "subdomain", Output: "dev.example.com"},
+		{OutputType: "subdomain", Output: "other.net"},
+		{OutputType: "aws", Output: "[aws-s3] - bucket.s3.amazonaws.com"},
+	} {
+		crawler.emit(sout, "", "")
+	}
+
+	var kept []string
+	for _, sout := range sink.results {
+		kept = append(kept, sout.Output)
+	}
+	assert.Equal(t, []string{
+		"http://example.com/a",
+		"POST https://api.example.com/login",
+		"dev.example.com",
+		"[aws-s3] - bucket.s3.amazonaws.com",
+	}, kept)
+}
+
+func TestEmitStatusFilters(t *testing.T) {
+	exclude, err := ParseStatusCodes([]string{"404", " 403"})
+	assert.NoError(t, err)
+	include, err := ParseStatusCodes([]string{"200", "301", "404"})
+	assert.NoError(t, err)
+	_, err = ParseStatusCodes([]string{"200", "2OO"})
+	assert.Error(t, err)
+	_, err = ParseStatusCodes([]string{"42"})
+	assert.Error(t, err)
+
+	sink := &recordingSink{}
+	crawler := &Crawler{Input: "http://example.com", sink: sink, excludeStatus: statusSet(exclude), includeStatus: statusSet(include)}
+	for _, sout := range []SpiderOutput{
+		{OutputType: "url", StatusCode: 200, Output: "http://example.com/ok"},
+		{OutputType: "url", StatusCode: 404, Output: "http://example.com/missing"},
+		{OutputType: "url", StatusCode: 403, Output: "http://example.com/denied"},
+		{OutputType: "url", StatusCode: 500, Output: "http://example.com/broken"},
+		{OutputType: "linkfinder", Output: "http://example.com/lead"},
+	} {
+		crawler.emit(sout, "", "")
+	}
+
+	var kept []string
+	for _, sout := range sink.results {
+		kept = append(kept, sout.Output)
+	}
+	assert.Equal(t, []string{"http://example.com/ok", "http://example.com/lead"}, kept)
+}
+
+func TestEmitOutputTemplate(t *testing.T) {
+	_, err := ParseOutputTemplate("{{.Output")
+	assert.Error(t, err)
+	_, err = ParseOutputTemplate("{{nohelper .Output}}")
+	assert.Error(t, err, "unknown functions are caught at parse time")
+	none, err := ParseOutputTemplate("")
+	assert.NoError(t, err)
+	assert.Nil(t, none)
+
+	tmpl, err := ParseOutputTemplate("{{.OutputType}} {{.StatusCode}} {{host .Output}} {{path .Output}}?{{query .Output}}")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	out := NewOutput(dir, "example_com")
+	crawler := &Crawler{Input: "http://example.com", Output: out, sink: &recordingSink{}, outputTemplate: tmpl, fileFormat: OutputFormatText}
+	crawler.emit(SpiderOutput{OutputType: "url", StatusCode: 200, Output: "http://example.com/a?b=1"}, "[url] - [code-200] - http://example.com/a?b=1", "http://example.com/a?b=1")
+	crawler.emit(SpiderOutput{OutputType: "js-request", Output: "POST https://api.example.com/login"}, "[js-request] - POST https://api.example.com/login", "")
+	crawler.emit(SpiderOutput{OutputType: "aws", Output: "bucket.s3.amazonaws.com"}, "[aws-s3] - bucket.s3.amazonaws.com", "")
+	crawler.fileFormat = OutputFormatJSON
+	crawler.emit(SpiderOutput{OutputType: "url", StatusCode: 404, Output: "http://example.com/missing"}, "[url] - [code-404] - http://example.com/missing", "")
+	out.Close()
+
+	data, err := os.ReadFile(filepath.Join(dir, "example_com"))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, "url 200 example.com /a?b=1", lines[0])
+	assert.Equal(t, "js-request 0 api.example.com /login?", lines[1])
+	assert.Equal(t, "aws 0  ?", lines[2])
+	assert.True(t, strings.HasPrefix(lines[3], "{"), "JSON output ignores the template")
+}
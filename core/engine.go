@@ -3,6 +3,8 @@ package core
 import (
 	"bufio"
 	"context"
+	"errors"
+	"fmt"
 	"net/url"
 	"os"
 	"os/signal"
@@ -10,8 +12,19 @@ import (
 	"sync"
 	"syscall"
 	"time"
+
+	"github.com/jaeles-project/gospider/core/antidetect"
+	"github.com/jaeles-project/gospider/core/report"
 )
 
+// ErrNoTargets is returned by Run when the configuration names no site
+var ErrNoTargets = errors.New("no site to crawl")
+
+// ErrNoWorkingProxies is returned by Run when RequireWorkingProxies is set
+// and no proxy passes the --test-proxies check
+var ErrNoWorkingProxies = errors.New("no working proxy")
+
+
 // Engine manages the overall crawling process.
 type Engine struct {
 	ctx       context.Context
@@ -19,17 +32,154 @@ type Engine struct {
 	cfg       CrawlerConfig
 	stats     *CrawlStats
 	startTime time.Time
+	tui       *TUI
+	// results is set by Results and closed when Run returns
+	results chan SpiderOutput
+	// stdin is set by Start, which also takes targets piped on stdin
+	stdin bool
 }
 
-// NewEngine creates a new crawling engine.
-func NewEngine(cfg CrawlerConfig) *Engine {
-	ctx, cancel := context.WithCancel(context.Background())
+// NewEngine creates a new crawling engine. Inputs that cannot be loaded and
+// outputs that cannot be opened fail here, before anything is crawled
+func NewEngine(cfg CrawlerConfig) (*Engine, error) {
+	// An empty Intensity defaults to passive, matching the CLI
+	if cfg.Intensity == "" {
+		cfg.Intensity = string(IntensityPassive)
+	}
 
 	// Ensure a single URL registry is shared across all crawlers.
 	if cfg.Registry == nil {
 		cfg.Registry = NewURLRegistry()
 	}
+	if cfg.SinceModified != "" && cfg.Validators == nil {
+		validators, err := LoadValidatorCache(cfg.SinceModified)
+		if err != nil {
+			Logger.Errorf("Failed to load validator cache %s: %s", cfg.SinceModified, err)
+		} else {
+			cfg.Validators = validators
+		}
+	}
+
+	if cfg.Postman != "" && cfg.APIRequests == nil {
+		requests, err := LoadAPICollection(cfg.Postman, cfg.PostmanEnv)
+		if err != nil {
+			return nil, fmt.Errorf("loading API collection %s: %w", cfg.Postman, err)
+		}
+		Logger.Infof("Loaded %d requests from %s", len(requests), cfg.Postman)
+		cfg.APIRequests = requests
+	}
+
+	if cfg.BurpSitemap != "" && cfg.BurpRequests == nil {
+		requests, err := LoadBurpSitemap(cfg.BurpSitemap)
+		if err != nil {
+			return nil, fmt.Errorf("loading Burp sitemap %s: %w", cfg.BurpSitemap, err)
+		}
+		Logger.Infof("Loaded %d requests from %s", len(requests), cfg.BurpSitemap)
+		cfg.BurpRequests = requests
+	}
+
+	if cfg.HAR != "" && cfg.HARCapture == nil {
+		capture, err := LoadHAR(cfg.HAR)
+		if err != nil {
+			return nil, fmt.Errorf("loading HAR %s: %w", cfg.HAR, err)
+		}
+		Logger.Infof("Loaded %d requests from %s", len(capture.Requests), cfg.HAR)
+		cfg.HARCapture = capture
+	}
+
+	if cfg.ReplayFailed != "" && cfg.ReplayRequests == nil {
+		requests, err := LoadFailedRequests(cfg.ReplayFailed)
+		if err != nil {
+			return nil, fmt.Errorf("loading failed requests %s: %w", cfg.ReplayFailed, err)
+		}
+		Logger.Infof("Replaying %d failed requests from %s", len(requests), cfg.ReplayFailed)
+		cfg.ReplayRequests = requests
+	}
+
+	var sinks []OutputSink
+	for _, spec := range cfg.outputSinkSpecs() {
+		sink, err := OpenOutputSink(spec)
+		if err != nil {
+			closeOutputSinks(sinks)
+			return nil, fmt.Errorf("opening --sink %s: %w", spec, err)
+		}
+		sinks = append(sinks, sink)
+	}
+	cfg.OutputSinks = append(sinks, cfg.OutputSinks...)
+
+	if cfg.Resume != "" && cfg.State == nil {
+		state, err := OpenCrawlState(cfg.Resume)
+		if err != nil {
+			closeOutputSinks(sinks)
+			return nil, fmt.Errorf("opening crawl state %s: %w", cfg.Resume, err)
+		}
+		loaded, err := state.Attach(cfg.Registry)
+		if err != nil {
+			state.Close()
+			closeOutputSinks(sinks)
+			return nil, fmt.Errorf("loading crawl state %s: %w", cfg.Resume, err)
+		}
+		if loaded > 0 {
+			Logger.Infof("Resuming from %s with %d URLs already seen", cfg.Resume, loaded)
+		}
+		cfg.State = state
+	}
+
+	if cfg.OutputByHost && cfg.OutputDir != "" && cfg.HostOutputs == nil {
+		cfg.HostOutputs = NewHostOutputs(cfg.OutputDir, cfg.SplitOutput, cfg.DedupOutput, cfg.DedupFields)
+	}
+	if cfg.JSONLOutput == nil && cfg.jsonlOutput() {
+		cfg.JSONLOutput = NewJSONLOutput(cfg.OutputDir)
+		cfg.JSONLOutput.EnableDedup(cfg.DedupOutput, cfg.DedupFields)
+	}
+
+	if cfg.FailedOutput != "" && cfg.Failed == nil {
+		cfg.Failed = NewFailedCollector(cfg.FailedOutput)
+	}
+
+	if cfg.SARIFOutput != "" && cfg.SARIF == nil {
+		cfg.SARIF = report.NewSARIF(cfg.SARIFOutput, CLIName, VERSION)
+	}
+
+	if cfg.ParamsOutput != "" && cfg.Params == nil {
+		cfg.Params = NewParamCollector(cfg.ParamsOutput, cfg.ParamsSplit)
+	}
 
+	if cfg.PostmanOutput != "" && cfg.PostmanExport == nil {
+		cfg.PostmanExport = NewPostmanExporter(cfg.PostmanOutput)
+	}
+
+	if cfg.OpenAPIOutput != "" && cfg.OpenAPIDraft == nil {
+		cfg.OpenAPIDraft = NewOpenAPICollector(cfg.OpenAPIOutput)
+	}
+
+	if cfg.WordsOutput != "" && cfg.Words == nil {
+		cfg.Words = NewWordCollector(cfg.WordsOutput, cfg.WordsMinLength, cfg.WordsMinCount)
+	}
+
+	if cfg.Redis != "" && cfg.Frontier == nil {
+		frontier, err := OpenFrontier(cfg.Redis, cfg.RedisPrefix)
+		if err != nil {
+			cfg.State.Close()
+			closeOutputSinks(sinks)
+			return nil, fmt.Errorf("connecting to Redis %s: %w", cfg.Redis, err)
+		}
+		cfg.Frontier = frontier
+	}
+	if cfg.Frontier != nil {
+		cfg.Frontier.Attach(cfg.Registry)
+	}
+
+	// One limiter for every thread, so --global-rps caps the whole run
+	if cfg.GlobalRPS > 0 && cfg.RateLimiter == nil {
+		cfg.RateLimiter = NewGlobalRateLimiter(cfg.GlobalRPS)
+	}
+
+	if cfg.ProfileExtractors && cfg.Profile == nil {
+		cfg.Profile = NewExtractorProfile()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
 	e := &Engine{
 		ctx:       ctx,
 		cancel:    cancel,
@@ -38,59 +188,157 @@ func NewEngine(cfg CrawlerConfig) *Engine {
 		startTime: time.Now(),
 	}
 
-	go func() {
-		sigchan := make(chan os.Signal, 1)
-		signal.Notify(sigchan, syscall.SIGINT, syscall.SIGTERM)
-		<-sigchan
-		Logger.Infof("Interrupt signal received, shutting down...")
-		e.cancel()
-	}()
+	if cfg.TUI && cfg.Sink == nil {
+		if tui := NewTUI(e.stats); tui != nil {
+			e.tui = tui
+			e.cfg.Sink = tui
+			e.cfg.Pause = tui.Gate()
+		}
+	}
+
+	return e, nil
+}
+
+// Results returns the channel Run delivers results on, in place of printing
+// them. It must be called before Run, and the channel is closed when Run
+// returns. Results not read hold the crawl up until the context is cancelled
+func (e *Engine) Results() <-chan SpiderOutput {
+	if e.results == nil {
+		e.results = make(chan SpiderOutput)
+		e.cfg.Sink = &chanSink{ctx: e.ctx, results: e.results}
+	}
+	return e.results
+}
 
-	return e
+// Stats returns the counters of the run
+func (e *Engine) Stats() *CrawlStats {
+	return e.stats
 }
 
-// resolveSites gathers the list of target sites from configuration and stdin.
-func (e *Engine) resolveSites() []string {
-	var siteList []string
+// resolveSites gathers the list of target sites from configuration and, for
+// the CLI, stdin. Lines from the sites file and stdin may carry per-target
+// overrides as JSON.
+func (e *Engine) resolveSites() []Target {
+	var siteList []Target
 	if e.cfg.Site != "" {
-		siteList = append(siteList, e.cfg.Site)
+		siteList = append(siteList, Target{URL: e.cfg.Site})
+	}
+	siteList = append(siteList, e.cfg.Targets...)
+
+	if len(e.cfg.ReplayRequests) > 0 {
+		siteList = append(siteList, replayTargets(siteList, e.cfg.ReplayRequests)...)
 	}
 
 	if e.cfg.Sites != "" {
 		// NOTE: ReadingLines is defined in core/utils.go, which is in the same package.
-		sitesFile := ReadingLines(e.cfg.Sites)
-		if len(sitesFile) > 0 {
-			siteList = append(siteList, sitesFile...)
+		for _, line := range ReadingLines(e.cfg.Sites) {
+			if target, ok := parseTarget(line); ok {
+				siteList = append(siteList, target)
+			}
 		}
 	}
 
-	stat, _ := os.Stdin.Stat()
-	if (stat.Mode() & os.ModeCharDevice) == 0 {
+	if stat, err := os.Stdin.Stat(); e.stdin && err == nil && (stat.Mode()&os.ModeCharDevice) == 0 {
 		sc := bufio.NewScanner(os.Stdin)
 		for sc.Scan() {
-			target := strings.TrimSpace(sc.Text())
-			if err := sc.Err(); err == nil && target != "" {
-				siteList = append(siteList, target)
+			line := strings.TrimSpace(sc.Text())
+			if err := sc.Err(); err == nil && line != "" {
+				if target, ok := parseTarget(line); ok {
+					siteList = append(siteList, target)
+				}
 			}
 		}
 	}
 
+	// Without explicit targets a capture is crawled from every origin in it
 	if len(siteList) == 0 {
+		if e.cfg.HARCapture != nil {
+			siteList = e.cfg.HARCapture.Targets()
+		}
+		siteList = append(siteList, requestTargets(siteList, e.cfg.BurpRequests)...)
+	}
+
+	return siteList
+}
+
+func parseTarget(line string) (Target, bool) {
+	target, err := ParseTargetLine(line)
+	if err != nil {
+		Logger.Errorf("Skipping target %q: %s", line, err)
+		return Target{}, false
+	}
+	return target, true
+}
+
+// Start is the command line entry point: it also crawls the targets piped
+// on stdin and stops on SIGINT or SIGTERM. It waits for the crawl to complete
+// and returns what stopped it from running, other than having no targets
+func (e *Engine) Start() error {
+	e.stdin = true
+	go func() {
+		sigchan := make(chan os.Signal, 1)
+		signal.Notify(sigchan, syscall.SIGINT, syscall.SIGTERM)
+		<-sigchan
+		Logger.Infof("Interrupt signal received, shutting down...")
+		e.cancel()
+	}()
+
+	err := e.run()
+	if errors.Is(err, ErrNoTargets) {
 		Logger.Info("No site in list. Please check your site input again")
 		return nil
 	}
-	return siteList
+	return err
 }
 
-// Start kicks off the crawling process and waits for it to complete.
-func (e *Engine) Start() {
-	sites := e.resolveSites()
-	if sites == nil {
-		return
+// Run crawls the configured sites and returns once they are done or ctx is
+// cancelled. Unlike Start it neither reads stdin nor handles signals, so it
+// suits programs embedding the engine; pair it with Results to receive the
+// findings. Run may be called once
+func (e *Engine) Run(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, e.cancel)
+	defer stop()
+	if e.results != nil {
+		defer close(e.results)
+	}
+	if err := e.run(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+func (e *Engine) run() error {
+	defer e.finish()
+	var sites []Target
+	if e.cfg.Frontier == nil || e.cfg.RedisRole == RedisRoleCoordinator {
+		sites = e.resolveSites()
+		if len(sites) == 0 {
+			return ErrNoTargets
+		}
+	}
+	if e.cfg.Frontier != nil && e.cfg.RedisRole == RedisRoleCoordinator {
+		queued, err := e.cfg.Frontier.Seed(sites)
+		if err != nil {
+			Logger.Errorf("Failed to queue targets in %s: %s", e.cfg.Redis, err)
+			return fmt.Errorf("seeding %s: %w", e.cfg.Redis, err)
+		}
+		Logger.Infof("Queued %d targets for the workers of %s", queued, e.cfg.Redis)
+		return nil
+	}
+
+	if e.cfg.TestProxies {
+		if err := e.testProxies(); err != nil {
+			return err
+		}
+	}
+
+	if e.tui != nil {
+		stopTUI := e.tui.Run(e.ctx, e.cancel)
+		defer stopTUI()
 	}
 
 	var wg sync.WaitGroup
-	jobs := make(chan string, len(sites))
+	jobs := make(chan Target, len(sites))
 
 	numThreads := e.cfg.Threads
 	if numThreads <= 0 {
@@ -101,29 +349,152 @@ func (e *Engine) Start() {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for siteURL := range jobs {
+			for target := range jobs {
 				select {
 				case <-e.ctx.Done():
 					return
 				default:
-					u, err := url.Parse(siteURL)
+					u, err := url.Parse(target.URL)
 					if err != nil {
 						Logger.Errorf("Failed to parse site URL: %s", err)
 						continue
 					}
-					crawler := NewCrawler(e.ctx, u, e.cfg, e.stats)
+					if e.cfg.State.Finished(stateSiteKey(u)) {
+						Logger.Infof("Skipping %s, already crawled in %s", u, e.cfg.Resume)
+						continue
+					}
+					crawler := NewCrawler(e.ctx, u, target.Apply(e.cfg), e.stats)
+					e.stats.AddActiveSites(1)
+					e.stats.AddActiveHost(u.Host)
+					stop := context.AfterFunc(e.ctx, crawler.Stop)
 					crawler.Start()
+					stop()
+					e.stats.RemoveActiveHost(u.Host)
+					e.stats.AddActiveSites(-1)
+					// A target cut short stays on the processing list
+					if e.cfg.Frontier != nil && e.ctx.Err() == nil {
+						if err := e.cfg.Frontier.Done(target); err != nil {
+							Logger.Warnf("Failed to mark %s done in %s: %s", target.URL, e.cfg.Redis, err)
+						}
+					}
 				}
 			}
 		}()
 	}
 
-	for _, siteURL := range sites {
-		jobs <- siteURL
+	var err error
+	if e.cfg.Frontier != nil {
+		err = e.feedFrontier(jobs)
+	} else {
+		for _, target := range sites {
+			jobs <- target
+		}
 	}
 	close(jobs)
 
 	wg.Wait()
+	return err
+}
+
+// finish closes the run's outputs and writes its files, however run ended
+func (e *Engine) finish() {
+	if e.cfg.HostOutputs != nil {
+		e.cfg.HostOutputs.Close()
+	}
+	if e.cfg.JSONLOutput != nil {
+		e.cfg.JSONLOutput.Close()
+	}
+	closeOutputSinks(e.cfg.OutputSinks)
+	if e.cfg.Frontier != nil {
+		e.cfg.Frontier.Close()
+	}
+	if e.cfg.Validators != nil {
+		if err := e.cfg.Validators.Save(); err != nil {
+			Logger.Errorf("Failed to save validator cache %s: %s", e.cfg.SinceModified, err)
+		}
+	}
+	if e.cfg.Failed != nil {
+		if err := e.cfg.Failed.Save(); err != nil {
+			Logger.Errorf("Failed to write failed requests %s: %s", e.cfg.FailedOutput, err)
+		}
+	}
+	if e.cfg.SARIF != nil {
+		if err := e.cfg.SARIF.Save(); err != nil {
+			Logger.Errorf("Failed to write SARIF report %s: %s", e.cfg.SARIFOutput, err)
+		}
+	}
+	if e.cfg.Params != nil {
+		if err := e.cfg.Params.Save(); err != nil {
+			Logger.Errorf("Failed to write parameter wordlist %s: %s", e.cfg.ParamsOutput, err)
+		}
+	}
+	if e.cfg.PostmanExport != nil {
+		if err := e.cfg.PostmanExport.Save(); err != nil {
+			Logger.Errorf("Failed to write Postman collection %s: %s", e.cfg.PostmanOutput, err)
+		}
+	}
+	if e.cfg.OpenAPIDraft != nil {
+		if err := e.cfg.OpenAPIDraft.Save(); err != nil {
+			Logger.Errorf("Failed to write OpenAPI spec %s: %s", e.cfg.OpenAPIOutput, err)
+		}
+	}
+	if e.cfg.Words != nil {
+		if err := e.cfg.Words.Save(); err != nil {
+			Logger.Errorf("Failed to write wordlist %s: %s", e.cfg.WordsOutput, err)
+		}
+	}
+	if err := e.cfg.State.Close(); err != nil {
+		Logger.Errorf("Failed to write crawl state %s: %s", e.cfg.Resume, err)
+	}
+}
+
+// feedFrontier hands the targets of a --redis crawl to the threads until
+// the frontier is drained, or returns the Redis error that stopped it
+func (e *Engine) feedFrontier(jobs chan<- Target) error {
+	for {
+		target, ok, err := e.cfg.Frontier.Next(e.ctx)
+		if err != nil {
+			return fmt.Errorf("taking a target from %s: %w", e.cfg.Redis, err)
+		}
+		if !ok {
+			return nil
+		}
+		select {
+		case jobs <- target:
+		case <-e.ctx.Done():
+			return nil
+		}
+	}
+}
+
+// testProxies validates the configured proxy list and keeps only working
+// proxies. Unless RequireWorkingProxies is set the check is advisory: when
+// no proxy passes, the crawl goes on with the whole list, since the test
+// URLs may be what is unreachable
+func (e *Engine) testProxies() error {
+	if len(e.cfg.ProxyList) == 0 {
+		if e.cfg.RequireWorkingProxies {
+			return fmt.Errorf("%w: no proxies were loaded, use --proxy-file", ErrNoWorkingProxies)
+		}
+		Logger.Warn("--test-proxies set but no proxies were loaded, use --proxy-file")
+		return nil
+	}
+
+	Logger.Infof("Testing %d proxies...", len(e.cfg.ProxyList))
+	rotator := antidetect.NewProxyRotator(e.cfg.ProxyList, 1)
+	rotator.SetTestURLs(e.cfg.ProxyTestURLs)
+	working, dead := rotator.TestAllProxies()
+	Logger.Infof("Proxy test finished: %d working, %d dead", working, dead)
+
+	if working == 0 {
+		if e.cfg.RequireWorkingProxies {
+			return fmt.Errorf("%w: all %d proxies failed the check", ErrNoWorkingProxies, len(e.cfg.ProxyList))
+		}
+		Logger.Warnf("No proxy passed the check, crawling with all %d proxies", len(e.cfg.ProxyList))
+		return nil
+	}
+	e.cfg.ProxyList = rotator.RemoveInactiveProxies()
+	return nil
 }
 
 // Shutdown prints final statistics.
@@ -137,6 +508,22 @@ func (e *Engine) Shutdown() {
 	Logger.Infof("URLs found: %d", e.stats.GetURLsFound())
 	Logger.Infof("Errors: %d", e.stats.GetErrors())
 	Logger.Infof("RPS: %.2f", rps)
+	summary := e.stats.Summary(elapsed)
+	if len(summary.Statuses) > 0 {
+		Logger.Infof("Status codes: %s", statusHistogram(summary.Statuses))
+	}
+	if len(summary.Hosts) > 0 {
+		Logger.Info("Per-host summary:")
+		for _, line := range strings.Split(strings.TrimRight(summary.HostTable(), "\n"), "\n") {
+			Logger.Info(line)
+		}
+	}
+	if e.cfg.StatsOutput != "" {
+		if err := WriteStatsSummary(e.cfg.StatsOutput, summary); err != nil {
+			Logger.Errorf("Failed to write stats %s: %s", e.cfg.StatsOutput, err)
+		}
+	}
+	e.cfg.Profile.LogSummary()
 }
 
 // Ctx returns the engine's context.
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func linkSite(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><a href="/page">page</a></body></html>`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newEngine is NewEngine for configurations that must set up cleanly
+func newEngine(t *testing.T, cfg CrawlerConfig) *Engine {
+	t.Helper()
+	engine, err := NewEngine(cfg)
+	require.NoError(t, err)
+	return engine
+}
+
+func TestEngineRunStreamsResults(t *testing.T) {
+	a, b := linkSite(t), linkSite(t)
+	engine := newEngine(t, CrawlerConfig{
+		MaxDepth: 2, MaxConcurrency: 1, Threads: 2, Timeout: 5 * time.Second,
+		Site:    a.URL,
+		Targets: []Target{{URL: b.URL}},
+	})
+	results := engine.Results()
+
+	var urls []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for sout := range results {
+			assert.Equal(t, SpiderOutputSchemaVersion, sout.Schema)
+			if sout.OutputType == "url" {
+				urls = append(urls, sout.Output)
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	require.NoError(t, engine.Run(ctx))
+	<-done
+	assert.ElementsMatch(t, []string{a.URL, a.URL + "/page", b.URL, b.URL + "/page"}, urls)
+	assert.NotZero(t, engine.Stats().GetRequestsMade())
+}
+
+func TestEngineRunWithoutTargets(t *testing.T) {
+	sink := &memorySink{}
+	engine := newEngine(t, CrawlerConfig{OutputSinks: []OutputSink{sink}})
+	results := engine.Results()
+	assert.ErrorIs(t, engine.Run(context.Background()), ErrNoTargets)
+	_, open := <-results
+	assert.False(t, open, "the results channel is closed when Run returns")
+	assert.True(t, sink.closed, "output sinks are closed even when Run returns early")
+}
+
+func TestEngineRunCancelled(t *testing.T) {
+	srv := linkSite(t)
+	engine := newEngine(t, CrawlerConfig{MaxDepth: 2, MaxConcurrency: 1, Timeout: 5 * time.Second, Site: srv.URL})
+	results := engine.Results()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, engine.Run(ctx), context.Canceled)
+	for range results {
+	}
+}
+
+func TestNewEngineSetupErrors(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing.json")
+	for name, cfg := range map[string]CrawlerConfig{
+		"postman":       {Postman: missing},
+		"burp sitemap":  {BurpSitemap: missing},
+		"har":           {HAR: missing},
+		"replay failed": {ReplayFailed: missing},
+		"sink":          {SinkSpecs: []string{"bogus:nowhere"}},
+		"resume":        {Resume: filepath.Join(dir, "no", "such", "state.db")},
+		"redis":         {Redis: "redis://127.0.0.1:1"},
+	} {
+		cfg.Site = "http://127.0.0.1:1"
+		_, err := NewEngine(cfg)
+		assert.Error(t, err, name)
+		_, err = Crawl(context.Background(), cfg.Site, cfg)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestEngineTestProxies(t *testing.T) {
+	working := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer working.Close()
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	engine := newEngine(t, CrawlerConfig{
+		ProxyList:     []string{working.URL, dead.URL},
+		ProxyTestURLs: []string{"http://probe.example/ip"},
+	})
+	require.NoError(t, engine.testProxies())
+	assert.Equal(t, []string{working.URL}, engine.cfg.ProxyList, "dead proxies are dropped")
+
+	engine = newEngine(t, CrawlerConfig{
+		ProxyList:     []string{dead.URL},
+		ProxyTestURLs: []string{"http://probe.example/ip"},
+	})
+	require.NoError(t, engine.testProxies())
+	assert.Equal(t, []string{dead.URL}, engine.cfg.ProxyList, "the crawl keeps every proxy when none passes")
+
+	engine = newEngine(t, CrawlerConfig{
+		Site:                  "http://127.0.0.1:1",
+		ProxyList:             []string{dead.URL},
+		ProxyTestURLs:         []string{"http://probe.example/ip"},
+		TestProxies:           true,
+		RequireWorkingProxies: true,
+	})
+	assert.ErrorIs(t, engine.Run(context.Background()), ErrNoWorkingProxies, "the crawl aborts when working proxies are required")
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// Error classes reported by --report-errors
+const (
+	ErrorClassTimeout = "timeout"
+	ErrorClassDNS     = "dns"
+	ErrorClassTLS     = "tls"
+	ErrorClassRefused = "refused"
+	ErrorClassStatus  = "status"
+	ErrorClassNetwork = "network"
+)
+
+// ClassifyRequestError names the reason a request failed. Responses that
+// arrived with an error status are "status"; transport failures are sorted
+// into dns, timeout, tls and refused, and anything else is "network"
+func ClassifyRequestError(statusCode int, err error) string {
+	if statusCode >= 100 {
+		return ErrorClassStatus
+	}
+	if err == nil {
+		return ErrorClassNetwork
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return ErrorClassDNS
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return ErrorClassTimeout
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return ErrorClassRefused
+	}
+
+	var (
+		recordErr    tls.RecordHeaderError
+		verifyErr    *tls.CertificateVerificationError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	if errors.As(err, &recordErr) || errors.As(err, &verifyErr) || errors.As(err, &authorityErr) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) {
+		return ErrorClassTLS
+	}
+
+	// Some transports only surface the reason in the message
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "no such host"):
+		return ErrorClassDNS
+	case strings.Contains(msg, "timeout") || strings.Contains(msg, "deadline exceeded"):
+		return ErrorClassTimeout
+	case strings.Contains(msg, "tls:") || strings.Contains(msg, "x509:"):
+		return ErrorClassTLS
+	case strings.Contains(msg, "connection refused"):
+		return ErrorClassRefused
+	}
+	return ErrorClassNetwork
+}
+
+// reportRequestError emits an error finding for a failed request, once per
+// host and error class (and status code, for status errors)
+func (crawler *Crawler) reportRequestError(response *colly.Response, class string, err error) {
+	if response.Request == nil || response.Request.URL == nil || crawler.stopped.Load() || errors.Is(err, context.Canceled) {
+		return
+	}
+	key := fmt.Sprintf("%s|%s|%d", strings.ToLower(response.Request.URL.Host), class, response.StatusCode)
+	if crawler.errorSet.Duplicate(key) {
+		return
+	}
+
+	u := NormalizeDisplayURL(response.Request.URL.String())
+	reason := class
+	if err != nil {
+		reason = err.Error()
+	}
+	sout := SpiderOutput{
+		Input:      crawler.Input,
+		Source:     "body",
+		OutputType: "error",
+		StatusCode: response.StatusCode,
+		Output:     u,
+		Param:      class,
+		Snippet:    reason,
+		Depth:      response.Request.Depth,
+	}
+	crawler.emit(sout, fmt.Sprintf("[error] - [%s] - %s - %s", class, u, reason), u)
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyRequestError(t *testing.T) {
+	wrap := func(err error) error { return &url.Error{Op: "Get", URL: "https://example.com", Err: err} }
+
+	assert.Equal(t, ErrorClassStatus, ClassifyRequestError(http.StatusForbidden, errors.New("Forbidden")))
+	assert.Equal(t, ErrorClassDNS, ClassifyRequestError(0, wrap(&net.DNSError{Err: "no such host", Name: "nope.invalid", IsNotFound: true})))
+	assert.Equal(t, ErrorClassTimeout, ClassifyRequestError(0, wrap(context.DeadlineExceeded)))
+	assert.Equal(t, ErrorClassRefused, ClassifyRequestError(0, wrap(&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)})))
+	assert.Equal(t, ErrorClassTLS, ClassifyRequestError(0, wrap(x509.UnknownAuthorityError{})))
+	assert.Equal(t, ErrorClassNetwork, ClassifyRequestError(0, wrap(errors.New("EOF"))))
+}
+
+func TestCrawlReportErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><a href="/a">a</a><a href="/b">b</a><a href="/admin">admin</a></body></html>`)
+		case "/admin":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	crawl := func(report bool) []SpiderOutput {
+		results := crawlAll(t, srv.URL, CrawlerConfig{ReportErrors: report})
+
+		var errs []SpiderOutput
+		for _, sout := range results {
+			if sout.OutputType == "error" {
+				errs = append(errs, sout)
+			}
+		}
+		return errs
+	}
+
+	assert.Empty(t, crawl(false))
+
+	// The two 500s share a host and class, so only the first is reported
+	errs := crawl(true)
+	require.Len(t, errs, 2)
+	codes := map[int]string{}
+	for _, e := range errs {
+		assert.Equal(t, ErrorClassStatus, e.Param)
+		codes[e.StatusCode] = e.Output
+	}
+	assert.Contains(t, []string{srv.URL + "/a", srv.URL + "/b"}, codes[http.StatusInternalServerError])
+	assert.Equal(t, srv.URL+"/admin", codes[http.StatusForbidden])
+}
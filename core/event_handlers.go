@@ -0,0 +1,84 @@
+package core
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// locationAssignRegex matches navigations such as location.href='/a' or
+// window.location = "/b". Comparisons and concatenated targets are skipped
+var locationAssignRegex = regexp.MustCompile(`(?i)(?:^|[^\w$.])(?:(?:window|document|self|top|parent)\.)?location(?:\.href)?\s*=\s*(['"\x60])([^'"\x60]+)['"\x60]\s*(?:$|[;,)}])`)
+
+// navigationCalls are functions whose first argument is a page to open
+var navigationCalls = []string{"location.assign", "location.replace", "window.open"}
+
+// ExtractEventHandlerURLs returns the pages an inline event handler such as
+// onclick="window.location='/secret'" navigates to
+func ExtractEventHandlerURLs(code string) []string {
+	var urls []string
+	// Per statement, so back-to-back assignments each find their boundary
+	for _, statement := range strings.Split(code, ";") {
+		for _, match := range locationAssignRegex.FindAllStringSubmatch(statement, -1) {
+			urls = append(urls, match[2])
+		}
+	}
+	for _, name := range navigationCalls {
+		for _, call := range scanFunctionCallsName(code, name) {
+			if args := splitArgs(call.args); len(args) > 0 {
+				urls = append(urls, decodeStringArgument(args[0]))
+			}
+		}
+	}
+
+	var cleaned []string
+	for _, raw := range urls {
+		if raw, ok := attributeURL(raw); ok && !strings.HasPrefix(strings.ToLower(raw), "javascript:") {
+			cleaned = append(cleaned, raw)
+		}
+	}
+	return Unique(cleaned)
+}
+
+// registerEventHandlers follows navigations and requests found in on*
+// attributes, which [href] and [src] extraction never sees
+func (crawler *Crawler) registerEventHandlers() {
+	crawler.C.OnHTML("html", func(e *colly.HTMLElement) {
+		if crawler.stopped.Load() || crawler.shouldSkipDOM(e.Request.URL.String()) {
+			return
+		}
+		base := crawler.documentBase(e)
+		pageURL := e.Request.URL.String()
+		for _, node := range e.DOM.Find("*").Nodes {
+			for _, attr := range node.Attr {
+				name := strings.ToLower(attr.Key)
+				if !strings.HasPrefix(name, "on") || strings.TrimSpace(attr.Val) == "" {
+					continue
+				}
+				for _, raw := range ExtractEventHandlerURLs(attr.Val) {
+					if urlToVisit := crawler.urlProcessor.ProcessWithBase(raw, name, "event-handler", base, e.Request); urlToVisit != "" {
+						crawler.visit(e.Request, urlToVisit)
+					}
+				}
+
+				stop := crawler.profile.start(extractorJSRequests)
+				requests, _ := crawler.extractJSRequests(attr.Val, attr.Val, base)
+				stop()
+				for _, req := range requests {
+					req.Source = name
+					if resolved, ok := NormalizeURL(base, req.RawURL); ok {
+						req.RawURL = resolved
+					}
+					if isDestructiveMethod(req.Method) {
+						if normalized, ok := crawler.normalizeJSRequest(req, pageURL); ok {
+							crawler.emitJSRequest(normalized, pageURL, e.Request.Depth+1)
+						}
+						continue
+					}
+					crawler.processGeneratedRequest(req, pageURL, e.Request.Depth)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractEventHandlerURLs(t *testing.T) {
+	for code, want := range map[string][]string{
+		`window.location='/secret'`:                     {"/secret"},
+		`location.href = "/a"; return false`:            {"/a"},
+		`document.location="/b"`:                        {"/b"},
+		`location.assign('/c')`:                         {"/c"},
+		`window.location.replace("/d")`:                 {"/d"},
+		`window.open('/popup', '_blank')`:               {"/popup"},
+		`if (location == '/x') go()`:                    nil,
+		`location.href = '/users/' + id`:                nil,
+		`window.location = "javascript:void(0)"`:        nil,
+		`this.form.location='/e'; window.location='/e'`: {"/e"},
+		`location='/f';location.href='/g'`:              {"/f", "/g"},
+	} {
+		assert.Equal(t, want, ExtractEventHandlerURLs(code), code)
+	}
+}
+
+func TestCrawlFollowsEventHandlers(t *testing.T) {
+	var deleted atomic.Bool
+	var mu sync.Mutex
+	seen := map[string]bool{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			deleted.Store(true)
+		}
+		mu.Lock()
+		seen[r.Method+" "+r.URL.Path] = true
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<html><body>
+<button onclick="window.location='/secret'">go</button>
+<div onmouseover="fetch('/api/track', {method: 'POST', body: 'x=1'})"></div>
+<form onsubmit="fetch('/api/item/1', {method: 'DELETE'}); return false"></form>
+</body></html>`)
+			return
+		}
+		fmt.Fprint(w, `<html><body>leaf</body></html>`)
+	}))
+	defer srv.Close()
+
+	results := crawlAll(t, srv.URL, CrawlerConfig{})
+
+	var outputs []string
+	for _, sout := range results {
+		outputs = append(outputs, sout.OutputType+" "+sout.Output)
+	}
+	assert.Contains(t, outputs, "event-handler "+srv.URL+"/secret")
+	assert.Contains(t, outputs, "js-request POST "+srv.URL+"/api/track")
+	assert.Contains(t, outputs, "js-request DELETE "+srv.URL+"/api/item/1")
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.True(t, seen["GET /secret"])
+	assert.True(t, seen["POST /api/track"])
+	assert.False(t, deleted.Load(), "destructive requests are reported, not sent")
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// Extractors timed by --profile-extractors
+const (
+	extractorLinkFinder = iota
+	extractorJSRequests
+	extractorDOMFingerprint
+	extractorDOMAnalysis
+	extractorReflection
+	extractorSubdomains
+	extractorSecrets
+	extractorCloudStorage
+	extractorCount
+)
+
+var extractorNames = [extractorCount]string{
+	extractorLinkFinder:     "linkfinder",
+	extractorJSRequests:     "js-requests",
+	extractorDOMFingerprint: "dom-fingerprint",
+	extractorDOMAnalysis:    "dom-analysis",
+	extractorReflection:     "reflection",
+	extractorSubdomains:     "subdomains",
+	extractorSecrets:        "secrets",
+	extractorCloudStorage:   "cloud-storage",
+}
+
+// ExtractorProfile accumulates the time spent in each response extractor.
+// It is shared by all crawlers of a run, and a nil profile records nothing
+type ExtractorProfile struct {
+	nanos [extractorCount]atomic.Int64
+	calls [extractorCount]atomic.Int64
+}
+
+// ExtractorTiming is the total time spent in one extractor
+type ExtractorTiming struct {
+	Name  string
+	Calls int64
+	Total time.Duration
+}
+
+// NewExtractorProfile returns an empty profile
+func NewExtractorProfile() *ExtractorProfile {
+	return &ExtractorProfile{}
+}
+
+// start begins timing one call of extractor; call the returned function when
+// it returns
+func (p *ExtractorProfile) start(extractor int) func() {
+	if p == nil {
+		return func() {}
+	}
+	begin := time.Now()
+	return func() {
+		p.nanos[extractor].Add(int64(time.Since(begin)))
+		p.calls[extractor].Add(1)
+	}
+}
+
+// Timings returns the extractors that ran, slowest first
+func (p *ExtractorProfile) Timings() []ExtractorTiming {
+	if p == nil {
+		return nil
+	}
+	var timings []ExtractorTiming
+	for i, name := range extractorNames {
+		calls := p.calls[i].Load()
+		if calls == 0 {
+			continue
+		}
+		timings = append(timings, ExtractorTiming{Name: name, Calls: calls, Total: time.Duration(p.nanos[i].Load())})
+	}
+	sort.SliceStable(timings, func(i, j int) bool { return timings[i].Total > timings[j].Total })
+	return timings
+}
+
+// LogSummary writes the breakdown to the log at the end of a run
+func (p *ExtractorProfile) LogSummary() {
+	timings := p.Timings()
+	if len(timings) == 0 {
+		return
+	}
+	var total time.Duration
+	for _, timing := range timings {
+		total += timing.Total
+	}
+	Logger.Info("Extractor timings:")
+	for _, timing := range timings {
+		share := 0.0
+		if total > 0 {
+			share = 100 * float64(timing.Total) / float64(total)
+		}
+		Logger.Infof("  %-16s %12s  %5.1f%%  %d calls, %s avg", timing.Name, timing.Total.Round(time.Microsecond), share, timing.Calls, (timing.Total / time.Duration(timing.Calls)).Round(time.Microsecond))
+	}
+}
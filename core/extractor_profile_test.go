@@ -0,0 +1,29 @@
+package core
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractorProfile(t *testing.T) {
+	var disabled *ExtractorProfile
+	disabled.start(extractorLinkFinder)()
+	assert.Nil(t, disabled.Timings())
+
+	profile := NewExtractorProfile()
+	crawler := &Crawler{profile: profile, secrets: NewSecretScanner(nil)}
+	base, _ := url.Parse("https://example.com/app.js")
+	paths, requests, err := crawler.linkFinder(`fetch("/api/users", {method: "POST"}); var u = "/static/page.html";`, base)
+	assert.NoError(t, err)
+	assert.Contains(t, paths, "/api/users")
+	assert.NotEmpty(t, requests)
+	crawler.findSecrets("no secrets here", "https://example.com/app.js", 1)
+
+	calls := map[string]int64{}
+	for _, timing := range profile.Timings() {
+		calls[timing.Name] = timing.Calls
+	}
+	assert.Equal(t, map[string]int64{"linkfinder": 1, "js-requests": 1, "secrets": 1}, calls)
+}
@@ -0,0 +1,265 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gocolly/colly/v2"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// FailedRequest is a request that errored during a crawl, as written by
+// --failed-output and read back by --replay-failed. Body and ContentType
+// are only known for generated requests such as forms and JS calls
+type FailedRequest struct {
+	URL         string `json:"url"`
+	Method      string `json:"method,omitempty"`
+	Body        string `json:"body,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Class       string `json:"class"`
+	Status      int    `json:"status,omitempty"`
+}
+
+// FailedCollector accumulates the failed requests of a run worth retrying.
+// It is shared by all crawlers of a run
+type FailedCollector struct {
+	path string
+
+	mu       sync.Mutex
+	seen     map[string]struct{}
+	requests []FailedRequest
+}
+
+// NewFailedCollector returns a collector that writes its requests to path
+func NewFailedCollector(path string) *FailedCollector {
+	return &FailedCollector{path: path, seen: make(map[string]struct{})}
+}
+
+// retryable reports whether a failure may go away on its own. Transport
+// errors qualify; of the error statuses only timeouts, rate limiting and
+// server errors do
+func retryable(class string, status int) bool {
+	if class != ErrorClassStatus {
+		return true
+	}
+	return status == http.StatusRequestTimeout || status == http.StatusTooManyRequests || status >= 500
+}
+
+// Add records a failed request once per method, URL and body
+func (f *FailedCollector) Add(req FailedRequest) {
+	if req.URL == "" || !retryable(req.Class, req.Status) {
+		return
+	}
+	if req.Method == "" {
+		req.Method = http.MethodGet
+	}
+	key := req.Method + " " + req.URL + "\n" + req.Body
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.seen[key]; ok {
+		return
+	}
+	f.seen[key] = struct{}{}
+	f.requests = append(f.requests, req)
+}
+
+// Requests returns the recorded requests in the order they failed
+func (f *FailedCollector) Requests() []FailedRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FailedRequest(nil), f.requests...)
+}
+
+// Save writes one JSON object per line to the collector's file. A run
+// without failures leaves an empty file, so a replay of it does nothing
+func (f *FailedCollector) Save() error {
+	var b strings.Builder
+	for _, req := range f.Requests() {
+		line, err := jsoniter.MarshalToString(req)
+		if err != nil {
+			return err
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), ".gospider-failed-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.WriteString(b.String()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// LoadFailedRequests reads a --failed-output file. Blank lines are skipped
+// and bare URLs are accepted as GET requests, so the file can be edited by hand
+func LoadFailedRequests(path string) ([]FailedRequest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var requests []FailedRequest
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		req := FailedRequest{URL: line}
+		if strings.HasPrefix(line, "{") {
+			req = FailedRequest{}
+			if err := jsoniter.UnmarshalFromString(line, &req); err != nil {
+				return nil, fmt.Errorf("%s: line %d: %w", path, n, err)
+			}
+		}
+		u, err := url.Parse(req.URL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("%s: line %d: invalid url %q", path, n, req.URL)
+		}
+		req.Method = strings.ToUpper(strings.TrimSpace(req.Method))
+		if req.Method == "" {
+			req.Method = http.MethodGet
+		}
+		requests = append(requests, req)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// replayOrigin is the scheme and host a replayed request is crawled under
+func replayOrigin(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// replayTargets returns one target per origin of requests that is not
+// already among sites
+func replayTargets(sites []Target, requests []FailedRequest) []Target {
+	urls := make([]string, 0, len(requests))
+	for _, req := range requests {
+		urls = append(urls, req.URL)
+	}
+	return originTargets(sites, urls)
+}
+
+// requestTargets returns one target per origin of requests that is not
+// already among sites
+func requestTargets(sites []Target, requests []JSRequest) []Target {
+	urls := make([]string, 0, len(requests))
+	for _, req := range requests {
+		urls = append(urls, req.RawURL)
+	}
+	return originTargets(sites, urls)
+}
+
+// originTargets returns one target per origin of urls that is not already
+// among sites
+func originTargets(sites []Target, urls []string) []Target {
+	known := make(map[string]struct{}, len(sites))
+	for _, site := range sites {
+		known[replayOrigin(site.URL)] = struct{}{}
+	}
+	var targets []Target
+	for _, u := range urls {
+		origin := replayOrigin(u)
+		if _, ok := known[origin]; ok || origin == "" {
+			continue
+		}
+		known[origin] = struct{}{}
+		targets = append(targets, Target{URL: origin + "/"})
+	}
+	return targets
+}
+
+// replayRequestsFor returns the requests of requests to be sent by the
+// crawler of site
+func replayRequestsFor(site *url.URL, requests []FailedRequest) []FailedRequest {
+	origin := strings.ToLower(site.Scheme + "://" + site.Host)
+	var own []FailedRequest
+	for _, req := range requests {
+		if replayOrigin(req.URL) == origin {
+			own = append(own, req)
+		}
+	}
+	return own
+}
+
+// recordFailedRequest adds a failed response's request to --failed-output
+func (crawler *Crawler) recordFailedRequest(response *colly.Response, class string, err error) {
+	if crawler.failed == nil || response.Request == nil || response.Request.URL == nil || errors.Is(err, context.Canceled) {
+		return
+	}
+	req := FailedRequest{
+		URL:    response.Request.URL.String(),
+		Method: response.Request.Method,
+		Class:  class,
+		Status: response.StatusCode,
+	}
+	if response.Ctx != nil {
+		req.Body = response.Ctx.Get("body")
+	}
+	if req.Body != "" && response.Request.Headers != nil {
+		req.ContentType = response.Request.Headers.Get("Content-Type")
+	}
+	// Reflection probes are regenerated by the crawl that replays their page
+	if requestHasSentinel(JSRequest{RawURL: req.URL, Body: req.Body}, crawler.reflectedPayload) {
+		return
+	}
+	crawler.failed.Add(req)
+}
+
+// replayFailedRequests sends the --replay-failed requests of the crawled
+// site in place of its start page. GET requests are crawled like any
+// discovered page; others go through the generated request pipeline so
+// their method and body are kept
+func (crawler *Crawler) replayFailedRequests() {
+	origin := crawler.site.String()
+	for _, req := range crawler.replay {
+		if crawler.stopped.Load() {
+			return
+		}
+		u, err := url.Parse(req.URL)
+		if err != nil || !InScope(u, crawler.C.URLFilters) {
+			Logger.Debugf("replay: %s %s is out of scope", req.Method, req.URL)
+			continue
+		}
+		if req.Method == http.MethodGet && req.Body == "" {
+			crawler.visit(nil, req.URL)
+			continue
+		}
+		if isDestructiveMethod(req.Method) {
+			Logger.Warnf("replay: skipping %s %s", req.Method, req.URL)
+			continue
+		}
+		crawler.queueRequest(JSRequest{
+			Method:      req.Method,
+			RawURL:      req.URL,
+			Body:        req.Body,
+			ContentType: req.ContentType,
+			Source:      origin,
+		}, origin, false, "", 0, "", "")
+	}
+}
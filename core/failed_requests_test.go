@@ -0,0 +1,106 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailedCollectorKeepsRetryableFailures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "failed.jsonl")
+	failed := NewFailedCollector(path)
+	failed.Add(FailedRequest{URL: "https://a.example/x", Class: ErrorClassTimeout})
+	failed.Add(FailedRequest{URL: "https://a.example/x", Method: http.MethodGet, Class: ErrorClassTimeout})
+	failed.Add(FailedRequest{URL: "https://a.example/gone", Class: ErrorClassStatus, Status: http.StatusNotFound})
+	failed.Add(FailedRequest{URL: "https://a.example/busy", Class: ErrorClassStatus, Status: http.StatusServiceUnavailable})
+	failed.Add(FailedRequest{URL: "https://a.example/api", Method: http.MethodPost, Body: `{"a":1}`, ContentType: "application/json", Class: ErrorClassRefused})
+	require.NoError(t, failed.Save())
+
+	requests, err := LoadFailedRequests(path)
+	require.NoError(t, err)
+	assert.Equal(t, []FailedRequest{
+		{URL: "https://a.example/x", Method: http.MethodGet, Class: ErrorClassTimeout},
+		{URL: "https://a.example/busy", Method: http.MethodGet, Class: ErrorClassStatus, Status: http.StatusServiceUnavailable},
+		{URL: "https://a.example/api", Method: http.MethodPost, Body: `{"a":1}`, ContentType: "application/json", Class: ErrorClassRefused},
+	}, requests)
+}
+
+func TestLoadFailedRequestsAcceptsBareURLs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "failed.txt")
+	require.NoError(t, os.WriteFile(path, []byte("https://a.example/x\n\n{\"url\":\"https://b.example/y\",\"method\":\"post\",\"class\":\"timeout\"}\n"), 0644))
+
+	requests, err := LoadFailedRequests(path)
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+	assert.Equal(t, FailedRequest{URL: "https://a.example/x", Method: http.MethodGet}, requests[0])
+	assert.Equal(t, http.MethodPost, requests[1].Method)
+
+	assert.Equal(t, []Target{{URL: "https://b.example/"}}, replayTargets([]Target{{URL: "https://a.example"}}, requests))
+
+	require.NoError(t, os.WriteFile(path, []byte("not a url\n"), 0644))
+	_, err = LoadFailedRequests(path)
+	assert.Error(t, err)
+}
+
+func TestCrawlReplaysFailedRequests(t *testing.T) {
+	var healthy atomic.Bool
+	var mu sync.Mutex
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		seen = append(seen, r.Method+" "+r.URL.Path+" "+string(body))
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><a href="/flaky">flaky</a><a href="/missing">missing</a>
+<form method="post" action="/submit"><input name="q" value="1"></form></body></html>`)
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			if !healthy.Load() {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body>ok</body></html>`)
+		}
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "failed.jsonl")
+	crawlAll(t, srv.URL, CrawlerConfig{FailedOutput: path})
+	requests, err := LoadFailedRequests(path)
+	require.NoError(t, err)
+	// The 404 is not retried; reflection probes of the form are not kept
+	require.NotEmpty(t, requests)
+	assert.Equal(t, FailedRequest{URL: srv.URL + "/flaky", Method: http.MethodGet, Class: ErrorClassStatus, Status: http.StatusBadGateway}, requests[0])
+	assert.Contains(t, requests, FailedRequest{URL: srv.URL + "/submit", Method: http.MethodPost, Body: "q=1", ContentType: "application/x-www-form-urlencoded", Class: ErrorClassStatus, Status: http.StatusBadGateway})
+	for _, req := range requests {
+		assert.NotContains(t, req.URL, "/missing")
+		assert.NotContains(t, req.Body, defaultReflectedPayload)
+	}
+
+	healthy.Store(true)
+	mu.Lock()
+	seen = nil
+	mu.Unlock()
+	crawlAll(t, srv.URL, CrawlerConfig{ReplayFailed: path})
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, seen, len(requests))
+	assert.Contains(t, seen, "GET /flaky ")
+	assert.Contains(t, seen, "POST /submit q=1")
+	assert.NotContains(t, seen, "GET / ")
+}
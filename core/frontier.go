@@ -0,0 +1,169 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// Roles of --redis-role
+const (
+	RedisRoleCoordinator = "coordinator"
+	RedisRoleWorker      = "worker"
+)
+
+// frontierPoll is how long a worker blocks on an empty frontier before it
+// checks whether the coordinator has finished seeding
+const frontierPoll = time.Second
+
+// Frontier shares a crawl between gospider instances through Redis. The
+// coordinator queues the targets; each worker takes targets off the queue
+// and crawls them, and every worker checks new URLs against one shared set
+// so no request is sent twice across the fleet. Only targets are queued:
+// the URLs a target leads to are crawled by the worker that took it. A
+// taken target moves to the processing list until its crawl is done, so
+// the targets of a worker that died can be found there and queued again.
+// All keys live under prefix:
+//
+//	<prefix>:frontier    list of queued targets, as JSON
+//	<prefix>:processing  list of the targets being crawled, as JSON
+//	<prefix>:sites       set of the target URLs ever queued
+//	<prefix>:seeded      set once the coordinator queued every target
+//	<prefix>:seen        set of the canonical request keys sent
+type Frontier struct {
+	prefix string
+	// queue serves the blocking moves, seen the dedup checks, so a worker
+	// waiting for targets never holds up the crawlers
+	queue *redisClient
+	seen  *redisClient
+
+	mu sync.Mutex
+	// taken maps the URL of each target this worker took to its queued
+	// JSON, which Done removes from the processing list
+	taken map[string]string
+}
+
+// OpenFrontier connects to the Redis server at redisURL
+func OpenFrontier(redisURL, prefix string) (*Frontier, error) {
+	if prefix == "" {
+		prefix = CLIName
+	}
+	queue, err := dialRedis(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	seen, err := dialRedis(redisURL)
+	if err != nil {
+		queue.Close()
+		return nil, err
+	}
+	return &Frontier{prefix: prefix, queue: queue, seen: seen, taken: map[string]string{}}, nil
+}
+
+func (f *Frontier) key(name string) string {
+	return f.prefix + ":" + name
+}
+
+// Seed starts a fresh distributed crawl: it drops what an earlier crawl
+// left under the prefix, then queues targets. Targets sharing a URL are
+// queued once. It returns how many were queued
+func (f *Frontier) Seed(targets []Target) (int, error) {
+	if _, err := f.queue.do("DEL", f.key("frontier"), f.key("processing"), f.key("sites"), f.key("seeded"), f.key("seen")); err != nil {
+		return 0, err
+	}
+	queued := 0
+	for _, target := range targets {
+		added, err := f.queue.do("SADD", f.key("sites"), target.URL)
+		if err != nil {
+			return queued, err
+		}
+		if added == int64(0) {
+			continue
+		}
+		data, err := jsoniter.MarshalToString(target)
+		if err != nil {
+			return queued, err
+		}
+		if _, err := f.queue.do("RPUSH", f.key("frontier"), data); err != nil {
+			return queued, err
+		}
+		queued++
+	}
+	_, err := f.queue.do("SET", f.key("seeded"), "1")
+	return queued, err
+}
+
+// Next moves the next target off the frontier onto the processing list;
+// call Done once it is crawled. It waits while the frontier is empty and
+// the coordinator is still seeding, and reports false once the frontier
+// is drained or ctx is done
+func (f *Frontier) Next(ctx context.Context) (Target, bool, error) {
+	for ctx.Err() == nil {
+		reply, err := f.queue.doTimeout(redisTimeout+frontierPoll, "BLMOVE", f.key("frontier"), f.key("processing"), "LEFT", "RIGHT", fmt.Sprint(int(frontierPoll.Seconds())))
+		if err == nil {
+			data, ok := reply.(string)
+			if !ok {
+				return Target{}, false, fmt.Errorf("unexpected BLMOVE reply %v", reply)
+			}
+			var target Target
+			if err := jsoniter.UnmarshalFromString(data, &target); err != nil {
+				return Target{}, false, fmt.Errorf("invalid queued target: %w", err)
+			}
+			f.mu.Lock()
+			f.taken[target.URL] = data
+			f.mu.Unlock()
+			return target, true, nil
+		}
+		if !errors.Is(err, errRedisNil) {
+			return Target{}, false, err
+		}
+		seeded, err := f.queue.do("GET", f.key("seeded"))
+		if err == nil && seeded == "1" {
+			return Target{}, false, nil
+		}
+		if err != nil && !errors.Is(err, errRedisNil) {
+			return Target{}, false, err
+		}
+	}
+	return Target{}, false, nil
+}
+
+// Done takes a target Next returned off the processing list once its crawl
+// is finished
+func (f *Frontier) Done(target Target) error {
+	f.mu.Lock()
+	data, ok := f.taken[target.URL]
+	delete(f.taken, target.URL)
+	f.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	_, err := f.queue.do("LREM", f.key("processing"), "1", data)
+	return err
+}
+
+// Duplicate records a canonical request key in the shared set and reports
+// whether some worker recorded it before. A Redis failure counts as new,
+// so an outage costs duplicate requests rather than lost coverage
+func (f *Frontier) Duplicate(key string) bool {
+	added, err := f.seen.do("SADD", f.key("seen"), key)
+	if err != nil {
+		Logger.Debugf("Redis dedup of %s failed: %s", key, err)
+		return false
+	}
+	return added == int64(0)
+}
+
+// Attach makes registry check new requests against the shared set
+func (f *Frontier) Attach(registry *URLRegistry) {
+	registry.shared = f.Duplicate
+}
+
+func (f *Frontier) Close() {
+	f.queue.Close()
+	f.seen.Close()
+}
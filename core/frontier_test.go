@@ -0,0 +1,264 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRedis implements the handful of commands the frontier sends
+type fakeRedis struct {
+	ln       net.Listener
+	password string
+	mu       sync.Mutex
+	strings  map[string]string
+	sets     map[string]map[string]bool
+	lists    map[string][]string
+	down     bool
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	f := &fakeRedis{ln: ln, password: password, strings: map[string]string{}, sets: map[string]map[string]bool{}, lists: map[string][]string{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return f
+}
+
+func (f *fakeRedis) URL() string {
+	if f.password != "" {
+		return "redis://:" + f.password + "@" + f.ln.Addr().String() + "/0"
+	}
+	return "redis://" + f.ln.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		args := make([]string, n)
+		for i := range args {
+			header, _ := r.ReadString('\n')
+			size, _ := strconv.Atoi(strings.TrimSpace(header[1:]))
+			buf := make([]byte, size+2)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return
+			}
+			args[i] = string(buf[:size])
+		}
+		if args[0] == "AUTH" {
+			authed = args[len(args)-1] == f.password
+			if !authed {
+				fmt.Fprint(conn, "-WRONGPASS invalid password\r\n")
+				continue
+			}
+			fmt.Fprint(conn, "+OK\r\n")
+			continue
+		}
+		if !authed {
+			fmt.Fprint(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		fmt.Fprint(conn, f.exec(args))
+	}
+}
+
+func (f *fakeRedis) exec(args []string) string {
+	if args[0] == "BLMOVE" {
+		deadline := time.Now().Add(200 * time.Millisecond)
+		for {
+			f.mu.Lock()
+			if f.down {
+				f.mu.Unlock()
+				return "-ERR server going down\r\n"
+			}
+			if list := f.lists[args[1]]; len(list) > 0 {
+				f.lists[args[1]] = list[1:]
+				f.lists[args[2]] = append(f.lists[args[2]], list[0])
+				f.mu.Unlock()
+				return fmt.Sprintf("$%d\r\n%s\r\n", len(list[0]), list[0])
+			}
+			f.mu.Unlock()
+			if time.Now().After(deadline) {
+				return "$-1\r\n"
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down {
+		return "-ERR server going down\r\n"
+	}
+	switch args[0] {
+	case "DEL":
+		for _, key := range args[1:] {
+			delete(f.strings, key)
+			delete(f.sets, key)
+			delete(f.lists, key)
+		}
+		return ":1\r\n"
+	case "SADD":
+		if f.sets[args[1]] == nil {
+			f.sets[args[1]] = map[string]bool{}
+		}
+		if f.sets[args[1]][args[2]] {
+			return ":0\r\n"
+		}
+		f.sets[args[1]][args[2]] = true
+		return ":1\r\n"
+	case "RPUSH":
+		f.lists[args[1]] = append(f.lists[args[1]], args[2])
+		return fmt.Sprintf(":%d\r\n", len(f.lists[args[1]]))
+	case "LREM":
+		list := f.lists[args[1]]
+		for i, item := range list {
+			if item == args[3] {
+				f.lists[args[1]] = append(list[:i:i], list[i+1:]...)
+				return ":1\r\n"
+			}
+		}
+		return ":0\r\n"
+	case "SET":
+		f.strings[args[1]] = args[2]
+		return "+OK\r\n"
+	case "GET":
+		value, ok := f.strings[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	}
+	return "-ERR unknown command\r\n"
+}
+
+func TestFrontierSeedAndNext(t *testing.T) {
+	redis := newFakeRedis(t, "secret")
+	frontier, err := OpenFrontier(redis.URL(), "test")
+	require.NoError(t, err)
+	defer frontier.Close()
+
+	redis.mu.Lock()
+	redis.sets["test:seen"] = map[string]bool{"stale": true}
+	redis.mu.Unlock()
+	queued, err := frontier.Seed([]Target{{URL: "http://a.example"}, {URL: "http://b.example", Cookie: "s=1"}, {URL: "http://a.example"}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, queued)
+	assert.False(t, frontier.Duplicate("stale"), "seeding starts a fresh crawl")
+
+	ctx := context.Background()
+	var targets []Target
+	for {
+		target, ok, err := frontier.Next(ctx)
+		require.NoError(t, err)
+		if !ok {
+			break
+		}
+		targets = append(targets, target)
+	}
+	assert.Equal(t, []Target{{URL: "http://a.example"}, {URL: "http://b.example", Cookie: "s=1"}}, targets)
+	redis.mu.Lock()
+	assert.Len(t, redis.lists["test:processing"], 2, "taken targets wait on the processing list")
+	redis.mu.Unlock()
+	require.NoError(t, frontier.Done(targets[1]))
+	redis.mu.Lock()
+	assert.Len(t, redis.lists["test:processing"], 1)
+	redis.mu.Unlock()
+
+	_, err = OpenFrontier("redis://:wrong@"+redis.ln.Addr().String(), "test")
+	assert.ErrorContains(t, err, "WRONGPASS")
+	_, err = OpenFrontier("http://"+redis.ln.Addr().String(), "test")
+	assert.Error(t, err)
+}
+
+func TestFrontierSharesDedup(t *testing.T) {
+	redis := newFakeRedis(t, "")
+	first, err := OpenFrontier(redis.URL(), "")
+	require.NoError(t, err)
+	defer first.Close()
+	second, err := OpenFrontier(redis.URL(), "")
+	require.NoError(t, err)
+	defer second.Close()
+
+	a, b := NewURLRegistry(), NewURLRegistry()
+	first.Attach(a)
+	second.Attach(b)
+	assert.False(t, a.Duplicate("http://example.com/x?b=2&a=1"))
+	assert.True(t, b.Duplicate("http://example.com/x?a=1&b=2"))
+	assert.False(t, b.Duplicate("http://example.com/y"))
+	assert.True(t, a.Duplicate("http://example.com/y"))
+}
+
+func TestDistributedCrawl(t *testing.T) {
+	redis := newFakeRedis(t, "")
+	a, b := linkSite(t), linkSite(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	coordinator := newEngine(t, CrawlerConfig{Redis: redis.URL(), RedisRole: RedisRoleCoordinator, Site: a.URL, Targets: []Target{{URL: b.URL}}})
+	require.NoError(t, coordinator.Run(ctx))
+
+	var mu sync.Mutex
+	var urls []string
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		worker := newEngine(t, CrawlerConfig{Redis: redis.URL(), RedisRole: RedisRoleWorker, MaxDepth: 2, MaxConcurrency: 1, Timeout: 5 * time.Second})
+		results := worker.Results()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for sout := range results {
+				if sout.OutputType == "url" {
+					mu.Lock()
+					urls = append(urls, sout.Output)
+					mu.Unlock()
+				}
+			}
+		}()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, worker.Run(ctx))
+		}()
+	}
+	wg.Wait()
+	assert.ElementsMatch(t, []string{a.URL, a.URL + "/page", b.URL, b.URL + "/page"}, urls)
+	assert.Empty(t, redis.lists[CLIName+":processing"], "crawled targets leave the processing list")
+}
+
+func TestDistributedCrawlRedisFailure(t *testing.T) {
+	redis := newFakeRedis(t, "")
+	worker := newEngine(t, CrawlerConfig{Redis: redis.URL(), RedisRole: RedisRoleWorker})
+	redis.mu.Lock()
+	redis.down = true
+	redis.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	assert.ErrorContains(t, worker.Run(ctx), "server going down")
+}
@@ -0,0 +1,31 @@
+package core
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/jaeles-project/gospider/core/antidetect"
+)
+
+// NewGlobalRateLimiter returns the limiter behind --global-rps, or nil when
+// rps is not positive. A burst of one spaces requests evenly
+func NewGlobalRateLimiter(rps float64) *antidetect.RateLimiter {
+	if rps <= 0 {
+		return nil
+	}
+	return antidetect.NewRateLimiter(1, time.Duration(float64(time.Second)/rps))
+}
+
+// rateLimitRoundTripper takes a token from a limiter shared by every crawler
+// of the run before each request, including retries and probes
+type rateLimitRoundTripper struct {
+	base    http.RoundTripper
+	limiter *antidetect.RateLimiter
+}
+
+func (rt *rateLimitRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := rt.limiter.WaitContext(req.Context()); err != nil {
+		return nil, err
+	}
+	return rt.base.RoundTrip(req)
+}
@@ -0,0 +1,48 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGlobalRateLimiterSharedByCrawlers(t *testing.T) {
+	assert.Nil(t, NewGlobalRateLimiter(0))
+
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer server.Close()
+
+	// Two crawlers' clients share one 50 rps budget
+	limiter := NewGlobalRateLimiter(50)
+	clients := []*http.Client{
+		{Transport: &rateLimitRoundTripper{base: http.DefaultTransport, limiter: limiter}},
+		{Transport: &rateLimitRoundTripper{base: http.DefaultTransport, limiter: limiter}},
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, client := range clients {
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func(client *http.Client) {
+				defer wg.Done()
+				resp, err := client.Get(server.URL)
+				if assert.NoError(t, err) {
+					resp.Body.Close()
+				}
+			}(client)
+		}
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(10), hits.Load())
+	assert.GreaterOrEqual(t, time.Since(start), 170*time.Millisecond, "10 requests at 50 rps take at least 180ms")
+}
@@ -0,0 +1,248 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// graphQLDefaultPath is where operations are sent when a script names no
+// GraphQL endpoint
+const graphQLDefaultPath = "/graphql"
+
+var (
+	// graphQLTagRegex matches the start of a gql`...` or graphql`...`
+	// tagged template, or a gql(`...`) call
+	graphQLTagRegex = regexp.MustCompile("\\b(?:gql|graphql)\\s*\\(?\\s*`")
+	// graphQLLiteralRegex matches the start of a string literal holding an
+	// operation
+	graphQLLiteralRegex = regexp.MustCompile("[\"'`]\\s*(?:query|mutation|subscription)\\s*[A-Za-z_({]")
+	// graphQLOperationRegex matches an operation definition up to its
+	// selection set
+	graphQLOperationRegex = regexp.MustCompile(`\b(query|mutation|subscription)\b\s*([_A-Za-z][_0-9A-Za-z]*)?\s*(\([^)]*\))?\s*(?:@[^{]*)?\{`)
+	// graphQLVariableRegex matches a variable definition and its type
+	graphQLVariableRegex = regexp.MustCompile(`\$([_A-Za-z][_0-9A-Za-z]*)\s*:\s*(\[?\s*[_A-Za-z][_0-9A-Za-z]*\s*!?\s*\]?\s*!?)`)
+	// graphQLEndpointRegex matches string literals naming a GraphQL endpoint
+	graphQLEndpointRegex = regexp.MustCompile("(?i)[\"'`]((?:https?:)?//[^\"'`\\s]+/graphql[^\"'`\\s]*|/[^\"'`\\s]*graphql[^\"'`\\s]*)[\"'`]")
+)
+
+// graphQLOperation is a query, mutation or subscription found in a script
+type graphQLOperation struct {
+	Type      string
+	Name      string
+	Variables []graphQLVariable
+	// Document is the whole document the operation came from, so the
+	// fragments it spreads are sent with it
+	Document string
+}
+
+type graphQLVariable struct {
+	Name string
+	Type string
+}
+
+// findGraphQLOperations extracts the operations of the GraphQL documents
+// in a script: gql and graphql tagged templates, and string literals that
+// start with an operation. Fragments interpolated with ${...} are dropped
+func findGraphQLOperations(body string) []graphQLOperation {
+	var found []graphQLOperation
+	seen := map[string]bool{}
+	scanned := map[int]bool{}
+	add := func(quote int) {
+		if scanned[quote] {
+			return
+		}
+		scanned[quote] = true
+		document, ok := readJSLiteral(body, quote)
+		if !ok {
+			return
+		}
+		for _, op := range parseGraphQLDocument(document) {
+			key := op.Type + " " + op.Name + " " + op.Document
+			if !seen[key] {
+				seen[key] = true
+				found = append(found, op)
+			}
+		}
+	}
+	for _, loc := range graphQLTagRegex.FindAllStringIndex(body, -1) {
+		add(loc[1] - 1)
+	}
+	for _, loc := range graphQLLiteralRegex.FindAllStringIndex(body, -1) {
+		add(loc[0])
+	}
+	return found
+}
+
+// readJSLiteral returns the decoded content of the string or template
+// literal whose opening quote is at body[start], without its ${...}
+// substitutions
+func readJSLiteral(body string, start int) (string, bool) {
+	quote := body[start]
+	var b strings.Builder
+	for i := start + 1; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case c == '\\' && i+1 < len(body):
+			// Escaped line breaks and tabs are whitespace to GraphQL
+			if next := body[i+1]; next == 'n' || next == 'r' || next == 't' {
+				b.WriteByte(' ')
+			} else {
+				b.WriteByte(c)
+				b.WriteByte(next)
+			}
+			i++
+		case c == quote:
+			return DecodeJSString(string(quote) + b.String() + string(quote)), true
+		case quote == '`' && c == '$' && i+1 < len(body) && body[i+1] == '{':
+			depth := 0
+			for i++; i < len(body); i++ {
+				if body[i] == '{' {
+					depth++
+				} else if body[i] == '}' {
+					if depth--; depth == 0 {
+						break
+					}
+				}
+			}
+		case quote != '`' && c == '\n':
+			return "", false
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", false
+}
+
+// parseGraphQLDocument returns the operations defined at the top level of
+// a document
+func parseGraphQLDocument(document string) []graphQLOperation {
+	document = strings.Join(strings.Fields(document), " ")
+	if !strings.Contains(document, "{") {
+		return nil
+	}
+	var ops []graphQLOperation
+	depth, pos := 0, 0
+	for _, m := range graphQLOperationRegex.FindAllStringSubmatchIndex(document, -1) {
+		depth += strings.Count(document[pos:m[0]], "{") - strings.Count(document[pos:m[0]], "}")
+		pos = m[0]
+		if depth != 0 {
+			continue
+		}
+		op := graphQLOperation{Type: document[m[2]:m[3]], Document: document}
+		if m[4] >= 0 {
+			op.Name = document[m[4]:m[5]]
+		}
+		if m[6] >= 0 {
+			for _, v := range graphQLVariableRegex.FindAllStringSubmatch(document[m[6]:m[7]], -1) {
+				op.Variables = append(op.Variables, graphQLVariable{Name: v[1], Type: strings.Join(strings.Fields(v[2]), "")})
+			}
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// findGraphQLEndpoint returns the first GraphQL endpoint a script names,
+// resolved against page, or /graphql on page's host
+func findGraphQLEndpoint(body string, page *url.URL) string {
+	ref := &url.URL{Path: graphQLDefaultPath}
+	if m := graphQLEndpointRegex.FindStringSubmatch(body); m != nil {
+		if u, err := url.Parse(m[1]); err == nil {
+			ref = u
+		}
+	}
+	return page.ResolveReference(ref).String()
+}
+
+// graphQLRequest builds the POST that runs op, with a placeholder value
+// for each variable of a built-in scalar type
+func graphQLRequest(op graphQLOperation, endpoint string) JSRequest {
+	variables := map[string]interface{}{}
+	for _, v := range op.Variables {
+		if value, ok := graphQLPlaceholder(v.Type); ok {
+			variables[v.Name] = value
+		}
+	}
+	payload := map[string]interface{}{"query": op.Document, "variables": variables}
+	if op.Name != "" {
+		payload["operationName"] = op.Name
+	}
+	body, _ := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(payload)
+	return JSRequest{
+		Method:      http.MethodPost,
+		RawURL:      endpoint,
+		Body:        string(body),
+		ContentType: "application/json",
+		Source:      strings.TrimSpace(op.Type + " " + op.Name),
+	}
+}
+
+// graphQLPlaceholder is the placeholder value of a variable type; input
+// objects and enums have none
+func graphQLPlaceholder(typ string) (interface{}, bool) {
+	typ = strings.TrimSuffix(typ, "!")
+	if inner, ok := strings.CutPrefix(typ, "["); ok {
+		value, ok := graphQLPlaceholder(strings.TrimSuffix(inner, "]"))
+		return []interface{}{value}, ok
+	}
+	switch typ {
+	case "Int", "Float":
+		return 1, true
+	case "Boolean":
+		return true, true
+	case "String", "ID":
+		return collectionPlaceholder, true
+	}
+	return nil, false
+}
+
+// findGraphQL reports the GraphQL operations of a script or page and
+// queues a request for each query. Mutations and subscriptions are only
+// reported, as a crawl should not change server state
+func (crawler *Crawler) findGraphQL(body string, request *colly.Request) {
+	if !strings.Contains(body, "query") && !strings.Contains(body, "mutation") && !strings.Contains(body, "subscription") {
+		return
+	}
+	ops := findGraphQLOperations(body)
+	if len(ops) == 0 {
+		return
+	}
+	origin := request.URL.String()
+	endpoint := findGraphQLEndpoint(body, request.URL)
+	for _, op := range ops {
+		if crawler.graphQLSet.Duplicate(endpoint + " " + op.Type + " " + op.Name + " " + op.Document) {
+			continue
+		}
+		if crawler.Stats != nil {
+			crawler.Stats.IncrementURLsFound()
+		}
+		var variables []string
+		for _, v := range op.Variables {
+			variables = append(variables, "$"+v.Name+": "+v.Type)
+		}
+		name := strings.TrimSpace(op.Type + " " + op.Name)
+		sout := SpiderOutput{
+			Input:      crawler.Input,
+			Source:     origin,
+			OutputType: "graphql",
+			Output:     endpoint,
+			Param:      name,
+			Payload:    op.Document,
+			Snippet:    strings.Join(variables, ", "),
+		}
+		crawler.emit(sout, fmt.Sprintf("[graphql] - [%s] - [%s] - %s - %s", name, sout.Snippet, endpoint, origin), endpoint)
+
+		req := graphQLRequest(op, endpoint)
+		if op.Type == "query" {
+			crawler.processGeneratedRequest(req, origin, request.Depth)
+		} else if normalized, ok := crawler.normalizeJSRequest(req, origin); ok {
+			crawler.emitJSRequest(normalized, origin, request.Depth+1)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const graphQLBundle = "const USER_FIELDS=gql`fragment UserFields on User { id name }`;" +
+	"const GET_USER=gql`\n  query GetUser($id: ID!, $withPosts: Boolean) {\n    user(id: $id) { ...UserFields posts @include(if: $withPosts) { title } }\n  }\n  ${USER_FIELDS}\n`;" +
+	"const DELETE=graphql(`mutation DeletePost($ids: [Int!]!, $input: DeleteInput) { deletePost(ids: $ids, input: $input) { ok } }`);" +
+	`var q="query {\n  viewer { login }\n}";` +
+	`var notGraphQL="query string parameters";` +
+	`new ApolloClient({uri:"https://api.example.com/v1/graphql"});`
+
+func TestFindGraphQLOperations(t *testing.T) {
+	ops := findGraphQLOperations(graphQLBundle)
+	require.Len(t, ops, 3)
+
+	assert.Equal(t, "query", ops[0].Type)
+	assert.Equal(t, "GetUser", ops[0].Name)
+	assert.Equal(t, []graphQLVariable{{Name: "id", Type: "ID!"}, {Name: "withPosts", Type: "Boolean"}}, ops[0].Variables)
+	assert.Equal(t, "query GetUser($id: ID!, $withPosts: Boolean) { user(id: $id) { ...UserFields posts @include(if: $withPosts) { title } } }", ops[0].Document)
+
+	assert.Equal(t, "mutation", ops[1].Type)
+	assert.Equal(t, "DeletePost", ops[1].Name)
+	assert.Equal(t, []graphQLVariable{{Name: "ids", Type: "[Int!]!"}, {Name: "input", Type: "DeleteInput"}}, ops[1].Variables)
+
+	assert.Equal(t, "query", ops[2].Type)
+	assert.Empty(t, ops[2].Name)
+	assert.Equal(t, "query { viewer { login } }", ops[2].Document)
+
+	assert.Empty(t, findGraphQLOperations(`var s="query"; fetch("/search?query="+q)`))
+}
+
+func TestGraphQLRequest(t *testing.T) {
+	page, _ := url.Parse("https://app.example.com/static/app.js")
+	assert.Equal(t, "https://api.example.com/v1/graphql", findGraphQLEndpoint(graphQLBundle, page))
+	assert.Equal(t, "https://app.example.com/graphql", findGraphQLEndpoint(`gql`+"`query A { a }`", page))
+
+	ops := findGraphQLOperations(graphQLBundle)
+	req := graphQLRequest(ops[1], "https://api.example.com/v1/graphql")
+	assert.Equal(t, "POST", req.Method)
+	assert.Equal(t, "application/json", req.ContentType)
+	assert.Equal(t, "mutation DeletePost", req.Source)
+	var body struct {
+		OperationName string                 `json:"operationName"`
+		Query         string                 `json:"query"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+	require.NoError(t, jsoniter.Unmarshal([]byte(req.Body), &body))
+	assert.Equal(t, "DeletePost", body.OperationName)
+	assert.Equal(t, ops[1].Document, body.Query)
+	assert.Equal(t, map[string]interface{}{"ids": []interface{}{float64(1)}}, body.Variables, "input objects have no placeholder")
+}
+
+func TestCrawlGraphQLOperations(t *testing.T) {
+	var mu sync.Mutex
+	var posted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/graphql":
+			data, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			posted = append(posted, string(data))
+			mu.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"data":{}}`)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><script>const client = new ApolloClient({uri: "/api/graphql"});`+
+				"const A = gql`query Me { me { id } }`; const B = gql`mutation Logout { logout }`;</script></body></html>")
+		}
+	}))
+	defer srv.Close()
+
+	results := crawlAll(t, srv.URL, CrawlerConfig{LinkFinder: true})
+
+	found := map[string]string{}
+	for _, sout := range results {
+		if sout.OutputType == "graphql" {
+			found[sout.Param] = sout.Output
+		}
+	}
+	assert.Equal(t, map[string]string{"query Me": srv.URL + "/api/graphql", "mutation Logout": srv.URL + "/api/graphql"}, found)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(t, posted, `{"operationName":"Me","query":"query Me { me { id } }","variables":{}}`)
+	for _, body := range posted {
+		assert.NotContains(t, body, "Logout", "mutations are reported but not sent")
+	}
+}
@@ -7,7 +7,10 @@ import (
 
 const SUBRE = `(?i)(([a-zA-Z0-9]{1}|[_a-zA-Z0-9]{1}[_a-zA-Z0-9-]{0,61}[a-zA-Z0-9]{1})[.]{1})+`
 
-var AWSS3 = regexp.MustCompile(`(?i)[a-z0-9.-]+\.s3\.amazonaws\.com|[a-z0-9.-]+\.s3-[a-z0-9-]\.amazonaws\.com|[a-z0-9.-]+\.s3-website[.-](eu|ap|us|ca|sa|cn)|//s3\.amazonaws\.com/[a-z0-9._-]+|//s3-[a-z0-9-]+\.amazonaws\.com/[a-z0-9._-]+`)
+// AWSS3 matches S3 bucket addresses.
+//
+// Deprecated: use FindCloudStorage, which also covers other providers.
+var AWSS3 = cloudStorageRules[0].Pattern // the aws-s3 rule
 
 // SubdomainRegex returns a Regexp object initialized to match
 // subdomain names that end with the domain provided by the parameter.
@@ -17,19 +20,39 @@ func subdomainRegex(domain string) *regexp.Regexp {
 	return regexp.MustCompile(SUBRE + d)
 }
 
+// unicodeSubdomainLabels matches the labels in front of an internationalized
+// domain written in Unicode
+const unicodeSubdomainLabels = `(?i)(([\p{L}\p{N}_]|[\p{L}\p{N}_][\p{L}\p{N}_-]{0,61}[\p{L}\p{N}])[.])+`
+
+// GetSubdomains finds subdomains of domain in source. An internationalized
+// domain is searched in both its punycode and Unicode forms, and every match
+// is returned in punycode
 func GetSubdomains(source, domain string) []string {
 	var subs []string
 	re := subdomainRegex(domain)
 	for _, match := range re.FindAllStringSubmatch(source, -1) {
 		subs = append(subs, CleanSubdomain(match[0]))
 	}
+	if unicode := UnicodeHost(domain); unicode != domain {
+		re := regexp.MustCompile(unicodeSubdomainLabels + regexp.QuoteMeta(unicode))
+		for _, match := range re.FindAllString(source, -1) {
+			subs = append(subs, CleanSubdomain(match))
+		}
+		subs = Unique(subs)
+	}
 	return subs
 }
 
+// GetAWSS3 returns the S3 bucket addresses in source.
+//
+// Deprecated: use FindCloudStorage, which also reports the bucket, region
+// and other providers.
 func GetAWSS3(source string) []string {
 	var aws []string
-	for _, match := range AWSS3.FindAllStringSubmatch(source, -1) {
-		aws = append(aws, DecodeChars(match[0]))
+	for _, storage := range FindCloudStorage(source) {
+		if storage.Provider == "aws-s3" {
+			aws = append(aws, storage.Location)
+		}
 	}
 	return aws
 }
@@ -0,0 +1,198 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// harSource is the source of requests seeded from --har
+const harSource = "har"
+
+// seedSkippedHeaders are the headers of captured requests the crawler sets
+// itself. --har collects cookies separately, per host
+var seedSkippedHeaders = map[string]struct{}{
+	"Host":              {},
+	"Content-Length":    {},
+	"Connection":        {},
+	"Keep-Alive":        {},
+	"Transfer-Encoding": {},
+	"Accept-Encoding":   {},
+	"Cookie":            {},
+	"Upgrade":           {},
+	"Te":                {},
+}
+
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	Request struct {
+		Method   string   `json:"method"`
+		URL      string   `json:"url"`
+		Headers  []harNV  `json:"headers"`
+		Cookies  []harNV  `json:"cookies"`
+		PostData *harPost `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Cookies []harNV `json:"cookies"`
+	} `json:"response"`
+}
+
+type harNV struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPost struct {
+	MimeType string  `json:"mimeType"`
+	Text     string  `json:"text"`
+	Params   []harNV `json:"params"`
+}
+
+// HARCapture is what a --har archive seeds a crawl with: its requests and
+// the cookies it recorded
+type HARCapture struct {
+	Requests []JSRequest
+	// Cookies holds a Cookie header per host, built from the cookies the
+	// capture sent and was given, the latest value of each winning
+	Cookies map[string]string
+}
+
+// LoadHAR reads the requests and cookies of a HAR archive, as exported by
+// browsers and intercepting proxies. Repeated requests are kept once
+func LoadHAR(path string) (*HARCapture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	capture := &HARCapture{Cookies: map[string]string{}}
+	jars := map[string]*harJar{}
+	seen := map[string]struct{}{}
+	for _, entry := range har.Log.Entries {
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			continue
+		}
+		host := strings.ToLower(u.Hostname())
+		jar := jars[host]
+		if jar == nil {
+			jar = &harJar{values: map[string]string{}}
+			jars[host] = jar
+		}
+		jar.add(entry.Request.Cookies)
+		if len(entry.Request.Cookies) == 0 {
+			jar.addHeader(entry.Request.Headers)
+		}
+		jar.add(entry.Response.Cookies)
+
+		u.Fragment = ""
+		req := JSRequest{
+			Method:  strings.ToUpper(strings.TrimSpace(entry.Request.Method)),
+			RawURL:  u.String(),
+			Headers: map[string]string{},
+			Source:  harSource,
+		}
+		if req.Method == "" {
+			req.Method = http.MethodGet
+		}
+		for _, h := range entry.Request.Headers {
+			name := http.CanonicalHeaderKey(strings.TrimSpace(h.Name))
+			// HTTP/2 captures record pseudo-headers such as :authority
+			if name == "" || strings.HasPrefix(name, ":") {
+				continue
+			}
+			if _, skip := seedSkippedHeaders[name]; skip {
+				continue
+			}
+			req.Headers[name] = h.Value
+		}
+		if post := entry.Request.PostData; post != nil {
+			req.Body = post.Text
+			if req.Body == "" && len(post.Params) > 0 {
+				form := url.Values{}
+				for _, p := range post.Params {
+					form.Add(p.Name, p.Value)
+				}
+				req.Body = form.Encode()
+			}
+			req.ContentType = post.MimeType
+		}
+		if req.ContentType == "" {
+			req.ContentType = req.Headers["Content-Type"]
+		}
+
+		key := req.Method + " " + req.RawURL + "\n" + req.Body
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		seen[key] = struct{}{}
+		capture.Requests = append(capture.Requests, req)
+	}
+	for host, jar := range jars {
+		if header := jar.header(); header != "" {
+			capture.Cookies[host] = header
+		}
+	}
+	return capture, nil
+}
+
+// harJar keeps the cookies of one host in the order they were first set
+type harJar struct {
+	names  []string
+	values map[string]string
+}
+
+func (j *harJar) add(cookies []harNV) {
+	for _, c := range cookies {
+		name := strings.TrimSpace(c.Name)
+		if name == "" {
+			continue
+		}
+		if _, ok := j.values[name]; !ok {
+			j.names = append(j.names, name)
+		}
+		j.values[name] = c.Value
+	}
+}
+
+// addHeader takes the cookies from a recorded Cookie header, for captures
+// that leave the parsed cookies out
+func (j *harJar) addHeader(headers []harNV) {
+	for _, h := range headers {
+		if !strings.EqualFold(h.Name, "Cookie") {
+			continue
+		}
+		var cookies []harNV
+		for _, part := range strings.Split(h.Value, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			cookies = append(cookies, harNV{Name: name, Value: value})
+		}
+		j.add(cookies)
+	}
+}
+
+func (j *harJar) header() string {
+	parts := make([]string, 0, len(j.names))
+	for _, name := range j.names {
+		parts = append(parts, name+"="+j.values[name])
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Targets returns one target per origin the capture sent requests to, for
+// crawls started from a capture alone
+func (h *HARCapture) Targets() []Target {
+	return requestTargets(nil, h.Requests)
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testHAR = `{"log": {"version": "1.2", "entries": [
+  {"request": {"method": "GET", "url": "%[1]s/",
+    "headers": [{"name": ":authority", "value": "x"}, {"name": "Cookie", "value": "sid=old; theme=dark"}, {"name": "Accept-Encoding", "value": "br"}],
+    "cookies": []},
+   "response": {"cookies": [{"name": "sid", "value": "fresh"}]}},
+  {"request": {"method": "GET", "url": "%[1]s/hidden#top",
+    "headers": [{"name": "X-Requested-With", "value": "XMLHttpRequest"}, {"name": "Host", "value": "x"}],
+    "cookies": [{"name": "sid", "value": "fresh"}]},
+   "response": {"cookies": []}},
+  {"request": {"method": "GET", "url": "%[1]s/hidden",
+    "headers": [], "cookies": []},
+   "response": {"cookies": []}},
+  {"request": {"method": "post", "url": "%[1]s/api/login",
+    "headers": [{"name": "authorization", "value": "Bearer t"}],
+    "postData": {"mimeType": "application/x-www-form-urlencoded", "params": [{"name": "user", "value": "bob"}]}},
+   "response": {"cookies": []}},
+  {"request": {"method": "GET", "url": "data:text/plain,x", "headers": []}, "response": {}}
+]}}`
+
+func writeHAR(t *testing.T, base string) string {
+	path := filepath.Join(t.TempDir(), "capture.har")
+	require.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf(testHAR, base)), 0o644))
+	return path
+}
+
+func TestLoadHAR(t *testing.T) {
+	capture, err := LoadHAR(writeHAR(t, "https://app.example.com"))
+	require.NoError(t, err)
+
+	require.Len(t, capture.Requests, 3, "repeated requests and non-HTTP URLs are dropped")
+	assert.Equal(t, JSRequest{Method: "GET", RawURL: "https://app.example.com/", Headers: map[string]string{}, Source: harSource}, capture.Requests[0])
+	assert.Equal(t, "https://app.example.com/hidden", capture.Requests[1].RawURL)
+	assert.Equal(t, map[string]string{"X-Requested-With": "XMLHttpRequest"}, capture.Requests[1].Headers)
+	assert.Equal(t, "POST", capture.Requests[2].Method)
+	assert.Equal(t, "user=bob", capture.Requests[2].Body)
+	assert.Equal(t, "application/x-www-form-urlencoded", capture.Requests[2].ContentType)
+	assert.Equal(t, map[string]string{"Authorization": "Bearer t"}, capture.Requests[2].Headers)
+
+	assert.Equal(t, map[string]string{"app.example.com": "sid=fresh; theme=dark"}, capture.Cookies)
+	assert.Equal(t, []Target{{URL: "https://app.example.com/"}}, capture.Targets())
+}
+
+func TestCrawlSeedsHARRequests(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen[r.Method+" "+r.URL.Path] = r.Header.Get("Cookie") + "|" + r.Header.Get("Authorization")
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/hidden" {
+			fmt.Fprint(w, `<html><body><a href="/deeper">d</a></body></html>`)
+			return
+		}
+		fmt.Fprint(w, `<html><body>ok</body></html>`)
+	}))
+	defer srv.Close()
+
+	crawlAll(t, srv.URL, CrawlerConfig{
+		MaxDepth: 3, HAR: writeHAR(t, srv.URL),
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "sid=fresh; theme=dark|", seen["GET /"], "the recorded cookies are reused")
+	assert.Equal(t, "sid=fresh; theme=dark|Bearer t", seen["POST /api/login"])
+	assert.Contains(t, seen, "GET /hidden")
+	assert.Contains(t, seen, "GET /deeper", "the crawl expands from the captured pages")
+}
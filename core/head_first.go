@@ -0,0 +1,101 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// defaultHeadFirstMaxSize is the largest non-text resource --head-first still fetches
+const defaultHeadFirstMaxSize = 1024 * 1024
+
+// headWorthFetching decides from HEAD response headers whether the GET is
+// needed: text-like content always is, anything else only when its declared
+// length is within maxSize
+func (crawler *Crawler) headWorthFetching(contentType string, contentLength, maxSize int64) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	switch {
+	case mediaType == "",
+		strings.HasPrefix(mediaType, "text/"),
+		strings.Contains(mediaType, "html"),
+		strings.Contains(mediaType, "javascript"),
+		strings.Contains(mediaType, "ecmascript"),
+		strings.Contains(mediaType, "json"),
+		strings.Contains(mediaType, "xml"):
+		return true
+	case crawler.wasm && mediaType == "application/wasm":
+		return true
+	case crawler.parseDocuments && (mediaType == "application/pdf" || strings.Contains(mediaType, "officedocument")):
+		return true
+	}
+	return contentLength >= 0 && contentLength <= maxSize
+}
+
+// probeHead sends a HEAD for a pending GET and reports whether the GET should
+// be dropped. Failed or refused probes fall through to the GET, and the probe
+// ends with the crawl
+func (crawler *Crawler) probeHead(r *colly.Request) bool {
+	ctx := crawler.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, r.URL.String(), nil)
+	if err != nil {
+		return false
+	}
+	if r.Headers != nil {
+		req.Header = r.Headers.Clone()
+	}
+	if crawler.Stats != nil {
+		crawler.Stats.IncrementRequestsMade()
+	}
+	resp, err := crawler.sideClient().Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	if crawler.Stats != nil {
+		crawler.Stats.RecordStatus(resp.StatusCode)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented, http.StatusForbidden:
+		return false
+	}
+	crawler.recordBackoff(resp.StatusCode)
+	if crawler.headWorthFetching(resp.Header.Get("Content-Type"), resp.ContentLength, crawler.headFirstMaxSize) {
+		return false
+	}
+
+	Logger.Debugf("head-first skip %s (%s, %d bytes)", r.URL, resp.Header.Get("Content-Type"), resp.ContentLength)
+	crawler.emitHeadResult(r.URL.String(), resp.StatusCode, resp.ContentLength)
+	return true
+}
+
+// emitHeadResult reports a resource known only from its HEAD response
+func (crawler *Crawler) emitHeadResult(rawURL string, status int, length int64) {
+	if status == 404 || status == 429 || status < 100 || status >= 500 {
+		return
+	}
+	u := NormalizeDisplayURL(rawURL)
+	outputFormat := fmt.Sprintf("[url] - [code-%d] - %s", status, u)
+	if crawler.length && length >= 0 {
+		outputFormat = fmt.Sprintf("[url] - [code-%d] - [len_%d] - %s", status, length, u)
+	}
+
+	sout := SpiderOutput{
+		Input:      crawler.Input,
+		Source:     "head",
+		OutputType: "url",
+		StatusCode: status,
+		Output:     u,
+	}
+	crawler.emit(sout, outputFormat, u)
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeadWorthFetching(t *testing.T) {
+	crawler := &Crawler{}
+	assert.True(t, crawler.headWorthFetching("text/html; charset=utf-8", -1, 1024))
+	assert.True(t, crawler.headWorthFetching("application/javascript", 10<<20, 1024))
+	assert.True(t, crawler.headWorthFetching("image/png", 512, 1024))
+	assert.False(t, crawler.headWorthFetching("video/mp4", 10<<20, 1024))
+	assert.False(t, crawler.headWorthFetching("application/octet-stream", -1, 1024))
+	assert.False(t, crawler.headWorthFetching("application/pdf", 10<<20, 1024))
+
+	crawler.parseDocuments = true
+	assert.True(t, crawler.headWorthFetching("application/pdf", 10<<20, 1024))
+}
+
+func TestCrawlHeadFirstSkipsLargeBinaries(t *testing.T) {
+	var bigGets atomic.Int32
+	big := make([]byte, 2<<20)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><a href="/page">page</a><a href="/download/setup.bin">setup</a></body></html>`)
+		case "/download/setup.bin":
+			if r.Method == http.MethodGet {
+				bigGets.Add(1)
+			}
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Content-Length", strconv.Itoa(len(big)))
+			if r.Method == http.MethodGet {
+				_, _ = w.Write(big)
+			}
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body>page</body></html>`)
+		}
+	}))
+	defer srv.Close()
+
+	results := crawlAll(t, srv.URL, CrawlerConfig{HeadFirst: true})
+
+	sources := map[string]string{}
+	for _, sout := range results {
+		if sout.OutputType == "url" {
+			sources[sout.Output] = sout.Source
+		}
+	}
+	assert.Equal(t, "head", sources[srv.URL+"/download/setup.bin"], "skipped resources are reported from the HEAD")
+	assert.Equal(t, "body", sources[srv.URL+"/page"])
+	assert.Zero(t, bigGets.Load())
+}
+
+func TestProbeHeadRecordsStatusAndEndsWithCrawl(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Length", strconv.Itoa(4<<20))
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	crawler := &Crawler{ctx: ctx, Stats: NewCrawlStats(), headFirstMaxSize: defaultHeadFirstMaxSize, sink: &recordingSink{}}
+	u, err := url.Parse(srv.URL + "/archive.zip")
+	require.NoError(t, err)
+
+	assert.True(t, crawler.probeHead(&colly.Request{URL: u}))
+	assert.Equal(t, map[int]int64{http.StatusOK: 1}, crawler.Stats.GetStatusCounts())
+
+	cancel()
+	assert.False(t, crawler.probeHead(&colly.Request{URL: u}), "a cancelled crawl falls through without probing")
+	assert.Equal(t, int64(2), crawler.Stats.GetRequestsMade())
+	assert.Equal(t, map[int]int64{http.StatusOK: 1}, crawler.Stats.GetStatusCounts())
+}
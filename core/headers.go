@@ -0,0 +1,65 @@
+package core
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// ParseHeaderFlags turns -H values ("Name: value") into a header set. A name
+// given more than once keeps every value in the order given, so replayed
+// requests can carry repeated headers such as X-Forwarded-For
+func ParseHeaderFlags(values []string) http.Header {
+	headers := http.Header{}
+	for _, h := range values {
+		headerArgs := strings.SplitN(h, ":", 2)
+		if len(headerArgs) != 2 {
+			continue
+		}
+		headerKey := strings.TrimSpace(headerArgs[0])
+		if headerKey == "" {
+			continue
+		}
+		headers.Add(headerKey, strings.TrimSpace(headerArgs[1]))
+	}
+	return headers
+}
+
+// ParseBurpHeaders reads the headers of a raw request saved from Burp.
+// Repeated headers keep all their values in file order, except Cookie, whose
+// lines are merged into one as HTTP/1.1 requires
+func ParseBurpHeaders(r io.Reader) (http.Header, error) {
+	req, err := http.ReadRequest(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+	headers := http.Header{}
+	for k, values := range req.Header {
+		for _, v := range values {
+			headers.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+		}
+	}
+	if cookies := req.Cookies(); len(cookies) > 0 {
+		headers.Set("Cookie", GetRawCookie(cookies))
+	}
+	return headers, nil
+}
+
+// applyHeaders replaces every header named in headers with its values. The
+// wire order of distinct names is up to net/http, which sorts them
+func applyHeaders(dst http.Header, headers http.Header) {
+	for k, values := range headers {
+		dst[k] = append([]string(nil), values...)
+	}
+}
+
+// headerFlagName returns the canonical name of a -H value, or "" if it has none
+func headerFlagName(h string) string {
+	name, _, ok := strings.Cut(h, ":")
+	if !ok {
+		return ""
+	}
+	return textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name))
+}
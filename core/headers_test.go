@@ -0,0 +1,75 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const burpWithDuplicates = "GET /account HTTP/1.1\r\n" +
+	"Host: example.com\r\n" +
+	"X-Forwarded-For: 10.0.0.1\r\n" +
+	"Accept: text/html\r\n" +
+	"X-Forwarded-For: 10.0.0.2\r\n" +
+	"Cookie: a=1\r\n" +
+	"Cookie: b=2\r\n" +
+	"\r\n"
+
+func TestParseBurpHeadersKeepsDuplicates(t *testing.T) {
+	headers, err := ParseBurpHeaders(strings.NewReader(burpWithDuplicates))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, headers.Values("X-Forwarded-For"))
+	assert.Equal(t, []string{"a=1; b=2"}, headers.Values("Cookie"))
+	assert.Equal(t, []string{"text/html"}, headers.Values("Accept"))
+}
+
+func TestParseHeaderFlagsKeepsDuplicates(t *testing.T) {
+	headers := ParseHeaderFlags([]string{"X-Forwarded-For: 1.1.1.1", "x-forwarded-for: 2.2.2.2", "bogus", ": empty", "Accept: */*"})
+	assert.Equal(t, http.Header{
+		"X-Forwarded-For": {"1.1.1.1", "2.2.2.2"},
+		"Accept":          {"*/*"},
+	}, headers)
+}
+
+func TestCrawlSendsDuplicateHeaders(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string]http.Header{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received[r.URL.Path] = r.Header.Clone()
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><a href="/next">next</a></body></html>`))
+	}))
+	defer srv.Close()
+
+	crawl := func(cfg CrawlerConfig) {
+		crawlAll(t, srv.URL, cfg)
+	}
+
+	burpFile := filepath.Join(t.TempDir(), "request.txt")
+	require.NoError(t, os.WriteFile(burpFile, []byte(burpWithDuplicates), 0644))
+	crawl(CrawlerConfig{BurpFile: burpFile})
+	mu.Lock()
+	for _, path := range []string{"/", "/next"} {
+		require.Contains(t, received, path)
+		assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, received[path].Values("X-Forwarded-For"), path)
+		assert.Equal(t, []string{"a=1; b=2"}, received[path].Values("Cookie"), path)
+	}
+	received = map[string]http.Header{}
+	mu.Unlock()
+
+	crawl(CrawlerConfig{Headers: []string{"X-Forwarded-For: 1.1.1.1", "X-Forwarded-For: 2.2.2.2", "User-Agent: replay"}})
+	mu.Lock()
+	defer mu.Unlock()
+	require.Contains(t, received, "/")
+	assert.Equal(t, []string{"1.1.1.1", "2.2.2.2"}, received["/"].Values("X-Forwarded-For"))
+	assert.Equal(t, []string{"replay"}, received["/"].Values("User-Agent"))
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"net/http"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// RequestTransform rewrites an outgoing request just before it is sent, e.g.
+// to sign it or add a nonce. It sees the final URL, method and headers, after
+// colly OnRequest callbacks and the anti-detect header composition. A
+// transform that reads req.Body must replace it with an unread copy
+type RequestTransform func(req *http.Request)
+
+// requestTransformRoundTripper applies the crawler's RequestTransform to
+// every request made through the shared client: main and LinkFinder
+// collectors, generated requests, probes and each redirect hop
+type requestTransformRoundTripper struct {
+	base    http.RoundTripper
+	crawler *Crawler
+}
+
+func (rt *requestTransformRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if transform := rt.crawler.RequestTransform; transform != nil {
+		// RoundTrippers must not modify the caller's request
+		req = req.Clone(req.Context())
+		transform(req)
+	}
+	return rt.base.RoundTrip(req)
+}
+
+// ResponseTransform inspects or rewrites a response before the built-in
+// extractors run, e.g. to decrypt or unwrap the body. Whatever it leaves in
+// r.Body and r.Headers is what every extractor, HTML callback and result sees
+type ResponseTransform func(r *colly.Response)
+
+// applyResponseTransform is registered ahead of every extractor callback on
+// both collectors; colly runs OnResponse callbacks in registration order and
+// OnHTML ones after all of them
+func (crawler *Crawler) applyResponseTransform(r *colly.Response) {
+	if transform := crawler.ResponseTransform; transform != nil {
+		transform(r)
+	}
+}
@@ -0,0 +1,90 @@
+package core
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCrawlAppliesRequestTransform(t *testing.T) {
+	var mu sync.Mutex
+	unsigned, served := map[string]bool{}, map[string]bool{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Signature") != "sig:"+r.URL.Path {
+			mu.Lock()
+			unsigned[r.URL.Path] = true
+			mu.Unlock()
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		mu.Lock()
+		served[r.URL.Path] = true
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><a href="/page">page</a><script src="/static/app.js"></script></body></html>`)
+		case "/static/app.js":
+			w.Header().Set("Content-Type", "application/javascript")
+			fmt.Fprint(w, `var api = "/api/items";`)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body>ok</body></html>`)
+		}
+	}))
+	defer srv.Close()
+
+	crawlAll(t, srv.URL, CrawlerConfig{
+		LinkFinder: true,
+		RequestTransform: func(req *http.Request) {
+			path := req.URL.Path
+			if path == "" {
+				path = "/"
+			}
+			req.Header.Set("X-Signature", "sig:"+path)
+		},
+	})
+	assert.Empty(t, unsigned)
+	assert.True(t, served["/page"])
+	assert.True(t, served["/static/app.js"], "LinkFinder requests are signed too")
+}
+
+func TestCrawlAppliesResponseTransform(t *testing.T) {
+	wrap := func(body string) string {
+		return "ENC:" + base64.StdEncoding.EncodeToString([]byte(body))
+	}
+	var mu sync.Mutex
+	served := map[string]bool{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		served[r.URL.Path] = true
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, wrap(`<html><body><a href="/hidden">hidden</a></body></html>`))
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body>ok</body></html>`)
+		}
+	}))
+	defer srv.Close()
+
+	crawlAll(t, srv.URL, CrawlerConfig{
+		ResponseTransform: func(r *colly.Response) {
+			if encoded, ok := strings.CutPrefix(string(r.Body), "ENC:"); ok {
+				if decoded, err := base64.StdEncoding.DecodeString(encoded); err == nil {
+					r.Body = decoded
+				}
+			}
+		},
+	})
+	assert.True(t, served["/hidden"], "links inside the unwrapped body are crawled")
+}
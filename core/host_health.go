@@ -0,0 +1,93 @@
+package core
+
+import (
+	"sync"
+)
+
+// HostHealth counts consecutive transport failures per host. Hosts that keep
+// failing DNS resolution or refusing connections are marked dead, and hosts
+// that keep failing the TLS handshake are flagged for an HTTP/1.1 fallback
+type HostHealth struct {
+	mu            sync.Mutex
+	deadThreshold int
+	tlsThreshold  int
+	hosts         map[string]*hostHealthEntry
+}
+
+type hostHealthEntry struct {
+	unreachable int
+	tlsFailures int
+	dead        bool
+	tlsFlagged  bool
+}
+
+// NewHostHealth returns a tracker that marks a host dead after deadThreshold
+// consecutive DNS or refused errors and flags it after tlsThreshold
+// consecutive TLS errors. A threshold of 0 disables that check
+func NewHostHealth(deadThreshold, tlsThreshold int) *HostHealth {
+	return &HostHealth{
+		deadThreshold: deadThreshold,
+		tlsThreshold:  tlsThreshold,
+		hosts:         make(map[string]*hostHealthEntry),
+	}
+}
+
+// Failure records a failed request of the given error class. becameDead and
+// tlsFlagged are true only for the failure that crosses a threshold
+func (h *HostHealth) Failure(host, class string) (becameDead, tlsFlagged bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entry, ok := h.hosts[host]
+	if !ok {
+		entry = &hostHealthEntry{}
+		h.hosts[host] = entry
+	}
+	switch class {
+	case ErrorClassDNS, ErrorClassRefused:
+		entry.unreachable++
+		if !entry.dead && h.deadThreshold > 0 && entry.unreachable >= h.deadThreshold {
+			entry.dead = true
+			becameDead = true
+		}
+	case ErrorClassTLS:
+		entry.tlsFailures++
+		if !entry.tlsFlagged && h.tlsThreshold > 0 && entry.tlsFailures >= h.tlsThreshold {
+			entry.tlsFlagged = true
+			tlsFlagged = true
+		}
+	case ErrorClassStatus:
+		// The host answered, so its connection is fine
+		entry.unreachable, entry.tlsFailures = 0, 0
+	}
+	return becameDead, tlsFlagged
+}
+
+// Success resets the consecutive failure counts of host
+func (h *HostHealth) Success(host string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if entry, ok := h.hosts[host]; ok {
+		entry.unreachable, entry.tlsFailures = 0, 0
+	}
+}
+
+// IsDead reports whether host has been marked dead
+func (h *HostHealth) IsDead(host string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	entry, ok := h.hosts[host]
+	return ok && entry.dead
+}
+
+// observeRequestFailure feeds a failed request into the host health tracker
+// and warns the first time a host is given up on or looks TLS-incompatible
+func (crawler *Crawler) observeRequestFailure(host, class string) {
+	becameDead, tlsFlagged := crawler.hostHealth.Failure(host, class)
+	if becameDead {
+		Logger.Warnf("[dead-host] %s keeps failing with %s errors, skipping its remaining requests", host, class)
+	}
+	if tlsFlagged {
+		Logger.Warnf("[tls-fallback] %s keeps failing the TLS handshake; the server may not support the negotiated protocol, try forcing HTTP/1.1 or a different TLS profile", host)
+	}
+}
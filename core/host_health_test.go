@@ -0,0 +1,54 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostHealthMarksDeadHostOnce(t *testing.T) {
+	h := NewHostHealth(3, 2)
+
+	dead, _ := h.Failure("gone.example.com", ErrorClassDNS)
+	assert.False(t, dead)
+	dead, _ = h.Failure("gone.example.com", ErrorClassRefused)
+	assert.False(t, dead)
+	dead, _ = h.Failure("gone.example.com", ErrorClassDNS)
+	assert.True(t, dead)
+	dead, _ = h.Failure("gone.example.com", ErrorClassDNS)
+	assert.False(t, dead, "only the crossing failure reports")
+	assert.True(t, h.IsDead("gone.example.com"))
+
+	// Successes and status errors break the streak
+	h.Failure("flaky.example.com", ErrorClassRefused)
+	h.Failure("flaky.example.com", ErrorClassRefused)
+	h.Success("flaky.example.com")
+	h.Failure("flaky.example.com", ErrorClassRefused)
+	h.Failure("flaky.example.com", ErrorClassStatus)
+	h.Failure("flaky.example.com", ErrorClassRefused)
+	assert.False(t, h.IsDead("flaky.example.com"))
+
+	// Timeouts neither count nor reset
+	h.Failure("slow.example.com", ErrorClassRefused)
+	h.Failure("slow.example.com", ErrorClassTimeout)
+	h.Failure("slow.example.com", ErrorClassRefused)
+	dead, _ = h.Failure("slow.example.com", ErrorClassRefused)
+	assert.True(t, dead)
+}
+
+func TestHostHealthFlagsTLSFailures(t *testing.T) {
+	h := NewHostHealth(0, 2)
+
+	_, flagged := h.Failure("h2.example.com", ErrorClassTLS)
+	assert.False(t, flagged)
+	_, flagged = h.Failure("h2.example.com", ErrorClassTLS)
+	assert.True(t, flagged)
+	_, flagged = h.Failure("h2.example.com", ErrorClassTLS)
+	assert.False(t, flagged)
+	assert.False(t, h.IsDead("h2.example.com"), "TLS errors do not kill a host")
+
+	for i := 0; i < 10; i++ {
+		h.Failure("gone.example.com", ErrorClassDNS)
+	}
+	assert.False(t, h.IsDead("gone.example.com"), "a zero threshold disables dead hosts")
+}
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const hostLatencyAlpha = 0.3
+
+// HostLatency keeps an exponential moving average of response times per host
+// and flags hosts whose average stays above a threshold
+type HostLatency struct {
+	mu         sync.Mutex
+	threshold  time.Duration
+	minSamples int
+	hosts      map[string]*hostLatencyEntry
+}
+
+type hostLatencyEntry struct {
+	avg     time.Duration
+	samples int
+	slow    bool
+}
+
+// NewHostLatency returns a tracker that marks a host slow once at least
+// minSamples responses average above threshold
+func NewHostLatency(threshold time.Duration, minSamples int) *HostLatency {
+	if minSamples < 1 {
+		minSamples = 1
+	}
+	return &HostLatency{
+		threshold:  threshold,
+		minSamples: minSamples,
+		hosts:      make(map[string]*hostLatencyEntry),
+	}
+}
+
+// Observe records a response time for host. becameSlow is true only for the
+// sample that first pushes the host over the threshold
+func (h *HostLatency) Observe(host string, d time.Duration) (avg time.Duration, becameSlow bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entry, ok := h.hosts[host]
+	if !ok {
+		entry = &hostLatencyEntry{avg: d}
+		h.hosts[host] = entry
+	} else {
+		entry.avg = time.Duration(hostLatencyAlpha*float64(d) + (1-hostLatencyAlpha)*float64(entry.avg))
+	}
+	entry.samples++
+
+	if !entry.slow && h.threshold > 0 && entry.samples >= h.minSamples && entry.avg > h.threshold {
+		entry.slow = true
+		becameSlow = true
+	}
+	return entry.avg, becameSlow
+}
+
+// IsSlow reports whether host has been flagged slow
+func (h *HostLatency) IsSlow(host string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	entry, ok := h.hosts[host]
+	return ok && entry.slow
+}
+
+// hostLatencyRoundTripper times each request up to the response headers and
+// optionally bounds it with a per-host timeout stricter than the client one
+type hostLatencyRoundTripper struct {
+	base    http.RoundTripper
+	timeout time.Duration
+	observe func(host string, d time.Duration)
+}
+
+func (rt *hostLatencyRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	cancel := context.CancelFunc(func() {})
+	if rt.timeout > 0 {
+		var ctx context.Context
+		ctx, cancel = context.WithTimeout(req.Context(), rt.timeout)
+		req = req.WithContext(ctx)
+	}
+
+	start := time.Now()
+	resp, err := rt.base.RoundTrip(req)
+	if rt.observe != nil {
+		rt.observe(req.URL.Hostname(), time.Since(start))
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// The deadline also covers the body, so release it once the body is done
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// observeHostLatency records a sample and warns the first time a host turns slow
+func (crawler *Crawler) observeHostLatency(host string, d time.Duration) {
+	avg, becameSlow := crawler.hostLatency.Observe(host, d)
+	if !becameSlow {
+		return
+	}
+	if crawler.skipSlowHosts {
+		Logger.Warnf("[slow-host] %s averages %s per response, skipping its remaining requests", host, avg.Round(time.Millisecond))
+	} else {
+		Logger.Warnf("[slow-host] %s averages %s per response", host, avg.Round(time.Millisecond))
+	}
+}
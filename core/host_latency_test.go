@@ -0,0 +1,53 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostLatencyFlagsSlowHostOnce(t *testing.T) {
+	h := NewHostLatency(100*time.Millisecond, 2)
+
+	_, slow := h.Observe("slow.example.com", 500*time.Millisecond)
+	assert.False(t, slow, "needs the minimum sample count first")
+	_, slow = h.Observe("slow.example.com", 500*time.Millisecond)
+	assert.True(t, slow)
+	_, slow = h.Observe("slow.example.com", 500*time.Millisecond)
+	assert.False(t, slow, "only the crossing sample reports")
+	assert.True(t, h.IsSlow("slow.example.com"))
+
+	h.Observe("fast.example.com", 10*time.Millisecond)
+	h.Observe("fast.example.com", 20*time.Millisecond)
+	assert.False(t, h.IsSlow("fast.example.com"))
+	assert.False(t, h.IsSlow("unknown.example.com"))
+}
+
+func TestHostLatencyRoundTripperTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	var observed time.Duration
+	client := &http.Client{Transport: &hostLatencyRoundTripper{
+		base:    http.DefaultTransport,
+		timeout: 50 * time.Millisecond,
+		observe: func(host string, d time.Duration) { observed = d },
+	}}
+
+	resp, err := client.Get(srv.URL)
+	if resp != nil {
+		resp.Body.Close()
+	}
+	require.Error(t, err)
+	assert.GreaterOrEqual(t, observed, 50*time.Millisecond)
+	assert.Less(t, observed, time.Second)
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// HostOutputs routes findings to one output file per host they were found
+// on, for --output-by-host. Files are named like the per-target files and
+// opened on first use; the set is shared by every crawler of a run, so two
+// targets reporting the same host append to one file
+type HostOutputs struct {
+	mu          sync.Mutex
+	folder      string
+	split       bool
+	dedupMode   string
+	dedupFields []string
+	outputs     map[string]*Output
+	closed      bool
+}
+
+// NewHostOutputs returns an empty set writing into folder. split and the
+// dedup settings apply to every file as they do to -o output
+func NewHostOutputs(folder string, split bool, dedupMode string, dedupFields []string) *HostOutputs {
+	return &HostOutputs{
+		folder:      folder,
+		split:       split,
+		dedupMode:   dedupMode,
+		dedupFields: dedupFields,
+		outputs:     make(map[string]*Output),
+	}
+}
+
+// Get returns the output of host, or nil when it cannot be opened
+func (h *HostOutputs) Get(host string) *Output {
+	filename := strings.ReplaceAll(strings.ToLower(host), ".", "_")
+	if filename == "" || strings.ContainsAny(filename, `/\`) {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil
+	}
+	if output, ok := h.outputs[filename]; ok {
+		return output
+	}
+
+	var output *Output
+	if h.split {
+		output = NewSplitOutput(h.folder, filename)
+	} else {
+		var err error
+		if output, err = openOutput(filepath.Join(h.folder, filename)); err != nil {
+			Logger.Errorf("Failed to open file to write Output: %s", err)
+			// Remember the failure so the open is not retried for every record
+			h.outputs[filename] = nil
+			return nil
+		}
+	}
+	output.EnableDedup(h.dedupMode, h.dedupFields)
+	h.outputs[filename] = output
+	return output
+}
+
+// Close closes every file opened so far
+func (h *HostOutputs) Close() {
+	h.mu.Lock()
+	h.closed = true
+	outputs := h.outputs
+	h.mu.Unlock()
+	for _, output := range outputs {
+		if output != nil {
+			output.Close()
+		}
+	}
+}
+
+// outputFor picks the file a finding is written to: the file of the host in
+// its URL with --output-by-host, the target's file otherwise or when the
+// finding has no host
+func (crawler *Crawler) outputFor(sout SpiderOutput) *Output {
+	if crawler.hostOutputs == nil {
+		return crawler.Output
+	}
+	raw := sout.Output
+	if hostFinding(sout.OutputType) && !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u := outputURL(raw)
+	if u == nil {
+		return crawler.Output
+	}
+	if output := crawler.hostOutputs.Get(u.Hostname()); output != nil {
+		return output
+	}
+	return crawler.Output
+}
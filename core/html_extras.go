@@ -0,0 +1,141 @@
+package core
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly/v2"
+)
+
+var (
+	htmlCommentRegex = regexp.MustCompile(`(?s)<!--(.*?)-->`)
+	// Absolute URLs, or root-relative paths preceded by whitespace, a quote, = or (
+	commentURLRegex     = regexp.MustCompile(`https?://[^\s"'<>]+|(?:^|[\s"'=(])(/[A-Za-z0-9_\-.~%]+(?:/[A-Za-z0-9_\-.~%]*)*(?:\?[^\s"'<>]*)?)`)
+	metaRefreshURLRegex = regexp.MustCompile(`(?i)url\s*=\s*['"]?([^'";\s]+)`)
+)
+
+// ExtractCommentURLs returns URLs and root-relative paths mentioned in HTML
+// comments, e.g. <!-- TODO: remove /admin/debug -->
+func ExtractCommentURLs(body string) []string {
+	var urls []string
+	for _, comment := range htmlCommentRegex.FindAllStringSubmatch(body, -1) {
+		for _, match := range commentURLRegex.FindAllStringSubmatch(comment[1], -1) {
+			candidate := match[0]
+			if match[1] != "" {
+				candidate = match[1]
+			}
+			candidate = strings.TrimRight(candidate, ".,;:)'\"")
+			if candidate == "" || candidate == "/" || strings.HasPrefix(candidate, "//") {
+				continue
+			}
+			urls = append(urls, candidate)
+		}
+	}
+	return Unique(urls)
+}
+
+// ParseMetaRefresh returns the target of a meta refresh content value such as
+// "0;url=/next", or "" when it has none
+func ParseMetaRefresh(content string) string {
+	match := metaRefreshURLRegex.FindStringSubmatch(content)
+	if match == nil {
+		return ""
+	}
+	return strings.TrimSpace(match[1])
+}
+
+// DocumentBaseURL returns the URL relative links in doc resolve against: the
+// first <base href>, resolved against the page URL, or the page URL itself
+func DocumentBaseURL(doc *goquery.Selection, page *url.URL) *url.URL {
+	href, ok := doc.Find("base[href]").First().Attr("href")
+	href = strings.TrimSpace(href)
+	if !ok || href == "" {
+		return page
+	}
+	base, err := page.Parse(href)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") {
+		return page
+	}
+	return base
+}
+
+// documentBase returns the resolution base of e's page, computed once per
+// response and dropped in OnScraped
+func (crawler *Crawler) documentBase(e *colly.HTMLElement) *url.URL {
+	if cached, ok := crawler.documentBases.Load(e.Response); ok {
+		return cached.(*url.URL)
+	}
+	root := e.DOM.Parents().Last()
+	if root.Length() == 0 {
+		root = e.DOM
+	}
+	base := DocumentBaseURL(root, e.Request.URL)
+	crawler.documentBases.Store(e.Response, base)
+	return base
+}
+
+// registerHTMLExtras adds handlers for URLs outside plain href/src attributes
+func (crawler *Crawler) registerHTMLExtras() {
+	crawler.C.OnHTML("html", func(e *colly.HTMLElement) {
+		if crawler.stopped.Load() || crawler.shouldSkipDOM(e.Request.URL.String()) {
+			return
+		}
+		base := crawler.documentBase(e)
+		for _, raw := range ExtractCommentURLs(string(e.Response.Body)) {
+			if urlToVisit := crawler.urlProcessor.ProcessWithBase(raw, "comment", "comment", base, e.Request); urlToVisit != "" {
+				crawler.visit(e.Request, urlToVisit)
+			}
+		}
+	})
+
+	crawler.C.OnHTML("meta[http-equiv]", func(e *colly.HTMLElement) {
+		if crawler.stopped.Load() || crawler.shouldSkipDOM(e.Request.URL.String()) {
+			return
+		}
+		if !strings.EqualFold(strings.TrimSpace(e.Attr("http-equiv")), "refresh") {
+			return
+		}
+		target := ParseMetaRefresh(e.Attr("content"))
+		if target == "" {
+			return
+		}
+		if urlToVisit := crawler.urlProcessor.ProcessWithBase(target, "meta-refresh", "meta-refresh", crawler.documentBase(e), e.Request); urlToVisit != "" {
+			crawler.visit(e.Request, urlToVisit)
+		}
+	})
+
+	crawler.C.OnHTML("link[href]", func(e *colly.HTMLElement) {
+		if crawler.stopped.Load() || crawler.shouldSkipDOM(e.Request.URL.String()) {
+			return
+		}
+		raw := e.Attr("href")
+		crawler.recordDependency(e, raw)
+		// Preloaded and prefetched scripts go to LinkFinder like <script src>
+		if GetExtType(raw) == ".js" || strings.EqualFold(e.Attr("as"), "script") {
+			jsFileURL, ok := NormalizeURL(crawler.documentBase(e), raw)
+			if !ok {
+				return
+			}
+			crawler.feedLinkfinder(jsFileURL, "javascript", "link")
+			return
+		}
+		if urlToVisit := crawler.urlProcessor.ProcessWithBase(raw, "link", "link", crawler.documentBase(e), e.Request); urlToVisit != "" {
+			crawler.visit(e.Request, urlToVisit)
+		}
+	})
+
+	crawler.C.OnHTML("base[href]", func(e *colly.HTMLElement) {
+		if crawler.stopped.Load() || crawler.shouldSkipDOM(e.Request.URL.String()) {
+			return
+		}
+		if urlToVisit := crawler.urlProcessor.Process(e.Attr("href"), "body", "base", e.Request); urlToVisit != "" {
+			crawler.visit(e.Request, urlToVisit)
+		}
+	})
+
+	crawler.C.OnScraped(func(r *colly.Response) {
+		crawler.documentBases.Delete(r)
+	})
+}
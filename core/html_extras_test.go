@@ -0,0 +1,75 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractCommentURLs(t *testing.T) {
+	body := `<html><!-- TODO: remove /admin/debug before release -->
+<body><a href="/visible">x</a>
+<!-- old api: https://api.example.com/v1/users?id=1, see also "/internal/report.php". -->
+<!-- 1/2 done, path / only --></body></html>`
+
+	assert.Equal(t, []string{
+		"/admin/debug",
+		"https://api.example.com/v1/users?id=1",
+		"/internal/report.php",
+	}, ExtractCommentURLs(body))
+	assert.Empty(t, ExtractCommentURLs(`<a href="/visible">x</a>`))
+}
+
+func TestParseMetaRefresh(t *testing.T) {
+	assert.Equal(t, "/next", ParseMetaRefresh("0;url=/next"))
+	assert.Equal(t, "https://example.com/landing", ParseMetaRefresh("5; URL='https://example.com/landing'"))
+	assert.Equal(t, "", ParseMetaRefresh("30"))
+}
+
+func TestDocumentBaseURL(t *testing.T) {
+	page, _ := url.Parse("https://example.com/a/b/page.html")
+	parse := func(html string) *goquery.Selection {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+		require.NoError(t, err)
+		return doc.Selection
+	}
+
+	assert.Equal(t, "https://example.com/app/", DocumentBaseURL(parse(`<head><base href="/app/"></head>`), page).String())
+	assert.Equal(t, "https://cdn.example.com/x/", DocumentBaseURL(parse(`<base href="https://cdn.example.com/x/"><base href="/ignored/">`), page).String())
+	assert.Equal(t, page.String(), DocumentBaseURL(parse(`<base target="_blank">`), page).String())
+	assert.Equal(t, page.String(), DocumentBaseURL(parse(`<base href="javascript:void(0)">`), page).String())
+}
+
+func TestCrawlHonorsBaseHref(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/shop/index.html" {
+			fmt.Fprint(w, `<html><head><base href="/app/"></head><body>
+<a href="orders">orders</a>
+<iframe src="frames/help"></iframe>
+<a href="/root-page">root</a>
+</body></html>`)
+			return
+		}
+		fmt.Fprint(w, `<html><body>leaf</body></html>`)
+	}))
+	defer srv.Close()
+
+	results := crawlAll(t, srv.URL+"/shop/index.html", CrawlerConfig{MaxDepth: 1})
+
+	var outputs []string
+	for _, sout := range results {
+		outputs = append(outputs, sout.Output)
+	}
+	assert.Contains(t, outputs, srv.URL+"/app/orders")
+	assert.Contains(t, outputs, srv.URL+"/app/frames/help")
+	assert.Contains(t, outputs, srv.URL+"/root-page")
+	assert.NotContains(t, outputs, srv.URL+"/shop/orders")
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"regexp"
+	"sync/atomic"
+	"unicode"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// spaMarkerRegex matches the mount points and state blobs of client-rendered apps
+var spaMarkerRegex = regexp.MustCompile(`(?i)<div[^>]+id=["'](?:root|app|__next|__nuxt|svelte)["']|__NEXT_DATA__|window\.__NUXT__|__INITIAL_STATE__|\bng-version=|\bdata-reactroot\b|<app-root\b`)
+
+var (
+	nonTextBlockRegex = regexp.MustCompile(`(?is)<(script|style|noscript|template)\b.*?</(?:script|style|noscript|template)>|<!--.*?-->`)
+	tagRegex          = regexp.MustCompile(`(?s)<[^>]*>`)
+	scriptTagRegex    = regexp.MustCompile(`(?i)<script\b`)
+)
+
+// HybridRenderReason tells whether a statically crawled HTML page needs the
+// browser, and why: it carries SPA markers, the extractors found fewer than
+// minLinks links in it, or it has under thinText characters of visible text
+// besides its scripts. An empty reason leaves the page to the static crawl
+func HybridRenderReason(body []byte, links, minLinks, thinText int) string {
+	switch {
+	case spaMarkerRegex.Match(body):
+		return "spa-marker"
+	case links < minLinks:
+		return "few-links"
+	case scriptTagRegex.Match(body) && visibleTextLength(body) < thinText:
+		return "thin-body"
+	}
+	return ""
+}
+
+// visibleTextLength counts the non-space characters a page shows as text
+func visibleTextLength(body []byte) int {
+	text := tagRegex.ReplaceAll(nonTextBlockRegex.ReplaceAll(body, nil), nil)
+	n := 0
+	for _, r := range string(text) {
+		if !unicode.IsSpace(r) {
+			n++
+		}
+	}
+	return n
+}
+
+// countHybridLink records a link the extractors found on r's page, for the
+// --hybrid-selective decision taken once the page is scraped
+func (crawler *Crawler) countHybridLink(r *colly.Request) {
+	if r == nil || !crawler.hybridSelective || !crawler.hybridActive.Load() {
+		return
+	}
+	counter, _ := crawler.hybridLinks.LoadOrStore(r, new(atomic.Int32))
+	counter.(*atomic.Int32).Add(1)
+}
+
+// registerHybridTrigger hands scraped HTML pages to the browser pool when
+// HybridRenderReason says static extraction likely missed their content
+func (crawler *Crawler) registerHybridTrigger() {
+	crawler.C.OnScraped(func(response *colly.Response) {
+		links := 0
+		if counter, ok := crawler.hybridLinks.LoadAndDelete(response.Request); ok {
+			links = int(counter.(*atomic.Int32).Load())
+		}
+		if !crawler.hybridSelective || !crawler.hybridActive.Load() || crawler.stopped.Load() {
+			return
+		}
+		if !isLikelyHTML(response.Headers.Get("Content-Type"), response.Body) {
+			return
+		}
+		urlStr := response.Request.URL.String()
+		if reason := HybridRenderReason(response.Body, links, crawler.hybridMinLinks, crawler.hybridThinText); reason != "" {
+			Logger.Debugf("hybrid render %s (%s, %d links)", urlStr, reason, links)
+			crawler.enqueueHybrid(urlStr)
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package core
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHybridRenderReason(t *testing.T) {
+	article := `<html><body><h1>Release notes</h1><p>` + strings.Repeat("Fixed a bug in the exporter. ", 20) + `</p><script src="/app.js"></script></body></html>`
+	assert.Equal(t, "", HybridRenderReason([]byte(article), 12, 5, 200))
+	assert.Equal(t, "few-links", HybridRenderReason([]byte(article), 2, 5, 200))
+
+	next := `<html><body><div id="__next"></div><script id="__NEXT_DATA__" type="application/json">{}</script></body></html>`
+	assert.Equal(t, "spa-marker", HybridRenderReason([]byte(next), 40, 5, 200))
+	assert.Equal(t, "spa-marker", HybridRenderReason([]byte(`<body><app-root ng-version="17.0.0"></app-root></body>`), 40, 5, 200))
+
+	// Lots of links in a nav bar but nothing else until the bundle runs
+	shell := `<html><head><style>body{margin:0}</style></head><body><nav><a href="/a">A</a><a href="/b">B</a></nav><main></main><script src="/bundle.js"></script><script>window.cfg = {"text": "` + strings.Repeat("x", 500) + `"}</script></body></html>`
+	assert.Equal(t, "thin-body", HybridRenderReason([]byte(shell), 12, 5, 200))
+	// Without any script there is nothing to render
+	assert.Equal(t, "", HybridRenderReason([]byte(`<html><body><p>Short page</p></body></html>`), 12, 5, 200))
+}
+
+func TestVisibleTextLength(t *testing.T) {
+	body := `<html><head><title>Hi</title><style>p{color:red}</style></head><body><!-- note --><p>a b</p><script>var x = "hidden";</script><noscript>Enable JS</noscript></body></html>`
+	assert.Equal(t, 4, visibleTextLength([]byte(body)))
+}
+
+func TestCountHybridLinks(t *testing.T) {
+	crawler := &Crawler{hybridSelective: true}
+	r := &colly.Request{}
+	crawler.countHybridLink(r)
+	_, counted := crawler.hybridLinks.Load(r)
+	assert.False(t, counted, "nothing is counted while the browser pool is down")
+
+	crawler.hybridActive.Store(true)
+	crawler.countHybridLink(r)
+	crawler.countHybridLink(r)
+	crawler.countHybridLink(nil)
+	counter, ok := crawler.hybridLinks.Load(r)
+	if assert.True(t, ok) {
+		assert.Equal(t, int32(2), counter.(*atomic.Int32).Load())
+	}
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// ASCIIHost returns the punycode form of an internationalized host, lower
+// cased, so "Bücher.example" and "xn--bcher-kva.example" compare equal. Hosts
+// that are already ASCII or fail IDNA validation are only lower cased
+func ASCIIHost(host string) string {
+	host = strings.ToLower(host)
+	if isASCII(host) {
+		return host
+	}
+	if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+		return ascii
+	}
+	return host
+}
+
+// UnicodeHost returns the Unicode form of a punycode host for display
+func UnicodeHost(host string) string {
+	if !strings.Contains(host, "xn--") {
+		return host
+	}
+	if unicode, err := idna.Display.ToUnicode(host); err == nil {
+		return unicode
+	}
+	return host
+}
+
+// asciiURL returns u with its host in punycode. u itself is returned when
+// nothing changes
+func asciiURL(u *url.URL) *url.URL {
+	if u == nil || isASCII(u.Host) {
+		return u
+	}
+	normalized := *u
+	normalized.Host = asciiHostPort(u.Host)
+	return &normalized
+}
+
+func asciiHostPort(hostport string) string {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return ASCIIHost(hostport)
+	}
+	return net.JoinHostPort(ASCIIHost(host), port)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// unicodeDisplay rewrites the punycode host of a finding's URL to Unicode in
+// its Output and rendered lines, for --normalize-unicode
+func unicodeDisplay(sout *SpiderOutput, text, plain *string) {
+	host := sout.Output
+	if u := outputURL(sout.Output); u != nil {
+		host = u.Hostname()
+	} else if strings.ContainsAny(host, " /") {
+		return
+	}
+	unicode := UnicodeHost(host)
+	if unicode == host {
+		return
+	}
+	sout.Output = strings.ReplaceAll(sout.Output, host, unicode)
+	*text = strings.ReplaceAll(*text, host, unicode)
+	*plain = strings.ReplaceAll(*plain, host, unicode)
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	idnUnicode  = "bücher.example"
+	idnPunycode = "xn--bcher-kva.example"
+)
+
+func TestASCIIHost(t *testing.T) {
+	assert.Equal(t, idnPunycode, ASCIIHost(idnUnicode))
+	assert.Equal(t, idnPunycode, ASCIIHost("Bücher.Example"))
+	assert.Equal(t, idnPunycode, ASCIIHost(idnPunycode))
+	assert.Equal(t, "example.com", ASCIIHost("Example.COM"))
+	assert.Equal(t, idnUnicode, UnicodeHost(idnPunycode))
+	assert.Equal(t, "example.com", UnicodeHost("example.com"))
+}
+
+func TestIDNHostsShareScope(t *testing.T) {
+	unicodeSite, err := url.Parse("https://" + idnUnicode + "/")
+	require.NoError(t, err)
+	punycodeSite, err := url.Parse("https://" + idnPunycode + "/")
+	require.NoError(t, err)
+
+	assert.Equal(t, GetDomain(punycodeSite), GetDomain(unicodeSite))
+	assert.Equal(t, ScopePattern(punycodeSite, false), ScopePattern(unicodeSite, false))
+
+	scope := []*regexp.Regexp{regexp.MustCompile(ScopePattern(unicodeSite, true))}
+	for _, raw := range []string{
+		"https://" + idnUnicode + "/a",
+		"https://" + idnPunycode + "/a",
+		"https://api." + idnUnicode + "/v1",
+		"https://api." + idnPunycode + "/v1",
+	} {
+		u, err := url.Parse(raw)
+		require.NoError(t, err)
+		assert.True(t, InScope(u, scope), raw)
+	}
+
+	fromUnicode, ok := NormalizeURL(unicodeSite, "https://"+idnUnicode+"/a")
+	require.True(t, ok)
+	fromPunycode, ok := NormalizeURL(punycodeSite, "/a")
+	require.True(t, ok)
+	assert.Equal(t, "https://"+idnPunycode+"/a", fromUnicode)
+	assert.Equal(t, fromPunycode, fromUnicode, "both forms deduplicate to the same URL")
+}
+
+func TestIDNSubdomainsDeduplicated(t *testing.T) {
+	source := `<a href="https://api.` + idnUnicode + `/">api</a> <script src="//API.` + idnPunycode + `/x.js"></script> cdn.` + idnUnicode
+	subs := GetSubdomains(source, GetDomain(&url.URL{Host: idnUnicode}))
+	assert.ElementsMatch(t, []string{"api." + idnPunycode, "cdn." + idnPunycode}, subs)
+}
+
+func TestEmitNormalizeUnicode(t *testing.T) {
+	sink := &recordingSink{}
+	crawler := &Crawler{sink: sink, normalizeUnicode: true}
+	crawler.emit(SpiderOutput{OutputType: "url", Output: "https://" + idnPunycode + "/a"}, "[url] - https://"+idnPunycode+"/a", "")
+	crawler.emit(SpiderOutput{OutputType: "subdomain", Output: "api." + idnPunycode}, "", "")
+	crawler.emit(SpiderOutput{OutputType: "url", Output: "https://example.com/a"}, "", "")
+
+	require.Len(t, sink.results, 3)
+	assert.Equal(t, "https://"+idnUnicode+"/a", sink.results[0].Output)
+	assert.Equal(t, "api."+idnUnicode, sink.results[1].Output)
+	assert.Equal(t, "https://example.com/a", sink.results[2].Output)
+}
@@ -0,0 +1,424 @@
+package core
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	// DefaultMaxJobs is how many jobs a JobServer runs at once by default
+	DefaultMaxJobs = 4
+	// DefaultMaxJobResults is how many findings a job keeps by default
+	DefaultMaxJobResults = 100000
+	// DefaultJobRetention is how long a finished job is kept by default
+	DefaultJobRetention = time.Hour
+)
+
+// ErrTooManyJobs is returned by Submit while MaxJobs jobs are running
+var ErrTooManyJobs = errors.New("too many running jobs")
+
+// Job states reported by the control API
+const (
+	JobRunning   = "running"
+	JobDone      = "done"
+	JobCancelled = "cancelled"
+	JobFailed    = "failed"
+)
+
+// JobConfigFunc builds the configuration of a job from its options, flag
+// values keyed by long flag name as in a --config file
+type JobConfigFunc func(options map[string]interface{}) (CrawlerConfig, error)
+
+// JobRequest is the body of POST /jobs. Targets are URLs or objects in the
+// format of a --sites line
+type JobRequest struct {
+	Targets []jsoniter.RawMessage  `json:"targets"`
+	Options map[string]interface{} `json:"options,omitempty"`
+}
+
+// JobStatus describes a job in the control API
+type JobStatus struct {
+	ID       string        `json:"id"`
+	State    string        `json:"state"`
+	Targets  []string      `json:"targets"`
+	Started  time.Time     `json:"started"`
+	Finished *time.Time    `json:"finished,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Results  int           `json:"results"`
+	Dropped  int           `json:"dropped,omitempty"`
+	Stats    *StatsSummary `json:"stats,omitempty"`
+}
+
+// job is one crawl run by the server. Its latest results are kept until
+// the job is deleted, so any number of clients can stream them; the oldest
+// are dropped past the server's MaxResults
+type job struct {
+	id      string
+	targets []string
+	started time.Time
+	engine  *Engine
+	cancel  context.CancelFunc
+
+	mu       sync.Mutex
+	state    string
+	finished time.Time
+	err      error
+	results  []SpiderOutput
+	// dropped counts the results no longer kept, which came before results
+	dropped int
+	// changed is closed and replaced whenever results or state change
+	changed chan struct{}
+}
+
+func (j *job) status(withStats bool) JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	status := JobStatus{ID: j.id, State: j.state, Targets: j.targets, Started: j.started, Results: j.dropped + len(j.results), Dropped: j.dropped}
+	if j.state != JobRunning {
+		finished := j.finished
+		status.Finished = &finished
+	}
+	if j.err != nil {
+		status.Error = j.err.Error()
+	}
+	if withStats {
+		end := time.Now()
+		if j.state != JobRunning {
+			end = j.finished
+		}
+		summary := j.engine.Stats().Summary(end.Sub(j.started))
+		status.Stats = &summary
+	}
+	return status
+}
+
+func (j *job) notify() {
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// JobServer runs crawl jobs submitted over an HTTP API:
+//
+//	POST   /jobs              submit a JobRequest, returns its JobStatus
+//	GET    /jobs              list the jobs
+//	GET    /jobs/{id}         the job's status with its statistics
+//	GET    /jobs/{id}/results stream its findings as JSON lines, following
+//	                          a running job until it ends unless follow=false;
+//	                          since=N skips the first N
+//	DELETE /jobs/{id}         cancel a running job, or forget a finished one
+type JobServer struct {
+	// MaxJobs caps the jobs running at once; more are refused until one ends
+	MaxJobs int
+	// MaxResults caps the findings kept per job; the oldest are dropped
+	MaxResults int
+	// Retention is how long a finished job is kept before it is forgotten
+	Retention time.Duration
+
+	configure JobConfigFunc
+	token     string
+	ctx       context.Context
+
+	mu      sync.Mutex
+	jobs    map[string]*job
+	nextID  int
+	running int
+}
+
+// NewJobServer returns a server building each job's configuration with
+// configure. A non-empty token must be sent as a bearer token. Jobs are
+// cancelled when ctx is done
+func NewJobServer(ctx context.Context, configure JobConfigFunc, token string) *JobServer {
+	return &JobServer{
+		MaxJobs:    DefaultMaxJobs,
+		MaxResults: DefaultMaxJobResults,
+		Retention:  DefaultJobRetention,
+		configure:  configure,
+		token:      token,
+		ctx:        ctx,
+		jobs:       map[string]*job{},
+	}
+}
+
+// Handler returns the HTTP handler of the API
+func (s *JobServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /jobs", s.handleSubmit)
+	mux.HandleFunc("GET /jobs", s.handleList)
+	mux.HandleFunc("GET /jobs/{id}", s.handleStatus)
+	mux.HandleFunc("GET /jobs/{id}/results", s.handleResults)
+	mux.HandleFunc("DELETE /jobs/{id}", s.handleDelete)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(given), []byte(s.token)) != 1 {
+				writeAPIError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+				return
+			}
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// prune forgets the jobs that finished more than Retention ago
+func (s *JobServer) prune() {
+	if s.Retention <= 0 {
+		return
+	}
+	s.mu.Lock()
+	jobs := make([]*job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j)
+	}
+	s.mu.Unlock()
+	for _, j := range jobs {
+		j.mu.Lock()
+		expired := j.state != JobRunning && time.Since(j.finished) > s.Retention
+		j.mu.Unlock()
+		if expired {
+			s.mu.Lock()
+			delete(s.jobs, j.id)
+			s.mu.Unlock()
+		}
+	}
+}
+
+// Submit starts a job
+func (s *JobServer) Submit(req JobRequest) (JobStatus, error) {
+	s.prune()
+	targets := make([]Target, 0, len(req.Targets))
+	for _, raw := range req.Targets {
+		line := string(raw)
+		var url string
+		if jsoniter.Unmarshal(raw, &url) == nil {
+			line = url
+		}
+		target, err := ParseTargetLine(line)
+		if err != nil {
+			return JobStatus{}, err
+		}
+		targets = append(targets, target)
+	}
+	if len(targets) == 0 {
+		return JobStatus{}, ErrNoTargets
+	}
+	cfg, err := s.configure(req.Options)
+	if err != nil {
+		return JobStatus{}, err
+	}
+	cfg.Site, cfg.Sites = "", ""
+	cfg.Targets = targets
+
+	s.mu.Lock()
+	if s.MaxJobs > 0 && s.running >= s.MaxJobs {
+		s.mu.Unlock()
+		return JobStatus{}, ErrTooManyJobs
+	}
+	s.running++
+	s.mu.Unlock()
+	release := func() {
+		s.mu.Lock()
+		s.running--
+		s.mu.Unlock()
+	}
+	engine, err := NewEngine(cfg)
+	if err != nil {
+		release()
+		return JobStatus{}, err
+	}
+
+	s.mu.Lock()
+	s.nextID++
+	id := strconv.Itoa(s.nextID)
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	j := &job{id: id, started: time.Now(), engine: engine, cancel: cancel, state: JobRunning, changed: make(chan struct{})}
+	for _, target := range targets {
+		j.targets = append(j.targets, target.URL)
+	}
+	results := j.engine.Results()
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		for sout := range results {
+			j.mu.Lock()
+			j.results = append(j.results, sout)
+			if over := len(j.results) - s.MaxResults; s.MaxResults > 0 && over > 0 {
+				j.results = j.results[over:]
+				j.dropped += over
+			}
+			j.notify()
+			j.mu.Unlock()
+		}
+	}()
+	go func() {
+		err := j.engine.Run(ctx)
+		cancel()
+		<-drained
+		release()
+		j.mu.Lock()
+		j.finished = time.Now()
+		switch {
+		case errors.Is(err, context.Canceled):
+			j.state = JobCancelled
+		case err != nil:
+			j.state, j.err = JobFailed, err
+		default:
+			j.state = JobDone
+		}
+		state := j.state
+		j.notify()
+		j.mu.Unlock()
+		Logger.Infof("Job %s %s", id, state)
+	}()
+
+	s.mu.Lock()
+	s.jobs[id] = j
+	s.mu.Unlock()
+	Logger.Infof("Job %s started on %s", id, strings.Join(j.targets, ", "))
+	return j.status(false), nil
+}
+
+func (s *JobServer) job(w http.ResponseWriter, r *http.Request) *job {
+	s.prune()
+	s.mu.Lock()
+	j := s.jobs[r.PathValue("id")]
+	s.mu.Unlock()
+	if j == nil {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("no job %q", r.PathValue("id")))
+	}
+	return j
+}
+
+func (s *JobServer) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	// Browsers send other types cross-site without a preflight
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeAPIError(w, http.StatusUnsupportedMediaType, errors.New("jobs must be sent as application/json"))
+		return
+	}
+	var req JobRequest
+	if err := jsoniter.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid job: %w", err))
+		return
+	}
+	status, err := s.Submit(req)
+	if errors.Is(err, ErrTooManyJobs) {
+		writeAPIError(w, http.StatusTooManyRequests, err)
+		return
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusCreated, status)
+}
+
+func (s *JobServer) handleList(w http.ResponseWriter, _ *http.Request) {
+	s.prune()
+	s.mu.Lock()
+	jobs := make([]*job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j)
+	}
+	s.mu.Unlock()
+	statuses := make([]JobStatus, len(jobs))
+	for i, j := range jobs {
+		statuses[i] = j.status(false)
+	}
+	sort.Slice(statuses, func(a, b int) bool {
+		x, _ := strconv.Atoi(statuses[a].ID)
+		y, _ := strconv.Atoi(statuses[b].ID)
+		return x < y
+	})
+	writeAPIJSON(w, http.StatusOK, statuses)
+}
+
+func (s *JobServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if j := s.job(w, r); j != nil {
+		writeAPIJSON(w, http.StatusOK, j.status(true))
+	}
+}
+
+func (s *JobServer) handleResults(w http.ResponseWriter, r *http.Request) {
+	j := s.job(w, r)
+	if j == nil {
+		return
+	}
+	next, _ := strconv.Atoi(r.URL.Query().Get("since"))
+	follow := r.URL.Query().Get("follow") != "false"
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	for {
+		j.mu.Lock()
+		// Results dropped before the client got to them are skipped
+		next = max(next, j.dropped)
+		var batch []SpiderOutput
+		if next < j.dropped+len(j.results) {
+			batch = j.results[next-j.dropped:]
+		}
+		running, changed := j.state == JobRunning, j.changed
+		j.mu.Unlock()
+
+		for _, sout := range batch {
+			line, err := jsoniter.Marshal(sout)
+			if err != nil {
+				continue
+			}
+			if _, err := w.Write(append(line, '\n')); err != nil {
+				return
+			}
+		}
+		next += len(batch)
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if !follow || !running {
+			return
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *JobServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	j := s.job(w, r)
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	running := j.state == JobRunning
+	j.mu.Unlock()
+	if running {
+		j.cancel()
+		writeAPIJSON(w, http.StatusAccepted, j.status(false))
+		return
+	}
+	s.mu.Lock()
+	delete(s.jobs, j.id)
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeAPIJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = jsoniter.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, code int, err error) {
+	writeAPIJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,218 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestJobServer(t *testing.T, token string) *httptest.Server {
+	_, srv := newTestJobServerWith(t, token)
+	return srv
+}
+
+// newTestJobServerWith also returns the JobServer, for tests changing its limits
+func newTestJobServerWith(t *testing.T, token string) (*JobServer, *httptest.Server) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	server := NewJobServer(ctx, func(options map[string]interface{}) (CrawlerConfig, error) {
+		if _, ok := options["bogus"]; ok {
+			return CrawlerConfig{}, fmt.Errorf("unknown flag %q", "bogus")
+		}
+		return CrawlerConfig{MaxDepth: 2, MaxConcurrency: 1, Timeout: 5 * time.Second}, nil
+	}, token)
+	srv := httptest.NewServer(server.Handler())
+	t.Cleanup(srv.Close)
+	return server, srv
+}
+
+func apiRequest(t *testing.T, method, url, token, body string, v interface{}) int {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	if v != nil {
+		require.NoError(t, jsoniter.NewDecoder(resp.Body).Decode(v))
+	}
+	return resp.StatusCode
+}
+
+func TestJobServerRunsJobs(t *testing.T) {
+	site := linkSite(t)
+	api := newTestJobServer(t, "s3cret")
+
+	assert.Equal(t, http.StatusUnauthorized, apiRequest(t, http.MethodGet, api.URL+"/jobs", "", "", nil))
+
+	var submitted JobStatus
+	body := fmt.Sprintf(`{"targets":[%q]}`, site.URL)
+	require.Equal(t, http.StatusCreated, apiRequest(t, http.MethodPost, api.URL+"/jobs", "s3cret", body, &submitted))
+	assert.Equal(t, "1", submitted.ID)
+	assert.Equal(t, []string{site.URL}, submitted.Targets)
+
+	req, err := http.NewRequest(http.MethodGet, api.URL+"/jobs/1/results", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var urls []string
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		var sout SpiderOutput
+		require.NoError(t, jsoniter.Unmarshal(sc.Bytes(), &sout))
+		if sout.OutputType == "url" {
+			urls = append(urls, sout.Output)
+		}
+	}
+	assert.ElementsMatch(t, []string{site.URL, site.URL + "/page"}, urls)
+
+	var status JobStatus
+	require.Equal(t, http.StatusOK, apiRequest(t, http.MethodGet, api.URL+"/jobs/1", "s3cret", "", &status))
+	assert.Equal(t, JobDone, status.State)
+	require.NotNil(t, status.Finished)
+	require.NotNil(t, status.Stats)
+	assert.NotZero(t, status.Stats.Requests)
+
+	var since []string
+	resp2, err := http.NewRequest(http.MethodGet, api.URL+fmt.Sprintf("/jobs/1/results?since=%d", status.Results-1), nil)
+	require.NoError(t, err)
+	resp2.Header.Set("Authorization", "Bearer s3cret")
+	got, err := http.DefaultClient.Do(resp2)
+	require.NoError(t, err)
+	sc = bufio.NewScanner(got.Body)
+	for sc.Scan() {
+		since = append(since, sc.Text())
+	}
+	got.Body.Close()
+	assert.Len(t, since, 1)
+
+	var list []JobStatus
+	require.Equal(t, http.StatusOK, apiRequest(t, http.MethodGet, api.URL+"/jobs", "s3cret", "", &list))
+	require.Len(t, list, 1)
+	assert.Nil(t, list[0].Stats)
+
+	assert.Equal(t, http.StatusNoContent, apiRequest(t, http.MethodDelete, api.URL+"/jobs/1", "s3cret", "", nil))
+	assert.Equal(t, http.StatusNotFound, apiRequest(t, http.MethodGet, api.URL+"/jobs/1", "s3cret", "", nil))
+}
+
+func TestJobServerRejectsBadJobs(t *testing.T) {
+	api := newTestJobServer(t, "")
+	var apiErr map[string]string
+	assert.Equal(t, http.StatusBadRequest, apiRequest(t, http.MethodPost, api.URL+"/jobs", "", `{"targets":[]}`, &apiErr))
+	assert.Equal(t, ErrNoTargets.Error(), apiErr["error"])
+	assert.Equal(t, http.StatusBadRequest, apiRequest(t, http.MethodPost, api.URL+"/jobs", "", `{"targets":["http://a.example"],"options":{"bogus":1}}`, &apiErr))
+	assert.Contains(t, apiErr["error"], "bogus")
+	assert.Equal(t, http.StatusBadRequest, apiRequest(t, http.MethodPost, api.URL+"/jobs", "", `{"targets":[{"cookie":"a=1"}]}`, &apiErr))
+	assert.Equal(t, http.StatusBadRequest, apiRequest(t, http.MethodPost, api.URL+"/jobs", "", `not json`, nil))
+
+	// A form post, which a browser sends cross-site without asking
+	resp, err := http.Post(api.URL+"/jobs", "text/plain", strings.NewReader(`{"targets":["http://a.example"]}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+}
+
+func TestJobServerLimits(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+	server, api := newTestJobServerWith(t, "")
+	server.MaxJobs = 1
+
+	body := fmt.Sprintf(`{"targets":[%q]}`, slow.URL)
+	var status JobStatus
+	require.Equal(t, http.StatusCreated, apiRequest(t, http.MethodPost, api.URL+"/jobs", "", body, &status))
+	var apiErr map[string]string
+	assert.Equal(t, http.StatusTooManyRequests, apiRequest(t, http.MethodPost, api.URL+"/jobs", "", body, &apiErr))
+	assert.Equal(t, ErrTooManyJobs.Error(), apiErr["error"])
+
+	// Cancelling the running job frees its slot
+	apiRequest(t, http.MethodDelete, api.URL+"/jobs/"+status.ID, "", "", nil)
+	require.Eventually(t, func() bool {
+		apiRequest(t, http.MethodGet, api.URL+"/jobs/"+status.ID, "", "", &status)
+		return status.State != JobRunning
+	}, 10*time.Second, 20*time.Millisecond)
+	server.MaxJobs = 0
+
+	site := linkSite(t)
+	server.MaxResults = 1
+	require.Equal(t, http.StatusCreated, apiRequest(t, http.MethodPost, api.URL+"/jobs", "", fmt.Sprintf(`{"targets":[%q]}`, site.URL), &status))
+	require.Eventually(t, func() bool {
+		apiRequest(t, http.MethodGet, api.URL+"/jobs/"+status.ID, "", "", &status)
+		return status.State != JobRunning
+	}, 10*time.Second, 20*time.Millisecond)
+	require.Greater(t, status.Results, 1)
+	assert.Equal(t, status.Results-1, status.Dropped)
+
+	resp, err := http.Get(api.URL + "/jobs/" + status.ID + "/results")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var results []SpiderOutput
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		var sout SpiderOutput
+		require.NoError(t, jsoniter.Unmarshal(sc.Bytes(), &sout))
+		results = append(results, sout)
+	}
+	assert.Len(t, results, 1, "only the latest result is kept")
+}
+
+func TestJobServerCancelsJobs(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+	api := newTestJobServer(t, "")
+
+	var status JobStatus
+	require.Equal(t, http.StatusCreated, apiRequest(t, http.MethodPost, api.URL+"/jobs", "", fmt.Sprintf(`{"targets":[{"url":%q}]}`, slow.URL), &status))
+	assert.Equal(t, JobRunning, status.State)
+	assert.Equal(t, http.StatusAccepted, apiRequest(t, http.MethodDelete, api.URL+"/jobs/"+status.ID, "", "", nil))
+
+	require.Eventually(t, func() bool {
+		apiRequest(t, http.MethodGet, api.URL+"/jobs/"+status.ID, "", "", &status)
+		return status.State != JobRunning
+	}, 10*time.Second, 20*time.Millisecond)
+	assert.Equal(t, JobCancelled, status.State)
+}
+
+func TestJobServerForgetsFinishedJobs(t *testing.T) {
+	site := linkSite(t)
+	server, api := newTestJobServerWith(t, "")
+
+	var status JobStatus
+	require.Equal(t, http.StatusCreated, apiRequest(t, http.MethodPost, api.URL+"/jobs", "", fmt.Sprintf(`{"targets":[%q]}`, site.URL), &status))
+	require.Eventually(t, func() bool {
+		apiRequest(t, http.MethodGet, api.URL+"/jobs/"+status.ID, "", "", &status)
+		return status.State != JobRunning
+	}, 10*time.Second, 20*time.Millisecond)
+
+	server.Retention = time.Nanosecond
+	var jobs []JobStatus
+	require.Equal(t, http.StatusOK, apiRequest(t, http.MethodGet, api.URL+"/jobs", "", "", &jobs))
+	assert.Empty(t, jobs)
+	assert.Equal(t, http.StatusNotFound, apiRequest(t, http.MethodGet, api.URL+"/jobs/"+status.ID, "", "", nil))
+}
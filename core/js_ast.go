@@ -0,0 +1,672 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/javascript"
+)
+
+// errJSParse is returned by ExtractJSRequestsAST when the source is not
+// JavaScript the parser accepts, so the caller can use the regex extractor
+var errJSParse = errors.New("javascript parse error")
+
+// jsCallQuery captures every call and constructor invocation in a tree,
+// and the variables assigned after their declaration, which are not
+// constants
+const jsCallQuery = `[(call_expression) (new_expression)] @call
+(assignment_expression left: (identifier) @assigned)
+(augmented_assignment_expression left: (identifier) @assigned)
+(update_expression argument: (identifier) @assigned)`
+
+// maxJSResolveDepth bounds how many constants deep a value is followed
+const maxJSResolveDepth = 8
+
+var (
+	jsQueryOnce sync.Once
+	jsQuery     *sitter.Query
+	jsQueryErr  error
+)
+
+func jsCalls() (*sitter.Query, error) {
+	jsQueryOnce.Do(func() {
+		jsQuery, jsQueryErr = sitter.NewQuery([]byte(jsCallQuery), javascript.GetLanguage())
+	})
+	return jsQuery, jsQueryErr
+}
+
+// axiosBodyVerbs are the axios shorthands taking (url, data, config);
+// the others take (url, config)
+var axiosBodyVerbs = map[string]bool{"post": true, "put": true, "patch": true}
+
+var axiosVerbs = map[string]bool{
+	"get": true, "delete": true, "head": true, "options": true,
+	"post": true, "put": true, "patch": true, "request": true,
+}
+
+// ExtractJSRequestsAST finds the same requests as ExtractJSRequests by
+// parsing source and walking its call expressions: fetch, axios (including
+// axios.create instances), jQuery, XMLHttpRequest, new Request and
+// sendBeacon. URLs built from template literals or string concatenation
+// keep their literal parts. Constants, variables declared once with a
+// known value and never reassigned, are substituted, as are the objects
+// spread into options, headers and bodies; any other expression becomes
+// the placeholder value, and a URL that starts with one is kept relative.
+// It returns errJSParse when source does not parse cleanly
+func ExtractJSRequestsAST(source string, base *url.URL) ([]JSRequest, error) {
+	query, err := jsCalls()
+	if err != nil {
+		return nil, err
+	}
+	src := []byte(source)
+	parser := sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(javascript.GetLanguage())
+	tree, err := parser.ParseCtx(context.Background(), nil, src)
+	if err != nil {
+		return nil, err
+	}
+	defer tree.Close()
+	root := tree.RootNode()
+	if root == nil || root.HasError() {
+		return nil, errJSParse
+	}
+
+	cursor := sitter.NewQueryCursor()
+	defer cursor.Close()
+	cursor.Exec(query, root)
+	w := &jsWalker{src: src, clients: map[string]bool{"axios": true}, assigned: map[string]bool{}}
+	var calls []*sitter.Node
+	for {
+		match, index, ok := cursor.NextCapture()
+		if !ok {
+			break
+		}
+		capture := match.Captures[index]
+		if query.CaptureNameForId(capture.Index) == "assigned" {
+			w.assigned[w.text(capture.Node)] = true
+			continue
+		}
+		calls = append(calls, capture.Node)
+	}
+
+	for _, call := range calls {
+		w.recordClient(call)
+	}
+	for _, call := range calls {
+		w.visit(call)
+	}
+	w.pairXHRSends()
+	return finalizeJSRequests(w.requests), nil
+}
+
+// jsWalker holds the state of one ExtractJSRequestsAST pass
+type jsWalker struct {
+	src      []byte
+	clients  map[string]bool
+	assigned map[string]bool
+	depth    int
+	opens    []jsXHROpen
+	sends    []jsXHRSend
+	requests []JSRequest
+}
+
+type jsXHROpen struct {
+	object string
+	end    uint32
+	index  int
+}
+
+type jsXHRSend struct {
+	object string
+	start  uint32
+	body   string
+}
+
+// jsPart is a piece of a string value: literal text, or an expression
+// whose value is unknown until runtime
+type jsPart struct {
+	text  string
+	known bool
+}
+
+func (w *jsWalker) text(n *sitter.Node) string {
+	if n == nil {
+		return ""
+	}
+	return n.Content(w.src)
+}
+
+// callee returns the dotted name of what call invokes, without a leading
+// window. or self.
+func (w *jsWalker) callee(call *sitter.Node) string {
+	field := "function"
+	if call.Type() == "new_expression" {
+		field = "constructor"
+	}
+	name := strings.Join(strings.Fields(w.text(call.ChildByFieldName(field))), "")
+	for _, global := range []string{"window.", "self.", "globalThis."} {
+		name = strings.TrimPrefix(name, global)
+	}
+	return name
+}
+
+func (w *jsWalker) args(call *sitter.Node) []*sitter.Node {
+	list := call.ChildByFieldName("arguments")
+	if list == nil {
+		return nil
+	}
+	args := make([]*sitter.Node, 0, list.NamedChildCount())
+	for i := 0; i < int(list.NamedChildCount()); i++ {
+		if arg := list.NamedChild(i); arg != nil && arg.Type() != "comment" {
+			args = append(args, arg)
+		}
+	}
+	return args
+}
+
+// recordClient remembers the names axios.create results are assigned to,
+// so api.get(...) is read like axios.get(...)
+func (w *jsWalker) recordClient(call *sitter.Node) {
+	if w.callee(call) != "axios.create" {
+		return
+	}
+	parent := call.Parent()
+	if parent == nil {
+		return
+	}
+	var name *sitter.Node
+	switch parent.Type() {
+	case "variable_declarator":
+		name = parent.ChildByFieldName("name")
+	case "assignment_expression":
+		name = parent.ChildByFieldName("left")
+	}
+	if name != nil && (name.Type() == "identifier" || name.Type() == "member_expression") {
+		w.clients[strings.TrimPrefix(w.text(name), "this.")] = true
+	}
+}
+
+func (w *jsWalker) visit(call *sitter.Node) {
+	name := w.callee(call)
+	args := w.args(call)
+	if len(args) == 0 {
+		return
+	}
+	req := JSRequest{Method: "GET", Source: strings.TrimSpace(w.text(call))}
+
+	object, verb := name, ""
+	if dot := strings.LastIndex(name, "."); dot >= 0 {
+		object, verb = name[:dot], name[dot+1:]
+	}
+
+	switch {
+	case call.Type() == "new_expression":
+		if name != "Request" {
+			return
+		}
+		req.RawURL = w.urlValue(args[0])
+		if len(args) > 1 {
+			w.applyOptions(&req, args[1])
+		}
+	case name == "fetch":
+		req.RawURL = w.urlValue(args[0])
+		if len(args) > 1 {
+			w.applyOptions(&req, args[1])
+		}
+	case w.clients[name]:
+		// axios(config) or axios(url, config)
+		if !w.urlOrConfig(&req, args) {
+			return
+		}
+	case w.clients[strings.TrimPrefix(object, "this.")] && axiosVerbs[verb]:
+		if verb == "request" {
+			if !w.urlOrConfig(&req, args) {
+				return
+			}
+			break
+		}
+		req.Method = strings.ToUpper(verb)
+		req.RawURL = w.urlValue(args[0])
+		config := 1
+		if axiosBodyVerbs[verb] {
+			if len(args) > 1 {
+				req.Body = w.bodyValue(args[1])
+			}
+			config = 2
+		}
+		if len(args) > config {
+			w.applyOptions(&req, args[config])
+		}
+	case name == "$.ajax" || name == "jQuery.ajax":
+		if !w.urlOrConfig(&req, args) {
+			return
+		}
+	case name == "$.get" || name == "jQuery.get" || name == "$.getJSON" || name == "jQuery.getJSON":
+		req.RawURL = w.urlValue(args[0])
+		if len(args) > 1 {
+			req.Body = w.bodyValue(args[1])
+		}
+	case name == "$.post" || name == "jQuery.post":
+		req.Method = "POST"
+		req.RawURL = w.urlValue(args[0])
+		if len(args) > 1 {
+			req.Body = w.bodyValue(args[1])
+		}
+	case name == "navigator.sendBeacon":
+		req.Method = "POST"
+		req.RawURL = w.urlValue(args[0])
+		if len(args) > 1 {
+			req.Body = w.bodyValue(args[1])
+		}
+	case verb == "open" && len(args) > 1:
+		method := w.stringValue(args[0])
+		if !isHTTPMethodName(method) {
+			return
+		}
+		req.Method = method
+		req.RawURL = w.urlValue(args[1])
+		if req.RawURL != "" {
+			w.opens = append(w.opens, jsXHROpen{object: object, end: call.EndByte(), index: len(w.requests)})
+		}
+	case verb == "send":
+		w.sends = append(w.sends, jsXHRSend{object: object, start: call.StartByte(), body: w.bodyValue(args[0])})
+		return
+	default:
+		return
+	}
+
+	if req.RawURL == "" {
+		return
+	}
+	w.requests = append(w.requests, req)
+}
+
+// urlOrConfig reads the (config) and (url, config) call forms shared by
+// axios and $.ajax
+func (w *jsWalker) urlOrConfig(req *JSRequest, args []*sitter.Node) bool {
+	if config := w.objectValue(args[0]); config != nil {
+		w.applyOptions(req, config)
+		return req.RawURL != ""
+	}
+	req.RawURL = w.urlValue(args[0])
+	if len(args) > 1 {
+		w.applyOptions(req, args[1])
+	}
+	return req.RawURL != ""
+}
+
+// pairXHRSends gives each xhr.open request the body of the first later
+// send on the same object
+func (w *jsWalker) pairXHRSends() {
+	for _, open := range w.opens {
+		for _, send := range w.sends {
+			if send.object == open.object && send.start >= open.end {
+				w.requests[open.index].Body = send.body
+				break
+			}
+		}
+	}
+}
+
+// applyOptions reads a fetch init, axios config or $.ajax settings object,
+// given literally or as a constant
+func (w *jsWalker) applyOptions(req *JSRequest, node *sitter.Node) {
+	for _, pair := range w.members(w.objectValue(node)) {
+		if pair.Type() != "pair" {
+			continue
+		}
+		value := pair.ChildByFieldName("value")
+		switch strings.ToLower(w.propertyName(pair.ChildByFieldName("key"))) {
+		case "method", "type":
+			if method := w.stringValue(value); method != "" {
+				req.Method = strings.ToUpper(method)
+			}
+		case "url":
+			if req.RawURL == "" {
+				req.RawURL = w.urlValue(value)
+			}
+		case "body", "data":
+			if body := w.bodyValue(value); body != "" {
+				req.Body = body
+			}
+		case "contenttype", "content-type":
+			if ct := w.stringValue(value); ct != "" {
+				req.ContentType = ct
+			}
+		case "headers":
+			for k, v := range w.headerValues(value) {
+				if req.Headers == nil {
+					req.Headers = make(map[string]string)
+				}
+				req.Headers[k] = v
+			}
+		}
+	}
+}
+
+// headerValues reads a headers object literal, or the one passed to
+// new Headers(...)
+func (w *jsWalker) headerValues(node *sitter.Node) map[string]string {
+	if node != nil && node.Type() == "new_expression" && w.callee(node) == "Headers" {
+		if args := w.args(node); len(args) > 0 {
+			node = args[0]
+		}
+	}
+	node = w.objectValue(node)
+	if node == nil {
+		return nil
+	}
+	headers := make(map[string]string)
+	for _, pair := range w.members(node) {
+		if pair.Type() != "pair" {
+			continue
+		}
+		key := w.propertyName(pair.ChildByFieldName("key"))
+		if key == "" {
+			continue
+		}
+		if value := w.joinParts(w.parts(pair.ChildByFieldName("value")), false); value != "" {
+			headers[key] = value
+		}
+	}
+	return headers
+}
+
+func (w *jsWalker) propertyName(key *sitter.Node) string {
+	if key == nil {
+		return ""
+	}
+	switch key.Type() {
+	case "string":
+		return DecodeJSString(w.text(key))
+	case "property_identifier", "identifier", "number":
+		return w.text(key)
+	}
+	return ""
+}
+
+// stringValue is the value of a string literal or a template literal
+// without substitutions
+func (w *jsWalker) stringValue(node *sitter.Node) string {
+	parts := w.parts(node)
+	if len(parts) != 1 || !parts[0].known {
+		return ""
+	}
+	return parts[0].text
+}
+
+// urlValue is the value of a URL argument, with a leading expression such
+// as an API base dropped so the URL resolves against the page
+func (w *jsWalker) urlValue(node *sitter.Node) string {
+	return w.joinParts(w.parts(node), true)
+}
+
+// bodyValue is a request body: a string, the text of an object or array
+// literal, or the object given to JSON.stringify
+func (w *jsWalker) bodyValue(node *sitter.Node) string {
+	if node == nil {
+		return ""
+	}
+	if value := w.resolve(node); value != nil && (value.Type() == "object" || value.Type() == "array") {
+		node = value
+	}
+	switch node.Type() {
+	case "object":
+		return w.objectText(node)
+	case "array":
+		return w.text(node)
+	case "call_expression":
+		if w.callee(node) == "JSON.stringify" {
+			if args := w.args(node); len(args) > 0 {
+				return w.bodyValue(args[0])
+			}
+		}
+		return ""
+	}
+	return w.joinParts(w.parts(node), false)
+}
+
+// parts splits a string-valued expression into literal text and unknown
+// expressions, following template literals, + concatenation and constants
+func (w *jsWalker) parts(node *sitter.Node) []jsPart {
+	if node == nil {
+		return nil
+	}
+	switch node.Type() {
+	case "string":
+		return []jsPart{{text: DecodeJSString(w.text(node)), known: true}}
+	case "template_string":
+		var parts []jsPart
+		pos := node.StartByte() + 1
+		end := node.EndByte() - 1
+		for i := 0; i < int(node.NamedChildCount()); i++ {
+			child := node.NamedChild(i)
+			if child == nil || child.Type() != "template_substitution" {
+				continue
+			}
+			if child.StartByte() > pos {
+				parts = append(parts, w.literal(pos, child.StartByte()))
+			}
+			if child.NamedChildCount() == 1 {
+				parts = append(parts, w.parts(child.NamedChild(0))...)
+			} else {
+				parts = append(parts, jsPart{})
+			}
+			pos = child.EndByte()
+		}
+		if end > pos {
+			parts = append(parts, w.literal(pos, end))
+		}
+		return parts
+	case "binary_expression":
+		if op := node.Child(1); op == nil || op.Type() != "+" {
+			return []jsPart{{}}
+		}
+		return append(w.parts(node.ChildByFieldName("left")), w.parts(node.ChildByFieldName("right"))...)
+	case "parenthesized_expression":
+		if node.NamedChildCount() == 1 {
+			return w.parts(node.NamedChild(0))
+		}
+	case "identifier", "member_expression":
+		if value := w.resolve(node); value != nil && w.depth < maxJSResolveDepth {
+			w.depth++
+			defer func() { w.depth-- }()
+			return w.parts(value)
+		}
+	}
+	return []jsPart{{}}
+}
+
+// resolve returns the value of a constant: an identifier declared once in
+// an enclosing block and never assigned again, or a property of a constant
+// object literal. It returns nil for anything else
+func (w *jsWalker) resolve(node *sitter.Node) *sitter.Node {
+	if node == nil {
+		return nil
+	}
+	switch node.Type() {
+	case "identifier":
+		return w.binding(node)
+	case "member_expression":
+		object := w.objectValue(node.ChildByFieldName("object"))
+		property := node.ChildByFieldName("property")
+		if object == nil || property == nil || property.Type() != "property_identifier" {
+			return nil
+		}
+		var value *sitter.Node
+		for _, pair := range w.members(object) {
+			if pair.Type() == "pair" && w.propertyName(pair.ChildByFieldName("key")) == w.text(property) {
+				value = pair.ChildByFieldName("value")
+			}
+		}
+		return value
+	}
+	return nil
+}
+
+// binding finds the declaration ident refers to by walking out through
+// the enclosing blocks, stopping at a function parameter of the same name
+func (w *jsWalker) binding(ident *sitter.Node) *sitter.Node {
+	name := w.text(ident)
+	if w.assigned[name] {
+		return nil
+	}
+	for n := ident.Parent(); n != nil; n = n.Parent() {
+		switch n.Type() {
+		case "function", "function_declaration", "generator_function", "generator_function_declaration", "method_definition", "arrow_function":
+			for _, field := range []string{"parameters", "parameter"} {
+				if params := n.ChildByFieldName(field); params != nil && w.declares(params, name) {
+					return nil
+				}
+			}
+		case "statement_block", "program":
+			var value *sitter.Node
+			declared := 0
+			for i := 0; i < int(n.NamedChildCount()); i++ {
+				decl := n.NamedChild(i)
+				if decl == nil || (decl.Type() != "lexical_declaration" && decl.Type() != "variable_declaration") {
+					continue
+				}
+				for j := 0; j < int(decl.NamedChildCount()); j++ {
+					declarator := decl.NamedChild(j)
+					if declarator != nil && declarator.Type() == "variable_declarator" && w.text(declarator.ChildByFieldName("name")) == name {
+						value = declarator.ChildByFieldName("value")
+						declared++
+					}
+				}
+			}
+			if declared > 1 {
+				return nil
+			}
+			if declared == 1 {
+				return value
+			}
+		}
+	}
+	return nil
+}
+
+// declares reports whether a parameter list binds name, including through
+// defaults and destructuring
+func (w *jsWalker) declares(params *sitter.Node, name string) bool {
+	switch params.Type() {
+	case "identifier", "shorthand_property_identifier_pattern":
+		return w.text(params) == name
+	}
+	for i := 0; i < int(params.NamedChildCount()); i++ {
+		if child := params.NamedChild(i); child != nil && w.declares(child, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// objectValue is node when it is an object literal, or the object literal
+// a constant holds
+func (w *jsWalker) objectValue(node *sitter.Node) *sitter.Node {
+	for depth := 0; node != nil && depth < maxJSResolveDepth; depth++ {
+		if node.Type() == "object" {
+			return node
+		}
+		if node.Type() == "parenthesized_expression" && node.NamedChildCount() == 1 {
+			node = node.NamedChild(0)
+			continue
+		}
+		node = w.resolve(node)
+	}
+	return nil
+}
+
+// members lists the properties of an object literal, replacing each
+// ...spread of a constant object by that object's properties. Spreads of
+// unknown values are kept as they are
+func (w *jsWalker) members(object *sitter.Node) []*sitter.Node {
+	if object == nil || w.depth >= maxJSResolveDepth {
+		return nil
+	}
+	var members []*sitter.Node
+	for i := 0; i < int(object.NamedChildCount()); i++ {
+		member := object.NamedChild(i)
+		if member == nil || member.Type() == "comment" {
+			continue
+		}
+		if member.Type() == "spread_element" && member.NamedChildCount() == 1 {
+			if spread := w.objectValue(member.NamedChild(0)); spread != nil {
+				w.depth++
+				members = append(members, w.members(spread)...)
+				w.depth--
+				continue
+			}
+		}
+		members = append(members, member)
+	}
+	return members
+}
+
+// objectText is the source of an object literal with its spreads of
+// constant objects expanded
+func (w *jsWalker) objectText(object *sitter.Node) string {
+	if !strings.Contains(w.text(object), "...") {
+		return w.text(object)
+	}
+	var texts []string
+	for _, member := range w.members(object) {
+		texts = append(texts, w.text(member))
+	}
+	return "{" + strings.Join(texts, ",") + "}"
+}
+
+func (w *jsWalker) literal(start, end uint32) jsPart {
+	return jsPart{text: DecodeJSString("`" + string(w.src[start:end]) + "`"), known: true}
+}
+
+// joinParts joins parts with unknown expressions as the placeholder value.
+// A value with no literal text is empty; relative drops leading
+// expressions
+func (w *jsWalker) joinParts(parts []jsPart, relative bool) string {
+	if relative {
+		for len(parts) > 0 && !parts[0].known {
+			parts = parts[1:]
+		}
+	}
+	var b strings.Builder
+	known := false
+	for _, part := range parts {
+		if !part.known {
+			b.WriteString(collectionPlaceholder)
+			continue
+		}
+		known = known || part.text != ""
+		b.WriteString(part.text)
+	}
+	if !known {
+		return ""
+	}
+	return b.String()
+}
+
+func isHTTPMethodName(method string) bool {
+	switch strings.ToUpper(method) {
+	case "GET", "POST", "PUT", "DELETE", "PATCH", "HEAD", "OPTIONS":
+		return true
+	}
+	return false
+}
+
+// extractJSRequests runs the --js-ast extractor when enabled, falling back
+// to the regex extractor over decoded when source does not parse
+func (crawler *Crawler) extractJSRequests(source, decoded string, base *url.URL) ([]JSRequest, error) {
+	if crawler.jsAST {
+		reqs, err := ExtractJSRequestsAST(source, base)
+		if err == nil {
+			return reqs, nil
+		}
+		Logger.Debugf("JS AST extraction failed, using regex: %s", err)
+	}
+	return ExtractJSRequests(decoded, base)
+}
@@ -0,0 +1,143 @@
+package core
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jsASTBundle is shaped like minified webpack output: API bases in
+// variables, template literal paths, axios instances and XHR
+const jsASTBundle = `!function(){"use strict";var n="https://api.example.com",e=axios.create({baseURL:n});` +
+	`function t(t){return fetch(` + "`${n}/v2/users/${t}/profile`" + `,{method:"PATCH",headers:{"Content-Type":"application/json","X-Req":"1"},body:JSON.stringify({name:t})})}` +
+	`function r(t){return e.post("/v2/orders",{sku:t},{headers:{Authorization:"Bearer x"}})}` +
+	`function o(t){var r=new XMLHttpRequest;r.open("PUT","/v2/cart/"+t),r.setRequestHeader("Content-Type","text/plain"),r.send("qty=1")}` +
+	`function i(){return axios({url:"/v2/search",method:"post",data:{q:"a"}})}` +
+	`function c(){return $.post("/legacy/save.php",{a:1})}` +
+	`function u(){return window.fetch("/v2/health")}` +
+	`function s(t){return e.get(n+"/v2/items?page="+t)}` +
+	`function a(){navigator.sendBeacon("/v2/beacon",JSON.stringify({e:1}))}` +
+	`t("a"),r("b"),o(1),i(),c(),u(),s(2),a()}();`
+
+func jsASTIndex(reqs []JSRequest) map[string]JSRequest {
+	out := make(map[string]JSRequest, len(reqs))
+	for _, req := range reqs {
+		out[req.Method+" "+req.RawURL] = req
+	}
+	return out
+}
+
+func TestExtractJSRequestsAST(t *testing.T) {
+	reqs, err := ExtractJSRequestsAST(jsASTBundle, nil)
+	require.NoError(t, err)
+	got := jsASTIndex(reqs)
+
+	profile, ok := got["PATCH https://api.example.com/v2/users/1/profile"]
+	require.True(t, ok, "template literal fetch: %v", reqs)
+	assert.Equal(t, "application/json", profile.ContentType)
+	assert.Equal(t, "1", profile.Headers["X-Req"])
+	assert.Equal(t, "{name:t}", profile.Body)
+
+	order, ok := got["POST /v2/orders"]
+	require.True(t, ok, "axios.create instance: %v", reqs)
+	assert.Equal(t, "{sku:t}", order.Body)
+	assert.Equal(t, "Bearer x", order.Headers["Authorization"])
+
+	cart, ok := got["PUT /v2/cart/1"]
+	require.True(t, ok, "XHR with concatenated URL: %v", reqs)
+	assert.Equal(t, "qty=1", cart.Body)
+
+	search, ok := got["POST /v2/search"]
+	require.True(t, ok, "axios config object: %v", reqs)
+	assert.Equal(t, `{q:"a"}`, search.Body)
+
+	assert.Contains(t, got, "POST /legacy/save.php")
+	assert.Contains(t, got, "GET /v2/health")
+	assert.Contains(t, got, "GET https://api.example.com/v2/items?page=1", "constant API base")
+	assert.Contains(t, got, "POST /v2/beacon")
+	assert.Len(t, reqs, 8)
+}
+
+func TestExtractJSRequestsASTConstants(t *testing.T) {
+	reqs, err := ExtractJSRequestsAST(`const API="/api/v3",ROUTES={users:API+"/users"},json={"Content-Type":"application/json"};`+
+		`const base={method:"POST",headers:{...json,"X-App":"web"}},payload={role:"admin"};`+
+		"function a(API){return fetch(`${API}/shadowed`)}"+
+		`function b(id){return fetch(ROUTES.users+"/"+id,{...base,body:JSON.stringify({...payload,id:id})})}`+
+		`let host="https://old.example.com";host="https://new.example.com";fetch(host+"/reassigned");`+
+		`const opts={method:"DELETE"};fetch(API+"/session",opts);`, nil)
+	require.NoError(t, err)
+	got := jsASTIndex(reqs)
+
+	users, ok := got["POST /api/v3/users/1"]
+	require.True(t, ok, "constant object property and spread options: %v", reqs)
+	assert.Equal(t, map[string]string{"Content-Type": "application/json", "X-App": "web"}, users.Headers)
+	assert.Equal(t, `{role:"admin",id:id}`, users.Body)
+
+	assert.Contains(t, got, "GET /shadowed", "a parameter shadows the constant")
+	assert.Contains(t, got, "GET /reassigned", "a reassigned variable is not a constant")
+	assert.Contains(t, got, "DELETE /api/v3/session", "options passed as a constant")
+	assert.Len(t, reqs, 4)
+}
+
+func TestExtractJSRequestsASTSkipsUnknownURLs(t *testing.T) {
+	reqs, err := ExtractJSRequestsAST(`fetch(url); axios.get(cfg.endpoint); x.open(m, "/a"); foo.get("/not-axios")`, nil)
+	require.NoError(t, err)
+	assert.Empty(t, reqs)
+}
+
+func TestExtractJSRequestsASTParseError(t *testing.T) {
+	_, err := ExtractJSRequestsAST(`fetch("/a", {method: "POST"`, nil)
+	assert.ErrorIs(t, err, errJSParse)
+}
+
+func TestCrawlerExtractJSRequestsFallsBack(t *testing.T) {
+	base, _ := url.Parse("https://example.com/")
+	broken := `fetch("/api/a", {method: "POST"}); if (`
+
+	crawler := &Crawler{jsAST: true}
+	reqs, err := crawler.extractJSRequests(broken, broken, base)
+	require.NoError(t, err)
+	require.Len(t, reqs, 1)
+	assert.Equal(t, "POST", reqs[0].Method)
+	assert.Equal(t, "/api/a", reqs[0].RawURL)
+}
+
+// TestExtractJSRequestsASTAccuracy checks the parser finds every request
+// the regex extractor finds in the bundle, where the regex sees only the
+// first literal of a concatenated URL, plus the ones it cannot see
+func TestExtractJSRequestsASTAccuracy(t *testing.T) {
+	regex, err := ExtractJSRequests(jsASTBundle, nil)
+	require.NoError(t, err)
+	ast, err := ExtractJSRequestsAST(jsASTBundle, nil)
+	require.NoError(t, err)
+
+	for key := range jsASTIndex(regex) {
+		if strings.Contains(key, "${") {
+			// The regex extractor keeps template substitutions verbatim
+			continue
+		}
+		found := false
+		for astKey := range jsASTIndex(ast) {
+			found = found || strings.HasPrefix(astKey, key)
+		}
+		assert.True(t, found, "%s missed by the parser", key)
+	}
+	assert.Greater(t, len(ast), len(regex))
+}
+
+func BenchmarkExtractJSRequests(b *testing.B) {
+	bundle := strings.Repeat(jsASTBundle, 50)
+	for i := 0; i < b.N; i++ {
+		_, _ = ExtractJSRequests(bundle, nil)
+	}
+}
+
+func BenchmarkExtractJSRequestsAST(b *testing.B) {
+	bundle := strings.Repeat(jsASTBundle, 50)
+	for i := 0; i < b.N; i++ {
+		_, _ = ExtractJSRequestsAST(bundle, nil)
+	}
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCrawlJSConcurrencyIsIndependent(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".js") {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(100 * time.Millisecond)
+			w.Header().Set("Content-Type", "application/javascript")
+			fmt.Fprint(w, `var x = 1;`)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		var scripts strings.Builder
+		for i := 0; i < 4; i++ {
+			fmt.Fprintf(&scripts, `<script src="/static/app%d.js"></script>`, i)
+		}
+		fmt.Fprintf(w, `<html><body>%s</body></html>`, scripts.String())
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	results, err := Crawl(ctx, srv.URL, CrawlerConfig{MaxDepth: 1, MaxConcurrency: 5, JSConcurrency: 1, Timeout: 5 * time.Second})
+	require.NoError(t, err)
+
+	scripts := 0
+	for sout := range results {
+		if sout.OutputType == "javascript" {
+			scripts++
+		}
+	}
+	assert.Equal(t, 4, scripts)
+	assert.Equal(t, int32(1), peak.Load(), "JS fetches are throttled by their own limit rule")
+}
@@ -8,7 +8,6 @@ import (
 	"strings"
 	"time"
 
-	jsoniter "github.com/json-iterator/go"
 	"github.com/projectdiscovery/goflags"
 	"github.com/projectdiscovery/katana/pkg/engine/standard"
 	katanaOutput "github.com/projectdiscovery/katana/pkg/output"
@@ -76,10 +75,15 @@ func (crawler *Crawler) DeepCrawlWithKatana(cfg CrawlerConfig) error {
 		options.TechDetect = true
 	}
 
+	// Katana pools its own connections, outside --max-conns-per-host
+	if limit := openFileLimit(); limit > 0 && options.Concurrency > limit/2 {
+		Logger.Warnf("Katana concurrency %d may exhaust the open file limit (%d); lower -c or raise ulimit -n", options.Concurrency, limit)
+	}
+
 	if cfg.Proxy != "" {
 		options.Proxy = cfg.Proxy
 	}
-	if cfg.NoRedirect {
+	if cfg.NoRedirect || cfg.redirectLimit() == 0 {
 		options.DisableRedirects = true
 	}
 
@@ -112,6 +116,7 @@ func (crawler *Crawler) DeepCrawlWithKatana(cfg CrawlerConfig) error {
 	if len(scopeSlice) > 0 {
 		options.Scope = scopeSlice
 	}
+	outScopeSlice = append(outScopeSlice, crawler.robotsDisallow...)
 	if len(outScopeSlice) > 0 {
 		options.OutOfScope = outScopeSlice
 	}
@@ -193,6 +198,8 @@ func buildScopeRules(cfg CrawlerConfig, site *url.URL) (goflags.StringSlice, gof
 	hostPattern := regexp.QuoteMeta(site.Hostname())
 	if cfg.Whitelist != "" {
 		scopeSlice = append(scopeSlice, cfg.Whitelist)
+	} else if IsIPHost(site) {
+		scopeSlice = append(scopeSlice, ScopePattern(site, false))
 	} else {
 		if cfg.Subs {
 			scopeSlice = append(scopeSlice, fmt.Sprintf("(?i)%s", hostPattern))
@@ -279,6 +286,13 @@ func (crawler *Crawler) handleKatanaResult(res katanaOutput.Result) {
 	if crawler.Stats != nil {
 		crawler.Stats.IncrementURLsFound()
 	}
+	if crawler.postmanExport != nil {
+		req := JSRequest{Method: method, RawURL: target, Body: body, Source: "katana"}
+		if res.Request != nil {
+			req.Headers = res.Request.Headers
+		}
+		crawler.postmanExport.Add(crawler.site.String(), req)
+	}
 
 	method = strings.ToUpper(strings.TrimSpace(method))
 	if method == "" {
@@ -303,21 +317,37 @@ func (crawler *Crawler) handleKatanaResult(res katanaOutput.Result) {
 	if method == http.MethodPost && status > 0 {
 		Logger.Infof("[post-hit] %s %s (%d)", method, target, status)
 	}
-	line := crawler.renderKatanaLine(res, target, method, status, length)
-	if line == "" {
-		return
+	text, plain := crawler.renderKatanaLine(res, target, method, status, length)
+	crawler.emit(crawler.katanaSpiderOutput(res, target, method, status, length), text, plain)
+}
+
+func (crawler *Crawler) katanaSpiderOutput(res katanaOutput.Result, target, method string, status, length int) SpiderOutput {
+	source := "katana"
+	if res.Request != nil && res.Request.Source != "" {
+		source = res.Request.Source
 	}
-	if !crawler.Quiet || crawler.JsonOutput {
-		fmt.Println(line)
-	} else if crawler.Quiet {
-		fmt.Println(line)
+	outputType := "katana"
+	if methodTag := strings.ToUpper(strings.TrimSpace(method)); methodTag != "" && methodTag != http.MethodGet {
+		outputType = "katana-" + strings.ToLower(methodTag)
+	}
+	// Katana counts its seed as depth 0 where colly starts at 1
+	depth := 0
+	if res.Request != nil {
+		depth = res.Request.Depth + 1
 	}
-	if crawler.Output != nil {
-		crawler.Output.WriteToFile(line)
+	return SpiderOutput{
+		Input:      crawler.Input,
+		Source:     source,
+		OutputType: outputType,
+		Output:     target,
+		StatusCode: status,
+		Length:     length,
+		Depth:      depth,
 	}
 }
 
-func (crawler *Crawler) renderKatanaLine(res katanaOutput.Result, target, method string, status, length int) string {
+// renderKatanaLine returns the text and plain renderings of a Katana result
+func (crawler *Crawler) renderKatanaLine(res katanaOutput.Result, target, method string, status, length int) (string, string) {
 	source := "katana"
 	if res.Request != nil && res.Request.Source != "" {
 		source = res.Request.Source
@@ -326,28 +356,9 @@ func (crawler *Crawler) renderKatanaLine(res katanaOutput.Result, target, method
 	if methodTag == "" {
 		methodTag = http.MethodGet
 	}
-	outputType := "katana"
+	plain := target
 	if methodTag != http.MethodGet {
-		outputType = "katana-" + strings.ToLower(methodTag)
-	}
-	if crawler.JsonOutput {
-		sout := SpiderOutput{
-			Input:      crawler.Input,
-			Source:     source,
-			OutputType: outputType,
-			Output:     target,
-			StatusCode: status,
-			Length:     length,
-		}
-		if data, err := jsoniter.MarshalToString(sout); err == nil {
-			return data
-		}
-	}
-	if crawler.Quiet {
-		if methodTag != http.MethodGet {
-			return fmt.Sprintf("%s %s", methodTag, target)
-		}
-		return target
+		plain = fmt.Sprintf("%s %s", methodTag, target)
 	}
 	builder := strings.Builder{}
 	builder.WriteString("[katana]")
@@ -365,5 +376,5 @@ func (crawler *Crawler) renderKatanaLine(res katanaOutput.Result, target, method
 	if source != "" {
 		builder.WriteString(fmt.Sprintf(" <- %s", source))
 	}
-	return builder.String()
+	return builder.String(), plain
 }
\ No newline at end of file
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+const defaultHeartbeatInterval = time.Minute
+
+// sessionKeepalive periodically requests a heartbeat URL with the crawl's
+// credentials and checks the session is still logged in
+type sessionKeepalive struct {
+	url       string
+	interval  time.Duration
+	loggedOut *regexp.Regexp
+	headers   http.Header
+	client    *http.Client
+	expired   bool
+}
+
+// newSessionKeepalive returns nil when no heartbeat URL is configured. The
+// URL may be relative to site
+func newSessionKeepalive(site *url.URL, cfg CrawlerConfig, headers http.Header, base *http.Client) *sessionKeepalive {
+	if cfg.SessionHeartbeat == "" {
+		return nil
+	}
+	target, ok := NormalizeURL(site, cfg.SessionHeartbeat)
+	if !ok {
+		Logger.Warnf("Invalid --session-heartbeat %q; session keepalive disabled", cfg.SessionHeartbeat)
+		return nil
+	}
+	var loggedOut *regexp.Regexp
+	if cfg.SessionLoggedOut != "" {
+		re, err := regexp.Compile(cfg.SessionLoggedOut)
+		if err != nil {
+			Logger.Warnf("Invalid --session-logged-out %q: %s; checking the status only", cfg.SessionLoggedOut, err)
+		} else {
+			loggedOut = re
+		}
+	}
+	interval := cfg.SessionHeartbeatInterval
+	if interval <= 0 {
+		interval = defaultHeartbeatInterval
+	}
+	return &sessionKeepalive{
+		url:       target,
+		interval:  interval,
+		loggedOut: loggedOut,
+		headers:   headers,
+		// A redirect is how most apps bounce an expired session to the login page
+		client: &http.Client{
+			Transport:     base.Transport,
+			Timeout:       base.Timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+}
+
+// check requests the heartbeat URL once. reason is empty while the session
+// looks logged in
+func (k *sessionKeepalive) check() (status int, reason string, err error) {
+	req, err := http.NewRequest(http.MethodGet, k.url, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header = k.headers.Clone()
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 300 && resp.StatusCode < 400:
+		return resp.StatusCode, "redirected to " + resp.Header.Get("Location"), nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return resp.StatusCode, http.StatusText(resp.StatusCode), nil
+	case resp.StatusCode >= 400:
+		// Server trouble says nothing about the session
+		return resp.StatusCode, "", nil
+	}
+	if k.loggedOut != nil {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if match := k.loggedOut.Find(body); match != nil {
+			return resp.StatusCode, fmt.Sprintf("logged-out marker %q found", match), nil
+		}
+	}
+	return resp.StatusCode, "", nil
+}
+
+// startSessionKeepalive checks the session right away and then on every
+// interval until the returned stop function is called or the crawl ends
+func (crawler *Crawler) startSessionKeepalive() (stop func()) {
+	k := crawler.keepalive
+	if k == nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(k.interval)
+		defer ticker.Stop()
+		for {
+			crawler.heartbeat(k)
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			case <-crawler.stopChan:
+				return
+			case <-crawler.ctxDone():
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+func (crawler *Crawler) heartbeat(k *sessionKeepalive) {
+	status, reason, err := k.check()
+	if err != nil {
+		Logger.Debugf("session heartbeat %s failed: %s", k.url, err)
+		return
+	}
+	if reason == "" {
+		if k.expired {
+			Logger.Infof("[session] %s answers logged in again", k.url)
+			k.expired = false
+		}
+		return
+	}
+	if k.expired {
+		return
+	}
+	k.expired = true
+	Logger.Warnf("[session-expired] %s: %s. Results from here on are probably unauthenticated", k.url, reason)
+	sout := SpiderOutput{
+		Input:      crawler.Input,
+		Source:     "heartbeat",
+		OutputType: "session-expired",
+		StatusCode: status,
+		Output:     k.url,
+		Snippet:    reason,
+	}
+	crawler.emit(sout, fmt.Sprintf("[session-expired] - [code-%d] - %s - %s", status, k.url, reason), k.url)
+}
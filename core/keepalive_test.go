@@ -0,0 +1,64 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionKeepalive(t *testing.T) {
+	var mode atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Cookie") != "sid=abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch mode.Load() {
+		case 0:
+			fmt.Fprint(w, `{"user":"alice"}`)
+		case 1:
+			http.Redirect(w, r, "/login", http.StatusFound)
+		default:
+			fmt.Fprint(w, `<form><input type="password" name="password"></form>`)
+		}
+	}))
+	defer srv.Close()
+
+	site, _ := url.Parse(srv.URL)
+	headers := http.Header{"Cookie": []string{"sid=abc"}}
+	cfg := CrawlerConfig{SessionHeartbeat: "/api/me", SessionLoggedOut: `name="password"`}
+	k := newSessionKeepalive(site, cfg, headers, &http.Client{Timeout: 5 * time.Second})
+	require.NotNil(t, k)
+	assert.Equal(t, srv.URL+"/api/me", k.url)
+	assert.Equal(t, defaultHeartbeatInterval, k.interval)
+
+	sink := &recordingSink{}
+	crawler := &Crawler{Input: srv.URL, sink: sink, stopChan: make(chan struct{})}
+
+	crawler.heartbeat(k)
+	assert.Empty(t, sink.results, "a logged-in answer emits nothing")
+
+	mode.Store(1)
+	crawler.heartbeat(k)
+	crawler.heartbeat(k)
+	require.Len(t, sink.results, 1, "an expiry is reported once")
+	assert.Equal(t, "session-expired", sink.results[0].OutputType)
+	assert.Equal(t, http.StatusFound, sink.results[0].StatusCode)
+	assert.Contains(t, sink.results[0].Snippet, "/login")
+
+	mode.Store(0)
+	crawler.heartbeat(k)
+	mode.Store(2)
+	crawler.heartbeat(k)
+	require.Len(t, sink.results, 2, "a session that came back can expire again")
+	assert.Contains(t, sink.results[1].Snippet, "logged-out marker")
+
+	assert.Nil(t, newSessionKeepalive(site, CrawlerConfig{}, headers, http.DefaultClient))
+}
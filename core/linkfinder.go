@@ -6,9 +6,35 @@ import (
 	"strings"
 )
 
-var linkFinderRegex = regexp.MustCompile(`(?:"|')(((?:[a-zA-Z]{1,10}://|//)[^"'/]{1,}\.[a-zA-Z]{2,}[^"']{0,})|((?:/|\.\./|\./)[^"'><,;| *()(%%$^/\\\[\]][^"'><,;|()]{1,})|([a-zA-Z0-9_\-/]{1,}/[a-zA-Z0-9_\-/]{1,}\.(?:[a-zA-Z]{1,4}|action)(?:[\?|#][^"|']{0,}|))|([a-zA-Z0-9_\-/]{1,}/[a-zA-Z0-9_\-/]{3,}(?:[\?|#][^"|']{0,}|))|([a-zA-Z0-9_\-]{1,}\.(?:php|asp|aspx|jsp|json|action|html|js|txt|xml)(?:[\?|#][^"|']{0,}|)))(?:"|')`)
+var linkFinderRegex = regexp.MustCompile(`(?:"|')(((?:[a-zA-Z]{1,10}://|//)[^"'/]{1,}\.[a-zA-Z]{2,}[^"']{0,})|((?:/|\.\./|\./)[^"'><,;| *()(%%$^/\\\[\]][^"'><,;|()]{1,})|([a-zA-Z0-9_\-/]{1,}/[a-zA-Z0-9_\-/]{1,}\.(?:[a-zA-Z]{1,4}|action)(?:[\?|#][^"|']{0,}|))|([a-zA-Z0-9_\-/]{1,}/[a-zA-Z0-9_\-/]{3,}(?:[\?|#][^"|']{0,}|))|([a-zA-Z0-9_\-]{1,}\.(?:php|asp|aspx|jsp|json|action|html|js|txt|xml|wasm)(?:[\?|#][^"|']{0,}|)))(?:"|')`)
 
 func LinkFinder(source string, base *url.URL) ([]string, []JSRequest, error) {
+	links, source := linkFinderPaths(source)
+	reqs, err := ExtractJSRequests(source, base)
+	if err != nil {
+		return links, nil, err
+	}
+	return links, reqs, nil
+}
+
+// linkFinder is LinkFinder with the regex pass and the JS request
+// extraction timed separately for --profile-extractors
+func (crawler *Crawler) linkFinder(source string, base *url.URL) ([]string, []JSRequest, error) {
+	stop := crawler.profile.start(extractorLinkFinder)
+	links, decoded := linkFinderPaths(source)
+	stop()
+	stop = crawler.profile.start(extractorJSRequests)
+	reqs, err := crawler.extractJSRequests(source, decoded, base)
+	stop()
+	if err != nil {
+		return links, nil, err
+	}
+	return links, reqs, nil
+}
+
+// linkFinderPaths returns the unique paths matched in source, along with the
+// decoded source the JS request extraction runs over
+func linkFinderPaths(source string) ([]string, string) {
 	var links []string
 	// source = strings.ToLower(source)
 	if len(source) > 1000000 {
@@ -25,10 +51,5 @@ func LinkFinder(source string, base *url.URL) ([]string, []JSRequest, error) {
 		}
 		links = append(links, matchGroup1)
 	}
-	links = Unique(links)
-	reqs, err := ExtractJSRequests(source, base)
-	if err != nil {
-		return links, nil, err
-	}
-	return links, reqs, nil
+	return Unique(links), source
 }
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// ServeMetrics starts an HTTP server on addr exposing net/http/pprof under
+// /debug/pprof/ and the crawl statistics in Prometheus text format under
+// /metrics. The server stops with the engine's context or when the returned
+// function is called
+func (e *Engine) ServeMetrics(addr string) (stop func(), err error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("metrics server: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writeMetrics(w, e.stats, time.Since(e.startTime))
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			Logger.Errorf("Metrics server stopped: %s", err)
+		}
+	}()
+	Logger.Infof("Serving metrics on http://%s/metrics and pprof on /debug/pprof/", listener.Addr())
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-e.ctx.Done():
+		case <-done:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}()
+	return func() { close(done) }, nil
+}
+
+func writeMetrics(w io.Writer, stats *CrawlStats, elapsed time.Duration) {
+	metric := func(name, kind, help string, value interface{}) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+	}
+	metric("gospider_requests_total", "counter", "Requests sent.", stats.GetRequestsMade())
+	metric("gospider_urls_found_total", "counter", "URLs discovered.", stats.GetURLsFound())
+	metric("gospider_errors_total", "counter", "Failed requests and extraction errors.", stats.GetErrors())
+	metric("gospider_requests_per_second", "gauge", "Average request rate since the start.", fmt.Sprintf("%.2f", stats.GetRPS(elapsed)))
+	metric("gospider_request_queue_depth", "gauge", "Generated requests holding a --request-queue-size slot.", stats.GetQueued())
+	metric("gospider_visit_queue_depth", "gauge", "Discovered pages waiting in the --prioritize/--max-queue-size visit queue.", stats.GetPendingVisits())
+	metric("gospider_active_sites", "gauge", "Targets being crawled.", stats.GetActiveSites())
+	metric("gospider_goroutines", "gauge", "Goroutines in the process.", runtime.NumGoroutine())
+	metric("gospider_uptime_seconds", "gauge", "Seconds since the crawl started.", fmt.Sprintf("%.0f", elapsed.Seconds()))
+
+	counts := stats.GetStatusCounts()
+	codes := make([]int, 0, len(counts))
+	for code := range counts {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	fmt.Fprintf(w, "# HELP gospider_responses_total Responses by status code.\n# TYPE gospider_responses_total counter\n")
+	for _, code := range codes {
+		fmt.Fprintf(w, "gospider_responses_total{code=\"%d\"} %d\n", code, counts[code])
+	}
+}
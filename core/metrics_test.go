@@ -0,0 +1,60 @@
+package core
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeMetrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	e := &Engine{ctx: ctx, cancel: cancel, stats: NewCrawlStats(), startTime: time.Now()}
+	e.stats.IncrementRequestsMade()
+	e.stats.IncrementRequestsMade()
+	e.stats.RecordStatus(200)
+	e.stats.RecordStatus(404)
+	e.stats.RecordStatus(200)
+	e.stats.AddQueued(3)
+
+	// Grab a free port, then hand it to the server
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	stop, err := e.ServeMetrics(addr)
+	require.NoError(t, err)
+
+	resp, err := http.Get("http://" + addr + "/metrics")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	metrics := string(body)
+	assert.Contains(t, metrics, "gospider_requests_total 2\n")
+	assert.Contains(t, metrics, "gospider_request_queue_depth 3\n")
+	assert.Contains(t, metrics, `gospider_responses_total{code="200"} 2`)
+	assert.Contains(t, metrics, `gospider_responses_total{code="404"} 1`)
+	assert.True(t, strings.Index(metrics, `code="200"`) < strings.Index(metrics, `code="404"`))
+
+	resp, err = http.Get("http://" + addr + "/debug/pprof/goroutine?debug=1")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, err = e.ServeMetrics(addr)
+	assert.Error(t, err, "the address is taken")
+
+	stop()
+	assert.Eventually(t, func() bool {
+		_, err := http.Get("http://" + addr + "/metrics")
+		return err != nil
+	}, 2*time.Second, 20*time.Millisecond)
+}
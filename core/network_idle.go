@@ -0,0 +1,87 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// Hybrid navigation wait strategies
+const (
+	HybridWaitLoad        = "load"
+	HybridWaitNetworkIdle = "network-idle"
+)
+
+const (
+	defaultHybridIdleWindow = 500 * time.Millisecond
+	networkIdlePoll         = 50 * time.Millisecond
+)
+
+// networkIdleTracker follows a page's requests from the DevTools network
+// events. Streams that never finish do not count as activity
+type networkIdleTracker struct {
+	mu           sync.Mutex
+	inFlight     map[proto.NetworkRequestID]struct{}
+	lastActivity time.Time
+}
+
+func newNetworkIdleTracker() *networkIdleTracker {
+	return &networkIdleTracker{inFlight: make(map[proto.NetworkRequestID]struct{}), lastActivity: time.Now()}
+}
+
+func (t *networkIdleTracker) started(e *proto.NetworkRequestWillBeSent) {
+	switch e.Type {
+	case proto.NetworkResourceTypeWebSocket, proto.NetworkResourceTypeEventSource, proto.NetworkResourceTypeMedia:
+		return
+	}
+	t.mu.Lock()
+	t.inFlight[e.RequestID] = struct{}{}
+	t.lastActivity = time.Now()
+	t.mu.Unlock()
+}
+
+func (t *networkIdleTracker) finished(id proto.NetworkRequestID) {
+	t.mu.Lock()
+	if _, ok := t.inFlight[id]; ok {
+		delete(t.inFlight, id)
+		t.lastActivity = time.Now()
+	}
+	t.mu.Unlock()
+}
+
+// idleFor returns how long no request has been in flight, 0 while one is
+func (t *networkIdleTracker) idleFor() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.inFlight) > 0 {
+		return 0
+	}
+	return time.Since(t.lastActivity)
+}
+
+// wait blocks until no request has been in flight for window, deadline
+// passes or ctx ends. It reports whether the page went idle
+func (t *networkIdleTracker) wait(ctx context.Context, window time.Duration, deadline time.Time) bool {
+	for {
+		idle := t.idleFor()
+		if idle >= window {
+			return true
+		}
+		sleep := window - idle
+		if sleep > networkIdlePoll {
+			sleep = networkIdlePoll
+		}
+		if remaining := time.Until(deadline); remaining <= 0 {
+			return false
+		} else if sleep > remaining {
+			sleep = remaining
+		}
+		select {
+		case <-time.After(sleep):
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
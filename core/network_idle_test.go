@@ -0,0 +1,48 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetworkIdleTrackerWaitsForRequests(t *testing.T) {
+	tracker := newNetworkIdleTracker()
+	tracker.started(&proto.NetworkRequestWillBeSent{RequestID: "1", Type: proto.NetworkResourceTypeFetch})
+	tracker.started(&proto.NetworkRequestWillBeSent{RequestID: "2", Type: proto.NetworkResourceTypeXHR})
+	// Streams stay open for the life of the page
+	tracker.started(&proto.NetworkRequestWillBeSent{RequestID: "3", Type: proto.NetworkResourceTypeEventSource})
+	assert.Zero(t, tracker.idleFor())
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		tracker.finished("1")
+		time.Sleep(50 * time.Millisecond)
+		tracker.finished("2")
+	}()
+	start := time.Now()
+	assert.True(t, tracker.wait(context.Background(), 100*time.Millisecond, time.Now().Add(5*time.Second)))
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+}
+
+func TestNetworkIdleTrackerGivesUp(t *testing.T) {
+	tracker := newNetworkIdleTracker()
+	tracker.started(&proto.NetworkRequestWillBeSent{RequestID: "poll", Type: proto.NetworkResourceTypeFetch})
+
+	start := time.Now()
+	assert.False(t, tracker.wait(context.Background(), 50*time.Millisecond, time.Now().Add(150*time.Millisecond)))
+	assert.Less(t, time.Since(start), time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(t, tracker.wait(ctx, 50*time.Millisecond, time.Now().Add(time.Minute)))
+
+	// Unknown request IDs do not reset the quiet period
+	tracker.finished("poll")
+	time.Sleep(60 * time.Millisecond)
+	tracker.finished("other")
+	assert.GreaterOrEqual(t, tracker.idleFor(), 50*time.Millisecond)
+}
@@ -0,0 +1,148 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// OIDCConfig holds the parts of an OpenID Connect discovery document or
+// OAuth authorization server metadata (RFC 8414) we report
+type OIDCConfig struct {
+	Issuer                             string   `json:"issuer"`
+	AuthorizationEndpoint              string   `json:"authorization_endpoint"`
+	TokenEndpoint                      string   `json:"token_endpoint"`
+	JWKSURI                            string   `json:"jwks_uri"`
+	UserinfoEndpoint                   string   `json:"userinfo_endpoint"`
+	EndSessionEndpoint                 string   `json:"end_session_endpoint"`
+	RegistrationEndpoint               string   `json:"registration_endpoint"`
+	IntrospectionEndpoint              string   `json:"introspection_endpoint"`
+	RevocationEndpoint                 string   `json:"revocation_endpoint"`
+	DeviceAuthorizationEndpoint        string   `json:"device_authorization_endpoint"`
+	PushedAuthorizationRequestEndpoint string   `json:"pushed_authorization_request_endpoint"`
+	ScopesSupported                    []string `json:"scopes_supported"`
+}
+
+// OIDCEndpoint is a named endpoint taken from an OIDCConfig
+type OIDCEndpoint struct {
+	Name string
+	URL  string
+}
+
+// ParseOIDCConfig decodes an openid-configuration or
+// oauth-authorization-server document
+func ParseOIDCConfig(body []byte) (*OIDCConfig, error) {
+	var cfg OIDCConfig
+	if err := jsoniter.Unmarshal(body, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Issuer == "" && cfg.AuthorizationEndpoint == "" && cfg.TokenEndpoint == "" {
+		return nil, fmt.Errorf("not an openid configuration or oauth authorization server metadata")
+	}
+	return &cfg, nil
+}
+
+// Endpoints returns the configured endpoints in a stable order, skipping empty ones
+func (cfg *OIDCConfig) Endpoints() []OIDCEndpoint {
+	var endpoints []OIDCEndpoint
+	for _, e := range []OIDCEndpoint{
+		{"issuer", cfg.Issuer},
+		{"authorization_endpoint", cfg.AuthorizationEndpoint},
+		{"token_endpoint", cfg.TokenEndpoint},
+		{"jwks_uri", cfg.JWKSURI},
+		{"userinfo_endpoint", cfg.UserinfoEndpoint},
+		{"end_session_endpoint", cfg.EndSessionEndpoint},
+		{"registration_endpoint", cfg.RegistrationEndpoint},
+		{"introspection_endpoint", cfg.IntrospectionEndpoint},
+		{"revocation_endpoint", cfg.RevocationEndpoint},
+		{"device_authorization_endpoint", cfg.DeviceAuthorizationEndpoint},
+		{"pushed_authorization_request_endpoint", cfg.PushedAuthorizationRequestEndpoint},
+	} {
+		if e.URL != "" {
+			endpoints = append(endpoints, e)
+		}
+	}
+	return endpoints
+}
+
+// ParseJWKSKeyIDs returns the "kid" of every key in a JWK set
+func ParseJWKSKeyIDs(body []byte) []string {
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+		} `json:"keys"`
+	}
+	if err := jsoniter.Unmarshal(body, &jwks); err != nil {
+		return nil
+	}
+	var kids []string
+	for _, key := range jwks.Keys {
+		if key.Kid != "" {
+			kids = append(kids, key.Kid)
+		}
+	}
+	return Unique(kids)
+}
+
+// handleOIDC reports every endpoint of an OIDC discovery document or OAuth
+// authorization server metadata and crawls the in-scope ones; endpoints on
+// other hosts are reported only
+func (crawler *Crawler) handleOIDC(configURL string, body []byte, c *colly.Collector) {
+	cfg, err := ParseOIDCConfig(body)
+	if err != nil {
+		Logger.Debugf("Skipping %s: %s", configURL, err)
+		return
+	}
+
+	for _, endpoint := range cfg.Endpoints() {
+		u, err := url.Parse(endpoint.URL)
+		if err != nil || !u.IsAbs() {
+			continue
+		}
+		inScope := InScope(u, c.URLFilters)
+		crawler.emitOIDC(configURL, endpoint.Name, endpoint.URL, inScope)
+		if !inScope {
+			continue
+		}
+		if endpoint.Name == "jwks_uri" {
+			crawler.fetchJWKS(endpoint.URL)
+		}
+		if urlToVisit := crawler.urlProcessor.Process(endpoint.URL, configURL, "oidc", nil); urlToVisit != "" {
+			crawler.visit(nil, urlToVisit)
+		}
+	}
+
+	if len(cfg.ScopesSupported) > 0 {
+		crawler.emitOIDC(configURL, "scopes_supported", strings.Join(cfg.ScopesSupported, ","), true)
+	}
+}
+
+func (crawler *Crawler) fetchJWKS(jwksURL string) {
+	status, body, err := crawler.fetchSide(jwksURL, maxWellKnownSize)
+	if err != nil || status != http.StatusOK {
+		return
+	}
+	for _, kid := range ParseJWKSKeyIDs(body) {
+		crawler.emitOIDC(jwksURL, "jwks_kid", kid, true)
+	}
+}
+
+func (crawler *Crawler) emitOIDC(source, name, value string, inScope bool) {
+	outputFormat := fmt.Sprintf("[oidc] - [%s] %s", name, value)
+	if !inScope {
+		outputFormat += " (out-of-scope)"
+	}
+
+	sout := SpiderOutput{
+		Input:      crawler.Input,
+		Source:     source,
+		OutputType: "oidc",
+		Output:     value,
+		Param:      name,
+	}
+	crawler.emit(sout, outputFormat, value)
+}
@@ -0,0 +1,51 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOIDCConfig(t *testing.T) {
+	cfg, err := ParseOIDCConfig([]byte(`{
+		"issuer": "https://login.example.com",
+		"authorization_endpoint": "https://login.example.com/authorize",
+		"token_endpoint": "https://api.example.com/oauth/token",
+		"jwks_uri": "https://login.example.com/.well-known/jwks.json",
+		"scopes_supported": ["openid", "profile"]
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, []OIDCEndpoint{
+		{"issuer", "https://login.example.com"},
+		{"authorization_endpoint", "https://login.example.com/authorize"},
+		{"token_endpoint", "https://api.example.com/oauth/token"},
+		{"jwks_uri", "https://login.example.com/.well-known/jwks.json"},
+	}, cfg.Endpoints())
+
+	oauth, err := ParseOIDCConfig([]byte(`{
+		"issuer": "https://auth.example.com",
+		"authorization_endpoint": "https://auth.example.com/authorize",
+		"token_endpoint": "https://auth.example.com/token",
+		"registration_endpoint": "https://auth.example.com/register",
+		"introspection_endpoint": "https://auth.example.com/introspect",
+		"revocation_endpoint": "https://auth.example.com/revoke",
+		"device_authorization_endpoint": "https://auth.example.com/device",
+		"response_types_supported": ["code"]
+	}`))
+	require.NoError(t, err, "oauth-authorization-server metadata")
+	assert.Equal(t, []OIDCEndpoint{
+		{"issuer", "https://auth.example.com"},
+		{"authorization_endpoint", "https://auth.example.com/authorize"},
+		{"token_endpoint", "https://auth.example.com/token"},
+		{"registration_endpoint", "https://auth.example.com/register"},
+		{"introspection_endpoint", "https://auth.example.com/introspect"},
+		{"revocation_endpoint", "https://auth.example.com/revoke"},
+		{"device_authorization_endpoint", "https://auth.example.com/device"},
+	}, oauth.Endpoints())
+
+	_, err = ParseOIDCConfig([]byte(`{"keys": []}`))
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{"k1", "k2"}, ParseJWKSKeyIDs([]byte(`{"keys":[{"kid":"k1","kty":"RSA"},{"kid":"k2"},{"kty":"EC"}]}`)))
+}
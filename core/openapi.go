@@ -0,0 +1,557 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+	"gopkg.in/yaml.v3"
+)
+
+// openAPISource is the source of the spec findings of --openapi
+const openAPISource = "openapi"
+
+// maxOpenAPISize caps how much of an API spec we read; specs of large APIs
+// run to several megabytes
+const maxOpenAPISize = 16 * 1024 * 1024
+
+// maxSchemaDepth bounds how deep example bodies follow nested and
+// recursive schemas
+const maxSchemaDepth = 8
+
+// DefaultOpenAPIPaths are the spec locations probed by --openapi
+var DefaultOpenAPIPaths = []string{
+	"/swagger.json",
+	"/swagger.yaml",
+	"/openapi.json",
+	"/openapi.yaml",
+	"/v2/api-docs",
+	"/v3/api-docs",
+	"/api-docs",
+	"/swagger/v1/swagger.json",
+	"/api/swagger.json",
+	"/api/openapi.json",
+}
+
+// openAPIMethods are the operations of a path item, in the order requests
+// are generated
+var openAPIMethods = []string{"get", "post", "put", "patch", "delete", "options", "head", "trace"}
+
+// errNotOpenAPI is returned for documents that are not Swagger 2 or
+// OpenAPI 3 specs
+var errNotOpenAPI = errors.New("not a Swagger 2 or OpenAPI 3 document")
+
+// ProbeOpenAPI fetches the configured spec paths and seeds the crawl with
+// a request for every operation of the specs found
+func ProbeOpenAPI(site *url.URL, crawler *Crawler, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	client := http.DefaultClient
+	if crawler.AntiDetectClient != nil {
+		client = crawler.AntiDetectClient.GetHTTPClient()
+	}
+
+	seen := map[[sha1.Size]byte]bool{}
+	for _, p := range crawler.openAPIPaths {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		ref, err := url.Parse(p)
+		if err != nil {
+			continue
+		}
+		specURL := site.ResolveReference(ref)
+
+		resp, err := client.Get(specURL.String())
+		if err != nil {
+			continue
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxOpenAPISize))
+		resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusOK {
+			continue
+		}
+		// The same spec is often served under several of the paths
+		sum := sha1.Sum(body)
+		if seen[sum] {
+			continue
+		}
+		seen[sum] = true
+
+		requests, err := OpenAPIRequests(body, specURL)
+		if err != nil {
+			Logger.Debugf("%s: %s", specURL, err)
+			continue
+		}
+		Logger.Infof("Found API spec with %d operations: %s", len(requests), specURL)
+		crawler.emitOpenAPI(specURL.String(), resp.StatusCode, len(requests))
+		crawler.seedRequests(openAPISource, requests)
+	}
+}
+
+func (crawler *Crawler) emitOpenAPI(specURL string, statusCode, operations int) {
+	outputFormat := fmt.Sprintf("[openapi] - [code-%d] - [%d operations] - %s", statusCode, operations, specURL)
+
+	sout := SpiderOutput{
+		Input:      crawler.Input,
+		Source:     openAPISource,
+		OutputType: "openapi",
+		StatusCode: statusCode,
+		Output:     specURL,
+	}
+	crawler.emit(sout, outputFormat, specURL)
+}
+
+// OpenAPIRequests parses a Swagger 2 or OpenAPI 3 spec, in JSON or YAML,
+// into one request per documented operation. Path parameters get their
+// example or a placeholder, query, header and cookie parameters their
+// example or a value fitting their schema, and request bodies an example
+// built from the spec's examples or schemas. Relative servers resolve
+// against specURL
+func OpenAPIRequests(data []byte, specURL *url.URL) ([]JSRequest, error) {
+	var doc map[string]interface{}
+	if err := jsoniter.Unmarshal(data, &doc); err != nil {
+		doc = nil
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, errNotOpenAPI
+		}
+	}
+	spec := openAPISpec{doc: doc}
+	_, swagger := doc["swagger"]
+	_, openapi := doc["openapi"]
+	paths, _ := doc["paths"].(map[string]interface{})
+	if !swagger && !openapi || paths == nil {
+		return nil, errNotOpenAPI
+	}
+	base := spec.baseURL(specURL, swagger)
+
+	names := make([]string, 0, len(paths))
+	for name := range paths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var requests []JSRequest
+	for _, name := range names {
+		item, _ := spec.resolve(paths[name]).(map[string]interface{})
+		if item == nil {
+			continue
+		}
+		for _, method := range openAPIMethods {
+			op, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			req := spec.operationRequest(base, name, method, item, op, swagger)
+			req.Source = specURL.String()
+			requests = append(requests, req)
+		}
+	}
+	return requests, nil
+}
+
+// openAPISpec is a decoded spec, kept generic so both versions and any
+// vendor extensions parse
+type openAPISpec struct {
+	doc map[string]interface{}
+}
+
+// baseURL is where the spec's paths are rooted: host, basePath and
+// schemes for Swagger 2, the first server for OpenAPI 3
+func (s openAPISpec) baseURL(specURL *url.URL, swagger bool) *url.URL {
+	base := &url.URL{Scheme: specURL.Scheme, Host: specURL.Host}
+	if swagger {
+		if host, _ := s.doc["host"].(string); host != "" {
+			base.Host = host
+		}
+		if schemes, _ := s.doc["schemes"].([]interface{}); len(schemes) > 0 {
+			// Keep the spec's own scheme when the API serves both
+			served := false
+			for _, scheme := range schemes {
+				served = served || scheme == specURL.Scheme
+			}
+			if scheme, _ := schemes[0].(string); !served && scheme != "" {
+				base.Scheme = scheme
+			}
+		}
+		basePath, _ := s.doc["basePath"].(string)
+		base.Path = strings.TrimSuffix(basePath, "/")
+		return base
+	}
+
+	servers, _ := s.doc["servers"].([]interface{})
+	if len(servers) == 0 {
+		return base
+	}
+	server, _ := servers[0].(map[string]interface{})
+	raw, _ := server["url"].(string)
+	variables, _ := server["variables"].(map[string]interface{})
+	for name, v := range variables {
+		variable, _ := v.(map[string]interface{})
+		raw = strings.ReplaceAll(raw, "{"+name+"}", fmt.Sprint(variable["default"]))
+	}
+	ref, err := url.Parse(raw)
+	if err != nil || strings.Contains(raw, "{") {
+		return base
+	}
+	resolved := specURL.ResolveReference(ref)
+	resolved.Path = strings.TrimSuffix(resolved.Path, "/")
+	resolved.RawQuery, resolved.Fragment = "", ""
+	return resolved
+}
+
+// operationRequest builds the request of one operation
+func (s openAPISpec) operationRequest(base *url.URL, path, method string, item, op map[string]interface{}, swagger bool) JSRequest {
+	req := JSRequest{Method: strings.ToUpper(method), Headers: map[string]string{}}
+	query := url.Values{}
+	form := url.Values{}
+	var cookies []string
+	var body interface{}
+	hasBody := false
+
+	for _, param := range s.parameters(item, op) {
+		name, _ := param["name"].(string)
+		in, _ := param["in"].(string)
+		if name == "" {
+			continue
+		}
+		switch in {
+		case "path":
+			value := collectionPlaceholder
+			if example, ok := s.paramExample(param, swagger); ok {
+				value = exampleString(example)
+			}
+			path = strings.ReplaceAll(path, "{"+name+"}", url.PathEscape(value))
+		case "query":
+			query.Set(name, exampleString(s.paramValue(param, swagger)))
+		case "header":
+			if !strings.EqualFold(name, "Content-Type") && !strings.EqualFold(name, "Accept") {
+				req.Headers[name] = exampleString(s.paramValue(param, swagger))
+			}
+		case "cookie":
+			cookies = append(cookies, name+"="+exampleString(s.paramValue(param, swagger)))
+		case "formData":
+			form.Set(name, exampleString(s.paramValue(param, swagger)))
+		case "body":
+			body, hasBody = s.schemaExample(param["schema"], 0), true
+		}
+	}
+	if len(cookies) > 0 {
+		req.Headers["Cookie"] = strings.Join(cookies, "; ")
+	}
+
+	u := *base
+	u.Path = base.Path + "/" + strings.TrimPrefix(path, "/")
+	u.RawQuery = query.Encode()
+	req.RawURL = u.String()
+
+	if swagger {
+		consumes := stringList(op["consumes"])
+		if consumes == nil {
+			consumes = stringList(s.doc["consumes"])
+		}
+		switch {
+		case hasBody:
+			req.ContentType = "application/json"
+			req.Body = exampleJSON(body)
+		case len(form) > 0:
+			multipartForm := false
+			for _, c := range consumes {
+				multipartForm = multipartForm || strings.HasPrefix(c, "multipart/")
+			}
+			if multipartForm {
+				req.Body, req.ContentType = multipartBody(form)
+			} else {
+				req.Body, req.ContentType = form.Encode(), "application/x-www-form-urlencoded"
+			}
+		}
+	} else if requestBody, ok := s.resolve(op["requestBody"]).(map[string]interface{}); ok {
+		content, _ := requestBody["content"].(map[string]interface{})
+		req.Body, req.ContentType = s.mediaBody(content)
+	}
+	if len(req.Headers) == 0 {
+		req.Headers = nil
+	}
+	return req
+}
+
+// parameters merges the path item's parameters with the operation's,
+// which override those of the same name and location
+func (s openAPISpec) parameters(item, op map[string]interface{}) []map[string]interface{} {
+	var merged []map[string]interface{}
+	index := map[string]int{}
+	for _, list := range []interface{}{item["parameters"], op["parameters"]} {
+		params, _ := list.([]interface{})
+		for _, p := range params {
+			param, ok := s.resolve(p).(map[string]interface{})
+			if !ok {
+				continue
+			}
+			key := fmt.Sprint(param["in"]) + ":" + fmt.Sprint(param["name"])
+			if i, ok := index[key]; ok {
+				merged[i] = param
+				continue
+			}
+			index[key] = len(merged)
+			merged = append(merged, param)
+		}
+	}
+	return merged
+}
+
+// paramExample returns the example a parameter documents, if any
+func (s openAPISpec) paramExample(param map[string]interface{}, swagger bool) (interface{}, bool) {
+	if example, ok := param["example"]; ok {
+		return example, true
+	}
+	if examples, ok := param["examples"].(map[string]interface{}); ok {
+		for _, name := range sortedKeys(examples) {
+			if example, ok := s.resolve(examples[name]).(map[string]interface{}); ok {
+				if value, ok := example["value"]; ok {
+					return value, true
+				}
+			}
+		}
+	}
+	schema := s.resolve(param["schema"])
+	if swagger {
+		schema = param
+	}
+	if schema, ok := schema.(map[string]interface{}); ok {
+		for _, key := range []string{"example", "default"} {
+			if value, ok := schema[key]; ok {
+				return value, true
+			}
+		}
+		if enum, _ := schema["enum"].([]interface{}); len(enum) > 0 {
+			return enum[0], true
+		}
+	}
+	return nil, false
+}
+
+// paramValue returns a parameter's example, or a value fitting its schema
+func (s openAPISpec) paramValue(param map[string]interface{}, swagger bool) interface{} {
+	if example, ok := s.paramExample(param, swagger); ok {
+		return example
+	}
+	if swagger {
+		return s.schemaExample(param, 0)
+	}
+	return s.schemaExample(param["schema"], 0)
+}
+
+// mediaBody picks the body of an OpenAPI 3 request: JSON first, then
+// forms, then whatever else has an example
+func (s openAPISpec) mediaBody(content map[string]interface{}) (string, string) {
+	types := sortedKeys(content)
+	sort.SliceStable(types, func(a, b int) bool { return mediaRank(types[a]) < mediaRank(types[b]) })
+	for _, contentType := range types {
+		media, _ := s.resolve(content[contentType]).(map[string]interface{})
+		if media == nil {
+			continue
+		}
+		value, ok := media["example"]
+		if !ok {
+			if examples, _ := media["examples"].(map[string]interface{}); len(examples) > 0 {
+				example, _ := s.resolve(examples[sortedKeys(examples)[0]]).(map[string]interface{})
+				value, ok = example["value"]
+			}
+		}
+		if !ok {
+			value = s.schemaExample(media["schema"], 0)
+		}
+		switch rank := mediaRank(contentType); {
+		case rank == 0:
+			return exampleJSON(value), contentType
+		case rank <= 2:
+			form := url.Values{}
+			if fields, ok := value.(map[string]interface{}); ok {
+				for name, field := range fields {
+					form.Set(name, exampleString(field))
+				}
+			}
+			if rank == 2 {
+				return multipartBody(form)
+			}
+			return form.Encode(), contentType
+		default:
+			if value == nil {
+				continue
+			}
+			return exampleString(value), contentType
+		}
+	}
+	return "", ""
+}
+
+func mediaRank(contentType string) int {
+	switch {
+	case contentType == "application/json" || strings.HasSuffix(contentType, "+json"):
+		return 0
+	case contentType == "application/x-www-form-urlencoded":
+		return 1
+	case contentType == "multipart/form-data":
+		return 2
+	}
+	return 3
+}
+
+// schemaExample builds an example value from a schema: its example,
+// default or first enum value when it has one, else one property or item
+// of each kind with a value fitting its type and format
+func (s openAPISpec) schemaExample(node interface{}, depth int) interface{} {
+	schema, ok := s.resolve(node).(map[string]interface{})
+	if !ok || depth > maxSchemaDepth {
+		return nil
+	}
+	for _, key := range []string{"example", "default"} {
+		if value, ok := schema[key]; ok {
+			return value
+		}
+	}
+	if enum, _ := schema["enum"].([]interface{}); len(enum) > 0 {
+		return enum[0]
+	}
+	if all, _ := schema["allOf"].([]interface{}); len(all) > 0 {
+		merged := map[string]interface{}{}
+		for _, part := range all {
+			if fields, ok := s.schemaExample(part, depth+1).(map[string]interface{}); ok {
+				for k, v := range fields {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if choices, _ := schema[key].([]interface{}); len(choices) > 0 {
+			return s.schemaExample(choices[0], depth+1)
+		}
+	}
+
+	kind, _ := schema["type"].(string)
+	if list, ok := schema["type"].([]interface{}); ok && len(list) > 0 {
+		kind, _ = list[0].(string)
+	}
+	properties, _ := schema["properties"].(map[string]interface{})
+	switch {
+	case kind == "object" || properties != nil:
+		fields := map[string]interface{}{}
+		for name, property := range properties {
+			fields[name] = s.schemaExample(property, depth+1)
+		}
+		return fields
+	case kind == "array":
+		return []interface{}{s.schemaExample(schema["items"], depth+1)}
+	case kind == "integer" || kind == "number":
+		return 1
+	case kind == "boolean":
+		return true
+	case kind == "file":
+		return "test.txt"
+	}
+	format, _ := schema["format"].(string)
+	switch format {
+	case "date":
+		return "2024-01-01"
+	case "date-time":
+		return "2024-01-01T00:00:00Z"
+	case "email":
+		return "user@example.com"
+	case "uuid":
+		return "00000000-0000-0000-0000-000000000001"
+	case "uri", "url":
+		return "https://example.com/"
+	case "ipv4":
+		return "127.0.0.1"
+	case "byte":
+		return "dGVzdA=="
+	}
+	return "test"
+}
+
+// resolve follows local $refs, #/definitions/... and #/components/...
+func (s openAPISpec) resolve(node interface{}) interface{} {
+	for hops := 0; hops < maxSchemaDepth; hops++ {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return node
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok {
+			return node
+		}
+		if !strings.HasPrefix(ref, "#/") {
+			return nil
+		}
+		var target interface{} = s.doc
+		for _, part := range strings.Split(ref[2:], "/") {
+			part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+			parent, _ := target.(map[string]interface{})
+			target = parent[part]
+		}
+		node = target
+	}
+	return nil
+}
+
+func exampleString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		return exampleJSON(v)
+	}
+	return fmt.Sprint(value)
+}
+
+func exampleJSON(value interface{}) string {
+	data, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func multipartBody(form url.Values) (string, string) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, name := range sortedKeys(form) {
+		_ = w.WriteField(name, form.Get(name))
+	}
+	_ = w.Close()
+	return buf.String(), w.FormDataContentType()
+}
+
+func stringList(node interface{}) []string {
+	items, _ := node.([]interface{})
+	var list []string
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,413 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gocolly/colly/v2"
+	jsoniter "github.com/json-iterator/go"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// Path segments that identify a resource rather than name an endpoint
+	uuidSegmentRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hashSegmentRegex = regexp.MustCompile(`^[0-9a-fA-F]{16,}$`)
+)
+
+// OpenAPICollector clusters the requests a crawl sends into a draft
+// OpenAPI 3.0 document: paths with resource ids templated, their methods,
+// query and body parameters, request content types and the status codes
+// and content types of their responses. It is shared by all crawlers of a
+// run
+type OpenAPICollector struct {
+	path string
+
+	mu         sync.Mutex
+	servers    map[string]struct{}
+	operations map[string]*observedOperation
+}
+
+type observedOperation struct {
+	method     string
+	path       string
+	pathParams []OpenAPIParameter
+	query      map[string]*OpenAPISchema
+	bodies     map[string]*OpenAPISchema
+	responses  map[int]map[string]struct{}
+}
+
+// NewOpenAPICollector returns a collector that writes its document to
+// path, as YAML for .yaml and .yml files and JSON otherwise
+func NewOpenAPICollector(path string) *OpenAPICollector {
+	return &OpenAPICollector{
+		path:       path,
+		servers:    make(map[string]struct{}),
+		operations: make(map[string]*observedOperation),
+	}
+}
+
+// Add records a request and the status code and content type of its
+// response. Paths that only ever answered 404 are left out of the document
+func (o *OpenAPICollector) Add(method, rawURL, contentType, body string, status int, responseType string) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return
+	}
+	method = strings.ToLower(method)
+	if method == "" {
+		method = "get"
+	}
+	path, pathParams := templatePath(u.EscapedPath())
+	key := method + " " + path
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.servers[u.Scheme+"://"+u.Host] = struct{}{}
+	op, ok := o.operations[key]
+	if !ok {
+		op = &observedOperation{
+			method:     method,
+			path:       path,
+			pathParams: pathParams,
+			query:      make(map[string]*OpenAPISchema),
+			bodies:     make(map[string]*OpenAPISchema),
+			responses:  make(map[int]map[string]struct{}),
+		}
+		o.operations[key] = op
+	}
+	for name, values := range u.Query() {
+		if name == "" || len(values) == 0 {
+			continue
+		}
+		op.query[name] = mergeSchemas(op.query[name], inferValueSchema(values[0]))
+	}
+	if body != "" {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		if mediaType == "" {
+			mediaType = "application/octet-stream"
+			if looksLikeJSON(body) {
+				mediaType = "application/json"
+			}
+		}
+		op.bodies[mediaType] = mergeSchemas(op.bodies[mediaType], inferBodySchema(mediaType, body))
+	}
+	if status > 0 {
+		types, ok := op.responses[status]
+		if !ok {
+			types = make(map[string]struct{})
+			op.responses[status] = types
+		}
+		if mediaType, _, _ := mime.ParseMediaType(responseType); mediaType != "" {
+			types[mediaType] = struct{}{}
+		}
+	}
+}
+
+// templatePath replaces numeric, UUID and hash segments with path
+// parameters, so /users/42 and /users/7 cluster into /users/{id}
+func templatePath(path string) (string, []OpenAPIParameter) {
+	if path == "" {
+		return "/", nil
+	}
+	var params []OpenAPIParameter
+	used := map[string]int{}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		var name string
+		schema := &OpenAPISchema{Type: "string"}
+		switch {
+		case segment == "":
+			continue
+		case isDigits(segment):
+			name, schema = "id", &OpenAPISchema{Type: "integer"}
+		case uuidSegmentRegex.MatchString(segment):
+			name, schema.Format = "uuid", "uuid"
+		case hashSegmentRegex.MatchString(segment):
+			name = "hash"
+		default:
+			continue
+		}
+		used[name]++
+		if used[name] > 1 {
+			name += strconv.Itoa(used[name])
+		}
+		schema.Example = segment
+		if schema.Type == "integer" {
+			schema.Example, _ = strconv.Atoi(segment)
+		}
+		segments[i] = "{" + name + "}"
+		params = append(params, OpenAPIParameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+	return strings.Join(segments, "/"), params
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// inferValueSchema types a query or form value from how it reads
+func inferValueSchema(value string) *OpenAPISchema {
+	switch {
+	case isDigits(value) && len(value) < 16:
+		n, _ := strconv.Atoi(value)
+		return &OpenAPISchema{Type: "integer", Example: n}
+	case value == "true" || value == "false":
+		return &OpenAPISchema{Type: "boolean", Example: value == "true"}
+	}
+	return &OpenAPISchema{Type: "string", Example: value}
+}
+
+// inferBodySchema describes a form or JSON request body
+func inferBodySchema(mediaType, body string) *OpenAPISchema {
+	switch {
+	case strings.Contains(mediaType, "json"):
+		var doc interface{}
+		if err := json.Unmarshal([]byte(body), &doc); err == nil {
+			return inferJSONSchema(doc)
+		}
+	case mediaType == "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(body)
+		if err != nil && len(values) == 0 {
+			break
+		}
+		schema := &OpenAPISchema{Type: "object", Properties: map[string]*OpenAPISchema{}}
+		for name, v := range values {
+			schema.Properties[name] = inferValueSchema(v[0])
+		}
+		return schema
+	}
+	return &OpenAPISchema{Type: "string"}
+}
+
+func inferJSONSchema(value interface{}) *OpenAPISchema {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		schema := &OpenAPISchema{Type: "object", Properties: map[string]*OpenAPISchema{}}
+		for name, field := range v {
+			schema.Properties[name] = inferJSONSchema(field)
+		}
+		return schema
+	case []interface{}:
+		schema := &OpenAPISchema{Type: "array", Items: &OpenAPISchema{}}
+		for _, item := range v {
+			schema.Items = mergeSchemas(schema.Items, inferJSONSchema(item))
+		}
+		return schema
+	case float64:
+		if v == float64(int64(v)) {
+			return &OpenAPISchema{Type: "integer", Example: int64(v)}
+		}
+		return &OpenAPISchema{Type: "number", Example: v}
+	case bool:
+		return &OpenAPISchema{Type: "boolean", Example: v}
+	case string:
+		return &OpenAPISchema{Type: "string", Example: v}
+	}
+	return &OpenAPISchema{Nullable: true}
+}
+
+// mergeSchemas widens a recorded schema with a new observation: objects
+// gain the properties seen, and conflicting scalar types fall back to
+// string
+func mergeSchemas(seen, next *OpenAPISchema) *OpenAPISchema {
+	switch {
+	case seen == nil:
+		return next
+	case next == nil || next.Type == "":
+		seen.Nullable = seen.Nullable || next != nil && next.Nullable
+		return seen
+	case seen.Type == "":
+		next.Nullable = next.Nullable || seen.Nullable
+		return next
+	case seen.Type == "object" && next.Type == "object":
+		for name, property := range next.Properties {
+			seen.Properties[name] = mergeSchemas(seen.Properties[name], property)
+		}
+	case seen.Type == "array" && next.Type == "array":
+		seen.Items = mergeSchemas(seen.Items, next.Items)
+	case seen.Type == "integer" && next.Type == "number":
+		seen.Type = "number"
+	case seen.Type != next.Type && !(seen.Type == "number" && next.Type == "integer"):
+		seen.Type, seen.Format, seen.Properties, seen.Items = "string", "", nil, nil
+	}
+	return seen
+}
+
+// OpenAPIDocument is the draft spec written by --openapi-out
+type OpenAPIDocument struct {
+	OpenAPI string                                  `json:"openapi" yaml:"openapi"`
+	Info    OpenAPIInfo                             `json:"info" yaml:"info"`
+	Servers []OpenAPIServer                         `json:"servers,omitempty" yaml:"servers,omitempty"`
+	Paths   map[string]map[string]*OpenAPIOperation `json:"paths" yaml:"paths"`
+}
+
+// OpenAPIInfo is the info object of an OpenAPIDocument
+type OpenAPIInfo struct {
+	Title       string `json:"title" yaml:"title"`
+	Version     string `json:"version" yaml:"version"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// OpenAPIServer is an origin the crawl sent requests to
+type OpenAPIServer struct {
+	URL string `json:"url" yaml:"url"`
+}
+
+// OpenAPIOperation is a method observed on a path
+type OpenAPIOperation struct {
+	Parameters  []OpenAPIParameter          `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses" yaml:"responses"`
+}
+
+// OpenAPIParameter is a path or query parameter of an operation
+type OpenAPIParameter struct {
+	Name     string         `json:"name" yaml:"name"`
+	In       string         `json:"in" yaml:"in"`
+	Required bool           `json:"required,omitempty" yaml:"required,omitempty"`
+	Schema   *OpenAPISchema `json:"schema,omitempty" yaml:"schema,omitempty"`
+}
+
+// OpenAPIRequestBody lists the bodies an operation was sent, by content type
+type OpenAPIRequestBody struct {
+	Content map[string]OpenAPIMediaType `json:"content" yaml:"content"`
+}
+
+// OpenAPIResponse is a status code an operation answered with
+type OpenAPIResponse struct {
+	Description string                      `json:"description" yaml:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+// OpenAPIMediaType is a content type with the schema of its payload
+type OpenAPIMediaType struct {
+	Schema *OpenAPISchema `json:"schema,omitempty" yaml:"schema,omitempty"`
+}
+
+// OpenAPISchema is the subset of JSON schema inferred from observed values
+type OpenAPISchema struct {
+	Type       string                    `json:"type,omitempty" yaml:"type,omitempty"`
+	Format     string                    `json:"format,omitempty" yaml:"format,omitempty"`
+	Nullable   bool                      `json:"nullable,omitempty" yaml:"nullable,omitempty"`
+	Properties map[string]*OpenAPISchema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	Items      *OpenAPISchema            `json:"items,omitempty" yaml:"items,omitempty"`
+	Example    interface{}               `json:"example,omitempty" yaml:"example,omitempty"`
+}
+
+// Document returns the draft spec of what was recorded so far
+func (o *OpenAPICollector) Document() OpenAPIDocument {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	doc := OpenAPIDocument{
+		OpenAPI: "3.0.3",
+		Info: OpenAPIInfo{
+			Title:       "Crawled API",
+			Version:     "draft",
+			Description: fmt.Sprintf("Inferred by %s %s from the requests of a crawl", CLIName, VERSION),
+		},
+		Paths: map[string]map[string]*OpenAPIOperation{},
+	}
+	for server := range o.servers {
+		doc.Servers = append(doc.Servers, OpenAPIServer{URL: server})
+	}
+	sort.Slice(doc.Servers, func(a, b int) bool { return doc.Servers[a].URL < doc.Servers[b].URL })
+
+	for _, op := range o.operations {
+		responses := map[string]*OpenAPIResponse{}
+		found := false
+		for status, types := range op.responses {
+			found = found || status != 404
+			response := &OpenAPIResponse{Description: statusDescription(status)}
+			for mediaType := range types {
+				if response.Content == nil {
+					response.Content = map[string]OpenAPIMediaType{}
+				}
+				response.Content[mediaType] = OpenAPIMediaType{}
+			}
+			responses[strconv.Itoa(status)] = response
+		}
+		if !found {
+			continue
+		}
+
+		operation := &OpenAPIOperation{Parameters: op.pathParams, Responses: responses}
+		for _, name := range sortedKeys(op.query) {
+			operation.Parameters = append(operation.Parameters, OpenAPIParameter{Name: name, In: "query", Schema: op.query[name]})
+		}
+		if len(op.bodies) > 0 {
+			operation.RequestBody = &OpenAPIRequestBody{Content: map[string]OpenAPIMediaType{}}
+			for mediaType, schema := range op.bodies {
+				operation.RequestBody.Content[mediaType] = OpenAPIMediaType{Schema: schema}
+			}
+		}
+		if doc.Paths[op.path] == nil {
+			doc.Paths[op.path] = map[string]*OpenAPIOperation{}
+		}
+		doc.Paths[op.path][op.method] = operation
+	}
+	return doc
+}
+
+func statusDescription(status int) string {
+	if text := http.StatusText(status); text != "" {
+		return text
+	}
+	return "Status " + strconv.Itoa(status)
+}
+
+// Save writes the document
+func (o *OpenAPICollector) Save() error {
+	doc := o.Document()
+	var data []byte
+	var err error
+	switch strings.ToLower(filepath.Ext(o.path)) {
+	case ".yaml", ".yml":
+		data, err = yaml.Marshal(doc)
+	default:
+		data, err = jsoniter.ConfigCompatibleWithStandardLibrary.MarshalIndent(doc, "", "  ")
+		data = append(data, '\n')
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(o.path, data, 0644)
+}
+
+// recordOpenAPI adds a response of the crawl to --openapi-out. Reflection
+// probes are left out, their payloads are not part of the API
+func (crawler *Crawler) recordOpenAPI(response *colly.Response) {
+	if crawler.openAPIDraft == nil || response == nil || response.StatusCode == 0 || response.Request == nil || response.Request.URL == nil {
+		return
+	}
+	var contentType, body string
+	if response.Ctx != nil {
+		if response.Ctx.Get("reflected") == "true" {
+			return
+		}
+		body = response.Ctx.Get("body")
+	}
+	if response.Request.Headers != nil {
+		contentType = response.Request.Headers.Get("Content-Type")
+	}
+	var responseType string
+	if response.Headers != nil {
+		responseType = response.Headers.Get("Content-Type")
+	}
+	crawler.openAPIDraft.Add(response.Request.Method, response.Request.URL.String(), contentType, body, response.StatusCode, responseType)
+}
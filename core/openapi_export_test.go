@@ -0,0 +1,96 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestOpenAPICollector(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spec.yaml")
+	o := NewOpenAPICollector(path)
+	o.Add("GET", "https://api.example.com/users/42?page=1", "", "", 200, "application/json; charset=utf-8")
+	o.Add("GET", "https://api.example.com/users/7?page=2&q=bob", "", "", 200, "application/json")
+	o.Add("POST", "https://api.example.com/users", "application/json", `{"name":"bob","tags":["a"]}`, 201, "")
+	o.Add("POST", "https://api.example.com/users", "application/json", `{"name":"amy","age":3}`, 400, "application/json")
+	o.Add("POST", "https://api.example.com/login", "application/x-www-form-urlencoded", "user=admin&remember=true", 302, "")
+	o.Add("GET", "https://api.example.com/files/0b9e0a4c-6c2f-4f53-9a6e-1f0d7c2b8a11/v/3", "", "", 200, "")
+	o.Add("GET", "https://api.example.com/missing", "", "", 404, "text/html")
+
+	doc := o.Document()
+	assert.Equal(t, []OpenAPIServer{{URL: "https://api.example.com"}}, doc.Servers)
+	assert.NotContains(t, doc.Paths, "/missing", "paths that only answered 404 are dropped")
+
+	get := doc.Paths["/users/{id}"]["get"]
+	require.NotNil(t, get, "numeric segments cluster into one templated path")
+	require.Len(t, get.Parameters, 3)
+	assert.Equal(t, OpenAPIParameter{Name: "id", In: "path", Required: true, Schema: &OpenAPISchema{Type: "integer", Example: 42}}, get.Parameters[0])
+	assert.Equal(t, "page", get.Parameters[1].Name)
+	assert.Equal(t, "integer", get.Parameters[1].Schema.Type)
+	assert.Equal(t, "q", get.Parameters[2].Name)
+	assert.Contains(t, get.Responses["200"].Content, "application/json")
+
+	post := doc.Paths["/users"]["post"]
+	require.NotNil(t, post)
+	body := post.RequestBody.Content["application/json"].Schema
+	assert.Equal(t, "object", body.Type)
+	assert.ElementsMatch(t, []string{"name", "tags", "age"}, sortedKeys(body.Properties), "bodies merge their properties")
+	assert.Equal(t, "array", body.Properties["tags"].Type)
+	assert.Contains(t, post.Responses, "201")
+	assert.Contains(t, post.Responses, "400")
+
+	login := doc.Paths["/login"]["post"].RequestBody.Content["application/x-www-form-urlencoded"].Schema
+	assert.Equal(t, "boolean", login.Properties["remember"].Type)
+
+	files := doc.Paths["/files/{uuid}/v/{id}"]["get"]
+	require.NotNil(t, files)
+	assert.Equal(t, "uuid", files.Parameters[0].Schema.Format)
+
+	require.NoError(t, o.Save())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var saved map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(data, &saved))
+	assert.Equal(t, "3.0.3", saved["openapi"])
+	assert.Contains(t, saved["paths"], "/users/{id}")
+}
+
+func TestMergeSchemas(t *testing.T) {
+	assert.Equal(t, "number", mergeSchemas(&OpenAPISchema{Type: "integer"}, &OpenAPISchema{Type: "number"}).Type)
+	assert.Equal(t, "number", mergeSchemas(&OpenAPISchema{Type: "number"}, &OpenAPISchema{Type: "integer"}).Type)
+	assert.Equal(t, "string", mergeSchemas(&OpenAPISchema{Type: "boolean"}, &OpenAPISchema{Type: "integer"}).Type)
+	merged := mergeSchemas(&OpenAPISchema{Nullable: true}, &OpenAPISchema{Type: "string"})
+	assert.Equal(t, &OpenAPISchema{Type: "string", Nullable: true}, merged, "a null then a value is a nullable value")
+}
+
+func TestCrawlRecordsOpenAPI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<a href="/items/1">one</a><a href="/items/2?sort=asc">two</a><a href="/gone">gone</a>`))
+		case "/gone":
+			http.Error(w, "gone", http.StatusGone)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer srv.Close()
+
+	draft := NewOpenAPICollector(filepath.Join(t.TempDir(), "spec.json"))
+	crawlAll(t, srv.URL, CrawlerConfig{
+		OpenAPIDraft: draft,
+	})
+
+	doc := draft.Document()
+	require.Contains(t, doc.Paths, "/items/{id}")
+	assert.Equal(t, "sort", doc.Paths["/items/{id}"]["get"].Parameters[1].Name)
+	assert.Contains(t, doc.Paths["/gone"]["get"].Responses, "410", "error responses are recorded too")
+}
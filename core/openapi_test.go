@@ -0,0 +1,172 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSwaggerSpec = `{
+  "swagger": "2.0",
+  "basePath": "/api/",
+  "paths": {
+    "/users/{id}": {
+      "parameters": [{"name": "id", "in": "path", "required": true, "type": "integer"}],
+      "get": {"parameters": [{"name": "fields", "in": "query", "type": "string", "enum": ["name", "email"]}]},
+      "delete": {}
+    },
+    "/users": {
+      "post": {"parameters": [{"name": "user", "in": "body", "schema": {"$ref": "#/definitions/User"}}]}
+    },
+    "/login": {
+      "post": {"consumes": ["application/x-www-form-urlencoded"], "parameters": [
+        {"name": "username", "in": "formData", "type": "string", "default": "admin"},
+        {"name": "X-Client", "in": "header", "type": "string"}
+      ]}
+    }
+  },
+  "definitions": {
+    "User": {"type": "object", "properties": {
+      "email": {"type": "string", "format": "email"},
+      "age": {"type": "integer"},
+      "tags": {"type": "array", "items": {"type": "string"}},
+      "manager": {"$ref": "#/definitions/User"}
+    }}
+  }
+}`
+
+const testOpenAPISpec = `openapi: 3.0.1
+servers:
+  - url: https://{env}.example.com/v1
+    variables:
+      env:
+        default: api
+paths:
+  /items/{itemId}:
+    get:
+      parameters:
+        - name: itemId
+          in: path
+          example: abc
+        - name: session
+          in: cookie
+          schema:
+            type: string
+      responses:
+        200:
+          description: ok
+    put:
+      requestBody:
+        $ref: '#/components/requestBodies/Item'
+  /search:
+    post:
+      requestBody:
+        content:
+          application/x-www-form-urlencoded:
+            schema:
+              type: object
+              properties:
+                q:
+                  type: string
+                  example: shoes
+components:
+  requestBodies:
+    Item:
+      content:
+        application/json:
+          example: {"name": "lamp", "price": 3}
+`
+
+func TestOpenAPIRequestsSwagger(t *testing.T) {
+	specURL, _ := url.Parse("https://shop.example.com/swagger.json")
+	requests, err := OpenAPIRequests([]byte(testSwaggerSpec), specURL)
+	require.NoError(t, err)
+	require.Len(t, requests, 4)
+
+	assert.Equal(t, "POST", requests[0].Method)
+	assert.Equal(t, "https://shop.example.com/api/login", requests[0].RawURL)
+	assert.Equal(t, "username=admin", requests[0].Body)
+	assert.Equal(t, "application/x-www-form-urlencoded", requests[0].ContentType)
+	assert.Equal(t, map[string]string{"X-Client": "test"}, requests[0].Headers)
+
+	assert.Equal(t, "https://shop.example.com/api/users", requests[1].RawURL)
+	assert.Equal(t, "application/json", requests[1].ContentType)
+	assert.Contains(t, requests[1].Body, `"email":"user@example.com"`)
+	assert.Contains(t, requests[1].Body, `"tags":["test"]`)
+	assert.Equal(t, specURL.String(), requests[1].Source)
+
+	assert.Equal(t, "GET", requests[2].Method)
+	assert.Equal(t, "https://shop.example.com/api/users/1?fields=name", requests[2].RawURL, "path parameters get a placeholder, enums their first value")
+	assert.Equal(t, "DELETE", requests[3].Method)
+	assert.Equal(t, "https://shop.example.com/api/users/1", requests[3].RawURL, "path-level parameters apply to every operation")
+}
+
+func TestOpenAPIRequestsOpenAPI3(t *testing.T) {
+	specURL, _ := url.Parse("https://docs.example.com/openapi.yaml")
+	requests, err := OpenAPIRequests([]byte(testOpenAPISpec), specURL)
+	require.NoError(t, err)
+	require.Len(t, requests, 3)
+
+	assert.Equal(t, "GET", requests[0].Method)
+	assert.Equal(t, "https://api.example.com/v1/items/abc", requests[0].RawURL)
+	assert.Equal(t, "session=test", requests[0].Headers["Cookie"])
+
+	assert.Equal(t, "PUT", requests[1].Method)
+	assert.JSONEq(t, `{"name": "lamp", "price": 3}`, requests[1].Body)
+	assert.Equal(t, "application/json", requests[1].ContentType)
+
+	assert.Equal(t, "https://api.example.com/v1/search", requests[2].RawURL)
+	assert.Equal(t, "q=shoes", requests[2].Body)
+
+	_, err = OpenAPIRequests([]byte(`<html>not found</html>`), specURL)
+	assert.ErrorIs(t, err, errNotOpenAPI)
+	_, err = OpenAPIRequests([]byte(`{"info": {}, "item": []}`), specURL)
+	assert.ErrorIs(t, err, errNotOpenAPI)
+}
+
+func TestCrawlProbesOpenAPI(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/swagger.json" {
+			w.Write([]byte(testSwaggerSpec))
+			return
+		}
+		mu.Lock()
+		seen[r.Method+" "+r.URL.Path] = r.Header.Get("Content-Type")
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	results := crawlAll(t, srv.URL, CrawlerConfig{
+		OpenAPI: true, OpenAPIPaths: []string{"/swagger.json", "/openapi.json"},
+	})
+
+	var specs, reported []string
+	for _, sout := range results {
+		switch sout.OutputType {
+		case "openapi":
+			specs = append(specs, sout.Output)
+		case "js-request":
+			if strings.HasSuffix(sout.Source, "/swagger.json") {
+				reported = append(reported, sout.Output)
+			}
+		}
+	}
+
+	assert.Equal(t, []string{srv.URL + "/swagger.json"}, specs, "a catch-all response is not a spec")
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "application/json", seen["POST /api/users"])
+	assert.Contains(t, seen, "GET /api/users/1")
+	assert.NotContains(t, seen, "DELETE /api/users/1", "destructive operations are never sent")
+	assert.Contains(t, reported, "DELETE "+srv.URL+"/api/users/1")
+}
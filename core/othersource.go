@@ -2,55 +2,190 @@ package core
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"sync"
+	"time"
+
+	"github.com/jaeles-project/gospider/core/antidetect"
+)
+
+const (
+	// waybackPageSize is how many captures one CDX page asks for
+	waybackPageSize = 5000
+	// otherSourceRetries is how often a page answered with 429 or 5xx is
+	// asked again before the provider gives up where it is
+	otherSourceRetries = 3
+	// otherSourceTimeout bounds one page request; CDX pages are slow
+	otherSourceTimeout = 60 * time.Second
 )
 
+// Provider endpoints, variables so tests can point them at a local server
+var (
+	waybackCDXURL          = "http://web.archive.org/cdx/search/cdx"
+	commonCrawlCollInfoURL = "https://index.commoncrawl.org/collinfo.json"
+	commonCrawlDefaultAPI  = "http://index.commoncrawl.org/CC-MAIN-2019-51-index"
+)
+
+// otherSourceFetchers are the providers OtherSources queries
+var otherSourceFetchers = []fetchFn{
+	getWaybackURLs,
+	getCommonCrawlURLs,
+	getVirusTotalURLs,
+	getOtxUrls,
+}
+
+// OtherSourceOptions bounds an other-source lookup
+type OtherSourceOptions struct {
+	// Limit caps the unique URLs taken across providers, 0 for no cap
+	Limit int
+	// RPS caps the page requests per second sent to each provider, 0 for
+	// no cap
+	RPS float64
+}
+
 func OtherSources(domain string, includeSubs bool) []string {
-	noSubs := true
-	if includeSubs {
-		noSubs = false
-	}
 	var urls []string
+	StreamOtherSources(context.Background(), domain, includeSubs, OtherSourceOptions{}, func(u string) {
+		urls = append(urls, u)
+	})
+	return urls
+}
 
-	fetchFns := []fetchFn{
-		getWaybackURLs,
-		getCommonCrawlURLs,
-		getVirusTotalURLs,
-		getOtxUrls,
+// StreamOtherSources queries every provider at once and calls fn with each
+// unique URL as its page arrives, following Wayback's CDX resume key and
+// Common Crawl's index pages until a provider runs dry, opts.Limit URLs
+// were taken or ctx is done. fn is never called concurrently
+func StreamOtherSources(ctx context.Context, domain string, includeSubs bool, opts OtherSourceOptions, fn func(string)) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream := &otherSourceStream{
+		opts:   opts,
+		client: &http.Client{Timeout: otherSourceTimeout},
+		seen:   make(map[string]struct{}),
+		fn:     fn,
+		cancel: cancel,
 	}
 
 	var wg sync.WaitGroup
-
-	for _, fn := range fetchFns {
-		wUrlChan := make(chan wurl)
+	for _, fetch := range otherSourceFetchers {
 		wg.Add(1)
-		fetch := fn
-		go func() {
+		go func(fetch fetchFn) {
 			defer wg.Done()
-			resp, err := fetch(domain, noSubs)
-			if err != nil {
-				return
+			if err := fetch(ctx, stream.provider(), domain, !includeSubs); err != nil && ctx.Err() == nil {
+				Logger.Debugf("Other source lookup for %s failed: %s", domain, err)
 			}
-			for _, r := range resp {
-				wUrlChan <- r
-			}
-		}()
+		}(fetch)
+	}
+	wg.Wait()
+}
+
+// otherSourceStream dedups provider results, enforces the limit and hands
+// URLs to the caller
+type otherSourceStream struct {
+	opts   OtherSourceOptions
+	client *http.Client
+	cancel context.CancelFunc
+
+	mu    sync.Mutex
+	seen  map[string]struct{}
+	taken int
+
+	// fnMu serializes fn apart from mu, so a slow fn does not hold up the
+	// dedup of other providers' pages
+	fnMu sync.Mutex
+	fn   func(string)
+}
+
+// add passes w on unless it was seen before, and reports whether the
+// provider should keep going
+func (s *otherSourceStream) add(w wurl) bool {
+	s.mu.Lock()
+	if s.opts.Limit > 0 && s.taken >= s.opts.Limit {
+		s.mu.Unlock()
+		return false
+	}
+	if w.url == "" {
+		s.mu.Unlock()
+		return true
+	}
+	if _, ok := s.seen[w.url]; ok {
+		s.mu.Unlock()
+		return true
+	}
+	s.seen[w.url] = struct{}{}
+	s.taken++
+	last := s.opts.Limit > 0 && s.taken >= s.opts.Limit
+	s.mu.Unlock()
+
+	s.fnMu.Lock()
+	s.fn(w.url)
+	s.fnMu.Unlock()
+	if last {
+		s.cancel()
+		return false
+	}
+	return true
+}
 
-		go func() {
-			wg.Wait()
-			close(wUrlChan)
-		}()
+// provider returns a handle with its own rate limit for one provider
+func (s *otherSourceStream) provider() *otherSourceProvider {
+	p := &otherSourceProvider{stream: s}
+	if s.opts.RPS > 0 {
+		p.limiter = antidetect.NewRateLimiter(1, time.Duration(float64(time.Second)/s.opts.RPS))
+	}
+	return p
+}
+
+type otherSourceProvider struct {
+	stream  *otherSourceStream
+	limiter *antidetect.RateLimiter
+}
+
+func (p *otherSourceProvider) add(w wurl) bool {
+	return p.stream.add(w)
+}
 
-		for w := range wUrlChan {
-			urls = append(urls, w.url)
+// get fetches one page, waiting on the provider's rate limit and asking
+// again with backoff when the provider answers 429 or 5xx, so a long
+// pagination resumes where it was throttled instead of ending there
+func (p *otherSourceProvider) get(ctx context.Context, rawURL string) (*http.Response, error) {
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		if p.limiter != nil {
+			if err := p.limiter.WaitContext(ctx); err != nil {
+				return nil, err
+			}
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		res, err := p.stream.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode != http.StatusTooManyRequests && res.StatusCode < 500 {
+			return res, nil
 		}
+		res.Body.Close()
+		if attempt >= otherSourceRetries {
+			return nil, fmt.Errorf("%s: %s", rawURL, res.Status)
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff *= 2
 	}
-	return Unique(urls)
 }
 
 type wurl struct {
@@ -58,89 +193,149 @@ type wurl struct {
 	url  string
 }
 
-type fetchFn func(string, bool) ([]wurl, error)
+type fetchFn func(context.Context, *otherSourceProvider, string, bool) error
 
-func getWaybackURLs(domain string, noSubs bool) ([]wurl, error) {
-	subsWildcard := "*."
+func subsWildcard(noSubs bool) string {
 	if noSubs {
-		subsWildcard = ""
-	}
-	res, err := http.Get(
-		fmt.Sprintf("http://web.archive.org/cdx/search/cdx?url=%s%s/*&output=json&collapse=urlkey", subsWildcard, domain),
-	)
-	if err != nil {
-		return []wurl{}, err
+		return ""
 	}
+	return "*."
+}
 
-	raw, err := ioutil.ReadAll(res.Body)
-
-	res.Body.Close()
-	if err != nil {
-		return []wurl{}, err
-	}
-
-	var wrapper [][]string
-	err = json.Unmarshal(raw, &wrapper)
-
-	out := make([]wurl, 0, len(wrapper))
+// getWaybackURLs pages through the CDX API with showResumeKey: each page
+// ends with an empty row and a row holding the key of the next page
+func getWaybackURLs(ctx context.Context, p *otherSourceProvider, domain string, noSubs bool) error {
+	resumeKey := ""
+	for {
+		query := url.Values{}
+		query.Set("url", subsWildcard(noSubs)+domain+"/*")
+		query.Set("output", "json")
+		query.Set("collapse", "urlkey")
+		query.Set("fl", "timestamp,original")
+		query.Set("limit", strconv.Itoa(waybackPageSize))
+		query.Set("showResumeKey", "true")
+		if resumeKey != "" {
+			query.Set("resumeKey", resumeKey)
+		}
+		res, err := p.get(ctx, waybackCDXURL+"?"+query.Encode())
+		if err != nil {
+			return err
+		}
+		var rows [][]string
+		err = json.NewDecoder(res.Body).Decode(&rows)
+		res.Body.Close()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
 
-	skip := true
-	for _, urls := range wrapper {
-		// The first item is always just the string "original",
-		// so we should skip the first item
-		if skip {
-			skip = false
-			continue
+		resumeKey = ""
+		for i, row := range rows {
+			// The first row is the field names
+			if i == 0 {
+				continue
+			}
+			if len(row) == 0 {
+				if i+1 < len(rows) && len(rows[i+1]) == 1 {
+					resumeKey = rows[i+1][0]
+				}
+				break
+			}
+			if len(row) < 2 {
+				continue
+			}
+			if !p.add(wurl{date: row[0], url: row[1]}) {
+				return nil
+			}
+		}
+		if resumeKey == "" {
+			return nil
 		}
-		out = append(out, wurl{date: urls[1], url: urls[2]})
 	}
-
-	return out, nil
-
 }
 
-func getCommonCrawlURLs(domain string, noSubs bool) ([]wurl, error) {
-	subsWildcard := "*."
-	if noSubs {
-		subsWildcard = ""
-	}
-	res, err := http.Get(
-		fmt.Sprintf("http://index.commoncrawl.org/CC-MAIN-2019-51-index?url=%s%s/*&output=json", subsWildcard, domain),
-	)
+// commonCrawlAPI returns the CDX endpoint of the newest Common Crawl index,
+// or the default one when the collection list cannot be read
+func commonCrawlAPI(ctx context.Context, p *otherSourceProvider) string {
+	res, err := p.get(ctx, commonCrawlCollInfoURL)
 	if err != nil {
-		return []wurl{}, err
+		return commonCrawlDefaultAPI
 	}
-
 	defer res.Body.Close()
-	sc := bufio.NewScanner(res.Body)
-
-	out := make([]wurl, 0)
+	var indexes []struct {
+		API string `json:"cdx-api"`
+	}
+	if json.NewDecoder(res.Body).Decode(&indexes) != nil || len(indexes) == 0 || indexes[0].API == "" {
+		return commonCrawlDefaultAPI
+	}
+	return indexes[0].API
+}
 
-	for sc.Scan() {
-		wrapper := struct {
-			URL       string `json:"url"`
-			Timestamp string `json:"timestamp"`
-		}{}
-		err = json.Unmarshal([]byte(sc.Text()), &wrapper)
+// getCommonCrawlURLs asks the index how many pages match, then reads them
+// in order with the page parameter
+func getCommonCrawlURLs(ctx context.Context, p *otherSourceProvider, domain string, noSubs bool) error {
+	api := commonCrawlAPI(ctx, p)
+	query := url.Values{}
+	query.Set("url", subsWildcard(noSubs)+domain+"/*")
+	query.Set("output", "json")
+
+	count := url.Values{}
+	for k, v := range query {
+		count[k] = v
+	}
+	count.Set("showNumPages", "true")
+	res, err := p.get(ctx, api+"?"+count.Encode())
+	if err != nil {
+		return err
+	}
+	var info struct {
+		Pages int `json:"pages"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&info)
+	res.Body.Close()
+	if err != nil {
+		return err
+	}
 
+	for page := 0; page < info.Pages; page++ {
+		query.Set("page", strconv.Itoa(page))
+		res, err := p.get(ctx, api+"?"+query.Encode())
 		if err != nil {
+			return err
+		}
+		if res.StatusCode != http.StatusOK {
+			// No captures on this page
+			res.Body.Close()
 			continue
 		}
-
-		out = append(out, wurl{date: wrapper.Timestamp, url: wrapper.URL})
+		keepGoing := true
+		sc := bufio.NewScanner(res.Body)
+		sc.Buffer(make([]byte, 64*1024), 1024*1024)
+		for keepGoing && sc.Scan() {
+			wrapper := struct {
+				URL       string `json:"url"`
+				Timestamp string `json:"timestamp"`
+			}{}
+			if json.Unmarshal(sc.Bytes(), &wrapper) != nil {
+				continue
+			}
+			keepGoing = p.add(wurl{date: wrapper.Timestamp, url: wrapper.URL})
+		}
+		res.Body.Close()
+		if !keepGoing {
+			return nil
+		}
 	}
-
-	return out, nil
-
+	return nil
 }
 
-func getVirusTotalURLs(domain string, noSubs bool) ([]wurl, error) {
-	out := make([]wurl, 0)
-
+func getVirusTotalURLs(ctx context.Context, p *otherSourceProvider, domain string, noSubs bool) error {
 	apiKey := os.Getenv("VT_API_KEY")
 	if apiKey == "" {
 		Logger.Warnf("You are not set VirusTotal API Key yet.")
-		return out, nil
+		return nil
 	}
 
 	fetchURL := fmt.Sprintf(
@@ -149,9 +344,9 @@ func getVirusTotalURLs(domain string, noSubs bool) ([]wurl, error) {
 		domain,
 	)
 
-	resp, err := http.Get(fetchURL)
+	resp, err := p.get(ctx, fetchURL)
 	if err != nil {
-		return out, err
+		return err
 	}
 	defer resp.Body.Close()
 
@@ -166,25 +361,26 @@ func getVirusTotalURLs(domain string, noSubs bool) ([]wurl, error) {
 	err = dec.Decode(&wrapper)
 
 	for _, u := range wrapper.URLs {
-		out = append(out, wurl{url: u.URL})
+		if !p.add(wurl{url: u.URL}) {
+			break
+		}
 	}
 
-	return out, nil
+	return nil
 }
 
-func getOtxUrls(domain string, noSubs bool) ([]wurl, error) {
-	var urls []wurl
+func getOtxUrls(ctx context.Context, p *otherSourceProvider, domain string, noSubs bool) error {
 	page := 0
 	for {
-		r, err := http.Get(fmt.Sprintf("https://otx.alienvault.com/api/v1/indicators/hostname/%s/url_list?limit=50&page=%d", domain, page))
+		r, err := p.get(ctx, fmt.Sprintf("https://otx.alienvault.com/api/v1/indicators/hostname/%s/url_list?limit=50&page=%d", domain, page))
 		if err != nil {
-			return []wurl{}, err
+			return err
 		}
 		bytes, err := ioutil.ReadAll(r.Body)
+		r.Body.Close()
 		if err != nil {
-			return []wurl{}, err
+			return err
 		}
-		r.Body.Close()
 
 		wrapper := struct {
 			HasNext    bool `json:"has_next"`
@@ -201,15 +397,17 @@ func getOtxUrls(domain string, noSubs bool) ([]wurl, error) {
 		}{}
 		err = json.Unmarshal(bytes, &wrapper)
 		if err != nil {
-			return []wurl{}, err
+			return err
 		}
 		for _, url := range wrapper.URLList {
-			urls = append(urls, wurl{url: url.URL})
+			if !p.add(wurl{url: url.URL}) {
+				return nil
+			}
 		}
 		if !wrapper.HasNext {
 			break
 		}
 		page++
 	}
-	return urls, nil
+	return nil
 }
@@ -0,0 +1,133 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// archiveServer fakes a three page Wayback CDX and a two page Common Crawl
+// index, with one URL known to both
+func archiveServer(t *testing.T, requests *int32) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cdx", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		assert.Equal(t, "example.com/*", r.URL.Query().Get("url"))
+		rows := [][]string{{"timestamp", "original"}}
+		key := r.URL.Query().Get("resumeKey")
+		page := map[string]int{"": 0, "k1": 1, "k2": 2}[key]
+		for i := 0; i < 2; i++ {
+			rows = append(rows, []string{"2020", fmt.Sprintf("https://example.com/wb/%d/%d", page, i)})
+		}
+		if page < 2 {
+			rows = append(rows, []string{}, []string{fmt.Sprintf("k%d", page+1)})
+		}
+		_ = json.NewEncoder(w).Encode(rows)
+	})
+	mux.HandleFunc("/collinfo.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[{"id":"CC-NEW","cdx-api":"http://%s/cc"}]`, r.Host)
+	})
+	mux.HandleFunc("/cc", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		if r.URL.Query().Get("showNumPages") == "true" {
+			fmt.Fprint(w, `{"pages": 2, "pageSize": 5, "blocks": 7}`)
+			return
+		}
+		page := r.URL.Query().Get("page")
+		fmt.Fprintf(w, "{\"url\":\"https://example.com/cc/%s\",\"timestamp\":\"2021\"}\n", page)
+		fmt.Fprint(w, "{\"url\":\"https://example.com/wb/0/0\",\"timestamp\":\"2021\"}\n")
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	prev := []string{waybackCDXURL, commonCrawlCollInfoURL}
+	prevFetchers := otherSourceFetchers
+	waybackCDXURL = srv.URL + "/cdx"
+	commonCrawlCollInfoURL = srv.URL + "/collinfo.json"
+	otherSourceFetchers = []fetchFn{getWaybackURLs, getCommonCrawlURLs}
+	t.Cleanup(func() {
+		waybackCDXURL, commonCrawlCollInfoURL = prev[0], prev[1]
+		otherSourceFetchers = prevFetchers
+	})
+	return srv
+}
+
+func TestStreamOtherSourcesPaginates(t *testing.T) {
+	var requests int32
+	archiveServer(t, &requests)
+
+	var urls []string
+	StreamOtherSources(context.Background(), "example.com", false, OtherSourceOptions{}, func(u string) {
+		urls = append(urls, u)
+	})
+	assert.ElementsMatch(t, []string{
+		"https://example.com/wb/0/0", "https://example.com/wb/0/1",
+		"https://example.com/wb/1/0", "https://example.com/wb/1/1",
+		"https://example.com/wb/2/0", "https://example.com/wb/2/1",
+		"https://example.com/cc/0", "https://example.com/cc/1",
+	}, urls)
+	// Three CDX pages, the page count and two index pages
+	assert.Equal(t, int32(6), atomic.LoadInt32(&requests))
+}
+
+func TestStreamOtherSourcesLimit(t *testing.T) {
+	var requests int32
+	archiveServer(t, &requests)
+
+	var urls []string
+	StreamOtherSources(context.Background(), "example.com", false, OtherSourceOptions{Limit: 3}, func(u string) {
+		urls = append(urls, u)
+	})
+	assert.Len(t, urls, 3)
+}
+
+func TestStreamOtherSourcesCancel(t *testing.T) {
+	var requests int32
+	archiveServer(t, &requests)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	done := make(chan struct{})
+	var urls []string
+	go func() {
+		defer close(done)
+		// A slow rate limit would hold the second page if cancellation
+		// were ignored
+		StreamOtherSources(ctx, "example.com", false, OtherSourceOptions{RPS: 0.1}, func(u string) {
+			urls = append(urls, u)
+		})
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("lookup ignored cancellation")
+	}
+	assert.Empty(t, urls)
+}
+
+func TestOtherSourceProviderRetriesThrottledPage(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer srv.Close()
+
+	stream := &otherSourceStream{client: srv.Client(), seen: map[string]struct{}{}}
+	res, err := stream.provider().get(context.Background(), srv.URL)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
@@ -2,18 +2,50 @@ package core
 
 import (
 	"bufio"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
 
 	"github.com/jaeles-project/gospider/stringset"
+	jsoniter "github.com/json-iterator/go"
 )
 
+// Output dedup modes for --dedup-output
+const (
+	DedupOutputStream = "stream"
+	DedupOutputFinal  = "final"
+)
+
+// DefaultDedupFields are the JSON keys that identify a duplicate record
+var DefaultDedupFields = []string{"type", "output"}
+
+// splitOutputSuffixes names the split files of the most common output types;
+// any other type is used as the file suffix as is
+var splitOutputSuffixes = map[string]string{
+	"url":        "urls",
+	"subdomain":  "subdomains",
+	"js-request": "js-requests",
+	"form":       "forms",
+}
+
+var splitSuffixSanitizer = regexp.MustCompile(`[^a-z0-9_-]+`)
+
 type Output struct {
-	mu     sync.Mutex
-	f      *os.File
-	filter *stringset.StringFilter
+	mu          sync.Mutex
+	f           *os.File
+	path        string
+	filter      *stringset.StringFilter
+	dedupMode   string
+	dedupFields []string
+
+	// routes is set in split mode and holds one lazily opened file per output type
+	routes map[string]*Output
+	closed bool
+	// jsonl routes to <folder>/<type>.jsonl files and keeps JSON records only
+	jsonl bool
 }
 
 func NewOutput(folder, filename string) *Output {
@@ -23,15 +55,112 @@ func NewOutput(folder, filename string) *Output {
 	})
 }
 
+// NewSplitOutput writes each output type to its own <filename>.<type> file.
+// Files are only created once a record of their type is written
+func NewSplitOutput(folder, filename string) *Output {
+	return &Output{
+		path:   filepath.Join(folder, filename),
+		routes: make(map[string]*Output),
+	}
+}
+
+// NewJSONLOutput writes the JSON records of a whole run into folder, one
+// newline-delimited file per output type: urls.jsonl, forms.jsonl,
+// js-requests.jsonl and <type>.jsonl for the rest. Records of any site go to
+// the same files, and non-JSON lines such as --raw bodies are left out
+func NewJSONLOutput(folder string) *Output {
+	return &Output{
+		path:   folder,
+		routes: make(map[string]*Output),
+		jsonl:  true,
+	}
+}
+
+// jsonlOutput reports whether -o gets the typed JSONL files, which is when
+// the files are JSON and not split by host
+func (cfg *CrawlerConfig) jsonlOutput() bool {
+	return cfg.OutputDir != "" && cfg.FileFormat == OutputFormatJSON && !cfg.OutputByHost
+}
+
 func (o *Output) WriteToFile(msg string) {
+	o.WriteRecord("", msg)
+}
+
+// WriteRecord writes a rendered line of the given output type. Outside split
+// mode the type is ignored; untyped lines go to the bare <filename> file
+func (o *Output) WriteRecord(outputType, msg string) {
 	if strings.TrimSpace(msg) == "" {
 		return
 	}
+	if o.jsonl && !strings.HasPrefix(msg, "{") {
+		return
+	}
+	if o.routes != nil {
+		if dest := o.route(outputType); dest != nil {
+			dest.write(msg)
+		}
+		return
+	}
+	o.write(msg)
+}
+
+func (o *Output) route(outputType string) *Output {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return nil
+	}
+	if dest, ok := o.routes[outputType]; ok {
+		return dest
+	}
+
+	path := o.path
+	if o.jsonl {
+		suffix := "results"
+		if outputType != "" {
+			suffix = splitSuffix(outputType)
+		}
+		path = filepath.Join(path, suffix+".jsonl")
+	} else if outputType != "" {
+		path += "." + splitSuffix(outputType)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, os.ModePerm)
+	if err != nil {
+		Logger.Errorf("Failed to open file to write Output: %s", err)
+		// Remember the failure so the open is not retried for every record
+		o.routes[outputType] = nil
+		return nil
+	}
+	dest := &Output{
+		f:      f,
+		path:   path,
+		filter: stringset.NewStringFilter(),
+	}
+	dest.loadExisting(path)
+	if o.dedupMode != "" {
+		dest.EnableDedup(o.dedupMode, o.dedupFields)
+	}
+	o.routes[outputType] = dest
+	return dest
+}
+
+// splitSuffix names the split file of an output type
+func splitSuffix(outputType string) string {
+	if suffix, ok := splitOutputSuffixes[outputType]; ok {
+		return suffix
+	}
+	return splitSuffixSanitizer.ReplaceAllString(strings.ToLower(outputType), "_")
+}
 
+func (o *Output) write(msg string) {
 	o.mu.Lock()
 	defer o.mu.Unlock()
 
-	if o.filter != nil && o.filter.Duplicate(msg) {
+	if o.f == nil {
+		return
+	}
+	if o.filter != nil && o.filter.Duplicate(o.dedupKey(msg)) {
 		return
 	}
 
@@ -39,9 +168,139 @@ func (o *Output) WriteToFile(msg string) {
 }
 
 func (o *Output) Close() {
-	if o.f != nil {
-		_ = o.f.Close()
+	if o.routes != nil {
+		o.mu.Lock()
+		o.closed = true
+		routes := o.routes
+		o.mu.Unlock()
+		for _, dest := range routes {
+			if dest != nil {
+				dest.Close()
+			}
+		}
+		return
 	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.f == nil {
+		return
+	}
+	_ = o.f.Close()
+	o.f = nil
+
+	if o.dedupMode == DedupOutputFinal {
+		if err := o.rewriteUnique(); err != nil {
+			Logger.Errorf("Failed to dedup output %s: %s", o.path, err)
+		}
+	}
+}
+
+// EnableDedup switches duplicate detection from whole lines to records keyed
+// by fields. JSON lines are keyed by the given fields while plain lines still
+// have to match exactly. In final mode the file is also rewritten on Close,
+// keeping the richest record of each group.
+func (o *Output) EnableDedup(mode string, fields []string) {
+	if mode != DedupOutputStream && mode != DedupOutputFinal {
+		return
+	}
+	if len(fields) == 0 {
+		fields = DefaultDedupFields
+	}
+
+	o.mu.Lock()
+	o.dedupMode = mode
+	o.dedupFields = fields
+	o.filter = stringset.NewStringFilter()
+	o.mu.Unlock()
+
+	if !o.jsonl {
+		o.loadExisting(o.path)
+	}
+}
+
+// dedupKey is the key a line is deduplicated by, both while writing and
+// when the file is rewritten. Keys are case-insensitive
+func (o *Output) dedupKey(line string) string {
+	if o.dedupMode == "" || !strings.HasPrefix(line, "{") {
+		return strings.ToLower(line)
+	}
+	var record map[string]interface{}
+	if err := jsoniter.UnmarshalFromString(line, &record); err != nil {
+		return strings.ToLower(line)
+	}
+	parts := make([]string, 0, len(o.dedupFields))
+	for _, field := range o.dedupFields {
+		parts = append(parts, fmt.Sprint(record[field]))
+	}
+	return strings.ToLower(strings.Join(parts, "\x00"))
+}
+
+// recordRichness scores a line so the most informative duplicate survives
+func recordRichness(line string) int {
+	var record map[string]interface{}
+	if err := jsoniter.UnmarshalFromString(line, &record); err != nil {
+		return 0
+	}
+	score := 0
+	for key, value := range record {
+		switch v := value.(type) {
+		case string:
+			if v != "" {
+				score++
+			}
+		case float64:
+			if v != 0 {
+				score++
+				if key == "status" || key == "length" {
+					score += 2
+				}
+			}
+		case nil:
+		default:
+			score++
+		}
+	}
+	return score
+}
+
+func (o *Output) rewriteUnique() error {
+	data, err := os.ReadFile(o.path)
+	if err != nil {
+		return err
+	}
+
+	var order []string
+	best := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		key := o.dedupKey(line)
+		current, seen := best[key]
+		if !seen {
+			order = append(order, key)
+			best[key] = line
+			continue
+		}
+		if recordRichness(line) > recordRichness(current) {
+			best[key] = line
+		}
+	}
+
+	var sb strings.Builder
+	for _, key := range order {
+		sb.WriteString(best[key])
+		sb.WriteByte('\n')
+	}
+
+	tmp := o.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(sb.String()), os.ModePerm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, o.path)
 }
 
 func NewOutputPath(filePath string) *Output {
@@ -66,9 +325,22 @@ func newOutput(outFile string, opener func(string) (*os.File, error)) *Output {
 		Logger.Errorf("Failed to open file to write Output: %s", err)
 		os.Exit(1)
 	}
+	return wrapOutput(f, outFile)
+}
+
+// openOutput is NewOutput for files opened mid-crawl, which must not exit
+func openOutput(outFile string) (*Output, error) {
+	f, err := os.OpenFile(outFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+	return wrapOutput(f, outFile), nil
+}
 
+func wrapOutput(f *os.File, outFile string) *Output {
 	out := &Output{
 		f:      f,
+		path:   outFile,
 		filter: stringset.NewStringFilter(),
 	}
 	out.loadExisting(outFile)
@@ -89,7 +361,7 @@ func (o *Output) loadExisting(path string) {
 			continue
 		}
 		if o.filter != nil {
-			_ = o.filter.Duplicate(line)
+			_ = o.filter.Duplicate(o.dedupKey(line))
 		}
 	}
 }
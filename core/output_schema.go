@@ -0,0 +1,85 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// SpiderOutputSchemaVersion is written to every JSON record. Bump it whenever
+// a SpiderOutput field is added, removed, renamed or changes meaning
+const SpiderOutputSchemaVersion = "1.2"
+
+// SpiderOutput is a single crawl result, the unit of --json output and of
+// OutputSink. Every field is always present in JSON; unused ones are "" or 0
+type SpiderOutput struct {
+	Schema     string `json:"schema"`
+	Input      string `json:"input"`
+	Source     string `json:"source"`
+	OutputType string `json:"type"`
+	Output     string `json:"output"`
+	StatusCode int    `json:"status"`
+	Length     int    `json:"length"`
+	Param      string `json:"param"`
+	Payload    string `json:"payload"`
+	Confidence string `json:"confidence"`
+	Snippet    string `json:"snippet"`
+	Depth      int    `json:"depth"`
+}
+
+// spiderOutputFieldDocs describes each JSON field for the published schema
+var spiderOutputFieldDocs = map[string]string{
+	"schema":     "SpiderOutput schema version of this record",
+	"input":      "Target the crawl was started from",
+	"source":     "Where the result was found, e.g. body, robots, sitemap, or the referring URL",
+	"type":       "Kind of result, e.g. url, href, form, javascript, subdomain, reflected",
+	"output":     "The result itself, usually a URL",
+	"status":     "HTTP status code of the result, 0 when it was not requested",
+	"length":     "Response size (line count for crawled pages), 0 when unknown",
+	"param":      "Parameter or sink name for reflection, DOM sink and OIDC findings; error class for error findings; pattern name for match findings; script or stylesheet for dependency findings; operation for graphql findings; rule for secret findings; provider for cloud-storage findings",
+	"payload":    "Payload or code snippet that triggered the finding; integrity hash for dependency findings; GraphQL document for graphql findings",
+	"confidence": "Confidence of DOM sink, sensitive-file and secret findings",
+	"snippet":    "Supporting excerpt such as a redirect chain, DOM snippet or error message; bucket and region for cloud-storage findings",
+	"depth":      "Crawl depth the output was or would be requested at, 1 for the start URL; 0 when not reached by crawling",
+}
+
+// MarshalJSON stamps the schema version so every emit path agrees on it
+func (o SpiderOutput) MarshalJSON() ([]byte, error) {
+	type plain SpiderOutput
+	o.Schema = SpiderOutputSchemaVersion
+	return jsoniter.Marshal(plain(o))
+}
+
+// SpiderOutputSchema returns the JSON Schema describing --json records
+func SpiderOutputSchema() map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+	t := reflect.TypeOf(SpiderOutput{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		prop := map[string]interface{}{"description": spiderOutputFieldDocs[name]}
+		switch field.Type.Kind() {
+		case reflect.Int, reflect.Int64:
+			prop["type"] = "integer"
+		default:
+			prop["type"] = "string"
+		}
+		if name == "schema" {
+			prop["const"] = SpiderOutputSchemaVersion
+		}
+		properties[name] = prop
+		required = append(required, name)
+	}
+	return map[string]interface{}{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"$id":                  "https://github.com/jaeles-project/gospider/schema/spider-output/" + SpiderOutputSchemaVersion,
+		"title":                "SpiderOutput",
+		"description":          "A single gospider result as emitted by --json",
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
@@ -0,0 +1,37 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpiderOutputJSONMatchesSchema(t *testing.T) {
+	data, err := jsoniter.MarshalToString(SpiderOutput{Input: "https://example.com", OutputType: "url", Output: "https://example.com/a"})
+	require.NoError(t, err)
+
+	var record map[string]interface{}
+	require.NoError(t, jsoniter.UnmarshalFromString(data, &record))
+	assert.Equal(t, SpiderOutputSchemaVersion, record["schema"])
+
+	schema := SpiderOutputSchema()
+	properties := schema["properties"].(map[string]interface{})
+	assert.Len(t, record, len(properties), "every field is always present")
+	for name, prop := range properties {
+		assert.Contains(t, record, name)
+		assert.NotEmpty(t, prop.(map[string]interface{})["description"], name)
+	}
+	assert.ElementsMatch(t, schema["required"], keysOf(properties))
+	assert.True(t, strings.HasSuffix(schema["$id"].(string), SpiderOutputSchemaVersion))
+}
+
+func keysOf(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
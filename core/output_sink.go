@@ -0,0 +1,356 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// OutputSink receives the findings of a crawl that pass the output
+// filters, in the order they are found. Sinks in CrawlerConfig.OutputSinks
+// are shared by every crawler of a run, so Write must be safe for
+// concurrent use; they are closed once when the run ends
+type OutputSink interface {
+	Write(SpiderOutput) error
+	Close()
+}
+
+// lineSink is implemented by the built-in sinks, which render the
+// "[type] - ..." text and bare plain value the finding was emitted with
+// rather than rebuilding them from its fields
+type lineSink interface {
+	writeLine(sout SpiderOutput, text, plain string) error
+}
+
+// OutputSinkFactory opens a sink from the argument of a --sink name:arg value
+type OutputSinkFactory func(arg string) (OutputSink, error)
+
+var (
+	outputSinkMu        sync.RWMutex
+	outputSinkFactories = map[string]OutputSinkFactory{}
+)
+
+// RegisterOutputSink makes a sink available to --sink under name,
+// replacing any sink registered under it before. Call it from an init
+// function of the program embedding gospider
+func RegisterOutputSink(name string, factory OutputSinkFactory) {
+	outputSinkMu.Lock()
+	defer outputSinkMu.Unlock()
+	outputSinkFactories[name] = factory
+}
+
+// OutputSinkNames returns the names --sink accepts, sorted
+func OutputSinkNames() []string {
+	outputSinkMu.RLock()
+	defer outputSinkMu.RUnlock()
+	names := make([]string, 0, len(outputSinkFactories))
+	for name := range outputSinkFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OpenOutputSink opens a sink from a --sink value such as
+// "webhook:https://hooks.example.com/x". The part after the first colon is
+// handed to the sink as is
+func OpenOutputSink(spec string) (OutputSink, error) {
+	name, arg, _ := strings.Cut(spec, ":")
+	outputSinkMu.RLock()
+	factory, ok := outputSinkFactories[name]
+	outputSinkMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown sink %q (available: %s)", name, strings.Join(OutputSinkNames(), ", "))
+	}
+	return factory(arg)
+}
+
+func init() {
+	RegisterOutputSink("stdout", func(format string) (OutputSink, error) {
+		if format == "" {
+			format = OutputFormatJSON
+		}
+		if _, _, err := ResolveOutputFormats(format, "", false, false, false); err != nil {
+			return nil, err
+		}
+		return NewStdoutSink(os.Stdout, format, nil), nil
+	})
+	RegisterOutputSink("file", func(path string) (OutputSink, error) {
+		if path == "" {
+			return nil, fmt.Errorf("the file sink needs a path, e.g. file:results.jsonl")
+		}
+		return NewFileSink(NewOutputPath(path), OutputFormatJSON, nil), nil
+	})
+	RegisterOutputSink("webhook", func(endpoint string) (OutputSink, error) {
+		if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+			return nil, fmt.Errorf("the webhook sink needs an http(s) URL, e.g. webhook:https://hooks.example.com/gospider")
+		}
+		return NewWebhookSink(endpoint), nil
+	})
+}
+
+// defaultText is the line of a finding written by a sink outside emit
+func defaultText(sout SpiderOutput) string {
+	return fmt.Sprintf("[%s] - %s", sout.OutputType, sout.Output)
+}
+
+// StdoutSink prints one rendered line per finding
+type StdoutSink struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format string
+	tmpl   *template.Template
+}
+
+// NewStdoutSink prints findings to w in format (text, plain or json),
+// rendered with tmpl when it is not nil
+func NewStdoutSink(w io.Writer, format string, tmpl *template.Template) *StdoutSink {
+	return &StdoutSink{w: w, format: format, tmpl: tmpl}
+}
+
+func (s *StdoutSink) Write(sout SpiderOutput) error {
+	return s.writeLine(sout, defaultText(sout), sout.Output)
+}
+
+// writeLine skips findings that render empty, such as those without a bare
+// value in the plain format
+func (s *StdoutSink) writeLine(sout SpiderOutput, text, plain string) error {
+	line := renderLine(s.format, s.tmpl, sout, text, plain)
+	if line == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := fmt.Fprintln(s.w, line)
+	return err
+}
+
+func (s *StdoutSink) Close() {}
+
+// FileSink writes one rendered line per finding to an Output
+type FileSink struct {
+	format string
+	tmpl   *template.Template
+	// route picks the file of a finding; the crawler's -o sink routes by
+	// host with --output-by-host
+	route func(SpiderOutput) *Output
+	// owned is closed with the sink
+	owned *Output
+}
+
+// NewFileSink writes findings to output in format (text, plain or json),
+// rendered with tmpl when it is not nil. Closing the sink closes output
+func NewFileSink(output *Output, format string, tmpl *template.Template) *FileSink {
+	return &FileSink{
+		format: format,
+		tmpl:   tmpl,
+		route:  func(SpiderOutput) *Output { return output },
+		owned:  output,
+	}
+}
+
+func (s *FileSink) Write(sout SpiderOutput) error {
+	return s.writeLine(sout, defaultText(sout), sout.Output)
+}
+
+// writeLine falls back to text for findings without a plain value, so the
+// file never loses records
+func (s *FileSink) writeLine(sout SpiderOutput, text, plain string) error {
+	output := s.route(sout)
+	if output == nil {
+		return nil
+	}
+	line := renderLine(s.format, s.tmpl, sout, text, plain)
+	if line == "" {
+		line = text
+	}
+	output.WriteRecord(sout.OutputType, line)
+	return nil
+}
+
+func (s *FileSink) Close() {
+	if s.owned != nil {
+		s.owned.Close()
+	}
+}
+
+const (
+	// webhookBatch is the most findings one webhook request carries
+	webhookBatch = 100
+	// webhookFlush is the longest a finding waits for its batch to fill
+	webhookFlush = 2 * time.Second
+	// webhookAttempts is how often a batch is posted before it is dropped
+	webhookAttempts = 4
+)
+
+// WebhookSink posts findings to an HTTP endpoint as JSON arrays of
+// records, in batches of up to webhookBatch sent at least every
+// webhookFlush. Failed posts are retried with a doubling delay; while a
+// batch is retried Write blocks once the queue is full, so a slow endpoint
+// slows the crawl down instead of losing findings. Batches that still fail
+// are logged and dropped
+type WebhookSink struct {
+	endpoint string
+	client   *http.Client
+	// retryDelay is the wait before the first retry
+	retryDelay time.Duration
+	// encode renders a batch as the body of a request of contentType
+	encode      func([]SpiderOutput) ([]byte, error)
+	contentType string
+
+	mu      sync.Mutex
+	closed  bool
+	records chan SpiderOutput
+	done    chan struct{}
+}
+
+// NewWebhookSink starts a sink posting to endpoint
+func NewWebhookSink(endpoint string) *WebhookSink {
+	return newWebhookSink(endpoint, time.Second)
+}
+
+func newWebhookSink(endpoint string, retryDelay time.Duration) *WebhookSink {
+	return startBatchSink(&WebhookSink{
+		endpoint:    endpoint,
+		retryDelay:  retryDelay,
+		encode:      func(batch []SpiderOutput) ([]byte, error) { return jsoniter.Marshal(batch) },
+		contentType: "application/json",
+	})
+}
+
+// startBatchSink fills in the queue of a sink and starts posting
+func startBatchSink(s *WebhookSink) *WebhookSink {
+	s.client = &http.Client{Timeout: 10 * time.Second}
+	s.records = make(chan SpiderOutput, webhookBatch)
+	s.done = make(chan struct{})
+	go s.loop()
+	return s
+}
+
+// Write queues a finding, waiting while the queue is full
+func (s *WebhookSink) Write(sout SpiderOutput) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return fmt.Errorf("webhook sink closed")
+	}
+	s.records <- sout
+	return nil
+}
+
+// Close posts what is queued and waits for it
+func (s *WebhookSink) Close() {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.records)
+	}
+	s.mu.Unlock()
+	<-s.done
+}
+
+func (s *WebhookSink) loop() {
+	defer close(s.done)
+	ticker := time.NewTicker(webhookFlush)
+	defer ticker.Stop()
+	batch := make([]SpiderOutput, 0, webhookBatch)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.send(batch); err != nil {
+			Logger.Errorf("Failed to post %d findings to %s: %s", len(batch), s.endpoint, err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case sout, ok := <-s.records:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, sout)
+			if len(batch) == webhookBatch {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// send posts a batch, retrying network errors, 429s and 5xx responses
+func (s *WebhookSink) send(batch []SpiderOutput) error {
+	body, err := s.encode(batch)
+	if err != nil {
+		return err
+	}
+	delay := s.retryDelay
+	for attempt := 1; ; attempt++ {
+		retry, err := s.post(body)
+		if err == nil || !retry || attempt == webhookAttempts {
+			return err
+		}
+		Logger.Debugf("Posting findings to %s failed (%s), retrying in %s", s.endpoint, err, delay)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// post reports whether a failed request is worth retrying
+func (s *WebhookSink) post(body []byte) (bool, error) {
+	resp, err := s.client.Post(s.endpoint, s.contentType, bytes.NewReader(body))
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return false, nil
+}
+
+// outputSinks returns the sinks a finding is written to: stdout or the Sink
+// that takes its place, the -o files, then the configured sinks
+func (crawler *Crawler) outputSinks() []OutputSink {
+	stdoutFormat, fileFormat := crawler.outputFormats()
+	sinks := make([]OutputSink, 0, 2+len(crawler.extraSinks))
+	if crawler.sink != nil {
+		sinks = append(sinks, crawler.sink)
+	} else {
+		sinks = append(sinks, NewStdoutSink(os.Stdout, stdoutFormat, crawler.outputTemplate))
+	}
+	if crawler.Output != nil || crawler.hostOutputs != nil {
+		sinks = append(sinks, &FileSink{format: fileFormat, tmpl: crawler.outputTemplate, route: crawler.outputFor})
+	}
+	return append(sinks, crawler.extraSinks...)
+}
+
+// outputSinkSpecs returns the --sink values, with --webhook-url as one more
+// webhook sink
+func (cfg *CrawlerConfig) outputSinkSpecs() []string {
+	specs := cfg.SinkSpecs
+	if cfg.WebhookURL != "" {
+		specs = append(specs[:len(specs):len(specs)], "webhook:"+cfg.WebhookURL)
+	}
+	return specs
+}
+
+// closeOutputSinks closes the configured sinks, once per sink
+func closeOutputSinks(sinks []OutputSink) {
+	for _, sink := range sinks {
+		sink.Close()
+	}
+}
@@ -0,0 +1,182 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memorySink struct {
+	mu      sync.Mutex
+	results []SpiderOutput
+	closed  bool
+}
+
+func (s *memorySink) Write(sout SpiderOutput) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results = append(s.results, sout)
+	return nil
+}
+
+func (s *memorySink) Close() { s.closed = true }
+
+func TestEmitWritesOutputSinks(t *testing.T) {
+	custom := &memorySink{}
+	crawler := &Crawler{Input: "http://example.com", sink: &recordingSink{}, extraSinks: []OutputSink{custom}, excludeStatus: statusSet([]int{404})}
+
+	crawler.emit(SpiderOutput{Input: crawler.Input, Output: "http://example.com/a", OutputType: "url", StatusCode: 200}, "[url] - http://example.com/a", "http://example.com/a")
+	crawler.emit(SpiderOutput{Input: crawler.Input, Output: "http://example.com/b", OutputType: "url", StatusCode: 404}, "[url] - http://example.com/b", "http://example.com/b")
+
+	require.Len(t, custom.results, 1)
+	assert.Equal(t, "http://example.com/a", custom.results[0].Output)
+}
+
+func TestStdoutSinkFormats(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewStdoutSink(&buf, OutputFormatText, nil)
+	require.NoError(t, sink.Write(SpiderOutput{Output: "http://example.com/a", OutputType: "url"}))
+	assert.Equal(t, "[url] - http://example.com/a\n", buf.String())
+
+	buf.Reset()
+	tmpl, err := ParseOutputTemplate("{{.OutputType}} {{host .Output}}")
+	require.NoError(t, err)
+	sink = NewStdoutSink(&buf, OutputFormatPlain, tmpl)
+	require.NoError(t, sink.Write(SpiderOutput{Output: "http://example.com/a", OutputType: "url"}))
+	assert.Equal(t, "url example.com\n", buf.String())
+}
+
+func TestWebhookSinkBatches(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]SpiderOutput
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var batch []SpiderOutput
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&batch))
+		mu.Lock()
+		batches = append(batches, batch)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	sink, err := OpenOutputSink("webhook:" + srv.URL)
+	require.NoError(t, err)
+	for i := 0; i < webhookBatch+5; i++ {
+		require.NoError(t, sink.Write(SpiderOutput{Output: "http://example.com/", OutputType: "url"}))
+	}
+	sink.Close()
+	assert.Error(t, sink.Write(SpiderOutput{}))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, batches, 2)
+	assert.Len(t, batches[0], webhookBatch)
+	assert.Len(t, batches[1], 5)
+	assert.Equal(t, SpiderOutputSchemaVersion, batches[0][0].Schema)
+}
+
+func TestOpenOutputSink(t *testing.T) {
+	custom := &memorySink{}
+	RegisterOutputSink("memory-test", func(arg string) (OutputSink, error) {
+		assert.Equal(t, "a:b", arg)
+		return custom, nil
+	})
+	sink, err := OpenOutputSink("memory-test:a:b")
+	require.NoError(t, err)
+	assert.Same(t, custom, sink)
+	assert.Contains(t, OutputSinkNames(), "webhook")
+
+	_, err = OpenOutputSink("nope:x")
+	assert.ErrorContains(t, err, "unknown sink")
+	_, err = OpenOutputSink("webhook:ftp://example.com")
+	assert.Error(t, err)
+	_, err = OpenOutputSink("stdout:xml")
+	assert.Error(t, err)
+}
+
+func TestWebhookSinkRetries(t *testing.T) {
+	var mu sync.Mutex
+	var statuses []int
+	received := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		status := http.StatusOK
+		switch len(statuses) {
+		case 0:
+			status = http.StatusServiceUnavailable
+		case 1:
+			status = http.StatusTooManyRequests
+		}
+		statuses = append(statuses, status)
+		if status == http.StatusOK {
+			var batch []SpiderOutput
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&batch))
+			received += len(batch)
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	sink := newWebhookSink(srv.URL, time.Millisecond)
+	require.NoError(t, sink.Write(SpiderOutput{Output: "http://example.com/", OutputType: "url"}))
+	sink.Close()
+	mu.Lock()
+	assert.Equal(t, []int{503, 429, 200}, statuses)
+	assert.Equal(t, 1, received)
+	statuses = []int{http.StatusOK, http.StatusOK}
+	mu.Unlock()
+
+	// Client errors are not retried
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		statuses = append(statuses, http.StatusBadRequest)
+		mu.Unlock()
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	sink = newWebhookSink(srv.URL, time.Millisecond)
+	require.NoError(t, sink.Write(SpiderOutput{Output: "http://example.com/", OutputType: "url"}))
+	sink.Close()
+	mu.Lock()
+	assert.Len(t, statuses, 3)
+	mu.Unlock()
+}
+
+func TestCrawlWebhookURL(t *testing.T) {
+	var mu sync.Mutex
+	var posted []string
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []SpiderOutput
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&batch))
+		mu.Lock()
+		for _, sout := range batch {
+			posted = append(posted, sout.Output)
+		}
+		mu.Unlock()
+	}))
+	defer hook.Close()
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><a href="/a">a</a></body></html>`)
+	}))
+	defer site.Close()
+
+	results := crawlAll(t, site.URL, CrawlerConfig{WebhookURL: hook.URL})
+	var streamed []string
+	for _, sout := range results {
+		streamed = append(streamed, sout.Output)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.NotEmpty(t, streamed)
+	assert.ElementsMatch(t, streamed, posted)
+}
@@ -63,3 +63,182 @@ func TestOutputLoadsExistingEntries(t *testing.T) {
 		}
 	}
 }
+
+func TestOutputDedupFinalKeepsRichestRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	out := NewOutput(dir, "records.txt")
+	out.EnableDedup(DedupOutputFinal, nil)
+
+	out.WriteToFile(`{"input":"a","source":"body","type":"url","output":"http://a/x","status":0,"length":0}`)
+	out.WriteToFile(`{"input":"a","source":"body","type":"url","output":"http://a/y","status":200,"length":3}`)
+	out.Close()
+
+	// a richer duplicate written by another run is collapsed on rewrite
+	f, err := os.OpenFile(filepath.Join(dir, "records.txt"), os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatalf("failed to reopen output file: %v", err)
+	}
+	_, _ = f.WriteString(`{"input":"a","source":"body","type":"url","output":"http://a/x","status":200,"length":12}` + "\n")
+	f.Close()
+
+	out = NewOutput(dir, "records.txt")
+	out.EnableDedup(DedupOutputFinal, nil)
+	out.WriteToFile(`{"input":"b","source":"href","type":"url","output":"http://a/y","status":0,"length":0}`)
+	out.Close()
+
+	data, err := os.ReadFile(filepath.Join(dir, "records.txt"))
+	if err != nil {
+		t.Fatalf("failed to read output file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	want := []string{
+		`{"input":"a","source":"body","type":"url","output":"http://a/x","status":200,"length":12}`,
+		`{"input":"a","source":"body","type":"url","output":"http://a/y","status":200,"length":3}`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d lines, got %d: %v", len(want), len(lines), lines)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Fatalf("line %d: expected %s, got %s", i, want[i], lines[i])
+		}
+	}
+}
+
+func TestSplitOutputRoutesByType(t *testing.T) {
+	dir := t.TempDir()
+
+	out := NewSplitOutput(dir, "example_com")
+	out.WriteRecord("url", "[url] - http://example.com/a")
+	out.WriteRecord("url", "[url] - http://example.com/a")
+	out.WriteRecord("subdomain", "[subdomains] - api.example.com")
+	out.WriteRecord("js-request", "[js-request] - POST http://example.com/api")
+	out.WriteRecord("dom-sink", "[dom-sink] - http://example.com/ innerHTML")
+	out.Close()
+	out.WriteRecord("url", "[url] - http://example.com/late")
+
+	want := map[string]string{
+		"example_com.urls":        "[url] - http://example.com/a",
+		"example_com.subdomains":  "[subdomains] - api.example.com",
+		"example_com.js-requests": "[js-request] - POST http://example.com/api",
+		"example_com.dom-sink":    "[dom-sink] - http://example.com/ innerHTML",
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to list output dir: %v", err)
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d files, got %d", len(want), len(entries))
+	}
+	for name, line := range want {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if got := strings.TrimSpace(string(data)); got != line {
+			t.Fatalf("%s: expected %q, got %q", name, line, got)
+		}
+	}
+}
+
+func TestOutputByHostRoutesByFindingHost(t *testing.T) {
+	dir := t.TempDir()
+
+	hosts := NewHostOutputs(dir, false, "", nil)
+	crawler := &Crawler{hostOutputs: hosts}
+	crawler.Output = hosts.Get("example.com")
+	records := []SpiderOutput{
+		{OutputType: "url", Output: "http://example.com/a"},
+		{OutputType: "url", Output: "https://api.example.com/v1"},
+		{OutputType: "subdomain", Output: "api.example.com"},
+		{OutputType: "form", Output: "no url here"},
+	}
+	for _, sout := range records {
+		crawler.outputFor(sout).WriteRecord(sout.OutputType, sout.Output)
+	}
+	hosts.Close()
+
+	want := map[string]string{
+		"example_com":     "http://example.com/a\nno url here",
+		"api_example_com": "https://api.example.com/v1\napi.example.com",
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to list output dir: %v", err)
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d files, got %d", len(want), len(entries))
+	}
+	for name, lines := range want {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if got := strings.TrimSpace(string(data)); got != lines {
+			t.Fatalf("%s: expected %q, got %q", name, lines, got)
+		}
+	}
+}
+
+func TestJSONLOutputWritesTypedFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	out := NewJSONLOutput(dir)
+	out.EnableDedup(DedupOutputStream, nil)
+	out.WriteRecord("url", `{"type":"url","output":"http://example.com/a"}`)
+	out.WriteRecord("url", `{"type":"url","output":"http://example.com/a","status":200}`)
+	out.WriteRecord("url", `{"type":"url","output":"http://other.example/b"}`)
+	out.WriteRecord("form", `{"type":"form","output":"http://example.com/login"}`)
+	out.WriteRecord("js-request", `{"type":"js-request","output":"POST http://example.com/api"}`)
+	out.WriteRecord("raw", "[Raw] - \n<html></html>\n")
+	out.Close()
+
+	want := map[string]string{
+		"urls.jsonl":        "{\"type\":\"url\",\"output\":\"http://example.com/a\"}\n{\"type\":\"url\",\"output\":\"http://other.example/b\"}",
+		"forms.jsonl":       `{"type":"form","output":"http://example.com/login"}`,
+		"js-requests.jsonl": `{"type":"js-request","output":"POST http://example.com/api"}`,
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to list output dir: %v", err)
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d files, got %d", len(want), len(entries))
+	}
+	for name, lines := range want {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if got := strings.TrimSpace(string(data)); got != lines {
+			t.Fatalf("%s: expected %q, got %q", name, lines, got)
+		}
+	}
+}
+
+func TestOutputDedupModesAgreeOnKeys(t *testing.T) {
+	lines := []string{
+		`{"type":"url","output":"http://example.com/a?ID=1"}`,
+		`{"type":"url","output":"http://EXAMPLE.com/a?id=1","status":200}`,
+		`[url] - http://example.com/a`,
+		`[url] - http://EXAMPLE.com/a`,
+	}
+	count := func(mode string) int {
+		dir := t.TempDir()
+		out := NewOutput(dir, "records.txt")
+		out.EnableDedup(mode, nil)
+		for _, line := range lines {
+			out.WriteToFile(line)
+		}
+		out.Close()
+		data, err := os.ReadFile(filepath.Join(dir, "records.txt"))
+		if err != nil {
+			t.Fatalf("failed to read output file: %v", err)
+		}
+		return len(strings.Split(strings.TrimSpace(string(data)), "\n"))
+	}
+	if stream, final := count(DedupOutputStream), count(DedupOutputFinal); stream != 2 || final != 2 {
+		t.Fatalf("stream and final dedup should both keep 2 lines, got %d and %d", stream, final)
+	}
+}
@@ -0,0 +1,162 @@
+package core
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+)
+
+var (
+	pageNumberParams = map[string]bool{"page": true, "p": true, "pg": true, "pagenum": true, "page_num": true, "pageno": true}
+	pageOffsetParams = map[string]bool{"offset": true, "start": true, "skip": true, "from": true}
+	pageSizeParams   = []string{"limit", "per_page", "perpage", "page_size", "pagesize", "size", "count", "rows"}
+)
+
+var nextPageTextRegex = regexp.MustCompile(`(?i)^(next|next page|more|load more|show more|older|older posts|›|»|>|>>)$`)
+
+// NextPageURL guesses the following page of a paginated URL by incrementing
+// a page number, or an offset by the page size. Offsets without an explicit
+// size are only stepped once the current offset reveals it
+func NextPageURL(u *url.URL) (string, bool) {
+	if u == nil || u.RawQuery == "" {
+		return "", false
+	}
+	query := u.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		name := strings.ToLower(key)
+		n, err := strconv.Atoi(query.Get(key))
+		if err != nil || n < 0 {
+			continue
+		}
+		switch {
+		case pageNumberParams[name]:
+			query.Set(key, strconv.Itoa(n+1))
+		case pageOffsetParams[name]:
+			step := n
+			for _, sizeParam := range pageSizeParams {
+				if size, err := strconv.Atoi(query.Get(sizeParam)); err == nil && size > 0 {
+					step = size
+					break
+				}
+			}
+			if step <= 0 {
+				continue
+			}
+			query.Set(key, strconv.Itoa(n+step))
+		default:
+			continue
+		}
+		next := *u
+		next.RawQuery = query.Encode()
+		next.Fragment = ""
+		return next.String(), true
+	}
+	return "", false
+}
+
+// paginationKey identifies the listing a page belongs to, so every page of
+// it shares one --max-pages-follow budget
+func paginationKey(u *url.URL) string {
+	query := u.Query()
+	for key := range query {
+		name := strings.ToLower(key)
+		if pageNumberParams[name] || pageOffsetParams[name] {
+			query.Del(key)
+		}
+	}
+	return strings.ToLower(u.Host) + u.Path + "?" + query.Encode()
+}
+
+// isNextPageLink reports whether an element points at the next page of a listing
+func isNextPageLink(e *colly.HTMLElement) bool {
+	for _, rel := range strings.Fields(strings.ToLower(e.Attr("rel"))) {
+		if rel == "next" {
+			return true
+		}
+	}
+	label := strings.TrimSpace(e.Attr("aria-label"))
+	if label == "" {
+		label = strings.Join(strings.Fields(e.Text), " ")
+	}
+	return nextPageTextRegex.MatchString(label)
+}
+
+// registerPagination follows next-page links and page/offset increments at
+// the depth of the page they were found on, up to maxPagesFollow pages per
+// listing. It must run before the [href] handler claims the same links
+func (crawler *Crawler) registerPagination() {
+	if crawler.maxPagesFollow <= 0 {
+		return
+	}
+
+	crawler.C.OnHTML("a[href], link[href], button", func(e *colly.HTMLElement) {
+		if crawler.stopped.Load() || !isNextPageLink(e) || crawler.isSoft404Response(e.Response) {
+			return
+		}
+		target := e.Attr("href")
+		if e.Name == "button" {
+			target = e.Attr("formaction")
+			for _, attr := range []string{"data-href", "data-url", "data-next"} {
+				if target != "" {
+					break
+				}
+				target = e.Attr(attr)
+			}
+		}
+		if target != "" {
+			crawler.followPage(target, crawler.documentBase(e), e.Request)
+		}
+	})
+
+	crawler.C.OnResponse(func(response *colly.Response) {
+		if crawler.stopped.Load() || len(response.Body) == 0 || response.Request.Method != http.MethodGet {
+			return
+		}
+		// A page past the end that gets the catch-all page ends the listing
+		if next, ok := NextPageURL(response.Request.URL); ok && !crawler.isSoft404Response(response) {
+			crawler.followPage(next, response.Request.URL, response.Request)
+		}
+	})
+}
+
+func (crawler *Crawler) followPage(raw string, base *url.URL, r *colly.Request) {
+	resolved, ok := NormalizeURL(base, raw)
+	if !ok {
+		return
+	}
+	u, err := url.Parse(resolved)
+	if err != nil {
+		return
+	}
+	key := paginationKey(u)
+
+	crawler.pagesMutex.Lock()
+	urlToVisit := ""
+	if crawler.pagesFollowed[key] < crawler.maxPagesFollow {
+		urlToVisit = crawler.urlProcessor.ProcessWithBase(raw, r.URL.String(), "pagination", base, r)
+		if urlToVisit != "" {
+			crawler.pagesFollowed[key]++
+		}
+	}
+	// Released before the request, which runs inline in deterministic mode
+	crawler.pagesMutex.Unlock()
+	if urlToVisit == "" {
+		return
+	}
+
+	// Later pages sit at the depth of the first, so MaxDepth does not cut the listing short
+	ctx := colly.NewContext()
+	ctx.Put("__depth", strconv.Itoa(r.Depth))
+	_ = crawler.C.Request(http.MethodGet, urlToVisit, nil, ctx, nil)
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextPageURL(t *testing.T) {
+	for raw, want := range map[string]string{
+		"https://example.com/posts?page=2&sort=new":    "https://example.com/posts?page=3&sort=new",
+		"https://example.com/posts?offset=20&limit=10": "https://example.com/posts?limit=10&offset=30",
+		"https://example.com/posts?start=25":           "https://example.com/posts?start=50",
+	} {
+		u, _ := url.Parse(raw)
+		next, ok := NextPageURL(u)
+		assert.True(t, ok, raw)
+		assert.Equal(t, want, next, raw)
+	}
+
+	for _, raw := range []string{"https://example.com/posts", "https://example.com/posts?offset=0", "https://example.com/posts?page=last"} {
+		u, _ := url.Parse(raw)
+		_, ok := NextPageURL(u)
+		assert.False(t, ok, raw)
+	}
+}
+
+func TestPaginationKey(t *testing.T) {
+	a, _ := url.Parse("https://Example.com/posts?page=2&sort=new")
+	b, _ := url.Parse("https://example.com/posts?sort=new&page=7")
+	c, _ := url.Parse("https://example.com/posts?sort=old&page=2")
+	assert.Equal(t, paginationKey(a), paginationKey(b))
+	assert.NotEqual(t, paginationKey(a), paginationKey(c))
+}
+
+func TestCrawlFollowsPagination(t *testing.T) {
+	var mu sync.Mutex
+	var pages []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<html><body><a href="/posts?page=1">posts</a></body></html>`)
+			return
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		mu.Lock()
+		pages = append(pages, page)
+		mu.Unlock()
+		fmt.Fprintf(w, `<html><head><link rel="next" href="/posts?page=%d"></head><body>page</body></html>`, page+1)
+	}))
+	defer srv.Close()
+
+	crawl := func(maxPages int) []int {
+		mu.Lock()
+		pages = nil
+		mu.Unlock()
+		crawlAll(t, srv.URL, CrawlerConfig{MaxPagesFollow: maxPages})
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]int(nil), pages...)
+	}
+
+	// Depth 2 stops at the first page without pagination
+	assert.Equal(t, []int{1}, crawl(0))
+	assert.ElementsMatch(t, []int{1, 2, 3, 4}, crawl(3))
+}
@@ -0,0 +1,176 @@
+package core
+
+import (
+	"encoding/json"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Parameter sources recorded by ParamCollector
+const (
+	ParamSourceQuery = "query"
+	ParamSourceBody  = "body"
+	ParamSourceJSON  = "json"
+)
+
+// maxParamNameLen drops values that are clearly not parameter names
+const maxParamNameLen = 64
+
+// ParamCollector accumulates the distinct parameter names seen during a crawl,
+// grouped by where they appeared. It is shared by all crawlers of a run
+type ParamCollector struct {
+	path  string
+	split bool
+
+	mu    sync.Mutex
+	names map[string]map[string]struct{}
+}
+
+// NewParamCollector returns a collector that writes its wordlist to path.
+// With split set, one extra file per source is written next to it
+func NewParamCollector(path string, split bool) *ParamCollector {
+	return &ParamCollector{
+		path:  path,
+		split: split,
+		names: make(map[string]map[string]struct{}),
+	}
+}
+
+// Add records a parameter name under source
+func (p *ParamCollector) Add(source, name string) {
+	name = strings.TrimSpace(name)
+	// PHP style array suffixes name the same parameter
+	name = strings.TrimSuffix(name, "[]")
+	if name == "" || len(name) > maxParamNameLen || name == reflectedParamName || strings.ContainsAny(name, " \t\r\n") {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	set, ok := p.names[source]
+	if !ok {
+		set = make(map[string]struct{})
+		p.names[source] = set
+	}
+	set[name] = struct{}{}
+}
+
+// AddURL records the query parameter names of rawURL
+func (p *ParamCollector) AddURL(rawURL string) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return
+	}
+	values, err := url.ParseQuery(u.RawQuery)
+	if err != nil && len(values) == 0 {
+		return
+	}
+	for name := range values {
+		p.Add(ParamSourceQuery, name)
+	}
+}
+
+// AddBody records parameter names from a form-encoded or JSON request body
+func (p *ParamCollector) AddBody(body, contentType string) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if strings.Contains(mediaType, "json") || (mediaType == "" && looksLikeJSON(body)) {
+		p.AddJSON([]byte(body))
+		return
+	}
+	if mediaType != "" && mediaType != "application/x-www-form-urlencoded" {
+		return
+	}
+	values, err := url.ParseQuery(body)
+	if err != nil && len(values) == 0 {
+		return
+	}
+	for name := range values {
+		p.Add(ParamSourceBody, name)
+	}
+}
+
+// AddJSON records every object key of a JSON document
+func (p *ParamCollector) AddJSON(data []byte) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return
+	}
+	var paths [][]jsonPathSegment
+	collectJSONPaths(doc, nil, &paths)
+	for _, path := range paths {
+		for _, segment := range path {
+			if !segment.isIndex {
+				p.Add(ParamSourceJSON, segment.key)
+			}
+		}
+	}
+}
+
+// AddFormFields records form field names as query or body parameters
+// depending on the form method
+func (p *ParamCollector) AddFormFields(method string, fields []FormField) {
+	source := ParamSourceBody
+	if method == "" || strings.EqualFold(method, "GET") {
+		source = ParamSourceQuery
+	}
+	for _, field := range fields {
+		p.Add(source, field.Name)
+	}
+}
+
+// Names returns the sorted names recorded for source, or for all sources
+// when source is empty
+func (p *ParamCollector) Names(source string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	merged := make(map[string]struct{})
+	for src, set := range p.names {
+		if source != "" && src != source {
+			continue
+		}
+		for name := range set {
+			merged[name] = struct{}{}
+		}
+	}
+	names := make([]string, 0, len(merged))
+	for name := range merged {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Save writes the combined wordlist and, when splitting, params-query.txt
+// style files beside it
+func (p *ParamCollector) Save() error {
+	if err := writeWordlist(p.path, p.Names("")); err != nil {
+		return err
+	}
+	if !p.split {
+		return nil
+	}
+	ext := filepath.Ext(p.path)
+	stem := strings.TrimSuffix(p.path, ext)
+	for _, source := range []string{ParamSourceQuery, ParamSourceBody, ParamSourceJSON} {
+		if err := writeWordlist(stem+"-"+source+ext, p.Names(source)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeWordlist(path string, words []string) error {
+	content := strings.Join(words, "\n")
+	if content != "" {
+		content += "\n"
+	}
+	return os.WriteFile(path, []byte(content), 0644)
+}
@@ -0,0 +1,35 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParamCollector(t *testing.T) {
+	dir := t.TempDir()
+	p := NewParamCollector(filepath.Join(dir, "params.txt"), true)
+
+	p.AddURL("https://example.com/search?q=1&page=2&ids[]=3&gospider_ref=x")
+	p.AddBody("user=a&pass=b", "application/x-www-form-urlencoded")
+	p.AddBody(`{"profile":{"email":"a@b.c","tags":[{"label":"x"}]}}`, "application/json")
+	p.AddBody("--boundary\r\n", "multipart/form-data; boundary=boundary")
+	p.AddFormFields("post", []FormField{{Name: "csrf_token"}})
+	p.AddFormFields("", []FormField{{Name: "q"}})
+
+	assert.Equal(t, []string{"ids", "page", "q"}, p.Names(ParamSourceQuery))
+	assert.Equal(t, []string{"csrf_token", "pass", "user"}, p.Names(ParamSourceBody))
+	assert.Equal(t, []string{"email", "label", "profile", "tags"}, p.Names(ParamSourceJSON))
+
+	require.NoError(t, p.Save())
+	all, err := os.ReadFile(filepath.Join(dir, "params.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "csrf_token\nemail\nids\nlabel\npage\npass\nprofile\nq\ntags\nuser\n", string(all))
+
+	query, err := os.ReadFile(filepath.Join(dir, "params-query.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "ids\npage\nq\n", string(query))
+}
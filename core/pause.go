@@ -0,0 +1,61 @@
+package core
+
+import "sync"
+
+// PauseGate holds requests back while a crawl is paused. It is shared by
+// every crawler of a run; a nil gate never pauses
+type PauseGate struct {
+	mu     sync.Mutex
+	paused bool
+	resume chan struct{}
+}
+
+// NewPauseGate returns a gate that starts open
+func NewPauseGate() *PauseGate {
+	return &PauseGate{}
+}
+
+// Toggle pauses an open gate or resumes a paused one and reports whether
+// the gate is now paused
+func (g *PauseGate) Toggle() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.paused {
+		g.paused = false
+		close(g.resume)
+	} else {
+		g.paused = true
+		g.resume = make(chan struct{})
+	}
+	return g.paused
+}
+
+// Paused reports whether requests are held back
+func (g *PauseGate) Paused() bool {
+	if g == nil {
+		return false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.paused
+}
+
+// Wait blocks while the gate is paused, until it is resumed or stop or done
+// is closed
+func (g *PauseGate) Wait(stop, done <-chan struct{}) {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	if !g.paused {
+		g.mu.Unlock()
+		return
+	}
+	resume := g.resume
+	g.mu.Unlock()
+	select {
+	case <-resume:
+	case <-stop:
+	case <-done:
+	}
+}
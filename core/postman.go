@@ -0,0 +1,524 @@
+package core
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// postmanSource is the source of requests seeded from --postman
+const postmanSource = "postman"
+
+// Postman writes {{name}}, Insomnia {{ _.name }}
+var collectionVariableRegex = regexp.MustCompile(`{{\s*(?:_\.)?([A-Za-z0-9_.\-$]+)\s*}}`)
+
+// collectionPlaceholder stands in for variables neither the collection nor
+// the environment defines, e.g. the {{id}} in /users/{{id}}
+const collectionPlaceholder = "1"
+
+type postmanCollection struct {
+	Info     struct{ Schema string } `json:"info"`
+	Item     []postmanItem           `json:"item"`
+	Auth     *postmanAuth            `json:"auth"`
+	Variable []postmanVariable       `json:"variable"`
+}
+
+type postmanItem struct {
+	Name    string          `json:"name"`
+	Request *postmanRequest `json:"request"`
+	Item    []postmanItem   `json:"item"`
+	Auth    *postmanAuth    `json:"auth"`
+}
+
+type postmanRequest struct {
+	Method string          `json:"method"`
+	URL    json.RawMessage `json:"url"`
+	Header []postmanKV     `json:"header"`
+	Body   *postmanBody    `json:"body"`
+	Auth   *postmanAuth    `json:"auth"`
+}
+
+type postmanURL struct {
+	Raw      string      `json:"raw"`
+	Protocol string      `json:"protocol"`
+	Host     []string    `json:"host"`
+	Path     []string    `json:"path"`
+	Query    []postmanKV `json:"query"`
+}
+
+type postmanBody struct {
+	Mode       string      `json:"mode"`
+	Raw        string      `json:"raw"`
+	URLEncoded []postmanKV `json:"urlencoded"`
+	FormData   []postmanKV `json:"formdata"`
+	GraphQL    *struct {
+		Query     string `json:"query"`
+		Variables string `json:"variables"`
+	} `json:"graphql"`
+	Options struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+}
+
+type postmanKV struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Type     string `json:"type"`
+	Disabled bool   `json:"disabled"`
+}
+
+type postmanAuth struct {
+	Type   string      `json:"type"`
+	Bearer []postmanKV `json:"bearer"`
+	Basic  []postmanKV `json:"basic"`
+	APIKey []postmanKV `json:"apikey"`
+}
+
+type postmanVariable struct {
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+	Enabled *bool  `json:"enabled"`
+}
+
+type insomniaExport struct {
+	Type      string             `json:"_type"`
+	Resources []insomniaResource `json:"resources"`
+}
+
+type insomniaResource struct {
+	Type    string         `json:"_type"`
+	Method  string         `json:"method"`
+	URL     string         `json:"url"`
+	Data    map[string]any `json:"data"`
+	Headers []insomniaKV   `json:"headers"`
+	Params  []insomniaKV   `json:"parameters"`
+	Auth    map[string]any `json:"authentication"`
+	Body    struct {
+		MimeType string       `json:"mimeType"`
+		Text     string       `json:"text"`
+		Params   []insomniaKV `json:"params"`
+	} `json:"body"`
+}
+
+type insomniaKV struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
+}
+
+// LoadAPICollection reads a Postman v2.x collection or an Insomnia v4 export
+// and returns its requests. Variables are resolved from envPath (a Postman
+// environment or a flat JSON object) first, then from the collection itself;
+// unknown ones become a placeholder, and a URL starting with an unknown base
+// URL variable is left relative so it resolves against the target
+func LoadAPICollection(path, envPath string) ([]JSRequest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	vars := map[string]string{}
+	if envPath != "" {
+		envData, err := os.ReadFile(envPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := parseCollectionEnvironment(envData, vars); err != nil {
+			return nil, fmt.Errorf("%s: %w", envPath, err)
+		}
+	}
+
+	var probe struct {
+		Type string          `json:"_type"`
+		Info json.RawMessage `json:"info"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	switch {
+	case probe.Type == "export":
+		var export insomniaExport
+		if err := json.Unmarshal(data, &export); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return insomniaRequests(export, vars), nil
+	case probe.Info != nil:
+		var collection postmanCollection
+		if err := json.Unmarshal(data, &collection); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, v := range collection.Variable {
+			if _, ok := vars[v.Key]; !ok && (v.Enabled == nil || *v.Enabled) {
+				vars[v.Key] = v.Value
+			}
+		}
+		var requests []JSRequest
+		walkPostmanItems(collection.Item, collection.Auth, vars, &requests)
+		return requests, nil
+	}
+	return nil, fmt.Errorf("%s: not a Postman collection or Insomnia export", path)
+}
+
+// parseCollectionEnvironment accepts a Postman environment export
+// ({"values": [{"key", "value", "enabled"}]}) or a flat {"name": "value"} map
+func parseCollectionEnvironment(data []byte, vars map[string]string) error {
+	var env struct {
+		Values []postmanVariable `json:"values"`
+	}
+	if err := json.Unmarshal(data, &env); err == nil && env.Values != nil {
+		for _, v := range env.Values {
+			if v.Enabled == nil || *v.Enabled {
+				vars[v.Key] = v.Value
+			}
+		}
+		return nil
+	}
+	var flat map[string]any
+	if err := json.Unmarshal(data, &flat); err != nil {
+		return err
+	}
+	for k, v := range flat {
+		if s, ok := v.(string); ok {
+			vars[k] = s
+		}
+	}
+	return nil
+}
+
+func walkPostmanItems(items []postmanItem, auth *postmanAuth, vars map[string]string, requests *[]JSRequest) {
+	for _, item := range items {
+		itemAuth := auth
+		if item.Auth != nil {
+			itemAuth = item.Auth
+		}
+		if len(item.Item) > 0 {
+			walkPostmanItems(item.Item, itemAuth, vars, requests)
+		}
+		if item.Request == nil {
+			continue
+		}
+		if item.Request.Auth != nil {
+			itemAuth = item.Request.Auth
+		}
+		if req, ok := postmanToRequest(item.Request, itemAuth, vars); ok {
+			*requests = append(*requests, req)
+		}
+	}
+}
+
+func postmanToRequest(pr *postmanRequest, auth *postmanAuth, vars map[string]string) (JSRequest, bool) {
+	rawURL := postmanRawURL(pr.URL)
+	if rawURL == "" {
+		return JSRequest{}, false
+	}
+	req := JSRequest{
+		Method:  strings.ToUpper(pr.Method),
+		RawURL:  expandCollectionURL(rawURL, vars),
+		Headers: map[string]string{},
+		Source:  postmanSource,
+	}
+	for _, h := range pr.Header {
+		if !h.Disabled && h.Key != "" {
+			req.Headers[h.Key] = expandCollectionVariables(h.Value, vars)
+		}
+	}
+	if auth != nil {
+		applyCollectionAuth(&req, auth.Type, collectionAuthValues(auth), vars)
+	}
+
+	if body := pr.Body; body != nil {
+		switch body.Mode {
+		case "raw":
+			req.Body = expandCollectionVariables(body.Raw, vars)
+			switch body.Options.Raw.Language {
+			case "json":
+				req.ContentType = "application/json"
+			case "xml":
+				req.ContentType = "application/xml"
+			}
+		case "urlencoded":
+			form := url.Values{}
+			for _, kv := range body.URLEncoded {
+				if !kv.Disabled {
+					form.Add(kv.Key, expandCollectionVariables(kv.Value, vars))
+				}
+			}
+			req.Body = form.Encode()
+			req.ContentType = "application/x-www-form-urlencoded"
+		case "formdata":
+			var fields []insomniaKV
+			for _, kv := range body.FormData {
+				// File parts point at paths on the exporter's machine
+				if !kv.Disabled && kv.Type != "file" {
+					fields = append(fields, insomniaKV{Name: kv.Key, Value: expandCollectionVariables(kv.Value, vars)})
+				}
+			}
+			req.Body, req.ContentType = encodeMultipartFields(fields)
+		case "graphql":
+			if body.GraphQL != nil {
+				payload := map[string]any{"query": body.GraphQL.Query}
+				var variables any
+				if json.Unmarshal([]byte(expandCollectionVariables(body.GraphQL.Variables, vars)), &variables) == nil {
+					payload["variables"] = variables
+				}
+				data, _ := json.Marshal(payload)
+				req.Body = string(data)
+				req.ContentType = "application/json"
+			}
+		}
+	}
+	if ct, ok := req.Headers["Content-Type"]; ok {
+		req.ContentType = ct
+	}
+	return req, true
+}
+
+// postmanRawURL accepts both forms of a request URL: a string, or an object
+// with "raw" or its host, path and query parts
+func postmanRawURL(data json.RawMessage) string {
+	var raw string
+	if json.Unmarshal(data, &raw) == nil {
+		return raw
+	}
+	var u postmanURL
+	if json.Unmarshal(data, &u) != nil {
+		return ""
+	}
+	if u.Raw != "" {
+		return u.Raw
+	}
+	if len(u.Host) == 0 {
+		return ""
+	}
+	built := strings.Join(u.Host, ".")
+	if u.Protocol != "" {
+		built = u.Protocol + "://" + built
+	}
+	if len(u.Path) > 0 {
+		built += "/" + strings.Join(u.Path, "/")
+	}
+	var query []string
+	for _, q := range u.Query {
+		if !q.Disabled {
+			query = append(query, q.Key+"="+q.Value)
+		}
+	}
+	if len(query) > 0 {
+		built += "?" + strings.Join(query, "&")
+	}
+	return built
+}
+
+func collectionAuthValues(auth *postmanAuth) map[string]string {
+	var kvs []postmanKV
+	switch auth.Type {
+	case "bearer":
+		kvs = auth.Bearer
+	case "basic":
+		kvs = auth.Basic
+	case "apikey":
+		kvs = auth.APIKey
+	}
+	values := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		values[kv.Key] = kv.Value
+	}
+	return values
+}
+
+// applyCollectionAuth turns a bearer, basic or API key auth helper into the
+// header or query parameter it stands for. Explicit headers win
+func applyCollectionAuth(req *JSRequest, kind string, values map[string]string, vars map[string]string) {
+	value := func(key string) string { return expandCollectionVariables(values[key], vars) }
+	setHeader := func(name, v string) {
+		for existing := range req.Headers {
+			if strings.EqualFold(existing, name) {
+				return
+			}
+		}
+		req.Headers[name] = v
+	}
+	switch kind {
+	case "bearer":
+		if token := value("token"); token != "" {
+			setHeader("Authorization", "Bearer "+token)
+		}
+	case "basic":
+		credentials := value("username") + ":" + value("password")
+		setHeader("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	case "apikey":
+		key := value("key")
+		if key == "" {
+			return
+		}
+		if values["in"] == "query" {
+			sep := "?"
+			if strings.Contains(req.RawURL, "?") {
+				sep = "&"
+			}
+			req.RawURL += sep + url.QueryEscape(key) + "=" + url.QueryEscape(value("value"))
+			return
+		}
+		setHeader(key, value("value"))
+	}
+}
+
+// seedRequests queues the in-scope requests of --postman, --har or
+// --burp-sitemap through the JS request pipeline. Like other generated requests, PUT, PATCH and
+// DELETE are reported but never sent
+func (crawler *Crawler) seedRequests(source string, requests []JSRequest) {
+	origin := crawler.site.String()
+	for _, req := range requests {
+		normalized, ok := crawler.normalizeJSRequest(req, origin)
+		if !ok {
+			continue
+		}
+		u, err := url.Parse(normalized.RawURL)
+		if err != nil || !InScope(u, crawler.C.URLFilters) {
+			Logger.Debugf("%s: %s %s is out of scope", source, normalized.Method, normalized.RawURL)
+			continue
+		}
+		if isDestructiveMethod(normalized.Method) {
+			crawler.emitJSRequest(normalized, origin, 1)
+			continue
+		}
+		crawler.processGeneratedRequest(normalized, origin, 0)
+	}
+}
+
+func insomniaRequests(export insomniaExport, vars map[string]string) []JSRequest {
+	// Environment data is the fallback; --postman-env values take precedence
+	for _, resource := range export.Resources {
+		if resource.Type != "environment" {
+			continue
+		}
+		for k, v := range resource.Data {
+			if s, ok := v.(string); ok {
+				if _, set := vars[k]; !set {
+					vars[k] = s
+				}
+			}
+		}
+	}
+
+	var requests []JSRequest
+	for _, resource := range export.Resources {
+		if resource.Type != "request" || resource.URL == "" {
+			continue
+		}
+		rawURL := resource.URL
+		var query []string
+		for _, p := range resource.Params {
+			if !p.Disabled && p.Name != "" {
+				query = append(query, p.Name+"="+p.Value)
+			}
+		}
+		if len(query) > 0 {
+			sep := "?"
+			if strings.Contains(rawURL, "?") {
+				sep = "&"
+			}
+			rawURL += sep + strings.Join(query, "&")
+		}
+		req := JSRequest{
+			Method:  strings.ToUpper(resource.Method),
+			RawURL:  expandCollectionURL(rawURL, vars),
+			Headers: map[string]string{},
+			Source:  postmanSource,
+		}
+		for _, h := range resource.Headers {
+			if !h.Disabled && h.Name != "" {
+				req.Headers[h.Name] = expandCollectionVariables(h.Value, vars)
+			}
+		}
+		if resource.Auth != nil && resource.Auth["disabled"] != true {
+			auth := map[string]string{}
+			for k, v := range resource.Auth {
+				if s, ok := v.(string); ok {
+					auth[k] = s
+				}
+			}
+			// Insomnia keeps API key placement in addTo
+			if auth["addTo"] == "queryParams" {
+				auth["in"] = "query"
+			}
+			applyCollectionAuth(&req, auth["type"], auth, vars)
+		}
+
+		mimeType := resource.Body.MimeType
+		switch {
+		case mimeType == "application/x-www-form-urlencoded":
+			form := url.Values{}
+			for _, p := range resource.Body.Params {
+				if !p.Disabled {
+					form.Add(p.Name, expandCollectionVariables(p.Value, vars))
+				}
+			}
+			req.Body = form.Encode()
+			req.ContentType = mimeType
+		case mimeType == "multipart/form-data":
+			var fields []insomniaKV
+			for _, p := range resource.Body.Params {
+				if !p.Disabled {
+					fields = append(fields, insomniaKV{Name: p.Name, Value: expandCollectionVariables(p.Value, vars)})
+				}
+			}
+			req.Body, req.ContentType = encodeMultipartFields(fields)
+		case resource.Body.Text != "":
+			req.Body = expandCollectionVariables(resource.Body.Text, vars)
+			req.ContentType = mimeType
+		}
+		if ct, ok := req.Headers["Content-Type"]; ok && !strings.HasPrefix(ct, "multipart/") {
+			req.ContentType = ct
+		}
+		requests = append(requests, req)
+	}
+	return requests
+}
+
+func encodeMultipartFields(fields []insomniaKV) (string, string) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	for _, field := range fields {
+		_ = writer.WriteField(field.Name, field.Value)
+	}
+	_ = writer.Close()
+	return buf.String(), writer.FormDataContentType()
+}
+
+func expandCollectionVariables(s string, vars map[string]string) string {
+	// Variables may refer to other variables, e.g. {{baseUrl}} = {{host}}/api
+	for i := 0; i < 5 && strings.Contains(s, "{{"); i++ {
+		s = collectionVariableRegex.ReplaceAllStringFunc(s, func(match string) string {
+			name := collectionVariableRegex.FindStringSubmatch(match)[1]
+			if value, ok := vars[name]; ok {
+				return value
+			}
+			return match
+		})
+	}
+	return collectionVariableRegex.ReplaceAllString(s, collectionPlaceholder)
+}
+
+// expandCollectionURL is expandCollectionVariables for request URLs. An
+// unknown leading variable is the collection's base URL, so it is dropped to
+// leave a path that resolves against the crawl target
+func expandCollectionURL(raw string, vars map[string]string) string {
+	raw = strings.TrimSpace(raw)
+	if loc := collectionVariableRegex.FindStringSubmatchIndex(raw); loc != nil && loc[0] == 0 {
+		if _, ok := vars[raw[loc[2]:loc[3]]]; !ok {
+			raw = raw[loc[1]:]
+			if !strings.HasPrefix(raw, "/") {
+				raw = "/" + raw
+			}
+		}
+	}
+	return expandCollectionVariables(raw, vars)
+}
@@ -0,0 +1,262 @@
+package core
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// postmanSchema is the collection format written by --postman-out
+const postmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+// PostmanExporter gathers the requests a crawl discovers, from scripts,
+// forms and Katana, into a Postman v2.1 collection for manual replay. Each
+// target gets a folder, and its requests carry the target's session
+// headers; session cookies become collection variables so they can be
+// refreshed in one place. It is shared by all crawlers of a run
+type PostmanExporter struct {
+	path string
+
+	mu       sync.Mutex
+	sites    []string
+	sessions map[string]http.Header
+	requests map[string][]JSRequest
+	seen     map[string]struct{}
+}
+
+// NewPostmanExporter returns an exporter that writes its collection to path
+func NewPostmanExporter(path string) *PostmanExporter {
+	return &PostmanExporter{
+		path:     path,
+		sessions: make(map[string]http.Header),
+		requests: make(map[string][]JSRequest),
+		seen:     make(map[string]struct{}),
+	}
+}
+
+// AddSession records the headers, such as --cookie and -H, that every
+// request of site is sent with
+func (p *PostmanExporter) AddSession(site string, headers http.Header) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.addSite(site)
+	p.sessions[site] = headers.Clone()
+}
+
+// Add records a request discovered while crawling site. A request is kept
+// once per site
+func (p *PostmanExporter) Add(site string, req JSRequest) {
+	if strings.TrimSpace(req.RawURL) == "" {
+		return
+	}
+	req.Method = strings.ToUpper(strings.TrimSpace(req.Method))
+	key := site + "\n" + buildRequestKey(req)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.seen[key]; ok {
+		return
+	}
+	p.seen[key] = struct{}{}
+	p.addSite(site)
+	p.requests[site] = append(p.requests[site], req)
+}
+
+// addSite keeps the folders in the order targets started; p.mu must be held
+func (p *PostmanExporter) addSite(site string) {
+	if _, ok := p.requests[site]; ok {
+		return
+	}
+	p.sites = append(p.sites, site)
+	p.requests[site] = nil
+}
+
+type postmanExportCollection struct {
+	Info struct {
+		Name   string `json:"name"`
+		Schema string `json:"schema"`
+	} `json:"info"`
+	Item     []postmanExportItem `json:"item"`
+	Variable []postmanExportKV   `json:"variable,omitempty"`
+}
+
+type postmanExportKV struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type postmanExportItem struct {
+	Name    string                `json:"name"`
+	Item    []postmanExportItem   `json:"item,omitempty"`
+	Request *postmanExportRequest `json:"request,omitempty"`
+}
+
+type postmanExportRequest struct {
+	Method string             `json:"method"`
+	Header []postmanExportKV  `json:"header"`
+	URL    postmanExportURL   `json:"url"`
+	Body   *postmanExportBody `json:"body,omitempty"`
+}
+
+type postmanExportURL struct {
+	Raw      string            `json:"raw"`
+	Protocol string            `json:"protocol,omitempty"`
+	Host     []string          `json:"host,omitempty"`
+	Port     string            `json:"port,omitempty"`
+	Path     []string          `json:"path,omitempty"`
+	Query    []postmanExportKV `json:"query,omitempty"`
+}
+
+type postmanExportBody struct {
+	Mode       string                `json:"mode"`
+	Raw        string                `json:"raw,omitempty"`
+	URLEncoded []postmanExportKV     `json:"urlencoded,omitempty"`
+	Options    *postmanExportOptions `json:"options,omitempty"`
+}
+
+type postmanExportOptions struct {
+	Raw struct {
+		Language string `json:"language"`
+	} `json:"raw"`
+}
+
+// collection returns the collection of what was recorded so far
+func (p *PostmanExporter) collection() postmanExportCollection {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var collection postmanExportCollection
+	collection.Info.Name = CLIName + " crawl"
+	collection.Info.Schema = postmanSchema
+	collection.Item = []postmanExportItem{}
+
+	cookieVars := map[string]string{}
+	for _, site := range p.sites {
+		session := p.sessions[site]
+		if cookie := session.Get("Cookie"); cookie != "" {
+			if _, ok := cookieVars[cookie]; !ok {
+				name := "cookie"
+				if n := len(cookieVars) + 1; n > 1 {
+					name += strconv.Itoa(n)
+				}
+				cookieVars[cookie] = name
+				collection.Variable = append(collection.Variable, postmanExportKV{Key: name, Value: cookie})
+			}
+		}
+
+		folder := postmanExportItem{Name: site, Item: []postmanExportItem{}}
+		for _, req := range p.requests[site] {
+			folder.Item = append(folder.Item, postmanExportRequestItem(req, session, cookieVars))
+		}
+		collection.Item = append(collection.Item, folder)
+	}
+	return collection
+}
+
+func postmanExportRequestItem(req JSRequest, session http.Header, cookieVars map[string]string) postmanExportItem {
+	method := strings.ToUpper(strings.TrimSpace(req.Method))
+	if method == "" {
+		method = http.MethodGet
+	}
+	request := &postmanExportRequest{Method: method, Header: []postmanExportKV{}, URL: postmanExportURLOf(req.RawURL)}
+
+	set := map[string]bool{}
+	for _, name := range sortedKeys(req.Headers) {
+		request.Header = append(request.Header, postmanExportKV{Key: name, Value: req.Headers[name]})
+		set[http.CanonicalHeaderKey(name)] = true
+	}
+	if req.ContentType != "" && !set["Content-Type"] {
+		request.Header = append(request.Header, postmanExportKV{Key: "Content-Type", Value: req.ContentType})
+		set["Content-Type"] = true
+	}
+	for _, name := range sortedKeys(session) {
+		// Postman sends its own user agent
+		if set[name] || name == "User-Agent" {
+			continue
+		}
+		value := session.Get(name)
+		if name == "Cookie" && cookieVars[value] != "" {
+			value = "{{" + cookieVars[value] + "}}"
+		}
+		request.Header = append(request.Header, postmanExportKV{Key: name, Value: value})
+	}
+
+	if req.Body != "" {
+		contentType := req.ContentType
+		if contentType == "" {
+			contentType = req.Headers["Content-Type"]
+		}
+		request.Body = postmanExportBodyOf(req.Body, contentType)
+	}
+
+	name := method + " " + request.URL.Raw
+	if u, err := url.Parse(req.RawURL); err == nil && u.Path != "" {
+		name = method + " " + u.Path
+	}
+	return postmanExportItem{Name: name, Request: request}
+}
+
+func postmanExportURLOf(raw string) postmanExportURL {
+	exported := postmanExportURL{Raw: raw}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return exported
+	}
+	exported.Protocol = u.Scheme
+	exported.Host = strings.Split(u.Hostname(), ".")
+	exported.Port = u.Port()
+	if path := strings.Trim(u.EscapedPath(), "/"); path != "" {
+		exported.Path = strings.Split(path, "/")
+	}
+	exported.Query = splitURLEncoded(u.RawQuery)
+	return exported
+}
+
+func postmanExportBodyOf(body, contentType string) *postmanExportBody {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/x-www-form-urlencoded" {
+		if fields := splitURLEncoded(body); fields != nil {
+			return &postmanExportBody{Mode: "urlencoded", URLEncoded: fields}
+		}
+	}
+	exported := &postmanExportBody{Mode: "raw", Raw: body}
+	if strings.Contains(mediaType, "json") || mediaType == "" && looksLikeJSON(body) {
+		exported.Options = &postmanExportOptions{}
+		exported.Options.Raw.Language = "json"
+	}
+	return exported
+}
+
+// splitURLEncoded splits a query string or form body into its pairs,
+// keeping their order
+func splitURLEncoded(encoded string) []postmanExportKV {
+	var pairs []postmanExportKV
+	for _, pair := range strings.Split(encoded, "&") {
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, "=")
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		if v, err := url.QueryUnescape(value); err == nil {
+			value = v
+		}
+		pairs = append(pairs, postmanExportKV{Key: key, Value: value})
+	}
+	return pairs
+}
+
+// Save writes the collection
+func (p *PostmanExporter) Save() error {
+	data, err := jsoniter.ConfigCompatibleWithStandardLibrary.MarshalIndent(p.collection(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode collection: %w", err)
+	}
+	return os.WriteFile(p.path, append(data, '\n'), 0644)
+}
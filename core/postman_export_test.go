@@ -0,0 +1,87 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostmanExporter(t *testing.T) {
+	p := NewPostmanExporter(filepath.Join(t.TempDir(), "collection.json"))
+	p.AddSession("https://a.example.com", http.Header{"Cookie": {"sid=1"}, "User-Agent": {"ua"}, "X-Api": {"k"}})
+	p.AddSession("https://b.example.com", http.Header{"Cookie": {"sid=2"}})
+	p.Add("https://a.example.com", JSRequest{Method: "post", RawURL: "https://a.example.com:8443/api/users?x=1&y=a%20b", Body: `{"name":"bob"}`, ContentType: "application/json", Headers: map[string]string{"X-Api": "override"}})
+	p.Add("https://a.example.com", JSRequest{Method: "POST", RawURL: "https://a.example.com:8443/api/users?x=1&y=a%20b", Body: `{"name":"bob"}`, ContentType: "application/json", Headers: map[string]string{"X-Api": "override"}})
+	p.Add("https://b.example.com", JSRequest{Method: "POST", RawURL: "https://b.example.com/login", Body: "user=admin&pass=x", ContentType: "application/x-www-form-urlencoded"})
+
+	collection := p.collection()
+	assert.Equal(t, postmanSchema, collection.Info.Schema)
+	assert.Equal(t, []postmanExportKV{{Key: "cookie", Value: "sid=1"}, {Key: "cookie2", Value: "sid=2"}}, collection.Variable)
+	require.Len(t, collection.Item, 2)
+	require.Len(t, collection.Item[0].Item, 1, "a request is kept once per site")
+
+	users := collection.Item[0].Item[0]
+	assert.Equal(t, "POST /api/users", users.Name)
+	assert.Equal(t, []postmanExportKV{{Key: "X-Api", Value: "override"}, {Key: "Content-Type", Value: "application/json"}, {Key: "Cookie", Value: "{{cookie}}"}}, users.Request.Header)
+	assert.Equal(t, []string{"a", "example", "com"}, users.Request.URL.Host)
+	assert.Equal(t, "8443", users.Request.URL.Port)
+	assert.Equal(t, []string{"api", "users"}, users.Request.URL.Path)
+	assert.Equal(t, []postmanExportKV{{Key: "x", Value: "1"}, {Key: "y", Value: "a b"}}, users.Request.URL.Query)
+	assert.Equal(t, "raw", users.Request.Body.Mode)
+	assert.Equal(t, "json", users.Request.Body.Options.Raw.Language)
+
+	login := collection.Item[1].Item[0].Request
+	assert.Equal(t, "urlencoded", login.Body.Mode)
+	assert.Equal(t, []postmanExportKV{{Key: "user", Value: "admin"}, {Key: "pass", Value: "x"}}, login.Body.URLEncoded)
+	assert.Contains(t, login.Header, postmanExportKV{Key: "Cookie", Value: "{{cookie2}}"})
+
+	// The written collection loads back through --postman
+	require.NoError(t, p.Save())
+	requests, err := LoadAPICollection(p.path, "")
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+	assert.Equal(t, "sid=1", requests[0].Headers["Cookie"])
+	assert.Equal(t, `{"name":"bob"}`, requests[0].Body)
+	assert.Contains(t, requests[1].Body, "user=admin")
+	assert.Contains(t, requests[1].Body, "pass=x")
+}
+
+func TestCrawlExportsPostman(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<form action="/search" method="post"><input name="q" value="x"></form>` +
+				`<script>fetch("/api/items", {method: "POST", body: JSON.stringify({a: 1}), headers: {"Content-Type": "application/json"}})</script>`))
+		default:
+			w.Write([]byte(`ok`))
+		}
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "collection.json")
+	export := NewPostmanExporter(path)
+	crawlAll(t, srv.URL, CrawlerConfig{
+		LinkFinder: true, Cookie: "session=abc", PostmanExport: export,
+	})
+	require.NoError(t, export.Save())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var collection postmanExportCollection
+	require.NoError(t, jsoniter.Unmarshal(data, &collection))
+	require.Len(t, collection.Item, 1)
+	var names []string
+	for _, item := range collection.Item[0].Item {
+		names = append(names, item.Name)
+		assert.Contains(t, item.Request.Header, postmanExportKV{Key: "Cookie", Value: "{{cookie}}"})
+	}
+	assert.Contains(t, names, "POST /search")
+	assert.Equal(t, []postmanExportKV{{Key: "cookie", Value: "session=abc"}}, collection.Variable)
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPostmanCollection = `{
+  "info": {"name": "api", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+  "auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{token}}"}]},
+  "variable": [{"key": "baseUrl", "value": "https://api.example.com"}, {"key": "token", "value": "collection-token"}],
+  "item": [
+    {"name": "users", "item": [
+      {"name": "list", "request": {"method": "GET", "url": "{{baseUrl}}/users?page=1", "header": [{"key": "X-Trace", "value": "on"}, {"key": "X-Off", "value": "x", "disabled": true}]}},
+      {"name": "get", "request": {"method": "GET", "url": {"protocol": "https", "host": ["api", "example", "com"], "path": ["users", "{{userId}}"]}}},
+      {"name": "create", "request": {"method": "POST", "url": {"raw": "{{baseUrl}}/users"},
+        "body": {"mode": "raw", "raw": "{\"name\": \"{{name}}\"}", "options": {"raw": {"language": "json"}}}}}
+    ]},
+    {"name": "admin", "auth": {"type": "basic", "basic": [{"key": "username", "value": "admin"}, {"key": "password", "value": "s3cret"}]}, "item": [
+      {"name": "login", "request": {"method": "POST", "url": "{{baseUrl}}/login",
+        "body": {"mode": "urlencoded", "urlencoded": [{"key": "user", "value": "admin"}, {"key": "skip", "value": "x", "disabled": true}]}}}
+    ]},
+    {"name": "public", "request": {"method": "GET", "url": "{{baseUrl}}/health", "auth": {"type": "noauth"}}}
+  ]
+}`
+
+func TestLoadPostmanCollection(t *testing.T) {
+	dir := t.TempDir()
+	collection := filepath.Join(dir, "collection.json")
+	require.NoError(t, os.WriteFile(collection, []byte(testPostmanCollection), 0o644))
+	env := filepath.Join(dir, "env.json")
+	require.NoError(t, os.WriteFile(env, []byte(`{"values": [{"key": "token", "value": "env-token", "enabled": true}, {"key": "name", "value": "bob"}]}`), 0o644))
+
+	requests, err := LoadAPICollection(collection, env)
+	require.NoError(t, err)
+	require.Len(t, requests, 5)
+
+	assert.Equal(t, "GET", requests[0].Method)
+	assert.Equal(t, "https://api.example.com/users?page=1", requests[0].RawURL)
+	assert.Equal(t, map[string]string{"X-Trace": "on", "Authorization": "Bearer env-token"}, requests[0].Headers, "the environment overrides collection variables")
+	assert.Equal(t, postmanSource, requests[0].Source)
+
+	assert.Equal(t, "https://api.example.com/users/1", requests[1].RawURL, "unknown variables become a placeholder")
+
+	assert.Equal(t, "POST", requests[2].Method)
+	assert.Equal(t, `{"name": "bob"}`, requests[2].Body)
+	assert.Equal(t, "application/json", requests[2].ContentType)
+
+	assert.Equal(t, "user=admin", requests[3].Body)
+	assert.Equal(t, "application/x-www-form-urlencoded", requests[3].ContentType)
+	assert.Equal(t, "Basic "+base64.StdEncoding.EncodeToString([]byte("admin:s3cret")), requests[3].Headers["Authorization"], "folder auth overrides the collection's")
+
+	assert.Empty(t, requests[4].Headers["Authorization"], "noauth clears inherited auth")
+
+	// Without the base URL, paths resolve against the crawl target
+	require.NoError(t, os.WriteFile(collection, []byte(`{"info": {}, "item": [{"request": {"method": "GET", "url": "{{host}}/v1/{{id}}"}}]}`), 0o644))
+	requests, err = LoadAPICollection(collection, "")
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+	assert.Equal(t, "/v1/1", requests[0].RawURL)
+
+	require.NoError(t, os.WriteFile(collection, []byte(`{"openapi": "3.0.0"}`), 0o644))
+	_, err = LoadAPICollection(collection, "")
+	assert.Error(t, err)
+}
+
+func TestLoadInsomniaExport(t *testing.T) {
+	dir := t.TempDir()
+	export := filepath.Join(dir, "insomnia.json")
+	require.NoError(t, os.WriteFile(export, []byte(`{
+  "_type": "export", "__export_format": 4,
+  "resources": [
+    {"_type": "environment", "data": {"base_url": "https://api.example.com", "key": "k-123"}},
+    {"_type": "request", "method": "POST", "url": "{{ _.base_url }}/orders",
+     "headers": [{"name": "Content-Type", "value": "application/json"}],
+     "parameters": [{"name": "dry", "value": "1"}],
+     "authentication": {"type": "apikey", "key": "X-Api-Key", "value": "{{ _.key }}", "disabled": false},
+     "body": {"mimeType": "application/json", "text": "{\"sku\": 1}"}},
+    {"_type": "request_group", "name": "folder"}
+  ]
+}`), 0o644))
+
+	requests, err := LoadAPICollection(export, "")
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+	assert.Equal(t, "POST", requests[0].Method)
+	assert.Equal(t, "https://api.example.com/orders?dry=1", requests[0].RawURL)
+	assert.Equal(t, "k-123", requests[0].Headers["X-Api-Key"])
+	assert.Equal(t, `{"sku": 1}`, requests[0].Body)
+	assert.Equal(t, "application/json", requests[0].ContentType)
+}
+
+func TestCrawlSeedsAPIRequests(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen[r.Method+" "+r.URL.Path] = r.Header.Get("Authorization")
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	results := crawlAll(t, srv.URL, CrawlerConfig{
+		APIRequests: []JSRequest{
+			{Method: "GET", RawURL: "/api/users", Headers: map[string]string{"Authorization": "Bearer t"}, Source: postmanSource},
+			{Method: "POST", RawURL: srv.URL + "/api/users", Body: `{"a":1}`, ContentType: "application/json", Source: postmanSource},
+			{Method: "DELETE", RawURL: srv.URL + "/api/users/1", Source: postmanSource},
+			{Method: "GET", RawURL: "https://other.example.net/api", Source: postmanSource},
+		},
+	})
+
+	var reported []string
+	for _, sout := range results {
+		if sout.OutputType == "js-request" && sout.Source == postmanSource {
+			reported = append(reported, sout.Output)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "Bearer t", seen["GET /api/users"])
+	assert.Contains(t, seen, "POST /api/users")
+	assert.NotContains(t, seen, "DELETE /api/users/1", "destructive requests are never sent")
+	assert.Contains(t, reported, "DELETE "+srv.URL+"/api/users/1")
+	assert.NotContains(t, reported, "GET https://other.example.net/api")
+}
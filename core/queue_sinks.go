@@ -0,0 +1,325 @@
+package core
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// Message queue sinks let gospider feed findings into recon pipelines.
+// NATS is spoken natively; Kafka goes through a Confluent-compatible REST
+// proxy, as no Kafka client ships with gospider, hence kafka-rest
+func init() {
+	RegisterOutputSink("nats", func(arg string) (OutputSink, error) {
+		return NewNATSSink(arg)
+	})
+	RegisterOutputSink("kafka-rest", func(arg string) (OutputSink, error) {
+		endpoint, topic, err := splitTopicURL(arg, "http", "https")
+		if err != nil {
+			return nil, fmt.Errorf("%w, e.g. kafka-rest:http://rest-proxy:8082/gospider-findings", err)
+		}
+		return NewKafkaRESTSink(endpoint, topic), nil
+	})
+}
+
+// splitTopicURL splits a sink URL into the server and the topic named by
+// the last path element
+func splitTopicURL(raw string, schemes ...string) (string, string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", "", err
+	}
+	known := false
+	for _, scheme := range schemes {
+		known = known || u.Scheme == scheme
+	}
+	path := strings.Trim(u.Path, "/")
+	if !known || u.Host == "" || path == "" {
+		return "", "", fmt.Errorf("expected a %s:// URL ending in a topic", strings.Join(schemes, ":// or "))
+	}
+	base, topic := "", path
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		base, topic = "/"+path[:i], path[i+1:]
+	}
+	u.Path, u.RawPath = base, ""
+	return u.String(), topic, nil
+}
+
+// NewKafkaRESTSink publishes findings to topic through the Kafka REST proxy
+// at endpoint, batched and retried like a webhook. Records are keyed by the
+// host of the finding so one host's findings stay in one partition
+func NewKafkaRESTSink(endpoint, topic string) *WebhookSink {
+	return startBatchSink(&WebhookSink{
+		endpoint:    strings.TrimSuffix(endpoint, "/") + "/topics/" + url.PathEscape(topic),
+		retryDelay:  time.Second,
+		encode:      encodeKafkaRecords,
+		contentType: "application/vnd.kafka.json.v2+json",
+	})
+}
+
+type kafkaRecord struct {
+	Key   string       `json:"key,omitempty"`
+	Value SpiderOutput `json:"value"`
+}
+
+func encodeKafkaRecords(batch []SpiderOutput) ([]byte, error) {
+	records := make([]kafkaRecord, len(batch))
+	for i, sout := range batch {
+		records[i] = kafkaRecord{Value: sout}
+		if u := outputURL(sout.Output); u != nil {
+			records[i].Key = u.Host
+		}
+	}
+	return jsoniter.Marshal(struct {
+		Records []kafkaRecord `json:"records"`
+	}{records})
+}
+
+// natsTimeout bounds connecting to NATS and waiting for its replies
+const natsTimeout = 10 * time.Second
+
+var errNATSClosed = errors.New("NATS sink closed")
+
+// NATSSink publishes each finding as a JSON message on a NATS subject. Each
+// message is followed by a PING, so a publish the server rejects fails its
+// own Write. A broken connection is redialled once per message
+type NATSSink struct {
+	server  *url.URL
+	subject string
+
+	// mu guards conn; dialMu lets one writer redial a broken connection
+	// while the others wait for it
+	mu     sync.Mutex
+	conn   *natsConn
+	dialMu sync.Mutex
+}
+
+// natsConn is one connection to the server
+type natsConn struct {
+	net.Conn
+	// wmu guards w, which publishes and the reader's PONGs share; pubMu
+	// holds one publish at a time from its PUB to the server's reply
+	wmu   sync.Mutex
+	w     *bufio.Writer
+	pubMu sync.Mutex
+	// pong receives the server's PONGs and err its -ERR lines; done is
+	// closed when the server hangs up
+	pong chan struct{}
+	err  chan error
+	done chan struct{}
+}
+
+// NewNATSSink connects to a server given as nats://[user:pass@]host:port/subject,
+// or tls:// for TLS
+func NewNATSSink(raw string) (*NATSSink, error) {
+	server, subject, err := splitTopicURL(raw, "nats", "tls")
+	if err != nil {
+		return nil, fmt.Errorf("%w, e.g. nats:nats://localhost:4222/gospider.findings", err)
+	}
+	subject = strings.ReplaceAll(subject, "/", ".")
+	u, _ := url.Parse(server)
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	s := &NATSSink{server: u, subject: subject}
+	if s.conn, err = s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// connect dials the server, authenticates and waits for the server to
+// acknowledge with a PONG, so bad credentials fail here
+func (s *NATSSink) connect() (*natsConn, error) {
+	dialer := &net.Dialer{Timeout: natsTimeout}
+	var conn net.Conn
+	var err error
+	if s.server.Scheme == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.server.Host, &tls.Config{ServerName: s.server.Hostname()})
+	} else {
+		conn, err = dialer.Dial("tcp", s.server.Host)
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(conn)
+	_ = conn.SetReadDeadline(time.Now().Add(natsTimeout))
+	line, err := r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return nil, fmt.Errorf("%s is not a NATS server", s.server.Host)
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	options := map[string]interface{}{"verbose": false, "pedantic": false, "name": CLIName, "lang": "go", "version": VERSION}
+	if user := s.server.User; user != nil {
+		if pass, ok := user.Password(); ok {
+			options["user"], options["pass"] = user.Username(), pass
+		} else {
+			options["auth_token"] = user.Username()
+		}
+	}
+	data, _ := jsoniter.Marshal(options)
+	c := &natsConn{Conn: conn, w: bufio.NewWriter(conn), pong: make(chan struct{}, 1), err: make(chan error, 1), done: make(chan struct{})}
+	go s.read(c, r)
+	if err := s.send(c, fmt.Sprintf("CONNECT %s\r\n", data)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// read answers the server's PINGs and forwards PONGs and errors
+func (s *NATSSink) read(c *natsConn, r *bufio.Reader) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			close(c.done)
+			return
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PING":
+			c.wmu.Lock()
+			c.w.WriteString("PONG\r\n")
+			c.w.Flush()
+			c.wmu.Unlock()
+		case line == "PONG":
+			select {
+			case c.pong <- struct{}{}:
+			default:
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			select {
+			case c.err <- fmt.Errorf("NATS %s: %s", s.server.Host, strings.Trim(strings.TrimPrefix(line, "-ERR "), "'")):
+			default:
+			}
+			Logger.Debugf("NATS %s: %s", s.server.Host, line)
+		}
+	}
+}
+
+// send writes cmd followed by a PING and waits for the server's reply. An
+// -ERR sent in reply to cmd is returned as is; failing to write or to hear
+// back means the connection is broken
+func (s *NATSSink) send(c *natsConn, cmd string) error {
+	c.pubMu.Lock()
+	defer c.pubMu.Unlock()
+	// Drop a reply nobody waited for, such as an -ERR to a PUB that
+	// timed out, so it is not taken for this one
+	select {
+	case <-c.pong:
+	default:
+	}
+	select {
+	case <-c.err:
+	default:
+	}
+
+	c.wmu.Lock()
+	c.w.WriteString(cmd)
+	c.w.WriteString("PING\r\n")
+	err := c.w.Flush()
+	c.wmu.Unlock()
+	if err != nil {
+		return &natsBrokenError{err}
+	}
+	select {
+	case <-c.pong:
+		return nil
+	case err := <-c.err:
+		return err
+	case <-c.done:
+		// An -ERR is often the last thing a server sends
+		select {
+		case err := <-c.err:
+			return &natsBrokenError{err}
+		default:
+			return &natsBrokenError{fmt.Errorf("NATS %s closed the connection", s.server.Host)}
+		}
+	case <-time.After(natsTimeout):
+		return &natsBrokenError{fmt.Errorf("NATS %s did not answer", s.server.Host)}
+	}
+}
+
+// natsBrokenError is a send that failed on the connection rather than
+// being rejected by the server, so it is worth redialling
+type natsBrokenError struct{ err error }
+
+func (e *natsBrokenError) Error() string { return e.err.Error() }
+func (e *natsBrokenError) Unwrap() error { return e.err }
+
+func (s *NATSSink) Write(sout SpiderOutput) error {
+	payload, err := jsoniter.Marshal(sout)
+	if err != nil {
+		return err
+	}
+	cmd := fmt.Sprintf("PUB %s %d\r\n%s\r\n", s.subject, len(payload), payload)
+	s.mu.Lock()
+	c := s.conn
+	s.mu.Unlock()
+	if c == nil {
+		return errNATSClosed
+	}
+	err = s.send(c, cmd)
+	var broken *natsBrokenError
+	if !errors.As(err, &broken) {
+		return err
+	}
+	if c, err = s.reconnect(c); err != nil {
+		return err
+	}
+	return s.send(c, cmd)
+}
+
+// reconnect replaces the broken connection, unless another writer already
+// did, and returns the one to use. It dials without holding mu
+func (s *NATSSink) reconnect(broken *natsConn) (*natsConn, error) {
+	s.dialMu.Lock()
+	defer s.dialMu.Unlock()
+	s.mu.Lock()
+	c := s.conn
+	s.mu.Unlock()
+	if c != broken {
+		if c == nil {
+			return nil, errNATSClosed
+		}
+		return c, nil
+	}
+	broken.Close()
+	fresh, err := s.connect()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != broken {
+		fresh.Close()
+		return nil, errNATSClosed
+	}
+	s.conn = fresh
+	return fresh, nil
+}
+
+// Close waits for the message being published, if any, and disconnects.
+// Every Write has been acknowledged by then, so nothing is left to flush
+func (s *NATSSink) Close() {
+	s.mu.Lock()
+	c := s.conn
+	s.conn = nil
+	s.mu.Unlock()
+	if c == nil {
+		return
+	}
+	c.pubMu.Lock()
+	defer c.pubMu.Unlock()
+	c.Close()
+}
@@ -0,0 +1,188 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitTopicURL(t *testing.T) {
+	server, topic, err := splitTopicURL("http://proxy:8082/kafka/findings", "http", "https")
+	require.NoError(t, err)
+	assert.Equal(t, "http://proxy:8082/kafka", server)
+	assert.Equal(t, "findings", topic)
+
+	_, _, err = splitTopicURL("http://proxy:8082/", "http")
+	assert.Error(t, err)
+	_, _, err = splitTopicURL("ftp://proxy/findings", "http")
+	assert.Error(t, err)
+}
+
+func TestKafkaRESTSink(t *testing.T) {
+	var body struct {
+		Records []kafkaRecord `json:"records"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/topics/findings", r.URL.Path)
+		assert.Equal(t, "application/vnd.kafka.json.v2+json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+	}))
+	defer srv.Close()
+
+	sink, err := OpenOutputSink("kafka-rest:" + srv.URL + "/findings")
+	require.NoError(t, err)
+	require.NoError(t, sink.Write(SpiderOutput{Output: "https://example.com/a", OutputType: "url"}))
+	require.NoError(t, sink.Write(SpiderOutput{Output: "example.com", OutputType: "subdomain"}))
+	sink.Close()
+
+	require.Len(t, body.Records, 2)
+	assert.Equal(t, "example.com", body.Records[0].Key)
+	assert.Equal(t, "https://example.com/a", body.Records[0].Value.Output)
+	assert.Empty(t, body.Records[1].Key)
+}
+
+// fakeNATS records the CONNECT options and messages of its clients. It
+// answers PUBs to the denied subject with -ERR and hangs up on a client
+// after drop more messages
+type fakeNATS struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	connect  string
+	conns    int
+	messages map[string][]string
+	denied   string
+	drop     int
+}
+
+func newFakeNATS(t *testing.T, reject bool) *fakeNATS {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	f := &fakeNATS{ln: ln, messages: map[string][]string{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.conns++
+			f.mu.Unlock()
+			go f.serve(conn, reject)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return f
+}
+
+func (f *fakeNATS) serve(conn net.Conn, reject bool) {
+	defer conn.Close()
+	fmt.Fprint(conn, "INFO {\"server_id\":\"test\"}\r\n")
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "CONNECT":
+			f.mu.Lock()
+			f.connect = strings.TrimSpace(strings.TrimPrefix(line, "CONNECT "))
+			f.mu.Unlock()
+			if reject {
+				fmt.Fprint(conn, "-ERR 'Authorization Violation'\r\n")
+				return
+			}
+		case "PING":
+			fmt.Fprint(conn, "PONG\r\n")
+		case "PUB":
+			var n int
+			fmt.Sscan(fields[2], &n)
+			payload := make([]byte, n+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return
+			}
+			f.mu.Lock()
+			denied, drop := fields[1] == f.denied, f.drop == 1
+			if !denied {
+				f.messages[fields[1]] = append(f.messages[fields[1]], string(payload[:n]))
+			}
+			if f.drop > 0 {
+				f.drop--
+			}
+			f.mu.Unlock()
+			if denied {
+				fmt.Fprintf(conn, "-ERR 'Permissions Violation for Publish to \"%s\"'\r\n", fields[1])
+			}
+			if drop {
+				return
+			}
+		}
+	}
+}
+
+func TestNATSSink(t *testing.T) {
+	srv := newFakeNATS(t, false)
+	sink, err := OpenOutputSink("nats:nats://user:secret@" + srv.ln.Addr().String() + "/recon.findings")
+	require.NoError(t, err)
+	require.NoError(t, sink.Write(SpiderOutput{Output: "https://example.com/a", OutputType: "url"}))
+	require.NoError(t, sink.Write(SpiderOutput{Output: "https://example.com/b", OutputType: "url"}))
+	sink.Close()
+	assert.Error(t, sink.Write(SpiderOutput{}))
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	assert.Contains(t, srv.connect, `"user":"user"`)
+	assert.Contains(t, srv.connect, `"pass":"secret"`)
+	require.Len(t, srv.messages["recon.findings"], 2)
+	var sout SpiderOutput
+	require.NoError(t, json.Unmarshal([]byte(srv.messages["recon.findings"][1]), &sout))
+	assert.Equal(t, "https://example.com/b", sout.Output)
+}
+
+func TestNATSSinkRejected(t *testing.T) {
+	srv := newFakeNATS(t, true)
+	_, err := NewNATSSink("nats://" + srv.ln.Addr().String() + "/findings")
+	assert.ErrorContains(t, err, "Authorization Violation")
+}
+
+func TestNATSSinkPublishErrors(t *testing.T) {
+	srv := newFakeNATS(t, false)
+	sink, err := NewNATSSink("nats://" + srv.ln.Addr().String() + "/findings")
+	require.NoError(t, err)
+	defer sink.Close()
+
+	srv.mu.Lock()
+	srv.denied = "findings"
+	srv.mu.Unlock()
+	assert.ErrorContains(t, sink.Write(SpiderOutput{Output: "https://example.com/a"}), "Permissions Violation", "a rejected publish fails its own Write")
+
+	// The server hangs up after taking the next message without answering
+	// its PING, so the message is sent again on a new connection
+	srv.mu.Lock()
+	srv.denied, srv.drop = "", 1
+	srv.mu.Unlock()
+	require.NoError(t, sink.Write(SpiderOutput{Output: "https://example.com/b"}))
+	require.NoError(t, sink.Write(SpiderOutput{Output: "https://example.com/c"}))
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	assert.Equal(t, 2, srv.conns)
+	var outputs []string
+	for _, message := range srv.messages["findings"] {
+		var sout SpiderOutput
+		require.NoError(t, json.Unmarshal([]byte(message), &sout))
+		outputs = append(outputs, sout.Output)
+	}
+	assert.Equal(t, []string{"https://example.com/b", "https://example.com/b", "https://example.com/c"}, outputs)
+}
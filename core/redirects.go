@@ -0,0 +1,130 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// defaultMaxRedirects is the redirect limit when MaxRedirects is unset
+const defaultMaxRedirects = 10
+
+// redirectLimit returns how many redirects a request may follow: the
+// default for an unset MaxRedirects, none for a negative one
+func (cfg *CrawlerConfig) redirectLimit() int {
+	switch {
+	case cfg.MaxRedirects == 0:
+		return defaultMaxRedirects
+	case cfg.MaxRedirects < 0:
+		return 0
+	}
+	return cfg.MaxRedirects
+}
+
+// redirectPolicy bounds redirect depth and optionally remembers the chain
+// that led to each final URL so it can be reported once the response lands.
+type redirectPolicy struct {
+	host         string
+	sameHostOnly bool
+	maxRedirects int
+	trackChains  bool
+	chains       sync.Map // first or last URL -> *redirectChain
+}
+
+// redirectChain is a recorded chain and the URL it ended at
+type redirectChain struct {
+	last string
+	hops []string
+}
+
+func newRedirectPolicy(host string, sameHostOnly bool, maxRedirects int, trackChains bool) *redirectPolicy {
+	return &redirectPolicy{
+		host:         host,
+		sameHostOnly: sameHostOnly,
+		maxRedirects: maxRedirects,
+		trackChains:  trackChains,
+	}
+}
+
+// CheckRedirect is installed as http.Client.CheckRedirect. Stopping a chain
+// returns http.ErrUseLastResponse so the last 3xx is still processed; loops
+// are bounded by maxRedirects and show up as repeated hops in the chain.
+func (rp *redirectPolicy) CheckRedirect(req *http.Request, via []*http.Request) error {
+	nextLocation := req.URL.String()
+	Logger.Debugf("Found Redirect: %s", nextLocation)
+
+	chain := make([]string, 0, len(via)+1)
+	for _, r := range via {
+		chain = append(chain, r.URL.String())
+	}
+	chain = append(chain, nextLocation)
+	last := via[len(via)-1].URL.String()
+
+	if len(via) > rp.maxRedirects {
+		Logger.Debugf("Stopped after %d redirects at %s", rp.maxRedirects, last)
+		rp.record(last, append(chain, "(max redirects reached)"))
+		return http.ErrUseLastResponse
+	}
+	if rp.sameHostOnly && !strings.Contains(nextLocation, rp.host) {
+		rp.record(last, chain)
+		return http.ErrUseLastResponse
+	}
+
+	if rp.sameHostOnly {
+		Logger.Infof("Redirecting to: %s", nextLocation)
+	}
+	if rp.trackChains {
+		rp.chains.Delete(last)
+	}
+	rp.record(nextLocation, chain)
+	return nil
+}
+
+// record stores chain under the URL it ended at and the URL it started
+// from. Colly hands a response it treats as an error, such as the 3xx a
+// chain was stopped at, to OnError before updating the request URL, so
+// those responses still carry the first URL
+func (rp *redirectPolicy) record(last string, chain []string) {
+	if !rp.trackChains {
+		return
+	}
+	entry := &redirectChain{last: last, hops: chain}
+	rp.chains.Store(last, entry)
+	rp.chains.Store(chain[0], entry)
+}
+
+// takeChain returns and forgets the redirect chain that started or ended
+// at u.
+func (rp *redirectPolicy) takeChain(u string) []string {
+	if rp == nil || !rp.trackChains {
+		return nil
+	}
+	v, ok := rp.chains.LoadAndDelete(u)
+	if !ok {
+		return nil
+	}
+	entry := v.(*redirectChain)
+	rp.chains.Delete(entry.last)
+	rp.chains.Delete(entry.hops[0])
+	return entry.hops
+}
+
+func (crawler *Crawler) emitRedirectChain(finalURL string, statusCode int) {
+	chain := crawler.redirects.takeChain(finalURL)
+	if len(chain) == 0 {
+		return
+	}
+	snippet := strings.Join(chain, " -> ")
+
+	outputFormat := fmt.Sprintf("[redirect] - [%d] %s", statusCode, snippet)
+	sout := SpiderOutput{
+		Input:      crawler.Input,
+		Source:     "redirect",
+		OutputType: "redirect",
+		Output:     finalURL,
+		StatusCode: statusCode,
+		Snippet:    snippet,
+	}
+	crawler.emit(sout, outputFormat, snippet)
+}
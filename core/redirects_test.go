@@ -0,0 +1,84 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCrawlRedirectLoop(t *testing.T) {
+	var hops atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			http.Redirect(w, r, "/a", http.StatusFound)
+		case "/a":
+			hops.Add(1)
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			hops.Add(1)
+			http.Redirect(w, r, "/a", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	results := crawlAll(t, srv.URL, CrawlerConfig{MaxDepth: 1, MaxRedirects: 3, RedirectChain: true})
+
+	var chains []SpiderOutput
+	for _, sout := range results {
+		if sout.OutputType == "redirect" {
+			chains = append(chains, sout)
+		}
+	}
+	assert.EqualValues(t, 3, hops.Load(), "the loop is followed for --max-redirects hops")
+	require.Len(t, chains, 1)
+	assert.Equal(t, http.StatusFound, chains[0].StatusCode)
+	assert.Equal(t, srv.URL+" -> "+srv.URL+"/a -> "+srv.URL+"/b -> "+srv.URL+"/a -> "+srv.URL+"/b -> (max redirects reached)", chains[0].Snippet)
+}
+
+func TestRedirectPolicyChains(t *testing.T) {
+	rp := newRedirectPolicy("example.com", false, 10, true)
+	req := func(u string) *http.Request {
+		r, _ := http.NewRequest(http.MethodGet, u, nil)
+		return r
+	}
+	start, mid, end := req("https://example.com/"), req("https://example.com/login"), req("https://example.com/home")
+	require.NoError(t, rp.CheckRedirect(mid, []*http.Request{start}))
+	require.NoError(t, rp.CheckRedirect(end, []*http.Request{start, mid}))
+
+	assert.Nil(t, rp.takeChain("https://example.com/login"), "intermediate hops are not reported")
+	assert.Equal(t, []string{"https://example.com/", "https://example.com/login", "https://example.com/home"}, rp.takeChain("https://example.com/home"))
+	assert.Nil(t, rp.takeChain("https://example.com/"), "a chain is reported once")
+}
+
+func TestCrawlFollowsRedirectsByDefault(t *testing.T) {
+	var landed atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/home", http.StatusFound)
+			return
+		}
+		landed.Store(true)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html>home</html>"))
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	results, err := Crawl(ctx, srv.URL, CrawlerConfig{})
+	require.NoError(t, err)
+	for range results {
+	}
+	assert.True(t, landed.Load(), "an unset MaxRedirects follows up to the default")
+
+	landed.Store(false)
+	crawlAll(t, srv.URL, CrawlerConfig{MaxRedirects: -1})
+	assert.False(t, landed.Load(), "a negative MaxRedirects follows none")
+}
@@ -0,0 +1,190 @@
+package core
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisTimeout bounds dialing and every command but blocking pops
+const redisTimeout = 10 * time.Second
+
+// errRedisNil is returned for Redis nil replies
+var errRedisNil = errors.New("redis: nil")
+
+// redisClient is a minimal RESP2 client, enough for the distributed
+// frontier. Commands run one at a time; a broken connection is redialled
+// on the next command
+type redisClient struct {
+	server *url.URL
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// dialRedis connects to redis://[:password@]host:port/db, or rediss:// for TLS
+func dialRedis(raw string) (*redisClient, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" || u.Host == "" {
+		return nil, fmt.Errorf("invalid Redis URL %q, expected redis://host:port/db", raw)
+	}
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	c := &redisClient{server: u}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// connect dials and authenticates; c.mu must be held
+func (c *redisClient) connect() error {
+	dialer := &net.Dialer{Timeout: redisTimeout}
+	var conn net.Conn
+	var err error
+	if c.server.Scheme == "rediss" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", c.server.Host, &tls.Config{ServerName: c.server.Hostname()})
+	} else {
+		conn, err = dialer.Dial("tcp", c.server.Host)
+	}
+	if err != nil {
+		return err
+	}
+	c.conn, c.r = conn, bufio.NewReader(conn)
+
+	var setup [][]string
+	if user := c.server.User; user != nil {
+		if pass, ok := user.Password(); ok && user.Username() != "" {
+			setup = append(setup, []string{"AUTH", user.Username(), pass})
+		} else if ok {
+			setup = append(setup, []string{"AUTH", pass})
+		} else {
+			setup = append(setup, []string{"AUTH", user.Username()})
+		}
+	}
+	if db := strings.Trim(c.server.Path, "/"); db != "" && db != "0" {
+		setup = append(setup, []string{"SELECT", db})
+	}
+	for _, args := range setup {
+		if _, err := c.roundTrip(redisTimeout, args); err != nil {
+			c.close()
+			return fmt.Errorf("redis %s: %s", args[0], err)
+		}
+	}
+	return nil
+}
+
+// do runs a command, redialling once if the connection broke
+func (c *redisClient) do(args ...string) (interface{}, error) {
+	return c.doTimeout(redisTimeout, args...)
+}
+
+func (c *redisClient) doTimeout(timeout time.Duration, args ...string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		if err := c.connect(); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := c.roundTrip(timeout, args)
+	var netErr net.Error
+	if err != nil && (errors.Is(err, io.EOF) || errors.As(err, &netErr)) {
+		c.close()
+		if err = c.connect(); err != nil {
+			return nil, err
+		}
+		reply, err = c.roundTrip(timeout, args)
+	}
+	return reply, err
+}
+
+func (c *redisClient) roundTrip(timeout time.Duration, args []string) (interface{}, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	_ = c.conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return readRedisReply(c.r)
+}
+
+func readRedisReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, errors.New(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, errRedisNil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, errRedisNil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readRedisReply(r); err != nil && !errors.Is(err, errRedisNil) {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
+
+// close drops the connection; c.mu must be held
+func (c *redisClient) close() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn, c.r = nil, nil
+	}
+}
+
+func (c *redisClient) Close() {
+	c.mu.Lock()
+	c.close()
+	c.mu.Unlock()
+}
@@ -0,0 +1,192 @@
+package core
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// replayDir is the folder of --replay-files inside the output folder
+const replayDir = "replay"
+
+// replayNameUnsafe matches what a replay file name part may not hold
+var replayNameUnsafe = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// replayNamePart reduces s to [a-z0-9_-] for use in a file name
+func replayNamePart(s string) string {
+	return replayNameUnsafe.ReplaceAllString(strings.ToLower(s), "_")
+}
+
+// replayRequest is a request as it was, or would be, sent on the wire
+type replayRequest struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   string
+}
+
+// ReplayWriter writes each finding's request twice into a folder: as a raw
+// HTTP request (.http) to paste into Burp Repeater, and as a curl command
+// (.sh). Files are named after the request, so the same request found twice
+// is written once
+type ReplayWriter struct {
+	dir string
+
+	mu      sync.Mutex
+	written map[string]struct{}
+}
+
+// NewReplayWriter returns a writer storing its files in dir, which is
+// created on the first write
+func NewReplayWriter(dir string) *ReplayWriter {
+	return &ReplayWriter{dir: dir, written: make(map[string]struct{})}
+}
+
+// Write stores req under a name starting with kind and returns the path of
+// the raw request file
+func (w *ReplayWriter) Write(kind string, req replayRequest) (string, error) {
+	u, err := url.Parse(req.URL)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid replay URL %q", req.URL)
+	}
+	raw := rawHTTPRequest(req, u)
+	sum := sha1.Sum([]byte(raw))
+	// The method comes from scripts on the crawled site, so only a known
+	// one makes it into the name
+	method := "other"
+	if m := replayMethod(req); isHTTPMethodName(m) {
+		method = m
+	}
+	name := fmt.Sprintf("%s-%s-%s-%s", replayNamePart(kind), replayNamePart(method), replayNamePart(u.Hostname()), hex.EncodeToString(sum[:5]))
+	path := filepath.Join(w.dir, name+".http")
+	if rel, err := filepath.Rel(w.dir, path); err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("replay file %q is outside %s", name, w.dir)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.written[name]; ok {
+		return path, nil
+	}
+	if err := os.MkdirAll(w.dir, os.ModePerm); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(raw), 0644); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(w.dir, name+".sh"), []byte("#!/bin/sh\n"+curlCommand(req)+"\n"), 0755); err != nil {
+		return "", err
+	}
+	w.written[name] = struct{}{}
+	return path, nil
+}
+
+// rawHTTPRequest renders req as an HTTP/1.1 request with CRLF line endings
+func rawHTTPRequest(req replayRequest, u *url.URL) string {
+	var b strings.Builder
+	target := u.RequestURI()
+	fmt.Fprintf(&b, "%s %s HTTP/1.1\r\n", replayMethod(req), target)
+	fmt.Fprintf(&b, "Host: %s\r\n", u.Host)
+	for _, name := range replayHeaderNames(req.Header) {
+		for _, value := range req.Header[name] {
+			fmt.Fprintf(&b, "%s: %s\r\n", name, value)
+		}
+	}
+	if req.Body != "" {
+		fmt.Fprintf(&b, "Content-Length: %s\r\n", strconv.Itoa(len(req.Body)))
+	}
+	b.WriteString("\r\n")
+	b.WriteString(req.Body)
+	return b.String()
+}
+
+// curlCommand renders req as a curl command line for a POSIX shell
+func curlCommand(req replayRequest) string {
+	parts := []string{"curl", "-i", "-s", "-k"}
+	if method := replayMethod(req); method != http.MethodGet || req.Body != "" {
+		parts = append(parts, "-X", shellQuote(method))
+	}
+	for _, name := range replayHeaderNames(req.Header) {
+		for _, value := range req.Header[name] {
+			parts = append(parts, "-H", shellQuote(name+": "+value))
+		}
+	}
+	if req.Body != "" {
+		parts = append(parts, "--data-binary", shellQuote(req.Body))
+	}
+	parts = append(parts, shellQuote(req.URL))
+	return strings.Join(parts, " ")
+}
+
+func replayMethod(req replayRequest) string {
+	if method := strings.ToUpper(strings.TrimSpace(req.Method)); method != "" {
+		return method
+	}
+	return http.MethodGet
+}
+
+// replayHeaderNames sorts the headers to replay, leaving out those the
+// client computes
+func replayHeaderNames(header http.Header) []string {
+	var names []string
+	for name := range header {
+		switch http.CanonicalHeaderKey(name) {
+		case "Host", "Content-Length", "Accept-Encoding":
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// interestingJSRequest reports whether a js-request is worth a replay
+// file: anything but a bare GET
+func interestingJSRequest(req JSRequest) bool {
+	method := strings.ToUpper(strings.TrimSpace(req.Method))
+	return method != "" && method != http.MethodGet && method != http.MethodHead || req.Body != "" || len(req.Headers) > 0
+}
+
+// writeJSRequestReplay writes the replay files of a js-request with the
+// headers the crawl sends with it
+func (crawler *Crawler) writeJSRequestReplay(req JSRequest) {
+	if crawler.replayFiles == nil || !interestingJSRequest(req) {
+		return
+	}
+	header := crawler.sessionHeaders.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	for name, value := range req.Headers {
+		header.Set(name, value)
+	}
+	if req.ContentType != "" && header.Get("Content-Type") == "" {
+		header.Set("Content-Type", req.ContentType)
+	}
+	crawler.writeReplay("js-request", replayRequest{Method: req.Method, URL: req.RawURL, Header: header, Body: req.Body})
+}
+
+func (crawler *Crawler) writeReplay(kind string, req replayRequest) {
+	if crawler.replayFiles == nil {
+		return
+	}
+	path, err := crawler.replayFiles.Write(kind, req)
+	if err != nil {
+		Logger.Debugf("Failed to write replay files for %s %s: %s", req.Method, req.URL, err)
+		return
+	}
+	Logger.Debugf("Wrote replay files %s", strings.TrimSuffix(path, ".http"))
+}
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayFormats(t *testing.T) {
+	req := replayRequest{
+		Method: "post",
+		URL:    "https://api.example.com:8443/v1/items?id=1",
+		Header: http.Header{"Cookie": {"sid=1"}, "Content-Type": {"application/json"}, "Content-Length": {"99"}},
+		Body:   `{"name":"it's"}`,
+	}
+	u, _ := url.Parse(req.URL)
+	assert.Equal(t, "POST /v1/items?id=1 HTTP/1.1\r\n"+
+		"Host: api.example.com:8443\r\n"+
+		"Content-Type: application/json\r\n"+
+		"Cookie: sid=1\r\n"+
+		"Content-Length: 15\r\n"+
+		"\r\n"+
+		`{"name":"it's"}`, rawHTTPRequest(req, u))
+	assert.Equal(t, `curl -i -s -k -X 'POST' -H 'Content-Type: application/json' -H 'Cookie: sid=1' --data-binary '{"name":"it'\''s"}' 'https://api.example.com:8443/v1/items?id=1'`, curlCommand(req))
+	assert.Equal(t, `curl -i -s -k 'https://example.com/'`, curlCommand(replayRequest{URL: "https://example.com/"}))
+
+	assert.False(t, interestingJSRequest(JSRequest{Method: "GET", RawURL: "/a"}))
+	assert.True(t, interestingJSRequest(JSRequest{Method: "DELETE", RawURL: "/a"}))
+	assert.True(t, interestingJSRequest(JSRequest{Method: "GET", RawURL: "/a", Headers: map[string]string{"X-Token": "t"}}))
+}
+
+func TestReplayWriter(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), replayDir)
+	w := NewReplayWriter(dir)
+	req := replayRequest{Method: "PUT", URL: "https://api.example.com/items/1", Body: "a=1"}
+	path, err := w.Write("js-request", req)
+	require.NoError(t, err)
+	again, err := w.Write("js-request", req)
+	require.NoError(t, err)
+	assert.Equal(t, path, again)
+	assert.True(t, strings.HasPrefix(filepath.Base(path), "js-request-put-api_example_com-"))
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	assert.Len(t, files, 2, "a request found twice is written once")
+	script, err := os.ReadFile(strings.TrimSuffix(path, ".http") + ".sh")
+	require.NoError(t, err)
+	assert.Contains(t, string(script), "curl -i -s -k -X 'PUT'")
+
+	_, err = w.Write("js-request", replayRequest{Method: "GET", URL: "/relative"})
+	assert.Error(t, err)
+}
+
+func TestReplayWriterHostileMethod(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "out", replayDir)
+	w := NewReplayWriter(dir)
+	path, err := w.Write("js-request", replayRequest{Method: "x/../../../../evil", URL: "https://example.com/a"})
+	require.NoError(t, err)
+	assert.Equal(t, dir, filepath.Dir(path))
+	assert.True(t, strings.HasPrefix(filepath.Base(path), "js-request-other-example_com-"))
+
+	path, err = w.Write("../kind", replayRequest{Method: "post", URL: "https://example.com/a"})
+	require.NoError(t, err)
+	assert.Equal(t, dir, filepath.Dir(path))
+	assert.True(t, strings.HasPrefix(filepath.Base(path), "_kind-post-"))
+
+	files, _ := filepath.Glob(filepath.Join(root, "*"))
+	assert.Equal(t, []string{filepath.Join(root, "out")}, files, "nothing is written outside the folder")
+}
+
+func TestCrawlWritesReplayFiles(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<form action="/search"><input name="q" value="shoes"></form>` +
+				`<form action="/api/items" method="post"><input name="name" value="lamp"></form>`))
+		case "/search":
+			w.Write([]byte("<p>Results for " + r.URL.Query().Get("q") + "</p>"))
+		default:
+			w.Write([]byte(`ok`))
+		}
+	}))
+	defer srv.Close()
+
+	out := t.TempDir()
+	results := crawlAll(t, srv.URL, CrawlerConfig{
+		Reflected: true, Cookie: "session=abc", OutputDir: out, ReplayFiles: true,
+	})
+	reflected := false
+	for _, sout := range results {
+		reflected = reflected || sout.OutputType == "reflected"
+	}
+	require.True(t, reflected)
+
+	requests, _ := filepath.Glob(filepath.Join(out, replayDir, "reflected-get-*.http"))
+	require.NotEmpty(t, requests)
+	raw, err := os.ReadFile(requests[0])
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(raw), "GET /search?"))
+	assert.Contains(t, string(raw), "Cookie: session=abc")
+
+	requests, _ = filepath.Glob(filepath.Join(out, replayDir, "js-request-post-*.http"))
+	var bodies []string
+	for _, path := range requests {
+		raw, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(raw), "POST /api/items HTTP/1.1\r\n"))
+		assert.Contains(t, string(raw), "Cookie: session=abc")
+		bodies = append(bodies, string(raw[strings.Index(string(raw), "\r\n\r\n")+4:]))
+	}
+	assert.Contains(t, bodies, "name=lamp")
+}
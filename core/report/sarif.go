@@ -0,0 +1,298 @@
+// Package report converts crawl findings into formats that triage tools
+// import, such as SARIF for GitHub code scanning.
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolURI      = "https://github.com/jaeles-project/gospider"
+
+	// fingerprintKey names the partial fingerprint that lets triage tools
+	// match a finding across runs
+	fingerprintKey = "gospiderFinding/v1"
+
+	reflectedRuleID   = "reflected-input"
+	domSinkRulePrefix = "dom-sink/"
+)
+
+// Reflection is a request parameter whose value came back in the response
+type Reflection struct {
+	URL     string
+	Method  string
+	Param   string
+	Payload string
+	Origin  string
+	Status  int
+	Reasons []string
+}
+
+// DOMSink is a DOM sink fed from an attacker controlled source, found by
+// static analysis of a page or script
+type DOMSink struct {
+	URL        string
+	Rule       string
+	Source     string
+	Sink       string
+	Snippet    string
+	Confidence string
+	// Line is the 1-based line of the snippet in the analysed code, 0 if unknown
+	Line int
+}
+
+// SARIF collects the findings of a run into a SARIF 2.1.0 log. It is shared
+// by all crawlers of a run and safe for concurrent use
+type SARIF struct {
+	path    string
+	name    string
+	version string
+
+	mu      sync.Mutex
+	seen    map[string]struct{}
+	rules   []sarifRule
+	ruleIdx map[string]int
+	results []sarifResult
+}
+
+// NewSARIF returns a log for the named tool that Save writes to path
+func NewSARIF(path, name, version string) *SARIF {
+	return &SARIF{
+		path:    path,
+		name:    name,
+		version: version,
+		seen:    make(map[string]struct{}),
+		ruleIdx: make(map[string]int),
+	}
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string          `json:"id"`
+	Name                 string          `json:"name"`
+	ShortDescription     sarifMessage    `json:"shortDescription"`
+	FullDescription      sarifMessage    `json:"fullDescription"`
+	DefaultConfiguration sarifRuleConfig `json:"defaultConfiguration"`
+	Properties           map[string]any  `json:"properties,omitempty"`
+}
+
+type sarifRuleConfig struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+	Properties          map[string]any    `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int           `json:"startLine"`
+	Snippet   *sarifMessage `json:"snippet,omitempty"`
+}
+
+// AddReflection records a reflected parameter
+func (s *SARIF) AddReflection(r Reflection) {
+	method := strings.ToUpper(r.Method)
+	if method == "" {
+		method = "GET"
+	}
+	reasons := strings.Join(r.Reasons, ",")
+	result := sarifResult{
+		Level: "warning",
+		Message: sarifMessage{Text: fmt.Sprintf("Parameter %q of %s %s is reflected in the response (%s)",
+			r.Param, method, r.URL, reasons)},
+		Locations: []sarifLocation{location(r.URL, nil)},
+		Properties: map[string]any{
+			"method":  method,
+			"param":   r.Param,
+			"payload": r.Payload,
+			"status":  r.Status,
+			"reasons": r.Reasons,
+			"source":  r.Origin,
+		},
+	}
+	s.add(sarifRule{
+		ID:                   reflectedRuleID,
+		Name:                 "ReflectedInput",
+		ShortDescription:     sarifMessage{Text: "Request parameter reflected in the response"},
+		FullDescription:      sarifMessage{Text: "A value sent in a request parameter appears in the response, possibly unencoded. Reflected input is the starting point of reflected XSS and injection bugs."},
+		DefaultConfiguration: sarifRuleConfig{Level: "warning"},
+	}, result, method, r.URL, r.Param)
+}
+
+// AddDOMSink records a suspicious DOM sink
+func (s *SARIF) AddDOMSink(f DOMSink) {
+	var region *sarifRegion
+	if f.Line > 0 {
+		region = &sarifRegion{StartLine: f.Line}
+		if f.Snippet != "" {
+			region.Snippet = &sarifMessage{Text: f.Snippet}
+		}
+	}
+	level := confidenceLevel(f.Confidence)
+	result := sarifResult{
+		Level:     level,
+		Message:   sarifMessage{Text: fmt.Sprintf("Possible DOM XSS: %s fed from an attacker controlled source in %s (%s)", f.Sink, f.URL, f.Source)},
+		Locations: []sarifLocation{location(f.URL, region)},
+		Properties: map[string]any{
+			"confidence": f.Confidence,
+			"sink":       f.Sink,
+			"source":     f.Source,
+			"snippet":    f.Snippet,
+		},
+	}
+	rule := f.Rule
+	if rule == "" {
+		rule = "unknown"
+	}
+	s.add(sarifRule{
+		ID:                   domSinkRulePrefix + rule,
+		Name:                 "DOMSink",
+		ShortDescription:     sarifMessage{Text: fmt.Sprintf("DOM sink %s fed from a controllable source", rule)},
+		FullDescription:      sarifMessage{Text: "Client-side code passes a value an attacker can control, such as the location, referrer or window name, to a sink that runs or renders it. Confirm the flow in a browser before reporting it."},
+		DefaultConfiguration: sarifRuleConfig{Level: level},
+	}, result, f.URL, f.Snippet)
+}
+
+func location(uri string, region *sarifRegion) sarifLocation {
+	return sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: uri},
+		Region:           region,
+	}}
+}
+
+// confidenceLevel maps a DOM analysis confidence onto a SARIF level
+func confidenceLevel(confidence string) string {
+	switch strings.ToLower(confidence) {
+	case "high":
+		return "error"
+	case "low":
+		return "note"
+	default:
+		return "warning"
+	}
+}
+
+// add records result under rule unless the same finding was added before.
+// The identity parts make up its fingerprint
+func (s *SARIF) add(rule sarifRule, result sarifResult, identity ...string) {
+	sum := sha256.Sum256([]byte(rule.ID + "\x00" + strings.Join(identity, "\x00")))
+	fingerprint := hex.EncodeToString(sum[:16])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.seen[fingerprint]; ok {
+		return
+	}
+	s.seen[fingerprint] = struct{}{}
+	idx, ok := s.ruleIdx[rule.ID]
+	if !ok {
+		rule.Properties = map[string]any{"tags": []string{"security"}}
+		idx = len(s.rules)
+		s.rules = append(s.rules, rule)
+		s.ruleIdx[rule.ID] = idx
+	}
+	result.RuleID = rule.ID
+	result.RuleIndex = idx
+	result.PartialFingerprints = map[string]string{fingerprintKey: fingerprint}
+	s.results = append(s.results, result)
+}
+
+// Len returns the number of findings recorded
+func (s *SARIF) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.results)
+}
+
+// Encode writes the log as indented JSON
+func (s *SARIF) Encode(w io.Writer) error {
+	s.mu.Lock()
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           s.name,
+			Version:        s.version,
+			InformationURI: toolURI,
+			Rules:          append([]sarifRule{}, s.rules...),
+		}},
+		Results: append([]sarifResult{}, s.results...),
+	}
+	s.mu.Unlock()
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(sarifLog{Version: sarifVersion, Schema: sarifSchema, Runs: []sarifRun{run}})
+}
+
+// Save writes the log to its file. A run without findings still writes a
+// valid log with no results, so an import clears earlier alerts
+func (s *SARIF) Save() error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".gospider-sarif-*")
+	if err != nil {
+		return err
+	}
+	if err := s.Encode(tmp); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,87 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSARIFEncodesFindings(t *testing.T) {
+	s := NewSARIF("", "gospider", "v1.0.0")
+	reflection := Reflection{
+		URL:     "http://example.com/search?q=x",
+		Method:  "get",
+		Param:   "q",
+		Payload: "<x>",
+		Origin:  "form",
+		Status:  200,
+		Reasons: []string{"payload-reflected", "body-delta"},
+	}
+	s.AddReflection(reflection)
+	s.AddReflection(reflection)
+	s.AddDOMSink(DOMSink{
+		URL:        "http://example.com/app.js",
+		Rule:       "eval-family",
+		Source:     "javascript",
+		Sink:       "eval",
+		Snippet:    "eval(location.hash)",
+		Confidence: "high",
+		Line:       12,
+	})
+	s.AddDOMSink(DOMSink{
+		URL:        "http://example.com/",
+		Rule:       "innerHTML-assignment",
+		Source:     "html",
+		Sink:       "innerHTML",
+		Snippet:    "el.innerHTML = location.search",
+		Confidence: "medium",
+	})
+	assert.Equal(t, 3, s.Len())
+
+	var buf bytes.Buffer
+	require.NoError(t, s.Encode(&buf))
+	var log sarifLog
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	run := log.Runs[0]
+	assert.Equal(t, "gospider", run.Tool.Driver.Name)
+
+	var ruleIDs []string
+	for _, rule := range run.Tool.Driver.Rules {
+		ruleIDs = append(ruleIDs, rule.ID)
+	}
+	assert.Equal(t, []string{"reflected-input", "dom-sink/eval-family", "dom-sink/innerHTML-assignment"}, ruleIDs)
+
+	require.Len(t, run.Results, 3)
+	for i, result := range run.Results {
+		assert.Equal(t, i, result.RuleIndex)
+		assert.Equal(t, ruleIDs[i], result.RuleID)
+		assert.NotEmpty(t, result.PartialFingerprints[fingerprintKey])
+	}
+	assert.Equal(t, "warning", run.Results[0].Level)
+	assert.Equal(t, "http://example.com/search?q=x", run.Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, "GET", run.Results[0].Properties["method"])
+
+	assert.Equal(t, "error", run.Results[1].Level)
+	region := run.Results[1].Locations[0].PhysicalLocation.Region
+	require.NotNil(t, region)
+	assert.Equal(t, 12, region.StartLine)
+	assert.Equal(t, "eval(location.hash)", region.Snippet.Text)
+	assert.Nil(t, run.Results[2].Locations[0].PhysicalLocation.Region)
+}
+
+func TestSARIFSaveWithoutFindings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.sarif")
+	require.NoError(t, NewSARIF(path, "gospider", "").Save())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"results": []`)
+	assert.Contains(t, string(data), `"rules": []`)
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// requestQueue bounds generated requests (JS requests, form variants,
+// reflection mutations) in front of crawler.C.Request. Once size of them are
+// waiting for a response, the callbacks generating more block until one
+// answers, so generation backpressures against sending instead of piling
+// goroutines up inside colly. Producers hand their request over themselves
+// while their own fetch is still running, which keeps colly's wait group
+// from dropping to zero under a concurrent Wait
+type requestQueue struct {
+	slots chan struct{}
+}
+
+// initRequestQueue puts a queue of size in front of crawler.C. It must run
+// before other response callbacks are registered so slots free up before any
+// callback queues more requests
+func (crawler *Crawler) initRequestQueue(size int) {
+	crawler.requestQueue = &requestQueue{slots: make(chan struct{}, size)}
+	crawler.registerSlotRelease()
+}
+
+// registerSlotRelease frees queue slots as responses and errors arrive. The
+// request and visit queues share it, so it is registered once
+func (crawler *Crawler) registerSlotRelease() {
+	if crawler.slotReleaseRegistered {
+		return
+	}
+	crawler.slotReleaseRegistered = true
+	crawler.C.OnResponse(func(response *colly.Response) { crawler.releaseRequestSlot(response.Ctx) })
+	crawler.C.OnError(func(response *colly.Response, _ error) { crawler.releaseRequestSlot(response.Ctx) })
+}
+
+// submitRequest hands a generated request to colly, first waiting for a slot
+// when the queue is enabled. Deterministic crawls run colly synchronously from
+// inside callbacks, so they never get a queue
+func (crawler *Crawler) submitRequest(method, rawURL string, body io.Reader, ctx *colly.Context, headers http.Header) {
+	if q := crawler.requestQueue; q != nil {
+		select {
+		case q.slots <- struct{}{}:
+		case <-crawler.stopChan:
+			return
+		case <-crawler.ctxDone():
+			return
+		}
+		if crawler.Stats != nil {
+			crawler.Stats.AddQueued(1)
+		}
+		var once sync.Once
+		ctx.Put("__release", func() {
+			once.Do(func() {
+				<-q.slots
+				if crawler.Stats != nil {
+					crawler.Stats.AddQueued(-1)
+				}
+			})
+		})
+	}
+	crawler.trackRequest(method, rawURL, ctx, headers)
+	if err := crawler.C.Request(method, rawURL, body, ctx, headers); err != nil {
+		Logger.Debugf("failed to queue request %s %s: %v", method, rawURL, err)
+		crawler.releaseRequestSlot(ctx)
+		crawler.state.Done(ctx.Get(stateKeyCtx))
+	}
+}
+
+// releaseRequestSlot frees the slot of a queued request. It runs as soon as
+// the response or error arrives, before the callbacks that may generate more
+// requests, so a full queue never waits on its own producers
+func (crawler *Crawler) releaseRequestSlot(ctx *colly.Context) {
+	if ctx == nil {
+		return
+	}
+	if release, ok := ctx.GetAny("__release").(func()); ok {
+		release()
+	}
+}
+
+// abortRequest aborts a request from OnRequest, after which colly runs no
+// callbacks for it, so a queued request gives its slot back here
+func (crawler *Crawler) abortRequest(r *colly.Request) {
+	crawler.releaseRequestSlot(r.Ctx)
+	r.Abort()
+}
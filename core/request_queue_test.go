@@ -0,0 +1,91 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func newQueueTestCrawler(size int) *Crawler {
+	crawler := &Crawler{
+		C:        colly.NewCollector(colly.Async(true), colly.AllowURLRevisit()),
+		stopChan: make(chan struct{}),
+	}
+	crawler.C.Limit(&colly.LimitRule{DomainGlob: "*", Parallelism: 50})
+	crawler.initRequestQueue(size)
+	return crawler
+}
+
+func TestRequestQueueBoundsInFlightRequests(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	var served sync.Map
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		served.Store(r.URL.Query().Get("i"), true)
+		time.Sleep(20 * time.Millisecond)
+		if r.URL.Query().Get("i") == "0" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	crawler := newQueueTestCrawler(2)
+	// Requests queued from a callback must not deadlock against a full queue
+	crawler.C.OnResponse(func(r *colly.Response) {
+		if r.Request.URL.Query().Get("i") == "1" {
+			for i := 10; i < 15; i++ {
+				crawler.submitRequest(http.MethodGet, fmt.Sprintf("%s/?i=%d", srv.URL, i), nil, colly.NewContext(), nil)
+			}
+		}
+	})
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			crawler.submitRequest(http.MethodGet, fmt.Sprintf("%s/?i=%d", srv.URL, i), nil, colly.NewContext(), nil)
+		}
+		crawler.C.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("queue did not drain")
+	}
+
+	count := 0
+	served.Range(func(_, _ interface{}) bool { count++; return true })
+	assert.Equal(t, 15, count)
+	assert.LessOrEqual(t, peak.Load(), int32(2))
+}
+
+func TestRequestQueueDropsAfterStop(t *testing.T) {
+	crawler := newQueueTestCrawler(1)
+	crawler.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			crawler.submitRequest(http.MethodGet, "http://127.0.0.1:1/", nil, colly.NewContext(), nil)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("submitRequest blocked after stop")
+	}
+}
@@ -2,32 +2,28 @@ package core
 
 import (
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
 	"sync"
 
-	jsoniter "github.com/json-iterator/go"
-
 	"github.com/gocolly/colly/v2"
 )
 
+// maxRobotsSize caps how much of a robots.txt we read, as search engines do
+const maxRobotsSize = 500 * 1024
+
 func ParseRobots(site *url.URL, crawler *Crawler, c *colly.Collector, wg *sync.WaitGroup) {
 	defer wg.Done()
 	robotsURL := site.String() + "/robots.txt"
 
-	resp, err := http.Get(robotsURL)
+	status, body, err := crawler.fetchSide(robotsURL, maxRobotsSize)
 	if err != nil {
 		return
 	}
-	if resp.StatusCode == 200 {
+	if status == http.StatusOK {
 		Logger.Infof("Found robots.txt: %s", robotsURL)
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return
-		}
 		lines := strings.Split(string(body), "\n")
 
 		var re = regexp.MustCompile(".*llow: ")
@@ -40,26 +36,217 @@ func ParseRobots(site *url.URL, crawler *Crawler, c *colly.Collector, wg *sync.W
 				}
 				outputFormat := fmt.Sprintf("[robots] - %s", url)
 
-				if crawler.JsonOutput {
-					sout := SpiderOutput{
-						Input:      crawler.Input,
-						Source:     "robots",
-						OutputType: "url",
-						Output:     url,
-					}
-					if data, err := jsoniter.MarshalToString(sout); err == nil {
-						outputFormat = data
-					}
-				} else if crawler.Quiet {
-					outputFormat = url
+				sout := SpiderOutput{
+					Input:      crawler.Input,
+					Source:     "robots",
+					OutputType: "url",
+					Output:     url,
 				}
-				fmt.Println(outputFormat)
-				if crawler.Output != nil {
-					crawler.Output.WriteToFile(outputFormat)
+				crawler.emit(sout, outputFormat, url)
+				// Under --obey-robots the disallow filters reject this; it is still reported above
+				crawler.visit(nil, url)
+			}
+		}
+	}
+
+}
+
+// RobotsRule is one Allow or Disallow line of a robots.txt group
+type RobotsRule struct {
+	Pattern string
+	Allow   bool
+}
+
+// RobotsRules are the rules of the group that applies to our user agent
+type RobotsRules []RobotsRule
+
+// Disallows reports whether u is disallowed. The longest pattern matching
+// its path wins and Allow wins a tie, as RFC 9309 specifies
+func (rules RobotsRules) Disallows(u *url.URL) bool {
+	path := u.RequestURI()
+	best, disallowed := -1, false
+	for _, rule := range rules {
+		if len(rule.Pattern) < best || (len(rule.Pattern) == best && !rule.Allow) {
+			continue
+		}
+		if robotsMatch(rule.Pattern, path) {
+			best, disallowed = len(rule.Pattern), !rule.Allow
+		}
+	}
+	return disallowed
+}
+
+// robotsMatch reports whether a robots.txt pattern matches the start of path,
+// honoring the * wildcard and the $ end anchor
+func robotsMatch(pattern, path string) bool {
+	if !strings.HasPrefix(pattern, "/") && !strings.HasPrefix(pattern, "*") {
+		pattern = "/" + pattern
+	}
+	anchored := strings.HasSuffix(pattern, "$")
+	parts := strings.Split(strings.TrimSuffix(pattern, "$"), "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for i, part := range parts[1:] {
+		last := i == len(parts)-2
+		if last && anchored {
+			return strings.HasSuffix(rest, part)
+		}
+		idx := strings.Index(rest, part)
+		if idx < 0 {
+			return false
+		}
+		rest = rest[idx+len(part):]
+	}
+	return !anchored || rest == ""
+}
+
+// Unconditional returns the Disallow patterns no Allow rule can take
+// precedence over, which are safe to enforce as plain URL filters
+func (rules RobotsRules) Unconditional() []string {
+	var patterns []string
+	for _, rule := range rules {
+		if rule.Allow {
+			continue
+		}
+		overridden := false
+		for _, other := range rules {
+			if other.Allow && len(other.Pattern) >= len(rule.Pattern) {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			patterns = append(patterns, rule.Pattern)
+		}
+	}
+	return patterns
+}
+
+type robotsGroup struct {
+	agents []string
+	rules  RobotsRules
+}
+
+// ParseRobotsDisallow returns the Allow and Disallow rules of the robots.txt
+// group that applies to userAgent: the group with the longest agent token
+// contained in it, falling back to "*". Disallows applies their precedence
+func ParseRobotsDisallow(body, userAgent string) RobotsRules {
+	var groups []*robotsGroup
+	var current *robotsGroup
+	lastWasAgent := false
+	for _, line := range strings.Split(body, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if current == nil || !lastWasAgent {
+				current = &robotsGroup{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			lastWasAgent = true
+		case "allow", "disallow":
+			if current != nil && value != "" {
+				current.rules = append(current.rules, RobotsRule{Pattern: value, Allow: key == "allow"})
+			}
+			lastWasAgent = false
+		case "crawl-delay":
+			lastWasAgent = false
+		}
+	}
+
+	ua := strings.ToLower(userAgent)
+	agent := "*"
+	for _, group := range groups {
+		for _, a := range group.agents {
+			if a != "*" && a != "" && strings.Contains(ua, a) && (agent == "*" || len(a) > len(agent)) {
+				agent = a
+			}
+		}
+	}
+
+	var rules RobotsRules
+	seen := make(map[RobotsRule]bool)
+	for _, group := range groups {
+		for _, a := range group.agents {
+			if a == agent {
+				for _, rule := range group.rules {
+					if !seen[rule] {
+						seen[rule] = true
+						rules = append(rules, rule)
+					}
 				}
-				_ = c.Visit(url)
+				break
 			}
 		}
 	}
+	return rules
+}
+
+// RobotsPatternRegex turns a robots.txt path pattern into a URL regex for host,
+// honoring the * wildcard and the $ end anchor
+func RobotsPatternRegex(host, pattern string) string {
+	if !strings.HasPrefix(pattern, "/") && !strings.HasPrefix(pattern, "*") {
+		pattern = "/" + pattern
+	}
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	path := strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, `.*`)
+	if anchored {
+		path += "$"
+	}
+	return `^https?://` + regexp.QuoteMeta(host) + `(?::\d+)?` + path
+}
+
+// obeyRobotsTxt loads the site's robots.txt before the crawl starts and keeps
+// the collectors away from paths disallowed for our user agent
+func (crawler *Crawler) obeyRobotsTxt() {
+	robotsURL := crawler.site.Scheme + "://" + crawler.site.Host + "/robots.txt"
+	status, body, err := crawler.fetchSide(robotsURL, maxRobotsSize)
+	if err != nil {
+		Logger.Warnf("Failed to fetch %s for --obey-robots: %s", robotsURL, err)
+		return
+	}
+	if status != http.StatusOK {
+		return
+	}
+
+	rules := ParseRobotsDisallow(string(body), crawler.C.UserAgent)
+	for _, pattern := range rules.Unconditional() {
+		re, err := regexp.Compile(RobotsPatternRegex(crawler.site.Hostname(), pattern))
+		if err != nil {
+			continue
+		}
+		crawler.C.DisallowedURLFilters = append(crawler.C.DisallowedURLFilters, re)
+		crawler.LinkFinderCollector.DisallowedURLFilters = append(crawler.LinkFinderCollector.DisallowedURLFilters, re)
+		crawler.robotsDisallow = append(crawler.robotsDisallow, re.String())
+	}
+	disallowCount := 0
+	for _, rule := range rules {
+		if !rule.Allow {
+			disallowCount++
+		}
+	}
+	// Disallow rules an Allow may override are checked per request
+	if len(crawler.robotsDisallow) < disallowCount {
+		crawler.robotsRules = rules
+	}
+	if disallowCount > 0 {
+		Logger.Infof("Obeying %d robots.txt disallow rules for %s", disallowCount, crawler.site.Host)
+	}
+}
 
+// robotsDisallows reports whether robots.txt keeps us away from u, for the
+// rules the collectors' URL filters cannot express
+func (crawler *Crawler) robotsDisallows(u *url.URL) bool {
+	return crawler.robotsRules != nil && strings.EqualFold(u.Hostname(), crawler.site.Hostname()) && crawler.robotsRules.Disallows(u)
 }
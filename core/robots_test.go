@@ -0,0 +1,102 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRobotsDisallow(t *testing.T) {
+	body := `# example
+User-agent: *
+Disallow: /private/
+Disallow: /tmp$
+Allow: /public/
+
+User-agent: gospider
+User-agent: otherbot
+Disallow: /no-spiders/ # trailing comment
+Disallow:
+
+Sitemap: https://example.com/sitemap.xml
+`
+	assert.Equal(t, RobotsRules{{Pattern: "/private/"}, {Pattern: "/tmp$"}, {Pattern: "/public/", Allow: true}}, ParseRobotsDisallow(body, "Mozilla/5.0"))
+	assert.Equal(t, RobotsRules{{Pattern: "/no-spiders/"}}, ParseRobotsDisallow(body, "GoSpider/1.0"))
+	assert.Empty(t, ParseRobotsDisallow("", "Mozilla/5.0"))
+}
+
+func TestRobotsRulesPrecedence(t *testing.T) {
+	rules := ParseRobotsDisallow(`User-agent: *
+Disallow: /shop/
+Allow: /shop/public/
+Disallow: /shop/public/drafts/
+Allow: /page
+Disallow: /page
+Disallow: /*.php$
+`, "Mozilla/5.0")
+	for path, want := range map[string]bool{
+		"/shop/cart":            true,
+		"/shop/public/item":     false,
+		"/shop/public/drafts/1": true,
+		"/page":                 false,
+		"/admin/index.php":      true,
+		"/admin/index.php?x=1":  false,
+		"/open":                 false,
+	} {
+		u, err := url.Parse("https://example.com" + path)
+		require.NoError(t, err)
+		assert.Equal(t, want, rules.Disallows(u), path)
+	}
+	assert.Equal(t, []string{"/shop/public/drafts/"}, rules.Unconditional(), "only rules no Allow can override become URL filters")
+}
+
+func TestRobotsPatternRegex(t *testing.T) {
+	re := regexp.MustCompile(RobotsPatternRegex("example.com", "/admin/*.php$"))
+	assert.True(t, re.MatchString("https://example.com/admin/users/list.php"))
+	assert.True(t, re.MatchString("http://example.com:8080/admin/x.php"))
+	assert.False(t, re.MatchString("https://example.com/admin/x.php?id=1"))
+	assert.False(t, re.MatchString("https://other.com/admin/x.php"))
+
+	prefix := regexp.MustCompile(RobotsPatternRegex("example.com", "private"))
+	assert.True(t, prefix.MatchString("https://example.com/private/data"))
+}
+
+func TestCrawlObeysRobots(t *testing.T) {
+	for name, headFirst := range map[string]bool{"get": false, "head first": true} {
+		t.Run(name, func(t *testing.T) {
+			var hitPrivate, hitDocs atomic.Bool
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/robots.txt":
+					fmt.Fprint(w, "User-agent: *\nDisallow: /private/\nAllow: /private/docs/\n")
+					return
+				case "/private/panel":
+					hitPrivate.Store(true)
+				case "/private/docs/intro":
+					hitDocs.Store(true)
+				}
+				w.Header().Set("Content-Type", "text/html")
+				fmt.Fprint(w, `<html><body><a href="/private/panel">p</a><a href="/private/docs/intro">d</a><a href="/open">o</a></body></html>`)
+			}))
+			defer srv.Close()
+
+			results := crawlAll(t, srv.URL, CrawlerConfig{Robots: true, ObeyRobots: true, HeadFirst: headFirst})
+
+			var outputs []string
+			for _, sout := range results {
+				outputs = append(outputs, sout.Output)
+			}
+			assert.Contains(t, outputs, srv.URL+"/open")
+			assert.Contains(t, outputs, srv.URL+"/private/", "disallowed paths are still reported")
+			assert.False(t, hitPrivate.Load(), "disallowed paths must not be requested")
+			assert.True(t, hitDocs.Load(), "a longer Allow takes precedence over Disallow")
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// CertificateSANs returns the DNS names of the peer leaf certificate, with
+// wildcard labels stripped
+func CertificateSANs(state *tls.ConnectionState) []string {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	var names []string
+	for _, name := range state.PeerCertificates[0].DNSNames {
+		name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "*."))
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return Unique(names)
+}
+
+// observeCertificate records the SANs of the first certificate seen for each
+// host; it runs inside the transport, so the names are only queued here
+func (crawler *Crawler) observeCertificate(host string, state *tls.ConnectionState) {
+	if crawler.sanHostSet.Duplicate(host) {
+		return
+	}
+	names := CertificateSANs(state)
+	if len(names) == 0 {
+		return
+	}
+	crawler.sanMutex.Lock()
+	crawler.pendingSANs = append(crawler.pendingSANs, names...)
+	crawler.sanMutex.Unlock()
+}
+
+// flushCertificateSANs reports queued SAN names and seeds the in-scope ones
+func (crawler *Crawler) flushCertificateSANs() {
+	crawler.sanMutex.Lock()
+	names := crawler.pendingSANs
+	crawler.pendingSANs = nil
+	crawler.sanMutex.Unlock()
+
+	for _, name := range names {
+		if crawler.sanSet.Duplicate(name) {
+			continue
+		}
+		crawler.emitSAN(name)
+
+		seedURL := crawler.site.Scheme + "://" + name
+		u, err := url.Parse(seedURL)
+		if err != nil || !InScope(u, crawler.C.URLFilters) {
+			continue
+		}
+		if urlToVisit := crawler.urlProcessor.Process(seedURL, "tls-san", "san", nil); urlToVisit != "" {
+			crawler.visit(nil, urlToVisit)
+		}
+	}
+}
+
+func (crawler *Crawler) emitSAN(name string) {
+	if crawler.Stats != nil {
+		crawler.Stats.IncrementURLsFound()
+	}
+	outputFormat := fmt.Sprintf("[san] - %s", name)
+
+	sout := SpiderOutput{
+		Input:      crawler.Input,
+		Source:     "tls-san",
+		OutputType: "san",
+		Output:     name,
+	}
+	crawler.emit(sout, outputFormat, name)
+}
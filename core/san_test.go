@@ -0,0 +1,30 @@
+package core
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertificateSANs(t *testing.T) {
+	state := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{
+		{DNSNames: []string{"Example.com", "*.example.com", "api.example.net", " "}},
+		{DNSNames: []string{"intermediate.example.org"}},
+	}}
+	assert.Equal(t, []string{"example.com", "api.example.net"}, CertificateSANs(state))
+	assert.Empty(t, CertificateSANs(nil))
+	assert.Empty(t, CertificateSANs(&tls.ConnectionState{}))
+}
+
+func TestEmitSANUsesOneName(t *testing.T) {
+	sink := &recordingSink{}
+	crawler := &Crawler{Input: "https://example.com", sink: sink, fileFormat: OutputFormatText}
+	crawler.emitSAN("api.example.com")
+	require.Len(t, sink.results, 1)
+	assert.Equal(t, "san", sink.results[0].OutputType)
+	assert.Equal(t, "api.example.com", sink.results[0].Output)
+	assert.Equal(t, "tls-san", sink.results[0].Source)
+}
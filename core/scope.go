@@ -0,0 +1,104 @@
+package core
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+// DisallowedExtensionPattern keeps static assets such as images, media,
+// fonts and stylesheets out of the crawl
+const DisallowedExtensionPattern = `(?i)\.(png|apng|bmp|gif|ico|cur|jpg|jpeg|jfif|pjp|pjpeg|svg|tif|tiff|webp|xbm|3gp|aac|flac|mpg|mpeg|mp3|mp4|m4a|m4v|m4p|oga|ogg|ogv|mov|wav|webm|eot|woff|woff2|ttf|otf|css)(?:\?|#|$)`
+
+// ScopeFilters builds the allow and deny regexes a crawl of site uses. The
+// target's scope is replaced by --whitelist, which is in turn replaced by
+// --whitelist-domain; the deny list is the asset extensions plus --blacklist
+func ScopeFilters(site *url.URL, cfg CrawlerConfig) (scope, disallowed []*regexp.Regexp, err error) {
+	scope = []*regexp.Regexp{regexp.MustCompile(ScopePattern(site, cfg.Subs))}
+	if cfg.Whitelist != "" {
+		re, err := regexp.Compile(cfg.Whitelist)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid --whitelist: %w", err)
+		}
+		scope = []*regexp.Regexp{re}
+	}
+	if cfg.WhitelistDomain != "" {
+		re, err := regexp.Compile("http(s)?://" + cfg.WhitelistDomain)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid --whitelist-domain: %w", err)
+		}
+		scope = []*regexp.Regexp{re}
+	}
+
+	disallowed = []*regexp.Regexp{regexp.MustCompile(DisallowedExtensionPattern)}
+	if cfg.Blacklist != "" {
+		re, err := regexp.Compile(cfg.Blacklist)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid --blacklist: %w", err)
+		}
+		disallowed = append(disallowed, re)
+	}
+	return scope, disallowed, nil
+}
+
+// ScopeCheck explains how a crawl would treat one URL
+type ScopeCheck struct {
+	URL                 string
+	Normalized          string
+	Excluded            bool
+	InScope             bool
+	ScopeMatch          string
+	DisallowedExtension bool
+	Blacklisted         bool
+	BlacklistMatch      string
+	WouldCrawl          bool
+	ScopePatterns       []string
+	BlacklistPatterns   []string
+}
+
+// CheckScope runs rawURL through the same normalization and filters a crawl
+// of site would, without sending any request
+func CheckScope(rawURL string, site *url.URL, cfg CrawlerConfig) (ScopeCheck, error) {
+	check := ScopeCheck{URL: rawURL}
+	scope, disallowed, err := ScopeFilters(site, cfg)
+	if err != nil {
+		return check, err
+	}
+	for _, re := range scope {
+		check.ScopePatterns = append(check.ScopePatterns, re.String())
+	}
+	for _, re := range disallowed {
+		check.BlacklistPatterns = append(check.BlacklistPatterns, re.String())
+	}
+
+	normalized, ok := NormalizeURL(site, rawURL)
+	if ok {
+		check.Normalized = normalized
+	} else {
+		// Still run the filters so the report says everything that applies
+		check.Excluded = true
+		u, err := site.Parse(rawURL)
+		if err != nil {
+			return check, nil
+		}
+		normalized = u.String()
+	}
+
+	for _, re := range scope {
+		if re.MatchString(normalized) {
+			check.InScope = true
+			check.ScopeMatch = re.String()
+			break
+		}
+	}
+	check.DisallowedExtension = disallowed[0].MatchString(normalized)
+	for _, re := range disallowed[1:] {
+		if re.MatchString(normalized) {
+			check.Blacklisted = true
+			check.BlacklistMatch = re.String()
+			break
+		}
+	}
+	check.WouldCrawl = !check.Excluded && check.InScope && !check.DisallowedExtension && !check.Blacklisted
+	return check, nil
+}
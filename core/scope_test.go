@@ -0,0 +1,44 @@
+package core
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckScope(t *testing.T) {
+	site, _ := url.Parse("https://example.com")
+
+	check, err := CheckScope("/admin/users", site, CrawlerConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/admin/users", check.Normalized)
+	assert.True(t, check.InScope)
+	assert.True(t, check.WouldCrawl)
+
+	check, err = CheckScope("https://cdn.other.net/app", site, CrawlerConfig{})
+	require.NoError(t, err)
+	assert.False(t, check.InScope)
+	assert.False(t, check.WouldCrawl)
+
+	check, err = CheckScope("https://api.example.com/admin", site, CrawlerConfig{Subs: true, Blacklist: "admin"})
+	require.NoError(t, err)
+	assert.True(t, check.InScope)
+	assert.True(t, check.Blacklisted)
+	assert.Equal(t, "admin", check.BlacklistMatch)
+	assert.False(t, check.WouldCrawl)
+
+	check, err = CheckScope("https://other.net/x", site, CrawlerConfig{Whitelist: "other", WhitelistDomain: "other.net"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http(s)?://other.net"}, check.ScopePatterns, "--whitelist-domain wins over --whitelist")
+	assert.True(t, check.WouldCrawl)
+
+	check, err = CheckScope("/logo.png", site, CrawlerConfig{})
+	require.NoError(t, err)
+	assert.True(t, check.InScope, "filters still run on URLs normalization rejects")
+	assert.False(t, check.WouldCrawl)
+
+	_, err = CheckScope("/", site, CrawlerConfig{Blacklist: "("})
+	assert.ErrorContains(t, err, "--blacklist")
+}
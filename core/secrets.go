@@ -0,0 +1,158 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SecretRule is a named pattern the secret scanner searches response
+// bodies for. When the pattern has a capture group, the first group is
+// the secret
+type SecretRule struct {
+	Name       string
+	Pattern    *regexp.Regexp
+	Confidence string
+}
+
+// Secret is a match of a rule
+type Secret struct {
+	Rule       string
+	Value      string
+	Confidence string
+}
+
+// builtinSecretRules are the rules every scan runs. Confidence is high for
+// formats that are unambiguous credentials, medium for values that are
+// often public or short-lived
+var builtinSecretRules = []SecretRule{
+	{"aws-access-key-id", regexp.MustCompile(`\b((?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16})\b`), "high"},
+	{"aws-secret-access-key", regexp.MustCompile(`(?i)aws.{0,20}?(?:secret|private).{0,20}?["'\x60]([0-9A-Za-z/+]{40})["'\x60]`), "medium"},
+	{"gcp-service-account", regexp.MustCompile(`"client_email"\s*:\s*"([a-z0-9-]+@[a-z0-9-]+\.iam\.gserviceaccount\.com)"`), "high"},
+	{"gcp-api-key", regexp.MustCompile(`\b(AIza[0-9A-Za-z_-]{35})\b`), "medium"},
+	{"azure-sas-token", regexp.MustCompile(`(?i)(\bsv=\d{4}-\d{2}-\d{2}&[^"'\x60\s<>]*?\bsig=[0-9A-Za-z%/+=]{20,})`), "high"},
+	{"azure-storage-key", regexp.MustCompile(`(AccountKey=[0-9A-Za-z+/]{86}==)`), "high"},
+	{"slack-token", regexp.MustCompile(`\b(xox[abposr]-[0-9A-Za-z-]{10,})`), "high"},
+	{"slack-webhook", regexp.MustCompile(`(https://hooks\.slack\.com/services/T[0-9A-Z]+/B[0-9A-Z]+/[0-9A-Za-z]+)`), "high"},
+	{"stripe-secret-key", regexp.MustCompile(`\b((?:sk|rk)_live_[0-9A-Za-z]{24,})\b`), "high"},
+	{"github-token", regexp.MustCompile(`\b((?:ghp|gho|ghu|ghs|ghr)_[0-9A-Za-z]{36}|github_pat_[0-9A-Za-z_]{82})\b`), "high"},
+	{"jwt", regexp.MustCompile(`\b(eyJ[0-9A-Za-z_-]{10,}\.eyJ[0-9A-Za-z_-]{10,}\.[0-9A-Za-z_-]{10,})`), "medium"},
+	{"private-key", regexp.MustCompile(`(-----BEGIN (?:RSA |EC |DSA |OPENSSH |PGP |ENCRYPTED )?PRIVATE KEY(?: BLOCK)?-----)`), "high"},
+}
+
+// SecretScanner finds credentials in response bodies with the built-in
+// rules and those of --secret-rules
+type SecretScanner struct {
+	rules []SecretRule
+}
+
+// NewSecretScanner returns a scanner running the built-in rules and
+// extra. An extra rule replaces the built-in rule of the same name
+func NewSecretScanner(extra []SecretRule) *SecretScanner {
+	replaced := make(map[string]bool, len(extra))
+	for _, rule := range extra {
+		replaced[rule.Name] = true
+	}
+	s := &SecretScanner{}
+	for _, rule := range builtinSecretRules {
+		if !replaced[rule.Name] {
+			s.rules = append(s.rules, rule)
+		}
+	}
+	s.rules = append(s.rules, extra...)
+	return s
+}
+
+// Scan returns the secrets in body, each value once per rule
+func (s *SecretScanner) Scan(body string) []Secret {
+	var found []Secret
+	seen := map[string]bool{}
+	for _, rule := range s.rules {
+		for _, m := range rule.Pattern.FindAllStringSubmatch(body, -1) {
+			value := m[0]
+			if len(m) > 1 && m[1] != "" {
+				value = m[1]
+			}
+			if key := rule.Name + "|" + value; !seen[key] {
+				seen[key] = true
+				found = append(found, Secret{Rule: rule.Name, Value: value, Confidence: rule.Confidence})
+			}
+		}
+	}
+	return found
+}
+
+// secretRulesFile is the YAML layout of --secret-rules
+type secretRulesFile struct {
+	Rules []struct {
+		Name       string `yaml:"name"`
+		Pattern    string `yaml:"pattern"`
+		Confidence string `yaml:"confidence"`
+	} `yaml:"rules"`
+}
+
+// LoadSecretRules reads a --secret-rules YAML file: a rules list of name,
+// pattern and an optional confidence of high, medium (the default) or low.
+// Patterns are held to the limits of --match-regex
+func LoadSecretRules(filename string) ([]SecretRule, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var file secretRulesFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	var rules []SecretRule
+	for i, entry := range file.Rules {
+		match, err := CompileContentMatch(entry.Name, entry.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: rule %d: %w", filename, i+1, err)
+		}
+		confidence := strings.ToLower(strings.TrimSpace(entry.Confidence))
+		switch confidence {
+		case "":
+			confidence = "medium"
+		case "high", "medium", "low":
+		default:
+			return nil, fmt.Errorf("%s: rule %d: %s: invalid confidence %q", filename, i+1, entry.Name, entry.Confidence)
+		}
+		rules = append(rules, SecretRule{Name: match.Name, Pattern: match.Pattern, Confidence: confidence})
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("%s: no rules found", filename)
+	}
+	return rules, nil
+}
+
+// findSecrets reports the secrets in the body of rawURL, each once per
+// crawl
+func (crawler *Crawler) findSecrets(body, rawURL string, depth int) {
+	if crawler.secrets == nil {
+		return
+	}
+	stop := crawler.profile.start(extractorSecrets)
+	secrets := crawler.secrets.Scan(body)
+	stop()
+	for _, secret := range secrets {
+		if crawler.secretSet.Duplicate(secret.Rule + "|" + secret.Value) {
+			continue
+		}
+		if crawler.Stats != nil {
+			crawler.Stats.IncrementURLsFound()
+		}
+		sout := SpiderOutput{
+			Input:      crawler.Input,
+			Source:     rawURL,
+			OutputType: "secret",
+			Output:     secret.Value,
+			Param:      secret.Rule,
+			Confidence: secret.Confidence,
+			Depth:      depth,
+		}
+		crawler.emit(sout, fmt.Sprintf("[secret] - [%s] - [%s] - %s - %s", secret.Rule, secret.Confidence, secret.Value, rawURL), secret.Value)
+	}
+}
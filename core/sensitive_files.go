@@ -0,0 +1,155 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxSensitiveFileSize caps how much of a probed file is read; signatures
+// sit at the start of the file
+const maxSensitiveFileSize = 64 * 1024
+
+// SensitiveFile is a commonly exposed path probed by --check-sensitive and
+// the content expected in it. A nil Signature accepts any 200 response that
+// --soft-404 does not take for the host's catch-all page
+type SensitiveFile struct {
+	Path      string
+	Signature *regexp.Regexp
+}
+
+// DefaultSensitiveFiles are the paths probed when no --sensitive-list is given
+var DefaultSensitiveFiles = []SensitiveFile{
+	{Path: "/.git/config", Signature: regexp.MustCompile(`(?m)^\s*\[core\]`)},
+	{Path: "/.env", Signature: regexp.MustCompile(`(?m)^\s*[A-Za-z_][A-Za-z0-9_]*\s*=`)},
+	{Path: "/.svn/entries", Signature: regexp.MustCompile(`\A(?:\d+\s*\n|<\?xml[^>]*>\s*<wc-entries)`)},
+	{Path: "/backup.zip", Signature: regexp.MustCompile(`\APK\x03\x04`)},
+	{Path: "/.DS_Store", Signature: regexp.MustCompile(`\A\x00\x00\x00\x01Bud1`)},
+	{Path: "/phpinfo.php", Signature: regexp.MustCompile(`<title>phpinfo\(\)</title>|PHP Version \d`)},
+	{Path: "/server-status", Signature: regexp.MustCompile(`Apache Server Status for|Server uptime:`)},
+}
+
+// LoadSensitiveFiles reads a --sensitive-list file. Each line holds a path,
+// optionally followed by whitespace and a regular expression the content
+// must match; blank lines and lines starting with # are skipped
+func LoadSensitiveFiles(filename string) ([]SensitiveFile, error) {
+	var files []SensitiveFile
+	for i, line := range ReadingLines(filename) {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		probe, signature := line, ""
+		if idx := strings.IndexAny(line, " \t"); idx != -1 {
+			probe, signature = line[:idx], line[idx+1:]
+		}
+		file := SensitiveFile{Path: "/" + strings.TrimLeft(probe, "/")}
+		if signature = strings.TrimSpace(signature); signature != "" {
+			re, err := regexp.Compile(signature)
+			if err != nil {
+				return nil, fmt.Errorf("%s: entry %d: %w", filename, i+1, err)
+			}
+			file.Signature = re
+		}
+		files = append(files, file)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%s: no paths found", filename)
+	}
+	return files, nil
+}
+
+type sensitiveProbe struct {
+	status      int
+	contentType string
+	body        []byte
+}
+
+// checkSensitiveFiles probes the configured sensitive paths on the crawled
+// host and reports the ones that answer with the expected content
+func (crawler *Crawler) checkSensitiveFiles() {
+	client := http.DefaultClient
+	if crawler.AntiDetectClient != nil {
+		client = crawler.AntiDetectClient.GetHTTPClient()
+	}
+	files := crawler.sensitiveFiles
+	if len(files) == 0 {
+		files = DefaultSensitiveFiles
+	}
+	for _, file := range files {
+		if crawler.stopped.Load() {
+			return
+		}
+		target := crawler.site.Scheme + "://" + crawler.site.Host + file.Path
+		u, err := url.Parse(target)
+		if err != nil || !InScope(u, crawler.C.URLFilters) {
+			continue
+		}
+
+		probe := crawler.fetchSensitive(client, target)
+		if probe == nil || probe.status != http.StatusOK {
+			continue
+		}
+		confidence := "low"
+		var match []byte
+		if file.Signature != nil {
+			if match = file.Signature.Find(probe.body); match == nil {
+				continue
+			}
+			confidence = "high"
+		}
+
+		if crawler.isSoft404(u, probe.status, probe.contentType, probe.body) {
+			Logger.Debugf("sensitive-file %s matches the catch-all response; skipped", target)
+			continue
+		}
+		crawler.emitSensitiveFile(target, probe.status, confidence, match)
+	}
+}
+
+func (crawler *Crawler) fetchSensitive(client *http.Client, target string) *sensitiveProbe {
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil
+	}
+	if crawler.Stats != nil {
+		crawler.Stats.IncrementRequestsMade()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		Logger.Debugf("sensitive-file %s failed: %s", target, err)
+		return nil
+	}
+	defer resp.Body.Close()
+	crawler.recordBackoff(resp.StatusCode)
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxSensitiveFileSize))
+	return &sensitiveProbe{status: resp.StatusCode, contentType: resp.Header.Get("Content-Type"), body: body}
+}
+
+func (crawler *Crawler) emitSensitiveFile(target string, status int, confidence string, match []byte) {
+	if crawler.Stats != nil {
+		crawler.Stats.IncrementURLsFound()
+	}
+	// Binary signatures such as zip headers are quoted to stay printable
+	var snippet string
+	if match != nil {
+		if len(match) > 80 {
+			match = match[:80]
+		}
+		snippet = strconv.QuoteToASCII(string(match))
+	}
+	outputFormat := fmt.Sprintf("[sensitive-file] - [code-%d] - [%s] - %s", status, confidence, target)
+	sout := SpiderOutput{
+		Input:      crawler.Input,
+		Source:     "sensitive",
+		OutputType: "sensitive-file",
+		StatusCode: status,
+		Output:     target,
+		Confidence: confidence,
+		Snippet:    snippet,
+	}
+	crawler.emit(sout, outputFormat, target)
+}
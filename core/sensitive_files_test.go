@@ -0,0 +1,68 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSensitiveFiles(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "sensitive.txt")
+	require.NoError(t, os.WriteFile(list, []byte("# exposed files\n.git/config\t\\[core\\]\n/dump.sql   CREATE TABLE\n\n/admin.bak\n"), 0o644))
+
+	files, err := LoadSensitiveFiles(list)
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	assert.Equal(t, "/.git/config", files[0].Path)
+	assert.True(t, files[0].Signature.MatchString("[core]"))
+	assert.Equal(t, "/dump.sql", files[1].Path)
+	assert.Equal(t, "CREATE TABLE", files[1].Signature.String())
+	assert.Equal(t, "/admin.bak", files[2].Path)
+	assert.Nil(t, files[2].Signature)
+
+	require.NoError(t, os.WriteFile(list, []byte("/x [unclosed\n"), 0o644))
+	_, err = LoadSensitiveFiles(list)
+	assert.Error(t, err)
+}
+
+func TestCrawlCheckSensitive(t *testing.T) {
+	const spa = `<html><head><title>App</title></head><body><div id="root"></div></body></html>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.git/config":
+			fmt.Fprint(w, "[core]\n\trepositoryformatversion = 0\n")
+		case "/backup.zip":
+			fmt.Fprint(w, "PK\x03\x04rest-of-archive")
+		case "/server-status":
+			http.NotFound(w, r)
+		default:
+			// A single-page app answers every other path with its shell,
+			// which must not count as an exposed .env or phpinfo
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, spa)
+		}
+	}))
+	defer srv.Close()
+
+	files := append([]SensitiveFile{{Path: "/app"}}, DefaultSensitiveFiles...)
+	results := crawlAll(t, srv.URL, CrawlerConfig{MaxDepth: 1, CheckSensitive: true, SensitiveFiles: files, Soft404: true})
+
+	found := map[string]SpiderOutput{}
+	for _, sout := range results {
+		if sout.OutputType == "sensitive-file" {
+			found[sout.Output] = sout
+		}
+	}
+	require.Len(t, found, 2)
+	git := found[srv.URL+"/.git/config"]
+	assert.Equal(t, "high", git.Confidence)
+	assert.Equal(t, http.StatusOK, git.StatusCode)
+	assert.Equal(t, `"[core]"`, git.Snippet)
+	assert.Equal(t, `"PK\x03\x04"`, found[srv.URL+"/backup.zip"].Snippet)
+}
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// DefaultSessionPatterns mark URLs that end or damage an authenticated
+// session: logout links, password changes and account deletion
+var DefaultSessionPatterns = []string{
+	"logout", "log-out", "log_out", "logoff", "signout", "sign-out", "sign_out", "disconnect",
+	"change-password", "change_password", "changepassword", "password/change",
+	"delete-account", "delete_account", "deleteaccount", "account/delete", "deactivate",
+}
+
+// sessionEndpointType is the output type of links that were not followed
+// because they would end the session
+const sessionEndpointType = "session-endpoint"
+
+var sessionLinkTextRegex = regexp.MustCompile(`(?i)^(log ?out|log ?off|sign ?out|disconnect|change (your )?password|delete (my |your )?account|deactivate (my |your )?account)$`)
+
+// IsSessionEndpoint reports whether the path or query of rawURL contains one
+// of patterns, case-insensitively
+func IsSessionEndpoint(rawURL string, patterns []string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	target := strings.ToLower(u.EscapedPath() + "?" + u.RawQuery)
+	for _, pattern := range patterns {
+		if pattern = strings.ToLower(strings.TrimSpace(pattern)); pattern != "" && strings.Contains(target, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSessionLinkText reports link labels such as "Sign out" whose URL gives
+// no hint, e.g. /u/1?a=x
+func isSessionLinkText(text string) bool {
+	return sessionLinkTextRegex.MatchString(strings.Join(strings.Fields(text), " "))
+}
+
+// skipSessionEndpoint reports whether a URL must be reported but not
+// followed, unless --follow-logout is set
+func (crawler *Crawler) skipSessionEndpoint(rawURL, outputType string) bool {
+	if crawler.followLogout {
+		return false
+	}
+	return outputType == sessionEndpointType || IsSessionEndpoint(rawURL, crawler.sessionPatterns)
+}
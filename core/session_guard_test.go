@@ -0,0 +1,75 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSessionEndpoint(t *testing.T) {
+	for raw, want := range map[string]bool{
+		"https://example.com/logout":                   true,
+		"https://example.com/auth/Sign-Out?next=/":     true,
+		"https://example.com/index.php?action=logoff":  true,
+		"https://example.com/account/delete":           true,
+		"https://example.com/settings/change_password": true,
+		"https://example.com/login":                    false,
+		"https://example.com/blog/how-to-sign-in":      false,
+	} {
+		assert.Equal(t, want, IsSessionEndpoint(raw, DefaultSessionPatterns), raw)
+	}
+	assert.True(t, isSessionLinkText("  Sign\n out "))
+	assert.True(t, isSessionLinkText("Delete my account"))
+	assert.False(t, isSessionLinkText("Sign out of newsletters for good"))
+}
+
+func TestCrawlSkipsSessionEndpoints(t *testing.T) {
+	var mu sync.Mutex
+	requested := map[string]bool{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested[r.Method+" "+r.URL.Path] = true
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<html><body>
+<a href="/profile">profile</a>
+<a href="/logout">bye</a>
+<a href="/u/session?x=1">Sign out</a>
+<form method="post" action="/account/delete"><input name="confirm"></form>
+</body></html>`)
+			return
+		}
+		fmt.Fprint(w, `<html><body>leaf</body></html>`)
+	}))
+	defer srv.Close()
+
+	crawl := func(follow bool) []string {
+		results := crawlAll(t, srv.URL, CrawlerConfig{FollowLogout: follow})
+		var outputs []string
+		for _, sout := range results {
+			outputs = append(outputs, sout.OutputType+" "+sout.Output)
+		}
+		return outputs
+	}
+
+	outputs := crawl(false)
+	assert.Contains(t, outputs, "session-endpoint "+srv.URL+"/logout")
+	assert.Contains(t, outputs, "session-endpoint "+srv.URL+"/u/session?x=1")
+	mu.Lock()
+	assert.True(t, requested["GET /profile"])
+	assert.False(t, requested["GET /logout"])
+	assert.False(t, requested["GET /u/session"])
+	assert.False(t, requested["POST /account/delete"])
+	requested = map[string]bool{}
+	mu.Unlock()
+
+	crawl(true)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.True(t, requested["GET /logout"], "--follow-logout follows them")
+}
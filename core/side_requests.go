@@ -0,0 +1,55 @@
+package core
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// sideRequestTimeout bounds a request sent outside colly when the crawl has
+// no --timeout
+const sideRequestTimeout = 10 * time.Second
+
+// sideClient is the client of requests sent outside colly: the crawl's
+// anti-detect client, so they go through --proxy and its fingerprint
+func (crawler *Crawler) sideClient() *http.Client {
+	if crawler.AntiDetectClient != nil {
+		return crawler.AntiDetectClient.GetHTTPClient()
+	}
+	return &http.Client{Timeout: sideRequestTimeout}
+}
+
+// fetchSide GETs rawURL outside colly with the session headers, such as
+// robots.txt or a .well-known document. The request ends with the crawl or
+// after the crawl timeout, and at most limit bytes of the body are read
+func (crawler *Crawler) fetchSide(rawURL string, limit int64) (int, []byte, error) {
+	ctx := crawler.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	timeout := crawler.cfg.Timeout
+	if timeout <= 0 {
+		timeout = sideRequestTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	if crawler.sessionHeaders != nil {
+		req.Header = crawler.sessionHeaders.Clone()
+	}
+	if crawler.Stats != nil {
+		crawler.Stats.IncrementRequestsMade()
+	}
+	resp, err := crawler.sideClient().Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	return resp.StatusCode, body, err
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// chanSink forwards results to a channel until its context is cancelled. It
+// is the Sink of an Engine whose Results are read, so it takes the place of
+// stdout; the channel is closed by the engine, not by Close
+type chanSink struct {
+	ctx     context.Context
+	results chan<- SpiderOutput
+}
+
+func (s *chanSink) Write(sout SpiderOutput) error {
+	sout.Schema = SpiderOutputSchemaVersion
+	select {
+	case s.results <- sout:
+		return nil
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+func (s *chanSink) Close() {}
+
+// println prints a rendered result unless a sink consumes results instead
+func (crawler *Crawler) println(line string) {
+	if crawler.sink == nil {
+		fmt.Println(line)
+	}
+}
+
+// Crawl runs a single-target crawl and streams its results on the returned
+// channel, which is closed once the crawl finishes or ctx is cancelled. It
+// is a one-site Engine, so setup errors are returned as NewEngine returns
+// them
+func Crawl(ctx context.Context, target string, cfg CrawlerConfig) (<-chan SpiderOutput, error) {
+	site, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if site.Scheme == "" || site.Host == "" {
+		return nil, fmt.Errorf("invalid target %q: expected an absolute URL", target)
+	}
+	cfg.Site = target
+	cfg.Sites = ""
+	cfg.Targets = nil
+	e, err := NewEngine(cfg)
+	if err != nil {
+		return nil, err
+	}
+	results := e.Results()
+	go func() {
+		if err := e.Run(ctx); err != nil && ctx.Err() == nil {
+			Logger.Errorf("Failed to crawl %s: %s", target, err)
+		}
+	}()
+	return results, nil
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCrawlStreamsResults(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<html><body><a href="/about">about</a></body></html>`)
+			return
+		}
+		fmt.Fprint(w, `<html><body>about</body></html>`)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	results, err := Crawl(ctx, srv.URL, CrawlerConfig{MaxDepth: 2, MaxConcurrency: 1, Timeout: 5 * time.Second})
+	require.NoError(t, err)
+
+	var outputs []string
+	for sout := range results {
+		outputs = append(outputs, sout.Output)
+	}
+	assert.Contains(t, outputs, srv.URL+"/about")
+
+	_, err = Crawl(ctx, "not a url", CrawlerConfig{})
+	assert.Error(t, err)
+}
+
+func TestDeterministicCrawlOrder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<html><body><a href="/a">a</a><a href="/b">b</a><a href="/c">c</a></body></html>`)
+			return
+		}
+		fmt.Fprintf(w, `<html><body><a href="%s/child">child</a></body></html>`, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	crawl := func() []string {
+		var outputs []string
+		for _, sout := range crawlAll(t, srv.URL, CrawlerConfig{MaxDepth: 3, Seed: 7}) {
+			outputs = append(outputs, sout.OutputType+" "+sout.Output)
+		}
+		return outputs
+	}
+
+	first := crawl()
+	assert.NotEmpty(t, first)
+	assert.Equal(t, first, crawl())
+}
+
+func TestCrawlReportsDepth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><body><a href="/a">a</a></body></html>`)
+		case "/a":
+			fmt.Fprint(w, `<html><body><a href="/a/b">b</a><form action="/a"></form></body></html>`)
+		default:
+			fmt.Fprint(w, `<html><body>leaf</body></html>`)
+		}
+	}))
+	defer srv.Close()
+
+	depths := map[string]int{}
+	for _, sout := range crawlAll(t, srv.URL, CrawlerConfig{MaxDepth: 3}) {
+		// Form probes re-request /a one level deeper; keep the first sighting
+		if _, seen := depths[sout.OutputType+" "+sout.Output]; !seen {
+			depths[sout.OutputType+" "+sout.Output] = sout.Depth
+		}
+	}
+	assert.Equal(t, 1, depths["url "+srv.URL])
+	assert.Equal(t, 2, depths["href "+srv.URL+"/a"])
+	assert.Equal(t, 2, depths["url "+srv.URL+"/a"])
+	assert.Equal(t, 2, depths["form "+srv.URL+"/a"])
+	assert.Equal(t, 3, depths["href "+srv.URL+"/a/b"])
+	assert.Equal(t, 3, depths["url "+srv.URL+"/a/b"])
+}
+
+// crawlAll runs a deterministic crawl of target and collects every result.
+// MaxDepth, MaxConcurrency and Timeout default to 2, 1 and 5s when unset
+func crawlAll(t *testing.T, target string, cfg CrawlerConfig) []SpiderOutput {
+	t.Helper()
+	if cfg.MaxDepth == 0 {
+		cfg.MaxDepth = 2
+	}
+	if cfg.MaxConcurrency == 0 {
+		cfg.MaxConcurrency = 1
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	cfg.Deterministic = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	results, err := Crawl(ctx, target, cfg)
+	require.NoError(t, err)
+	var outputs []SpiderOutput
+	for sout := range results {
+		outputs = append(outputs, sout)
+	}
+	return outputs
+}
@@ -5,8 +5,6 @@ import (
 	"net/url"
 	"sync"
 
-	jsoniter "github.com/json-iterator/go"
-
 	"github.com/gocolly/colly/v2"
 	sitemap "github.com/oxffaa/gopher-parse-sitemap"
 )
@@ -23,24 +21,14 @@ func ParseSiteMap(site *url.URL, crawler *Crawler, c *colly.Collector, wg *sync.
 		_ = sitemap.ParseFromSite(site.String()+path, func(entry sitemap.Entry) error {
 			outputFormat := fmt.Sprintf("[sitemap] - %s", entry.GetLocation())
 
-			if crawler.JsonOutput {
-				sout := SpiderOutput{
-					Input:      crawler.Input,
-					Source:     "sitemap",
-					OutputType: "url",
-					Output:     entry.GetLocation(),
-				}
-				if data, err := jsoniter.MarshalToString(sout); err == nil {
-					outputFormat = data
-				}
-			} else if crawler.Quiet {
-				outputFormat = entry.GetLocation()
-			}
-			fmt.Println(outputFormat)
-			if crawler.Output != nil {
-				crawler.Output.WriteToFile(outputFormat)
+			sout := SpiderOutput{
+				Input:      crawler.Input,
+				Source:     "sitemap",
+				OutputType: "url",
+				Output:     entry.GetLocation(),
 			}
-			_ = c.Visit(entry.GetLocation())
+			crawler.emit(sout, outputFormat, entry.GetLocation())
+			crawler.visit(nil, entry.GetLocation())
 			return nil
 		})
 	}
@@ -0,0 +1,210 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Shapes accepted by --ramp-shape
+const (
+	RampShapeLinear      = "linear"
+	RampShapeExponential = "exponential"
+)
+
+// ConcurrencyGate is a semaphore whose limit can change while requests hold
+// it. Lowering the limit never interrupts a request; it only makes new ones
+// wait until enough have finished
+type ConcurrencyGate struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	limit int
+	inUse int
+}
+
+// NewConcurrencyGate returns a gate admitting limit requests at a time
+func NewConcurrencyGate(limit int) *ConcurrencyGate {
+	g := &ConcurrencyGate{limit: 1}
+	g.cond = sync.NewCond(&g.mu)
+	g.SetLimit(limit)
+	return g
+}
+
+// Acquire waits for a free slot or for ctx to end
+func (g *ConcurrencyGate) Acquire(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() {
+		g.mu.Lock()
+		g.cond.Broadcast()
+		g.mu.Unlock()
+	})
+	defer stop()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for g.inUse >= g.limit {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		g.cond.Wait()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	g.inUse++
+	return nil
+}
+
+// Release frees a slot taken by Acquire
+func (g *ConcurrencyGate) Release() {
+	g.mu.Lock()
+	if g.inUse > 0 {
+		g.inUse--
+	}
+	g.cond.Signal()
+	g.mu.Unlock()
+}
+
+// SetLimit changes how many requests may hold the gate, never below 1
+func (g *ConcurrencyGate) SetLimit(limit int) {
+	if limit < 1 {
+		limit = 1
+	}
+	g.mu.Lock()
+	raised := limit > g.limit
+	g.limit = limit
+	if raised {
+		g.cond.Broadcast()
+	}
+	g.mu.Unlock()
+}
+
+// Limit returns the current limit
+func (g *ConcurrencyGate) Limit() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.limit
+}
+
+// SlowStart ramps concurrency from floor up to ceiling over a warm-up period,
+// a number of completed requests, or whichever of the two ends first
+type SlowStart struct {
+	floor, ceiling int
+	duration       time.Duration
+	requests       int
+	shape          string
+
+	mu        sync.Mutex
+	start     time.Time
+	completed int
+}
+
+// NewSlowStart validates the ramp settings. A ramp with neither a duration
+// nor a request count is disabled and returns nil
+func NewSlowStart(floor, ceiling int, duration time.Duration, requests int, shape string) (*SlowStart, error) {
+	if duration <= 0 && requests <= 0 {
+		return nil, nil
+	}
+	switch shape {
+	case "":
+		shape = RampShapeLinear
+	case RampShapeLinear, RampShapeExponential:
+	default:
+		return nil, fmt.Errorf("invalid --ramp-shape %q, expected %s or %s", shape, RampShapeLinear, RampShapeExponential)
+	}
+	if ceiling < 1 {
+		ceiling = 1
+	}
+	if floor < 1 {
+		floor = 1
+	}
+	if floor > ceiling {
+		floor = ceiling
+	}
+	return &SlowStart{floor: floor, ceiling: ceiling, duration: duration, requests: requests, shape: shape, start: time.Now()}, nil
+}
+
+// Limit returns the concurrency allowed at this point of the ramp
+func (s *SlowStart) Limit() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.limitAt(time.Since(s.start), s.completed)
+}
+
+// Done counts a completed request towards the ramp
+func (s *SlowStart) Done() {
+	s.mu.Lock()
+	s.completed++
+	s.mu.Unlock()
+}
+
+// Restart drops back to the floor and ramps up again, used when the target
+// starts rate limiting
+func (s *SlowStart) Restart() {
+	s.mu.Lock()
+	s.start = time.Now()
+	s.completed = 0
+	s.mu.Unlock()
+}
+
+func (s *SlowStart) limitAt(elapsed time.Duration, completed int) int {
+	progress := 0.0
+	if s.duration > 0 {
+		progress = float64(elapsed) / float64(s.duration)
+	}
+	if s.requests > 0 {
+		if p := float64(completed) / float64(s.requests); p > progress {
+			progress = p
+		}
+	}
+	if progress >= 1 {
+		return s.ceiling
+	}
+
+	span := s.ceiling - s.floor
+	if s.shape == RampShapeExponential {
+		// Doubles from the floor, reaching the ceiling at the end of the ramp
+		steps := 0
+		for n := s.floor; n < s.ceiling; n *= 2 {
+			steps++
+		}
+		limit := s.floor << int(progress*float64(steps))
+		if limit > s.ceiling {
+			limit = s.ceiling
+		}
+		return limit
+	}
+	return s.floor + int(progress*float64(span))
+}
+
+// concurrencyGateRoundTripper holds a gate slot from sending a request until
+// its body is closed, re-reading the ramp before and after every request
+type concurrencyGateRoundTripper struct {
+	base http.RoundTripper
+	gate *ConcurrencyGate
+	ramp *SlowStart
+}
+
+func (rt *concurrencyGateRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.gate.SetLimit(rt.ramp.Limit())
+	if err := rt.gate.Acquire(req.Context()); err != nil {
+		return nil, err
+	}
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			rt.gate.Release()
+			rt.ramp.Done()
+			rt.gate.SetLimit(rt.ramp.Limit())
+		})
+	}
+
+	resp, err := rt.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: release}
+	return resp, nil
+}
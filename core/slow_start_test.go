@@ -0,0 +1,98 @@
+package core
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlowStartLimit(t *testing.T) {
+	linear, err := NewSlowStart(1, 9, 10*time.Second, 0, RampShapeLinear)
+	require.NoError(t, err)
+	assert.Equal(t, 1, linear.limitAt(0, 0))
+	assert.Equal(t, 5, linear.limitAt(5*time.Second, 0))
+	assert.Equal(t, 9, linear.limitAt(10*time.Second, 0))
+
+	exponential, err := NewSlowStart(1, 8, 0, 30, RampShapeExponential)
+	require.NoError(t, err)
+	assert.Equal(t, 1, exponential.limitAt(time.Hour, 0), "a request ramp ignores time")
+	assert.Equal(t, 2, exponential.limitAt(0, 10))
+	assert.Equal(t, 4, exponential.limitAt(0, 20))
+	assert.Equal(t, 8, exponential.limitAt(0, 30))
+
+	both, err := NewSlowStart(2, 10, time.Minute, 8, "")
+	require.NoError(t, err)
+	assert.Equal(t, 6, both.limitAt(0, 4), "whichever ramp is further along wins")
+
+	disabled, err := NewSlowStart(1, 10, 0, 0, RampShapeLinear)
+	assert.NoError(t, err)
+	assert.Nil(t, disabled)
+
+	_, err = NewSlowStart(1, 10, time.Second, 0, "cubic")
+	assert.Error(t, err)
+}
+
+func TestConcurrencyGate(t *testing.T) {
+	gate := NewConcurrencyGate(1)
+	require.NoError(t, gate.Acquire(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, gate.Acquire(ctx), context.DeadlineExceeded)
+
+	acquired := make(chan struct{})
+	go func() {
+		_ = gate.Acquire(context.Background())
+		close(acquired)
+	}()
+	gate.SetLimit(2)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("raising the limit did not admit a waiting request")
+	}
+}
+
+func TestConcurrencyGateRoundTripperRamps(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		inFlight.Add(-1)
+	}))
+	defer srv.Close()
+
+	ramp, err := NewSlowStart(1, 4, 0, 1000, RampShapeLinear)
+	require.NoError(t, err)
+	client := &http.Client{Transport: &concurrencyGateRoundTripper{base: http.DefaultTransport, gate: NewConcurrencyGate(ramp.Limit()), ramp: ramp}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(srv.URL)
+			if err == nil {
+				_, _ = io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), peak.Load(), "the ramp starts at its floor")
+	assert.Equal(t, 8, ramp.completed)
+}
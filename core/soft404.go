@@ -0,0 +1,185 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// maxSoft404Size caps how much of a baseline response is read
+const maxSoft404Size = 256 * 1024
+
+// soft404Threshold is the DOM signature distance under which an HTML page is
+// taken for the catch-all page, as for DOMDeduper
+const soft404Threshold = 6
+
+// soft404Detector remembers, per host and file extension, how the server
+// answers a path that cannot exist. Hosts with a catch-all route answer 200
+// for anything, which would otherwise turn every probe into a finding, and
+// many route unknown .php or .json paths differently from bare ones
+type soft404Detector struct {
+	mu    sync.Mutex
+	hosts map[string]*soft404Host
+}
+
+type soft404Host struct {
+	once     sync.Once
+	baseline *soft404Baseline
+}
+
+// soft404Baseline fingerprints the answer to a random path
+type soft404Baseline struct {
+	status    int
+	length    int
+	html      bool
+	signature uint64
+	body      []byte
+}
+
+func newSoft404Detector() *soft404Detector {
+	return &soft404Detector{hosts: make(map[string]*soft404Host)}
+}
+
+// newSoft404Baseline fingerprints a response. Only answers that could be
+// mistaken for a hit are kept: a host answering 404 needs no baseline
+func newSoft404Baseline(status int, contentType string, body []byte) *soft404Baseline {
+	if status == http.StatusNotFound || status == http.StatusGone || status >= 500 {
+		return nil
+	}
+	baseline := &soft404Baseline{status: status, length: len(body), body: body}
+	if isLikelyHTML(contentType, body) {
+		if sig, err := ComputeDOMSignature(body); err == nil {
+			baseline.html = true
+			baseline.signature = sig
+		}
+	}
+	return baseline
+}
+
+// matches reports whether a response looks like the catch-all page. Pages
+// may echo the requested path, so lengths only need to be close
+func (b *soft404Baseline) matches(status int, contentType string, body []byte) bool {
+	if b == nil || status != b.status {
+		return false
+	}
+	if bytes.Equal(body, b.body) {
+		return true
+	}
+	if b.html {
+		if !isLikelyHTML(contentType, body) {
+			return false
+		}
+		if !b.similarLength(len(body)) {
+			return false
+		}
+		sig, err := ComputeDOMSignature(body)
+		return err == nil && HammingDistance(sig, b.signature) <= soft404Threshold
+	}
+	return b.similarLength(len(body))
+}
+
+func (b *soft404Baseline) similarLength(length int) bool {
+	diff := length - b.length
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= 64 || diff*20 <= b.length
+}
+
+// soft404Extension returns the extension a baseline is kept for. Values too
+// long to be a file extension share the bare-path baseline
+func soft404Extension(u *url.URL) string {
+	ext := strings.ToLower(path.Ext(u.Path))
+	if len(ext) > 8 {
+		return ""
+	}
+	return ext
+}
+
+// soft404Canary returns a random path name from the crawl's RNG, so
+// --deterministic runs request the same canaries
+func (crawler *Crawler) soft404Canary() string {
+	crawler.payloadRNGMutex.Lock()
+	defer crawler.payloadRNGMutex.Unlock()
+	if crawler.payloadRNG == nil {
+		crawler.payloadRNG = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return strconv.FormatUint(crawler.payloadRNG.Uint64(), 36) + "-" + strconv.FormatUint(crawler.payloadRNG.Uint64(), 36)
+}
+
+// soft404For returns the baseline of u's host and extension, requesting a
+// random path with that extension on first use. It is nil when the host
+// answers such unknown paths with an error
+func (crawler *Crawler) soft404For(u *url.URL) *soft404Baseline {
+	d := crawler.soft404
+	if d == nil || u == nil {
+		return nil
+	}
+	origin := strings.ToLower(u.Scheme + "://" + u.Host)
+	ext := soft404Extension(u)
+	key := origin + " " + ext
+	d.mu.Lock()
+	host, ok := d.hosts[key]
+	if !ok {
+		host = &soft404Host{}
+		d.hosts[key] = host
+	}
+	d.mu.Unlock()
+
+	host.once.Do(func() {
+		canary := origin + "/" + crawler.soft404Canary() + ext
+		req, err := http.NewRequest(http.MethodGet, canary, nil)
+		if err != nil {
+			return
+		}
+		if crawler.Stats != nil {
+			crawler.Stats.IncrementRequestsMade()
+		}
+		client := http.DefaultClient
+		if crawler.AntiDetectClient != nil {
+			client = crawler.AntiDetectClient.GetHTTPClient()
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			Logger.Debugf("soft-404 baseline %s failed: %s", canary, err)
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxSoft404Size))
+		host.baseline = newSoft404Baseline(resp.StatusCode, resp.Header.Get("Content-Type"), body)
+		if host.baseline != nil {
+			Logger.Infof("[soft-404] %s answers unknown %s paths with %d; matching responses are treated as not found", origin, soft404Label(ext), resp.StatusCode)
+		}
+	})
+	return host.baseline
+}
+
+func soft404Label(ext string) string {
+	if ext == "" {
+		return "extensionless"
+	}
+	return ext
+}
+
+// isSoft404 reports whether a response for u looks like its host's answer to
+// a path that does not exist
+func (crawler *Crawler) isSoft404(u *url.URL, status int, contentType string, body []byte) bool {
+	return crawler.soft404For(u).matches(status, contentType, body)
+}
+
+// isSoft404Response is isSoft404 for a crawled response
+func (crawler *Crawler) isSoft404Response(response *colly.Response) bool {
+	if crawler.soft404 == nil || response == nil || response.Request == nil {
+		return false
+	}
+	return crawler.isSoft404(response.Request.URL, response.StatusCode, response.Headers.Get("Content-Type"), response.Body)
+}
@@ -0,0 +1,150 @@
+package core
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const catchAllPage = `<!doctype html><html><head><title>Shop</title></head><body><div id="app"><nav><a href="/">Home</a></nav><p>Loading %s</p></div></body></html>`
+
+// newCatchAllServer answers every path but the listed ones with the same
+// single-page app shell, which echoes the requested path
+func newCatchAllServer(pages map[string]func(http.ResponseWriter)) (*httptest.Server, *atomic.Int32, *sync.Map) {
+	var requests atomic.Int32
+	var seen sync.Map
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		seen.Store(r.URL.RequestURI(), true)
+		if page, ok := pages[r.URL.Path]; ok {
+			page(w)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, catchAllPage, r.URL.Path)
+	}))
+	return srv, &requests, &seen
+}
+
+func TestSoft404BaselineMatches(t *testing.T) {
+	shell := []byte(fmt.Sprintf(catchAllPage, "/x7k2"))
+	baseline := newSoft404Baseline(http.StatusOK, "text/html", shell)
+	require.NotNil(t, baseline)
+
+	assert.True(t, baseline.matches(http.StatusOK, "text/html", []byte(fmt.Sprintf(catchAllPage, "/admin/settings"))))
+	page := `<html><body><h1>Users</h1><table>` + strings.Repeat(`<tr><td class="name">alice</td><td><a href="/u/1">edit</a></td></tr>`, 20) + `</table></body></html>`
+	assert.False(t, baseline.matches(http.StatusOK, "text/html", []byte(page)))
+	assert.False(t, baseline.matches(http.StatusOK, "text/plain", []byte("DB_PASSWORD=secret\n")))
+	assert.False(t, baseline.matches(http.StatusForbidden, "text/html", shell))
+
+	text := newSoft404Baseline(http.StatusOK, "text/plain", []byte("nothing here"))
+	assert.True(t, text.matches(http.StatusOK, "text/plain", []byte("nothing here!")))
+	assert.False(t, text.matches(http.StatusOK, "application/json", []byte(`{"users":[{"id":1,"name":"alice"},{"id":2,"name":"bob"},{"id":3,"name":"carol"},{"id":4,"name":"dave"}]}`)))
+
+	// A host that answers unknown paths with 404 needs no baseline
+	assert.Nil(t, newSoft404Baseline(http.StatusNotFound, "text/html", shell))
+	var none *soft404Baseline
+	assert.False(t, none.matches(http.StatusOK, "text/html", shell))
+}
+
+func TestSoft404BaselinePerHost(t *testing.T) {
+	srv, requests, _ := newCatchAllServer(nil)
+	defer srv.Close()
+
+	crawler := &Crawler{soft404: newSoft404Detector()}
+	u, _ := url.Parse(srv.URL + "/a")
+	v, _ := url.Parse(srv.URL + "/b?c=d")
+	assert.True(t, crawler.isSoft404(u, http.StatusOK, "text/html", []byte(fmt.Sprintf(catchAllPage, "/a"))))
+	assert.True(t, crawler.isSoft404(v, http.StatusOK, "text/html", []byte(fmt.Sprintf(catchAllPage, "/b"))))
+	assert.Equal(t, int32(1), requests.Load())
+
+	// Without the detector nothing is requested or suppressed
+	crawler = &Crawler{}
+	assert.False(t, crawler.isSoft404(u, http.StatusOK, "text/html", []byte(fmt.Sprintf(catchAllPage, "/a"))))
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestCrawlSoft404SuppressesProbes(t *testing.T) {
+	srv, _, seen := newCatchAllServer(map[string]func(http.ResponseWriter){
+		"/": func(w http.ResponseWriter) {
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><a href="/api/v1/users">users</a><a href="/list?page=1">list</a></body></html>`)
+		},
+		"/api/v1/users": func(w http.ResponseWriter) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `[]`)
+		},
+		"/api/v2/users": func(w http.ResponseWriter) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `[{"id":1}]`)
+		},
+	})
+	defer srv.Close()
+
+	crawl := func(soft404 bool) (probes []string, followed bool) {
+		seen.Clear()
+		results := crawlAll(t, srv.URL, CrawlerConfig{
+			VersionProbe: true, VersionRules: map[string][]string{"api": {"api/internal"}}, VersionBump: 1,
+			MaxPagesFollow: 3, Soft404: soft404,
+		})
+		for _, sout := range results {
+			if sout.OutputType == "version-probe" {
+				probes = append(probes, sout.Output)
+			}
+		}
+		_, followed = seen.Load("/list?page=2")
+		return probes, followed
+	}
+
+	probes, followed := crawl(true)
+	assert.Equal(t, []string{srv.URL + "/api/v2/users"}, probes)
+	assert.False(t, followed)
+
+	// The catch-all answers every guess without the detector
+	probes, followed = crawl(false)
+	assert.Contains(t, probes, srv.URL+"/api/internal/v1/users")
+	assert.True(t, followed)
+}
+
+func TestSoft404BaselinePerExtension(t *testing.T) {
+	var canaries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		canaries = append(canaries, r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, ".php") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, catchAllPage, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	newCrawler := func() *Crawler {
+		return &Crawler{soft404: newSoft404Detector(), payloadRNG: rand.New(rand.NewSource(7))}
+	}
+	crawler := newCrawler()
+	page, _ := url.Parse(srv.URL + "/about")
+	script, _ := url.Parse(srv.URL + "/admin.PHP")
+	other, _ := url.Parse(srv.URL + "/login.php")
+	assert.True(t, crawler.isSoft404(page, http.StatusOK, "text/html", []byte(fmt.Sprintf(catchAllPage, "/about"))))
+	assert.False(t, crawler.isSoft404(script, http.StatusOK, "text/html", []byte(fmt.Sprintf(catchAllPage, "/admin.PHP"))), "a .php hit is not the extensionless catch-all")
+	assert.False(t, crawler.isSoft404(other, http.StatusOK, "text/html", []byte("<html>login</html>")))
+	require.Len(t, canaries, 2, "one canary per host and extension")
+	assert.True(t, strings.HasSuffix(canaries[1], ".php"))
+
+	first := canaries
+	canaries = nil
+	crawler = newCrawler()
+	crawler.isSoft404(page, http.StatusOK, "text/html", nil)
+	crawler.isSoft404(script, http.StatusOK, "text/html", nil)
+	assert.Equal(t, first, canaries, "canaries come from the seeded RNG")
+}
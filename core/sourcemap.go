@@ -0,0 +1,283 @@
+package core
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// maxSourceMapSize caps how much of a source map we are willing to parse
+const maxSourceMapSize = 8 * 1024 * 1024
+
+var sourceMappingURLRegex = regexp.MustCompile(`(?m)[/*]\s*[#@]\s*sourceMappingURL=([^\s'"*]+)`)
+
+// SourceMap holds the parts of a v3 source map we care about
+type SourceMap struct {
+	SourceRoot     string   `json:"sourceRoot"`
+	Sources        []string `json:"sources"`
+	SourcesContent []string `json:"sourcesContent"`
+	Mappings       string   `json:"mappings"`
+}
+
+// ParseSourceMap decodes a source map body, rejecting JSON that is not one
+func ParseSourceMap(body []byte) (*SourceMap, error) {
+	var sm SourceMap
+	if err := jsoniter.Unmarshal(body, &sm); err != nil {
+		return nil, err
+	}
+	if len(sm.Sources) == 0 && sm.Mappings == "" {
+		return nil, fmt.Errorf("not a source map")
+	}
+	return &sm, nil
+}
+
+// ExtractSourceMappingURL returns the source map reference of a JS body, if any
+func ExtractSourceMappingURL(body string) string {
+	matches := sourceMappingURLRegex.FindAllStringSubmatch(body, -1)
+	if len(matches) == 0 {
+		return ""
+	}
+	ref := matches[len(matches)-1][1]
+	if strings.HasPrefix(ref, "data:") {
+		return ""
+	}
+	return ref
+}
+
+// InlineSourceMap returns the source map a JS body embeds as a data: URI,
+// as webpack and esbuild do in development builds
+func InlineSourceMap(body string) []byte {
+	matches := sourceMappingURLRegex.FindAllStringSubmatch(body, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	rest, ok := strings.CutPrefix(matches[len(matches)-1][1], "data:")
+	if !ok {
+		return nil
+	}
+	meta, data, ok := strings.Cut(rest, ",")
+	if !ok {
+		return nil
+	}
+	if strings.HasSuffix(meta, ";base64") {
+		decoded, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			decoded, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(data, "="))
+		}
+		if err != nil {
+			return nil
+		}
+		return decoded
+	}
+	decoded, err := url.PathUnescape(data)
+	if err != nil {
+		return nil
+	}
+	return []byte(decoded)
+}
+
+// isSourceMapResponse reports whether a response is a source map: a .map URL,
+// or a JSON object whose head holds "mappings", or "sources" with "version".
+// A bare "sources" key is too common in API responses to go by
+func isSourceMapResponse(rawURL string, body []byte) bool {
+	if GetExtType(rawURL) == ".map" {
+		return true
+	}
+	trimmed := bytes.TrimSpace(body)
+	if !bytes.HasPrefix(trimmed, []byte("{")) {
+		return false
+	}
+	head := trimmed[:min(512, len(trimmed))]
+	if bytes.Contains(head, []byte(`"mappings"`)) {
+		return true
+	}
+	return bytes.Contains(head, []byte(`"version"`)) && bytes.Contains(head, []byte(`"sources"`))
+}
+
+// discoverSourceMap feeds the source map referenced by a JS response
+func (crawler *Crawler) discoverSourceMap(response *colly.Response, body string) {
+	ref := response.Headers.Get("SourceMap")
+	if ref == "" {
+		ref = response.Headers.Get("X-SourceMap")
+	}
+	if ref == "" {
+		ref = ExtractSourceMappingURL(body)
+	}
+	if ref == "" {
+		if inline := InlineSourceMap(body); inline != nil {
+			scriptURL := response.Request.URL.String()
+			if !crawler.sourceMapSet.Duplicate(scriptURL + "#inline") {
+				crawler.mineSourceMap(scriptURL, inline, response.Request)
+			}
+		}
+		return
+	}
+	if mapURL, ok := NormalizeURL(response.Request.URL, ref); ok {
+		crawler.feedLinkfinder(mapURL, "sourcemap", response.Request.URL.String())
+	}
+}
+
+// handleSourceMap emits original source paths from a source map and mines
+// the embedded sources for endpoints hidden by minification.
+func (crawler *Crawler) handleSourceMap(response *colly.Response) {
+	if crawler.stopped.Load() || response.Request == nil || response.Request.URL == nil {
+		return
+	}
+	mapURL := response.Request.URL.String()
+	if !isSourceMapResponse(mapURL, response.Body) {
+		return
+	}
+	if crawler.sourceMapSet.Duplicate(mapURL) {
+		return
+	}
+	crawler.mineSourceMap(mapURL, response.Body, response.Request)
+}
+
+// mineSourceMap reports the sources of a source map, runs LinkFinder over
+// their embedded content and, with --sourcemap-dir, writes that content
+// out. mapURL is the map, or the script embedding it
+func (crawler *Crawler) mineSourceMap(mapURL string, body []byte, request *colly.Request) {
+	if len(body) > maxSourceMapSize {
+		Logger.Debugf("Skipping source map %s: %d bytes exceeds cap", mapURL, len(body))
+		return
+	}
+
+	sm, err := ParseSourceMap(body)
+	if err != nil {
+		Logger.Debugf("Failed to parse source map %s: %s", mapURL, err)
+		return
+	}
+
+	for _, source := range sm.Sources {
+		source = strings.TrimSpace(source)
+		if source == "" {
+			continue
+		}
+		if sm.SourceRoot != "" && !strings.Contains(source, "://") {
+			source = strings.TrimRight(sm.SourceRoot, "/") + "/" + strings.TrimLeft(source, "/")
+		}
+		crawler.emitSourceMapSource(source, mapURL)
+		// Vendored packages would swamp the list with library names
+		if crawler.words != nil && !strings.Contains(source, "node_modules") {
+			crawler.words.AddPath(source)
+		}
+	}
+
+	for _, content := range sm.SourcesContent {
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+		paths, jsRequests, err := crawler.linkFinder(content, crawler.site)
+		if err != nil {
+			Logger.Debugf("LinkFinder failed on %s sources: %s", mapURL, err)
+			continue
+		}
+		for _, relPath := range paths {
+			if urlToVisit := crawler.urlProcessor.Process(relPath, mapURL, "sourcemap", request); urlToVisit != "" {
+				crawler.visit(nil, urlToVisit)
+			}
+		}
+		for _, req := range jsRequests {
+			crawler.processGeneratedRequest(req, mapURL, request.Depth)
+		}
+	}
+
+	if crawler.sourceMapDir != "" {
+		written, err := writeSourceMapSources(crawler.sourceMapDir, mapURL, sm)
+		if err != nil {
+			Logger.Errorf("Failed to write sources of %s: %s", mapURL, err)
+		}
+		if written > 0 {
+			Logger.Infof("Reconstructed %d sources of %s", written, mapURL)
+		}
+	}
+}
+
+// writeSourceMapSources writes the embedded sources of a map under
+// dir/<host>/, keeping their original paths, and returns how many it wrote.
+// A source that cannot be written is skipped and its error returned with
+// the others once every source was tried
+func writeSourceMapSources(dir, mapURL string, sm *SourceMap) (int, error) {
+	host := "unknown"
+	if u, err := url.Parse(mapURL); err == nil && u.Hostname() != "" {
+		host = strings.ReplaceAll(strings.ToLower(u.Hostname()), ".", "_")
+	}
+	base := filepath.Join(dir, host)
+	written := 0
+	var errs []error
+	for i, source := range sm.Sources {
+		if i >= len(sm.SourcesContent) || strings.TrimSpace(sm.SourcesContent[i]) == "" {
+			continue
+		}
+		rel := sourceMapFilePath(source)
+		if rel == "" {
+			rel = "source-" + strconv.Itoa(i)
+		}
+		target := filepath.Join(base, filepath.FromSlash(rel))
+		if inside, err := filepath.Rel(base, target); err != nil || !filepath.IsLocal(inside) {
+			errs = append(errs, fmt.Errorf("%s: outside %s", source, base))
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := os.WriteFile(target, []byte(sm.SourcesContent[i]), 0644); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		written++
+	}
+	return written, errors.Join(errs...)
+}
+
+// sourceMapFilePath turns a source such as webpack:///./src/api.ts?a1b2
+// into a relative path that cannot leave the folder it is joined to. It
+// returns "" for sources with no usable path, such as ones holding
+// backslashes, which Windows would take as separators
+func sourceMapFilePath(source string) string {
+	if _, rest, ok := strings.Cut(source, "://"); ok {
+		source = rest
+	}
+	if i := strings.IndexAny(source, "?#"); i != -1 {
+		source = source[:i]
+	}
+	if strings.Contains(source, `\`) {
+		return ""
+	}
+	// IsLocal rejects what cleaning left of .., absolute paths and volumes
+	rel := filepath.Clean(filepath.FromSlash(strings.TrimPrefix(path.Clean("/"+source), "/")))
+	if rel == "." || !filepath.IsLocal(rel) {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+func (crawler *Crawler) emitSourceMapSource(source, mapURL string) {
+	if crawler.sourceMapSet.Duplicate(mapURL + "|" + source) {
+		return
+	}
+	if crawler.Stats != nil {
+		crawler.Stats.IncrementURLsFound()
+	}
+
+	outputFormat := fmt.Sprintf("[sourcemap-source] - %s", source)
+	sout := SpiderOutput{
+		Input:      crawler.Input,
+		Source:     mapURL,
+		OutputType: "sourcemap-source",
+		Output:     source,
+	}
+	crawler.emit(sout, outputFormat, source)
+}
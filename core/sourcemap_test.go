@@ -0,0 +1,125 @@
+package core
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSourceMap(t *testing.T) {
+	body := []byte(`{"version":3,"sourceRoot":"","sources":["webpack:///src/api/client.ts"],"sourcesContent":["fetch('/api/v2/users')"],"mappings":"AAAA"}`)
+	sm, err := ParseSourceMap(body)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"webpack:///src/api/client.ts"}, sm.Sources)
+	assert.Len(t, sm.SourcesContent, 1)
+
+	_, err = ParseSourceMap([]byte(`{"name":"package"}`))
+	assert.Error(t, err)
+}
+
+func TestIsSourceMapResponse(t *testing.T) {
+	assert.True(t, isSourceMapResponse("http://example.com/app.js.map", nil))
+	assert.True(t, isSourceMapResponse("http://example.com/map", []byte(`{"version":3,"sources":["a.ts"],"names":[]}`)))
+	assert.True(t, isSourceMapResponse("http://example.com/map", []byte(`{"mappings":"AAAA","sources":["a.ts"]}`)))
+	assert.False(t, isSourceMapResponse("http://example.com/api/feeds", []byte(`{"sources":["rss","atom"],"count":2}`)), "a sources key alone is not a source map")
+	assert.False(t, isSourceMapResponse("http://example.com/api", []byte(`["version","sources"]`)))
+}
+
+func TestExtractSourceMappingURL(t *testing.T) {
+	assert.Equal(t, "app.js.map", ExtractSourceMappingURL("var a=1;\n//# sourceMappingURL=app.js.map"))
+	assert.Equal(t, "/static/app.css.map", ExtractSourceMappingURL("a{}\n/*# sourceMappingURL=/static/app.css.map */"))
+	assert.Equal(t, "", ExtractSourceMappingURL("//# sourceMappingURL=data:application/json;base64,eyJ9"))
+	assert.Equal(t, "", ExtractSourceMappingURL("var a=1;"))
+}
+
+func TestInlineSourceMap(t *testing.T) {
+	sm := `{"version":3,"sources":["src/a.ts"],"mappings":"AAAA"}`
+	encoded := base64.StdEncoding.EncodeToString([]byte(sm))
+	assert.Equal(t, sm, string(InlineSourceMap("var a=1;\n//# sourceMappingURL=data:application/json;charset=utf-8;base64,"+encoded)))
+	assert.Equal(t, `{"sources":[]}`, string(InlineSourceMap(`//# sourceMappingURL=data:application/json,%7B%22sources%22:%5B%5D%7D`)))
+	assert.Nil(t, InlineSourceMap("//# sourceMappingURL=app.js.map"))
+	assert.Nil(t, InlineSourceMap("//# sourceMappingURL=data:application/json;base64,!!!"))
+}
+
+func TestSourceMapFilePath(t *testing.T) {
+	assert.Equal(t, "src/api.ts", sourceMapFilePath("webpack:///./src/api.ts?a1b2"))
+	assert.Equal(t, "app/src/api.ts", sourceMapFilePath("webpack://app/./src/api.ts"))
+	assert.Equal(t, "etc/passwd", sourceMapFilePath("../../../etc/passwd"), "sources cannot escape the folder")
+	assert.Equal(t, "cdn.example.com/lib.js", sourceMapFilePath("https://cdn.example.com/lib.js"))
+	assert.Equal(t, "", sourceMapFilePath("webpack:///"))
+	assert.Equal(t, "", sourceMapFilePath(`webpack:///..\..\evil.js`), "backslashes are separators on Windows")
+}
+
+func TestWriteSourceMapSourcesSkipsFailures(t *testing.T) {
+	dir := t.TempDir()
+	// A file where a folder is needed makes that one source fail
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "example_com"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "example_com", "lib"), []byte("x"), 0o644))
+	sm := &SourceMap{
+		Sources:        []string{"webpack:///lib/a.js", "webpack:///src/b.js", `webpack:///..\c.js`},
+		SourcesContent: []string{"a()", "b()", "c()"},
+	}
+	written, err := writeSourceMapSources(dir, "https://example.com/app.js.map", sm)
+	assert.Error(t, err)
+	assert.Equal(t, 2, written)
+	data, err := os.ReadFile(filepath.Join(dir, "example_com", "src", "b.js"))
+	require.NoError(t, err)
+	assert.Equal(t, "b()", string(data))
+	data, err = os.ReadFile(filepath.Join(dir, "example_com", "source-2"))
+	require.NoError(t, err)
+	assert.Equal(t, "c()", string(data))
+}
+
+func TestCrawlMinesSourceMaps(t *testing.T) {
+	inline := base64.StdEncoding.EncodeToString([]byte(`{"version":3,"sources":["webpack:///src/admin.ts"],"sourcesContent":["fetch('/api/admin/stats')"],"mappings":"AAAA"}`))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<script src="/app.js"></script><script src="/admin.js"></script>`))
+		case "/app.js":
+			w.Header().Set("Content-Type", "application/javascript")
+			w.Write([]byte("var a=1;\n//# sourceMappingURL=app.js.map"))
+		case "/app.js.map":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"version":3,"sources":["webpack:///./src/api/client.ts","webpack:///./src/empty.ts"],"sourcesContent":["fetch('/api/v2/users')",""],"mappings":"AAAA"}`))
+		case "/admin.js":
+			w.Header().Set("Content-Type", "application/javascript")
+			w.Write([]byte("var b=2;\n//# sourceMappingURL=data:application/json;base64," + inline))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	results := crawlAll(t, srv.URL, CrawlerConfig{
+		MaxDepth: 3, LinkFinder: true,
+		SourceMapDir: dir,
+	})
+	sources := map[string]string{}
+	var found []string
+	for _, sout := range results {
+		if sout.OutputType == "sourcemap-source" {
+			sources[sout.Output] = sout.Source
+		}
+		found = append(found, sout.Output)
+	}
+
+	assert.Equal(t, srv.URL+"/app.js.map", sources["webpack:///./src/api/client.ts"])
+	assert.Equal(t, srv.URL+"/admin.js", sources["webpack:///src/admin.ts"], "inline maps are mined too")
+	assert.Contains(t, found, srv.URL+"/api/admin/stats")
+
+	host := "127_0_0_1"
+	content, err := os.ReadFile(filepath.Join(dir, host, "src", "api", "client.ts"))
+	require.NoError(t, err)
+	assert.Equal(t, "fetch('/api/v2/users')", string(content))
+	assert.FileExists(t, filepath.Join(dir, host, "src", "admin.ts"))
+	assert.NoFileExists(t, filepath.Join(dir, host, "src", "empty.ts"), "sources without content are not written")
+}
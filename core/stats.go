@@ -1,14 +1,45 @@
 package core
 
 import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"sync/atomic"
+	"text/tabwriter"
 	"time"
+
+	jsoniter "github.com/json-iterator/go"
 )
 
 type CrawlStats struct {
 	urlsFound     int64
 	requestsMade  int64
 	errors        int64
+	queued        int64
+	pendingVisits int64
+	activeSites   int64
+
+	statusMu sync.Mutex
+	statuses map[int]int64
+
+	hostsMu     sync.Mutex
+	activeHosts map[string]int
+
+	perHostMu sync.Mutex
+	perHost   map[string]*hostCounters
+}
+
+type hostCounters struct {
+	requests  int64
+	statuses  map[int]int64
+	bytes     int64
+	elapsed   time.Duration
+	responses int64
 }
 
 func NewCrawlStats() *CrawlStats {
@@ -52,4 +83,263 @@ func (s *CrawlStats) GetRPS(elapsed time.Duration) float64 {
 	}
 	requests := s.GetRequestsMade()
 	return float64(requests) / seconds
-}
\ No newline at end of file
+}
+
+// RecordStatus counts a response by status code
+func (s *CrawlStats) RecordStatus(code int) {
+	s.statusMu.Lock()
+	if s.statuses == nil {
+		s.statuses = make(map[int]int64)
+	}
+	s.statuses[code]++
+	s.statusMu.Unlock()
+}
+
+// GetStatusCounts returns a copy of the status code histogram
+func (s *CrawlStats) GetStatusCounts() map[int]int64 {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	counts := make(map[int]int64, len(s.statuses))
+	for code, n := range s.statuses {
+		counts[code] = n
+	}
+	return counts
+}
+
+// host returns the counters of a host; perHostMu must be held
+func (s *CrawlStats) host(host string) *hostCounters {
+	if s.perHost == nil {
+		s.perHost = make(map[string]*hostCounters)
+	}
+	counters, ok := s.perHost[host]
+	if !ok {
+		counters = &hostCounters{statuses: make(map[int]int64)}
+		s.perHost[host] = counters
+	}
+	return counters
+}
+
+// RecordHostRequest counts a request sent to host
+func (s *CrawlStats) RecordHostRequest(host string) {
+	s.perHostMu.Lock()
+	s.host(host).requests++
+	s.perHostMu.Unlock()
+}
+
+// RecordHostResponse counts a response of host by status code, along with
+// how long it took to arrive
+func (s *CrawlStats) RecordHostResponse(host string, code int, d time.Duration) {
+	s.perHostMu.Lock()
+	counters := s.host(host)
+	counters.statuses[code]++
+	counters.elapsed += d
+	counters.responses++
+	s.perHostMu.Unlock()
+}
+
+// AddHostBytes counts response body bytes downloaded from host
+func (s *CrawlStats) AddHostBytes(host string, n int) {
+	if n <= 0 {
+		return
+	}
+	s.perHostMu.Lock()
+	s.host(host).bytes += int64(n)
+	s.perHostMu.Unlock()
+}
+
+// HostStats summarizes the traffic of one host. Every request sent counts,
+// including redirect hops, probes and retries
+type HostStats struct {
+	Host            string        `json:"host"`
+	Requests        int64         `json:"requests"`
+	Statuses        map[int]int64 `json:"statuses"`
+	AvgResponseTime time.Duration `json:"-"`
+	AvgResponseMS   float64       `json:"avg_response_ms"`
+	Bytes           int64         `json:"bytes"`
+}
+
+// GetHostStats returns the per-host summaries, busiest host first
+func (s *CrawlStats) GetHostStats() []HostStats {
+	s.perHostMu.Lock()
+	hosts := make([]HostStats, 0, len(s.perHost))
+	for host, counters := range s.perHost {
+		stats := HostStats{Host: host, Requests: counters.requests, Bytes: counters.bytes, Statuses: make(map[int]int64, len(counters.statuses))}
+		for code, n := range counters.statuses {
+			stats.Statuses[code] = n
+		}
+		if counters.responses > 0 {
+			stats.AvgResponseTime = counters.elapsed / time.Duration(counters.responses)
+			stats.AvgResponseMS = float64(stats.AvgResponseTime.Microseconds()) / 1000
+		}
+		hosts = append(hosts, stats)
+	}
+	s.perHostMu.Unlock()
+	sort.Slice(hosts, func(i, j int) bool {
+		if hosts[i].Requests != hosts[j].Requests {
+			return hosts[i].Requests > hosts[j].Requests
+		}
+		return hosts[i].Host < hosts[j].Host
+	})
+	return hosts
+}
+
+// AddQueued tracks generated requests holding a --request-queue-size slot
+func (s *CrawlStats) AddQueued(delta int) {
+	atomic.AddInt64(&s.queued, int64(delta))
+}
+
+func (s *CrawlStats) GetQueued() int64 {
+	return atomic.LoadInt64(&s.queued)
+}
+
+// AddPendingVisits tracks discovered pages waiting in the visit queue
+func (s *CrawlStats) AddPendingVisits(delta int) {
+	atomic.AddInt64(&s.pendingVisits, int64(delta))
+}
+
+func (s *CrawlStats) GetPendingVisits() int64 {
+	return atomic.LoadInt64(&s.pendingVisits)
+}
+
+// AddActiveSites tracks the targets being crawled right now
+func (s *CrawlStats) AddActiveSites(delta int) {
+	atomic.AddInt64(&s.activeSites, int64(delta))
+}
+
+func (s *CrawlStats) GetActiveSites() int64 {
+	return atomic.LoadInt64(&s.activeSites)
+}
+
+// AddActiveHost marks a target host as being crawled; targets sharing a
+// host are counted
+func (s *CrawlStats) AddActiveHost(host string) {
+	s.hostsMu.Lock()
+	if s.activeHosts == nil {
+		s.activeHosts = make(map[string]int)
+	}
+	s.activeHosts[host]++
+	s.hostsMu.Unlock()
+}
+
+// RemoveActiveHost undoes AddActiveHost once the target is done
+func (s *CrawlStats) RemoveActiveHost(host string) {
+	s.hostsMu.Lock()
+	if s.activeHosts[host]--; s.activeHosts[host] <= 0 {
+		delete(s.activeHosts, host)
+	}
+	s.hostsMu.Unlock()
+}
+
+// GetActiveHosts returns the hosts being crawled, sorted
+func (s *CrawlStats) GetActiveHosts() []string {
+	s.hostsMu.Lock()
+	hosts := make([]string, 0, len(s.activeHosts))
+	for host := range s.activeHosts {
+		hosts = append(hosts, host)
+	}
+	s.hostsMu.Unlock()
+	sort.Strings(hosts)
+	return hosts
+}
+
+// hostStatsRoundTripper feeds the per-host counters of CrawlStats
+type hostStatsRoundTripper struct {
+	base  http.RoundTripper
+	stats *CrawlStats
+}
+
+func (rt *hostStatsRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Hostname()
+	rt.stats.RecordHostRequest(host)
+	start := time.Now()
+	resp, err := rt.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	rt.stats.RecordHostResponse(host, resp.StatusCode, time.Since(start))
+	resp.Body = &countingBody{ReadCloser: resp.Body, count: func(n int) { rt.stats.AddHostBytes(host, n) }}
+	return resp, nil
+}
+
+type countingBody struct {
+	io.ReadCloser
+	count func(n int)
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.count(n)
+	return n, err
+}
+
+// StatsSummary is the end-of-run report written by --stats-output
+type StatsSummary struct {
+	ElapsedSeconds float64       `json:"elapsed_seconds"`
+	Requests       int64         `json:"requests"`
+	URLsFound      int64         `json:"urls_found"`
+	Errors         int64         `json:"errors"`
+	RPS            float64       `json:"rps"`
+	Statuses       map[int]int64 `json:"statuses"`
+	Hosts          []HostStats   `json:"hosts"`
+}
+
+// Summary snapshots the counters of a run that took elapsed
+func (s *CrawlStats) Summary(elapsed time.Duration) StatsSummary {
+	return StatsSummary{
+		ElapsedSeconds: elapsed.Seconds(),
+		Requests:       s.GetRequestsMade(),
+		URLsFound:      s.GetURLsFound(),
+		Errors:         s.GetErrors(),
+		RPS:            s.GetRPS(elapsed),
+		Statuses:       s.GetStatusCounts(),
+		Hosts:          s.GetHostStats(),
+	}
+}
+
+// statusHistogram renders status counts as "200=12 404=1", by code
+func statusHistogram(statuses map[int]int64) string {
+	codes := make([]int, 0, len(statuses))
+	for code := range statuses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	parts := make([]string, len(codes))
+	for i, code := range codes {
+		parts[i] = fmt.Sprintf("%d=%d", code, statuses[code])
+	}
+	return strings.Join(parts, " ")
+}
+
+// HostTable renders the per-host summaries as an aligned table
+func (summary StatsSummary) HostTable() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "HOST\tREQUESTS\tAVG TIME\tBYTES\tSTATUSES")
+	for _, host := range summary.Hosts {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%s\n", host.Host, host.Requests, host.AvgResponseTime.Round(time.Millisecond), host.Bytes, statusHistogram(host.Statuses))
+	}
+	w.Flush()
+	return b.String()
+}
+
+// WriteStatsSummary writes summary to path as indented JSON
+func WriteStatsSummary(path string, summary StatsSummary) error {
+	data, err := jsoniter.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".gospider-stats-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
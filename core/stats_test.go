@@ -0,0 +1,79 @@
+package core
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostStatsRoundTripper(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, "hello world")
+	}))
+	defer srv.Close()
+
+	stats := NewCrawlStats()
+	client := &http.Client{Transport: &hostStatsRoundTripper{base: http.DefaultTransport, stats: stats}}
+	for _, path := range []string{"/", "/a", "/missing"} {
+		resp, err := client.Get(srv.URL + path)
+		require.NoError(t, err)
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	hosts := stats.GetHostStats()
+	require.Len(t, hosts, 1)
+	assert.Equal(t, "127.0.0.1", hosts[0].Host)
+	assert.Equal(t, int64(3), hosts[0].Requests)
+	assert.Equal(t, map[int]int64{200: 2, 404: 1}, hosts[0].Statuses)
+	assert.Equal(t, int64(2*len("hello world")+len("404 page not found\n")), hosts[0].Bytes)
+	assert.Positive(t, hosts[0].AvgResponseTime)
+}
+
+func TestStatsSummary(t *testing.T) {
+	stats := NewCrawlStats()
+	stats.RecordStatus(200)
+	stats.RecordStatus(301)
+	stats.RecordHostRequest("b.example")
+	stats.RecordHostRequest("a.example")
+	stats.RecordHostRequest("a.example")
+	stats.RecordHostResponse("a.example", 200, 30*time.Millisecond)
+	stats.RecordHostResponse("a.example", 301, 10*time.Millisecond)
+	stats.AddHostBytes("a.example", 512)
+
+	summary := stats.Summary(2 * time.Second)
+	require.Len(t, summary.Hosts, 2)
+	assert.Equal(t, "a.example", summary.Hosts[0].Host)
+	assert.Equal(t, 20*time.Millisecond, summary.Hosts[0].AvgResponseTime)
+	assert.Equal(t, "200=1 301=1", statusHistogram(summary.Statuses))
+
+	table := strings.Split(strings.TrimSpace(summary.HostTable()), "\n")
+	require.Len(t, table, 3)
+	assert.Equal(t, []string{"a.example", "2", "20ms", "512", "200=1", "301=1"}, strings.Fields(table[1]))
+
+	path := filepath.Join(t.TempDir(), "stats.json")
+	require.NoError(t, WriteStatsSummary(path, summary))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, 2.0, decoded["elapsed_seconds"])
+	assert.Equal(t, map[string]interface{}{"200": 1.0, "301": 1.0}, decoded["statuses"])
+	host := decoded["hosts"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, 20.0, host["avg_response_ms"])
+	assert.Equal(t, 512.0, host["bytes"])
+	assert.NotContains(t, host, "AvgResponseTime")
+}
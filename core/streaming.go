@@ -0,0 +1,143 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultStreamReadWindow bounds how long a streaming response is read
+	// when no --read-timeout is set
+	defaultStreamReadWindow = 3 * time.Second
+	// maxStreamPrefix is how much of a streaming response is kept
+	maxStreamPrefix = 64 * 1024
+)
+
+// IsStreamingContentType reports whether a content type is one servers keep
+// open indefinitely, such as server-sent events or newline-delimited JSON
+func IsStreamingContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	switch mediaType {
+	case "text/event-stream",
+		"application/x-ndjson",
+		"application/stream+json",
+		"application/x-json-stream",
+		"application/jsonl",
+		"multipart/x-mixed-replace":
+		return true
+	}
+	return false
+}
+
+// streamRoundTripper bounds body reads separately from the connect/header
+// timeout. Streaming responses are cut to a short prefix read within the
+// stream window; other bodies are only cut when readTimeout is set
+type streamRoundTripper struct {
+	base         http.RoundTripper
+	readTimeout  time.Duration
+	streamWindow time.Duration
+	maxPrefix    int64
+	onStream     func(rawURL, contentType string)
+}
+
+func (rt *streamRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	resp, err := rt.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	deadline, limit := rt.readTimeout, int64(-1)
+	if contentType := resp.Header.Get("Content-Type"); IsStreamingContentType(contentType) {
+		if rt.onStream != nil {
+			rt.onStream(req.URL.String(), contentType)
+		}
+		deadline, limit = rt.streamWindow, rt.maxPrefix
+		resp.ContentLength = -1
+	}
+	if deadline <= 0 && limit < 0 {
+		resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+		return resp, nil
+	}
+	resp.Body = newBoundedBody(resp.Body, cancel, deadline, limit)
+	return resp, nil
+}
+
+// boundedBody ends a response body with a clean EOF once its read deadline
+// passes or limit bytes were read, so the crawler keeps what it got instead
+// of failing the whole response
+type boundedBody struct {
+	body      io.ReadCloser
+	cancel    context.CancelFunc
+	timer     *time.Timer
+	remaining int64
+	expired   atomic.Bool
+}
+
+func newBoundedBody(body io.ReadCloser, cancel context.CancelFunc, deadline time.Duration, limit int64) *boundedBody {
+	b := &boundedBody{body: body, cancel: cancel, remaining: limit}
+	if deadline > 0 {
+		b.timer = time.AfterFunc(deadline, func() {
+			b.expired.Store(true)
+			cancel()
+		})
+	}
+	return b
+}
+
+func (b *boundedBody) Read(p []byte) (int, error) {
+	if b.remaining == 0 {
+		return 0, io.EOF
+	}
+	if b.remaining > 0 && int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.body.Read(p)
+	if b.remaining > 0 {
+		b.remaining -= int64(n)
+	}
+	if err != nil && b.expired.Load() {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (b *boundedBody) Close() error {
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	// Cancel first so closing an endless stream does not wait on the server
+	b.cancel()
+	return b.body.Close()
+}
+
+// emitStreamEndpoint reports an endpoint that answered with a streaming body
+func (crawler *Crawler) emitStreamEndpoint(rawURL, contentType string) {
+	if crawler.streamSet.Duplicate(rawURL) {
+		return
+	}
+	if crawler.Stats != nil {
+		crawler.Stats.IncrementURLsFound()
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+	sout := SpiderOutput{
+		Input:      crawler.Input,
+		Source:     mediaType,
+		OutputType: "stream-endpoint",
+		Output:     rawURL,
+	}
+	crawler.emit(sout, fmt.Sprintf("[stream-endpoint] - [%s] - %s", mediaType, rawURL), rawURL)
+}
@@ -0,0 +1,90 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsStreamingContentType(t *testing.T) {
+	assert.True(t, IsStreamingContentType("text/event-stream"))
+	assert.True(t, IsStreamingContentType("text/event-stream; charset=utf-8"))
+	assert.True(t, IsStreamingContentType("application/x-ndjson"))
+	assert.False(t, IsStreamingContentType("text/html"))
+	assert.False(t, IsStreamingContentType("application/json"))
+}
+
+// neverClosingHandler streams events until the client goes away
+func neverClosingHandler(contentType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		flusher, _ := w.(http.Flusher)
+		for {
+			if _, err := fmt.Fprint(w, "data: {\"path\":\"/api/events\"}\n\n"); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+		}
+	}
+}
+
+func TestBoundedBodyReadDeadline(t *testing.T) {
+	srv := httptest.NewServer(neverClosingHandler("text/plain"))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &streamRoundTripper{base: http.DefaultTransport, readTimeout: 200 * time.Millisecond, streamWindow: time.Second, maxPrefix: maxStreamPrefix}}
+	start := time.Now()
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	require.NoError(t, err, "the deadline ends the body cleanly")
+	assert.NotEmpty(t, body)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestCrawlStreamEndpoint(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><a href="/events">events</a><a href="/about">about</a></body></html>`)
+		case "/events":
+			neverClosingHandler("text/event-stream")(w, r)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body>about</body></html>`)
+		}
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	results := crawlAll(t, srv.URL, CrawlerConfig{Timeout: 20 * time.Second, ReadTimeout: 500 * time.Millisecond})
+
+	var streams, urls []string
+	for _, sout := range results {
+		switch sout.OutputType {
+		case "stream-endpoint":
+			streams = append(streams, sout.Output)
+		case "url":
+			urls = append(urls, sout.Output)
+		}
+	}
+	assert.Equal(t, []string{srv.URL + "/events"}, streams)
+	assert.Contains(t, urls, srv.URL+"/about")
+	assert.Less(t, time.Since(start), 10*time.Second, "the stream must not hold the worker until the client timeout")
+}
@@ -0,0 +1,98 @@
+package core
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// Target is a single site to crawl with optional per-target overrides.
+// Lines in the sites file are either a bare URL or a JSON object such as
+// {"url":"https://a.com","cookie":"sid=1","headers":["X-A: b"],"scope":"a\\.com","auth":"user:pass"}
+type Target struct {
+	URL     string   `json:"url"`
+	Cookie  string   `json:"cookie,omitempty"`
+	Headers []string `json:"headers,omitempty"`
+	Scope   string   `json:"scope,omitempty"`
+	Auth    string   `json:"auth,omitempty"`
+}
+
+// ParseTargetLine parses a sites file line into a Target.
+func ParseTargetLine(line string) (Target, error) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "{") {
+		return Target{URL: line}, nil
+	}
+
+	var t Target
+	if err := jsoniter.UnmarshalFromString(line, &t); err != nil {
+		return Target{}, fmt.Errorf("invalid target line: %w", err)
+	}
+	t.URL = strings.TrimSpace(t.URL)
+	if t.URL == "" {
+		return Target{}, fmt.Errorf("target line has no url")
+	}
+	if t.Scope != "" {
+		if _, err := regexp.Compile(t.Scope); err != nil {
+			return Target{}, fmt.Errorf("invalid scope regex %q: %w", t.Scope, err)
+		}
+	}
+	return t, nil
+}
+
+// Apply returns a copy of base with the target's overrides layered on top.
+func (t Target) Apply(base CrawlerConfig) CrawlerConfig {
+	cfg := base
+	if t.Cookie != "" {
+		cfg.Cookie = t.Cookie
+	}
+	if len(t.Headers) > 0 || t.Auth != "" {
+		own := append([]string(nil), t.Headers...)
+		if t.Auth != "" {
+			own = append(own, "Authorization: "+authHeaderValue(t.Auth))
+		}
+		// A header the target sets replaces the global one instead of
+		// being sent twice; repeats within either list are kept
+		overridden := make(map[string]bool, len(own))
+		for _, h := range own {
+			overridden[headerFlagName(h)] = true
+		}
+		cfg.Headers = nil
+		for _, h := range base.Headers {
+			if !overridden[headerFlagName(h)] {
+				cfg.Headers = append(cfg.Headers, h)
+			}
+		}
+		cfg.Headers = append(cfg.Headers, own...)
+	}
+	if t.Scope != "" {
+		cfg.Whitelist = t.Scope
+	}
+	return cfg
+}
+
+// authSchemes are the Authorization schemes a target's auth may start with
+var authSchemes = []string{
+	"Basic", "Bearer", "Digest", "Negotiate", "NTLM", "Token", "ApiKey", "DPoP",
+	"HOBA", "Mutual", "vapid", "AWS4-HMAC-SHA256", "SCRAM-SHA-1", "SCRAM-SHA-256",
+}
+
+// authHeaderValue turns "user:pass" into a basic auth value and passes
+// anything starting with a known scheme (e.g. "Bearer xyz") through
+// untouched. A password may hold spaces: "user:pa ss" is still encoded
+func authHeaderValue(auth string) string {
+	if scheme, _, ok := strings.Cut(auth, " "); ok {
+		for _, known := range authSchemes {
+			if strings.EqualFold(scheme, known) {
+				return auth
+			}
+		}
+	}
+	if !strings.Contains(auth, ":") {
+		return auth
+	}
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(auth))
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTargetLine(t *testing.T) {
+	target, err := ParseTargetLine("https://example.com/")
+	assert.NoError(t, err)
+	assert.Equal(t, Target{URL: "https://example.com/"}, target)
+
+	target, err = ParseTargetLine(`{"url":"https://a.com","cookie":"sid=1","headers":["X-A: b"],"scope":"a\\.com","auth":"user:pass"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://a.com", target.URL)
+	assert.Equal(t, "sid=1", target.Cookie)
+	assert.Equal(t, []string{"X-A: b"}, target.Headers)
+	assert.Equal(t, `a\.com`, target.Scope)
+
+	_, err = ParseTargetLine(`{"cookie":"sid=1"}`)
+	assert.Error(t, err, "missing url should fail")
+
+	_, err = ParseTargetLine(`{"url":"https://a.com","scope":"("}`)
+	assert.Error(t, err, "bad scope regex should fail")
+}
+
+func TestTargetApply(t *testing.T) {
+	base := CrawlerConfig{Cookie: "global=1", Headers: []string{"X-Global: 1"}}
+
+	cfg := Target{URL: "https://a.com"}.Apply(base)
+	assert.Equal(t, base.Cookie, cfg.Cookie)
+	assert.Equal(t, base.Headers, cfg.Headers)
+
+	cfg = Target{URL: "https://a.com", Cookie: "sid=1", Headers: []string{"X-A: b"}, Scope: `a\.com`, Auth: "user:pass"}.Apply(base)
+	assert.Equal(t, "sid=1", cfg.Cookie)
+	assert.Equal(t, `a\.com`, cfg.Whitelist)
+	assert.Equal(t, []string{"X-Global: 1", "X-A: b", "Authorization: Basic dXNlcjpwYXNz"}, cfg.Headers)
+	assert.Equal(t, []string{"X-Global: 1"}, base.Headers, "base headers must not be mutated")
+
+	cfg = Target{URL: "https://a.com", Auth: "Bearer abc"}.Apply(base)
+	assert.Contains(t, cfg.Headers, "Authorization: Bearer abc")
+
+	base.Headers = []string{"X-Global: 1", "Authorization: Bearer global", "X-Forwarded-For: 1.1.1.1"}
+	cfg = Target{URL: "https://a.com", Headers: []string{"X-Forwarded-For: 2.2.2.2", "x-forwarded-for: 3.3.3.3"}, Auth: "Bearer own"}.Apply(base)
+	assert.Equal(t, []string{"X-Global: 1", "X-Forwarded-For: 2.2.2.2", "x-forwarded-for: 3.3.3.3", "Authorization: Bearer own"}, cfg.Headers)
+}
+
+func TestAuthHeaderValue(t *testing.T) {
+	for auth, want := range map[string]string{
+		"user:pass":                 "Basic dXNlcjpwYXNz",
+		"user:pa ss":                "Basic dXNlcjpwYSBzcw==",
+		"Custom user:pass":          "Basic Q3VzdG9tIHVzZXI6cGFzcw==",
+		"Bearer abc":                "Bearer abc",
+		"bearer a:b":                "bearer a:b",
+		"Basic dXNlcjpwYXNz":        "Basic dXNlcjpwYXNz",
+		"AWS4-HMAC-SHA256 Cred=a:b": "AWS4-HMAC-SHA256 Cred=a:b",
+		"opaque-token":              "opaque-token",
+	} {
+		assert.Equal(t, want, authHeaderValue(auth), auth)
+	}
+}
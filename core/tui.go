@@ -0,0 +1,217 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+const (
+	// tuiRefresh is how often the --tui dashboard is redrawn
+	tuiRefresh = 250 * time.Millisecond
+	// tuiMaxFindings is how many recent findings the dashboard keeps
+	tuiMaxFindings = 500
+)
+
+// TUI is the --tui dashboard: live counters, the status code histogram,
+// queue depths, the hosts being crawled and a tail of recent findings. It is
+// the run's Sink, so findings reach it instead of stdout while -o files
+// are written as usual. Keys: p or space pauses and resumes, q quits
+type TUI struct {
+	out   io.Writer
+	in    *os.File
+	stats *CrawlStats
+	gate  *PauseGate
+	start time.Time
+
+	mu       sync.Mutex
+	findings []string
+	total    int64
+}
+
+// NewTUI returns a dashboard for stats, or nil with a warning when stdout
+// is not a terminal, in which case the crawl prints its results as usual
+func NewTUI(stats *CrawlStats) *TUI {
+	if !term.IsTerminal(int(os.Stdout.Fd())) {
+		Logger.Warnf("--tui needs a terminal on stdout; printing results instead")
+		return nil
+	}
+	t := &TUI{out: os.Stdout, stats: stats, gate: NewPauseGate(), start: time.Now()}
+	// Keys are read from stdin only when it is the terminal; a piped target
+	// list leaves the dashboard without pause and quit keys
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		t.in = os.Stdin
+	}
+	return t
+}
+
+// Gate returns the pause gate the crawlers wait on
+func (t *TUI) Gate() *PauseGate {
+	return t.gate
+}
+
+// Write implements OutputSink
+func (t *TUI) Write(sout SpiderOutput) error {
+	line := fmt.Sprintf("[%s] - %s", sout.OutputType, sout.Output)
+	if sout.StatusCode != 0 {
+		line = fmt.Sprintf("[%s] - [code-%d] - %s", sout.OutputType, sout.StatusCode, sout.Output)
+	}
+	t.mu.Lock()
+	t.total++
+	t.findings = append(t.findings, strings.Join(strings.Fields(line), " "))
+	if len(t.findings) > tuiMaxFindings {
+		t.findings = append(t.findings[:0], t.findings[len(t.findings)-tuiMaxFindings:]...)
+	}
+	t.mu.Unlock()
+	return nil
+}
+
+// Close implements OutputSink; the dashboard is torn down by Run
+func (t *TUI) Close() {}
+
+// Run takes over the terminal and redraws the dashboard until the returned
+// function is called. quit is called when the user presses q or Ctrl-C,
+// which raw mode no longer turns into an interrupt
+func (t *TUI) Run(ctx context.Context, quit func()) (stop func()) {
+	restoreLog := Logger.Out
+	Logger.SetOutput(io.Discard)
+
+	var restoreTerm func()
+	if t.in != nil {
+		if state, err := term.MakeRaw(int(t.in.Fd())); err == nil {
+			restoreTerm = func() { _ = term.Restore(int(t.in.Fd()), state) }
+			go t.readKeys(quit)
+		}
+	}
+	// Alternate screen, cursor hidden
+	fmt.Fprint(t.out, "\x1b[?1049h\x1b[?25l")
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(tuiRefresh)
+		defer ticker.Stop()
+		for {
+			t.draw()
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-finished
+			fmt.Fprint(t.out, "\x1b[?25h\x1b[?1049l")
+			if restoreTerm != nil {
+				restoreTerm()
+			}
+			Logger.SetOutput(restoreLog)
+			t.mu.Lock()
+			total := t.total
+			t.mu.Unlock()
+			fmt.Fprintf(t.out, "%d findings in %s\n", total, time.Since(t.start).Round(time.Second))
+		})
+	}
+}
+
+func (t *TUI) readKeys(quit func()) {
+	buf := make([]byte, 16)
+	for {
+		n, err := t.in.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, key := range buf[:n] {
+			switch key {
+			case 'p', 'P', ' ':
+				t.gate.Toggle()
+				t.draw()
+			case 'q', 'Q', 0x03:
+				quit()
+				return
+			}
+		}
+	}
+}
+
+func (t *TUI) draw() {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		width, height = 80, 24
+	}
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	for _, line := range t.render(width, height) {
+		// Raw mode needs the carriage return
+		b.WriteString(line + "\x1b[K\r\n")
+	}
+	b.WriteString("\x1b[J")
+	fmt.Fprint(t.out, b.String())
+}
+
+// render lays the dashboard out in at most height lines of width columns
+func (t *TUI) render(width, height int) []string {
+	elapsed := time.Since(t.start)
+	state := "running"
+	if t.gate.Paused() {
+		state = "PAUSED"
+	}
+	keys := "p pause  q quit"
+	if t.in == nil {
+		keys = "no keyboard: stdin is not a terminal"
+	}
+
+	counts := t.stats.GetStatusCounts()
+	codes := make([]int, 0, len(counts))
+	for code := range counts {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	var histogram []string
+	for _, code := range codes {
+		histogram = append(histogram, fmt.Sprintf("%d:%d", code, counts[code]))
+	}
+	hosts := t.stats.GetActiveHosts()
+
+	t.mu.Lock()
+	total := t.total
+	lines := []string{
+		fmt.Sprintf("%s %s | %s | %s | %s", CLIName, VERSION, elapsed.Round(time.Second), state, keys),
+		fmt.Sprintf("Requests %d (%.1f/s)  URLs %d  Errors %d  Findings %d",
+			t.stats.GetRequestsMade(), t.stats.GetRPS(elapsed), t.stats.GetURLsFound(), t.stats.GetErrors(), total),
+		fmt.Sprintf("Queues: requests %d  visits %d  Active sites %d", t.stats.GetQueued(), t.stats.GetPendingVisits(), t.stats.GetActiveSites()),
+		"Status: " + strings.Join(histogram, " "),
+		"Hosts: " + strings.Join(hosts, ", "),
+		strings.Repeat("-", width),
+	}
+	if room := height - len(lines) - 1; room > 0 {
+		tail := t.findings
+		if len(tail) > room {
+			tail = tail[len(tail)-room:]
+		}
+		lines = append(lines, tail...)
+	}
+	t.mu.Unlock()
+
+	for i, line := range lines {
+		if len(line) > width {
+			lines[i] = line[:width]
+		}
+	}
+	return lines
+}
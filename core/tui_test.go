@@ -0,0 +1,82 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPauseGate(t *testing.T) {
+	var gate *PauseGate
+	gate.Wait(nil, nil)
+	assert.False(t, gate.Paused())
+
+	gate = NewPauseGate()
+	assert.True(t, gate.Toggle())
+	assert.True(t, gate.Paused())
+
+	released := make(chan struct{})
+	go func() {
+		gate.Wait(nil, nil)
+		close(released)
+	}()
+	select {
+	case <-released:
+		t.Fatal("Wait returned while paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.False(t, gate.Toggle())
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+		t.Fatal("Wait did not return after resume")
+	}
+
+	// A stopping crawl is not held back
+	gate.Toggle()
+	stop := make(chan struct{})
+	close(stop)
+	gate.Wait(stop, nil)
+}
+
+func TestTUIRender(t *testing.T) {
+	stats := NewCrawlStats()
+	stats.IncrementRequestsMade()
+	stats.RecordStatus(200)
+	stats.RecordStatus(200)
+	stats.RecordStatus(404)
+	stats.AddActiveHost("b.example")
+	stats.AddActiveHost("a.example")
+	stats.AddActiveHost("a.example")
+	stats.RemoveActiveHost("a.example")
+	stats.AddPendingVisits(7)
+
+	tui := &TUI{stats: stats, gate: NewPauseGate(), start: time.Now()}
+	for i := 0; i < 20; i++ {
+		tui.Write(SpiderOutput{OutputType: "url", StatusCode: 200, Output: "https://a.example/" + strings.Repeat("x", i)})
+	}
+	tui.Write(SpiderOutput{OutputType: "subdomain", Output: "api.a.example"})
+
+	lines := tui.render(60, 10)
+	require.Len(t, lines, 9)
+	screen := strings.Join(lines, "\n")
+	assert.Contains(t, lines[0], "running")
+	assert.Contains(t, screen, "Findings 21")
+	assert.Contains(t, screen, "visits 7")
+	assert.Contains(t, screen, "Status: 200:2 404:1")
+	assert.Contains(t, screen, "Hosts: a.example, b.example")
+	assert.Equal(t, "[subdomain] - api.a.example", lines[len(lines)-1])
+	assert.Equal(t, "[url] - [code-200] - https://a.example/xxxxxxxxxxxxxxxxxxx", lines[len(lines)-2])
+	for _, line := range lines {
+		assert.LessOrEqual(t, len(line), 60)
+	}
+
+	tui.gate.Toggle()
+	assert.Contains(t, tui.render(60, 10)[0], "PAUSED")
+
+	stats.RemoveActiveHost("a.example")
+	assert.Equal(t, []string{"b.example"}, stats.GetActiveHosts())
+}
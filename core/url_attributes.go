@@ -0,0 +1,125 @@
+package core
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// DefaultURLAttributes are the non-standard attributes scanned for URLs
+var DefaultURLAttributes = []string{
+	"data-url", "data-src", "data-href", "ng-href", "ng-src",
+	":href", ":src", "v-bind:href", "v-bind:src", "formaction",
+}
+
+// DefaultAttributeMethods map htmx request attributes to HTTP methods
+var DefaultAttributeMethods = []string{
+	"hx-get=GET", "hx-post=POST", "hx-put=PUT", "hx-patch=PATCH", "hx-delete=DELETE",
+}
+
+func attributeSet(names []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			set[name] = struct{}{}
+		}
+	}
+	return set
+}
+
+// isDestructiveMethod reports methods that may change server state; requests
+// using them are reported but never sent
+func isDestructiveMethod(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// attributeURL cleans an attribute value into a URL candidate, dropping
+// template expressions that cannot be resolved without running the framework
+func attributeURL(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	// Vue bindings hold JS expressions; only plain string literals are usable
+	if len(value) >= 2 && (value[0] == '\'' || value[0] == '`') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	if value == "" || value == "#" || strings.ContainsAny(value, " +{}$") {
+		return "", false
+	}
+	return value, true
+}
+
+// attributeSelector matches the elements carrying any of the named
+// attributes. Characters such as the ':' of ":href" are escaped for CSS
+func attributeSelector(names []string) string {
+	sort.Strings(names)
+	selectors := make([]string, 0, len(names))
+	for _, name := range names {
+		var escaped strings.Builder
+		for _, r := range name {
+			if !(r == '-' || r == '_' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9') {
+				escaped.WriteByte('\\')
+			}
+			escaped.WriteRune(r)
+		}
+		selectors = append(selectors, "["+escaped.String()+"]")
+	}
+	return strings.Join(selectors, ",")
+}
+
+// registerURLAttributes scans the elements carrying one of the configured
+// URL and method-mapped attributes once per page
+func (crawler *Crawler) registerURLAttributes() {
+	if len(crawler.urlAttributes) == 0 && len(crawler.attributeMethods) == 0 {
+		return
+	}
+	names := make([]string, 0, len(crawler.urlAttributes)+len(crawler.attributeMethods))
+	for name := range crawler.urlAttributes {
+		names = append(names, name)
+	}
+	for name := range crawler.attributeMethods {
+		names = append(names, name)
+	}
+	selector := attributeSelector(names)
+	crawler.C.OnHTML("html", func(e *colly.HTMLElement) {
+		if crawler.stopped.Load() || crawler.shouldSkipDOM(e.Request.URL.String()) {
+			return
+		}
+		base := crawler.documentBase(e)
+		pageURL := e.Request.URL.String()
+		for _, node := range e.DOM.Find(selector).Nodes {
+			for _, attr := range node.Attr {
+				name := strings.ToLower(attr.Key)
+				raw, ok := attributeURL(attr.Val)
+				if !ok {
+					continue
+				}
+				if method, mapped := crawler.attributeMethods[name]; mapped {
+					resolved, ok := NormalizeURL(base, raw)
+					if !ok {
+						continue
+					}
+					req := JSRequest{Method: method, RawURL: resolved, Source: name}
+					if isDestructiveMethod(method) {
+						if normalized, ok := crawler.normalizeJSRequest(req, pageURL); ok {
+							crawler.emitJSRequest(normalized, pageURL, e.Request.Depth+1)
+						}
+						continue
+					}
+					crawler.processGeneratedRequest(req, pageURL, e.Request.Depth)
+					continue
+				}
+				if _, wanted := crawler.urlAttributes[name]; !wanted {
+					continue
+				}
+				if urlToVisit := crawler.urlProcessor.ProcessWithBase(raw, name, "attr", base, e.Request); urlToVisit != "" {
+					crawler.visit(e.Request, urlToVisit)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttributeURL(t *testing.T) {
+	for value, want := range map[string]string{
+		"/api/items":          "/api/items",
+		"'/static/app.js'":    "/static/app.js",
+		"`/v2/users`":         "/v2/users",
+		"'/users/' + user.id": "",
+		"/users/{{id}}":       "",
+		"#":                   "",
+	} {
+		got, _ := attributeURL(value)
+		assert.Equal(t, want, got, value)
+	}
+}
+
+func TestParseAttributeMethods(t *testing.T) {
+	methods, err := ParseAttributeMethods([]string{"HX-GET=get", " data-action = post "})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"hx-get": "GET", "data-action": "POST"}, methods)
+
+	_, err = ParseAttributeMethods([]string{"hx-get"})
+	assert.Error(t, err)
+}
+
+func TestAttributeSelector(t *testing.T) {
+	assert.Equal(t, `[\:href],[hx-get],[v-bind\:src]`, attributeSelector([]string{"v-bind:src", ":href", "hx-get"}))
+}
+
+func TestCrawlScansURLAttributes(t *testing.T) {
+	var deleted, saved atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			deleted.Store(true)
+		}
+		if r.Method == http.MethodPost && r.URL.Path == "/htmx/save" {
+			saved.Store(true)
+		}
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<html><body>
+<div data-url="/lazy/panel"></div>
+<a :href="'/vue/route'">vue</a>
+<button hx-get="/htmx/list">list</button>
+<button hx-post="/htmx/save">save</button>
+<button hx-delete="/htmx/item/1">delete</button>
+</body></html>`)
+			return
+		}
+		fmt.Fprint(w, `<html><body>leaf</body></html>`)
+	}))
+	defer srv.Close()
+
+	methods, err := ParseAttributeMethods(DefaultAttributeMethods)
+	require.NoError(t, err)
+
+	results := crawlAll(t, srv.URL, CrawlerConfig{
+		URLAttributes:    DefaultURLAttributes,
+		AttributeMethods: methods,
+	})
+
+	var outputs []string
+	for _, sout := range results {
+		outputs = append(outputs, sout.Output)
+	}
+	joined := fmt.Sprint(outputs)
+	assert.Contains(t, outputs, srv.URL+"/lazy/panel")
+	assert.Contains(t, outputs, srv.URL+"/vue/route")
+	assert.Contains(t, joined, "/htmx/list")
+	assert.Contains(t, joined, "/htmx/save")
+	assert.True(t, saved.Load(), "hx-post requests are sent")
+	assert.Contains(t, joined, "/htmx/item/1")
+	assert.False(t, deleted.Load(), "destructive htmx requests must not be sent")
+}
@@ -2,10 +2,10 @@ package core
 
 import (
 	"fmt"
+	"net/url"
 	"strings"
 
 	"github.com/gocolly/colly/v2"
-	jsoniter "github.com/json-iterator/go"
 )
 
 // URLProcessor handles the processing of URLs found by the crawler.
@@ -27,8 +27,21 @@ func NewURLProcessor(crawler *Crawler) *URLProcessor {
 
 // Process handles a found URL, normalizes it, checks for duplicates, and returns it for visiting.
 func (p *URLProcessor) Process(rawURL, source, outputType string, request *colly.Request) string {
-	// Normalize the URL against the request's URL first, then the crawler's site URL.
-	normalizedURL, ok := NormalizeURL(request.URL, rawURL)
+	return p.ProcessWithBase(rawURL, source, outputType, nil, request)
+}
+
+// ProcessWithBase is Process with relative URLs resolved against base, such as
+// a page's <base href>, instead of the request URL. A nil base means the request URL.
+func (p *URLProcessor) ProcessWithBase(rawURL, source, outputType string, base *url.URL, request *colly.Request) string {
+	if base == nil && request != nil {
+		base = request.URL
+	}
+	// Normalize the URL against the base first, then the crawler's site URL.
+	var normalizedURL string
+	ok := false
+	if base != nil {
+		normalizedURL, ok = NormalizeURL(base, rawURL)
+	}
 	if !ok {
 		normalizedURL, ok = NormalizeURL(p.crawler.site, rawURL)
 		if !ok {
@@ -36,6 +49,16 @@ func (p *URLProcessor) Process(rawURL, source, outputType string, request *colly
 		}
 	}
 
+	// Remember outgoing links so a 304 on the parent can re-queue them.
+	if p.crawler.validators != nil && request != nil && request.URL != nil {
+		p.crawler.validators.AddLink(request.URL.String(), normalizedURL)
+	}
+
+	if p.crawler.params != nil {
+		p.crawler.params.AddURL(normalizedURL)
+	}
+	p.crawler.countHybridLink(request)
+
 	// Check for duplicates before proceeding.
 	if p.registry.Duplicate(normalizedURL) {
 		return ""
@@ -44,8 +67,24 @@ func (p *URLProcessor) Process(rawURL, source, outputType string, request *colly
 	if p.crawler.Stats != nil {
 		p.crawler.Stats.IncrementURLsFound()
 	}
+	if p.crawler.words != nil {
+		p.crawler.words.AddPath(normalizedURL)
+	}
 
-	p.logOutput(normalizedURL, source, outputType)
+	// Links are followed one level below the page they were found on, except
+	// later pages of a listing, which stay at the listing's depth
+	depth := 0
+	if request != nil {
+		depth = request.Depth + 1
+		if outputType == "pagination" {
+			depth = request.Depth
+		}
+	}
+	if p.crawler.skipSessionEndpoint(normalizedURL, outputType) {
+		p.logOutput(normalizedURL, source, sessionEndpointType, depth)
+		return ""
+	}
+	p.logOutput(normalizedURL, source, outputType, depth)
 
 	// Return the URL to be visited.
 	return normalizedURL
@@ -61,37 +100,27 @@ func (p *URLProcessor) ProcessJSURL(rawURL, source, outputType string) {
 		p.crawler.Stats.IncrementURLsFound()
 	}
 
-	p.logOutput(rawURL, source, outputType)
+	p.logOutput(rawURL, source, outputType, 0)
 
 	// Special handling for .min.js files
 	if strings.Contains(rawURL, ".min.js") {
 		originalJS := strings.ReplaceAll(rawURL, ".min.js", ".js")
-		_ = p.crawler.C.Visit(originalJS)
+		p.crawler.visit(nil, originalJS)
 	}
 
-	_ = p.crawler.C.Visit(rawURL)
+	p.crawler.visit(nil, rawURL)
 }
 
 // logOutput handles the printing and storing of the found URL.
-func (p *URLProcessor) logOutput(url, source, outputType string) {
+func (p *URLProcessor) logOutput(url, source, outputType string, depth int) {
 	outputFormat := fmt.Sprintf("[%s] - %s", outputType, url)
 
-	if p.crawler.JsonOutput {
-		sout := SpiderOutput{
-			Input:      p.crawler.Input,
-			Source:     source,
-			OutputType: outputType,
-			Output:     url,
-		}
-		if data, err := jsoniter.MarshalToString(sout); err == nil {
-			outputFormat = data
-		}
-	} else if p.crawler.Quiet {
-		outputFormat = url
-	}
-
-	fmt.Println(outputFormat)
-	if p.crawler.Output != nil {
-		p.crawler.Output.WriteToFile(outputFormat)
+	sout := SpiderOutput{
+		Input:      p.crawler.Input,
+		Source:     source,
+		OutputType: outputType,
+		Output:     url,
+		Depth:      depth,
 	}
+	p.crawler.emit(sout, outputFormat, url)
 }
\ No newline at end of file
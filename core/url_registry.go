@@ -18,6 +18,11 @@ type URLRegistry struct {
 	filter     *stringset.StringFilter
 	respMu     sync.Mutex
 	respHashes map[string]string
+	// journal, when set, is told each new key, for --resume
+	journal func(string)
+	// shared, when set, reports whether another gospider instance already
+	// sent a request new to this one, for --redis
+	shared func(string) bool
 }
 
 func NewURLRegistry() *URLRegistry {
@@ -36,6 +41,17 @@ func (r *URLRegistry) Duplicate(raw string) bool {
 	return r.DuplicateRequest(http.MethodGet, raw, "")
 }
 
+// Seen reports whether a GET of raw was recorded, without recording it.
+func (r *URLRegistry) Seen(raw string) bool {
+	key := canonicalRequestKey(http.MethodGet, raw, "")
+	if key == "" {
+		return false
+	}
+
+	r.ensure()
+	return r.filter.Has(key)
+}
+
 // DuplicateRequest canonicalizes method+URL+body and tracks uniqueness.
 func (r *URLRegistry) DuplicateRequest(method, rawURL, body string) bool {
 	key := canonicalRequestKey(method, rawURL, body)
@@ -44,7 +60,16 @@ func (r *URLRegistry) DuplicateRequest(method, rawURL, body string) bool {
 	}
 
 	r.ensure()
-	return r.filter.Duplicate(key)
+	if r.filter.Duplicate(key) {
+		return true
+	}
+	if r.shared != nil && r.shared(key) {
+		return true
+	}
+	if r.journal != nil {
+		r.journal(key)
+	}
+	return false
 }
 
 // MarkResponse stores a response hash and returns true when the same payload was observed before.
@@ -71,6 +71,7 @@ func NormalizeURL(base *url.URL, candidate string) (string, bool) {
 	if resolved.Host == "" {
 		return "", false
 	}
+	resolved.Host = asciiHostPort(resolved.Host)
 
 	// Normalise path and drop fragments.
 	resolved.Fragment = ""
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"html"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -32,7 +33,10 @@ func GetRawCookie(cookies []*http.Cookie) string {
 }
 
 func GetDomain(site *url.URL) string {
-	domain, err := publicsuffix.EffectiveTLDPlusOne(site.Hostname())
+	if IsIPHost(site) {
+		return site.Hostname()
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(ASCIIHost(site.Hostname()))
 	if err != nil {
 		return ""
 	}
@@ -123,7 +127,7 @@ func CleanSubdomain(s string) string {
 	s = strings.TrimPrefix(s, "*.")
 	// s = strings.Trim("u00","")
 	s = cleanName(s)
-	return s
+	return ASCIIHost(s)
 }
 
 // Clean up the names scraped from the web.
@@ -240,7 +244,50 @@ func decodeEscapedSequences(s string) string {
 	return builder.String()
 }
 
+// defaultPort returns the port a URL of scheme uses when it names none
+func defaultPort(scheme string) string {
+	switch strings.ToLower(scheme) {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	}
+	return ""
+}
+
+// IsIPHost reports whether the URL host is an IPv4 or IPv6 literal
+func IsIPHost(site *url.URL) bool {
+	return net.ParseIP(site.Hostname()) != nil
+}
+
+// ScopePattern returns the regex matching in-scope URLs for site. IP literal
+// targets are pinned to the exact host:port since subdomains make no sense
+// there. A target on its scheme's default port takes either scheme on its
+// default port, written or not, so 10.0.0.5, 10.0.0.5:80 and
+// https://10.0.0.5:443 are one scope.
+func ScopePattern(site *url.URL, subs bool) string {
+	if IsIPHost(site) {
+		host := site.Hostname()
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		host = regexp.QuoteMeta(host)
+		port := site.Port()
+		if port == "" || port == defaultPort(site.Scheme) {
+			return "(?i)^(?:http://" + host + "(?::80)?|https://" + host + "(?::443)?)(?:[/?#]|$)"
+		}
+		return "(?i)^https?://" + host + ":" + port + "(?:[/?#]|$)"
+	}
+	hostPattern := regexp.QuoteMeta(ASCIIHost(site.Hostname()))
+	if subs {
+		return "(?i)" + hostPattern
+	}
+	return "(?i)(?:https?://)" + hostPattern
+}
+
 func InScope(u *url.URL, regexps []*regexp.Regexp) bool {
+	// url.URL.String percent-encodes a Unicode host, which no scope matches
+	u = asciiURL(u)
 	for _, r := range regexps {
 		if r.MatchString(u.String()) {
 			return true
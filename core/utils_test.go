@@ -0,0 +1,79 @@
+package core
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScopePatternIPTargets(t *testing.T) {
+	cases := []struct {
+		site     string
+		inScope  []string
+		outScope []string
+	}{
+		{
+			site:     "http://10.0.0.5",
+			inScope:  []string{"http://10.0.0.5/", "https://10.0.0.5/login?a=1", "http://10.0.0.5"},
+			outScope: []string{"http://10.0.0.50/", "http://10.0.0.5:8080/", "http://evil.com/?u=http://10.0.0.5/"},
+		},
+		{
+			site:     "http://10.0.0.5:8080/app",
+			inScope:  []string{"http://10.0.0.5:8080/", "http://10.0.0.5:8080/app/x"},
+			outScope: []string{"http://10.0.0.5/", "http://10.0.0.5:80801/"},
+		},
+		{
+			site:     "http://10.0.0.5:80/",
+			inScope:  []string{"http://10.0.0.5/", "http://10.0.0.5:80/a", "https://10.0.0.5:443/", "https://10.0.0.5/"},
+			outScope: []string{"http://10.0.0.5:8080/", "http://10.0.0.5:443/", "https://10.0.0.5:80/"},
+		},
+		{
+			site:     "https://10.0.0.5:443",
+			inScope:  []string{"https://10.0.0.5/", "http://10.0.0.5:80/"},
+			outScope: []string{"https://10.0.0.5:8443/"},
+		},
+		{
+			site:     "https://[::1]",
+			inScope:  []string{"https://[::1]:443/", "http://[::1]/"},
+			outScope: []string{"http://[::1]:8080/"},
+		},
+		{
+			site:     "http://[::1]:8080/",
+			inScope:  []string{"http://[::1]:8080/", "http://[::1]:8080/a#b"},
+			outScope: []string{"http://[::1]/", "http://[::2]:8080/"},
+		},
+		{
+			site:     "http://[::1]/",
+			inScope:  []string{"http://[::1]/index"},
+			outScope: []string{"http://[::1]:8443/"},
+		},
+	}
+
+	for _, tc := range cases {
+		site, err := url.Parse(tc.site)
+		assert.NoError(t, err)
+		assert.True(t, IsIPHost(site), tc.site)
+		assert.Equal(t, site.Hostname(), GetDomain(site))
+
+		// subs must not widen the scope of an IP target
+		for _, subs := range []bool{false, true} {
+			filters := []*regexp.Regexp{regexp.MustCompile(ScopePattern(site, subs))}
+			for _, raw := range tc.inScope {
+				u, _ := url.Parse(raw)
+				assert.True(t, InScope(u, filters), "%s should be in scope of %s", raw, tc.site)
+			}
+			for _, raw := range tc.outScope {
+				u, _ := url.Parse(raw)
+				assert.False(t, InScope(u, filters), "%s should be out of scope of %s", raw, tc.site)
+			}
+		}
+	}
+}
+
+func TestGetDomainHostname(t *testing.T) {
+	site, _ := url.Parse("https://www.example.co.uk/")
+	assert.False(t, IsIPHost(site))
+	assert.Equal(t, "example.co.uk", GetDomain(site))
+}
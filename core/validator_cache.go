@@ -0,0 +1,138 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gocolly/colly/v2"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ValidatorEntry holds the cache validators and outgoing links recorded for a URL
+type ValidatorEntry struct {
+	ETag         string   `json:"etag,omitempty"`
+	LastModified string   `json:"last_modified,omitempty"`
+	Links        []string `json:"links,omitempty"`
+}
+
+// ValidatorCache persists ETag/Last-Modified validators between crawls so
+// unchanged pages can be revalidated with conditional requests
+type ValidatorCache struct {
+	path    string
+	mutex   sync.Mutex
+	entries map[string]*ValidatorEntry
+}
+
+// LoadValidatorCache reads the cache file at path; a missing file yields an empty cache
+func LoadValidatorCache(path string) (*ValidatorCache, error) {
+	cache := &ValidatorCache{
+		path:    path,
+		entries: make(map[string]*ValidatorEntry),
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := jsoniter.Unmarshal(data, &cache.entries); err != nil {
+			return nil, err
+		}
+	}
+	return cache, nil
+}
+
+// Get returns a copy of the entry recorded for rawURL
+func (vc *ValidatorCache) Get(rawURL string) (ValidatorEntry, bool) {
+	vc.mutex.Lock()
+	defer vc.mutex.Unlock()
+	entry, ok := vc.entries[rawURL]
+	if !ok {
+		return ValidatorEntry{}, false
+	}
+	result := *entry
+	result.Links = append([]string(nil), entry.Links...)
+	return result, true
+}
+
+// Store records fresh validators for rawURL and resets its links, which are
+// re-collected while the new response is processed
+func (vc *ValidatorCache) Store(rawURL, etag, lastModified string) {
+	vc.mutex.Lock()
+	defer vc.mutex.Unlock()
+	if etag == "" && lastModified == "" {
+		delete(vc.entries, rawURL)
+		return
+	}
+	vc.entries[rawURL] = &ValidatorEntry{ETag: etag, LastModified: lastModified}
+}
+
+// AddLink records that parent links to child
+func (vc *ValidatorCache) AddLink(parent, child string) {
+	vc.mutex.Lock()
+	defer vc.mutex.Unlock()
+	entry, ok := vc.entries[parent]
+	if !ok {
+		return
+	}
+	for _, link := range entry.Links {
+		if link == child {
+			return
+		}
+	}
+	entry.Links = append(entry.Links, child)
+}
+
+// Save writes the cache back to its file
+func (vc *ValidatorCache) Save() error {
+	vc.mutex.Lock()
+	data, err := jsoniter.Marshal(vc.entries)
+	vc.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(vc.path), ".gospider-validators-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), vc.path)
+}
+
+// handleNotModified reports a 304 without running extractors and re-queues the
+// links recorded for the page on the previous crawl
+func (crawler *Crawler) handleNotModified(response *colly.Response) {
+	pageURL := response.Request.URL.String()
+	u := NormalizeDisplayURL(pageURL)
+	outputFormat := fmt.Sprintf("[url] - [code-%d] - %s", http.StatusNotModified, u)
+
+	sout := SpiderOutput{
+		Input:      crawler.Input,
+		Source:     "cache",
+		OutputType: "url",
+		StatusCode: http.StatusNotModified,
+		Output:     u,
+		Depth:      response.Request.Depth,
+	}
+	crawler.emit(sout, outputFormat, u)
+
+	entry, _ := crawler.validators.Get(pageURL)
+	for _, link := range entry.Links {
+		if urlToVisit := crawler.urlProcessor.Process(link, pageURL, "cached", response.Request); urlToVisit != "" {
+			crawler.visit(response.Request, urlToVisit)
+		}
+	}
+}
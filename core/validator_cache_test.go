@@ -0,0 +1,69 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatorCacheRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "validators.json")
+
+	cache, err := LoadValidatorCache(path)
+	require.NoError(t, err)
+	cache.Store("https://example.com/", `"abc"`, "Mon, 02 Jan 2006 15:04:05 GMT")
+	cache.AddLink("https://example.com/", "https://example.com/about")
+	cache.AddLink("https://example.com/", "https://example.com/about")
+	cache.AddLink("https://example.com/missing", "https://example.com/x")
+	require.NoError(t, cache.Save())
+
+	reloaded, err := LoadValidatorCache(path)
+	require.NoError(t, err)
+	entry, ok := reloaded.Get("https://example.com/")
+	require.True(t, ok)
+	assert.Equal(t, `"abc"`, entry.ETag)
+	assert.Equal(t, []string{"https://example.com/about"}, entry.Links)
+	_, ok = reloaded.Get("https://example.com/missing")
+	assert.False(t, ok)
+}
+
+func TestConditionalRecrawlRequeuesCachedLinks(t *testing.T) {
+	var notModified int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := `"` + r.URL.Path + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<html><body><a href="/about">about</a></body></html>`)
+			return
+		}
+		fmt.Fprint(w, `<html><body>about</body></html>`)
+	}))
+	defer srv.Close()
+
+	cache, err := LoadValidatorCache(filepath.Join(t.TempDir(), "validators.json"))
+	require.NoError(t, err)
+
+	crawlAll(t, srv.URL, CrawlerConfig{Validators: cache})
+	second := crawlAll(t, srv.URL, CrawlerConfig{Validators: cache})
+
+	assert.EqualValues(t, 2, atomic.LoadInt32(&notModified))
+	var found bool
+	for _, sout := range second {
+		if sout.StatusCode == http.StatusNotModified && sout.Output == srv.URL+"/about" {
+			found = true
+		}
+	}
+	assert.True(t, found, "cached link was not revalidated: %+v", second)
+}
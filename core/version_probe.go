@@ -0,0 +1,144 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// DefaultVersionRules are the --version-rule rewrites applied besides vN bumping
+var DefaultVersionRules = []string{"api=api/internal|api/beta"}
+
+const (
+	defaultVersionBump        = 2
+	defaultVersionProbeBudget = 50
+)
+
+var versionSegmentRegex = regexp.MustCompile(`^(?i)(v)(\d{1,3})$`)
+
+// VersionProbeCandidates derives sibling API paths from an observed one: every
+// vN segment is bumped up to bump versions and every segment named by a rule
+// is swapped for its replacements. The query string is dropped
+func VersionProbeCandidates(u *url.URL, rules map[string][]string, bump int) []string {
+	if u == nil {
+		return nil
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	var candidates []string
+	seen := map[string]struct{}{}
+	add := func(i, span int, replacement string) {
+		parts := append(append(append([]string{}, segments[:i]...), replacement), segments[i+span:]...)
+		candidate := *u
+		candidate.RawQuery = ""
+		candidate.Fragment = ""
+		candidate.RawPath = ""
+		candidate.Path = "/" + strings.Join(parts, "/")
+		if strings.HasSuffix(u.Path, "/") {
+			candidate.Path += "/"
+		}
+		if candidate.Path == u.Path {
+			return
+		}
+		s := candidate.String()
+		if _, dup := seen[s]; dup {
+			return
+		}
+		seen[s] = struct{}{}
+		candidates = append(candidates, s)
+	}
+
+	for i, segment := range segments {
+		if m := versionSegmentRegex.FindStringSubmatch(segment); m != nil {
+			n, _ := strconv.Atoi(m[2])
+			for k := 1; k <= bump; k++ {
+				add(i, 1, m[1]+strconv.Itoa(n+k))
+			}
+		}
+		replacements := rules[strings.ToLower(segment)]
+		// On /api/internal/users the rule api=api/internal|api/beta should
+		// yield /api/beta/users, not nest under the variant already present
+		span := 1
+		for _, replacement := range replacements {
+			if _, tail, ok := strings.Cut(replacement, "/"); ok && i+1 < len(segments) && strings.EqualFold(segments[i+1], tail) {
+				span = 2
+			}
+		}
+		for _, replacement := range replacements {
+			add(i, span, replacement)
+		}
+	}
+	return candidates
+}
+
+// probeAPIVersions guesses adjacent API versions of a crawled path and
+// reports the ones the server knows about, within the probe budget
+func (crawler *Crawler) probeAPIVersions(r *colly.Request) {
+	if r == nil || r.URL == nil || crawler.stopped.Load() {
+		return
+	}
+	for _, candidate := range VersionProbeCandidates(r.URL, crawler.versionRules, crawler.versionBump) {
+		if crawler.versionSet.Duplicate(candidate) {
+			continue
+		}
+		u, err := url.Parse(candidate)
+		if err != nil || !InScope(u, crawler.C.URLFilters) {
+			continue
+		}
+		if crawler.versionProbeBudget.Add(-1) < 0 {
+			return
+		}
+
+		req, err := http.NewRequest(http.MethodGet, candidate, nil)
+		if err != nil {
+			continue
+		}
+		if r.Headers != nil {
+			req.Header = r.Headers.Clone()
+		}
+		if crawler.Stats != nil {
+			crawler.Stats.IncrementRequestsMade()
+		}
+		resp, err := crawler.AntiDetectClient.GetHTTPClient().Do(req)
+		if err != nil {
+			Logger.Debugf("version-probe %s failed: %s", candidate, err)
+			continue
+		}
+		var body []byte
+		if crawler.soft404 != nil && resp.StatusCode < 400 {
+			body, _ = io.ReadAll(io.LimitReader(resp.Body, maxSoft404Size))
+		}
+		resp.Body.Close()
+		crawler.recordBackoff(resp.StatusCode)
+
+		switch resp.StatusCode {
+		case http.StatusNotFound, http.StatusGone, http.StatusTooManyRequests:
+			continue
+		}
+		if body != nil && crawler.isSoft404(u, resp.StatusCode, resp.Header.Get("Content-Type"), body) {
+			continue
+		}
+		crawler.emitVersionProbe(candidate, r.URL.String(), resp.StatusCode, r.Depth)
+	}
+}
+
+func (crawler *Crawler) emitVersionProbe(candidate, source string, status, depth int) {
+	if crawler.Stats != nil {
+		crawler.Stats.IncrementURLsFound()
+	}
+	outputFormat := fmt.Sprintf("[version-probe] - [code-%d] - %s", status, candidate)
+	sout := SpiderOutput{
+		Input:      crawler.Input,
+		Source:     source,
+		OutputType: "version-probe",
+		StatusCode: status,
+		Output:     candidate,
+		Depth:      depth,
+	}
+	crawler.emit(sout, outputFormat, candidate)
+}
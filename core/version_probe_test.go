@@ -0,0 +1,90 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionProbeCandidates(t *testing.T) {
+	rules, err := ParseVersionRules(DefaultVersionRules)
+	require.NoError(t, err)
+
+	u, _ := url.Parse("https://example.com/api/v1/users?id=1")
+	assert.Equal(t, []string{
+		"https://example.com/api/internal/v1/users",
+		"https://example.com/api/beta/v1/users",
+		"https://example.com/api/v2/users",
+		"https://example.com/api/v3/users",
+	}, VersionProbeCandidates(u, rules, 2))
+
+	u, _ = url.Parse("https://example.com/api/internal/users")
+	assert.Equal(t, []string{"https://example.com/api/beta/users"}, VersionProbeCandidates(u, rules, 2))
+
+	u, _ = url.Parse("https://example.com/about")
+	assert.Empty(t, VersionProbeCandidates(u, rules, 2))
+}
+
+func TestParseVersionRules(t *testing.T) {
+	rules, err := ParseVersionRules([]string{"API=/api/internal/ | api/beta", "graphql=graphql/v2"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"api":     {"api/internal", "api/beta"},
+		"graphql": {"graphql/v2"},
+	}, rules)
+
+	_, err = ParseVersionRules([]string{"api"})
+	assert.Error(t, err)
+	_, err = ParseVersionRules([]string{"api=a||b"})
+	assert.Error(t, err)
+}
+
+func TestCrawlVersionProbe(t *testing.T) {
+	var probed atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" && r.URL.Path != "/api/v1/users" {
+			probed.Add(1)
+		}
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><a href="/api/v1/users">users</a></body></html>`)
+		case "/api/v1/users":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `[]`)
+		case "/api/v2/users":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	crawl := func(budget int) []SpiderOutput {
+		results := crawlAll(t, srv.URL, CrawlerConfig{VersionProbe: true, VersionProbeBudget: budget})
+
+		var probes []SpiderOutput
+		for _, sout := range results {
+			if sout.OutputType == "version-probe" {
+				probes = append(probes, sout)
+			}
+		}
+		return probes
+	}
+
+	// The first candidate is /api/internal/v1/users, a 404
+	assert.Empty(t, crawl(1))
+	assert.Equal(t, int32(1), probed.Load())
+
+	probes := crawl(0)
+	require.Len(t, probes, 1)
+	assert.Equal(t, srv.URL+"/api/v2/users", probes[0].Output)
+	assert.Equal(t, srv.URL+"/api/v1/users", probes[0].Source)
+	assert.Equal(t, http.StatusUnauthorized, probes[0].StatusCode)
+}
@@ -0,0 +1,274 @@
+package core
+
+import (
+	"container/heap"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// DefaultPriorityKeywords mark high-value paths that --prioritize crawls first
+var DefaultPriorityKeywords = []string{
+	"admin", "api", "debug", "internal", ".git", ".env", "backup", "config", "graphql", "swagger", "console", "upload",
+}
+
+// visitQueue holds discovered pages in front of colly, which starts every
+// visit right away and serves them first come, first served. Only as many
+// visits as --concurrent allows are handed to colly at a time; the rest wait
+// here and those whose path contains a priority keyword jump the line. With
+// --max-queue-size, visits past that many spill to disk.
+// Producers and finishing requests pump the queue themselves, always from
+// inside a colly callback or before the crawl is waited on, so colly's wait
+// group never goes from zero to one under a concurrent Wait
+type visitQueue struct {
+	mu         sync.Mutex
+	pending    visitHeap
+	queued     map[uint64]struct{}
+	seq        uint64
+	inFlight   int
+	limit      int
+	keywords   []string
+	maxPending int
+	spill      *visitSpill
+	spillErr   error
+}
+
+type pendingVisit struct {
+	url      string
+	depth    int
+	priority int
+	seq      uint64
+}
+
+type visitHeap []pendingVisit
+
+func (h visitHeap) Len() int { return len(h) }
+func (h visitHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h visitHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *visitHeap) Push(x any)   { *h = append(*h, x.(pendingVisit)) }
+func (h *visitHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// initVisitQueue puts a priority queue admitting limit visits at a time in
+// front of crawler.C, keeping up to maxPending waiting visits in memory (0 for
+// no limit). Like initRequestQueue it must run before other response
+// callbacks are registered
+func (crawler *Crawler) initVisitQueue(limit int, keywords []string, maxPending int) {
+	if limit < 1 {
+		limit = 1
+	}
+	var lowered []string
+	for _, keyword := range keywords {
+		if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" {
+			lowered = append(lowered, keyword)
+		}
+	}
+	crawler.visitQueue = &visitQueue{limit: limit, keywords: lowered, maxPending: maxPending, queued: make(map[uint64]struct{})}
+	crawler.registerSlotRelease()
+}
+
+// priority ranks a URL by whether its path or query holds a keyword
+func (q *visitQueue) priority(rawURL string) int {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return 0
+	}
+	target := strings.ToLower(u.EscapedPath() + "?" + u.RawQuery)
+	for _, keyword := range q.keywords {
+		if strings.Contains(target, keyword) {
+			return 1
+		}
+	}
+	return 0
+}
+
+// visit requests a discovered page one level below parent, or at depth 1
+// without a parent. Without --prioritize it is colly's own Visit
+func (crawler *Crawler) visit(parent *colly.Request, rawURL string) {
+	q := crawler.visitQueue
+	if q == nil {
+		key := crawler.trackVisit(rawURL)
+		var err error
+		if parent != nil {
+			err = parent.Visit(rawURL)
+		} else {
+			err = crawler.C.Visit(rawURL)
+		}
+		if err != nil {
+			crawler.state.Done(key)
+		}
+		return
+	}
+	if crawler.stopped.Load() {
+		return
+	}
+	depth := 1
+	if parent != nil {
+		depth = parent.Depth + 1
+	}
+	// Queued visits reach colly as depth 1 requests, so the limit is checked here
+	if maxDepth := crawler.C.MaxDepth; maxDepth > 0 && depth > maxDepth {
+		return
+	}
+	if crawler.C.URLFilters != nil {
+		if u, err := url.Parse(rawURL); err != nil || !InScope(u, crawler.C.URLFilters) {
+			return
+		}
+	}
+
+	// Duplicates are dropped before they take memory or disk space
+	if visited, _ := crawler.C.HasVisited(rawURL); visited {
+		return
+	}
+	key := visitKey(rawURL)
+
+	q.mu.Lock()
+	if _, dup := q.queued[key]; dup {
+		q.mu.Unlock()
+		return
+	}
+	q.queued[key] = struct{}{}
+	crawler.trackVisit(rawURL)
+	crawler.addPendingVisits(1)
+	q.seq++
+	next := pendingVisit{url: rawURL, depth: depth, priority: q.priority(rawURL), seq: q.seq}
+	if q.shouldSpill(next) {
+		if q.spillErr = q.spillVisit(next); q.spillErr == nil {
+			q.mu.Unlock()
+			return
+		}
+		Logger.Warnf("Could not spill the crawl queue to disk: %s; keeping it in memory", q.spillErr)
+	}
+	heap.Push(&q.pending, next)
+	q.mu.Unlock()
+	crawler.pumpVisits()
+}
+
+// shouldSpill reports whether a visit goes to disk: once memory is full, and
+// after that until the spilled visits are read back, so they keep their turn.
+// Priority visits always stay in memory
+func (q *visitQueue) shouldSpill(v pendingVisit) bool {
+	if q.maxPending <= 0 || v.priority > 0 || q.spillErr != nil {
+		return false
+	}
+	return q.pending.Len() >= q.maxPending || q.spill.Len() > 0
+}
+
+func (q *visitQueue) spillVisit(v pendingVisit) error {
+	if q.spill == nil {
+		spill, err := newVisitSpill()
+		if err != nil {
+			return err
+		}
+		q.spill = spill
+		Logger.Infof("Crawl queue passed %d pages; spilling the rest to %s", q.maxPending, spill.file.Name())
+	}
+	return q.spill.push(v)
+}
+
+// refill reads spilled visits back once memory is half empty
+func (q *visitQueue) refill() {
+	if q.spill.Len() == 0 || q.pending.Len() > q.maxPending/2 {
+		return
+	}
+	visits, err := q.spill.pop(q.maxPending - q.pending.Len())
+	if err != nil {
+		Logger.Warnf("Could not read the crawl queue back from disk: %s", err)
+	}
+	for _, v := range visits {
+		q.seq++
+		v.seq = q.seq
+		heap.Push(&q.pending, v)
+	}
+}
+
+// closeVisitQueue removes the spill file at the end of the crawl
+func (crawler *Crawler) closeVisitQueue() {
+	q := crawler.visitQueue
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	crawler.addPendingVisits(-(q.pending.Len() + q.spill.Len()))
+	q.pending = nil
+	q.spill.remove()
+	q.spill = nil
+}
+
+func (crawler *Crawler) addPendingVisits(delta int) {
+	if crawler.Stats != nil && delta != 0 {
+		crawler.Stats.AddPendingVisits(delta)
+	}
+}
+
+func visitKey(rawURL string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(rawURL))
+	return h.Sum64()
+}
+
+// pumpVisits hands queued visits to colly while slots are free
+func (crawler *Crawler) pumpVisits() {
+	q := crawler.visitQueue
+	for {
+		q.mu.Lock()
+		if crawler.stopped.Load() {
+			crawler.addPendingVisits(-(q.pending.Len() + q.spill.Len()))
+			q.pending = nil
+			q.spill.remove()
+			q.spill = nil
+		}
+		if q.inFlight >= q.limit {
+			q.mu.Unlock()
+			return
+		}
+		q.refill()
+		if q.pending.Len() == 0 {
+			q.mu.Unlock()
+			return
+		}
+		next := heap.Pop(&q.pending).(pendingVisit)
+		delete(q.queued, visitKey(next.url))
+		crawler.addPendingVisits(-1)
+		q.inFlight++
+		q.mu.Unlock()
+
+		ctx := colly.NewContext()
+		ctx.Put("__depth", strconv.Itoa(next.depth))
+		var once sync.Once
+		free := func() (freed bool) {
+			once.Do(func() {
+				q.mu.Lock()
+				q.inFlight--
+				q.mu.Unlock()
+				freed = true
+			})
+			return freed
+		}
+		ctx.Put("__release", func() {
+			if free() {
+				crawler.pumpVisits()
+			}
+		})
+		// Duplicates and filtered URLs fail right here; the loop reuses their slot
+		if err := crawler.C.Request(http.MethodGet, next.url, nil, ctx, nil); err != nil {
+			free()
+			crawler.state.Done(canonicalRequestKey(http.MethodGet, next.url, ""))
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package core
+
+import (
+	"container/heap"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVisitHeapOrdersByPriorityThenArrival(t *testing.T) {
+	q := &visitQueue{keywords: []string{"admin", ".git"}}
+	var h visitHeap
+	for i, u := range []string{"/a", "/admin/users", "/b", "/.git/config", "/c"} {
+		heap.Push(&h, pendingVisit{url: u, priority: q.priority("http://example.com" + u), seq: uint64(i)})
+	}
+	var order []string
+	for h.Len() > 0 {
+		order = append(order, heap.Pop(&h).(pendingVisit).url)
+	}
+	assert.Equal(t, []string{"/admin/users", "/.git/config", "/a", "/b", "/c"}, order)
+}
+
+func TestVisitQueuePrioritizesKeywords(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		order = append(order, r.URL.Path)
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	crawler := &Crawler{
+		C:        colly.NewCollector(colly.Async(true)),
+		stopChan: make(chan struct{}),
+	}
+	crawler.C.Limit(&colly.LimitRule{DomainGlob: "*", Parallelism: 1})
+	crawler.initVisitQueue(1, DefaultPriorityKeywords, 0)
+	crawler.C.OnResponse(func(r *colly.Response) {
+		if r.Request.URL.Path != "/" {
+			return
+		}
+		for i := 0; i < 10; i++ {
+			crawler.visit(r.Request, fmt.Sprintf("%s/page%d", srv.URL, i))
+		}
+		crawler.visit(r.Request, srv.URL+"/admin")
+		// Duplicates are dropped before they are queued
+		crawler.visit(r.Request, srv.URL+"/admin")
+	})
+
+	crawler.visit(nil, srv.URL+"/")
+	crawler.C.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, order, 12)
+	assert.Equal(t, "/", order[0])
+	// page0 may already be in flight when the keyword URL arrives
+	assert.Contains(t, order[1:3], "/admin")
+}
+
+func TestVisitQueueKeepsScopeAndDepth(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.URL.Path)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	crawler := &Crawler{
+		C:        colly.NewCollector(colly.Async(true), colly.MaxDepth(2)),
+		stopChan: make(chan struct{}),
+	}
+	crawler.C.URLFilters = []*regexp.Regexp{regexp.MustCompile(`/keep`)}
+	crawler.initVisitQueue(2, DefaultPriorityKeywords, 0)
+
+	parent := &colly.Request{Depth: 2}
+	crawler.visit(parent, srv.URL+"/keep/too-deep")
+	crawler.visit(nil, srv.URL+"/admin")
+	crawler.visit(nil, srv.URL+"/keep/api")
+	crawler.C.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"/keep/api"}, seen)
+}
+
+func TestVisitSpillRoundTrip(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	spill, err := newVisitSpill()
+	require.NoError(t, err)
+	defer spill.remove()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, spill.push(pendingVisit{url: fmt.Sprintf("http://example.com/%d", i), depth: i + 1}))
+	}
+	assert.Error(t, spill.push(pendingVisit{url: "http://example.com/a\tb"}))
+	visits, err := spill.pop(3)
+	require.NoError(t, err)
+	require.Len(t, visits, 3)
+	assert.Equal(t, "http://example.com/0", visits[0].url)
+	assert.Equal(t, 3, visits[2].depth)
+
+	// Pushes after a partial read queue up behind the rest
+	require.NoError(t, spill.push(pendingVisit{url: "http://example.com/late", priority: 1}))
+	visits, err = spill.pop(10)
+	require.NoError(t, err)
+	require.Len(t, visits, 3)
+	assert.Equal(t, "http://example.com/late", visits[2].url)
+	assert.Equal(t, 1, visits[2].priority)
+	assert.Equal(t, 0, spill.Len())
+
+	// A drained spill starts over at the beginning of the file
+	info, err := spill.file.Stat()
+	require.NoError(t, err)
+	assert.Zero(t, info.Size())
+	require.NoError(t, spill.push(pendingVisit{url: "http://example.com/again"}))
+	visits, err = spill.pop(1)
+	require.NoError(t, err)
+	assert.Equal(t, "http://example.com/again", visits[0].url)
+}
+
+func TestVisitQueueSpillsToDisk(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	var mu sync.Mutex
+	requested := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested[r.URL.Path]++
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	crawler := &Crawler{
+		C:        colly.NewCollector(colly.Async(true)),
+		stopChan: make(chan struct{}),
+		Stats:    NewCrawlStats(),
+	}
+	crawler.C.Limit(&colly.LimitRule{DomainGlob: "*", Parallelism: 1})
+	crawler.initVisitQueue(1, nil, 4)
+	var spilled bool
+	var pendingVisits int64
+	crawler.C.OnResponse(func(r *colly.Response) {
+		if r.Request.URL.Path != "/" {
+			return
+		}
+		for round := 0; round < 2; round++ {
+			for i := 0; i < 30; i++ {
+				crawler.visit(r.Request, fmt.Sprintf("%s/page%d", srv.URL, i))
+			}
+		}
+		crawler.visitQueue.mu.Lock()
+		spilled = crawler.visitQueue.spill.Len() > 0 && crawler.visitQueue.pending.Len() <= 4
+		crawler.visitQueue.mu.Unlock()
+		pendingVisits = crawler.Stats.GetPendingVisits()
+	})
+
+	crawler.visit(nil, srv.URL+"/")
+	crawler.C.Wait()
+	crawler.closeVisitQueue()
+
+	assert.True(t, spilled)
+	// The first page took the free slot; spilled ones count as waiting too
+	assert.Equal(t, int64(29), pendingVisits)
+	assert.Zero(t, crawler.Stats.GetPendingVisits())
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, requested, 31)
+	for path, n := range requested {
+		assert.Equal(t, 1, n, path)
+	}
+	entries, err := os.ReadDir(tmp)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
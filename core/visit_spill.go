@@ -0,0 +1,97 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// visitSpill is the disk overflow of the visit queue: pending visits past
+// --max-queue-size are appended to a temp file and read back in order as the
+// in-memory queue drains. The file is truncated whenever it has been read to
+// the end, so it only grows with the backlog
+type visitSpill struct {
+	file   *os.File
+	writer *bufio.Writer
+	offset int64
+	count  int
+}
+
+func newVisitSpill() (*visitSpill, error) {
+	file, err := os.CreateTemp("", "gospider-queue-*.tsv")
+	if err != nil {
+		return nil, err
+	}
+	return &visitSpill{file: file, writer: bufio.NewWriter(file)}, nil
+}
+
+// Len returns how many visits wait on disk
+func (s *visitSpill) Len() int {
+	if s == nil {
+		return 0
+	}
+	return s.count
+}
+
+// push appends a visit; URLs hold no tabs or newlines once normalized
+func (s *visitSpill) push(v pendingVisit) error {
+	if strings.ContainsAny(v.url, "\t\n") {
+		return fmt.Errorf("unspillable URL %q", v.url)
+	}
+	if _, err := fmt.Fprintf(s.writer, "%d\t%d\t%s\n", v.depth, v.priority, v.url); err != nil {
+		return err
+	}
+	s.count++
+	return nil
+}
+
+// pop reads back up to n visits in the order they were spilled
+func (s *visitSpill) pop(n int) ([]pendingVisit, error) {
+	if err := s.writer.Flush(); err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(io.NewSectionReader(s.file, s.offset, math.MaxInt64-s.offset))
+	var visits []pendingVisit
+	for len(visits) < n && s.count > 0 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return visits, err
+		}
+		s.offset += int64(len(line))
+		s.count--
+		fields := strings.SplitN(strings.TrimSuffix(line, "\n"), "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		depth, _ := strconv.Atoi(fields[0])
+		priority, _ := strconv.Atoi(fields[1])
+		visits = append(visits, pendingVisit{url: fields[2], depth: depth, priority: priority})
+	}
+	if s.count == 0 {
+		// Everything was read back; start over at the beginning of the file
+		if err := s.file.Truncate(0); err != nil {
+			return visits, err
+		}
+		if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+			return visits, err
+		}
+		s.offset = 0
+		s.writer.Reset(s.file)
+	}
+	return visits, nil
+}
+
+// remove deletes the spill file
+func (s *visitSpill) remove() {
+	if s == nil {
+		return
+	}
+	s.file.Close()
+	if err := os.Remove(s.file.Name()); err != nil && !os.IsNotExist(err) {
+		Logger.Warnf("Could not remove queue spill file %s: %s", s.file.Name(), err)
+	}
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"bytes"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+)
+
+const (
+	// maxWasmSize caps the modules we scan for strings
+	maxWasmSize = 5 * 1024 * 1024
+	// minWasmStringLen is the shortest printable run considered a string
+	minWasmStringLen = 5
+)
+
+var (
+	wasmMagic         = []byte{0x00, 'a', 's', 'm'}
+	wasmURLRegex      = regexp.MustCompile(`https?://[a-zA-Z0-9.\-]+(?::\d+)?(?:/[^\s"'<>\\]*)?`)
+	wasmPathRegex     = regexp.MustCompile(`(?:^|[\s"'=(])(/[a-zA-Z][a-zA-Z0-9_\-.~]*(?:/[a-zA-Z0-9_\-.~{}:]+)*/?(?:\?[a-zA-Z0-9_\-.~=&%]*)?)`)
+	wasmNoisePrefixes = []string{"/usr/", "/lib/", "/proc/", "/dev/", "/rustc/", "/home/", "/tmp/", "/bin/", "/etc/"}
+)
+
+// isWasmResponse reports whether a response looks like a WebAssembly module
+func isWasmResponse(rawURL, contentType string, body []byte) bool {
+	if strings.Contains(strings.ToLower(contentType), "application/wasm") {
+		return true
+	}
+	if GetExtType(rawURL) == ".wasm" {
+		return true
+	}
+	return bytes.HasPrefix(body, wasmMagic)
+}
+
+// ExtractWasmStrings returns printable ASCII runs of at least minLen bytes
+func ExtractWasmStrings(data []byte, minLen int) []string {
+	var out []string
+	start := -1
+	for i, b := range data {
+		if b >= 0x20 && b < 0x7f {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 && i-start >= minLen {
+			out = append(out, string(data[start:i]))
+		}
+		start = -1
+	}
+	if start >= 0 && len(data)-start >= minLen {
+		out = append(out, string(data[start:]))
+	}
+	return out
+}
+
+// WasmEndpoints pulls URL and path looking values out of extracted strings
+func WasmEndpoints(values []string) []string {
+	var endpoints []string
+	for _, value := range values {
+		endpoints = append(endpoints, wasmURLRegex.FindAllString(value, -1)...)
+		for _, m := range wasmPathRegex.FindAllStringSubmatch(value, -1) {
+			candidate := m[1]
+			if len(candidate) < 3 || isWasmNoisePath(candidate) {
+				continue
+			}
+			endpoints = append(endpoints, candidate)
+		}
+	}
+	return Unique(endpoints)
+}
+
+func isWasmNoisePath(p string) bool {
+	for _, prefix := range wasmNoisePrefixes {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	// source file paths compiled into the module
+	switch path.Ext(strings.SplitN(p, "?", 2)[0]) {
+	case ".rs", ".c", ".h", ".cpp", ".go", ".zig":
+		return true
+	}
+	return false
+}
+
+// skipOversizedWasm aborts wasm downloads whose declared size exceeds the cap
+func (crawler *Crawler) skipOversizedWasm(response *colly.Response) {
+	if response.Request == nil || response.Request.URL == nil {
+		return
+	}
+	contentType := strings.ToLower(response.Headers.Get("Content-Type"))
+	if !strings.Contains(contentType, "application/wasm") && GetExtType(response.Request.URL.String()) != ".wasm" {
+		return
+	}
+	if size, err := strconv.ParseInt(response.Headers.Get("Content-Length"), 10, 64); err == nil && size > maxWasmSize {
+		Logger.Debugf("Skipping wasm module %s: %d bytes exceeds cap", response.Request.URL, size)
+		response.Request.Abort()
+	}
+}
+
+// handleWasm scans a WebAssembly module for embedded endpoints and queues them
+func (crawler *Crawler) handleWasm(response *colly.Response) {
+	wasmURL := response.Request.URL.String()
+	if len(response.Body) > maxWasmSize {
+		Logger.Debugf("Skipping wasm module %s: %d bytes exceeds cap", wasmURL, len(response.Body))
+		return
+	}
+
+	for _, endpoint := range WasmEndpoints(ExtractWasmStrings(response.Body, minWasmStringLen)) {
+		if crawler.wasmSet.Duplicate(endpoint) {
+			continue
+		}
+		if urlToVisit := crawler.urlProcessor.Process(endpoint, wasmURL, "wasm", response.Request); urlToVisit != "" {
+			crawler.visit(nil, urlToVisit)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWasmEndpoints(t *testing.T) {
+	module := append([]byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00}, []byte("\x02https://api.example.com/v1/users\x00\x07/api/v2/login?next=1\x00\x05/rustc/abc/src/lib.rs\x00short\x00")...)
+
+	assert.True(t, isWasmResponse("https://example.com/app", "", module))
+	got := WasmEndpoints(ExtractWasmStrings(module, minWasmStringLen))
+	assert.ElementsMatch(t, []string{"https://api.example.com/v1/users", "/api/v2/login?next=1"}, got)
+}
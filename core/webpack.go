@@ -0,0 +1,260 @@
+package core
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxWebpackChunks caps the chunks enumerated from one runtime
+const maxWebpackChunks = 2000
+
+var (
+	// webpackChunkFuncRegex finds the function mapping a chunk id to its
+	// file: __webpack_require__.u in webpack 5, jsonpScriptSrc in webpack 4,
+	// minified or not. The groups hold its parameter, and the brace of an
+	// arrow function with a body
+	webpackChunkFuncRegex = regexp.MustCompile(`[\w$]+\.u\s*=\s*(?:function\s*\(\s*([\w$]+)\s*\)\s*\{|\(?\s*([\w$]+)\s*\)?\s*=>\s*(\{)?)|function\s+jsonpScriptSrc\s*\(\s*([\w$]+)\s*\)\s*\{`)
+	// webpackPublicPathRegex finds a literal __webpack_require__.p
+	webpackPublicPathRegex = regexp.MustCompile(`(?:__webpack_require__|\b[a-zA-Z_$][\w$]?)\.p\s*=\s*("[^"]*"|'[^']*')`)
+	// webpackMapEntryRegex matches the entries of a chunk id map
+	webpackMapEntryRegex = regexp.MustCompile(`(?:"([^"]*)"|'([^']*)'|([\w$-]+))\s*:\s*("(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*')`)
+	webpackCommentRegex  = regexp.MustCompile(`^(?:\s+|//[^\n]*\n|/\*[\s\S]*?\*/)*`)
+)
+
+// webpackTerm is one operand of the concatenation building a chunk file
+// name: a literal, the chunk id, or a lookup of the id in a map, falling
+// back to the id itself for the name maps
+type webpackTerm struct {
+	literal  string
+	isID     bool
+	lookup   map[string]string
+	fallback bool
+}
+
+// WebpackChunkURLs finds the webpack runtime in a script or page and
+// returns the URLs of every chunk it can load, so lazily loaded chunks are
+// crawled too. Chunks resolve against the runtime's literal public path,
+// or against page when it is computed at runtime
+func WebpackChunkURLs(body string, page *url.URL) []string {
+	base := page
+	if m := webpackPublicPathRegex.FindStringSubmatch(body); m != nil {
+		if publicPath, ok := unquoteJS(m[1]); ok {
+			if ref, err := url.Parse(publicPath); err == nil {
+				base = page.ResolveReference(ref)
+			}
+		}
+	}
+
+	var found []string
+	seen := map[string]bool{}
+	for _, loc := range webpackChunkFuncRegex.FindAllStringSubmatchIndex(body, -1) {
+		param := ""
+		for _, g := range []int{2, 4, 8} {
+			if loc[g] >= 0 {
+				param = body[loc[g]:loc[g+1]]
+			}
+		}
+		rest := body[loc[1]:]
+		block := loc[6] >= 0 || loc[2] >= 0 || loc[8] >= 0
+		rest = rest[len(webpackCommentRegex.FindString(rest)):]
+		if block {
+			var ok bool
+			if rest, ok = strings.CutPrefix(rest, "return"); !ok {
+				continue
+			}
+		}
+		terms, ok := parseWebpackTerms(readJSExpression(rest), param)
+		if !ok {
+			continue
+		}
+		for _, name := range webpackChunkNames(terms) {
+			ref, err := url.Parse(name)
+			if err != nil {
+				continue
+			}
+			chunk := base.ResolveReference(ref).String()
+			if !seen[chunk] {
+				seen[chunk] = true
+				found = append(found, chunk)
+			}
+		}
+	}
+	return found
+}
+
+// readJSExpression returns the expression at the start of s, up to the
+// first ; , } or ) outside brackets and strings
+func readJSExpression(s string) string {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			if depth == 0 {
+				return s[:i]
+			}
+			depth--
+		case (c == ';' || c == ',' || c == '\n') && depth == 0:
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// splitJSConcat splits an expression on the + signs outside brackets and
+// strings
+func splitJSConcat(expr string) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		case c == '+' && depth == 0:
+			parts = append(parts, expr[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, expr[start:])
+}
+
+// parseWebpackTerms understands the concatenations webpack generates;
+// anything else, such as a call, makes it give up
+func parseWebpackTerms(expr, param string) ([]webpackTerm, bool) {
+	if strings.TrimSpace(expr) == "" || param == "" {
+		return nil, false
+	}
+	var terms []webpackTerm
+	lookupRegex := regexp.MustCompile(`^(?s)\(?\s*(\{.*\})\s*\)?\s*\[\s*` + regexp.QuoteMeta(param) + `\s*\]$`)
+	for _, part := range splitJSConcat(expr) {
+		part = trimParens(strings.TrimSpace(part))
+		fallback := false
+		if left, ok := strings.CutSuffix(part, "||"+param); ok {
+			part, fallback = trimParens(strings.TrimSpace(left)), true
+		} else if left, ok := strings.CutSuffix(part, "|| "+param); ok {
+			part, fallback = trimParens(strings.TrimSpace(left)), true
+		}
+		switch {
+		case part == param && !fallback:
+			terms = append(terms, webpackTerm{isID: true})
+		case strings.HasSuffix(part, ".p") && !fallback:
+			// The public path, resolved separately
+		case lookupRegex.MatchString(part):
+			lookup := map[string]string{}
+			for _, m := range webpackMapEntryRegex.FindAllStringSubmatch(lookupRegex.FindStringSubmatch(part)[1], -1) {
+				if value, ok := unquoteJS(m[4]); ok {
+					lookup[m[1]+m[2]+m[3]] = value
+				}
+			}
+			terms = append(terms, webpackTerm{lookup: lookup, fallback: fallback})
+		default:
+			literal, ok := unquoteJS(part)
+			if !ok || fallback {
+				return nil, false
+			}
+			terms = append(terms, webpackTerm{literal: literal})
+		}
+	}
+	return terms, true
+}
+
+// webpackChunkNames evaluates the terms for every chunk id the maps list.
+// Ids missing from a map without fallback are not chunks of that kind
+func webpackChunkNames(terms []webpackTerm) []string {
+	ids := map[string]bool{}
+	for _, term := range terms {
+		for id := range term.lookup {
+			ids[id] = true
+		}
+	}
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+	if len(sorted) > maxWebpackChunks {
+		sorted = sorted[:maxWebpackChunks]
+	}
+
+	var names []string
+	for _, id := range sorted {
+		var b strings.Builder
+		ok := true
+		for _, term := range terms {
+			switch {
+			case term.isID:
+				b.WriteString(id)
+			case term.lookup != nil:
+				value, found := term.lookup[id]
+				if !found && !term.fallback {
+					ok = false
+				} else if !found {
+					value = id
+				}
+				b.WriteString(value)
+			default:
+				b.WriteString(term.literal)
+			}
+		}
+		if ok {
+			names = append(names, b.String())
+		}
+	}
+	return names
+}
+
+func trimParens(s string) string {
+	for len(s) >= 2 && s[0] == '(' && s[len(s)-1] == ')' && readJSExpression(s[1:]) == s[1:len(s)-1] {
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+	return s
+}
+
+// unquoteJS decodes a single or double quoted JS string literal
+func unquoteJS(s string) (string, bool) {
+	if len(s) < 2 || s[0] != s[len(s)-1] || (s[0] != '"' && s[0] != '\'') {
+		return "", false
+	}
+	if s[0] == '\'' {
+		s = `"` + strings.ReplaceAll(strings.ReplaceAll(s[1:len(s)-1], `\'`, `'`), `"`, `\"`) + `"`
+	}
+	unquoted, err := strconv.Unquote(s)
+	return unquoted, err == nil
+}
+
+// findWebpackChunks feeds the lazily loaded chunks of a webpack runtime
+// found in a script or page to LinkFinder
+func (crawler *Crawler) findWebpackChunks(body string, page *url.URL) {
+	if !strings.Contains(body, ".u=") && !strings.Contains(body, ".u =") && !strings.Contains(body, "jsonpScriptSrc") {
+		return
+	}
+	for _, chunk := range WebpackChunkURLs(body, page) {
+		crawler.feedLinkfinder(chunk, "webpack-chunk", page.String())
+	}
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebpackChunkURLs(t *testing.T) {
+	page, _ := url.Parse("https://app.example.com/assets/js/runtime.js")
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{
+			name: "webpack 5 minified",
+			body: `(()=>{var r={};r.u=e=>"static/js/"+({71:"about"}[e]||e)+"."+{71:"a1b2",82:"c3d4"}[e]+".chunk.js",r.miniCssF=e=>"x.css",r.p="/";})()`,
+			want: []string{
+				"https://app.example.com/static/js/about.a1b2.chunk.js",
+				"https://app.example.com/static/js/82.c3d4.chunk.js",
+			},
+		},
+		{
+			name: "webpack 5 development",
+			body: "__webpack_require__.u = (chunkId) => {\n\t// return url for filenames based on template\n\treturn \"\" + chunkId + \".\" + {\"src_Admin_js\":\"9f8e\"}[chunkId] + \".js\";\n};",
+			want: []string{"https://app.example.com/assets/js/src_Admin_js.9f8e.js"},
+		},
+		{
+			name: "webpack 4 jsonpScriptSrc",
+			body: `function jsonpScriptSrc(chunkId) { return __webpack_require__.p + "js/" + ({'vendors':'vendors'}[chunkId]||chunkId) + "." + {'0':'11aa','vendors':'22bb'}[chunkId] + ".js" } __webpack_require__.p = "https://cdn.example.com/";`,
+			want: []string{
+				"https://cdn.example.com/js/0.11aa.js",
+				"https://cdn.example.com/js/vendors.22bb.js",
+			},
+		},
+		{
+			name: "next.js",
+			body: `d.u=function(e){return"static/chunks/"+(({123:"dashboard"})[e]||e)+"."+({123:"ff00",456:"ee11"})[e]+".js"},d.p="/_next/"`,
+			want: []string{
+				"https://app.example.com/_next/static/chunks/dashboard.ff00.js",
+				"https://app.example.com/_next/static/chunks/456.ee11.js",
+			},
+		},
+		{
+			name: "no chunk map",
+			body: `r.u=e=>"static/js/"+e+".js"`,
+		},
+		{
+			name: "unknown expression",
+			body: `r.u=e=>getChunkName(e)+{1:"a"}[e]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, WebpackChunkURLs(tt.body, page))
+		})
+	}
+}
+
+func TestCrawlWebpackChunks(t *testing.T) {
+	var fetched atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/static/js/runtime.js":
+			w.Header().Set("Content-Type", "application/javascript")
+			fmt.Fprint(w, `(()=>{var r={};r.u=e=>"static/js/"+e+"."+{7:"abc1"}[e]+".chunk.js",r.p="/"})();`)
+		case "/static/js/7.abc1.chunk.js":
+			fetched.Store(true)
+			w.Header().Set("Content-Type", "application/javascript")
+			fmt.Fprint(w, `fetch("/api/admin/users")`)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><script src="/static/js/runtime.js"></script></body></html>`)
+		}
+	}))
+	defer srv.Close()
+
+	results := crawlAll(t, srv.URL, CrawlerConfig{LinkFinder: true})
+
+	chunks := map[string]string{}
+	for _, sout := range results {
+		if sout.OutputType == "webpack-chunk" {
+			chunks[sout.Output] = sout.Source
+		}
+	}
+	assert.Equal(t, map[string]string{srv.URL + "/static/js/7.abc1.chunk.js": srv.URL + "/static/js/runtime.js"}, chunks)
+	assert.True(t, fetched.Load(), "the lazy chunk is fetched like a script")
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// How a WebSocket endpoint was found, shown in its text line
+const (
+	webSocketLiteral     = "literal"
+	webSocketConstructor = "new-websocket"
+	webSocketSocketIO    = "socket.io"
+	webSocketBrowser     = "browser"
+)
+
+var (
+	// webSocketURLRegex matches absolute ws:// and wss:// URLs anywhere in a script
+	webSocketURLRegex = regexp.MustCompile("wss?://[^\\s\"'`<>()\\\\{}]+")
+	// webSocketCtorRegex matches new WebSocket("...") with a literal address
+	webSocketCtorRegex = regexp.MustCompile("new\\s+(?:window\\.)?WebSocket\\(\\s*([\"'`])([^\"'`]+)[\"'`]")
+	// socketIORegex matches io("..."), io.connect("...") and io() calls of
+	// the socket.io client, with the start of their options
+	socketIORegex = regexp.MustCompile("\\bio(?:\\.connect)?\\(\\s*(?:([\"'`])([^\"'`]*)[\"'`])?\\s*(?:,\\s*(\\{[^}]{0,400}))?")
+	// webSocketHostRegex rejects hosts that are placeholders or fragments of
+	// code rather than names
+	webSocketHostRegex = regexp.MustCompile(`^(?:[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*|\[[0-9A-Fa-f:.]+\])(?::[0-9]+)?$`)
+	// socketIOPathRegex finds the path option of a socket.io client
+	socketIOPathRegex = regexp.MustCompile("\\bpath\\s*:\\s*[\"'`]([^\"'`]+)[\"'`]")
+)
+
+// webSocketEndpoint is a WebSocket URL found in a script
+type webSocketEndpoint struct {
+	URL     string
+	Method  string
+	Snippet string
+}
+
+// findWebSocketEndpoints extracts the WebSocket endpoints of a script or
+// page. Relative addresses are resolved against page with its scheme
+// mapped to ws or wss; addresses built at runtime are skipped
+func findWebSocketEndpoints(body string, page *url.URL) []webSocketEndpoint {
+	var found []webSocketEndpoint
+	seen := map[string]bool{}
+	add := func(raw, method, snippet string) {
+		u, ok := webSocketURL(raw, page)
+		if !ok || seen[u] {
+			return
+		}
+		seen[u] = true
+		found = append(found, webSocketEndpoint{URL: u, Method: method, Snippet: snippet})
+	}
+
+	for _, m := range webSocketCtorRegex.FindAllStringSubmatch(body, -1) {
+		add(m[2], webSocketConstructor, m[0])
+	}
+	if strings.Contains(body, "socket.io") || strings.Contains(body, "io.connect") {
+		for _, m := range socketIORegex.FindAllStringSubmatch(body, -1) {
+			// A bare io() without options is too common a call to trust
+			if m[1] == "" && m[3] == "" {
+				continue
+			}
+			path := "/socket.io/"
+			if p := socketIOPathRegex.FindStringSubmatch(m[3]); p != nil {
+				path = p[1]
+			}
+			base := m[2]
+			if base == "" {
+				base = "/"
+			}
+			add(socketIOURL(base, path), webSocketSocketIO, strings.TrimSpace(m[0]))
+		}
+	}
+	for _, m := range webSocketURLRegex.FindAllString(body, -1) {
+		add(m, webSocketLiteral, m)
+	}
+	return found
+}
+
+// socketIOURL joins a socket.io server address and path option
+func socketIOURL(server, path string) string {
+	u, err := url.Parse(server)
+	if err != nil {
+		return ""
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	u.Path = path
+	return u.String()
+}
+
+// webSocketURL resolves an address against page and maps http(s) to ws(s)
+func webSocketURL(raw string, page *url.URL) (string, bool) {
+	raw = strings.TrimRight(strings.TrimSpace(raw), ".,;")
+	if raw == "" || strings.Contains(raw, "${") || strings.Contains(raw, "+") {
+		return "", false
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	if page != nil {
+		u = page.ResolveReference(u)
+	}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	case "ws", "wss":
+	default:
+		return "", false
+	}
+	if !webSocketHostRegex.MatchString(u.Host) {
+		return "", false
+	}
+	u.Fragment = ""
+	return u.String(), true
+}
+
+// findWebSockets reports the WebSocket endpoints of a script or page
+func (crawler *Crawler) findWebSockets(body string, page *url.URL) {
+	for _, endpoint := range findWebSocketEndpoints(body, page) {
+		crawler.emitWebSocket(endpoint.URL, page.String(), endpoint.Method, endpoint.Snippet)
+	}
+}
+
+// emitWebSocket reports a WebSocket endpoint found on origin, once per crawl
+func (crawler *Crawler) emitWebSocket(rawURL, origin, method, snippet string) {
+	if crawler.webSocketSet.Duplicate(rawURL) {
+		return
+	}
+	if crawler.Stats != nil {
+		crawler.Stats.IncrementURLsFound()
+	}
+	sout := SpiderOutput{
+		Input:      crawler.Input,
+		Source:     origin,
+		OutputType: "websocket",
+		Output:     rawURL,
+		Snippet:    snippet,
+	}
+	crawler.emit(sout, fmt.Sprintf("[websocket] - [%s] - %s - %s", method, rawURL, origin), rawURL)
+}
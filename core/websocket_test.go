@@ -0,0 +1,73 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindWebSocketEndpoints(t *testing.T) {
+	page, _ := url.Parse("https://app.example.com/dashboard/")
+	script := `
+		const live = new WebSocket("/live/feed");
+		const chat = new window.WebSocket('wss://chat.example.com/ws?room=1#x');
+		const dyn = new WebSocket(proto + "://" + location.host + "/dyn");
+		const tmpl = new WebSocket(` + "`wss://${host}/tmpl`" + `);
+		const cfg = {endpoint: "ws://legacy.example.com:8080/socket"};
+		// socket.io client
+		const a = io("https://rt.example.com", { path: "/realtime", transports: ["websocket"] });
+		const b = io.connect("/", {reconnection: true});
+		const c = io();
+	`
+	endpoints := findWebSocketEndpoints(script+`<script src="/socket.io/socket.io.js"></script>`, page)
+	var urls, methods []string
+	for _, e := range endpoints {
+		urls = append(urls, e.URL)
+		methods = append(methods, e.Method)
+	}
+	assert.Equal(t, []string{
+		"wss://app.example.com/live/feed",
+		"wss://chat.example.com/ws?room=1",
+		"wss://rt.example.com/realtime/",
+		"wss://app.example.com/socket.io/",
+		"ws://legacy.example.com:8080/socket",
+	}, urls)
+	assert.Equal(t, []string{webSocketConstructor, webSocketConstructor, webSocketSocketIO, webSocketSocketIO, webSocketLiteral}, methods)
+	assert.True(t, strings.HasPrefix(endpoints[0].Snippet, "new WebSocket("))
+
+	// io() calls only count in scripts that use socket.io
+	assert.Empty(t, findWebSocketEndpoints(`const x = io("/", {a: 1});`, page))
+}
+
+func TestCrawlWebSocketEndpoints(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app.js":
+			w.Header().Set("Content-Type", "application/javascript")
+			fmt.Fprint(w, `var ws = new WebSocket("/ws/updates");`)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><script src="/app.js"></script><script>var s = new WebSocket("wss://push.example.com/v1");</script></body></html>`)
+		}
+	}))
+	defer srv.Close()
+
+	results := crawlAll(t, srv.URL, CrawlerConfig{LinkFinder: true})
+
+	sources := map[string]string{}
+	for _, sout := range results {
+		if sout.OutputType == "websocket" {
+			sources[sout.Output] = sout.Source
+		}
+	}
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+	assert.Equal(t, map[string]string{
+		"wss://push.example.com/v1": srv.URL,
+		wsURL + "/ws/updates":       srv.URL + "/app.js",
+	}, sources)
+}
@@ -0,0 +1,175 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/gocolly/colly/v2"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// maxWellKnownSize caps how much of a .well-known document we read
+const maxWellKnownSize = 1024 * 1024
+
+// DefaultWellKnownPaths are the /.well-known/ documents probed by --well-known
+var DefaultWellKnownPaths = []string{
+	"security.txt",
+	"openid-configuration",
+	"oauth-authorization-server",
+	"apple-app-site-association",
+	"assetlinks.json",
+	"change-password",
+	"mta-sts.txt",
+	"host-meta.json",
+}
+
+// securityTxtFields are the security.txt fields whose values are URLs;
+// Expires and Preferred-Languages are not
+var securityTxtFields = []string{"contact", "policy", "hiring", "acknowledgments", "canonical", "encryption", "csaf"}
+
+// ParseWellKnown probes the configured /.well-known/ paths and feeds the
+// URLs and domains found in them back into the crawler
+func ParseWellKnown(site *url.URL, crawler *Crawler, c *colly.Collector, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for _, name := range crawler.wellKnownPaths {
+		name = strings.Trim(strings.TrimSpace(name), "/")
+		if name == "" {
+			continue
+		}
+		wellKnownURL := site.Scheme + "://" + site.Host + "/.well-known/" + name
+
+		if crawler.stopped.Load() {
+			return
+		}
+		status, body, err := crawler.fetchSide(wellKnownURL, maxWellKnownSize)
+		if err != nil || status != http.StatusOK {
+			continue
+		}
+
+		Logger.Infof("Found .well-known document: %s", wellKnownURL)
+		crawler.emitWellKnown(wellKnownURL, status)
+
+		if name == "openid-configuration" || name == "oauth-authorization-server" {
+			crawler.handleOIDC(wellKnownURL, body, c)
+			continue
+		}
+
+		for _, found := range ExtractWellKnownURLs(name, string(body)) {
+			if urlToVisit := crawler.urlProcessor.Process(found, wellKnownURL, "well-known", nil); urlToVisit != "" {
+				crawler.visit(nil, urlToVisit)
+			}
+		}
+	}
+}
+
+// ExtractWellKnownURLs returns the URLs, paths and related sites referenced
+// by a .well-known document
+func ExtractWellKnownURLs(name, body string) []string {
+	var found []string
+	switch {
+	case strings.HasSuffix(name, ".txt"):
+		found = parseSecurityTxt(body)
+	case name == "apple-app-site-association":
+		found = parseAppSiteAssociation(body)
+	default:
+		var doc interface{}
+		if err := jsoniter.UnmarshalFromString(body, &doc); err == nil {
+			found = collectJSONURLs(doc, nil)
+		}
+	}
+	return Unique(found)
+}
+
+func parseSecurityTxt(body string) []string {
+	var found []string
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		idx := strings.Index(line, ":")
+		if idx <= 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		field := strings.ToLower(strings.TrimSpace(line[:idx]))
+		value := strings.TrimSpace(line[idx+1:])
+		for _, known := range securityTxtFields {
+			if field == known && strings.HasPrefix(value, "http") {
+				found = append(found, value)
+			}
+		}
+	}
+	return found
+}
+
+// parseAppSiteAssociation turns AASA applinks paths into crawlable paths,
+// dropping wildcards and excluded ("NOT ") patterns
+func parseAppSiteAssociation(body string) []string {
+	var aasa struct {
+		Applinks struct {
+			Details []struct {
+				Paths      []string `json:"paths"`
+				Components []struct {
+					Path string `json:"/"`
+				} `json:"components"`
+			} `json:"details"`
+		} `json:"applinks"`
+	}
+	if err := jsoniter.UnmarshalFromString(body, &aasa); err != nil {
+		return nil
+	}
+
+	var found []string
+	for _, detail := range aasa.Applinks.Details {
+		paths := detail.Paths
+		for _, component := range detail.Components {
+			paths = append(paths, component.Path)
+		}
+		for _, p := range paths {
+			if strings.HasPrefix(p, "NOT ") {
+				continue
+			}
+			if idx := strings.IndexAny(p, "*?"); idx != -1 {
+				p = p[:idx]
+			}
+			if strings.HasPrefix(p, "/") && len(p) > 1 {
+				found = append(found, p)
+			}
+		}
+	}
+	return found
+}
+
+// collectJSONURLs walks a decoded JSON document collecting absolute URLs,
+// which covers OIDC endpoints and assetlinks "site" targets
+func collectJSONURLs(node interface{}, found []string) []string {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for _, child := range v {
+			found = collectJSONURLs(child, found)
+		}
+	case []interface{}:
+		for _, child := range v {
+			found = collectJSONURLs(child, found)
+		}
+	case string:
+		if strings.HasPrefix(v, "http://") || strings.HasPrefix(v, "https://") {
+			found = append(found, v)
+		}
+	}
+	return found
+}
+
+func (crawler *Crawler) emitWellKnown(wellKnownURL string, statusCode int) {
+	outputFormat := fmt.Sprintf("[well-known] - [code-%d] - %s", statusCode, wellKnownURL)
+
+	sout := SpiderOutput{
+		Input:      crawler.Input,
+		Source:     "well-known",
+		OutputType: "well-known",
+		StatusCode: statusCode,
+		Output:     wellKnownURL,
+	}
+	crawler.emit(sout, outputFormat, wellKnownURL)
+}
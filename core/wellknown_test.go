@@ -0,0 +1,81 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractWellKnownURLs(t *testing.T) {
+	oidc := `{"issuer":"https://id.example.com","authorization_endpoint":"https://id.example.com/oauth2/authorize","scopes_supported":["openid"]}`
+	assert.ElementsMatch(t, []string{"https://id.example.com", "https://id.example.com/oauth2/authorize"}, ExtractWellKnownURLs("openid-configuration", oidc))
+
+	aasa := `{"applinks":{"details":[{"paths":["/orders/*","NOT /admin/*"],"components":[{"/":"/share/?*"}]}]}}`
+	assert.ElementsMatch(t, []string{"/orders/", "/share/"}, ExtractWellKnownURLs("apple-app-site-association", aasa))
+
+	assetlinks := `[{"relation":["delegate_permission/common.get_login_creds"],"target":{"namespace":"web","site":"https://accounts.example.com"}}]`
+	assert.Equal(t, []string{"https://accounts.example.com"}, ExtractWellKnownURLs("assetlinks.json", assetlinks))
+
+	security := "# comment\nContact: mailto:security@example.com\nPolicy: https://example.com/security-policy\nExpires: 2030-01-01T00:00:00Z\nPreferred-Languages: en, fr\n"
+	assert.Equal(t, []string{"https://example.com/security-policy"}, ExtractWellKnownURLs("security.txt", security))
+}
+
+func TestWellKnownUsesCrawlSession(t *testing.T) {
+	var mu sync.Mutex
+	tokens := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		tokens[r.URL.Path] = r.Header.Get("X-Session")
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/.well-known/security.txt":
+			fmt.Fprint(w, "Contact: mailto:sec@example.com\nPolicy: /policy\nHiring: "+"http://"+r.Host+"/jobs\n")
+		default:
+			fmt.Fprint(w, "<html></html>")
+		}
+	}))
+	defer srv.Close()
+
+	crawlAll(t, srv.URL, CrawlerConfig{WellKnown: true, WellKnownPaths: []string{"security.txt"}, Headers: []string{"X-Session: abc"}})
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "abc", tokens["/.well-known/security.txt"], ".well-known probes carry the crawl headers")
+	assert.Equal(t, "abc", tokens["/jobs"])
+}
+
+func TestWellKnownOAuthAuthorizationServer(t *testing.T) {
+	var srvURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/oauth-authorization-server":
+			fmt.Fprintf(w, `{"issuer":"%[1]s","authorization_endpoint":"%[1]s/authorize","token_endpoint":"%[1]s/token","jwks_uri":"%[1]s/jwks","revocation_endpoint":"%[1]s/revoke"}`, srvURL)
+		case "/jwks":
+			fmt.Fprint(w, `{"keys":[{"kid":"k1"}]}`)
+		default:
+			fmt.Fprint(w, "<html></html>")
+		}
+	}))
+	defer srv.Close()
+	srvURL = srv.URL
+
+	results := crawlAll(t, srv.URL, CrawlerConfig{WellKnown: true, WellKnownPaths: []string{"oauth-authorization-server"}})
+	found := map[string]string{}
+	for _, sout := range results {
+		if sout.OutputType == "oidc" && sout.Param != "" {
+			found[sout.Param] = sout.Output
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"issuer":                 srv.URL,
+		"authorization_endpoint": srv.URL + "/authorize",
+		"token_endpoint":         srv.URL + "/token",
+		"jwks_uri":               srv.URL + "/jwks",
+		"revocation_endpoint":    srv.URL + "/revoke",
+		"jwks_kid":               "k1",
+	}, found)
+}
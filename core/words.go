@@ -0,0 +1,123 @@
+package core
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	defaultWordMinLength = 3
+	maxWordLength        = 40
+)
+
+var (
+	wordSplitRegex = regexp.MustCompile(`[^\p{L}\p{N}_\-]+`)
+	// Hashes, UUIDs and version-like tokens are noise in a content-discovery list
+	wordNoiseRegex = regexp.MustCompile(`^(?:[0-9a-fA-F]{12,}|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|v?\d+(?:[._-]\d+)*)$`)
+)
+
+// WordCollector builds a target-specific wordlist from path segments, source
+// map entries and page titles/headings. It is shared by all crawlers of a run
+type WordCollector struct {
+	path      string
+	minLength int
+	minCount  int
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// NewWordCollector returns a collector that writes words at least minLength
+// long and seen at least minCount times to path
+func NewWordCollector(path string, minLength, minCount int) *WordCollector {
+	if minLength <= 0 {
+		minLength = defaultWordMinLength
+	}
+	if minCount <= 0 {
+		minCount = 1
+	}
+	return &WordCollector{
+		path:      path,
+		minLength: minLength,
+		minCount:  minCount,
+		counts:    make(map[string]int),
+	}
+}
+
+// Add counts one sighting of word
+func (w *WordCollector) Add(word string) {
+	word = strings.Trim(word, "-_.")
+	if len(word) < w.minLength || len(word) > maxWordLength || wordNoiseRegex.MatchString(word) {
+		return
+	}
+	w.mu.Lock()
+	w.counts[word]++
+	w.mu.Unlock()
+}
+
+// AddPath counts the segments of a URL path or relative file path. File
+// names are counted both whole and without their extension
+func (w *WordCollector) AddPath(raw string) {
+	p := raw
+	if u, err := url.Parse(raw); err == nil {
+		p = u.Path
+	}
+	for _, segment := range strings.Split(p, "/") {
+		segment, err := url.PathUnescape(segment)
+		if err != nil || segment == "" || segment == "." || segment == ".." {
+			continue
+		}
+		w.Add(segment)
+		if ext := path.Ext(segment); ext != "" && ext != segment {
+			w.Add(strings.TrimSuffix(segment, ext))
+		}
+	}
+}
+
+// AddText counts the significant words of free text such as a page title
+func (w *WordCollector) AddText(text string) {
+	for _, word := range wordSplitRegex.Split(text, -1) {
+		if isSignificantWord(word) {
+			w.Add(strings.ToLower(word))
+		}
+	}
+}
+
+// isSignificantWord drops words without any letter
+func isSignificantWord(word string) bool {
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Words returns the words passing the frequency filter, most frequent first
+func (w *WordCollector) Words() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	words := make([]string, 0, len(w.counts))
+	for word, count := range w.counts {
+		if count >= w.minCount {
+			words = append(words, word)
+		}
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if w.counts[words[i]] != w.counts[words[j]] {
+			return w.counts[words[i]] > w.counts[words[j]]
+		}
+		return words[i] < words[j]
+	})
+	return words
+}
+
+// Save writes the wordlist, one word per line
+func (w *WordCollector) Save() error {
+	return writeWordlist(w.path, w.Words())
+}
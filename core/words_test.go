@@ -0,0 +1,34 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWordCollector(t *testing.T) {
+	w := NewWordCollector(filepath.Join(t.TempDir(), "words.txt"), 3, 1)
+
+	w.AddPath("https://example.com/api/v2/admin/user-export.php?id=1")
+	w.AddPath("https://example.com/api/internal/5f0c6d2e8a9b4c1d/")
+	w.AddPath("webpack:///./src/components/LoginForm.vue")
+	w.AddText("Billing Dashboard - ACME 2024")
+	w.AddText("Billing settings")
+
+	assert.Equal(t, []string{
+		"api", "billing",
+		"LoginForm", "LoginForm.vue", "acme", "admin", "components", "dashboard", "internal",
+		"settings", "src", "user-export", "user-export.php",
+	}, w.Words())
+
+	w.minCount = 2
+	assert.Equal(t, []string{"api", "billing"}, w.Words())
+
+	require.NoError(t, w.Save())
+	data, err := os.ReadFile(w.path)
+	require.NoError(t, err)
+	assert.Equal(t, "api\nbilling\n", string(data))
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// profilesKey holds the named profiles of a config file
+const profilesKey = "profiles"
+
+// FileOptions is a parsed config file: flag values keyed by their long
+// name, and named profiles of more values applied on top
+type FileOptions struct {
+	Values   map[string]interface{}
+	Profiles map[string]map[string]interface{}
+}
+
+// LoadFile reads a YAML or TOML config file, told apart by its extension.
+// Files with any other extension are read as YAML
+func LoadFile(path string) (FileOptions, error) {
+	var opts FileOptions
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return opts, err
+	}
+
+	raw := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		err = yaml.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return opts, fmt.Errorf("%s: %w", path, err)
+	}
+
+	opts.Values = raw
+	opts.Profiles = map[string]map[string]interface{}{}
+	if profiles, ok := raw[profilesKey]; ok {
+		delete(raw, profilesKey)
+		table, ok := profiles.(map[string]interface{})
+		if !ok {
+			return opts, fmt.Errorf("%s: %s must be a table of named profiles", path, profilesKey)
+		}
+		for name, values := range table {
+			profile, ok := values.(map[string]interface{})
+			if !ok {
+				return opts, fmt.Errorf("%s: profile %q must be a table of flags", path, name)
+			}
+			opts.Profiles[name] = profile
+		}
+	}
+	return opts, nil
+}
+
+// ProfileNames returns the names of the file's profiles, sorted
+func (o FileOptions) ProfileNames() []string {
+	names := make([]string, 0, len(o.Profiles))
+	for name := range o.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Apply sets the flags the file names, then those of profile when it is
+// not empty. Flags given on the command line keep their value
+func (o FileOptions) Apply(flags *pflag.FlagSet, profile string) error {
+	layers := []map[string]interface{}{o.Values}
+	if profile != "" {
+		values, ok := o.Profiles[profile]
+		if !ok {
+			return fmt.Errorf("unknown profile %q (available: %s)", profile, strings.Join(o.ProfileNames(), ", "))
+		}
+		layers = append(layers, values)
+	}
+
+	explicit := map[string]bool{}
+	flags.Visit(func(f *pflag.Flag) { explicit[f.Name] = true })
+
+	for _, values := range layers {
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			f := flags.Lookup(key)
+			if f == nil {
+				return fmt.Errorf("unknown flag %q", key)
+			}
+			if explicit[f.Name] {
+				continue
+			}
+			if err := setFlag(flags, f, values[key]); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ApplyFile loads path and applies it and profile to flags
+func ApplyFile(flags *pflag.FlagSet, path, profile string) error {
+	opts, err := LoadFile(path)
+	if err != nil {
+		return err
+	}
+	return opts.Apply(flags, profile)
+}
+
+func setFlag(flags *pflag.FlagSet, f *pflag.Flag, value interface{}) error {
+	if list, ok := value.([]interface{}); ok {
+		items := make([]string, 0, len(list))
+		for _, item := range list {
+			s, err := scalarString(item)
+			if err != nil {
+				return err
+			}
+			items = append(items, s)
+		}
+		slice, ok := f.Value.(pflag.SliceValue)
+		if !ok {
+			return fmt.Errorf("takes a single value, not a list")
+		}
+		if err := slice.Replace(items); err != nil {
+			return err
+		}
+		f.Changed = true
+		return nil
+	}
+	s, err := scalarString(value)
+	if err != nil {
+		return err
+	}
+	// A scalar replaces a list flag's default rather than adding to it
+	if slice, ok := f.Value.(pflag.SliceValue); ok {
+		if err := slice.Replace([]string{s}); err != nil {
+			return err
+		}
+		f.Changed = true
+		return nil
+	}
+	return flags.Set(f.Name, s)
+}
+
+func scalarString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", value)
+	}
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testFlags() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.IntP("depth", "d", 1, "")
+	flags.IntP("concurrent", "c", 5, "")
+	flags.Bool("stealth", false, "")
+	flags.Float64("global-rps", 0, "")
+	flags.String("proxy", "", "")
+	flags.StringArrayP("header", "H", []string{}, "")
+	flags.StringSlice("blacklist-ext", []string{"png"}, "")
+	return flags
+}
+
+func writeConfig(t *testing.T, name, data string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+	return path
+}
+
+const testYAMLConfig = `
+depth: 3
+concurrent: 10
+header:
+  - "X-Team: red"
+  - "X-Env: prod"
+blacklist-ext: css
+profiles:
+  stealth-bugbounty:
+    stealth: true
+    concurrent: 2
+    global-rps: 0.5
+  fast-internal:
+    concurrent: 50
+`
+
+func TestApplyFileYAMLProfile(t *testing.T) {
+	path := writeConfig(t, "spider.yaml", testYAMLConfig)
+	flags := testFlags()
+	require.NoError(t, flags.Parse([]string{"-d", "5"}))
+
+	require.NoError(t, ApplyFile(flags, path, "stealth-bugbounty"))
+
+	depth, _ := flags.GetInt("depth")
+	assert.Equal(t, 5, depth, "the command line wins")
+	concurrent, _ := flags.GetInt("concurrent")
+	assert.Equal(t, 2, concurrent, "the profile wins over the file")
+	stealth, _ := flags.GetBool("stealth")
+	assert.True(t, stealth)
+	rps, _ := flags.GetFloat64("global-rps")
+	assert.Equal(t, 0.5, rps)
+	headers, _ := flags.GetStringArray("header")
+	assert.Equal(t, []string{"X-Team: red", "X-Env: prod"}, headers)
+	exts, _ := flags.GetStringSlice("blacklist-ext")
+	assert.Equal(t, []string{"css"}, exts)
+	assert.True(t, flags.Changed("header"))
+}
+
+func TestApplyFileTOML(t *testing.T) {
+	path := writeConfig(t, "spider.toml", `
+depth = 2
+proxy = "http://127.0.0.1:8080"
+header = ["X-A: 1"]
+
+[profiles.fast-internal]
+concurrent = 40
+`)
+	flags := testFlags()
+	require.NoError(t, flags.Parse(nil))
+
+	require.NoError(t, ApplyFile(flags, path, "fast-internal"))
+
+	depth, _ := flags.GetInt("depth")
+	assert.Equal(t, 2, depth)
+	concurrent, _ := flags.GetInt("concurrent")
+	assert.Equal(t, 40, concurrent)
+	proxy, _ := flags.GetString("proxy")
+	assert.Equal(t, "http://127.0.0.1:8080", proxy)
+	headers, _ := flags.GetStringArray("header")
+	assert.Equal(t, []string{"X-A: 1"}, headers)
+}
+
+func TestApplyFileErrors(t *testing.T) {
+	flags := testFlags()
+	require.NoError(t, flags.Parse(nil))
+
+	err := ApplyFile(flags, writeConfig(t, "a.yaml", testYAMLConfig), "missing")
+	assert.EqualError(t, err, `unknown profile "missing" (available: fast-internal, stealth-bugbounty)`)
+
+	err = ApplyFile(flags, writeConfig(t, "b.yaml", "deph: 3\n"), "")
+	assert.EqualError(t, err, `unknown flag "deph"`)
+
+	err = ApplyFile(flags, writeConfig(t, "c.yaml", "depth: [1, 2]\n"), "")
+	assert.EqualError(t, err, "depth: takes a single value, not a list")
+
+	err = ApplyFile(flags, writeConfig(t, "d.yaml", "depth: lots\n"), "")
+	assert.Error(t, err)
+}
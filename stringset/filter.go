@@ -31,3 +31,11 @@ func (sf *StringFilter) Duplicate(s string) bool {
 	sf.filter.Insert(s)
 	return false
 }
+
+// Has reports whether the name was seen before, without recording it.
+func (sf *StringFilter) Has(s string) bool {
+	sf.lock.Lock()
+	defer sf.lock.Unlock()
+
+	return sf.filter.Has(s)
+}